@@ -2,7 +2,15 @@ package actionners
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
 
 	lambdaInvoke "github.com/falco-talon/falco-talon/actionners/aws/lambda"
 	"github.com/falco-talon/falco-talon/outputs"
@@ -21,17 +29,31 @@ import (
 	k8sTcpdump "github.com/falco-talon/falco-talon/actionners/kubernetes/tcpdump"
 	k8sTerminate "github.com/falco-talon/falco-talon/actionners/kubernetes/terminate"
 	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/async"
+	"github.com/falco-talon/falco-talon/internal/audit"
 	awsChecks "github.com/falco-talon/falco-talon/internal/aws/checks"
 	aws "github.com/falco-talon/falco-talon/internal/aws/client"
+	"github.com/falco-talon/falco-talon/internal/breaker"
 	calico "github.com/falco-talon/falco-talon/internal/calico/client"
 	cilium "github.com/falco-talon/falco-talon/internal/cilium/client"
 	"github.com/falco-talon/falco-talon/internal/context"
+	"github.com/falco-talon/falco-talon/internal/deadletter"
 	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/history"
+	"github.com/falco-talon/falco-talon/internal/holds"
+	"github.com/falco-talon/falco-talon/internal/idempotency"
+	"github.com/falco-talon/falco-talon/internal/incidents"
 	k8sChecks "github.com/falco-talon/falco-talon/internal/kubernetes/checks"
 	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/internal/locks"
+	"github.com/falco-talon/falco-talon/internal/nats"
+	"github.com/falco-talon/falco-talon/internal/queue"
 	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/internal/slo"
+	"github.com/falco-talon/falco-talon/internal/stream"
+	"github.com/falco-talon/falco-talon/internal/tracing"
+	"github.com/falco-talon/falco-talon/internal/undo"
 	"github.com/falco-talon/falco-talon/metrics"
-	"github.com/falco-talon/falco-talon/notifiers"
 	"github.com/falco-talon/falco-talon/outputs/model"
 	"github.com/falco-talon/falco-talon/utils"
 )
@@ -41,12 +63,18 @@ type Actionner struct {
 	Category                string
 	Action                  func(action *rules.Action, event *events.Event) (utils.LogLine, *model.Data, error)
 	CheckParameters         func(action *rules.Action) error
+	Snapshot                func(action *rules.Action, event *events.Event) (map[string]string, error)
+	Revert                  func(state map[string]string) error
+	Verify                  func(action *rules.Action, event *events.Event, result utils.LogLine, since time.Time) error
 	Init                    func() error
 	Checks                  []checkActionner
+	Parameters              interface{}      // the struct of the parameters, for the docs
+	Permissions             []k8s.Permission // the RBAC required in the cluster of the actions
 	DefaultContinue         bool
 	AllowAdditionalContexts bool
 	AllowOutput             bool
 	RequireOutput           bool
+	Async                   bool // the action can run in the background, with the `async_actions` setting
 }
 
 // type checkActionner func(event *events.Event, actions ...rules.Action) error
@@ -57,6 +85,16 @@ type Actionners []*Actionner
 var availableActionners *Actionners
 var enabledActionners *Actionners
 
+var runningWorkers sync.WaitGroup
+
+// the loop of the consumer is stopped before the shutdown, the events still in its channel are drained
+var (
+	consumerC        <-chan *nats.Message
+	stopConsumer     = make(chan struct{})
+	consumerStopped  = make(chan struct{})
+	stopConsumerOnce sync.Once
+)
+
 const (
 	trueStr  string = "true"
 	falseStr string = "false"
@@ -81,6 +119,12 @@ func GetDefaultActionners() *Actionners {
 				},
 				CheckParameters: k8sTerminate.CheckParameters,
 				Action:          k8sTerminate.Action,
+				Verify:          k8sTerminate.Verify,
+				Parameters:      k8sTerminate.Config{},
+				Permissions: []k8s.Permission{
+					{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "delete"}},
+					{APIGroups: []string{"apps"}, Resources: []string{"replicasets"}, Verbs: []string{"get"}},
+				},
 			},
 			&Actionner{
 				Category:        "kubernetes",
@@ -90,6 +134,13 @@ func GetDefaultActionners() *Actionners {
 				Checks:          []checkActionner{k8sChecks.CheckPodExist},
 				CheckParameters: k8sLabel.CheckParameters,
 				Action:          k8sLabel.Action,
+				Parameters:      k8sLabel.Config{},
+				Permissions: []k8s.Permission{
+					{APIGroups: []string{""}, Resources: []string{"pods", "nodes"}, Verbs: []string{"get", "patch"}},
+				},
+				Snapshot: k8sLabel.Snapshot,
+				Revert:   k8sLabel.Revert,
+				Verify:   k8sLabel.Verify,
 			},
 			&Actionner{
 				Category:        "kubernetes",
@@ -101,6 +152,15 @@ func GetDefaultActionners() *Actionners {
 				},
 				CheckParameters: k8sNetworkpolicy.CheckParameters,
 				Action:          k8sNetworkpolicy.Action,
+				Parameters:      k8sNetworkpolicy.Config{},
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{"apps"}, Resources: []string{"replicasets", "daemonsets", "statefulsets"}, Verbs: []string{"get"}},
+					{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"networkpolicies"}, Verbs: []string{"get", "create", "update", "patch", "delete"}},
+				},
+				Snapshot: k8sNetworkpolicy.Snapshot,
+				Revert:   k8sNetworkpolicy.Revert,
+				Verify:   k8sNetworkpolicy.Verify,
 			},
 			&Actionner{
 				Category:        "kubernetes",
@@ -110,8 +170,13 @@ func GetDefaultActionners() *Actionners {
 				Checks: []checkActionner{
 					k8sChecks.CheckPodExist,
 				},
-				CheckParameters:         k8sExec.CheckParameters,
-				Action:                  k8sExec.Action,
+				CheckParameters: k8sExec.CheckParameters,
+				Action:          k8sExec.Action,
+				Parameters:      k8sExec.Config{},
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"get", "create"}},
+				},
 				AllowAdditionalContexts: true,
 			},
 			&Actionner{
@@ -122,8 +187,13 @@ func GetDefaultActionners() *Actionners {
 				Checks: []checkActionner{
 					k8sChecks.CheckPodExist,
 				},
-				CheckParameters:         k8sScript.CheckParameters,
-				Action:                  k8sScript.Action,
+				CheckParameters: k8sScript.CheckParameters,
+				Action:          k8sScript.Action,
+				Parameters:      k8sScript.Config{},
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"get", "create"}},
+				},
 				AllowAdditionalContexts: true,
 			},
 			&Actionner{
@@ -136,7 +206,12 @@ func GetDefaultActionners() *Actionners {
 				},
 				CheckParameters: k8sLog.CheckParameters,
 				Action:          k8sLog.Action,
-				AllowOutput:     true,
+				Parameters:      k8sLog.Config{},
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{""}, Resources: []string{"pods/log"}, Verbs: []string{"get"}},
+				},
+				AllowOutput: true,
 			},
 			&Actionner{
 				Category:        "kubernetes",
@@ -144,10 +219,16 @@ func GetDefaultActionners() *Actionners {
 				DefaultContinue: false,
 				Init:            k8s.Init,
 				Checks: []checkActionner{
+					k8sChecks.CheckTargetNamespaceAllowed,
 					k8sChecks.CheckTargetExist,
 				},
 				CheckParameters: nil,
 				Action:          k8sDelete.Action,
+				Permissions: []k8s.Permission{
+					{APIGroups: []string{""}, Resources: []string{"namespaces", "configmaps", "secrets", "services", "serviceaccounts"}, Verbs: []string{"get", "delete"}},
+					{APIGroups: []string{"apps"}, Resources: []string{"deployments", "daemonsets", "statefulsets", "replicasets"}, Verbs: []string{"get", "delete"}},
+					{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"roles", "clusterroles"}, Verbs: []string{"get", "delete"}},
+				},
 			},
 			&Actionner{
 				Category:        "kubernetes",
@@ -155,10 +236,18 @@ func GetDefaultActionners() *Actionners {
 				DefaultContinue: true,
 				Init:            k8s.Init,
 				Checks: []checkActionner{
+					k8sChecks.CheckClusterScope,
 					k8sChecks.CheckPodExist,
 				},
 				CheckParameters: nil,
 				Action:          k8sCordon.Action,
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get", "patch"}},
+				},
+				Snapshot: k8sCordon.Snapshot,
+				Revert:   k8sCordon.Revert,
+				Verify:   k8sCordon.Verify,
 			},
 			&Actionner{
 				Category:        "kubernetes",
@@ -166,10 +255,19 @@ func GetDefaultActionners() *Actionners {
 				DefaultContinue: true,
 				Init:            k8s.Init,
 				Checks: []checkActionner{
+					k8sChecks.CheckClusterScope,
 					k8sChecks.CheckPodExist,
 				},
 				CheckParameters: k8sDrain.CheckParameters,
 				Action:          k8sDrain.Action,
+				Parameters:      k8sDrain.Config{},
+				Async:           true,
+				Permissions: []k8s.Permission{
+					{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+					{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"get", "patch"}},
+					{APIGroups: []string{""}, Resources: []string{"pods/eviction"}, Verbs: []string{"create"}},
+					{APIGroups: []string{"apps"}, Resources: []string{"replicasets"}, Verbs: []string{"get"}},
+				},
 			},
 			&Actionner{
 				Category:        "kubernetes",
@@ -179,8 +277,13 @@ func GetDefaultActionners() *Actionners {
 				Checks: []checkActionner{
 					k8sChecks.CheckPodExist,
 				},
-				CheckParameters:         k8sDownload.CheckParameters,
-				Action:                  k8sDownload.Action,
+				CheckParameters: k8sDownload.CheckParameters,
+				Action:          k8sDownload.Action,
+				Parameters:      k8sDownload.Config{},
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"get", "create"}},
+				},
 				AllowAdditionalContexts: true,
 				RequireOutput:           true,
 			},
@@ -194,7 +297,14 @@ func GetDefaultActionners() *Actionners {
 				},
 				CheckParameters: k8sTcpdump.CheckParameters,
 				Action:          k8sTcpdump.Action,
-				RequireOutput:   true,
+				Parameters:      k8sTcpdump.Config{},
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{""}, Resources: []string{"pods/ephemeralcontainers"}, Verbs: []string{"patch"}},
+					{APIGroups: []string{""}, Resources: []string{"pods/exec"}, Verbs: []string{"get", "create"}},
+				},
+				RequireOutput: true,
+				Async:         true,
 			},
 			&Actionner{
 				Category:        "aws",
@@ -206,6 +316,7 @@ func GetDefaultActionners() *Actionners {
 				},
 				CheckParameters:         lambdaInvoke.CheckParameters,
 				Action:                  lambdaInvoke.Action,
+				Parameters:              lambdaInvoke.Config{},
 				AllowAdditionalContexts: true,
 			},
 			&Actionner{
@@ -219,6 +330,12 @@ func GetDefaultActionners() *Actionners {
 				},
 				CheckParameters: calicoNetworkpolicy.CheckParameters,
 				Action:          calicoNetworkpolicy.Action,
+				Parameters:      calicoNetworkpolicy.Config{},
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{"apps"}, Resources: []string{"replicasets", "daemonsets", "statefulsets"}, Verbs: []string{"get"}},
+					{APIGroups: []string{"projectcalico.org"}, Resources: []string{"networkpolicies"}, Verbs: []string{"get", "create", "update"}},
+				},
 			},
 			&Actionner{
 				Category:        "cilium",
@@ -231,6 +348,12 @@ func GetDefaultActionners() *Actionners {
 				},
 				CheckParameters: ciliumNetworkPolicy.CheckParameters,
 				Action:          ciliumNetworkPolicy.Action,
+				Parameters:      ciliumNetworkPolicy.Config{},
+				Permissions: []k8s.Permission{
+					k8s.PodGetPermission,
+					{APIGroups: []string{"apps"}, Resources: []string{"replicasets", "daemonsets", "statefulsets"}, Verbs: []string{"get"}},
+					{APIGroups: []string{"cilium.io"}, Resources: []string{"ciliumnetworkpolicies"}, Verbs: []string{"get", "create", "update"}},
+				},
 			},
 		)
 	}
@@ -239,6 +362,16 @@ func GetDefaultActionners() *Actionners {
 }
 
 func Init() error {
+	if err := checkGuardrails(configuration.GetConfiguration().Guardrails); err != nil {
+		return err
+	}
+	if err := checkBlastRadius(configuration.GetConfiguration().BlastRadius); err != nil {
+		return err
+	}
+	if err := checkTenants(configuration.GetConfiguration().Tenants); err != nil {
+		return err
+	}
+
 	rules := rules.GetRules()
 
 	categories := map[string]bool{}
@@ -331,23 +464,53 @@ func (actionner *Actionner) AllowAdditionalContext() bool {
 }
 
 func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) error {
+	return executeAction(rule, action, event, false)
+}
+
+// executeAction runs the action, the actions released by an operator are not held by the blast-radius limits
+func executeAction(rule *rules.Rule, action *rules.Action, event *events.Event, released bool) error {
 	actionners := GetActionners()
 	if actionners == nil {
 		return nil
 	}
 
 	log := utils.LogLine{
-		Message:   "action",
-		Rule:      rule.GetName(),
-		Event:     event.Output,
-		Action:    action.GetName(),
-		Actionner: action.GetActionner(),
-		TraceID:   event.TraceID,
+		Message:    "action",
+		Rule:       rule.GetName(),
+		Event:      event.Output,
+		Action:     action.GetName(),
+		Actionner:  action.GetActionner(),
+		TraceID:    event.TraceID,
+		IncidentID: event.IncidentID,
 	}
 
-	if rule.DryRun == trueStr {
+	// the calls of the actionner and of the notifiers are children of the span of the action
+	ctx, span := tracing.Start(event.GetTraceContext(), "action "+action.GetName(),
+		attribute.String("falco_talon.rule", rule.GetName()),
+		attribute.String("falco_talon.action", action.GetName()),
+		attribute.String("falco_talon.actionner", action.GetActionner()),
+		attribute.String("falco_talon.trace_id", event.TraceID),
+	)
+	event.SetTraceContext(ctx)
+	// the span is ended by completeAction once the action is run
+	ended := false
+	defer func() {
+		if !ended {
+			tracing.EndWithLog(span, log)
+			setResult(event, log)
+		}
+	}()
+
+	if rule.IsDryRun() || event.IsTest() {
 		log.Output = "no action, dry-run is enabled"
+		if event.IsTest() {
+			log.Output = "no action, the event is a test"
+		}
+		log.Status = "dry-run"
 		utils.PrintLog("info", log)
+		recordReport(rule, event, log)
+		recordHistory(action, event, log, 0)
+		recordAudit(action, event, log)
 		return nil
 	}
 
@@ -358,17 +521,142 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 		return fmt.Errorf("unknown actionner '%v'", action.GetActionner())
 	}
 
+	if d := getTenantDenial(rule, action, event); d != "" {
+		log.Status = "denied"
+		log.Output = "no action, " + d
+		utils.PrintLog("warning", log)
+		notify(rule, action, event, log)
+		recordHistory(action, event, log, 0)
+		recordAudit(action, event, log)
+		return nil
+	}
+
+	if g := getGuardrail(action, event); g != "" {
+		log.Status = "skipped"
+		log.Output = "no action, " + g
+		utils.PrintLog("warning", log)
+		notify(rule, action, event, log)
+		recordHistory(action, event, log, 0)
+		recordAudit(action, event, log)
+		return nil
+	}
+
+	if v := getPolicyVeto(rule, action, event); v != "" {
+		log.Status = "vetoed"
+		log.Output = "no action, " + v
+		utils.PrintLog("warning", log)
+		notify(rule, action, event, log)
+		recordHistory(action, event, log, 0)
+		recordAudit(action, event, log)
+		return nil
+	}
+
 	if checks := actionner.Checks; len(checks) != 0 {
 		for _, i := range checks {
 			if err := i(event, action); err != nil {
 				log.Error = err.Error()
 				utils.PrintLog("error", log)
+				recordReport(rule, event, log)
 				return err
 			}
 		}
 	}
 
-	result, data, err := actionner.Action(action, event)
+	if !breaker.Allow(action.GetActionner()) {
+		log.Status = "skipped"
+		log.Output = "no action, the circuit breaker of the actionner is open"
+		utils.PrintLog("warning", log)
+		notify(rule, action, event, log)
+		recordHistory(action, event, log, 0)
+		recordAudit(action, event, log)
+		return nil
+	}
+
+	if reason := reserveBlastRadius(action, event, released); reason != "" {
+		entry := holds.Add(rule.GetName(), action.GetName(), action.GetActionner(), reason, event)
+		log.Status = "held"
+		log.Output = fmt.Sprintf("no action, %v, the action is held until its release (id: %v)", reason, entry.ID)
+		utils.PrintLog("warning", log)
+		notify(rule, action, event, log)
+		recordHistory(action, event, log, 0)
+		recordAudit(action, event, log)
+		return nil
+	}
+
+	// the state before a reversible action is kept to undo it
+	var state map[string]string
+	if actionner.Snapshot != nil && undo.GetStore() != nil {
+		var err error
+		state, err = actionner.Snapshot(action, event)
+		if err != nil {
+			utils.PrintLog("warning", utils.LogLine{Message: "undo", Rule: rule.GetName(), Action: action.GetName(), Actionner: action.GetActionner(), TraceID: event.TraceID, Error: err.Error()})
+		}
+		// the revert targets the same cluster, with the same identity, as the action
+		k8s.AddEventToState(state, event)
+	}
+
+	// the targets are resolved before the action, it can delete the pod
+	targets := getEventTargets(event)
+
+	ended = true
+	if actionner.Async && configuration.GetConfiguration().AsyncActions.Enabled {
+		startAsync(rule, action, event, actionner, state, targets, span, log)
+		return nil
+	}
+	return completeAction(rule, action, event, actionner, state, targets, span, log)
+}
+
+// startAsync runs the action in the background, the next actions of the rule don't wait for it, its status
+// is returned by the API with its id
+func startAsync(rule *rules.Rule, action *rules.Action, event *events.Event, actionner *Actionner, state map[string]string, targets []corev1.ObjectReference, span trace.Span, log utils.LogLine) {
+	// the event is copied, its context is updated by the next actions
+	e := *event
+
+	var id string
+	ctx, id := async.Start(e.GetTraceContext(), rule.GetName(), action.GetName(), action.GetActionner(), e.TraceID, func(progress string) {
+		l := log
+		l.Status = async.Running
+		l.Output = progress
+		l.Objects = map[string]string{"async_id": id}
+		utils.PrintLog("info", l)
+		notify(rule, action, &e, l)
+	})
+	e.SetTraceContext(ctx)
+
+	l := log
+	l.Status = async.Running
+	l.Output = "the action is running in the background"
+	l.Objects = map[string]string{"async_id": id}
+	utils.PrintLog("info", l)
+	notify(rule, action, &e, l)
+	setResult(&e, l)
+
+	runningWorkers.Add(1)
+	go func() {
+		defer runningWorkers.Done()
+		_ = completeAction(rule, action, &e, actionner, state, targets, span, log)
+	}()
+}
+
+// completeAction runs the action then stores its output, the span of the action is ended after
+func completeAction(rule *rules.Rule, action *rules.Action, event *events.Event, actionner *Actionner, state map[string]string, targets []corev1.ObjectReference, span trace.Span, log utils.LogLine) error {
+	defer func() {
+		tracing.EndWithLog(span, log)
+		if id := async.GetID(event.GetTraceContext()); id != "" {
+			async.End(id, log)
+		} else {
+			setResult(event, log)
+		}
+	}()
+
+	start := time.Now()
+	result, data, err := runWithRetries(actionner, action, event)
+	duration := time.Since(start)
+	if err != nil {
+		breaker.Failure(action.GetActionner())
+	} else {
+		breaker.Success(action.GetActionner())
+	}
 	log.Status = result.Status
 	if len(result.Objects) != 0 {
 		log.Objects = result.Objects
@@ -385,22 +673,44 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 		log.Output = string(data.Bytes)
 	}
 
+	if err == nil {
+		log.Verification, log.Result = verifyAction(actionner, action, event, result, start)
+	}
+
 	metrics.IncreaseCounter(log)
+	metrics.ObserveActionDuration(log, duration)
+	recordSLO(event, log, err)
+	recordHistory(action, event, log, duration)
+	recordAudit(action, event, log)
+	createKubernetesEvents(event.Cluster, targets, action, log)
 
 	if err != nil {
 		utils.PrintLog("error", log)
-		notifiers.Notify(rule, action, event, log)
+		notify(rule, action, event, log)
 		return err
 	}
 
-	utils.PrintLog("info", log)
-	notifiers.Notify(rule, action, event, log)
+	if log.Verification == unverifiedStr {
+		utils.PrintLog("warning", log)
+	} else {
+		utils.PrintLog("info", log)
+	}
+
+	// the state is stored before the notification, its id is the undo link of the message
+	undoID, errUndo := undo.Add(rule.GetName(), action.GetName(), action.GetActionner(), event.TraceID, state, log.Objects, action.GetRevertAfter())
+	if errUndo != nil {
+		utils.PrintLog("error", utils.LogLine{Message: "undo", Rule: rule.GetName(), Action: action.GetName(), TraceID: event.TraceID, Error: errUndo.Error()})
+	}
+	log.UndoID = undoID
+	notify(rule, action, event, log)
 
 	if actionner.IsOutputRequired() {
 		log = utils.LogLine{
-			Message: "output",
-			Action:  action.GetName(),
-			TraceID: event.TraceID,
+			Message:    "output",
+			Rule:       rule.GetName(),
+			Action:     action.GetName(),
+			TraceID:    event.TraceID,
+			IncidentID: event.IncidentID,
 		}
 		if output == nil || data == nil || len(data.Bytes) == 0 {
 			if output == nil {
@@ -412,7 +722,7 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 			log.Error = err.Error()
 			utils.PrintLog("error", log)
 			metrics.IncreaseCounter(log)
-			notifiers.Notify(rule, action, event, log)
+			notify(rule, action, event, log)
 			return err
 		}
 		target := output.GetTarget()
@@ -422,7 +732,7 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 			log.Error = err.Error()
 			utils.PrintLog("error", log)
 			metrics.IncreaseCounter(log)
-			notifiers.Notify(rule, action, event, log)
+			notify(rule, action, event, log)
 			return err
 		}
 
@@ -435,13 +745,13 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 					log.Status = "failure"
 					utils.PrintLog("error", log)
 					metrics.IncreaseCounter(log)
-					notifiers.Notify(rule, action, event, log)
+					notify(rule, action, event, log)
 					return err2
 				}
 			}
 		}
 
-		result, err = o.Output(output, data)
+		result, err = o.Store(output, data, log)
 		log.Status = result.Status
 		log.Objects = result.Objects
 		if result.Output != "" {
@@ -455,12 +765,12 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 
 		if err != nil {
 			utils.PrintLog("error", log)
-			notifiers.Notify(rule, action, event, log)
+			notify(rule, action, event, log)
 			return err
 		}
 
 		utils.PrintLog("info", log)
-		notifiers.Notify(rule, action, event, log)
+		notify(rule, action, event, log)
 		return nil
 	}
 
@@ -470,14 +780,15 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 			log.Error = err.Error()
 			utils.PrintLog("error", log)
 			metrics.IncreaseCounter(log)
-			notifiers.Notify(rule, action, event, log)
+			notify(rule, action, event, log)
 			return err
 		}
 		log = utils.LogLine{
-			Message: "output",
-			Rule:    rule.GetName(),
-			Action:  action.GetName(),
-			TraceID: event.TraceID,
+			Message:    "output",
+			Rule:       rule.GetName(),
+			Action:     action.GetName(),
+			TraceID:    event.TraceID,
+			IncidentID: event.IncidentID,
 		}
 		target := output.GetTarget()
 		o := outputs.GetOutputs().FindOutput(target)
@@ -485,11 +796,11 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 			err = fmt.Errorf("unknown target '%v'", target)
 			log.Error = err.Error()
 			utils.PrintLog("error", log)
-			notifiers.Notify(rule, action, event, log)
+			notify(rule, action, event, log)
 			return err
 		}
 		log.Target = target
-		result, err = o.Output(output, data)
+		result, err = o.Store(output, data, log)
 		log.Status = result.Status
 		log.Objects = result.Objects
 		if result.Output != "" {
@@ -503,98 +814,481 @@ func runAction(rule *rules.Rule, action *rules.Action, event *events.Event) erro
 
 		if err != nil {
 			utils.PrintLog("error", log)
-			notifiers.Notify(rule, action, event, log)
+			notify(rule, action, event, log)
 			return err
 		}
 
 		utils.PrintLog("info", log)
-		notifiers.Notify(rule, action, event, log)
+		notify(rule, action, event, log)
 		return nil
 	}
 
 	return nil
 }
 
-func StartConsumer(eventsC <-chan string) {
+// Revert restores the state before a reversible action
+func Revert(entry *undo.Entry) error {
+	actionner := GetDefaultActionners().FindActionner(entry.Actionner)
+	if actionner == nil || actionner.Revert == nil {
+		return fmt.Errorf("the actionner '%v' can't be reverted", entry.Actionner)
+	}
+	return actionner.Revert(entry.State)
+}
+
+// recordAudit appends the execution of the action to the audit log
+func recordAudit(action *rules.Action, event *events.Event, log utils.LogLine) {
+	details := map[string]string{}
+	for i, j := range log.Objects {
+		details[i] = j
+	}
+	if log.Output != "" {
+		details["output"] = log.Output
+	}
+	if log.Error != "" {
+		details["error"] = log.Error
+	}
+	printAuditError(audit.Add(audit.Record{
+		Type:      audit.ActionExecuted,
+		TraceID:   event.TraceID,
+		Rule:      log.Rule,
+		Action:    action.GetName(),
+		Actionner: action.GetActionner(),
+		Status:    log.Status,
+		Details:   details,
+	}), event)
+}
+
+func printAuditError(err error, event *events.Event) {
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "audit", TraceID: event.TraceID})
+	}
+}
+
+// recordHistory stores the result of the action in the history
+func recordHistory(action *rules.Action, event *events.Event, log utils.LogLine, duration time.Duration) {
+	err := history.Add(&history.Entry{
+		Time:         time.Now().UTC(),
+		TraceID:      event.TraceID,
+		Rule:         log.Rule,
+		Action:       action.GetName(),
+		Actionner:    action.GetActionner(),
+		Parameters:   action.GetParameters(),
+		Namespace:    event.GetNamespaceName(),
+		Pod:          event.GetPodName(),
+		Objects:      log.Objects,
+		Status:       log.Status,
+		Verification: log.Verification,
+		Output:       log.Output,
+		Error:        log.Error,
+		DurationMs:   duration.Milliseconds(),
+	})
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "history", Rule: log.Rule, Action: action.GetName(), TraceID: event.TraceID})
+	}
+}
+
+// Drain stops the workers once their current event is processed, it waits for them until the timeout.
+// The events still waiting in the queues and in the channel of the consumer are redelivered after the restart
+// with the persistence, they're added to the dead-letter queue otherwise, with the rules they match
+func Drain(timeout time.Duration) error {
+	stopSchedule()
+	if consumerC != nil {
+		stopConsumerOnce.Do(func() { close(stopConsumer) })
+		select {
+		case <-consumerStopped:
+		case <-time.After(timeout):
+		}
+	}
+	queue.Close()
+
+	stopped := make(chan struct{})
+	go func() {
+		runningWorkers.Wait()
+		close(stopped)
+	}()
+
+	var err error
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		err = errors.New("deadline exceeded before the end of the running actions")
+	}
+
+	list := queue.Drain()
+	list = append(list, drainConsumer()...)
+	if len(list) == 0 || configuration.GetConfiguration().Persistence.Enabled {
+		return err
+	}
+	for _, i := range list {
+		addPending(i)
+	}
+	if deadletter.GetStore() == nil {
+		utils.PrintLog("warning", utils.LogLine{Message: "shutdown", Result: fmt.Sprintf("%v event(s) not processed before the shutdown", len(list))})
+	}
+	return err
+}
+
+// drainConsumer returns the events still in the channel of the consumer, they aren't acknowledged
+func drainConsumer() []*events.Event {
+	list := make([]*events.Event, 0)
+	if consumerC == nil {
+		return list
+	}
+	for {
+		select {
+		case m := <-consumerC:
+			var event *events.Event
+			if err := json.Unmarshal([]byte(m.Data), &event); err == nil && event != nil {
+				list = append(list, event)
+			}
+		default:
+			return list
+		}
+	}
+}
+
+// addPending adds the event not processed to the dead-letter queue, with an entry for each rule it matches
+func addPending(event *events.Event) {
+	if !isEventAllowed(event) {
+		return
+	}
+	for _, i := range getTriggeredRules(event) {
+		if actions := i.GetActions(); len(actions) != 0 {
+			if err := deadletter.AddPending(i.GetName(), actions[0].GetName(), actions[0].GetActionner(), event, errors.New("not processed before the shutdown")); err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "deadletter", Rule: i.GetName(), TraceID: event.TraceID})
+			}
+		}
+		if i.Continue == falseStr {
+			break
+		}
+	}
+}
+
+// StartConsumer dispatches the events into the priority queues, each class of priorities
+// has its own pool of workers, the events with the highest priorities are processed first
+func StartConsumer(eventsC <-chan *nats.Message) {
 	config := configuration.GetConfiguration()
+	consumerC = eventsC
+	defer close(consumerStopped)
+
+	initConcurrency(config)
+	if config.CircuitBreaker.Enabled {
+		breaker.Init(config.CircuitBreaker.FailureThreshold, time.Duration(config.CircuitBreaker.OpenDurationSeconds)*time.Second, onCircuitChange)
+	}
+	if config.ActionLocks.Enabled {
+		if err := locks.Init(config.ActionLocks.Backend, time.Duration(config.ActionLocks.TTLSeconds)*time.Second); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "locks"})
+		}
+	}
+	if config.Idempotency.Enabled {
+		if err := idempotency.Init(config.Idempotency); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "idempotency"})
+		}
+	}
+
+	startScheduler()
+
+	for _, i := range queue.Classes {
+		workers := config.Ingestion.Workers.Get(i)
+		for j := 0; j < workers; j++ {
+			runningWorkers.Add(1)
+			go func(class string) {
+				defer runningWorkers.Done()
+				for {
+					event, done := queue.Pop(class)
+					if event == nil {
+						return
+					}
+					if !processEvent(event) {
+						// a new delivery of the event, by a retry of falcosidekick, runs the actions again
+						if err := idempotency.Release(event.UUID); err != nil {
+							utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "idempotency", TraceID: event.TraceID})
+						}
+					}
+					done()
+				}
+			}(i)
+		}
+	}
+
 	for {
-		e := <-eventsC
+		var m *nats.Message
+		select {
+		case <-stopConsumer:
+			return
+		case m = <-eventsC:
+		}
 		var event *events.Event
-		err := json.Unmarshal([]byte(e), &event)
-		if err != nil {
+		err := json.Unmarshal([]byte(m.Data), &event)
+		if err != nil || event == nil {
+			m.Ack()
 			continue
 		}
-		if event == nil {
+		// with the persistence, an event can be delivered again after a restart, its trace id is the idempotency key
+		if nats.GetConsumer().IsProcessed(event.TraceID) {
+			m.Ack()
 			continue
 		}
-
-		log := utils.LogLine{
-			Message:  "event",
-			Event:    event.Rule,
-			Priority: event.Priority,
-			Output:   event.Output,
-			Source:   event.Source,
-			TraceID:  event.TraceID,
-		}
-
-		enabledRules := rules.GetRules()
-		triggeredRules := make([]*rules.Rule, 0)
-		for _, i := range *enabledRules {
-			if i.CompareRule(event) {
-				triggeredRules = append(triggeredRules, i)
+		// the claim of an interrupted delivery is still in a shared store (redis), the event isn't a duplicate
+		if m.IsRedelivery() {
+			if err := idempotency.Release(event.UUID); err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "idempotency", TraceID: event.TraceID})
 			}
 		}
-
-		if len(triggeredRules) == 0 {
+		// the same Falco event can be received several times (retries of falcosidekick, several outputs of Falco),
+		// the event is processed anyway if the store can't be reached
+		duplicate, err := idempotency.IsDuplicate(event.UUID)
+		if err != nil {
+			utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "idempotency", TraceID: event.TraceID})
+		}
+		if duplicate {
+			utils.PrintLog("info", utils.LogLine{Message: "idempotency", Event: event.Rule, Result: fmt.Sprintf("the event with uuid '%v' has already been received, it's ignored", event.UUID), TraceID: event.TraceID})
+			metrics.IncreaseDroppedEvents("duplicate", 1)
+			m.Ack()
 			continue
 		}
+		queue.Push(event, func() {
+			if err := nats.GetConsumer().SetProcessed(event.TraceID); err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "nats", TraceID: event.TraceID})
+			}
+			m.Ack()
+		})
+	}
+}
 
-		if !config.PrintAllEvents {
-			utils.PrintLog("info", log)
-		}
+func onCircuitChange(actionner string, open bool) {
+	metrics.SetCircuitBreaker(actionner, open)
+	if open {
+		utils.PrintLog("warning", utils.LogLine{Message: "circuit-breaker", Actionner: actionner, Result: "circuit open, the actions are skipped"})
+		return
+	}
+	utils.PrintLog("info", utils.LogLine{Message: "circuit-breaker", Actionner: actionner, Result: "circuit closed"})
+}
 
-		for _, i := range triggeredRules {
-			log.Message = "match"
-			log.Rule = i.GetName()
+// isEventAllowed returns false for the events of the namespaces not allowed in the namespace-scoped mode
+func isEventAllowed(event *events.Event) bool {
+	config := configuration.GetConfiguration()
+	if !config.IsNamespaced() {
+		return true
+	}
+	namespace := event.GetNamespaceName()
+	if namespace == "" {
+		namespace = event.GetTargetNamespace()
+	}
+	return config.IsNamespaceAllowed(namespace)
+}
 
-			utils.PrintLog("info", log)
-			metrics.IncreaseCounter(log)
+// processEvent runs the actions of the rules matching the event, false is returned if an action failed
+func processEvent(event *events.Event) bool {
+	config := configuration.GetConfiguration()
 
-			for _, a := range i.GetActions() {
-				e := new(events.Event)
-				*e = *event
-				i.AddFalcoTalonContext(e, a)
-				if GetDefaultActionners().FindActionner(a.GetActionner()).AllowAdditionalContext() &&
-					len(a.GetAdditionalContexts()) != 0 {
-					for _, i := range a.GetAdditionalContexts() {
-						elements, err := context.GetContext(i, e)
-						if err != nil {
-							log := utils.LogLine{
-								Message:   "context",
-								Context:   i,
-								Rule:      e.Rule,
-								Action:    a.GetName(),
-								Actionner: a.GetActionner(),
-								TraceID:   e.TraceID,
-								Error:     err.Error(),
-							}
-							utils.PrintLog("error", log)
-						} else {
-							e.AddContext(elements)
-						}
-					}
-				}
-				if err := runAction(i, a, e); err != nil && a.IgnoreErrors == falseStr {
-					break
+	log := utils.LogLine{
+		Message:  "event",
+		Event:    event.Rule,
+		Priority: event.Priority,
+		Output:   event.Output,
+		Source:   event.Source,
+		TraceID:  event.TraceID,
+	}
+
+	if !isEventAllowed(event) {
+		return true
+	}
+
+	ctx, span := tracing.Start(event.GetTraceContext(), "event",
+		attribute.String("falco.uuid", event.UUID),
+		attribute.String("falco.rule", event.Rule),
+		attribute.String("falco.priority", event.Priority),
+		attribute.String("falco.source", event.Source),
+		attribute.String("falco.hostname", event.Hostname),
+		attribute.String("falco_talon.trace_id", event.TraceID),
+	)
+	event.SetTraceContext(ctx)
+	defer span.End()
+
+	printAuditError(audit.Add(audit.Record{
+		Type:    audit.EventReceived,
+		TraceID: event.TraceID,
+		Details: map[string]string{
+			"rule":     event.Rule,
+			"priority": event.Priority,
+			"source":   event.Source,
+			"output":   event.Output,
+		},
+	}), event)
+
+	_, matchSpan := tracing.Start(ctx, "match")
+	enabledRules := rules.GetRules()
+	triggeredRules := getTriggeredRules(event)
+	matched := make([]string, 0, len(triggeredRules))
+	for _, i := range triggeredRules {
+		matched = append(matched, i.GetName())
+	}
+	matchSpan.SetAttributes(
+		attribute.Int("falco_talon.evaluated_rules", len(*enabledRules)),
+		attribute.StringSlice("falco_talon.matched_rules", matched),
+	)
+	matchSpan.End()
+
+	printAuditError(audit.Add(audit.Record{
+		Type:    audit.RulesEvaluated,
+		TraceID: event.TraceID,
+		Details: map[string]string{
+			"evaluated": fmt.Sprintf("%v", len(*enabledRules)),
+			"matched":   strings.Join(matched, ", "),
+		},
+	}), event)
+
+	stream.Events.Publish(&stream.Event{
+		Time:         event.Time,
+		TraceID:      event.TraceID,
+		Rule:         event.Rule,
+		Priority:     event.Priority,
+		Source:       event.Source,
+		Hostname:     event.Hostname,
+		Output:       event.Output,
+		Namespace:    event.GetNamespaceName(),
+		Pod:          event.GetPodName(),
+		MatchedRules: matched,
+	})
+
+	if len(triggeredRules) == 0 {
+		return true
+	}
+
+	if incident := incidents.Correlate(event); incident != nil {
+		event.IncidentID = incident.ID
+		log.IncidentID = incident.ID
+	}
+
+	if !config.PrintAllEvents {
+		utils.PrintLog("info", log)
+	}
+
+	succeeded := true
+	for _, i := range triggeredRules {
+		log.Message = "match"
+		log.Rule = i.GetName()
+
+		slo.RecordMatch(i.GetName())
+		utils.PrintLog("info", log)
+		metrics.IncreaseCounter(log)
+		printAuditError(audit.Add(audit.Record{
+			Type:    audit.RuleMatched,
+			TraceID: event.TraceID,
+			Rule:    i.GetName(),
+		}), event)
+
+		// the same resource can't be remediated by the same rule concurrently
+		var release func()
+		if key := incidents.GetKey(event); locks.IsEnabled() && key != "" {
+			var err error
+			release, err = locks.Acquire(i.GetName() + "/" + key)
+			if err != nil {
+				log.Message = "lock"
+				log.Error = err.Error()
+				if errors.Is(err, locks.ErrLocked) {
+					utils.PrintLog("warning", log)
+				} else {
+					utils.PrintLog("error", log)
+					succeeded = false
 				}
-				if a.Continue == falseStr || a.Continue != trueStr && !GetDefaultActionners().FindActionner(a.GetActionner()).MustDefaultContinue() {
+				log.Error = ""
+				if i.Continue == falseStr {
 					break
 				}
+				continue
 			}
+		}
+
+		if !runRuleActions(i, event) {
+			succeeded = false
+		}
+		if release != nil {
+			release()
+		}
 
-			if i.Continue == falseStr {
+		if i.Continue == falseStr {
+			break
+		}
+	}
+	return succeeded
+}
+
+// getTriggeredRules returns the enabled rules matching the event
+func getTriggeredRules(event *events.Event) []*rules.Rule {
+	triggeredRules := make([]*rules.Rule, 0)
+	for _, i := range *rules.GetRules() {
+		if i.CompareRule(event) && isRuleAllowed(i, event) {
+			triggeredRules = append(triggeredRules, i)
+		}
+	}
+	return triggeredRules
+}
+
+// runRuleActions runs the actions of the rule for the event, until an action stops the chain, false is returned
+// if an action failed
+func runRuleActions(rule *rules.Rule, event *events.Event) bool {
+	return runActions(rule, rule.GetActions(), event)
+}
+
+// runActions runs the actions, of the rule, for the event, until an action stops the chain, false is returned
+// if an action failed
+func runActions(rule *rules.Rule, actions []*rules.Action, event *events.Event) bool {
+	succeeded := true
+	r := startReport(rule, event)
+	for _, a := range actions {
+		e := prepareEvent(rule, a, event)
+		if r != nil {
+			r.AddContext(e.Context)
+		}
+		if err := runActionOnTargets(rule, a, e); err != nil {
+			succeeded = false
+			if err2 := deadletter.Add(rule.GetName(), a.GetName(), a.GetActionner(), event, err); err2 != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err2.Error(), Message: "deadletter", Rule: rule.GetName(), Action: a.GetName(), TraceID: event.TraceID})
+			}
+			if a.IgnoreErrors == falseStr {
 				break
 			}
 		}
+		if a.Continue == falseStr || a.Continue != trueStr && !GetDefaultActionners().FindActionner(a.GetActionner()).MustDefaultContinue() {
+			break
+		}
+	}
+	if r != nil {
+		storeReport(rule, event, r)
+	}
+	return succeeded
+}
+
+// prepareEvent returns a copy of the event for the action, with the cluster, the impersonation and the context
+func prepareEvent(rule *rules.Rule, a *rules.Action, event *events.Event) *events.Event {
+	e := new(events.Event)
+	*e = *event
+	e.Cluster = getCluster(rule, event)
+	e.ImpersonateUser = rule.GetImpersonatedUser()
+	e.ImpersonateGroups = rule.GetImpersonatedGroups()
+	rule.AddFalcoTalonContext(e, a)
+	if GetDefaultActionners().FindActionner(a.GetActionner()).AllowAdditionalContext() &&
+		len(a.GetAdditionalContexts()) != 0 {
+		for _, i := range a.GetAdditionalContexts() {
+			elements, err := context.GetContext(i, e)
+			if err != nil {
+				log := utils.LogLine{
+					Message:    "context",
+					Context:    i,
+					Rule:       e.Rule,
+					Action:     a.GetName(),
+					Actionner:  a.GetActionner(),
+					TraceID:    e.TraceID,
+					IncidentID: e.IncidentID,
+					Error:      err.Error(),
+				}
+				utils.PrintLog("error", log)
+			} else {
+				e.AddContext(elements)
+			}
+		}
 	}
+	return e
 }
@@ -1,7 +1,6 @@
 package lambda
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 
@@ -17,8 +16,8 @@ import (
 
 type Config struct {
 	AWSLambdaName           string `mapstructure:"aws_lambda_name" validate:"required"`
-	AWSLambdaAliasOrVersion string `mapstructure:"aws_lambda_alias_or_version" validate:"omitempty"`
-	AWSLambdaInvocationType string `mapstructure:"aws_lambda_invocation_type" validate:"omitempty,oneof=RequestResponse Event DryRun"`
+	AWSLambdaAliasOrVersion string `mapstructure:"aws_lambda_alias_or_version" validate:"omitempty" default:"$LATEST"`
+	AWSLambdaInvocationType string `mapstructure:"aws_lambda_invocation_type" validate:"omitempty,oneof=RequestResponse Event DryRun" default:"RequestResponse"`
 }
 
 func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Data, error) {
@@ -57,7 +56,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		Qualifier:      getLambdaVersion(&config.AWSLambdaAliasOrVersion),
 	}
 
-	lambdaOutput, err := lambdaClient.Invoke(context.Background(), input)
+	lambdaOutput, err := lambdaClient.Invoke(event.GetTraceContext(), input)
 	if err != nil {
 		return utils.LogLine{
 			Objects: objects,
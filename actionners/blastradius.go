@@ -0,0 +1,171 @@
+package actionners
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/holds"
+	"github.com/falco-talon/falco-talon/internal/rules"
+)
+
+const (
+	namespaceScope string = "namespace"
+	clusterScope   string = "cluster"
+
+	defaultBlastRadiusWindow = 10 * time.Minute
+)
+
+// blastRadius counts the recent destructive actions, by limit and by event
+type blastRadius struct {
+	actions map[string][]time.Time // key: actionner/scope
+	events  map[string]*eventActions
+	mu      sync.Mutex
+}
+
+type eventActions struct {
+	first time.Time
+	count int
+}
+
+var radius = &blastRadius{
+	actions: make(map[string][]time.Time),
+	events:  make(map[string]*eventActions),
+}
+
+// checkBlastRadius checks the limits of the configuration
+func checkBlastRadius(config configuration.BlastRadiusConfig) error {
+	for _, i := range config.Limits {
+		if i.Actionner == "" {
+			return errors.New("wrong `actionner` setting in the blast-radius limits")
+		}
+		if i.MaxActions <= 0 {
+			return fmt.Errorf("wrong `max_actions` setting in the blast-radius limit of '%v'", i.Actionner)
+		}
+		if i.Scope != "" && i.Scope != namespaceScope && i.Scope != clusterScope {
+			return fmt.Errorf("wrong `scope` setting in the blast-radius limit of '%v', must be '%v' or '%v'", i.Actionner, namespaceScope, clusterScope)
+		}
+	}
+	return nil
+}
+
+// reserveBlastRadius counts the action in the limits, it returns why the action exceeds them, empty if it
+// doesn't, the action isn't counted then. The released actions are counted without check
+func reserveBlastRadius(action *rules.Action, event *events.Event, released bool) string {
+	config := configuration.GetConfiguration()
+	limits := config.BlastRadius.Limits
+	destructive := slices.Contains(config.Guardrails.Actionners, action.GetActionner())
+	perEvent := config.BlastRadius.MaxResourcesPerEvent > 0 && destructive
+
+	radius.mu.Lock()
+	defer radius.mu.Unlock()
+
+	now := time.Now()
+	radius.purge(now)
+
+	type counter struct {
+		key    string
+		window time.Duration
+	}
+	counters := make([]counter, 0)
+	for _, i := range limits {
+		if i.Actionner != action.GetActionner() {
+			continue
+		}
+		window := time.Duration(i.WindowSeconds) * time.Second
+		if window <= 0 {
+			window = defaultBlastRadiusWindow
+		}
+		key := i.Actionner + "/" + clusterScope + "/" + event.Cluster
+		scope := "the cluster"
+		if i.Scope != clusterScope {
+			namespace := event.GetNamespaceName()
+			if namespace == "" {
+				namespace = event.GetTargetNamespace()
+			}
+			key = i.Actionner + "/" + namespaceScope + "/" + event.Cluster + "/" + namespace
+			scope = fmt.Sprintf("the namespace '%v'", namespace)
+		}
+		recent := radius.recent(key, now.Add(-window))
+		if !released && recent >= i.MaxActions {
+			return fmt.Sprintf("the limit of %v action(s) of '%v' in %v for %v is reached", i.MaxActions, i.Actionner, window, scope)
+		}
+		counters = append(counters, counter{key: key, window: window})
+	}
+
+	if perEvent {
+		e := radius.events[event.TraceID]
+		if !released && e != nil && e.count >= config.BlastRadius.MaxResourcesPerEvent {
+			return fmt.Sprintf("the limit of %v resource(s) by event is reached", config.BlastRadius.MaxResourcesPerEvent)
+		}
+		if e == nil {
+			e = &eventActions{first: now}
+			radius.events[event.TraceID] = e
+		}
+		e.count++
+	}
+	for _, i := range counters {
+		radius.actions[i.key] = append(radius.actions[i.key], now)
+	}
+	return ""
+}
+
+// recent returns the number of actions since the time, the lock must be held
+func (b *blastRadius) recent(key string, since time.Time) int {
+	n := 0
+	for _, i := range b.actions[key] {
+		if i.After(since) {
+			n++
+		}
+	}
+	return n
+}
+
+// purge drops the actions older than the longest window, the lock must be held
+func (b *blastRadius) purge(now time.Time) {
+	longest := defaultBlastRadiusWindow
+	for _, i := range configuration.GetConfiguration().BlastRadius.Limits {
+		if w := time.Duration(i.WindowSeconds) * time.Second; w > longest {
+			longest = w
+		}
+	}
+	for i, j := range b.actions {
+		k := 0
+		for k < len(j) && now.Sub(j[k]) > longest {
+			k++
+		}
+		if k == len(j) {
+			delete(b.actions, i)
+			continue
+		}
+		b.actions[i] = j[k:]
+	}
+	// the actions of an event are run within a few minutes
+	for i, j := range b.events {
+		if now.Sub(j.first) > longest {
+			delete(b.events, i)
+		}
+	}
+}
+
+// Release runs a held action, without the blast-radius limits, its rule and its action must still be loaded
+func Release(entry *holds.Entry) error {
+	for _, i := range *rules.GetRules() {
+		if i.GetName() != entry.Rule {
+			continue
+		}
+		for _, j := range i.GetActions() {
+			if j.GetName() != entry.Action || !strings.EqualFold(j.GetActionner(), entry.Actionner) {
+				continue
+			}
+			event := entry.Event
+			return executeAction(i, j, &event, true)
+		}
+	}
+	return fmt.Errorf("the action '%v' of the rule '%v' is no longer loaded", entry.Action, entry.Rule)
+}
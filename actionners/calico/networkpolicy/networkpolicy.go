@@ -1,7 +1,6 @@
 package networkpolicy
 
 import (
-	"context"
 	"fmt"
 	"net"
 	"strings"
@@ -22,6 +21,7 @@ import (
 type Config struct {
 	AllowCIDR       []string `mapstructure:"allow_cidr" validate:"omitempty"`
 	AllowNamespaces []string `mapstructure:"allow_namespaces" validate:"omitempty"`
+	TTL             string   `mapstructure:"ttl" validate:"omitempty"`
 	Order           int      `mapstructure:"order" validate:"omitempty"`
 }
 
@@ -48,6 +48,25 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		"pod":       podName,
 		"namespace": namespace,
 	}
+	// the calico client uses the kubeconfig of the default cluster
+	if !kubernetes.IsDefaultCluster(event.Cluster) {
+		err := fmt.Errorf("the actionner supports the default cluster only, not '%v'", event.Cluster)
+		return utils.LogLine{
+			Objects: objects,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, nil, err
+	}
+	// the calico client can't impersonate, the policy would be created with the identity of Falco Talon
+	if event.ImpersonateUser != "" {
+		err := fmt.Errorf("the actionner doesn't support the impersonation of '%v'", event.ImpersonateUser)
+		return utils.LogLine{
+			Objects: objects,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, nil, err
+	}
+
 	k8sClient := kubernetes.GetClient()
 	calicoClient := calico.GetClient()
 
@@ -120,9 +139,10 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 
 	payload := networkingv3.NetworkPolicy{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      owner,
-			Namespace: namespace,
-			Labels:    labels,
+			Name:        owner,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: kubernetes.GetExpiryAnnotations(config.TTL),
 		},
 		Spec: networkingv3.NetworkPolicySpec{
 			Types: []networkingv3.PolicyType{networkingv3.PolicyTypeEgress},
@@ -168,7 +188,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 
 	var output string
 	var netpol *networkingv3.NetworkPolicy
-	netpol, err = calicoClient.ProjectcalicoV3().NetworkPolicies(namespace).Get(context.Background(), owner, metav1.GetOptions{})
+	netpol, err = calicoClient.ProjectcalicoV3().NetworkPolicies(namespace).Get(event.GetTraceContext(), owner, metav1.GetOptions{})
 	if errorsv1.IsNotFound(err) {
 		payload.Spec.Egress = []networkingv3.Rule{*denyRule}
 		if allowCIDRRule != nil {
@@ -177,7 +197,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		if allowNamespacesRule != nil {
 			payload.Spec.Egress = append(payload.Spec.Egress, *allowNamespacesRule)
 		}
-		_, err2 := calicoClient.ProjectcalicoV3().NetworkPolicies(namespace).Create(context.Background(), &payload, metav1.CreateOptions{})
+		_, err2 := calicoClient.ProjectcalicoV3().NetworkPolicies(namespace).Create(event.GetTraceContext(), &payload, metav1.CreateOptions{})
 		if err2 != nil {
 			if !errorsv1.IsAlreadyExists(err2) {
 				return utils.LogLine{
@@ -186,7 +206,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 					Status:  "failure",
 				}, nil, err2
 			}
-			netpol, err = calicoClient.ProjectcalicoV3().NetworkPolicies(namespace).Get(context.Background(), owner, metav1.GetOptions{})
+			netpol, err = calicoClient.ProjectcalicoV3().NetworkPolicies(namespace).Get(event.GetTraceContext(), owner, metav1.GetOptions{})
 		} else {
 			output = fmt.Sprintf("the caliconetworkpolicy '%v' in the namespace '%v' has been created", owner, namespace)
 			return utils.LogLine{
@@ -220,7 +240,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	if allowNamespacesRule != nil {
 		payload.Spec.Egress = append(payload.Spec.Egress, *allowNamespacesRule)
 	}
-	_, err = calicoClient.ProjectcalicoV3().NetworkPolicies(namespace).Update(context.Background(), &payload, metav1.UpdateOptions{})
+	_, err = calicoClient.ProjectcalicoV3().NetworkPolicies(namespace).Update(event.GetTraceContext(), &payload, metav1.UpdateOptions{})
 	if err != nil {
 		return utils.LogLine{
 			Objects: objects,
@@ -302,6 +322,10 @@ func CheckParameters(action *rules.Action) error {
 		}
 	}
 
+	if _, err2 := kubernetes.GetExpiration(config.TTL); err2 != nil {
+		return err2
+	}
+
 	err = utils.ValidateStruct(config)
 	if err != nil {
 		return err
@@ -1,7 +1,6 @@
 package networkpolicy
 
 import (
-	"context"
 	"fmt"
 	"net"
 
@@ -23,6 +22,7 @@ import (
 type Config struct {
 	AllowCIDR       []string `mapstructure:"allow_cidr" validate:"omitempty"`
 	AllowNamespaces []string `mapstructure:"allow_namespaces" validate:"omitempty"`
+	TTL             string   `mapstructure:"ttl" validate:"omitempty"`
 }
 
 const mask32 string = "/32"
@@ -51,6 +51,25 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		"namespace": namespace,
 	}
 
+	// the cilium client uses the kubeconfig of the default cluster
+	if !kubernetes.IsDefaultCluster(event.Cluster) {
+		err := fmt.Errorf("the actionner supports the default cluster only, not '%v'", event.Cluster)
+		return utils.LogLine{
+			Objects: objects,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, nil, err
+	}
+	// the cilium client can't impersonate, the policy would be created with the identity of Falco Talon
+	if event.ImpersonateUser != "" {
+		err := fmt.Errorf("the actionner doesn't support the impersonation of '%v'", event.ImpersonateUser)
+		return utils.LogLine{
+			Objects: objects,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, nil, err
+	}
+
 	k8sClient := kubernetes.GetClient()
 	ciliumClient := cilium.GetClient()
 
@@ -139,9 +158,10 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	payload := v2.CiliumNetworkPolicy{
 		TypeMeta: metav1.TypeMeta{},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      owner,
-			Namespace: namespace,
-			Labels:    resourceLabels,
+			Name:        owner,
+			Namespace:   namespace,
+			Labels:      resourceLabels,
+			Annotations: kubernetes.GetExpiryAnnotations(actionConfig.TTL),
 		},
 		Spec: &api.Rule{
 			Description: netpolDescription,
@@ -181,7 +201,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	var output string
 	var netpol *v2.CiliumNetworkPolicy
 
-	netpol, err = ciliumClient.CiliumV2().CiliumNetworkPolicies(namespace).Get(context.Background(), owner, metav1.GetOptions{})
+	netpol, err = ciliumClient.CiliumV2().CiliumNetworkPolicies(namespace).Get(event.GetTraceContext(), owner, metav1.GetOptions{})
 	if errorsv1.IsNotFound(err) {
 		payload.Spec.EgressDeny = []api.EgressDenyRule{*denyRule}
 		if allowCIDRRule != nil {
@@ -190,7 +210,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		if allowNamespacesRule != nil {
 			payload.Spec.Egress = append(payload.Spec.Egress, *allowNamespacesRule)
 		}
-		_, err2 := ciliumClient.CiliumV2().CiliumNetworkPolicies(namespace).Create(context.Background(), &payload, metav1.CreateOptions{})
+		_, err2 := ciliumClient.CiliumV2().CiliumNetworkPolicies(namespace).Create(event.GetTraceContext(), &payload, metav1.CreateOptions{})
 		if err2 != nil {
 			return utils.LogLine{
 					Objects: objects,
@@ -240,7 +260,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		}
 	}
 
-	_, err = ciliumClient.CiliumV2().CiliumNetworkPolicies(namespace).Update(context.Background(), &payload, metav1.UpdateOptions{})
+	_, err = ciliumClient.CiliumV2().CiliumNetworkPolicies(namespace).Update(event.GetTraceContext(), &payload, metav1.UpdateOptions{})
 	if err != nil {
 		return utils.LogLine{
 				Objects: objects,
@@ -346,6 +366,10 @@ func CheckParameters(action *rules.Action) error {
 		}
 	}
 
+	if _, err2 := kubernetes.GetExpiration(config.TTL); err2 != nil {
+		return err2
+	}
+
 	err = utils.ValidateStruct(config)
 	if err != nil {
 		return err
@@ -0,0 +1,23 @@
+package actionners
+
+import (
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+)
+
+// getCluster returns the cluster targeted by the actions of the rule, the cluster of the rule has
+// the priority over the one of the event, empty for the default cluster
+func getCluster(rule *rules.Rule, event *events.Event) string {
+	if c := rule.GetCluster(); c != "" {
+		return c
+	}
+	field := configuration.GetConfiguration().MultiCluster.EventField
+	if field == "" {
+		return ""
+	}
+	if c, ok := event.OutputFields[field].(string); ok {
+		return c
+	}
+	return ""
+}
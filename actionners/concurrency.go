@@ -0,0 +1,42 @@
+package actionners
+
+import (
+	"github.com/falco-talon/falco-talon/configuration"
+)
+
+// semaphores limiting the number of actions running at the same time, globally and by actionner
+var (
+	globalSemaphore     chan struct{}
+	actionnerSemaphores map[string]chan struct{}
+)
+
+func initConcurrency(config *configuration.Configuration) {
+	if config.Concurrency.MaxActions > 0 {
+		globalSemaphore = make(chan struct{}, config.Concurrency.MaxActions)
+	}
+	actionnerSemaphores = make(map[string]chan struct{}, len(config.Concurrency.Actionners))
+	for i, j := range config.Concurrency.Actionners {
+		if j > 0 {
+			actionnerSemaphores[i] = make(chan struct{}, j)
+		}
+	}
+}
+
+// acquire waits for a free slot for the actionner, the returned function releases it
+func acquire(actionner string) func() {
+	s := actionnerSemaphores[actionner]
+	if s != nil {
+		s <- struct{}{}
+	}
+	if globalSemaphore != nil {
+		globalSemaphore <- struct{}{}
+	}
+	return func() {
+		if globalSemaphore != nil {
+			<-globalSemaphore
+		}
+		if s != nil {
+			<-s
+		}
+	}
+}
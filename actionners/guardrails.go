@@ -0,0 +1,114 @@
+package actionners
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/internal/policy"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// checkGuardrails checks the label selectors of the guardrails, a wrong selector would protect nothing
+func checkGuardrails(config configuration.GuardrailsConfig) error {
+	for _, i := range config.Namespaces {
+		if _, err := path.Match(i, ""); err != nil {
+			return fmt.Errorf("wrong namespace '%v' in the guardrails: %v", i, err)
+		}
+	}
+	for _, i := range config.LabelSelectors {
+		if _, err := labels.Parse(i); err != nil {
+			return fmt.Errorf("wrong label selector '%v' in the guardrails: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// getGuardrail returns why the target of the action is protected by the guardrails, empty if it's not,
+// the destructive actions of the protected namespaces and pods are downgraded to notifications
+func getGuardrail(action *rules.Action, event *events.Event) string {
+	config := configuration.GetConfiguration().Guardrails
+	if !slices.Contains(config.Actionners, action.GetActionner()) {
+		return ""
+	}
+
+	namespace := event.GetNamespaceName()
+	if namespace == "" {
+		namespace = event.GetTargetNamespace()
+	}
+	for _, i := range config.Namespaces {
+		if ok, _ := path.Match(i, namespace); ok && namespace != "" {
+			return fmt.Sprintf("the namespace '%v' is protected by the guardrails", namespace)
+		}
+	}
+
+	pod := event.GetPodName()
+	if len(config.LabelSelectors) == 0 || pod == "" || namespace == "" {
+		return ""
+	}
+	client := k8s.GetClientForEvent(event)
+	if client == nil {
+		return "the labels of the pod can't be checked against the guardrails, wrong k8s client"
+	}
+	p, err := client.GetPod(pod, namespace)
+	if err != nil {
+		// a protected pod mustn't be hit because of an error of the API, the action is downgraded too
+		return fmt.Sprintf("the labels of the pod can't be checked against the guardrails: %v", err)
+	}
+	for _, i := range config.LabelSelectors {
+		selector, err := labels.Parse(i)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(p.Labels)) {
+			return fmt.Sprintf("the pod '%v/%v' is protected by the guardrails (%v)", namespace, pod, i)
+		}
+	}
+	return ""
+}
+
+// getPolicyVeto returns why the guard policy vetoes the action, empty if it allows it, the pod of the event
+// is sent as target of the action
+func getPolicyVeto(rule *rules.Rule, action *rules.Action, event *events.Event) string {
+	if !policy.IsEnabled(action.GetActionner()) {
+		return ""
+	}
+
+	input := &policy.Input{
+		Rule:       rule.GetName(),
+		Action:     action.GetName(),
+		Actionner:  action.GetActionner(),
+		Parameters: action.GetParameters(),
+		Cluster:    event.Cluster,
+		Event:      event,
+	}
+	if pod, namespace := event.GetPodName(), event.GetNamespaceName(); pod != "" && namespace != "" {
+		if client := k8s.GetClientForEvent(event); client != nil {
+			if p, err := client.GetPod(pod, namespace); err == nil {
+				input.Target = p
+			}
+		}
+	}
+
+	d, err := policy.Evaluate(input)
+	if err != nil {
+		if policy.IsFailOpen() {
+			utils.PrintLog("warning", utils.LogLine{Message: "action", Rule: rule.GetName(), Action: action.GetName(), Actionner: action.GetActionner(), TraceID: event.TraceID, Error: err.Error(), Result: "the guard policy can't be evaluated, the action is allowed"})
+			return ""
+		}
+		return fmt.Sprintf("the guard policy can't be evaluated: %v", err)
+	}
+	if d.Allow {
+		return ""
+	}
+	if d.Reason != "" {
+		return "vetoed by the guard policy: " + d.Reason
+	}
+	return "vetoed by the guard policy"
+}
@@ -0,0 +1,71 @@
+package actionners
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	eventReason     string = "FalcoTalonAction"
+	eventMaxMessage int    = 1024
+)
+
+// getEventTargets returns the objects to create the kubernetes events for, the pod and its workload,
+// or the node for the events without pod
+func getEventTargets(event *events.Event) []corev1.ObjectReference {
+	if !configuration.GetConfiguration().KubernetesEvents.Enabled {
+		return nil
+	}
+	client := k8s.GetClientFor(event.Cluster)
+	if client == nil {
+		return nil
+	}
+	if pod, namespace := event.GetPodName(), event.GetNamespaceName(); pod != "" && namespace != "" {
+		return client.GetObjectReferences(pod, namespace)
+	}
+	if hostname := event.GetHostname(); hostname != "" {
+		if node, err := client.GetNode(hostname); err == nil {
+			return []corev1.ObjectReference{{Kind: "Node", APIVersion: "v1", Name: node.Name, UID: node.UID}}
+		}
+	}
+	return nil
+}
+
+// createKubernetesEvents creates an event with the result of the action for each target,
+// responders see them with `kubectl describe`
+func createKubernetesEvents(cluster string, targets []corev1.ObjectReference, action *rules.Action, log utils.LogLine) {
+	if len(targets) == 0 {
+		return
+	}
+
+	eventType := corev1.EventTypeNormal
+	message := fmt.Sprintf("rule '%v', action '%v' (%v): %v", log.Rule, action.GetName(), action.GetActionner(), log.Status)
+	if log.Output != "" {
+		message += ", " + log.Output
+	}
+	if log.Error != "" {
+		eventType = corev1.EventTypeWarning
+		message += ", " + log.Error
+	}
+	message += fmt.Sprintf(" (trace id: %v)", log.TraceID)
+	if len(message) > eventMaxMessage {
+		message = message[:eventMaxMessage]
+	}
+
+	client := k8s.GetClientFor(cluster)
+	if client == nil {
+		return
+	}
+	for _, i := range targets {
+		if err := client.CreateEvent(i, eventType, eventReason, action.GetActionner(), message); err != nil {
+			utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "kubernetes-event", Rule: log.Rule, Action: action.GetName(), TraceID: log.TraceID})
+		}
+	}
+}
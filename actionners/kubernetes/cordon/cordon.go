@@ -3,6 +3,8 @@ package cordon
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -15,7 +17,8 @@ import (
 )
 
 const (
-	jsonPatch = `[{"op": "replace", "path": "/spec/unschedulable", "value": true}]`
+	jsonPatch       = `[{"op": "replace", "path": "/spec/unschedulable", "value": true}]`
+	revertJSONPatch = `[{"op": "replace", "path": "/spec/unschedulable", "value": %v}]`
 )
 
 func Action(_ *rules.Action, event *events.Event) (utils.LogLine, *model.Data, error) {
@@ -24,7 +27,7 @@ func Action(_ *rules.Action, event *events.Event) (utils.LogLine, *model.Data, e
 
 	objects := map[string]string{}
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	pod, err := client.GetPod(podName, namespace)
 	if err != nil {
@@ -48,7 +51,7 @@ func Action(_ *rules.Action, event *events.Event) (utils.LogLine, *model.Data, e
 
 	objects["node"] = node.Name
 
-	_, err = client.Clientset.CoreV1().Nodes().Patch(context.Background(), node.Name, types.JSONPatchType, []byte(jsonPatch), metav1.PatchOptions{})
+	_, err = client.Clientset.CoreV1().Nodes().Patch(event.GetTraceContext(), node.Name, types.JSONPatchType, []byte(jsonPatch), metav1.PatchOptions{})
 	if err != nil {
 		return utils.LogLine{
 			Objects: objects,
@@ -63,3 +66,47 @@ func Action(_ *rules.Action, event *events.Event) (utils.LogLine, *model.Data, e
 		Status:  "success",
 	}, nil, nil
 }
+
+// Snapshot returns the schedulability of the node before the action, to revert it
+func Snapshot(_ *rules.Action, event *events.Event) (map[string]string, error) {
+	client := kubernetes.GetClientForEvent(event)
+	pod, err := client.GetPod(event.GetPodName(), event.GetNamespaceName())
+	if err != nil {
+		return nil, err
+	}
+	node, err := client.GetNodeFromPod(pod)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"node":          node.Name,
+		"unschedulable": strconv.FormatBool(node.Spec.Unschedulable),
+	}, nil
+}
+
+// Revert restores the schedulability of the node
+func Revert(state map[string]string) error {
+	unschedulable, err := strconv.ParseBool(state["unschedulable"])
+	if err != nil {
+		return err
+	}
+	client := kubernetes.GetClientForState(state)
+	if client == nil {
+		return fmt.Errorf("wrong k8s client for the cluster '%v'", state["cluster"])
+	}
+	_, err = client.Clientset.CoreV1().Nodes().Patch(context.Background(), state["node"], types.JSONPatchType, []byte(fmt.Sprintf(revertJSONPatch, unschedulable)), metav1.PatchOptions{})
+	return err
+}
+
+// Verify checks that the node is unschedulable
+func Verify(_ *rules.Action, event *events.Event, result utils.LogLine, _ time.Time) error {
+	client := kubernetes.GetClientForEvent(event)
+	node, err := client.Clientset.CoreV1().Nodes().Get(event.GetTraceContext(), result.Objects["node"], metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if !node.Spec.Unschedulable {
+		return fmt.Errorf("the node '%v' is still schedulable", node.Name)
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package networkpolicy
 
 import (
-	"context"
 	"fmt"
 	"strings"
 
@@ -27,33 +26,35 @@ func Action(_ *rules.Action, event *events.Event) (utils.LogLine, *model.Data, e
 		"namespace": namespace,
 	}
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	var err error
 
 	switch resource {
 	case namespaces:
-		err = client.Clientset.CoreV1().Namespaces().Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.CoreV1().Namespaces().Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "configmaps":
-		err = client.Clientset.CoreV1().ConfigMaps(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.CoreV1().ConfigMaps(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "secrets":
-		err = client.Clientset.CoreV1().Secrets(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.CoreV1().Secrets(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "deployments":
-		err = client.Clientset.AppsV1().Deployments(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.AppsV1().Deployments(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "daemonsets":
-		err = client.Clientset.AppsV1().DaemonSets(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.AppsV1().DaemonSets(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "statefulsets":
-		err = client.Clientset.AppsV1().StatefulSets(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.AppsV1().StatefulSets(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "replicasets":
-		err = client.Clientset.AppsV1().ReplicaSets(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.AppsV1().ReplicaSets(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "services":
-		err = client.Clientset.CoreV1().Services(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.CoreV1().Services(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "serviceaccounts":
-		err = client.Clientset.CoreV1().ServiceAccounts(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.CoreV1().ServiceAccounts(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "roles":
-		err = client.Clientset.RbacV1().Roles(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.RbacV1().Roles(namespace).Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
 	case "clusterroles":
-		err = client.Clientset.RbacV1().ClusterRoles().Delete(context.Background(), name, metav1.DeleteOptions{})
+		err = client.Clientset.RbacV1().ClusterRoles().Delete(event.GetTraceContext(), name, metav1.DeleteOptions{})
+	default:
+		err = client.DeleteUnstructured(event.GetTraceContext(), resource, name, namespace)
 	}
 
 	if err != nil {
@@ -44,7 +44,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 
 	objects["file"] = *file
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	p, _ := client.GetPod(pod, namespace)
 	containers := kubernetes.GetContainers(p)
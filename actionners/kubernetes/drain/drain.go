@@ -1,14 +1,15 @@
 package drain
 
 import (
-	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	helpers "github.com/falco-talon/falco-talon/actionners/kubernetes/helpers"
+	"github.com/falco-talon/falco-talon/internal/async"
 	"github.com/falco-talon/falco-talon/internal/events"
 	kubernetes "github.com/falco-talon/falco-talon/internal/kubernetes/client"
 	"github.com/falco-talon/falco-talon/internal/rules"
@@ -50,7 +51,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	gracePeriodSeconds := new(int64)
 	*gracePeriodSeconds = int64(config.GracePeriodSeconds)
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 	pod, err := client.GetPod(podName, namespace)
 	if err != nil {
 		objects["pod"] = podName
@@ -75,9 +76,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	nodeName := node.GetName()
 	objects["node"] = nodeName
 
-	pods, err := client.Clientset.CoreV1().Pods("").List(context.Background(), metav1.ListOptions{
-		FieldSelector: fmt.Sprintf("spec.nodeName=%s", nodeName),
-	})
+	pods, err := client.ListPodsOnNode(nodeName)
 	if err != nil {
 		return utils.LogLine{
 			Objects: objects,
@@ -89,11 +88,16 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	var ignoredPodsCount, evictionErrorsCount, otherErrorsCount int
 
 	var wg sync.WaitGroup
+	var processed atomic.Int32
 
-	for _, p := range pods.Items {
+	for _, p := range pods {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			// the progress is reported if the drain runs in the background
+			defer func() {
+				async.Progress(event.GetTraceContext(), fmt.Sprintf("%v/%v pods processed", processed.Add(1), len(pods)))
+			}()
 
 			ownerKind, err := kubernetes.GetOwnerKind(p)
 			if err != nil {
@@ -166,7 +170,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 					GracePeriodSeconds: gracePeriodSeconds,
 				},
 			}
-			if err := client.PolicyV1().Evictions(pod.GetNamespace()).Evict(context.Background(), eviction); err != nil {
+			if err := client.PolicyV1().Evictions(pod.GetNamespace()).Evict(event.GetTraceContext(), eviction); err != nil {
 				utils.PrintLog("warning", utils.LogLine{Message: fmt.Sprintf("error evicting pod '%v': %v", p.Name, err)})
 				evictionErrorsCount++
 			}
@@ -14,7 +14,7 @@ import (
 
 type Config struct {
 	Commannd string `mapstructure:"command" validate:"required"`
-	Shell    string `mapstructure:"shell" validate:"omitempty"`
+	Shell    string `mapstructure:"shell" validate:"omitempty" default:"/bin/sh"`
 }
 
 func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Data, error) {
@@ -50,7 +50,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	event.ExportEnvVars()
 	*command = os.ExpandEnv(*command)
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	p, _ := client.GetPod(pod, namespace)
 	containers := kubernetes.GetContainers(p)
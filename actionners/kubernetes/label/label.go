@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
+	"github.com/falco-talon/falco-talon/configuration"
 	"github.com/falco-talon/falco-talon/internal/events"
 	kubernetes "github.com/falco-talon/falco-talon/internal/kubernetes/client"
 	"github.com/falco-talon/falco-talon/internal/rules"
@@ -25,7 +27,8 @@ type patch struct {
 
 type Config struct {
 	Labels map[string]string `mapstructure:"labels" validate:"required"`
-	Level  string            `mapstructure:"level" validate:"omitempty"`
+	Level  string            `mapstructure:"level" validate:"omitempty" default:"pod"`
+	TTL    string            `mapstructure:"ttl" validate:"omitempty"`
 }
 
 const (
@@ -40,7 +43,6 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 
 	objects := map[string]string{}
 
-	payload := make([]patch, 0)
 	parameters := action.GetParameters()
 	var config Config
 	err := utils.DecodeParams(parameters, &config)
@@ -52,7 +54,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		}, nil, err
 	}
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	var kind string
 	var node *corev1.Node
@@ -82,23 +84,23 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		objects["namespace"] = namespace
 	}
 
+	// the labels are applied (server-side apply), the action is idempotent and the conflicts
+	// with the labels managed by the other controllers are returned
+	labels := make(map[string]string)
+	removed := make([]string, 0)
 	for i, j := range config.Labels {
 		if fmt.Sprintf("%v", j) == "" {
+			removed = append(removed, i)
 			continue
 		}
-		payload = append(payload, patch{
-			Op:    "replace",
-			Path:  metadataLabels + i,
-			Value: fmt.Sprintf("%v", j),
-		})
+		labels[i] = fmt.Sprintf("%v", j)
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
 	if kind == podStr {
-		_, err = client.Clientset.CoreV1().Pods(namespace).Patch(context.Background(), podName, types.JSONPatchType, payloadBytes, metav1.PatchOptions{})
+		err = client.ApplyLabels(event.GetTraceContext(), podName, namespace, labels, removed)
 	}
 	if kind == nodeStr {
-		_, err = client.Clientset.CoreV1().Nodes().Patch(context.Background(), node.Name, types.JSONPatchType, payloadBytes, metav1.PatchOptions{})
+		err = client.ApplyLabels(event.GetTraceContext(), node.Name, "", labels, removed)
 	}
 	if err != nil {
 		return utils.LogLine{
@@ -108,7 +110,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		}, nil, err
 	}
 
-	payload = make([]patch, 0)
+	payload := make([]patch, 0)
 	action.GetParameters()
 	for i, j := range config.Labels {
 		if fmt.Sprintf("%v", j) != "" {
@@ -120,11 +122,12 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		})
 	}
 
-	payloadBytes, _ = json.Marshal(payload)
+	// the removed labels not applied by Falco Talon
+	payloadBytes, _ := json.Marshal(payload)
 	if kind == nodeStr {
-		_, err = client.Clientset.CoreV1().Nodes().Patch(context.Background(), node.Name, types.JSONPatchType, payloadBytes, metav1.PatchOptions{})
+		_, err = client.Clientset.CoreV1().Nodes().Patch(event.GetTraceContext(), node.Name, types.JSONPatchType, payloadBytes, metav1.PatchOptions{})
 	} else {
-		_, err = client.Clientset.CoreV1().Pods(namespace).Patch(context.Background(), podName, types.JSONPatchType, payloadBytes, metav1.PatchOptions{})
+		_, err = client.Clientset.CoreV1().Pods(namespace).Patch(event.GetTraceContext(), podName, types.JSONPatchType, payloadBytes, metav1.PatchOptions{})
 	}
 	if err != nil {
 		if err.Error() != "the server rejected our request due to an error in our request" {
@@ -135,6 +138,25 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 			}, nil, err
 		}
 	}
+	if config.TTL != "" {
+		if kind == nodeStr {
+			err = setExpiration(client, kind, node.Name, "", node.Annotations, &config)
+		} else {
+			var pod *corev1.Pod
+			pod, err = client.GetPod(podName, namespace)
+			if err == nil {
+				err = setExpiration(client, kind, podName, namespace, pod.Annotations, &config)
+			}
+		}
+		if err != nil {
+			return utils.LogLine{
+				Objects: objects,
+				Error:   err.Error(),
+				Status:  "failure",
+			}, nil, err
+		}
+	}
+
 	var output string
 	if kind == nodeStr {
 		output = fmt.Sprintf("the node '%v' has been labeled", node.Name)
@@ -148,6 +170,118 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	}, nil, nil
 }
 
+// setExpiration adds the expiration dates of the labels to the annotations of the pod or the node,
+// the expired labels are removed by the reconciler
+func setExpiration(client *kubernetes.Client, kind, name, namespace string, annotations map[string]string, config *Config) error {
+	expiration, err := kubernetes.GetExpiration(config.TTL)
+	if err != nil {
+		return err
+	}
+
+	expirations := map[string]string{}
+	_ = json.Unmarshal([]byte(annotations[kubernetes.ExpiringLabelsAnnotation]), &expirations)
+	for i, j := range config.Labels {
+		if j != "" {
+			expirations[i] = expiration
+		}
+	}
+	b, err := json.Marshal(expirations)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      map[string]string{kubernetes.ExpiringLabel: "true"},
+			"annotations": map[string]string{kubernetes.ExpiringLabelsAnnotation: string(b)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if kind == nodeStr {
+		_, err = client.Clientset.CoreV1().Nodes().Patch(context.Background(), name, types.MergePatchType, payload, metav1.PatchOptions{})
+	} else {
+		_, err = client.Clientset.CoreV1().Pods(namespace).Patch(context.Background(), name, types.MergePatchType, payload, metav1.PatchOptions{})
+	}
+	return err
+}
+
+// Snapshot returns the state of the labels before the action, to revert it
+func Snapshot(action *rules.Action, event *events.Event) (map[string]string, error) {
+	var config Config
+	if err := utils.DecodeParams(action.GetParameters(), &config); err != nil {
+		return nil, err
+	}
+
+	client := kubernetes.GetClientForEvent(event)
+	pod, err := client.GetPod(event.GetPodName(), event.GetNamespaceName())
+	if err != nil {
+		return nil, err
+	}
+
+	state := map[string]string{
+		"kind":      podStr,
+		"name":      pod.Name,
+		"namespace": pod.Namespace,
+	}
+	current := pod.Labels
+	if config.Level == nodeStr {
+		node, err := client.GetNodeFromPod(pod)
+		if err != nil {
+			return nil, err
+		}
+		state["kind"] = nodeStr
+		state["name"] = node.Name
+		state["namespace"] = ""
+		current = node.Labels
+	}
+
+	// a nil value is a label to remove for the revert
+	labels := make(map[string]*string, len(config.Labels))
+	for i := range config.Labels {
+		if v, ok := current[i]; ok {
+			labels[i] = &v
+		} else {
+			labels[i] = nil
+		}
+	}
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return nil, err
+	}
+	state["labels"] = string(b)
+
+	return state, nil
+}
+
+// Revert restores the labels of the snapshot
+func Revert(state map[string]string) error {
+	var labels map[string]*string
+	if err := json.Unmarshal([]byte(state["labels"]), &labels); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": labels,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	client := kubernetes.GetClientForState(state)
+	if client == nil {
+		return fmt.Errorf("wrong k8s client for the cluster '%v'", state["cluster"])
+	}
+	if state["kind"] == nodeStr {
+		_, err = client.Clientset.CoreV1().Nodes().Patch(context.Background(), state["name"], types.MergePatchType, payload, metav1.PatchOptions{})
+	} else {
+		_, err = client.Clientset.CoreV1().Pods(state["namespace"]).Patch(context.Background(), state["name"], types.MergePatchType, payload, metav1.PatchOptions{})
+	}
+	return err
+}
+
 func CheckParameters(action *rules.Action) error {
 	parameters := action.GetParameters()
 
@@ -166,5 +300,46 @@ func CheckParameters(action *rules.Action) error {
 	if len(config.Labels) == 0 {
 		return errors.New("parameter 'labels' should have at least one label")
 	}
+	if _, err := kubernetes.GetExpiration(config.TTL); err != nil {
+		return err
+	}
+	if config.Level == nodeStr && configuration.GetConfiguration().IsNamespaced() {
+		return errors.New("the nodes can't be labeled in the namespace-scoped mode")
+	}
+	return nil
+}
+
+// Verify checks that the labels are set, and the ones with an empty value removed
+func Verify(action *rules.Action, event *events.Event, result utils.LogLine, _ time.Time) error {
+	var config Config
+	if err := utils.DecodeParams(action.GetParameters(), &config); err != nil {
+		return err
+	}
+
+	client := kubernetes.GetClientForEvent(event)
+	var current map[string]string
+	if name, ok := result.Objects[nodeStr]; ok {
+		node, err := client.Clientset.CoreV1().Nodes().Get(event.GetTraceContext(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current = node.Labels
+	} else {
+		pod, err := client.Clientset.CoreV1().Pods(result.Objects["namespace"]).Get(event.GetTraceContext(), result.Objects[podStr], metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		current = pod.Labels
+	}
+
+	for i, j := range config.Labels {
+		v, ok := current[i]
+		if j == "" && ok {
+			return fmt.Errorf("the label '%v' is still present", i)
+		}
+		if j != "" && v != j {
+			return fmt.Errorf("the label '%v' isn't set to '%v'", i, j)
+		}
+	}
 	return nil
 }
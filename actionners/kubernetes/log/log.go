@@ -2,7 +2,6 @@ package log
 
 import (
 	"bytes"
-	"context"
 	"fmt"
 	"io"
 
@@ -16,7 +15,7 @@ import (
 )
 
 type Config struct {
-	TailLines int `mapstructure:"tail_lines" validate:"gte=0,omitempty"`
+	TailLines int `mapstructure:"tail_lines" validate:"gte=0,omitempty" default:"20"`
 }
 
 const (
@@ -49,7 +48,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		*tailLines = int64(config.TailLines)
 	}
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	p, _ := client.GetPod(pod, namespace)
 	containers := kubernetes.GetContainers(p)
@@ -62,7 +61,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		}, nil, err
 	}
 
-	ctx := context.Background()
+	ctx := event.GetTraceContext()
 	var output []byte
 
 	for i, container := range containers {
@@ -2,9 +2,12 @@ package networkpolicy
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	errorsv1 "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,6 +22,7 @@ import (
 type Config struct {
 	AllowCIDR       []string `mapstructure:"allow_cidr" validate:"omitempty"`
 	AllowNamespaces []string `mapstructure:"allow_namespaces" validate:"omitempty"`
+	TTL             string   `mapstructure:"ttl" validate:"omitempty"`
 }
 
 const managedByStr string = "app.kubernetes.io/managed-by"
@@ -31,7 +35,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		"pod":       podName,
 		"namespace": namespace,
 	}
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	parameters := action.GetParameters()
 
@@ -56,54 +60,15 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 			err
 	}
 
-	var owner string
-	labels := make(map[string]string)
-
-	if len(pod.OwnerReferences) != 0 {
-		switch pod.OwnerReferences[0].Kind {
-		case "DaemonSet":
-			u, err2 := client.GetDaemonsetFromPod(pod)
-			if err2 != nil {
-				return utils.LogLine{
-						Objects: objects,
-						Error:   err2.Error(),
-						Status:  "failure",
-					},
-					nil,
-					err2
-			}
-			owner = u.ObjectMeta.Name
-			labels = u.Spec.Selector.MatchLabels
-		case "StatefulSet":
-			u, err2 := client.GetStatefulsetFromPod(pod)
-			if err2 != nil {
-				return utils.LogLine{
-						Objects: objects,
-						Error:   err2.Error(),
-						Status:  "failure",
-					},
-					nil,
-					err2
-			}
-			owner = u.ObjectMeta.Name
-			labels = u.Spec.Selector.MatchLabels
-		case "ReplicaSet":
-			u, err2 := client.GetReplicasetFromPod(pod)
-			if err2 != nil {
-				return utils.LogLine{
-						Objects: objects,
-						Error:   err2.Error(),
-						Status:  "failure",
-					},
-					nil,
-					err2
-			}
-			owner = u.ObjectMeta.Name
-			labels = u.Spec.Selector.MatchLabels
-		}
-	} else {
-		owner = pod.ObjectMeta.Name
-		labels = pod.ObjectMeta.Labels
+	owner, labels, err := getOwner(client, pod)
+	if err != nil {
+		return utils.LogLine{
+				Objects: objects,
+				Error:   err.Error(),
+				Status:  "failure",
+			},
+			nil,
+			err
 	}
 
 	if owner == "" || len(labels) == 0 {
@@ -131,9 +96,10 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 
 	payload := networkingv1.NetworkPolicy{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      owner,
-			Namespace: namespace,
-			Labels:    labels,
+			Name:        owner,
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: kubernetes.GetExpiryAnnotations(config.TTL),
 		},
 		Spec: networkingv1.NetworkPolicySpec{
 			PolicyTypes: []networkingv1.PolicyType{"Egress"},
@@ -160,15 +126,13 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 
 	objects["networkpolicy"] = owner
 
-	var output string
-	_, err = client.Clientset.NetworkingV1().NetworkPolicies(namespace).Get(context.Background(), owner, metav1.GetOptions{})
+	output := fmt.Sprintf("the networkpolicy '%v' in the namespace '%v' has been updated", owner, namespace)
+	_, err = client.Clientset.NetworkingV1().NetworkPolicies(namespace).Get(event.GetTraceContext(), owner, metav1.GetOptions{})
 	if errorsv1.IsNotFound(err) {
-		_, err = client.Clientset.NetworkingV1().NetworkPolicies(namespace).Create(context.Background(), &payload, metav1.CreateOptions{})
 		output = fmt.Sprintf("the networkpolicy '%v' in the namespace '%v' has been created", owner, namespace)
-	} else {
-		_, err = client.Clientset.NetworkingV1().NetworkPolicies(namespace).Update(context.Background(), &payload, metav1.UpdateOptions{})
-		output = fmt.Sprintf("the networkpolicy '%v' in the namespace '%v' has been updated", owner, namespace)
 	}
+	// the policy is applied (server-side apply), re-running the action is idempotent
+	_, err = client.Apply(event.GetTraceContext(), &payload)
 	if err != nil {
 		return utils.LogLine{
 			Objects: objects,
@@ -184,6 +148,102 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	}, nil, nil
 }
 
+// getOwner returns the name and the selector of the owner of the pod, the pod itself if it has no owner
+func getOwner(client *kubernetes.Client, pod *corev1.Pod) (string, map[string]string, error) {
+	if len(pod.OwnerReferences) == 0 {
+		return pod.ObjectMeta.Name, pod.ObjectMeta.Labels, nil
+	}
+	switch pod.OwnerReferences[0].Kind {
+	case "DaemonSet":
+		u, err := client.GetDaemonsetFromPod(pod)
+		if err != nil {
+			return "", nil, err
+		}
+		return u.ObjectMeta.Name, u.Spec.Selector.MatchLabels, nil
+	case "StatefulSet":
+		u, err := client.GetStatefulsetFromPod(pod)
+		if err != nil {
+			return "", nil, err
+		}
+		return u.ObjectMeta.Name, u.Spec.Selector.MatchLabels, nil
+	case "ReplicaSet":
+		u, err := client.GetReplicasetFromPod(pod)
+		if err != nil {
+			return "", nil, err
+		}
+		return u.ObjectMeta.Name, u.Spec.Selector.MatchLabels, nil
+	}
+	return "", map[string]string{}, nil
+}
+
+// Snapshot returns the networkpolicy before the action, to revert it
+func Snapshot(_ *rules.Action, event *events.Event) (map[string]string, error) {
+	client := kubernetes.GetClientForEvent(event)
+	pod, err := client.GetPod(event.GetPodName(), event.GetNamespaceName())
+	if err != nil {
+		return nil, err
+	}
+	owner, _, err := getOwner(client, pod)
+	if err != nil {
+		return nil, err
+	}
+
+	state := map[string]string{
+		"name":      owner,
+		"namespace": pod.Namespace,
+	}
+	np, err := client.Clientset.NetworkingV1().NetworkPolicies(pod.Namespace).Get(event.GetTraceContext(), owner, metav1.GetOptions{})
+	if errorsv1.IsNotFound(err) {
+		state["existed"] = "false"
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	spec, err := json.Marshal(np.Spec)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := json.Marshal(np.Labels)
+	if err != nil {
+		return nil, err
+	}
+	state["existed"] = "true"
+	state["spec"] = string(spec)
+	state["labels"] = string(labels)
+	return state, nil
+}
+
+// Revert deletes the networkpolicy created by the action or restores the previous one
+func Revert(state map[string]string) error {
+	client := kubernetes.GetClientForState(state)
+	if client == nil {
+		return fmt.Errorf("wrong k8s client for the cluster '%v'", state["cluster"])
+	}
+	policies := client.Clientset.NetworkingV1().NetworkPolicies(state["namespace"])
+	if state["existed"] != "true" {
+		err := policies.Delete(context.Background(), state["name"], metav1.DeleteOptions{})
+		if errorsv1.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	np, err := policies.Get(context.Background(), state["name"], metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(state["spec"]), &np.Spec); err != nil {
+		return err
+	}
+	np.Labels = nil
+	if err := json.Unmarshal([]byte(state["labels"]), &np.Labels); err != nil {
+		return err
+	}
+	_, err = policies.Update(context.Background(), np, metav1.UpdateOptions{})
+	return err
+}
+
 func createEgressRule(config *Config) (*networkingv1.NetworkPolicyEgressRule, error) {
 	if len(config.AllowCIDR) == 0 && len(config.AllowNamespaces) == 0 {
 		return nil, nil
@@ -233,6 +293,10 @@ func CheckParameters(action *rules.Action) error {
 		}
 	}
 
+	if _, err2 := kubernetes.GetExpiration(config.TTL); err2 != nil {
+		return err2
+	}
+
 	err = utils.ValidateStruct(config)
 	if err != nil {
 		return err
@@ -240,3 +304,19 @@ func CheckParameters(action *rules.Action) error {
 
 	return nil
 }
+
+// Verify checks that the networkpolicy is present
+func Verify(_ *rules.Action, event *events.Event, result utils.LogLine, _ time.Time) error {
+	client := kubernetes.GetClientForEvent(event)
+	np, err := client.Clientset.NetworkingV1().NetworkPolicies(result.Objects["namespace"]).Get(event.GetTraceContext(), result.Objects["networkpolicy"], metav1.GetOptions{})
+	if errorsv1.IsNotFound(err) {
+		return fmt.Errorf("the networkpolicy '%v' in the namespace '%v' doesn't exist", result.Objects["networkpolicy"], result.Objects["namespace"])
+	}
+	if err != nil {
+		return err
+	}
+	if np.DeletionTimestamp != nil {
+		return fmt.Errorf("the networkpolicy '%v' in the namespace '%v' is being deleted", np.Name, np.Namespace)
+	}
+	return nil
+}
@@ -16,7 +16,7 @@ import (
 type Config struct {
 	Script string `mapstructure:"script" validate:"omitempty"`
 	File   string `mapstructure:"file" validate:"omitempty"`
-	Shell  string `mapstructure:"shell" validate:"omitempty"`
+	Shell  string `mapstructure:"shell" validate:"omitempty" default:"/bin/sh"`
 }
 
 func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Data, error) {
@@ -68,7 +68,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	event.ExportEnvVars()
 	*script = os.ExpandEnv(*script)
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	p, _ := client.GetPod(pod, namespace)
 	containers := kubernetes.GetContainers(p)
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/falco-talon/falco-talon/internal/async"
 	"github.com/falco-talon/falco-talon/internal/events"
 	kubernetes "github.com/falco-talon/falco-talon/internal/kubernetes/client"
 	"github.com/falco-talon/falco-talon/internal/rules"
@@ -13,7 +14,7 @@ import (
 )
 
 type Config struct {
-	Duration int `mapstructure:"duration" validate:"gte=0"`
+	Duration int `mapstructure:"duration" validate:"gte=0" default:"5"`
 	Snaplen  int `mapstructure:"snaplen" validate:"gte=0"`
 }
 
@@ -46,7 +47,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		config.Duration = 5
 	}
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 
 	pod, _ := client.GetPod(podName, namespace)
 	containers := kubernetes.GetContainers(pod)
@@ -81,6 +82,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		}, nil, err
 	}
 
+	async.Progress(event.GetTraceContext(), fmt.Sprintf("capture running for %vs", config.Duration))
 	command = []string{"sh", "/tmp/talon-script.sh"}
 	_, err = client.Exec(namespace, podName, ephemeralContainerName, command, "")
 	if err != nil {
@@ -91,6 +93,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		}, nil, err
 	}
 
+	async.Progress(event.GetTraceContext(), "capture done, downloading the pcap")
 	command = []string{"cat", "/tmp/tcpdump.pcap"}
 	output, err := client.Exec(namespace, podName, ephemeralContainerName, command, "")
 	if err != nil {
@@ -1,9 +1,10 @@
 package terminate
 
 import (
-	"context"
 	"fmt"
+	"time"
 
+	errorsv1 "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	helpers "github.com/falco-talon/falco-talon/actionners/kubernetes/helpers"
@@ -44,7 +45,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 	gracePeriodSeconds := new(int64)
 	*gracePeriodSeconds = int64(config.GracePeriodSeconds)
 
-	client := kubernetes.GetClient()
+	client := kubernetes.GetClientForEvent(event)
 	pod, err := client.GetPod(podName, namespace)
 	if err != nil {
 		return utils.LogLine{
@@ -147,7 +148,7 @@ func Action(action *rules.Action, event *events.Event) (utils.LogLine, *model.Da
 		}
 	}
 
-	err = client.Clientset.CoreV1().Pods(namespace).Delete(context.Background(), podName, metav1.DeleteOptions{GracePeriodSeconds: gracePeriodSeconds})
+	err = client.Clientset.CoreV1().Pods(namespace).Delete(event.GetTraceContext(), podName, metav1.DeleteOptions{GracePeriodSeconds: gracePeriodSeconds})
 	if err != nil {
 		return utils.LogLine{
 				Objects: objects,
@@ -186,3 +187,19 @@ func CheckParameters(action *rules.Action) error {
 
 	return nil
 }
+
+// Verify checks that the pod is gone, a pod with the same name created after the action (ex: by a StatefulSet) is a new one
+func Verify(_ *rules.Action, event *events.Event, result utils.LogLine, since time.Time) error {
+	client := kubernetes.GetClientForEvent(event)
+	pod, err := client.Clientset.CoreV1().Pods(result.Objects["namespace"]).Get(event.GetTraceContext(), result.Objects["pod"], metav1.GetOptions{})
+	if errorsv1.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if pod.DeletionTimestamp == nil && !pod.CreationTimestamp.Time.Before(since.Truncate(time.Second)) {
+		return nil
+	}
+	return fmt.Errorf("the pod '%v' in the namespace '%v' still exists", pod.Name, pod.Namespace)
+}
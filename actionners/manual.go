@@ -0,0 +1,150 @@
+package actionners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/outputs"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// the name of the rule of the manual actions, in the logs, the history and the audit log
+const manualRule string = "manual"
+
+// ManualAction is an action run by a responder through the API, outside of the rules
+type ManualAction struct {
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	OutputFields map[string]interface{} `json:"output_fields,omitempty"` // other fields of the target, eg: fd.sip
+	Output       *rules.Output          `json:"output,omitempty"`
+	Actionner    string                 `json:"actionner"`
+	Namespace    string                 `json:"namespace,omitempty"`
+	Pod          string                 `json:"pod,omitempty"`
+	Hostname     string                 `json:"hostname,omitempty"`
+	Cluster      string                 `json:"cluster,omitempty"`
+	Requester    string                 `json:"requester"` // the identity of the admin credential
+	Reason       string                 `json:"reason"`
+	DryRun       bool                   `json:"dry_run,omitempty"`
+}
+
+// ErrManualAction is returned for the wrong requests of manual actions
+var ErrManualAction = errors.New("wrong manual action")
+
+type resultKey struct{}
+
+// RunManualAction runs the actionner on the target of the request, it returns the result of the action,
+// its status is `running` for the actions run in the background
+func RunManualAction(m *ManualAction) (utils.LogLine, error) {
+	if err := checkManualAction(m); err != nil {
+		return utils.LogLine{}, err
+	}
+
+	rule := &rules.Rule{Name: manualRule}
+	if m.DryRun {
+		rule.DryRun = trueStr
+	}
+	action := &rules.Action{
+		Name:       fmt.Sprintf("%v by %v", m.Actionner, m.Requester),
+		Actionner:  m.Actionner,
+		Parameters: m.Parameters,
+	}
+	if m.Output != nil {
+		action.Output = *m.Output
+	}
+
+	hostname, _ := os.Hostname()
+	event := &events.Event{
+		TraceID:      uuid.NewString(),
+		Cluster:      m.Cluster,
+		Rule:         manualRule,
+		Output:       fmt.Sprintf("manual action requested by '%v': %v", m.Requester, m.Reason),
+		Priority:     "Notice",
+		Source:       manualRule,
+		Hostname:     m.Hostname,
+		Time:         time.Now().UTC(),
+		OutputFields: make(map[string]interface{}, len(m.OutputFields)+2),
+	}
+	if event.Hostname == "" {
+		event.Hostname = hostname
+	}
+	for i, j := range m.OutputFields {
+		event.OutputFields[i] = j
+	}
+	if m.Namespace != "" {
+		event.OutputFields["k8s.ns.name"] = m.Namespace
+	}
+	if m.Pod != "" {
+		event.OutputFields["k8s.pod.name"] = m.Pod
+	}
+	rule.AddFalcoTalonContext(event, action)
+
+	utils.PrintLog("info", utils.LogLine{
+		Message:   "manual",
+		Actionner: m.Actionner,
+		Result:    fmt.Sprintf("manual action requested by '%v': %v", m.Requester, m.Reason),
+		TraceID:   event.TraceID,
+	})
+
+	result := new(utils.LogLine)
+	event.SetTraceContext(context.WithValue(context.Background(), resultKey{}, result))
+	err := executeAction(rule, action, event, false)
+	return *result, err
+}
+
+// checkManualAction returns an error if the request is incomplete or if the actionner isn't allowed
+func checkManualAction(m *ManualAction) error {
+	config := configuration.GetConfiguration().ManualActions
+	if !config.Enabled {
+		return fmt.Errorf("%w: the manual actions are disabled", ErrManualAction)
+	}
+	if !configuration.GetConfiguration().Admin.IsSet() {
+		return fmt.Errorf("%w: the manual actions require an admin credential (`admin.tokens` or `admin.allowed_common_names`)", ErrManualAction)
+	}
+	if m.Actionner == "" || m.Requester == "" || m.Reason == "" {
+		return fmt.Errorf("%w: the actionner, the requester and the reason are required", ErrManualAction)
+	}
+	if len(config.Actionners) != 0 && !slices.Contains(config.Actionners, m.Actionner) {
+		return fmt.Errorf("%w: the actionner '%v' isn't allowed", ErrManualAction, m.Actionner)
+	}
+	if m.Namespace != "" && !configuration.GetConfiguration().IsNamespaceAllowed(m.Namespace) {
+		return fmt.Errorf("%w: the namespace '%v' isn't allowed", ErrManualAction, m.Namespace)
+	}
+
+	actionner := GetDefaultActionners().FindActionner(m.Actionner)
+	if actionner == nil {
+		return fmt.Errorf("%w: unknown actionner '%v'", ErrManualAction, m.Actionner)
+	}
+	// the actionners are inited for the categories used by the rules
+	if GetActionners().FindActionner(m.Actionner) == nil {
+		return fmt.Errorf("%w: the actionner '%v' isn't initialized, no rule uses its category", ErrManualAction, m.Actionner)
+	}
+	if actionner.CheckParameters != nil {
+		if err := actionner.CheckParameters(&rules.Action{Actionner: m.Actionner, Parameters: m.Parameters}); err != nil {
+			return fmt.Errorf("%w: %v", ErrManualAction, err)
+		}
+	}
+	if m.Output == nil && actionner.IsOutputRequired() {
+		return fmt.Errorf("%w: an output is required", ErrManualAction)
+	}
+	if m.Output != nil {
+		if err := outputs.CheckOutput(m.Output); err != nil {
+			return fmt.Errorf("%w: %v", ErrManualAction, err)
+		}
+	}
+	return nil
+}
+
+// setResult keeps the last log of the action for the manual actions
+func setResult(event *events.Event, log utils.LogLine) {
+	if r, ok := event.GetTraceContext().Value(resultKey{}).(*utils.LogLine); ok {
+		*r = log
+	}
+}
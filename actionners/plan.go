@@ -0,0 +1,95 @@
+package actionners
+
+import (
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+)
+
+// PlannedRule is a rule matching an event, with the actions it would run
+type PlannedRule struct {
+	Rule     string          `json:"rule"`
+	DryRun   bool            `json:"dry_run"`
+	Continue bool            `json:"continue"` // the next matching rules are evaluated
+	Actions  []PlannedAction `json:"actions"`
+}
+
+// PlannedAction is an action which would run for an event, after the previous ones of its rule
+type PlannedAction struct {
+	Action             string                 `json:"action"`
+	Actionner          string                 `json:"actionner"`
+	Known              bool                   `json:"known"`
+	Parameters         map[string]interface{} `json:"parameters,omitempty"`
+	Targets            *rules.Targets         `json:"targets,omitempty"`
+	Output             string                 `json:"output,omitempty"`
+	OutputParameters   map[string]interface{} `json:"output_parameters,omitempty"`
+	AdditionalContexts []string               `json:"additional_contexts,omitempty"`
+	Cluster            string                 `json:"cluster,omitempty"`
+	ImpersonateUser    string                 `json:"impersonate_user,omitempty"`
+	ImpersonateGroups  []string               `json:"impersonate_groups,omitempty"`
+	IgnoreErrors       bool                   `json:"ignore_errors"` // the next action runs even if this one fails
+	Continue           bool                   `json:"continue"`      // the next action runs after this one
+	Verify             bool                   `json:"verify"`        // the effect of the action is checked after its run
+}
+
+// PlanEvent returns the rules matching the event and the actions they would run, with the same
+// evaluation as a received event but without running anything, no client is needed
+func PlanEvent(event *events.Event) []PlannedRule {
+	plan := make([]PlannedRule, 0)
+	if !isEventAllowed(event) {
+		return plan
+	}
+	enabledRules := rules.GetRules()
+	if enabledRules == nil {
+		return plan
+	}
+
+	defaultActionners := GetDefaultActionners()
+	for _, i := range *enabledRules {
+		if !i.CompareRule(event) || !isRuleAllowed(i, event) {
+			continue
+		}
+		p := PlannedRule{
+			Rule:     i.GetName(),
+			DryRun:   i.IsDryRun() || event.IsTest(),
+			Continue: i.Continue != falseStr,
+			Actions:  make([]PlannedAction, 0),
+		}
+		for _, a := range i.GetActions() {
+			actionner := defaultActionners.FindActionner(a.GetActionner())
+			pa := PlannedAction{
+				Action:             a.GetName(),
+				Actionner:          a.GetActionner(),
+				Known:              actionner != nil,
+				Parameters:         a.GetParameters(),
+				Targets:            a.GetTargets(),
+				AdditionalContexts: a.GetAdditionalContexts(),
+				Cluster:            getCluster(i, event),
+				ImpersonateUser:    i.GetImpersonatedUser(),
+				ImpersonateGroups:  i.GetImpersonatedGroups(),
+				IgnoreErrors:       a.IgnoreErrors != falseStr,
+			}
+			if o := a.GetOutput(); o != nil {
+				pa.Output = o.GetTarget()
+				pa.OutputParameters = o.GetParameters()
+			}
+			pa.Verify = actionner != nil && actionner.Verify != nil && a.MustVerify(configuration.GetConfiguration().Verification.Enabled)
+			// an unknown actionner fails, the next actions don't run
+			pa.Continue = actionner != nil && (a.Continue == trueStr || a.Continue != falseStr && actionner.MustDefaultContinue())
+			p.Actions = append(p.Actions, pa)
+			if !pa.Continue {
+				break
+			}
+		}
+		plan = append(plan, p)
+		if !p.Continue {
+			break
+		}
+	}
+	return plan
+}
+
+// ProcessEvent runs the actions of the rules matching the event, as for a received event
+func ProcessEvent(event *events.Event) {
+	processEvent(event)
+}
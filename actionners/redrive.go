@@ -0,0 +1,43 @@
+package actionners
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/falco-talon/falco-talon/internal/deadletter"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// ErrNotLoaded is returned if the rule or the action of an entry of the dead-letter queue is no longer loaded
+var ErrNotLoaded = errors.New("the action is no longer loaded")
+
+// Redrive runs again the failed action of the entry of the dead-letter queue on its event, the other actions of
+// the rule aren't run, a new entry is added if it fails again. The actions of a pending entry, for an event not
+// processed before a shutdown, are all run from the action of the entry
+func Redrive(entry *deadletter.Entry, event *events.Event) error {
+	for _, i := range *rules.GetRules() {
+		if i.GetName() != entry.Rule {
+			continue
+		}
+		for k, j := range i.GetActions() {
+			if j.GetName() != entry.Action || !strings.EqualFold(j.GetActionner(), entry.Actionner) {
+				continue
+			}
+			if entry.Pending {
+				runActions(i, i.GetActions()[k:], event)
+				return nil
+			}
+			err := runActionOnTargets(i, j, prepareEvent(i, j, event))
+			if err != nil {
+				if err2 := deadletter.Add(i.GetName(), j.GetName(), j.GetActionner(), event, err); err2 != nil {
+					utils.PrintLog("error", utils.LogLine{Error: err2.Error(), Message: "deadletter", Rule: i.GetName(), Action: j.GetName(), TraceID: event.TraceID})
+				}
+			}
+			return err
+		}
+	}
+	return fmt.Errorf("%w: the action '%v' of the rule '%v'", ErrNotLoaded, entry.Action, entry.Rule)
+}
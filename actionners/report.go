@@ -0,0 +1,94 @@
+package actionners
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/incidents"
+	"github.com/falco-talon/falco-talon/internal/report"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/notifiers"
+	"github.com/falco-talon/falco-talon/outputs"
+	"github.com/falco-talon/falco-talon/outputs/model"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// reports in progress, by trace id and rule
+var reports sync.Map
+
+func getReportKey(rule *rules.Rule, event *events.Event) string {
+	return event.TraceID + "/" + rule.GetName()
+}
+
+func startReport(rule *rules.Rule, event *events.Event) *report.Report {
+	if rule.GetReport() == nil {
+		return nil
+	}
+	r := report.NewReport(rule.GetName(), rule.Description, event)
+	if incident := incidents.GetIncident(event.IncidentID); incident != nil {
+		r.SetIncident(incident.ID, incident.GetTimeline(), incident.GetDropped())
+	}
+	reports.Store(getReportKey(rule, event), r)
+	return r
+}
+
+// recordReport adds the log to the timeline of the report of the rule, if any
+func recordReport(rule *rules.Rule, event *events.Event, log utils.LogLine) {
+	if r, ok := reports.Load(getReportKey(rule, event)); ok {
+		r.(*report.Report).Add(log)
+	}
+}
+
+// notify records the log in the report and sends the notifications
+func notify(rule *rules.Rule, action *rules.Action, event *events.Event, log utils.LogLine) {
+	recordReport(rule, event, log)
+	notifiers.Notify(rule, action, event, log)
+}
+
+// storeReport renders the report once the actions of the rule are done and stores it with its output
+func storeReport(rule *rules.Rule, event *events.Event, r *report.Report) {
+	reports.Delete(getReportKey(rule, event))
+
+	settings := rule.GetReport()
+	log := utils.LogLine{
+		Message:    "report",
+		Rule:       rule.GetName(),
+		Target:     settings.Output.GetTarget(),
+		TraceID:    event.TraceID,
+		IncidentID: event.IncidentID,
+	}
+
+	o := outputs.GetOutputs().FindOutput(settings.Output.GetTarget())
+	if o == nil {
+		log.Error = fmt.Sprintf("unknown target '%v'", settings.Output.GetTarget())
+		log.Status = "failure"
+		utils.PrintLog("error", log)
+		return
+	}
+
+	b, err := r.Render(settings.Format)
+	if err != nil {
+		log.Error = err.Error()
+		log.Status = "failure"
+		utils.PrintLog("error", log)
+		return
+	}
+
+	result, err := o.Store(&settings.Output, &model.Data{
+		Name:      report.GetFileName(settings.Format),
+		Namespace: event.GetNamespaceName(),
+		Pod:       event.GetPodName(),
+		Hostname:  event.GetHostname(),
+		Bytes:     b,
+	}, log)
+	log.Status = result.Status
+	log.Objects = result.Objects
+	log.Output = result.Output
+	if err != nil {
+		log.Error = err.Error()
+		utils.PrintLog("error", log)
+		return
+	}
+	utils.PrintLog("info", log)
+}
@@ -0,0 +1,115 @@
+package actionners
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/outputs/model"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMultiplier     = 2.0
+)
+
+// getRetryPolicy returns the retry policy of the actionner, the one of `default` otherwise
+func getRetryPolicy(actionner string) (configuration.RetryPolicy, bool) {
+	retries := configuration.GetConfiguration().Retries
+	if p, ok := retries[actionner]; ok {
+		return p, true
+	}
+	p, ok := retries["default"]
+	return p, ok
+}
+
+// runWithRetries runs the action, it's retried with an exponential backoff if the error is retryable
+func runWithRetries(actionner *Actionner, action *rules.Action, event *events.Event) (utils.LogLine, *model.Data, error) {
+	policy, ok := getRetryPolicy(action.GetActionner())
+	if !ok || policy.MaxAttempts <= 1 {
+		return runAttempt(actionner, action, event)
+	}
+
+	backoff := defaultInitialBackoff
+	if policy.InitialBackoffMs > 0 {
+		backoff = time.Duration(policy.InitialBackoffMs) * time.Millisecond
+	}
+	maxBackoff := defaultMaxBackoff
+	if policy.MaxBackoffMs > 0 {
+		maxBackoff = time.Duration(policy.MaxBackoffMs) * time.Millisecond
+	}
+	multiplier := defaultMultiplier
+	if policy.Multiplier >= 1 {
+		multiplier = policy.Multiplier
+	}
+
+	var result utils.LogLine
+	var data *model.Data
+	var err error
+	for attempt := 1; ; attempt++ {
+		result, data, err = runAttempt(actionner, action, event)
+		if err == nil || attempt >= policy.MaxAttempts || !isRetryable(err, policy.RetryableErrors) {
+			return result, data, err
+		}
+
+		delay := backoff
+		if policy.Jitter > 0 {
+			delay += time.Duration((rand.Float64()*2 - 1) * policy.Jitter * float64(backoff)) //nolint:gosec
+		}
+		utils.PrintLog("warning", utils.LogLine{
+			Message:   "action",
+			Action:    action.GetName(),
+			Actionner: action.GetActionner(),
+			TraceID:   event.TraceID,
+			Error:     err.Error(),
+			Result:    fmt.Sprintf("attempt %v/%v failed, retry in %v", attempt, policy.MaxAttempts, delay.Round(time.Millisecond)),
+		})
+		time.Sleep(delay)
+
+		backoff = time.Duration(math.Min(float64(backoff)*multiplier, float64(maxBackoff)))
+	}
+}
+
+// runAttempt runs the action in a slot of the concurrency limits, the slot is released after each attempt,
+// the other actions can run during the backoff
+func runAttempt(actionner *Actionner, action *rules.Action, event *events.Event) (utils.LogLine, *model.Data, error) {
+	release := acquire(action.GetActionner())
+	defer release()
+	return actionner.Action(action, event)
+}
+
+// isRetryable classifies the error, the transient errors of the API servers and of the network are retryable,
+// as the errors containing one of the patterns
+func isRetryable(err error, patterns []string) bool {
+	if apierrors.IsTooManyRequests(err) ||
+		apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	for _, i := range patterns {
+		if strings.Contains(strings.ToLower(err.Error()), strings.ToLower(i)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package actionners
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/internal/locks"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// the scheduled rules aren't triggered by the events, the scheduler creates an event for each of their runs
+const (
+	scheduleTag    string = "falco-talon:schedule"
+	scheduleSource string = "schedule"
+	scheduleLock   string = "schedule/"
+)
+
+var (
+	stopScheduler = make(chan struct{})
+	stopOnce      sync.Once
+	// the scheduled rules with a run in progress
+	scheduledRuns   = make(map[string]bool)
+	scheduledRunsMu sync.Mutex
+)
+
+// startScheduler runs the scheduled rules at the start of their minutes, only by the leader with the leader
+// election, or by the replica holding the lock of the rule with the action locks of the lease backend, the
+// replicas would run them several times otherwise
+func startScheduler() {
+	go func() {
+		for {
+			now := time.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+			select {
+			case <-stopScheduler:
+				return
+			case t := <-time.After(next.Sub(now)):
+				runScheduledRules(t.Truncate(time.Minute))
+			}
+		}
+	}()
+}
+
+// stopSchedule stops the runs of the scheduled rules, the running ones continue
+func stopSchedule() {
+	stopOnce.Do(func() { close(stopScheduler) })
+}
+
+func runScheduledRules(t time.Time) {
+	config := configuration.GetConfiguration()
+	if config.Deduplication.LeaderElection && !k8s.IsLeader() {
+		return
+	}
+	lock := !config.Deduplication.LeaderElection
+	r := rules.GetRules()
+	if r == nil {
+		return
+	}
+	for _, i := range *r {
+		if !i.IsScheduled() || !i.IsEnabled() || !i.IsDue(t) {
+			continue
+		}
+		if lock && (!config.ActionLocks.Enabled || config.ActionLocks.Backend != locks.Lease) {
+			utils.PrintLog("warning", utils.LogLine{Message: "schedule", Rule: i.GetName(), Result: "the scheduled rules require the leader election or the action locks with the lease backend, the run is skipped"})
+			continue
+		}
+		if !startScheduledRun(i.GetName()) {
+			utils.PrintLog("warning", utils.LogLine{Message: "schedule", Rule: i.GetName(), Result: "the previous run is still in progress, the run is skipped"})
+			continue
+		}
+		runningWorkers.Add(1)
+		go func(rule *rules.Rule) {
+			defer runningWorkers.Done()
+			defer endScheduledRun(rule.GetName())
+			if lock {
+				release, err := locks.Acquire(scheduleLock + rule.GetName())
+				if err != nil {
+					// the run is done by another replica
+					if !errors.Is(err, locks.ErrLocked) {
+						utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "schedule", Rule: rule.GetName()})
+					}
+					return
+				}
+				// the lock is held until the end of the minute, for the replicas with a late tick
+				defer func() {
+					if d := time.Until(t.Add(time.Minute)); d > 0 {
+						time.AfterFunc(d, release)
+						return
+					}
+					release()
+				}()
+			}
+			runScheduledRule(rule, t)
+		}(i)
+	}
+}
+
+// startScheduledRun returns false if a run of the rule is still in progress
+func startScheduledRun(name string) bool {
+	scheduledRunsMu.Lock()
+	defer scheduledRunsMu.Unlock()
+	if scheduledRuns[name] {
+		return false
+	}
+	scheduledRuns[name] = true
+	return true
+}
+
+func endScheduledRun(name string) {
+	scheduledRunsMu.Lock()
+	defer scheduledRunsMu.Unlock()
+	delete(scheduledRuns, name)
+}
+
+// runScheduledRule runs the actions of the rule with an event without pod, its actions select their pods
+// with their `targets`
+func runScheduledRule(rule *rules.Rule, t time.Time) {
+	hostname, _ := os.Hostname()
+	event := &events.Event{
+		TraceID:      uuid.NewString(),
+		Rule:         rule.GetName(),
+		Output:       fmt.Sprintf("scheduled run of the rule '%v' (%v)", rule.GetName(), rule.Schedule),
+		Priority:     "Informational",
+		Source:       scheduleSource,
+		Hostname:     hostname,
+		Time:         t.UTC(),
+		OutputFields: make(map[string]interface{}),
+		Tags:         []interface{}{scheduleTag},
+	}
+
+	utils.PrintLog("info", utils.LogLine{
+		Message: "schedule",
+		Rule:    rule.GetName(),
+		Result:  fmt.Sprintf("scheduled run (%v)", rule.Schedule),
+		TraceID: event.TraceID,
+	})
+
+	runRuleActions(rule, event)
+}
@@ -0,0 +1,23 @@
+package actionners
+
+import (
+	"time"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/slo"
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// recordSLO updates the statistics of the rule with the result of the action, the time to remediate runs from
+// the event to the end of the action, its verification included, the unverified actions aren't remediations
+func recordSLO(event *events.Event, log utils.LogLine, err error) {
+	success := err == nil && log.Status == "success"
+	remediated := success && log.Verification != unverifiedStr && !event.Time.IsZero()
+	var ttr time.Duration
+	if remediated {
+		ttr = max(time.Since(event.Time), 0)
+		metrics.ObserveTimeToRemediate(log, ttr)
+	}
+	slo.RecordAction(log.Rule, success, err != nil || log.Status == "failure", remediated, ttr)
+}
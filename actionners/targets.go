@@ -0,0 +1,97 @@
+package actionners
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// runActionOnTargets runs the action for each pod selected by the targets of the action,
+// or for the pod of the event if the action has no targets
+func runActionOnTargets(rule *rules.Rule, action *rules.Action, event *events.Event) error {
+	targets := action.GetTargets()
+	if targets == nil {
+		return runAction(rule, action, event)
+	}
+
+	log := utils.LogLine{
+		Message:    "targets",
+		Rule:       rule.GetName(),
+		Action:     action.GetName(),
+		Actionner:  action.GetActionner(),
+		TraceID:    event.TraceID,
+		IncidentID: event.IncidentID,
+		Objects: map[string]string{
+			"label_selector": targets.LabelSelector,
+			"field_selector": targets.FieldSelector,
+		},
+	}
+
+	pods, err := listTargetPods(targets, event)
+	if err != nil {
+		log.Error = err.Error()
+		log.Status = "failure"
+		utils.PrintLog("error", log)
+		return err
+	}
+	if len(pods) == 0 {
+		log.Output = "no pod matches the selectors"
+		utils.PrintLog("warning", log)
+		return nil
+	}
+	log.Output = fmt.Sprintf("%v pod(s) selected", len(pods))
+	utils.PrintLog("info", log)
+
+	// the action is run for all the pods, the errors are returned together
+	var errs []error
+	for _, i := range pods {
+		e := new(events.Event)
+		*e = *event
+		e.OutputFields = maps.Clone(event.OutputFields)
+		if e.OutputFields == nil {
+			e.OutputFields = make(map[string]interface{})
+		}
+		e.OutputFields["k8s.pod.name"] = i.Name
+		e.OutputFields["k8s.ns.name"] = i.Namespace
+		if err := runAction(rule, action, e); err != nil {
+			errs = append(errs, fmt.Errorf("%v/%v: %w", i.Namespace, i.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// listTargetPods returns the pods matching the selectors, the namespace of the event is used by default,
+// the selection fails if it exceeds the maximum number of objects, to limit the impact of a wrong selector
+func listTargetPods(targets *rules.Targets, event *events.Event) ([]corev1.Pod, error) {
+	namespace := targets.Namespace
+	if namespace == "" {
+		namespace = event.GetNamespaceName()
+	}
+	if namespace == "" {
+		return nil, errors.New("missing namespace for the targets (k8s.ns.name)")
+	}
+	if !configuration.GetConfiguration().IsNamespaceAllowed(namespace) {
+		return nil, fmt.Errorf("the namespace '%v' isn't allowed", namespace)
+	}
+
+	client := k8s.GetClientForEvent(event)
+	if client == nil {
+		return nil, errors.New("wrong k8s client")
+	}
+	pods, err := client.ListPods(namespace, targets.LabelSelector, targets.FieldSelector)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) > targets.GetMaxObjects() {
+		return nil, fmt.Errorf("the selectors match %v pods, more than the limit of %v (max_objects)", len(pods), targets.GetMaxObjects())
+	}
+	return pods, nil
+}
@@ -0,0 +1,96 @@
+package actionners
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+)
+
+// checkTenants checks the settings of the tenants, a tenant without namespace couldn't act anywhere
+func checkTenants(config map[string]configuration.TenantConfig) error {
+	for i, j := range config {
+		if len(j.RulesFiles) == 0 {
+			return fmt.Errorf("wrong `rules_files` setting for the tenant '%v'", i)
+		}
+		if len(j.Namespaces) == 0 {
+			return fmt.Errorf("wrong `namespaces` setting for the tenant '%v'", i)
+		}
+		for _, k := range j.Namespaces {
+			if _, err := path.Match(k, ""); err != nil {
+				return fmt.Errorf("wrong namespace '%v' for the tenant '%v': %v", k, i, err)
+			}
+		}
+		for _, k := range j.Actionners {
+			if GetDefaultActionners().FindActionner(k) == nil {
+				return fmt.Errorf("unknown actionner '%v' for the tenant '%v'", k, i)
+			}
+		}
+		if j.Impersonate.User != "" && j.Impersonate.ServiceAccount != "" {
+			return fmt.Errorf("the impersonation of the tenant '%v' can't have both a `user` and a `service_account`", i)
+		}
+		if s := strings.Split(j.Impersonate.ServiceAccount, "/"); j.Impersonate.ServiceAccount != "" && (len(s) != 2 || s[0] == "" || s[1] == "") {
+			return fmt.Errorf("wrong `impersonate.service_account` setting for the tenant '%v', must be 'namespace/name'", i)
+		}
+	}
+	return nil
+}
+
+// CheckTenantAction returns an error if the action of a rule of a tenant uses an actionner not allowed
+// to the tenant or targets a namespace out of its namespaces
+func CheckTenantAction(rule *rules.Rule, action *rules.Action) error {
+	if rule.GetTenant() == "" {
+		return nil
+	}
+	tenant, ok := configuration.GetConfiguration().GetTenant(rule.GetTenant())
+	if !ok {
+		return fmt.Errorf("unknown tenant '%v'", rule.GetTenant())
+	}
+	if !slices.Contains(tenant.Actionners, action.GetActionner()) {
+		return fmt.Errorf("the actionner '%v' isn't allowed for the tenant '%v'", action.GetActionner(), rule.GetTenant())
+	}
+	if t := action.GetTargets(); t != nil && t.Namespace != "" && !tenant.IsNamespaceAllowed(t.Namespace) {
+		return fmt.Errorf("the namespace '%v' of the targets is out of the tenant '%v'", t.Namespace, rule.GetTenant())
+	}
+	return nil
+}
+
+// isRuleAllowed returns false if the rule belongs to a tenant and the event is out of its namespaces,
+// the events without namespace never match the rules of the tenants
+func isRuleAllowed(rule *rules.Rule, event *events.Event) bool {
+	if rule.GetTenant() == "" {
+		return true
+	}
+	tenant, ok := configuration.GetConfiguration().GetTenant(rule.GetTenant())
+	if !ok {
+		return false
+	}
+	namespace := event.GetNamespaceName()
+	if namespace == "" {
+		namespace = event.GetTargetNamespace()
+	}
+	return tenant.IsNamespaceAllowed(namespace)
+}
+
+// getTenantDenial returns why the action of a rule of a tenant is denied, empty if it's allowed, the namespace
+// is checked again before the run, the targets of the action can be in another namespace than the event
+func getTenantDenial(rule *rules.Rule, action *rules.Action, event *events.Event) string {
+	if rule.GetTenant() == "" {
+		return ""
+	}
+	if err := CheckTenantAction(rule, action); err != nil {
+		return err.Error()
+	}
+	if !isRuleAllowed(rule, event) {
+		namespace := event.GetNamespaceName()
+		if namespace == "" {
+			namespace = event.GetTargetNamespace()
+		}
+		return fmt.Sprintf("the namespace '%v' is out of the tenant '%v'", namespace, rule.GetTenant())
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package actionners
+
+import (
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	verifiedStr   string = "verified"
+	unverifiedStr string = "unverified"
+)
+
+// verifyAction checks the effect of a successful action until it's verified or the timeout expires, it returns
+// verified or unverified with the reason, empty values if the action isn't verified
+func verifyAction(actionner *Actionner, action *rules.Action, event *events.Event, result utils.LogLine, since time.Time) (string, string) {
+	config := configuration.GetConfiguration().Verification
+	if actionner.Verify == nil || result.Status != "success" || !action.MustVerify(config.Enabled) {
+		return "", ""
+	}
+
+	interval := time.Duration(config.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+	deadline := time.Now().Add(time.Duration(config.TimeoutSeconds) * time.Second)
+	for {
+		err := actionner.Verify(action, event, result, since)
+		if err == nil {
+			metrics.IncreaseVerifications(action.GetActionner(), verifiedStr)
+			return verifiedStr, ""
+		}
+		if time.Now().Add(interval).After(deadline) {
+			metrics.IncreaseVerifications(action.GetActionner(), unverifiedStr)
+			return unverifiedStr, err.Error()
+		}
+		time.Sleep(interval)
+	}
+}
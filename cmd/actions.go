@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/falco-talon/falco-talon/actionners"
+	"github.com/falco-talon/falco-talon/internal/async"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var actionsCmd = &cobra.Command{
+	Use:   "actions",
+	Short: "Run the actionners by hand",
+	Long:  "Run an actionner on a target outside of the rules, for the incident response, and follow the actions run in the background",
+}
+
+var actionsRunCmd = &cobra.Command{
+	Use:   "run [category:name]",
+	Short: "Run an actionner on a target",
+	Long: `Run an actionner on a target with explicit parameters, the manual actions must be enabled,
+the guardrails, the guard policy and the blast-radius limits still apply`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		m := actionners.ManualAction{Actionner: args[0]}
+		m.Namespace, _ = cmd.Flags().GetString("namespace")
+		m.Pod, _ = cmd.Flags().GetString("pod")
+		m.Hostname, _ = cmd.Flags().GetString("hostname")
+		m.Cluster, _ = cmd.Flags().GetString("cluster")
+		m.Reason, _ = cmd.Flags().GetString("reason")
+		m.DryRun, _ = cmd.Flags().GetBool("dry-run")
+
+		params, _ := cmd.Flags().GetStringArray("param")
+		m.Parameters = parseActionParams(params)
+		fields, _ := cmd.Flags().GetStringArray("field")
+		m.OutputFields = parseActionParams(fields)
+		if target, _ := cmd.Flags().GetString("output-target"); target != "" {
+			params, _ := cmd.Flags().GetStringArray("output-param")
+			m.Output = &rules.Output{Target: target, Parameters: parseActionParams(params)}
+		}
+
+		body, err := json.Marshal(m)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "manual"})
+		}
+		b, _ := requestAPI(cmd, "manual", http.MethodPost, "/api/v1/actions", body, true)
+		printActionResult(b)
+	},
+}
+
+var actionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the actions run in the background",
+	Long:  "List the actions run in the background, running or ended",
+	Run: func(cmd *cobra.Command, _ []string) {
+		b := callAPI(cmd, "async", http.MethodGet, "/api/v1/actions")
+		var list []async.Action
+		if err := json.Unmarshal(b, &list); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "async"})
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTARTED\tRULE\tACTION\tACTIONNER\tSTATUS\tPROGRESS")
+		for _, i := range list {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", i.ID, i.StartedAt.Format(time.RFC3339), i.Rule, i.Action, i.Actionner, i.Status, i.Progress)
+		}
+		w.Flush()
+	},
+}
+
+var actionsStatusCmd = &cobra.Command{
+	Use:   "status [id]",
+	Short: "Print the status of an action run in the background",
+	Long:  "Print the status, the progress and the result of an action run in the background",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(string(callAPI(cmd, "async", http.MethodGet, "/api/v1/actions/"+url.PathEscape(args[0]))))
+	},
+}
+
+// parseActionParams returns the key=value flags as a map, the values are decoded as JSON if they can be
+// (numbers, booleans, lists), as strings otherwise
+func parseActionParams(params []string) map[string]interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{}, len(params))
+	for _, i := range params {
+		k, v, ok := strings.Cut(i, "=")
+		if !ok || k == "" {
+			utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("wrong parameter '%v', must be key=value", i), Message: "manual"})
+		}
+		var j interface{}
+		if err := json.Unmarshal([]byte(v), &j); err != nil {
+			j = v
+		}
+		m[k] = j
+	}
+	return m
+}
+
+func printActionResult(b []byte) {
+	var result utils.LogLine
+	if err := json.Unmarshal(b, &result); err != nil {
+		fmt.Println(string(b))
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "trace id:\t%v\n", result.TraceID)
+	fmt.Fprintf(w, "actionner:\t%v\n", result.Actionner)
+	fmt.Fprintf(w, "status:\t%v\n", result.Status)
+	if result.Verification != "" {
+		fmt.Fprintf(w, "verification:\t%v\n", result.Verification)
+	}
+	for i, j := range result.Objects {
+		fmt.Fprintf(w, "%v:\t%v\n", i, j)
+	}
+	w.Flush()
+	if result.Output != "" {
+		fmt.Printf("output:\n%v\n", result.Output)
+	}
+	if result.Error != "" {
+		fmt.Printf("error: %v\n", result.Error)
+	}
+}
+
+func init() {
+	actionsCmd.PersistentFlags().StringP("address", "a", "http://localhost:2803", "Address of Falco Talon")
+	actionsCmd.PersistentFlags().Bool("insecure", false, "Skip the verification of the certificate of Falco Talon")
+	actionsRunCmd.Flags().StringP("namespace", "n", "", "Namespace of the target")
+	actionsRunCmd.Flags().String("pod", "", "Name of the target pod")
+	actionsRunCmd.Flags().String("hostname", "", "Hostname (node) of the target")
+	actionsRunCmd.Flags().String("cluster", "", "Name of the cluster of the target, the local one if empty")
+	actionsRunCmd.Flags().StringArrayP("param", "p", nil, "Parameter of the actionner, as key=value, can be repeated")
+	actionsRunCmd.Flags().StringArray("field", nil, "Other field of the target, as key=value (eg: fd.sip=10.0.0.1), can be repeated")
+	actionsRunCmd.Flags().String("output-target", "", "Target of the output of the actionner (eg: aws:s3)")
+	actionsRunCmd.Flags().StringArray("output-param", nil, "Parameter of the output, as key=value, can be repeated")
+	actionsRunCmd.Flags().String("reason", "", "Reason of the action, for the audit log")
+	actionsRunCmd.Flags().String("admin-token", "", "Admin token, its name is the requester, the first token of `admin.tokens` if empty")
+	actionsRunCmd.Flags().Bool("dry-run", false, "Run the action in dry-run mode")
+	_ = actionsRunCmd.MarkFlagRequired("reason")
+	actionsCmd.AddCommand(actionsRunCmd, actionsListCmd, actionsStatusCmd)
+	RootCmd.AddCommand(actionsCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/audit"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Manage the audit log",
+	Long:  "Manage the audit log",
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the chain of the audit log",
+	Long:  "Verify the hashes of the records of the audit log, a modified or removed record breaks the chain",
+	Run: func(cmd *cobra.Command, _ []string) {
+		path, _ := cmd.Flags().GetString("file")
+		if path == "" {
+			configFile, _ := cmd.Flags().GetString("config")
+			path = configuration.CreateConfiguration(configFile).Audit.File
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "audit"})
+		}
+		defer f.Close()
+
+		n, err := audit.Verify(f)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "audit", Result: fmt.Sprintf("%v valid record(s) before the error", n)})
+		}
+		utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("the audit log is valid, %v record(s)", n), Message: "audit"})
+	},
+}
+
+func init() {
+	auditVerifyCmd.Flags().StringP("file", "f", "", "Audit log to verify (default: the file of the configuration)")
+	auditCmd.AddCommand(auditVerifyCmd)
+	RootCmd.AddCommand(auditCmd)
+}
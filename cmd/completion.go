@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Generate the completion script for a shell",
+	Long: `Generate the completion script of Falco Talon for bash, zsh or fish.
+
+  bash: source <(falco-talon completion bash)
+  zsh:  falco-talon completion zsh > "${fpath[1]}/_falco-talon"
+  fish: falco-talon completion fish > ~/.config/fish/completions/falco-talon.fish`,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Run: func(_ *cobra.Command, args []string) {
+		var err error
+		switch args[0] {
+		case "bash":
+			err = RootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = RootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = RootCmd.GenFishCompletion(os.Stdout, true)
+		}
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "completion"})
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/deadletter"
+	"github.com/falco-talon/falco-talon/internal/handler"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var deadLettersCmd = &cobra.Command{
+	Use:   "deadletters",
+	Short: "Manage the events with a failed action",
+	Long:  "List, re-drive or delete the entries of the dead-letter queue of a running Falco Talon",
+}
+
+var deadLettersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the entries of the dead-letter queue",
+	Long:  "List the entries of the dead-letter queue",
+	Run: func(cmd *cobra.Command, _ []string) {
+		b := callAPI(cmd, "deadletter", http.MethodGet, "/deadletters")
+		var list []deadletter.Entry
+		if err := json.Unmarshal(b, &list); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "deadletter"})
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tRULE\tACTION\tERROR")
+		for _, i := range list {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", i.ID, i.Time.Format(time.RFC3339), i.Rule, i.Action, i.Error)
+		}
+		w.Flush()
+	},
+}
+
+var deadLettersRedriveCmd = &cobra.Command{
+	Use:   "redrive [id]",
+	Short: "Re-drive the failed action of an entry of the dead-letter queue",
+	Long:  "Run again the failed action of an entry of the dead-letter queue on its event, the entry is removed, a new one is added if the action fails again",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkDeadLetterID(args[0])
+		fmt.Println(string(callAdminAPI(cmd, "deadletter", http.MethodPost, "/deadletters/"+args[0]+"/redrive")))
+	},
+}
+
+var deadLettersDeleteCmd = &cobra.Command{
+	Use:   "delete [id]",
+	Short: "Delete an entry of the dead-letter queue",
+	Long:  "Delete an entry of the dead-letter queue",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		checkDeadLetterID(args[0])
+		callAPI(cmd, "deadletter", http.MethodDelete, "/deadletters/"+args[0])
+	},
+}
+
+// callAPI calls the API of Falco Talon, the first bearer token of the configuration is used if set
+func callAPI(cmd *cobra.Command, message, method, path string) []byte {
+	b, _ := requestAPI(cmd, message, method, path, nil, false)
+	return b
+}
+
+// callAdminAPI calls the admin API of Falco Talon, with the token of `--admin-token` or the first admin token
+// of the configuration
+func callAdminAPI(cmd *cobra.Command, message, method, path string) []byte {
+	b, _ := requestAPI(cmd, message, method, path, nil, true)
+	return b
+}
+
+// requestAPI sends the body to the API of Falco Talon and returns the body and the headers of the response,
+// the request is signed with the first HMAC secret of the configuration if no bearer token is set, the admin
+// routes use an admin token instead
+func requestAPI(cmd *cobra.Command, message, method, path string, body []byte, admin bool) ([]byte, http.Header) {
+	configFile, _ := cmd.Flags().GetString("config")
+	config := configuration.CreateConfiguration(configFile)
+	address, _ := cmd.Flags().GetString("address")
+	insecure, _ := cmd.Flags().GetBool("insecure")
+
+	req, err := http.NewRequestWithContext(context.Background(), method, address+path, bytes.NewReader(body))
+	if err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: message})
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	// the admin routes require an admin credential instead of the authentication
+	adminToken := ""
+	if admin {
+		adminToken, _ = cmd.Flags().GetString("admin-token")
+		if adminToken == "" && len(config.Admin.Tokens) != 0 {
+			adminToken = config.Admin.Tokens[0].Token
+		}
+	}
+	switch {
+	case admin:
+		if adminToken == "" {
+			utils.PrintLog("fatal", utils.LogLine{Error: "an admin token is required, with `--admin-token` or `admin.tokens`", Message: message})
+		}
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	case len(config.Authentication.BearerTokens) != 0:
+		req.Header.Set("Authorization", "Bearer "+config.Authentication.BearerTokens[0])
+	case len(config.Authentication.HMACSecrets) != 0:
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(config.Authentication.HMACTimestampHeader, ts)
+		req.Header.Set(config.Authentication.HMACHeader, "sha256="+handler.Sign(config.Authentication.HMACSecrets[0], ts, method, req.URL.RequestURI(), body))
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure}, //nolint:gosec
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: message})
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: message})
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("%v: %s", resp.Status, b), Message: message})
+	}
+	return b, resp.Header
+}
+
+func checkDeadLetterID(id string) {
+	if err := deadletter.CheckID(id); err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "deadletter"})
+	}
+}
+
+func init() {
+	deadLettersCmd.PersistentFlags().StringP("address", "a", "http://localhost:2803", "Address of Falco Talon")
+	deadLettersCmd.PersistentFlags().Bool("insecure", false, "Skip the verification of the certificate of Falco Talon")
+	deadLettersRedriveCmd.Flags().String("admin-token", "", "Admin token, the first token of `admin.tokens` if empty")
+	deadLettersCmd.AddCommand(deadLettersListCmd, deadLettersRedriveCmd, deadLettersDeleteCmd)
+	RootCmd.AddCommand(deadLettersCmd)
+}
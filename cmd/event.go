@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var eventCmd = &cobra.Command{
+	Use:   "event",
+	Short: "Send events to Falco Talon",
+	Long:  "Send events to a running Falco Talon, for the end-to-end tests of the rules",
+}
+
+var eventSendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send a Falco event",
+	Long: `Craft a Falco event from the flags, or from a template (JSON event) overridden by the flags, and send it
+to a running Falco Talon. With --test, the event is tagged as a test and the actions of the matching rules run in dry-run mode.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		payload, err := craftEvent(cmd)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "event"})
+		}
+		b, err := json.Marshal(payload)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "event"})
+		}
+
+		if printOnly, _ := cmd.Flags().GetBool("print"); printOnly {
+			fmt.Println(string(b))
+			return
+		}
+
+		_, header := requestAPI(cmd, "event", http.MethodPost, "/", b, false)
+		utils.PrintLog("info", utils.LogLine{
+			Message:  "event",
+			Event:    fmt.Sprintf("%v", payload["rule"]),
+			Priority: fmt.Sprintf("%v", payload["priority"]),
+			Source:   fmt.Sprintf("%v", payload["source"]),
+			TraceID:  header.Get("X-Request-Id"),
+			Result:   "event sent",
+		})
+	},
+}
+
+// craftEvent returns the payload of the event, the template is overridden by the flags
+func craftEvent(cmd *cobra.Command) (map[string]interface{}, error) {
+	payload := make(map[string]interface{})
+	if template, _ := cmd.Flags().GetString("template"); template != "" {
+		b, err := os.ReadFile(template)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &payload); err != nil {
+			return nil, fmt.Errorf("wrong template '%v': %v", template, err)
+		}
+	}
+
+	setDefault := func(key string, value interface{}) {
+		if _, ok := payload[key]; !ok {
+			payload[key] = value
+		}
+	}
+	for _, i := range []string{"rule", "priority", "source", "hostname"} {
+		if cmd.Flags().Changed(i) {
+			payload[i], _ = cmd.Flags().GetString(i)
+		} else {
+			v, _ := cmd.Flags().GetString(i)
+			setDefault(i, v)
+		}
+	}
+	if payload["hostname"] == "" {
+		payload["hostname"], _ = os.Hostname()
+	}
+	now := time.Now().UTC()
+	payload["time"] = now.Format(time.RFC3339Nano)
+	payload["uuid"] = uuid.New().String()
+
+	outputFields, _ := payload["output_fields"].(map[string]interface{})
+	if outputFields == nil {
+		outputFields = make(map[string]interface{})
+	}
+	fields, _ := cmd.Flags().GetStringArray("field")
+	for _, i := range fields {
+		k, v, ok := strings.Cut(i, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("wrong field '%v', the format is key=value", i)
+		}
+		outputFields[k] = v
+	}
+	for flag, field := range map[string]string{"pod": "k8s.pod.name", "namespace": "k8s.ns.name"} {
+		if v, _ := cmd.Flags().GetString(flag); v != "" {
+			outputFields[field] = v
+		}
+	}
+	payload["output_fields"] = outputFields
+
+	tags, _ := payload["tags"].([]interface{})
+	flagTags, _ := cmd.Flags().GetStringArray("tag")
+	for _, i := range flagTags {
+		tags = append(tags, i)
+	}
+	if test, _ := cmd.Flags().GetBool("test"); test {
+		tags = append(tags, events.TestTag)
+	}
+	if tags == nil {
+		tags = make([]interface{}, 0)
+	}
+	payload["tags"] = tags
+
+	if output, _ := cmd.Flags().GetString("output"); output != "" || payload["output"] == nil {
+		if output == "" {
+			output = fmt.Sprintf("%v", payload["rule"])
+			keys := make([]string, 0, len(outputFields))
+			for i := range outputFields {
+				keys = append(keys, i)
+			}
+			sort.Strings(keys)
+			for _, i := range keys {
+				output += fmt.Sprintf(" %v=%v", i, outputFields[i])
+			}
+		}
+		payload["output"] = fmt.Sprintf("%v: %v %v", now.Format("15:04:05.000000000"), payload["priority"], output)
+	}
+	return payload, nil
+}
+
+func init() {
+	eventCmd.PersistentFlags().StringP("address", "a", "http://localhost:2803", "Address of Falco Talon")
+	eventCmd.PersistentFlags().Bool("insecure", false, "Skip the verification of the certificate of Falco Talon")
+	eventSendCmd.Flags().StringP("template", "t", "", "File of a Falco event (JSON) to use as template")
+	eventSendCmd.Flags().String("rule", "Terminal shell in container", "Falco rule of the event")
+	eventSendCmd.Flags().String("priority", "Warning", "Priority of the event")
+	eventSendCmd.Flags().String("source", "syscall", "Source of the event")
+	eventSendCmd.Flags().String("hostname", "", "Hostname of the event (default: the local hostname)")
+	eventSendCmd.Flags().String("output", "", "Output of the event (default: the rule and the output fields)")
+	eventSendCmd.Flags().String("pod", "", "Pod of the event (k8s.pod.name)")
+	eventSendCmd.Flags().String("namespace", "", "Namespace of the event (k8s.ns.name)")
+	eventSendCmd.Flags().StringArrayP("field", "f", []string{}, "Output field of the event, as key=value")
+	eventSendCmd.Flags().StringArray("tag", []string{}, "Tag of the event")
+	eventSendCmd.Flags().Bool("test", false, "Tag the event as a test, the actions run in dry-run mode")
+	eventSendCmd.Flags().Bool("print", false, "Print the event instead of sending it")
+	eventCmd.AddCommand(eventSendCmd)
+	RootCmd.AddCommand(eventCmd)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/rbac"
+	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate resources for Falco Talon",
+	Long:  "Generate resources for the deployment of Falco Talon",
+}
+
+var generateRBACCmd = &cobra.Command{
+	Use:   "rbac",
+	Short: "Generate the minimal RBAC",
+	Long: `Generate the Roles or the ClusterRoles, with their bindings, containing only the permissions required by the actionners
+and the notifiers of the rules files and by the enabled features of the config (cache, kubernetes events, expiry, leases).
+In the namespace-scoped mode (namespaces setting), a Role is generated in each namespace.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		// stdout is kept for the generated resources
+		utils.SetLogOutput(os.Stderr)
+		configFile, _ := cmd.Flags().GetString("config")
+		config := configuration.CreateConfiguration(configFile)
+		utils.SetLogFormat(config.LogFormat)
+		rulesFiles, _ := cmd.Flags().GetStringArray("rules")
+		if len(rulesFiles) != 0 {
+			config.RulesFiles = rulesFiles
+		}
+		rules := ruleengine.ParseRules(config.RulesFiles, getTenants(config))
+		if rules == nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: "invalid rules", Message: "rules"})
+		}
+
+		var options rbac.Options
+		options.Name, _ = cmd.Flags().GetString("name")
+		options.Namespace, _ = cmd.Flags().GetString("namespace")
+		options.ServiceAccount, _ = cmd.Flags().GetString("service-account")
+
+		permissions, local, warnings := rbac.GetPermissions(rules, config)
+		objects, w := rbac.Generate(permissions, local, config.Namespaces, options)
+		for _, i := range append(warnings, w...) {
+			utils.PrintLog("warning", utils.LogLine{Error: i, Message: "rbac"})
+		}
+		b, err := rbac.Marshal(objects)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "rbac"})
+		}
+		writeOutput(cmd, "rbac", b)
+	},
+}
+
+func init() {
+	generateRBACCmd.Flags().StringP("output", "o", "", "File to write (default: stdout)")
+	generateRBACCmd.Flags().String("name", "falco-talon", "Name of the roles and the bindings")
+	generateRBACCmd.Flags().StringP("namespace", "n", "falco", "Namespace of Falco Talon")
+	generateRBACCmd.Flags().String("service-account", "falco-talon", "Service account of Falco Talon")
+	generateCmd.AddCommand(generateRBACCmd)
+	RootCmd.AddCommand(generateCmd)
+}
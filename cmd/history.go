@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/falco-talon/falco-talon/internal/history"
+	"github.com/falco-talon/falco-talon/internal/undo"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Query the history of the actions",
+	Long:  "List the actions run by a running Falco Talon, inspect their parameters and their outputs, or undo them",
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the recent actions",
+	Long:  "List the recent actions, filtered by the flags",
+	Run: func(cmd *cobra.Command, _ []string) {
+		format := getHistoryFormat(cmd)
+
+		q := url.Values{}
+		for _, i := range []string{"rule", "action", "actionner", "namespace", "status"} {
+			if v, _ := cmd.Flags().GetString(i); v != "" {
+				q.Set(i, v)
+			}
+		}
+		for _, i := range []string{"since", "until"} {
+			v, _ := cmd.Flags().GetString(i)
+			if v == "" {
+				continue
+			}
+			t, err := parseHistoryTime(v)
+			if err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("wrong `%v` flag: %v", i, err), Message: "history"})
+			}
+			q.Set(i, t.Format(time.RFC3339))
+		}
+		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 {
+			q.Set("limit", strconv.Itoa(limit))
+		}
+
+		b := callAPI(cmd, "history", http.MethodGet, "/api/v1/history?"+q.Encode())
+		if format == "json" {
+			fmt.Println(string(b))
+			return
+		}
+		var list []history.Entry
+		if err := json.Unmarshal(b, &list); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "history"})
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tRULE\tACTION\tACTIONNER\tTARGET\tSTATUS\tDURATION")
+		for _, i := range list {
+			target := "-"
+			if i.Pod != "" {
+				target = i.Namespace + "/" + i.Pod
+			} else if i.Namespace != "" {
+				target = i.Namespace
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%vms\n", i.ID, i.Time.Format(time.RFC3339), i.Rule, i.Action, i.Actionner, target, i.Status, i.DurationMs)
+		}
+		w.Flush()
+	},
+}
+
+var historyInspectCmd = &cobra.Command{
+	Use:   "inspect [id]",
+	Short: "Inspect an action",
+	Long:  "Print the parameters, the objects and the output of an action",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format := getHistoryFormat(cmd)
+
+		b := callAPI(cmd, "history", http.MethodGet, "/api/v1/history/"+url.PathEscape(args[0]))
+		if format == "json" {
+			fmt.Println(string(b))
+			return
+		}
+		var entry history.Entry
+		if err := json.Unmarshal(b, &entry); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "history"})
+		}
+		printHistoryEntry(&entry)
+	},
+}
+
+var historyUndoCmd = &cobra.Command{
+	Use:   "undo [id]",
+	Short: "Undo an action",
+	Long:  "Restore the state before a reversible action of the history, the undo must be enabled",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		b := callAPI(cmd, "history", http.MethodGet, "/api/v1/history/"+url.PathEscape(args[0]))
+		var entry history.Entry
+		if err := json.Unmarshal(b, &entry); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "history"})
+		}
+
+		// the state before the action is kept by the undo, for the same event and the same action
+		b = callAPI(cmd, "undo", http.MethodGet, "/undo")
+		var list []undo.Entry
+		if err := json.Unmarshal(b, &list); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "undo"})
+		}
+		for _, i := range list {
+			if i.TraceID == entry.TraceID && i.Rule == entry.Rule && i.Action == entry.Action {
+				fmt.Println(string(callAdminAPI(cmd, "undo", http.MethodPost, "/undo/"+i.ID)))
+				return
+			}
+		}
+		utils.PrintLog("fatal", utils.LogLine{
+			Error:   fmt.Sprintf("the action '%v' isn't reversible or is already reverted", entry.Action),
+			Message: "undo",
+			Rule:    entry.Rule,
+			Action:  entry.Action,
+			TraceID: entry.TraceID,
+		})
+	},
+}
+
+func getHistoryFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("format")
+	format = strings.ToLower(format)
+	if format != "text" && format != "json" {
+		utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("unknown format '%v'", format), Message: "history"})
+	}
+	return format
+}
+
+// parseHistoryTime returns the time of a RFC3339 date or of a duration before now
+func parseHistoryTime(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func printHistoryEntry(entry *history.Entry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "id:\t%v\n", entry.ID)
+	fmt.Fprintf(w, "time:\t%v\n", entry.Time.Format(time.RFC3339))
+	fmt.Fprintf(w, "trace id:\t%v\n", entry.TraceID)
+	fmt.Fprintf(w, "rule:\t%v\n", entry.Rule)
+	fmt.Fprintf(w, "action:\t%v\n", entry.Action)
+	fmt.Fprintf(w, "actionner:\t%v\n", entry.Actionner)
+	if entry.Namespace != "" {
+		fmt.Fprintf(w, "namespace:\t%v\n", entry.Namespace)
+	}
+	if entry.Pod != "" {
+		fmt.Fprintf(w, "pod:\t%v\n", entry.Pod)
+	}
+	fmt.Fprintf(w, "status:\t%v\n", entry.Status)
+	fmt.Fprintf(w, "duration:\t%vms\n", entry.DurationMs)
+	w.Flush()
+
+	if len(entry.Parameters) != 0 {
+		b, _ := json.MarshalIndent(entry.Parameters, "  ", "  ")
+		fmt.Printf("parameters:\n  %s\n", b)
+	}
+	if len(entry.Objects) != 0 {
+		fmt.Println("objects:")
+		keys := make([]string, 0, len(entry.Objects))
+		for i := range entry.Objects {
+			keys = append(keys, i)
+		}
+		sort.Strings(keys)
+		for _, i := range keys {
+			fmt.Printf("  %v: %v\n", i, entry.Objects[i])
+		}
+	}
+	if entry.Output != "" {
+		fmt.Printf("output:\n%v\n", entry.Output)
+	}
+	if entry.Error != "" {
+		fmt.Printf("error: %v\n", entry.Error)
+	}
+}
+
+func init() {
+	historyCmd.PersistentFlags().StringP("address", "a", "http://localhost:2803", "Address of Falco Talon")
+	historyCmd.PersistentFlags().Bool("insecure", false, "Skip the verification of the certificate of Falco Talon")
+	historyUndoCmd.Flags().String("admin-token", "", "Admin token, the first token of `admin.tokens` if empty")
+	historyListCmd.Flags().String("rule", "", "Name of the rule")
+	historyListCmd.Flags().String("action", "", "Name of the action")
+	historyListCmd.Flags().String("actionner", "", "Name of the actionner")
+	historyListCmd.Flags().StringP("namespace", "n", "", "Namespace of the event")
+	historyListCmd.Flags().String("status", "", "Status of the action (success, failure, ignored, dry-run, skipped)")
+	historyListCmd.Flags().String("since", "", "Start of the period, as a duration before now (1h) or a date (RFC3339)")
+	historyListCmd.Flags().String("until", "", "End of the period, as a duration before now (1h) or a date (RFC3339)")
+	historyListCmd.Flags().IntP("limit", "l", 20, "Max number of the most recent actions, 0 for all")
+	historyListCmd.Flags().StringP("format", "f", "text", "Format of the output: text or json")
+	historyInspectCmd.Flags().StringP("format", "f", "text", "Format of the output: text or json")
+	historyCmd.AddCommand(historyListCmd, historyInspectCmd, historyUndoCmd)
+	RootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/falco-talon/falco-talon/actionners"
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/notifiers"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// actionnerDoc is the description of an actionner, from its registration
+type actionnerDoc struct {
+	Name                    string           `json:"name"`
+	Category                string           `json:"category"`
+	DefaultContinue         bool             `json:"default_continue"`
+	AllowAdditionalContexts bool             `json:"allow_additional_contexts"`
+	AllowOutput             bool             `json:"allow_output"`
+	RequireOutput           bool             `json:"require_output"`
+	Reversible              bool             `json:"reversible"`
+	Parameters              []utils.FieldDoc `json:"parameters"`
+	Permissions             []k8s.Permission `json:"rbac,omitempty"`
+}
+
+// notifierDoc is the description of a notifier, from its registration
+type notifierDoc struct {
+	Name        string           `json:"name"`
+	Settings    []utils.FieldDoc `json:"settings"`
+	Permissions []k8s.Permission `json:"rbac,omitempty"`
+}
+
+var actionnersCmd = &cobra.Command{
+	Use:   "actionners",
+	Short: "Describe the actionners",
+	Long:  "Describe the actionners of Falco Talon",
+}
+
+var actionnersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the actionners with their parameters",
+	Long:  "List the actionners with their parameters (type, default, required) and the RBAC they require",
+	Run: func(cmd *cobra.Command, _ []string) {
+		docs := make([]actionnerDoc, 0)
+		for _, i := range *actionners.GetDefaultActionners() {
+			docs = append(docs, actionnerDoc{
+				Name:                    i.Name,
+				Category:                i.Category,
+				DefaultContinue:         i.DefaultContinue,
+				AllowAdditionalContexts: i.AllowAdditionalContexts,
+				AllowOutput:             i.AllowOutput || i.RequireOutput,
+				RequireOutput:           i.RequireOutput,
+				Reversible:              i.Revert != nil,
+				Parameters:              utils.DescribeFields(i.Parameters, "mapstructure"),
+				Permissions:             i.Permissions,
+			})
+		}
+		if getListFormat(cmd) == "json" {
+			printListJSON(docs)
+			return
+		}
+		for _, i := range docs {
+			fmt.Printf("%v:%v\n", i.Category, i.Name)
+			output := "no"
+			if i.RequireOutput {
+				output = "required"
+			} else if i.AllowOutput {
+				output = "allowed"
+			}
+			fmt.Printf("  continue by default: %v, output: %v, additional contexts: %v, reversible: %v\n", i.DefaultContinue, output, i.AllowAdditionalContexts, i.Reversible)
+			printFieldDocs("parameters", i.Parameters)
+			printPermissions(i.Permissions)
+			fmt.Println()
+		}
+	},
+}
+
+var notifiersCmd = &cobra.Command{
+	Use:   "notifiers",
+	Short: "Describe the notifiers",
+	Long:  "Describe the notifiers of Falco Talon",
+}
+
+var notifiersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the notifiers with their settings",
+	Long:  "List the notifiers with their settings (type, default) and the RBAC they require",
+	Run: func(cmd *cobra.Command, _ []string) {
+		docs := make([]notifierDoc, 0)
+		for _, i := range *notifiers.GetAvailableNotifiers() {
+			docs = append(docs, notifierDoc{
+				Name:        i.Name,
+				Settings:    utils.DescribeFields(i.Settings, "field"),
+				Permissions: i.Permissions,
+			})
+		}
+		if getListFormat(cmd) == "json" {
+			printListJSON(docs)
+			return
+		}
+		for _, i := range docs {
+			fmt.Println(i.Name)
+			printFieldDocs("settings", i.Settings)
+			printPermissions(i.Permissions)
+			fmt.Println()
+		}
+	},
+}
+
+func getListFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("format")
+	format = strings.ToLower(format)
+	if format != "text" && format != "json" {
+		utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("unknown format '%v'", format), Message: "list"})
+	}
+	return format
+}
+
+func printListJSON(v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "list"})
+	}
+	fmt.Println(string(b))
+}
+
+func printFieldDocs(name string, docs []utils.FieldDoc) {
+	if len(docs) == 0 {
+		fmt.Printf("  %v: none\n", name)
+		return
+	}
+	fmt.Printf("  %v:\n", name)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "    NAME\tTYPE\tDEFAULT\tREQUIRED")
+	for _, i := range docs {
+		deflt := i.Default
+		if deflt == "" {
+			deflt = "-"
+		}
+		fmt.Fprintf(w, "    %v\t%v\t%v\t%v\n", i.Name, i.Type, deflt, i.Required)
+	}
+	w.Flush()
+}
+
+func printPermissions(permissions []k8s.Permission) {
+	if len(permissions) == 0 {
+		fmt.Println("  rbac: none")
+		return
+	}
+	fmt.Println("  rbac:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "    APIGROUPS\tRESOURCES\tVERBS")
+	for _, i := range permissions {
+		groups := make([]string, 0, len(i.APIGroups))
+		for _, j := range i.APIGroups {
+			if j == "" {
+				j = `""`
+			}
+			groups = append(groups, j)
+		}
+		fmt.Fprintf(w, "    %v\t%v\t%v\n", strings.Join(groups, ","), strings.Join(i.Resources, ","), strings.Join(i.Verbs, ","))
+	}
+	w.Flush()
+}
+
+func init() {
+	actionnersListCmd.Flags().StringP("format", "f", "text", "Format of the output: text or json")
+	notifiersListCmd.Flags().StringP("format", "f", "text", "Format of the output: text or json")
+	actionnersCmd.AddCommand(actionnersListCmd)
+	notifiersCmd.AddCommand(notifiersListCmd)
+	RootCmd.AddCommand(actionnersCmd, notifiersCmd)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/monitoring"
+	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var monitoringCmd = &cobra.Command{
+	Use:   "monitoring",
+	Short: "Generate the monitoring resources",
+	Long:  "Generate a Grafana dashboard and Prometheus alerting rules for the metrics of Falco Talon and the loaded rules",
+}
+
+var monitoringDashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Generate a Grafana dashboard",
+	Long:  "Generate the JSON model of a Grafana dashboard, the rules of the rules files are the values of the 'rule' variable",
+	Run: func(cmd *cobra.Command, _ []string) {
+		b, err := monitoring.GenerateDashboard(loadMonitoringRules(cmd))
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "monitoring"})
+		}
+		writeOutput(cmd, "monitoring", b)
+	},
+}
+
+var monitoringAlertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Generate Prometheus alerting rules",
+	Long:  "Generate Prometheus alerting rules, the failures of the actions are alerted for each rule of the rules files",
+	Run: func(cmd *cobra.Command, _ []string) {
+		prometheusRule, _ := cmd.Flags().GetBool("prometheus-rule")
+		namespace, _ := cmd.Flags().GetString("namespace")
+		b, err := monitoring.MarshalAlerts(monitoring.GenerateAlerts(loadMonitoringRules(cmd)), prometheusRule, namespace)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "monitoring"})
+		}
+		writeOutput(cmd, "monitoring", b)
+	},
+}
+
+func loadMonitoringRules(cmd *cobra.Command) *[]*ruleengine.Rule {
+	configFile, _ := cmd.Flags().GetString("config")
+	config := configuration.CreateConfiguration(configFile)
+	utils.SetLogFormat(config.LogFormat)
+	rulesFiles, _ := cmd.Flags().GetStringArray("rules")
+	if len(rulesFiles) != 0 {
+		config.RulesFiles = rulesFiles
+	}
+	rules := ruleengine.ParseRules(config.RulesFiles, getTenants(config))
+	if rules == nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: "invalid rules", Message: "rules"})
+	}
+	return rules
+}
+
+// writeOutput writes the generated file in the file of the output flag, or on stdout
+func writeOutput(cmd *cobra.Command, module string, b []byte) {
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		fmt.Println(string(b))
+		return
+	}
+	if err := os.WriteFile(output, b, 0600); err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: module})
+	}
+	utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("written in '%v'", output), Message: module})
+}
+
+func init() {
+	monitoringCmd.PersistentFlags().StringP("output", "o", "", "File to write (default: stdout)")
+	monitoringAlertsCmd.Flags().Bool("prometheus-rule", false, "Wrap the rules in a PrometheusRule resource of the Prometheus Operator")
+	monitoringAlertsCmd.Flags().StringP("namespace", "n", "", "Namespace of the PrometheusRule resource")
+	monitoringCmd.AddCommand(monitoringDashboardCmd, monitoringAlertsCmd)
+	RootCmd.AddCommand(monitoringCmd)
+}
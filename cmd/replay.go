@@ -0,0 +1,424 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/falco-talon/falco-talon/actionners"
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// replayResult is the machine-readable result of the replay of an event
+type replayResult struct {
+	Time time.Time `json:"time"`
+	testResult
+}
+
+// maximum size of a line of the archive
+const maxReplayLineSize = 1024 * 1024
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay archived Falco events through the rules",
+	Long: `Replay archived Falco events (one JSON event per line, gzipped or not) through the rules in dry-run,
+and print the rules matching each event and the actions they would run. The results of two versions of the rules
+can be compared, to test their changes against the past incidents. No action is run, no client is needed.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		file, _ := cmd.Flags().GetString("file")
+		speedFlag, _ := cmd.Flags().GetString("speed")
+		format, _ := cmd.Flags().GetString("format")
+
+		format = strings.ToLower(format)
+		if format != "text" && format != "json" {
+			utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("unknown format '%v'", format), Message: "replay"})
+		}
+		if format == "json" {
+			// stdout is kept for the results
+			utils.SetLogOutput(os.Stderr)
+		}
+		speed, err := parseReplaySpeed(speedFlag)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "replay"})
+		}
+
+		configFile, _ := cmd.Flags().GetString("config")
+		config := configuration.CreateConfiguration(configFile)
+		utils.SetLogFormat(config.LogFormat)
+		rulesFiles, _ := cmd.Flags().GetStringArray("rules")
+		if len(rulesFiles) != 0 {
+			config.RulesFiles = rulesFiles
+		}
+
+		r, err := openReplayFile(file)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "replay"})
+		}
+		defer r.Close()
+
+		if baseRulesFiles, _ := cmd.Flags().GetStringArray("base-rules"); len(baseRulesFiles) != 0 {
+			changed := compareRules(r, baseRulesFiles, config, format)
+			if failOnChange, _ := cmd.Flags().GetBool("fail-on-change"); failOnChange && changed != 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		if ruleengine.ParseRules(config.RulesFiles, getTenants(config)) == nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: "invalid rules", Message: "rules"})
+		}
+
+		var (
+			count, matched int
+			previous       time.Time
+		)
+		rulesCount := map[string]int{}
+		actionnersCount := map[string]int{}
+
+		invalid, err := scanReplayEvents(r, func(event *events.Event) {
+			// the delays between the events are kept, divided by the speed
+			if speed > 0 && !previous.IsZero() && event.Time.After(previous) {
+				time.Sleep(time.Duration(float64(event.Time.Sub(previous)) / speed))
+			}
+			if !event.Time.IsZero() {
+				previous = event.Time
+			}
+
+			result := replayResult{
+				Time: event.Time,
+				testResult: testResult{
+					Event:    event.Rule,
+					Priority: event.Priority,
+					Source:   event.Source,
+					Matched:  actionners.PlanEvent(event),
+				},
+			}
+			count++
+			if len(result.Matched) != 0 {
+				matched++
+			}
+			for _, i := range result.Matched {
+				rulesCount[i.Rule]++
+				for _, j := range i.Actions {
+					actionnersCount[j.Actionner]++
+				}
+			}
+
+			if format == "json" {
+				b, err := json.Marshal(result)
+				if err != nil {
+					utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "replay"})
+				}
+				fmt.Println(string(b))
+				return
+			}
+			printReplayResult(&result)
+		})
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "replay"})
+		}
+
+		if format == "json" {
+			utils.PrintLog("info", utils.LogLine{Message: "replay", Result: fmt.Sprintf("%v event(s) replayed, %v matched, %v invalid", count, matched, invalid)})
+			return
+		}
+		fmt.Printf("\n%v event(s) replayed, %v matched, %v invalid\n", count, matched, invalid)
+		printReplayCounts("RULE", rulesCount)
+		printReplayCounts("ACTIONNER", actionnersCount)
+	},
+}
+
+// scanReplayEvents calls fn for each event of the archive, the wrong events are skipped and counted
+func scanReplayEvents(r io.Reader, fn func(event *events.Event)) (int, error) {
+	var invalid int
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxReplayLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		event, err := events.DecodeEvent(bytes.NewReader(b))
+		if err != nil {
+			invalid++
+			utils.PrintLog("warning", utils.LogLine{Error: fmt.Sprintf("wrong event at the line %v: %v", line, err), Message: "replay"})
+			continue
+		}
+		fn(event)
+	}
+	return invalid, scanner.Err()
+}
+
+// parseReplaySpeed returns the factor of the speed of the replay (10x, 0.5), 0 to replay without delay
+func parseReplaySpeed(s string) (float64, error) {
+	if s == "" || s == "max" {
+		return 0, nil
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(s), "x"), 64)
+	if err != nil || speed < 0 {
+		return 0, fmt.Errorf("wrong speed '%v', must be a positive factor (eg: 10x) or max", s)
+	}
+	return speed, nil
+}
+
+// openReplayFile opens the archive, '-' for stdin, the gzipped files are detected by their header
+func openReplayFile(file string) (io.ReadCloser, error) {
+	if file == "" {
+		return nil, errors.New("missing file of events")
+	}
+	var f io.ReadCloser = os.Stdin
+	if file != "-" {
+		var err error
+		f, err = os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := bufio.NewReader(f)
+	header, _ := r.Peek(2)
+	if len(header) == 2 && header[0] == 0x1f && header[1] == 0x8b {
+		z, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("wrong gzip file '%v': %v", file, err)
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{z, f}, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, f}, nil
+}
+
+func printReplayResult(result *replayResult) {
+	matched := make([]string, 0, len(result.Matched))
+	for _, i := range result.Matched {
+		actions := make([]string, 0, len(i.Actions))
+		for _, j := range i.Actions {
+			actions = append(actions, j.Actionner)
+		}
+		matched = append(matched, fmt.Sprintf("%v (%v)", i.Rule, strings.Join(actions, ", ")))
+	}
+	if len(matched) == 0 {
+		matched = append(matched, "-")
+	}
+	fmt.Printf("%v  %v [%v] -> %v\n", result.Time.Format(time.RFC3339), result.Event, result.Priority, strings.Join(matched, "; "))
+}
+
+func printReplayCounts(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(counts))
+	for i := range counts {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%v\tCOUNT\n", title)
+	for _, i := range keys {
+		fmt.Fprintf(w, "%v\t%v\n", i, counts[i])
+	}
+	w.Flush()
+}
+
+func init() {
+	replayCmd.Flags().StringP("file", "f", "", "File of the Falco events (NDJSON, gzipped or not), '-' for stdin")
+	replayCmd.Flags().StringP("speed", "s", "max", "Speed of the replay, as a factor of the delays between the events (eg: 10x), max for no delay")
+	replayCmd.Flags().String("format", "text", "Format of the output: text or json (one result per line)")
+	replayCmd.Flags().StringArray("base-rules", nil, "Rules files of the reference, the actions of the rules set with --rules are compared to theirs")
+	replayCmd.Flags().Bool("fail-on-change", false, "Exit with the code 1 if the compared rules change actions")
+	RootCmd.AddCommand(replayCmd)
+}
+
+// actionChange is an action which differs between the two sets of rules for an event
+type actionChange struct {
+	Rule      string `json:"rule"`
+	Action    string `json:"action"`
+	Actionner string `json:"actionner"`
+	Change    string `json:"change"` // added, removed or changed (parameters, targets, output, chaining)
+}
+
+// eventComparison is the machine-readable result of the comparison of two sets of rules for an event
+type eventComparison struct {
+	Time     time.Time      `json:"time"`
+	Event    string         `json:"event"`
+	Priority string         `json:"priority"`
+	Changes  []actionChange `json:"changes"`
+}
+
+// actionnerDelta counts the actions of an actionner for the two sets of rules
+type actionnerDelta struct {
+	Base     int `json:"base"`
+	Compared int `json:"compared"`
+	Added    int `json:"added"`
+	Removed  int `json:"removed"`
+	Changed  int `json:"changed"`
+}
+
+// comparisonReport is the machine-readable report of the comparison of two sets of rules
+type comparisonReport struct {
+	Actionners    map[string]*actionnerDelta `json:"actionners"`
+	Changes       []eventComparison          `json:"changes"`
+	Events        int                        `json:"events"`
+	Invalid       int                        `json:"invalid"`
+	ChangedEvents int                        `json:"changed_events"`
+}
+
+// plannedItem is a planned action with its settings, to compare them
+type plannedItem struct {
+	change      actionChange
+	fingerprint string
+}
+
+// compareRules replays the events with the rules of the reference then with the rules of the config,
+// and reports the actions which would be added, removed or changed, it returns the number of changed events
+func compareRules(r io.Reader, baseRulesFiles []string, config *configuration.Configuration, format string) int {
+	list := make([]*events.Event, 0)
+	invalid, err := scanReplayEvents(r, func(event *events.Event) {
+		list = append(list, event)
+	})
+	if err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "replay"})
+	}
+
+	// the rules are global, the two sets are loaded one after the other
+	base := planReplayEvents(list, baseRulesFiles, config)
+	compared := planReplayEvents(list, config.RulesFiles, config)
+
+	report := comparisonReport{
+		Actionners: map[string]*actionnerDelta{},
+		Changes:    make([]eventComparison, 0),
+		Events:     len(list),
+		Invalid:    invalid,
+	}
+	delta := func(actionner string) *actionnerDelta {
+		if report.Actionners[actionner] == nil {
+			report.Actionners[actionner] = new(actionnerDelta)
+		}
+		return report.Actionners[actionner]
+	}
+
+	for n, event := range list {
+		c := eventComparison{Time: event.Time, Event: event.Rule, Priority: event.Priority, Changes: make([]actionChange, 0)}
+		for k, i := range base[n] {
+			delta(i.change.Actionner).Base++
+			j, ok := compared[n][k]
+			switch {
+			case !ok:
+				i.change.Change = "removed"
+				delta(i.change.Actionner).Removed++
+			case i.fingerprint != j.fingerprint:
+				i.change.Change = "changed"
+				delta(i.change.Actionner).Changed++
+			default:
+				continue
+			}
+			c.Changes = append(c.Changes, i.change)
+		}
+		for k, i := range compared[n] {
+			delta(i.change.Actionner).Compared++
+			if _, ok := base[n][k]; ok {
+				continue
+			}
+			i.change.Change = "added"
+			delta(i.change.Actionner).Added++
+			c.Changes = append(c.Changes, i.change)
+		}
+		if len(c.Changes) == 0 {
+			continue
+		}
+		sort.Slice(c.Changes, func(i, j int) bool {
+			if c.Changes[i].Rule != c.Changes[j].Rule {
+				return c.Changes[i].Rule < c.Changes[j].Rule
+			}
+			return c.Changes[i].Action < c.Changes[j].Action
+		})
+		report.ChangedEvents++
+		report.Changes = append(report.Changes, c)
+	}
+
+	if format == "json" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "replay"})
+		}
+		fmt.Println(string(b))
+	} else {
+		printComparisonReport(&report)
+	}
+	return report.ChangedEvents
+}
+
+// planReplayEvents loads the rules and returns the planned actions of each event, by rule and action
+func planReplayEvents(list []*events.Event, rulesFiles []string, config *configuration.Configuration) []map[string]plannedItem {
+	if ruleengine.ParseRules(rulesFiles, getTenants(config)) == nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("invalid rules in '%v'", strings.Join(rulesFiles, ", ")), Message: "rules"})
+	}
+	plans := make([]map[string]plannedItem, 0, len(list))
+	for _, event := range list {
+		items := map[string]plannedItem{}
+		for _, i := range actionners.PlanEvent(event) {
+			// a same action can be run twice by a rule
+			occurrences := map[string]int{}
+			for _, j := range i.Actions {
+				occurrences[j.Action]++
+				b, _ := json.Marshal(j)
+				items[fmt.Sprintf("%v/%v/%v", i.Rule, j.Action, occurrences[j.Action])] = plannedItem{
+					change:      actionChange{Rule: i.Rule, Action: j.Action, Actionner: j.Actionner},
+					fingerprint: fmt.Sprintf("%v %s", i.DryRun, b),
+				}
+			}
+		}
+		plans = append(plans, items)
+	}
+	return plans
+}
+
+func printComparisonReport(report *comparisonReport) {
+	signs := map[string]string{"added": "+", "removed": "-", "changed": "~"}
+	for _, i := range report.Changes {
+		fmt.Printf("%v  %v [%v]\n", i.Time.Format(time.RFC3339), i.Event, i.Priority)
+		for _, j := range i.Changes {
+			fmt.Printf("  %v %v / %v (%v)\n", signs[j.Change], j.Rule, j.Action, j.Actionner)
+		}
+	}
+
+	fmt.Printf("\n%v event(s) replayed, %v with changed actions, %v invalid\n", report.Events, report.ChangedEvents, report.Invalid)
+	if len(report.Actionners) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(report.Actionners))
+	for i := range report.Actionners {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTIONNER\tBASE\tCOMPARED\tADDED\tREMOVED\tCHANGED")
+	for _, i := range keys {
+		d := report.Actionners[i]
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", i, d.Base, d.Compared, d.Added, d.Removed, d.Changed)
+	}
+	w.Flush()
+}
@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/falco-talon/falco-talon/actionners"
+	"github.com/falco-talon/falco-talon/configuration"
+	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/notifiers"
+	"github.com/falco-talon/falco-talon/outputs"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	findingError   string = "error"
+	findingWarning string = "warning"
+)
+
+// finding is an issue found by the validation of the rules and the config files
+type finding struct {
+	Level     string `json:"level"`
+	File      string `json:"file,omitempty"`
+	Rule      string `json:"rule,omitempty"`
+	Action    string `json:"action,omitempty"`
+	Actionner string `json:"actionner,omitempty"`
+	Notifier  string `json:"notifier,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Message   string `json:"message"`
+}
+
+// validationReport is the machine-readable result of the validation
+type validationReport struct {
+	Config   string    `json:"config,omitempty"`
+	Rules    []string  `json:"rules"`
+	Valid    bool      `json:"valid"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+	Findings []finding `json:"findings"`
+}
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Manage the rules",
+	Long:  "Manage the rules files of Falco Talon",
+}
+
+var rulesValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the rules and the config files",
+	Long: `Validate offline the rules and the config files: syntax, unknown actionners, outputs and notifiers,
+parameters, templates and duplicated names. The exit code is 1 if an error is found (or a warning with --strict).`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		format, _ := cmd.Flags().GetString("format")
+		strict, _ := cmd.Flags().GetBool("strict")
+		configFile, _ := cmd.Flags().GetString("config")
+		rulesFiles, _ := cmd.Flags().GetStringArray("rules")
+
+		format = strings.ToLower(format)
+		if format != "text" && format != "json" {
+			utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("unknown format '%v'", format), Message: "rules"})
+		}
+		if format == "json" {
+			// stdout is kept for the report
+			utils.SetLogOutput(os.Stderr)
+		}
+
+		report := validationReport{Config: configFile}
+		if f := validateConfigFile(configFile); f != nil {
+			report.Findings = append(report.Findings, *f)
+			printValidationReport(&report, format, strict)
+			return
+		}
+
+		config := configuration.CreateConfiguration(configFile)
+		if format == "text" {
+			utils.SetLogFormat(config.LogFormat)
+		}
+		if len(rulesFiles) != 0 {
+			config.RulesFiles = rulesFiles
+		}
+		report.Rules = config.RulesFiles
+
+		report.Findings = append(report.Findings, validateConfig(config)...)
+		for _, i := range config.RulesFiles {
+			report.Findings = append(report.Findings, findDuplicates(i)...)
+		}
+		for _, i := range getTenants(config) {
+			for _, j := range i.RulesFiles {
+				report.Findings = append(report.Findings, findDuplicates(j)...)
+			}
+		}
+
+		// the errors of the parsing are logged, they are captured as findings
+		utils.SetLogHook(func(level string, line utils.LogLine) {
+			if level != findingError && level != findingWarning {
+				return
+			}
+			report.Findings = append(report.Findings, finding{
+				Level:   level,
+				Rule:    line.Rule,
+				Action:  line.Action,
+				Message: line.Error,
+			})
+		})
+		rules := ruleengine.ParseRules(config.RulesFiles, getTenants(config))
+		utils.SetLogHook(nil)
+
+		if rules != nil {
+			report.Findings = append(report.Findings, validateRules(rules, config)...)
+		}
+		printValidationReport(&report, format, strict)
+	},
+}
+
+// validateConfigFile checks the syntax of the config file, CreateConfiguration exits if it can't be read
+func validateConfigFile(configFile string) *finding {
+	if configFile == "" {
+		return nil
+	}
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		return &finding{Level: findingError, File: configFile, Message: err.Error()}
+	}
+	var v map[string]interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return &finding{Level: findingError, File: configFile, Message: fmt.Sprintf("wrong syntax for the config file: %v", err)}
+	}
+	return nil
+}
+
+// validateConfig checks the notifiers of the config and their templates
+func validateConfig(config *configuration.Configuration) []finding {
+	findings := make([]finding, 0)
+	availableNotifiers := notifiers.GetAvailableNotifiers()
+	for _, i := range config.DefaultNotifiers {
+		if availableNotifiers.FindNotifier(i) == nil {
+			findings = append(findings, finding{Level: findingError, Notifier: i, Message: "unknown default notifier"})
+		}
+	}
+	for i, j := range config.Notifiers {
+		if availableNotifiers.FindNotifier(i) == nil {
+			findings = append(findings, finding{Level: findingWarning, Notifier: i, Message: "settings for an unknown notifier"})
+			continue
+		}
+		for _, k := range sortedKeys(j) {
+			if err := checkTemplate(j[k]); err != nil {
+				findings = append(findings, finding{Level: findingError, Notifier: i, Message: fmt.Sprintf("wrong template for '%v': %v", k, err)})
+			}
+		}
+	}
+	return findings
+}
+
+// validateRules checks the actionners, the outputs and the notifiers of the parsed rules
+func validateRules(rules *[]*ruleengine.Rule, config *configuration.Configuration) []finding {
+	findings := make([]finding, 0)
+	defaultActionners := actionners.GetDefaultActionners()
+	availableNotifiers := notifiers.GetAvailableNotifiers()
+
+	for _, i := range *rules {
+		for _, j := range i.GetActions() {
+			actionner := defaultActionners.FindActionner(j.GetActionner())
+			if actionner == nil {
+				findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Action: j.GetName(), Actionner: j.GetActionner(), Message: "unknown actionner"})
+				continue
+			}
+			if err := actionners.CheckTenantAction(i, j); err != nil {
+				findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Action: j.GetName(), Actionner: j.GetActionner(), Message: err.Error()})
+			}
+			if actionner.CheckParameters != nil {
+				if err := actionner.CheckParameters(j); err != nil {
+					findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Action: j.GetName(), Actionner: j.GetActionner(), Message: err.Error()})
+				}
+			}
+			o := j.GetOutput()
+			if o == nil {
+				if actionner.IsOutputRequired() {
+					findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Action: j.GetName(), Actionner: j.GetActionner(), Message: "an output is required"})
+				}
+				continue
+			}
+			if err := outputs.CheckOutput(o); err != nil {
+				findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Action: j.GetName(), Target: o.GetTarget(), Message: err.Error()})
+			}
+			for _, k := range sortedKeys(o.Parameters) {
+				if err := checkTemplate(o.Parameters[k]); err != nil {
+					findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Action: j.GetName(), Target: o.GetTarget(), Message: fmt.Sprintf("wrong template for '%v': %v", k, err)})
+				}
+			}
+		}
+		if r := i.GetReport(); r != nil {
+			if err := outputs.CheckOutput(&r.Output); err != nil {
+				findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Target: r.Output.GetTarget(), Message: err.Error()})
+			}
+			for _, k := range sortedKeys(r.Output.Parameters) {
+				if err := checkTemplate(r.Output.Parameters[k]); err != nil {
+					findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Target: r.Output.GetTarget(), Message: fmt.Sprintf("wrong template for '%v': %v", k, err)})
+				}
+			}
+		}
+		for _, j := range i.GetNotifiers() {
+			if availableNotifiers.FindNotifier(j) == nil {
+				findings = append(findings, finding{Level: findingError, Rule: i.GetName(), Notifier: j, Message: "unknown notifier"})
+				continue
+			}
+			if config == nil {
+				continue
+			}
+			if t, ok := config.GetTenant(i.GetTenant()); ok {
+				if t.Notifiers[j] == nil {
+					findings = append(findings, finding{Level: findingWarning, Rule: i.GetName(), Notifier: j, Message: fmt.Sprintf("no settings for the notifier in the tenant '%v'", i.GetTenant())})
+				}
+				continue
+			}
+			if config.Notifiers[j] == nil {
+				findings = append(findings, finding{Level: findingWarning, Rule: i.GetName(), Notifier: j, Message: "no settings for the notifier in the config"})
+			}
+		}
+	}
+	return findings
+}
+
+// checkRules logs the findings of the validation of the rules, it returns false if an error is found
+func checkRules(rules *[]*ruleengine.Rule, config *configuration.Configuration) bool {
+	valid := true
+	for _, i := range validateRules(rules, config) {
+		utils.PrintLog(i.Level, i.toLogLine())
+		if i.Level == findingError {
+			valid = false
+		}
+	}
+	return valid
+}
+
+// findDuplicates reports the rules and the actions declared more than once in the same file
+func findDuplicates(file string) []finding {
+	findings := make([]finding, 0)
+	rules, actions, err := ruleengine.FindDuplicates(file)
+	if err != nil {
+		// the error is reported by the parsing of the rules
+		return findings
+	}
+	for _, i := range rules {
+		findings = append(findings, finding{Level: findingWarning, File: file, Rule: i, Message: "rule declared more than once in the file, the declarations are merged"})
+	}
+	for _, i := range actions {
+		findings = append(findings, finding{Level: findingWarning, File: file, Action: i, Message: "action declared more than once in the file, the declarations are merged"})
+	}
+	return findings
+}
+
+// checkTemplate checks the syntax of a value if it's a template, the functions aren't checked
+// because they depend on the notifier or the output rendering it
+func checkTemplate(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || !strings.Contains(s, "{{") {
+		return nil
+	}
+	t := parse.New("template")
+	t.Mode = parse.SkipFuncCheck
+	_, err := t.Parse(s, "", "", make(map[string]*parse.Tree))
+	return err
+}
+
+// getTenants returns the tenants of the config, sorted by name, with their rule files and their impersonation
+func getTenants(config *configuration.Configuration) []ruleengine.Tenant {
+	tenants := make([]ruleengine.Tenant, 0, len(config.Tenants))
+	for i, j := range config.Tenants {
+		t := ruleengine.Tenant{Name: i, RulesFiles: j.RulesFiles}
+		if j.Impersonate.User != "" || j.Impersonate.ServiceAccount != "" {
+			t.Impersonate = &ruleengine.Impersonation{
+				User:           j.Impersonate.User,
+				ServiceAccount: j.Impersonate.ServiceAccount,
+				Groups:         j.Impersonate.Groups,
+			}
+		}
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Name < tenants[j].Name })
+	return tenants
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for i := range m {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printValidationReport(report *validationReport, format string, strict bool) {
+	if report.Findings == nil {
+		report.Findings = make([]finding, 0)
+	}
+	for _, i := range report.Findings {
+		if i.Level == findingError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.Valid = report.Errors == 0 && (!strict || report.Warnings == 0)
+
+	if format == "json" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "rules"})
+		}
+		fmt.Println(string(b))
+	} else {
+		for _, i := range report.Findings {
+			utils.PrintLog(i.Level, i.toLogLine())
+		}
+		if report.Valid {
+			utils.PrintLog("info", utils.LogLine{Result: "rules and config files valid", Message: "rules"})
+		} else {
+			utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("invalid rules or config files: %v error(s), %v warning(s)", report.Errors, report.Warnings), Message: "rules"})
+		}
+	}
+	if !report.Valid {
+		os.Exit(1)
+	}
+}
+
+func (f finding) toLogLine() utils.LogLine {
+	line := utils.LogLine{
+		Error:     f.Message,
+		Rule:      f.Rule,
+		Action:    f.Action,
+		Actionner: f.Actionner,
+		Notifier:  f.Notifier,
+		Target:    f.Target,
+		Message:   "rules",
+	}
+	if f.File != "" {
+		line.Objects = map[string]string{"file": f.File}
+	}
+	return line
+}
+
+func init() {
+	rulesValidateCmd.Flags().StringP("format", "f", "text", "Format of the output: text or json")
+	rulesValidateCmd.Flags().Bool("strict", false, "Fail on the warnings too")
+	rulesCmd.AddCommand(rulesValidateCmd)
+	RootCmd.AddCommand(rulesCmd)
+}
@@ -1,18 +1,52 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"github.com/falco-talon/falco-talon/actionners"
 	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/async"
+	"github.com/falco-talon/falco-talon/internal/audit"
+	"github.com/falco-talon/falco-talon/internal/certificates"
+	"github.com/falco-talon/falco-talon/internal/control"
+	"github.com/falco-talon/falco-talon/internal/deadletter"
+	"github.com/falco-talon/falco-talon/internal/falco"
 	"github.com/falco-talon/falco-talon/internal/handler"
+	"github.com/falco-talon/falco-talon/internal/health"
+	"github.com/falco-talon/falco-talon/internal/history"
+	"github.com/falco-talon/falco-talon/internal/holds"
+	"github.com/falco-talon/falco-talon/internal/incidents"
+	"github.com/falco-talon/falco-talon/internal/jetstream"
+	"github.com/falco-talon/falco-talon/internal/kafka"
 	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/internal/kubernetes/expiry"
+	"github.com/falco-talon/falco-talon/internal/labels"
 	"github.com/falco-talon/falco-talon/internal/nats"
+	"github.com/falco-talon/falco-talon/internal/otlp"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/policy"
+	"github.com/falco-talon/falco-talon/internal/pubsub"
 	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/internal/secrets"
+	"github.com/falco-talon/falco-talon/internal/sqs"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+	"github.com/falco-talon/falco-talon/internal/tracing"
+	"github.com/falco-talon/falco-talon/internal/undo"
+	vault "github.com/falco-talon/falco-talon/internal/vault/client"
 	"github.com/falco-talon/falco-talon/metrics"
 	"github.com/falco-talon/falco-talon/notifiers"
 	"github.com/falco-talon/falco-talon/outputs"
@@ -21,6 +55,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// exporters of the metrics and the logs, flushed on shutdown
+var (
+	otlpClient *otlp.Client
+	otlpLogs   *otlp.Logs
+)
+
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Start Falco Talon",
@@ -29,62 +69,40 @@ var serverCmd = &cobra.Command{
 		configFile, _ := cmd.Flags().GetString("config")
 		config := configuration.CreateConfiguration(configFile)
 		utils.SetLogFormat(config.LogFormat)
+		if err := utils.SetLogLevels(config.LogLevel, config.LogLevels); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "config"})
+		}
+
+		// the TLS policy, the proxy and the CA bundle are set before the init of the clients, the ones of the secrets included
+		if err := tlspolicy.Init(config.TLSPolicy); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "tls policy"})
+		}
+		if tlspolicy.IsFIPS() {
+			utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("fips mode enabled (boringcrypto: %v)", tlspolicy.IsBoringCrypto()), Message: "tls policy"})
+		}
+		if err := outbound.Init(config.Outbound); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "outbound"})
+		}
+
+		// the references to the secrets are resolved before the init of the clients which use them
+		providers, err := getSecretsProviders(config.Secrets)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "secrets"})
+		}
+		if err := secrets.Init(config, providers...); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "secrets"})
+		}
+
 		rulesFiles, _ := cmd.Flags().GetStringArray("rules")
 		if len(rulesFiles) != 0 {
 			config.RulesFiles = rulesFiles
 		}
-		rules := ruleengine.ParseRules(config.RulesFiles)
+		rules := ruleengine.ParseRules(config.RulesFiles, getTenants(config))
 		if rules == nil {
 			utils.PrintLog("fatal", utils.LogLine{Error: "invalid rules", Message: "rules"})
 		}
 
-		defaultActionners := actionners.GetDefaultActionners()
-		defaultOutputs := outputs.GetDefaultOutputs()
-
-		valid := true
-		if rules != nil {
-			for _, i := range *rules {
-				for _, j := range i.GetActions() {
-					actionner := defaultActionners.FindActionner(j.GetActionner())
-					if actionner == nil {
-						utils.PrintLog("error", utils.LogLine{Error: "unknown actionner", Rule: i.GetName(), Action: j.GetName(), Actionner: j.GetActionner(), Message: "rules"})
-						valid = false
-					} else {
-						if actionner.CheckParameters != nil {
-							if err := actionner.CheckParameters(j); err != nil {
-								utils.PrintLog("error", utils.LogLine{Error: err.Error(), Rule: i.GetName(), Action: j.GetName(), Actionner: j.GetActionner(), Message: "rules"})
-								valid = false
-							}
-						}
-					}
-					if actionner != nil {
-						o := j.GetOutput()
-						if o == nil && actionner.IsOutputRequired() {
-							utils.PrintLog("error", utils.LogLine{Error: "an output is required", Rule: i.GetName(), Action: j.GetName(), Actionner: j.GetActionner(), Message: "rules"})
-							valid = false
-						}
-						if o != nil {
-							output := defaultOutputs.FindOutput(o.GetTarget())
-							if output == nil {
-								utils.PrintLog("error", utils.LogLine{Error: "unknown target", Rule: i.GetName(), Action: j.GetName(), Target: o.GetTarget(), Message: "rules"})
-								valid = false
-							}
-							if len(o.Parameters) == 0 {
-								utils.PrintLog("error", utils.LogLine{Error: "missing parameters for the output", Rule: i.GetName(), Action: j.GetName(), Target: o.GetTarget(), Message: "rules"})
-								valid = false
-							}
-							if output != nil && output.CheckParameters != nil {
-								if err := output.CheckParameters(o); err != nil {
-									utils.PrintLog("error", utils.LogLine{Error: err.Error(), Rule: i.GetName(), Action: j.GetName(), Target: o.GetTarget(), Message: "rules"})
-									valid = false
-								}
-							}
-						}
-					}
-				}
-			}
-		}
-		if !valid {
+		if !checkRules(rules, nil) {
 			utils.PrintLog("fatal", utils.LogLine{Error: "invalid rules", Message: "rules"})
 		}
 
@@ -93,32 +111,106 @@ var serverCmd = &cobra.Command{
 			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "actionners"})
 		}
 
+		// the actions are checked by the guard policy before their execution
+		if config.GuardPolicy.Enabled {
+			if err := policy.Init(config.GuardPolicy); err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "policy"})
+			}
+		}
+
 		// init outputs
 		if err := outputs.Init(); err != nil {
 			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "outputs"})
 		}
 
+		if err := labels.Init(config.FieldLabelsFile); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "labels"})
+		}
+
 		// init notifiers
 		notifiers.Init()
+		secrets.OnChange(notifiers.Reload)
+
+		// init the correlation of the events into incidents
+		if config.Incidents.Enabled {
+			incidents.Init(time.Duration(config.Incidents.TimeWindowSeconds)*time.Second, config.Incidents.MaxEvents)
+		}
 
 		if rules != nil {
 			utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("%v rule(s) has/have been successfully loaded", len(*rules)), Message: "init"})
 		}
 
-		http.HandleFunc("/", handler.MainHandler)
-		http.HandleFunc("/healthz", handler.HealthHandler)
-		http.HandleFunc("/rules", handler.RulesHandler)
-		http.Handle("/metrics", metrics.Handler())
+		// the health and metrics endpoints stay reachable without credentials for the probes
+		protect := func(h http.HandlerFunc) http.HandlerFunc {
+			h = handler.Authenticate(h)
+			if config.TLS.Enabled && config.TLS.ClientCAFile != "" {
+				h = handler.RequireClientCert(config.TLS.AllowedCommonNames, h)
+			}
+			return h
+		}
+
+		// a dedicated mux, the default one gets the handlers of net/http/pprof
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", protect(handler.MainHandler))
+		mux.HandleFunc("/events/batch", protect(handler.BatchHandler))
+		mux.HandleFunc("/healthz", handler.HealthHandler)
+		mux.HandleFunc("/readyz", handler.ReadyHandler)
+		mux.HandleFunc("/rules", handler.RulesHandler)
+		mux.Handle("/metrics", metrics.Handler())
+		mux.HandleFunc("GET /deadletters", protect(handler.DeadLettersHandler))
+		mux.HandleFunc("/deadletters/{id}", protect(handler.DeadLetterHandler))
+		mux.HandleFunc("GET /api/v1/history", protect(handler.HistoryHandler))
+		mux.HandleFunc("GET /api/v1/history/{id}", protect(handler.HistoryEntryHandler))
+		mux.HandleFunc("GET /api/v1/slo", protect(handler.SLOHandler))
+		mux.HandleFunc("GET /undo", protect(handler.UndoHandler))
+		mux.HandleFunc("GET /api/v1/holds", protect(handler.HoldsHandler))
+		mux.HandleFunc("/api/v1/holds/{id}", protect(handler.HoldHandler))
+		mux.HandleFunc("GET /api/v1/actions", protect(handler.ActionsHandler))
+		mux.HandleFunc("GET /api/v1/actions/{id}", protect(handler.ActionHandler))
+		mux.HandleFunc("GET /api/v1/log-levels", protect(handler.LogLevelsHandler))
+		mux.HandleFunc("GET /api/v1/stream", protect(handler.StreamHandler))
+
+		// the admin API has its own credentials, it isn't served without them
+		if config.Admin.IsSet() {
+			if len(config.Admin.AllowedCommonNames) != 0 && (!config.TLS.Enabled || config.TLS.ClientCAFile == "") {
+				utils.PrintLog("fatal", utils.LogLine{Error: "`admin.allowed_common_names` requires `tls.client_ca_file`", Message: "admin"})
+			}
+			mux.HandleFunc("GET /api/v1/rules", handler.RequireAdmin(handler.AdminRulesHandler))
+			mux.HandleFunc("PATCH /api/v1/rules/{name}", handler.RequireAdmin(handler.AdminRuleHandler))
+			mux.HandleFunc("GET /api/v1/queue", handler.RequireAdmin(handler.AdminQueueHandler))
+			mux.HandleFunc("GET /api/v1/approvals", handler.RequireAdmin(handler.AdminApprovalsHandler))
+			mux.HandleFunc("POST /api/v1/actions", handler.RequireAdmin(handler.RunActionHandler))
+			mux.HandleFunc("POST /deadletters/{id}/redrive", handler.RequireAdmin(handler.RedriveHandler))
+			mux.HandleFunc("POST /undo/{id}", handler.RequireAdmin(handler.RevertHandler))
+			mux.HandleFunc("POST /api/v1/holds/{id}/release", handler.RequireAdmin(handler.ReleaseHandler))
+			mux.HandleFunc("PUT /api/v1/log-levels", handler.RequireAdmin(handler.SetLogLevelsHandler))
+		} else {
+			utils.PrintLog("warning", utils.LogLine{Result: "no admin credential, the admin API is disabled", Message: "admin"})
+			if config.ManualActions.Enabled {
+				utils.PrintLog("warning", utils.LogLine{Result: "the manual actions require an admin credential, they're disabled", Message: "manual"})
+			}
+		}
 
 		if config.WatchRules {
 			utils.PrintLog("info", utils.LogLine{Result: "watch of rules enabled", Message: "init"})
 		}
+		if config.WatchConfig && configFile != "" {
+			utils.PrintLog("info", utils.LogLine{Result: "watch of config enabled", Message: "init"})
+		}
 
 		srv := http.Server{
 			Addr:         fmt.Sprintf("%s:%d", config.ListenAddress, config.ListenPort),
 			ReadTimeout:  2 * time.Second,
 			WriteTimeout: 2 * time.Second,
-			Handler:      nil,
+			Handler:      mux,
+		}
+
+		if config.TLS.Enabled {
+			reloader, err := certificates.NewReloader(config.TLS)
+			if err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "tls"})
+			}
+			srv.TLSConfig = reloader.GetTLSConfig()
 		}
 
 		if config.WatchRules {
@@ -130,7 +222,11 @@ var serverCmd = &cobra.Command{
 					return
 				}
 				defer watcher.Close()
-				for _, i := range config.RulesFiles {
+				files := append([]string{}, config.RulesFiles...)
+				for _, i := range getTenants(config) {
+					files = append(files, i.RulesFiles...)
+				}
+				for _, i := range files {
 					if err := watcher.Add(i); err != nil {
 						utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "rules"})
 						return
@@ -146,64 +242,21 @@ var serverCmd = &cobra.Command{
 								ignore = false
 							}()
 							utils.PrintLog("info", utils.LogLine{Result: "changes detected", Message: "rules"})
-							newRules := ruleengine.ParseRules(config.RulesFiles)
+							newRules := ruleengine.ParseRules(config.RulesFiles, getTenants(config))
 							if newRules == nil {
 								utils.PrintLog("error", utils.LogLine{Error: "invalid rules", Message: "rules"})
 								break
 							}
 
-							defaultActionners := actionners.GetDefaultActionners()
-							defaultOutputs := outputs.GetDefaultOutputs()
-
-							if newRules != nil {
-								valid := true
-								for _, i := range *newRules {
-									for _, j := range i.GetActions() {
-										actionner := defaultActionners.FindActionner(j.GetActionner())
-										if actionner == nil {
-											break
-										}
-										if actionner.CheckParameters != nil {
-											if err := actionner.CheckParameters(j); err != nil {
-												utils.PrintLog("error", utils.LogLine{Error: err.Error(), Rule: i.GetName(), Message: "rules"})
-												valid = false
-											}
-										}
-										o := j.GetOutput()
-										if o == nil && actionner.IsOutputRequired() {
-											utils.PrintLog("error", utils.LogLine{Error: "an output is required", Rule: i.GetName(), Action: j.GetName(), Actionner: j.GetActionner(), Message: "rules"})
-											valid = false
-										}
-										if o != nil {
-											output := defaultOutputs.FindOutput(o.GetTarget())
-											if output == nil {
-												utils.PrintLog("error", utils.LogLine{Error: "unknown target", Rule: i.GetName(), Action: j.GetName(), Target: o.GetTarget(), Message: "rules"})
-												valid = false
-											}
-											if len(o.Parameters) == 0 {
-												utils.PrintLog("error", utils.LogLine{Error: "missing parameters for the output", Rule: i.GetName(), Action: j.GetName(), Target: o.GetTarget(), Message: "rules"})
-												valid = false
-											}
-											if output != nil && output.CheckParameters != nil {
-												if err := output.CheckParameters(o); err != nil {
-													utils.PrintLog("error", utils.LogLine{Error: err.Error(), Rule: i.GetName(), Action: j.GetName(), Target: o.GetTarget(), Message: "rules"})
-													valid = false
-												}
-											}
-										}
-									}
-
-									if !valid {
-										utils.PrintLog("error", utils.LogLine{Error: "invalid rules", Message: "rules"})
-										break
-									}
-									utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("%v rules have been successfully loaded", len(*rules)), Message: "rules"})
-									rules = newRules
-									if err := actionners.Init(); err != nil {
-										utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "actionners"})
-										break
-									}
-								}
+							if !checkRules(newRules, nil) {
+								utils.PrintLog("error", utils.LogLine{Error: "invalid rules", Message: "rules"})
+								break
+							}
+							utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("%v rules have been successfully loaded", len(*newRules)), Message: "rules"})
+							rules = newRules
+							if err := actionners.Init(); err != nil {
+								utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "actionners"})
+								break
 							}
 						}
 					case err := <-watcher.Errors:
@@ -214,7 +267,15 @@ var serverCmd = &cobra.Command{
 		}
 
 		// start the local NATS
-		ns, err := nats.StartServer(config.Deduplication.TimeWindowSeconds)
+		var persistence *nats.Persistence
+		if config.Persistence.Enabled {
+			persistence = &nats.Persistence{
+				StoreDir: config.Persistence.StoreDir,
+				MaxAge:   time.Duration(config.Persistence.MaxAgeHours) * time.Hour,
+				AckWait:  time.Duration(config.Persistence.AckWaitSeconds) * time.Second,
+			}
+		}
+		ns, err := nats.StartServer(config.Deduplication.TimeWindowSeconds, persistence)
 		if err != nil {
 			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "nats"})
 		}
@@ -245,21 +306,388 @@ var serverCmd = &cobra.Command{
 			}()
 		}
 
+		// checks of the health and readiness endpoints
+		health.Register("rules", true, func() error {
+			if r := ruleengine.GetRules(); r == nil || len(*r) == 0 {
+				return errors.New("no rules loaded")
+			}
+			return nil
+		})
+		health.Register("queue", true, func() error {
+			if err := nats.GetConsumer().Check(); err != nil {
+				return fmt.Errorf("consumer: %v", err)
+			}
+			if err := nats.GetPublisher().Check(); err != nil {
+				return fmt.Errorf("publisher: %v", err)
+			}
+			return nil
+		})
+		health.Register("kubernetes", false, func() error {
+			if !k8s.IsInitialized() {
+				return health.ErrDisabled
+			}
+			return k8s.GetClient().Ping()
+		})
+		health.Register("notifiers", false, notifiers.Check)
+
+		// init the dead-letter queue, after the nats for the jetstream store
+		if err := deadletter.Init(config.DeadLetter); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "deadletter"})
+		}
+
+		// export the spans of the processing of the events
+		if err := tracing.Init(config.Tracing); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "tracing"})
+		}
+
+		// push the metrics and the logs to an OpenTelemetry collector
+		if config.OTLP.Metrics.Enabled || config.OTLP.Logs.Enabled {
+			if config.OTLP.Metrics.IntervalSeconds <= 0 || config.OTLP.Logs.IntervalSeconds <= 0 {
+				utils.PrintLog("fatal", utils.LogLine{Error: "wrong `interval_seconds` setting", Message: "otlp"})
+			}
+			otlpClient = otlp.NewClient(config.OTLP.Endpoint, config.OTLP.Headers)
+			if config.OTLP.Metrics.Enabled {
+				metrics.StartExport(otlpClient, time.Duration(config.OTLP.Metrics.IntervalSeconds)*time.Second)
+			}
+			if config.OTLP.Logs.Enabled {
+				otlpLogs = otlp.NewLogs(otlpClient, time.Duration(config.OTLP.Logs.IntervalSeconds)*time.Second)
+				utils.SetLogHook(otlpLogs.Add)
+			}
+		}
+
+		// open the audit log before the processing of the events
+		if config.Audit.Enabled {
+			if err := audit.Init(config.Audit.File); err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "audit"})
+			}
+		}
+
+		// remove the expired containment resources
+		if config.Expiry.Enabled {
+			if err := expiry.Start(time.Duration(config.Expiry.IntervalSeconds) * time.Second); err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "expiry"})
+			}
+		}
+
+		// init the undo of the reversible actions, after the nats for the jetstream store
+		if err := undo.Init(config.Undo, actionners.Revert); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "undo"})
+		}
+
+		// the actions held by the blast-radius limits are run once released by an operator
+		holds.Init(time.Duration(config.BlastRadius.HoldMaxAgeHours)*time.Hour, actionners.Release)
+
+		// the status of the actions run in the background is kept for the API
+		async.Init(time.Duration(config.AsyncActions.MaxAgeHours)*time.Hour, time.Duration(config.AsyncActions.ProgressIntervalSeconds)*time.Second)
+
+		// init the history of the actions, after the nats for the jetstream store
+		if err := history.Init(config.History); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "history"})
+		}
+
 		// start the consumer for the actionners
-		c, err := nats.GetConsumer().ConsumeMsg()
+		c, err := nats.GetConsumer().ConsumeMsg(config.Ingestion.MaxQueueSize)
 		if err != nil {
 			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "nats"})
 		}
 		go actionners.StartConsumer(c)
 
+		// subscribe to the gRPC output of Falco
+		if config.FalcoGrpc.Enabled {
+			go falco.StartSubscriber()
+		}
+
+		// consume the events from kafka
+		if config.Kafka.Enabled {
+			go kafka.StartConsumer()
+		}
+
+		// consume the events from an external jetstream
+		if config.JetStream.Enabled {
+			if err := jetstream.StartSource(); err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "jetstream"})
+			}
+		}
+
+		// poll the events from sqs
+		if config.SQS.Enabled {
+			if err := sqs.StartSource(); err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "sqs"})
+			}
+		}
+
+		// pull the events from pubsub
+		if config.PubSub.Enabled {
+			if err := pubsub.StartSource(); err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "pubsub"})
+			}
+		}
+
 		utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("Falco Talon is up and listening on %s:%d", config.ListenAddress, config.ListenPort), Message: "http"})
 
-		if err := srv.ListenAndServe(); err != nil {
-			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "http"})
+		go func() {
+			var err error
+			if config.TLS.Enabled {
+				// the certificates are provided by the tls config of the server
+				err = srv.ListenAndServeTLS("", "")
+			} else {
+				err = srv.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "http"})
+			}
+		}()
+
+		// the diagnostics are on their own listener, not reachable with the events
+		if config.Diagnostics.Enabled {
+			if len(config.Diagnostics.BearerTokens) == 0 {
+				utils.PrintLog("warning", utils.LogLine{Result: "the diagnostics endpoint is enabled without authentication", Message: "diagnostics"})
+			}
+			diag := http.Server{
+				Addr:         fmt.Sprintf("%s:%d", config.Diagnostics.ListenAddress, config.Diagnostics.ListenPort),
+				Handler:      handler.DiagnosticsHandler(config.Diagnostics.BearerTokens),
+				ReadTimeout:  2 * time.Second,
+				WriteTimeout: 2 * time.Minute, // for the cpu profiles and the traces
+			}
+			go func() {
+				utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("diagnostics listening on %v", diag.Addr), Message: "init"})
+				if err := diag.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "diagnostics"})
+				}
+			}()
+		}
+
+		// the gRPC control API is on its own listener, with the same TLS and the same credentials as the http server,
+		// the management of the rules requires the admin credentials
+		if config.GrpcServer.Enabled {
+			grpcSrv := http.Server{
+				Addr:              fmt.Sprintf("%s:%d", config.GrpcServer.ListenAddress, config.GrpcServer.ListenPort),
+				Handler:           control.Handler(protect),
+				ReadHeaderTimeout: 2 * time.Second, // no write timeout for the streams of the results
+				TLSConfig:         srv.TLSConfig.Clone(),
+			}
+			if config.TLS.Enabled {
+				// gRPC requires HTTP/2, negotiated with ALPN
+				if err := http2.ConfigureServer(&grpcSrv, &http2.Server{}); err != nil {
+					utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "control"})
+				}
+			}
+			go func() {
+				utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("gRPC control API listening on %v", grpcSrv.Addr), Message: "init"})
+				var err error
+				if config.TLS.Enabled {
+					err = grpcSrv.ListenAndServeTLS("", "")
+				} else {
+					// gRPC requires HTTP/2, without TLS too
+					grpcSrv.Handler = h2c.NewHandler(grpcSrv.Handler, &http2.Server{})
+					err = grpcSrv.ListenAndServe()
+				}
+				if err != nil && !errors.Is(err, http.ErrServerClosed) {
+					utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "control"})
+				}
+			}()
 		}
+
+		// reload the settings from the config file on SIGHUP or when it changes
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		if config.WatchConfig && configFile != "" {
+			go watchConfig(configFile, reload)
+		}
+		go func() {
+			for range reload {
+				if err := reloadConfiguration(configFile, rulesFiles); err != nil {
+					utils.PrintLog("error", utils.LogLine{Error: err.Error(), Result: "the current configuration is kept", Message: "config"})
+				}
+			}
+		}()
+
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		s := <-signals
+
+		shutdown(&srv, time.Duration(configuration.GetConfiguration().ShutdownTimeout)*time.Second)
+		utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("Falco Talon stopped after the signal '%v'", s), Message: "shutdown"})
 	},
 }
 
+// shutdown rejects the new events, waits for the running actions and flushes the notifiers,
+// all the steps share the timeout
+func shutdown(srv *http.Server, timeout time.Duration) {
+	utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("draining the events, timeout of %v", timeout), Message: "shutdown"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	handler.StopIngestion()
+	if err := srv.Shutdown(ctx); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "shutdown"})
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := actionners.Drain(time.Until(deadline)); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "shutdown"})
+	}
+
+	notifiers.Flush()
+
+	// the last spans, metrics and logs are exported within the extra delay of the grace period
+	if err := tracing.Shutdown(5 * time.Second); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "tracing"})
+	}
+	if otlpClient != nil && configuration.GetConfiguration().OTLP.Metrics.Enabled {
+		if err := metrics.Export(otlpClient, 5*time.Second); err != nil {
+			utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "otlp"})
+		}
+	}
+	if otlpLogs != nil {
+		otlpLogs.Flush(5 * time.Second)
+	}
+}
+
+// reloadConfiguration reads again the config file and applies the settings which don't require a restart,
+// the secrets, the signing key and the notifiers are checked before the swap of the configuration
+func reloadConfiguration(configFile string, rulesFiles []string) error {
+	next, err := configuration.LoadConfiguration(configFile)
+	if err != nil {
+		return err
+	}
+	if len(rulesFiles) != 0 {
+		next.RulesFiles = rulesFiles
+	}
+	if err := utils.CheckLogLevels(next.LogLevel, next.LogLevels); err != nil {
+		return err
+	}
+	setSecrets, err := secrets.Reload(next)
+	if err != nil {
+		return fmt.Errorf("secrets: %v", err)
+	}
+	// the mapping file is read at each reload, it can change without the config file
+	setLabels, err := labels.Reload(next.FieldLabelsFile)
+	if err != nil {
+		return fmt.Errorf("field labels: %v", err)
+	}
+
+	reloaded, ignored := configuration.PrepareReload(next)
+	if len(ignored) != 0 {
+		utils.PrintLog("warning", utils.LogLine{Result: fmt.Sprintf("a restart is required to apply the changes of: %v", strings.Join(ignored, ", ")), Message: "config"})
+	}
+	if len(reloaded) == 0 {
+		// the log levels changed with the API are reset to those of the config file
+		if err := utils.SetLogLevels(next.LogLevel, next.LogLevels); err != nil {
+			return err
+		}
+		setLabels()
+		utils.PrintLog("info", utils.LogLine{Result: "no change to reload", Message: "config"})
+		return nil
+	}
+
+	setSigner := func() {}
+	if slices.Contains(reloaded, "integrity") {
+		if setSigner, err = outputs.ReloadSigner(next.Integrity); err != nil {
+			return fmt.Errorf("integrity: %v", err)
+		}
+	}
+	if slices.Contains(reloaded, "notifiers") || slices.Contains(reloaded, "default_notifiers") || slices.Contains(reloaded, "notifier_limits") || slices.Contains(reloaded, "templates") || slices.Contains(reloaded, "routing") {
+		if err := notifiers.Update(next); err != nil {
+			return fmt.Errorf("notifiers: %v", err)
+		}
+	}
+
+	configuration.SetConfiguration(next)
+	setSecrets()
+	setSigner()
+	setLabels()
+	utils.SetLogFormat(next.LogFormat)
+	if err := utils.SetLogLevels(next.LogLevel, next.LogLevels); err != nil {
+		return err
+	}
+	utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("settings reloaded: %v", strings.Join(reloaded, ", ")), Message: "config"})
+	return nil
+}
+
+// watchConfig triggers a reload when the config file changes, its directory is watched for the
+// files mounted from a ConfigMap or a Secret, which are replaced by a symlink swap
+func watchConfig(configFile string, reload chan<- os.Signal) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "config"})
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "config"})
+		return
+	}
+
+	// the editors and the swaps of the symlinks trigger several events, the reload waits for the last one
+	var timer *time.Timer
+	for {
+		select {
+		case event := <-watcher.Events:
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			if filepath.Clean(event.Name) != filepath.Clean(configFile) && filepath.Base(event.Name) != "..data" {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(1*time.Second, func() {
+				utils.PrintLog("info", utils.LogLine{Result: "changes detected", Message: "config"})
+				select {
+				case reload <- syscall.SIGHUP:
+				default:
+				}
+			})
+		case err := <-watcher.Errors:
+			utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "config"})
+		}
+	}
+}
+
+// getSecretsProviders returns the enabled secrets providers, selected by the references of the settings
+func getSecretsProviders(config configuration.SecretsConfig) ([]secrets.Provider, error) {
+	providers := make([]secrets.Provider, 0)
+	if config.Vault.Enabled {
+		p, err := vault.NewClient(config.Vault)
+		if err != nil {
+			return nil, fmt.Errorf("vault: %v", err)
+		}
+		providers = append(providers, p)
+	}
+	if config.AwsSecretsManager.Enabled {
+		p, err := secrets.NewAWSSecretsManager(config.AwsSecretsManager)
+		if err != nil {
+			return nil, fmt.Errorf("aws secrets manager: %v", err)
+		}
+		providers = append(providers, p)
+	}
+	if config.AwsSSM.Enabled {
+		p, err := secrets.NewAWSSSM(config.AwsSSM)
+		if err != nil {
+			return nil, fmt.Errorf("aws ssm: %v", err)
+		}
+		providers = append(providers, p)
+	}
+	if config.GcpSecretManager.Enabled {
+		p, err := secrets.NewGCPSecretManager(config.GcpSecretManager)
+		if err != nil {
+			return nil, fmt.Errorf("gcp secret manager: %v", err)
+		}
+		providers = append(providers, p)
+	}
+	if config.AzureKeyVault.Enabled {
+		p, err := secrets.NewAzureKeyVault(config.AzureKeyVault)
+		if err != nil {
+			return nil, fmt.Errorf("azure key vault: %v", err)
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
 func init() {
 	RootCmd.AddCommand(serverCmd)
 }
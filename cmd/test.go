@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/falco-talon/falco-talon/actionners"
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+	"github.com/falco-talon/falco-talon/notifiers"
+	"github.com/falco-talon/falco-talon/outputs"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+// testResult is the machine-readable result of the test of an event
+type testResult struct {
+	Event    string                   `json:"event"`
+	Priority string                   `json:"priority"`
+	Source   string                   `json:"source"`
+	Matched  []actionners.PlannedRule `json:"matched"`
+}
+
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Test the rules with a sample event",
+	Long: `Evaluate the rules with a sample Falco event (JSON payload) and print the matching rules and the actions
+they would run, with their parameters. The actions are run with the config if --dry-run isn't set.`,
+	Run: func(cmd *cobra.Command, _ []string) {
+		eventFile, _ := cmd.Flags().GetString("event")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		format, _ := cmd.Flags().GetString("format")
+
+		format = strings.ToLower(format)
+		if format != "text" && format != "json" {
+			utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("unknown format '%v'", format), Message: "test"})
+		}
+		if format == "json" {
+			// stdout is kept for the result
+			utils.SetLogOutput(os.Stderr)
+		}
+
+		configFile, _ := cmd.Flags().GetString("config")
+		config := configuration.CreateConfiguration(configFile)
+		utils.SetLogFormat(config.LogFormat)
+		rulesFiles, _ := cmd.Flags().GetStringArray("rules")
+		if len(rulesFiles) != 0 {
+			config.RulesFiles = rulesFiles
+		}
+		rules := ruleengine.ParseRules(config.RulesFiles, getTenants(config))
+		if rules == nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: "invalid rules", Message: "rules"})
+		}
+
+		event, err := readTestEvent(eventFile)
+		if err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "test"})
+		}
+
+		result := testResult{
+			Event:    event.Rule,
+			Priority: event.Priority,
+			Source:   event.Source,
+			Matched:  actionners.PlanEvent(event),
+		}
+		if format == "json" {
+			b, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "test"})
+			}
+			fmt.Println(string(b))
+		} else {
+			printTestResult(&result)
+		}
+
+		if dryRun || len(result.Matched) == 0 {
+			return
+		}
+
+		if err := tlspolicy.Init(config.TLSPolicy); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "tls policy"})
+		}
+		if err := outbound.Init(config.Outbound); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "outbound"})
+		}
+		if err := actionners.Init(); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "actionners"})
+		}
+		if err := outputs.Init(); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "outputs"})
+		}
+		notifiers.Init()
+		actionners.ProcessEvent(event)
+	},
+}
+
+func readTestEvent(file string) (*events.Event, error) {
+	if file == "" {
+		return nil, errors.New("missing event file")
+	}
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	event, err := events.DecodeEvent(r)
+	if err != nil {
+		return nil, fmt.Errorf("wrong event in '%v': %v", file, err)
+	}
+	return event, nil
+}
+
+func printTestResult(result *testResult) {
+	fmt.Printf("event: %v (priority: %v, source: %v)\n", result.Event, result.Priority, result.Source)
+	if len(result.Matched) == 0 {
+		fmt.Println("no rule matches the event")
+		return
+	}
+	for _, i := range result.Matched {
+		dryRun := ""
+		if i.DryRun {
+			dryRun = " (dry-run)"
+		}
+		fmt.Printf("\nrule: %v%v\n", i.Rule, dryRun)
+		for n, j := range i.Actions {
+			fmt.Printf("  %v. action: %v\n", n+1, j.Action)
+			if !j.Known {
+				fmt.Printf("     actionner: %v (unknown)\n", j.Actionner)
+			} else {
+				fmt.Printf("     actionner: %v\n", j.Actionner)
+			}
+			printTestValue("parameters", j.Parameters)
+			printTestValue("targets", j.Targets)
+			if j.Output != "" {
+				fmt.Printf("     output: %v\n", j.Output)
+				printTestValue("output parameters", j.OutputParameters)
+			}
+			if len(j.AdditionalContexts) != 0 {
+				fmt.Printf("     additional contexts: %v\n", strings.Join(j.AdditionalContexts, ", "))
+			}
+			if j.Cluster != "" {
+				fmt.Printf("     cluster: %v\n", j.Cluster)
+			}
+			if j.ImpersonateUser != "" || len(j.ImpersonateGroups) != 0 {
+				fmt.Printf("     impersonate: %v %v\n", j.ImpersonateUser, strings.Join(j.ImpersonateGroups, ","))
+			}
+			if !j.Continue {
+				fmt.Println("     the next actions don't run")
+			} else if !j.IgnoreErrors {
+				fmt.Println("     the next actions run only if this one succeeds")
+			}
+		}
+		if !i.Continue {
+			fmt.Println("\nthe next rules aren't evaluated")
+		}
+	}
+}
+
+func printTestValue(name string, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil || string(b) == "null" || string(b) == "{}" {
+		return
+	}
+	fmt.Printf("     %v: %v\n", name, string(b))
+}
+
+func init() {
+	testCmd.Flags().StringP("event", "e", "", "File of the Falco event (JSON), '-' for stdin")
+	testCmd.Flags().Bool("dry-run", false, "Print the matching rules and their actions without running them")
+	testCmd.Flags().StringP("format", "f", "text", "Format of the output: text or json")
+	RootCmd.AddCommand(testCmd)
+}
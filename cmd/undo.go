@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/falco-talon/falco-talon/internal/undo"
+	"github.com/falco-talon/falco-talon/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Manage the reversible actions",
+	Long:  "List or revert the reversible actions (labels, cordons, networkpolicies) of a running Falco Talon",
+}
+
+var undoListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the reversible actions",
+	Long:  "List the reversible actions",
+	Run: func(cmd *cobra.Command, _ []string) {
+		b := callAPI(cmd, "undo", http.MethodGet, "/undo")
+		var list []undo.Entry
+		if err := json.Unmarshal(b, &list); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "undo"})
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tRULE\tACTION\tACTIONNER\tREVERT AT")
+		for _, i := range list {
+			revertAt := "-"
+			if i.RevertAt != nil {
+				revertAt = i.RevertAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", i.ID, i.Time.Format(time.RFC3339), i.Rule, i.Action, i.Actionner, revertAt)
+		}
+		w.Flush()
+	},
+}
+
+var undoRevertCmd = &cobra.Command{
+	Use:   "revert [id]",
+	Short: "Revert a reversible action",
+	Long:  "Restore the state before a reversible action, the entry is removed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := undo.CheckID(args[0]); err != nil {
+			utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "undo"})
+		}
+		fmt.Println(string(callAdminAPI(cmd, "undo", http.MethodPost, "/undo/"+args[0])))
+	},
+}
+
+func init() {
+	undoCmd.PersistentFlags().StringP("address", "a", "http://localhost:2803", "Address of Falco Talon")
+	undoCmd.PersistentFlags().Bool("insecure", false, "Skip the verification of the certificate of Falco Talon")
+	undoRevertCmd.Flags().String("admin-token", "", "Admin token, the first token of `admin.tokens` if empty")
+	undoCmd.AddCommand(undoListCmd, undoRevertCmd)
+	RootCmd.AddCommand(undoCmd)
+}
@@ -1,22 +1,61 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/falco-talon/falco-talon/actionners"
 	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/notifiers"
+	"github.com/falco-talon/falco-talon/utils"
 
 	"github.com/spf13/cobra"
 )
 
+// versionInfo is the version of Falco Talon with the actionners and the notifiers it's built with
+type versionInfo struct {
+	*configuration.Info
+	Actionners []string `json:"actionners"`
+	Notifiers  []string `json:"notifiers"`
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version of Falco Talon.",
-	Long:  "Print version of Falco Talon",
-	Run: func(_ *cobra.Command, _ []string) {
-		fmt.Println(configuration.GetInfo().String())
+	Long:  "Print version of Falco Talon, with the actionners and the notifiers it's built with",
+	Run: func(cmd *cobra.Command, _ []string) {
+		output, _ := cmd.Flags().GetString("output")
+		info := versionInfo{
+			Info:       configuration.GetInfo(),
+			Actionners: make([]string, 0),
+			Notifiers:  make([]string, 0),
+		}
+		for _, i := range *actionners.GetDefaultActionners() {
+			info.Actionners = append(info.Actionners, i.GetFullName())
+		}
+		for _, i := range *notifiers.GetAvailableNotifiers() {
+			info.Notifiers = append(info.Notifiers, i.Name)
+		}
+
+		switch strings.ToLower(output) {
+		case "text":
+			fmt.Print(info.String())
+			fmt.Printf("Actionners:    %s\n", strings.Join(info.Actionners, ", "))
+			fmt.Printf("Notifiers:     %s\n", strings.Join(info.Notifiers, ", "))
+		case "json":
+			b, err := json.MarshalIndent(info, "", "  ")
+			if err != nil {
+				utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "version"})
+			}
+			fmt.Println(string(b))
+		default:
+			utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("unknown output '%v'", output), Message: "version"})
+		}
 	},
 }
 
 func init() {
+	versionCmd.Flags().StringP("output", "o", "text", "Format of the output: text or json")
 	RootCmd.AddCommand(versionCmd)
 }
@@ -1,11 +1,19 @@
 package configuration
 
 import (
+	"bytes"
 	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"slices"
 	"strings"
+	"sync/atomic"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 
+	"github.com/falco-talon/falco-talon/internal/sops"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
@@ -14,29 +22,658 @@ const (
 	defaultListPort                    int    = 2803
 	defaultRulesFile                   string = "/etc/falco-talon/rules.yaml"
 	defaultWatchRules                  bool   = true
+	defaultWatchConfig                 bool   = true
 	defaultPrintAllEvents              bool   = false
 	defaultDeduplicationLeaderElection bool   = true
 	defaultDeduplicationTimeWindow     int    = 5
+	defaultLeaseName                   string = "falco-talon"
+	defaultLeaseDuration               int    = 4
+	defaultRenewDeadline               int    = 3
+	defaultRetryPeriod                 int    = 2
+	defaultIncidentsTimeWindow         int    = 300
+	defaultIncidentsMaxEvents          int    = 1000
+	defaultStoreDir                    string = "/var/lib/falco-talon"
+	defaultPersistenceMaxAge           int    = 24
+	defaultPersistenceAckWait          int    = 300
+	defaultDeadLetterMaxAge            int    = 168
+	defaultFailureThreshold            int    = 5
+	defaultLockBackend                 string = "lease"
+	defaultLockTTL                     int    = 60
+	defaultVerificationTimeout         int    = 60
+	defaultVerificationInterval        int    = 2
+	defaultAsyncProgressInterval       int    = 30
+	defaultAsyncMaxAge                 int    = 24
+	defaultIdempotencyBackend          string = "local"
+	defaultIdempotencyTTL              int    = 600
+	defaultRedisKeyPrefix              string = "falco-talon:event:"
+	defaultShutdownTimeout             int    = 30
+	defaultHistoryMaxAge               int    = 30
+	defaultUndoMaxAge                  int    = 720
+	defaultHoldMaxAge                  int    = 24
+	defaultGuardPolicyTimeout          int    = 2
+	defaultExpiryInterval              int    = 60
+	defaultKubernetesCacheResync       int    = 600
+	defaultKubernetesQPS               int    = 5
+	defaultKubernetesBurst             int    = 10
+	defaultKubernetesMaxRetries        int    = 5
+	defaultKubernetesInitialBackoff    int    = 250
+	defaultKubernetesMaxBackoff        int    = 30
+	defaultTracingEndpoint             string = "http://localhost:4318"
+	defaultOTLPMetricsInterval         int    = 30
+	defaultOTLPLogsInterval            int    = 5
+	defaultDiagnosticsAddress          string = "127.0.0.1"
+	defaultDiagnosticsPort             int    = 6060
+	defaultGrpcServerPort              int    = 2804
+	envPrefix                          string = "TALON_"
+	defaultSecretsRefreshInterval      int    = 300
+	defaultVaultAuthMethod             string = "kubernetes"
+	defaultServiceAccountTokenFile     string = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	defaultAuditFile                   string = "/var/lib/falco-talon/audit.log"
+	defaultOpenDuration                int    = 60
+	defaultMaxQueueSize                int    = 1000
+	defaultRetryAfter                  int    = 5
+	defaultCriticalWorkers             int    = 2
+	defaultHMACHeader                  string = "X-Signature"
+	defaultHMACTimestampHeader         string = "X-Timestamp"
+	defaultHMACTolerance               int    = 300
+	defaultFalcoGrpcAddress            string = "unix:///run/falco/falco.sock"
+	defaultKafkaTopic                  string = "falco"
+	defaultKafkaConsumerGroup          string = "falco-talon"
+	defaultKafkaAutoOffsetReset        string = "latest"
+	defaultJetStreamSubject            string = "falco.>"
+	defaultJetStreamQueueGroup         string = "falco-talon"
+	defaultJetStreamDeliverPolicy      string = "all"
+	defaultJetStreamAckWait            int    = 30
+	defaultSQSMaxMessages              int    = 10
+	defaultSQSWaitTime                 int    = 20
+	defaultPubSubMaxMessages           int    = 10
 )
 
 type Configuration struct {
 	Notifiers        map[string]map[string]interface{} `mapstructure:"notifiers"`
+	Digests          map[string]DigestConfig           `mapstructure:"digests"`
+	NotifierLimits   map[string]NotifierLimitsConfig   `mapstructure:"notifier_limits"`
+	Templates        TemplatesConfig                   `mapstructure:"templates"`
+	Attachments      map[string]AttachmentsConfig      `mapstructure:"attachments"`
+	Routing          map[string]RoutingConfig          `mapstructure:"routing"`
+	Integrity        IntegrityConfig                   `mapstructure:"integrity"`
+	Incidents        incidents                         `mapstructure:"incidents"`
+	TLS              ServerTLSConfig                   `mapstructure:"tls"`
+	Authentication   AuthenticationConfig              `mapstructure:"authentication"`
+	Admin            AdminConfig                       `mapstructure:"admin"`
+	Ingestion        IngestionConfig                   `mapstructure:"ingestion"`
+	Concurrency      ConcurrencyConfig                 `mapstructure:"concurrency"`
+	Persistence      PersistenceConfig                 `mapstructure:"persistence"`
+	DeadLetter       DeadLetterConfig                  `mapstructure:"deadletter"`
+	History          HistoryConfig                     `mapstructure:"history"`
+	Undo             UndoConfig                        `mapstructure:"undo"`
+	Expiry           ExpiryConfig                      `mapstructure:"expiry"`
+	Audit            AuditConfig                       `mapstructure:"audit"`
+	KubernetesEvents KubernetesEventsConfig            `mapstructure:"kubernetes_events"`
+	KubernetesCache  KubernetesCacheConfig             `mapstructure:"kubernetes_cache"`
+	KubernetesClient KubernetesClientConfig            `mapstructure:"kubernetes_client"`
+	MultiCluster     MultiClusterConfig                `mapstructure:"multi_cluster"`
+	Namespaces       []string                          `mapstructure:"namespaces"`
+	Guardrails       GuardrailsConfig                  `mapstructure:"guardrails"`
+	BlastRadius      BlastRadiusConfig                 `mapstructure:"blast_radius"`
+	GuardPolicy      GuardPolicyConfig                 `mapstructure:"guard_policy"`
+	Tenants          map[string]TenantConfig           `mapstructure:"tenants"`
+	Tracing          TracingConfig                     `mapstructure:"tracing"`
+	OTLP             OTLPConfig                        `mapstructure:"otlp"`
+	Diagnostics      DiagnosticsConfig                 `mapstructure:"diagnostics"`
+	GrpcServer       GrpcServerConfig                  `mapstructure:"grpc_server"`
+	Secrets          SecretsConfig                     `mapstructure:"secrets"`
+	Outbound         OutboundConfig                    `mapstructure:"outbound"`
+	TLSPolicy        TLSPolicyConfig                   `mapstructure:"tls_policy"`
+	Retries          map[string]RetryPolicy            `mapstructure:"retries"`
+	CircuitBreaker   CircuitBreakerConfig              `mapstructure:"circuit_breaker"`
+	ActionLocks      ActionLocksConfig                 `mapstructure:"action_locks"`
+	Idempotency      IdempotencyConfig                 `mapstructure:"idempotency"`
+	Verification     VerificationConfig                `mapstructure:"verification"`
+	AsyncActions     AsyncActionsConfig                `mapstructure:"async_actions"`
+	ManualActions    ManualActionsConfig               `mapstructure:"manual_actions"`
+	FalcoGrpc        FalcoGrpcConfig                   `mapstructure:"falco_grpc"`
+	Kafka            KafkaConfig                       `mapstructure:"kafka"`
+	JetStream        JetStreamConfig                   `mapstructure:"jetstream"`
+	SQS              SQSConfig                         `mapstructure:"sqs"`
+	PubSub           PubSubConfig                      `mapstructure:"pubsub"`
 	AwsConfig        AwsConfig                         `mapstructure:"aws"`
+	AzureConfig      AzureConfig                       `mapstructure:"azure"`
+	GcpConfig        GcpConfig                         `mapstructure:"gcp"`
 	MinioConfig      MinioConfig                       `mapstructure:"minio"`
+	LogLevels        map[string]string                 `mapstructure:"log_levels"`
 	LogFormat        string                            `mapstructure:"log_format"`
+	LogLevel         string                            `mapstructure:"log_level"`
 	KubeConfig       string                            `mapstructure:"kubeconfig"`
+	KubeContext      string                            `mapstructure:"kube_context"`
 	ListenAddress    string                            `mapstructure:"listen_address"`
 	RulesFiles       []string                          `mapstructure:"rules_files"`
 	DefaultNotifiers []string                          `mapstructure:"default_notifiers"`
+	FieldLabelsFile  string                            `mapstructure:"field_labels_file"` // labels, order and visibility of the fields in the notifications and the reports
 	ListenPort       int                               `mapstructure:"listen_port"`
 	Deduplication    deduplication                     `mapstructure:"deduplication"`
 	WatchRules       bool                              `mapstructure:"watch_rules"`
+	WatchConfig      bool                              `mapstructure:"watch_config"`
 	PrintAllEvents   bool                              `mapstructure:"print_all_events"`
+	ShutdownTimeout  int                               `mapstructure:"shutdown_timeout_seconds"`
 }
 
-type deduplication struct {
-	LeaderElection    bool `mapstructure:"leader_election"`
+type incidents struct {
+	Enabled           bool `mapstructure:"enabled"`
 	TimeWindowSeconds int  `mapstructure:"time_window_seconds"`
+	MaxEvents         int  `mapstructure:"max_events"`
+}
+
+type deduplication struct {
+	LeaseName            string `mapstructure:"lease_name"`
+	LeaderElection       bool   `mapstructure:"leader_election"`
+	TimeWindowSeconds    int    `mapstructure:"time_window_seconds"`
+	LeaseDurationSeconds int    `mapstructure:"lease_duration_seconds"`
+	RenewDeadlineSeconds int    `mapstructure:"renew_deadline_seconds"`
+	RetryPeriodSeconds   int    `mapstructure:"retry_period_seconds"`
+}
+
+// DigestConfig buffers the notifications of low priority events and sends them as a single message
+type DigestConfig struct {
+	ImmediatePriority string   `mapstructure:"immediate_priority"`
+	ImmediateRules    []string `mapstructure:"immediate_rules"`
+	IntervalMinutes   int      `mapstructure:"interval_minutes"`
+}
+
+// NotifierLimitsConfig protects the actions from a slow or unreachable notifier
+type NotifierLimitsConfig struct {
+	DeadLetterNotifier string  `mapstructure:"dead_letter_notifier"`
+	RateLimit          float64 `mapstructure:"rate_limit"`
+	Burst              int     `mapstructure:"burst"`
+	TimeoutSeconds     int     `mapstructure:"timeout_seconds"`
+	MaxConcurrent      int     `mapstructure:"max_concurrent"`
+	FailureThreshold   int     `mapstructure:"failure_threshold"`
+	OpenSeconds        int     `mapstructure:"open_seconds"`
+}
+
+// AttachmentsConfig adds the evidence to the notifications of a notifier, the responders reach it from the message
+type AttachmentsConfig struct {
+	Event     bool `mapstructure:"event"`     // the raw JSON of the event
+	Artifacts bool `mapstructure:"artifacts"` // the links to the artifacts stored by the outputs, eg: the pre-signed urls
+}
+
+// RoutingConfig selects the destinations of the notifications of a notifier with the priority and the namespace of
+// the events, holds the low priorities during the quiet hours and escalates the repeated failures of the actions
+type RoutingConfig struct {
+	Routes     []RouteConfig    `mapstructure:"routes"`
+	QuietHours QuietHoursConfig `mapstructure:"quiet_hours"`
+	Escalation EscalationConfig `mapstructure:"escalation"`
+}
+
+// RouteConfig overrides the settings of the notifier for the matching events, the first matching route is used
+type RouteConfig struct {
+	Settings    map[string]interface{} `mapstructure:"settings"`     // eg: channel, webhook_url
+	MinPriority string                 `mapstructure:"min_priority"` // eg: critical
+	Namespaces  []string               `mapstructure:"namespaces"`   // eg: prod-*
+}
+
+// QuietHoursConfig holds the notifications of the low priority events during the night
+type QuietHoursConfig struct {
+	Start       string `mapstructure:"start"`        // eg: 22:00
+	End         string `mapstructure:"end"`          // eg: 07:00
+	Timezone    string `mapstructure:"timezone"`     // eg: Europe/Paris
+	MaxPriority string `mapstructure:"max_priority"` // the highest held priority, eg: warning
+	Mode        string `mapstructure:"mode"`         // drop or queue
+}
+
+// EscalationConfig sends the notifications of the repeated failures of an action to another destination too
+type EscalationConfig struct {
+	Settings      map[string]interface{} `mapstructure:"settings"`
+	Failures      int                    `mapstructure:"failures"` // consecutive failures of the action of a rule, 0 disables the escalation
+	WindowMinutes int                    `mapstructure:"window_minutes"`
+}
+
+// TemplatesConfig sets the templates of the messages of the notifiers, shared by all of them
+type TemplatesConfig struct {
+	Partials  map[string]string `mapstructure:"partials"`  // templates usable by the others with {{ template "name" . }}
+	Notifiers map[string]string `mapstructure:"notifiers"` // message template by notifier, overrides the default one
+	Message   string            `mapstructure:"message"`   // default message template, the notifiers keep their format if empty
+	Directory string            `mapstructure:"directory"` // directory of *.tmpl files, eg: a mounted ConfigMap
+}
+
+// IntegrityConfig enables the manifests of the artifacts stored by the outputs
+type IntegrityConfig struct {
+	SigningKeyFile string `mapstructure:"signing_key_file"`
+	Manifest       bool   `mapstructure:"manifest"`
+}
+
+// ServerTLSConfig enables the TLS and the authentication of the clients by certificate for the server
+type ServerTLSConfig struct {
+	CertFile           string   `mapstructure:"cert_file"`
+	KeyFile            string   `mapstructure:"key_file"`
+	ClientCAFile       string   `mapstructure:"client_ca_file"`
+	AllowedCommonNames []string `mapstructure:"allowed_common_names"`
+	Enabled            bool     `mapstructure:"enabled"`
+}
+
+// AuthenticationConfig sets the credentials accepted to send events, bearer tokens or secrets of HMAC signatures
+type AuthenticationConfig struct {
+	HMACHeader           string   `mapstructure:"hmac_header"`
+	HMACTimestampHeader  string   `mapstructure:"hmac_timestamp_header"`
+	BearerTokens         []string `mapstructure:"bearer_tokens"`
+	HMACSecrets          []string `mapstructure:"hmac_secrets"`
+	HMACToleranceSeconds int      `mapstructure:"hmac_tolerance_seconds"`
+}
+
+// AdminConfig sets the credentials of the admin API, separated from the ones to send events, the admin routes
+// aren't registered without them
+type AdminConfig struct {
+	Tokens             []AdminToken `mapstructure:"tokens"`
+	AllowedCommonNames []string     `mapstructure:"allowed_common_names"`
+}
+
+// AdminToken is a bearer token of the admin API, its name is the identity of the requester
+type AdminToken struct {
+	Name  string `mapstructure:"name"`
+	Token string `mapstructure:"token"`
+}
+
+// IsSet returns true if an admin credential is set
+func (c AdminConfig) IsSet() bool {
+	for _, i := range c.Tokens {
+		if i.Name != "" && i.Token != "" {
+			return true
+		}
+	}
+	return len(c.AllowedCommonNames) != 0
+}
+
+// IngestionConfig protects Falco Talon from the storms of events
+type IngestionConfig struct {
+	RateLimit         float64       `mapstructure:"rate_limit"`
+	Burst             int           `mapstructure:"burst"`
+	MaxQueueSize      int           `mapstructure:"max_queue_size"`
+	RetryAfterSeconds int           `mapstructure:"retry_after_seconds"`
+	Workers           WorkersConfig `mapstructure:"workers"`
+}
+
+// PersistenceConfig stores the queue of the events on disk, the events survive the restarts
+type PersistenceConfig struct {
+	StoreDir       string `mapstructure:"store_dir"`
+	MaxAgeHours    int    `mapstructure:"max_age_hours"`
+	AckWaitSeconds int    `mapstructure:"ack_wait_seconds"`
+	Enabled        bool   `mapstructure:"enabled"`
+}
+
+// RetryPolicy retries the actions which fail with a transient error
+type RetryPolicy struct {
+	RetryableErrors  []string `mapstructure:"retryable_errors"`
+	Multiplier       float64  `mapstructure:"multiplier"`
+	Jitter           float64  `mapstructure:"jitter"`
+	MaxAttempts      int      `mapstructure:"max_attempts"`
+	InitialBackoffMs int      `mapstructure:"initial_backoff_ms"`
+	MaxBackoffMs     int      `mapstructure:"max_backoff_ms"`
+}
+
+// CircuitBreakerConfig stops the actions of an actionner after consecutive failures, the rules only notify until it closes
+type CircuitBreakerConfig struct {
+	Enabled             bool `mapstructure:"enabled"`
+	FailureThreshold    int  `mapstructure:"failure_threshold"`
+	OpenDurationSeconds int  `mapstructure:"open_duration_seconds"`
+}
+
+// GuardrailsConfig downgrades the destructive actions to notifications for the protected namespaces and
+// pods, whatever the rules, a wrong rule can't terminate the critical workloads
+type GuardrailsConfig struct {
+	Namespaces     []string `mapstructure:"namespaces"`
+	LabelSelectors []string `mapstructure:"label_selectors"`
+	Actionners     []string `mapstructure:"actionners"`
+}
+
+// BlastRadiusConfig limits the number of destructive actions, the actions beyond the limits are held and
+// notified, until an operator releases them
+type BlastRadiusConfig struct {
+	Limits               []BlastRadiusLimit `mapstructure:"limits"`
+	MaxResourcesPerEvent int                `mapstructure:"max_resources_per_event"`
+	HoldMaxAgeHours      int                `mapstructure:"hold_max_age_hours"`
+}
+
+// BlastRadiusLimit is a maximum number of actions of an actionner, by namespace or for the cluster, in a time window
+type BlastRadiusLimit struct {
+	Actionner     string `mapstructure:"actionner"`
+	Scope         string `mapstructure:"scope"`
+	MaxActions    int    `mapstructure:"max_actions"`
+	WindowSeconds int    `mapstructure:"window_seconds"`
+}
+
+// GuardPolicyConfig queries a policy of an OPA server before the actions, with the proposed action and its
+// target, the policy can veto them whatever the rules
+type GuardPolicyConfig struct {
+	URL            string   `mapstructure:"url"`
+	BearerToken    string   `mapstructure:"bearer_token"`
+	CACertFile     string   `mapstructure:"ca_cert_file"`
+	Actionners     []string `mapstructure:"actionners"`
+	TimeoutSeconds int      `mapstructure:"timeout_seconds"`
+	FailOpen       bool     `mapstructure:"fail_open"`
+	Enabled        bool     `mapstructure:"enabled"`
+}
+
+// TenantConfig binds rule files to the namespaces of a team, with its own notifier settings and the actionners
+// allowed to its rules, the rules of a tenant can't act outside of its namespaces
+type TenantConfig struct {
+	Notifiers   map[string]map[string]interface{} `mapstructure:"notifiers"`
+	Impersonate TenantImpersonation               `mapstructure:"impersonate"`
+	RulesFiles  []string                          `mapstructure:"rules_files"`
+	Namespaces  []string                          `mapstructure:"namespaces"`
+	Actionners  []string                          `mapstructure:"actionners"`
+}
+
+// TenantImpersonation is the identity used by the Kubernetes actions of the rules of the tenant, its RBAC
+// limits them to the namespaces of the tenant in the cluster too
+type TenantImpersonation struct {
+	User           string   `mapstructure:"user"`
+	ServiceAccount string   `mapstructure:"service_account"` // namespace/name
+	Groups         []string `mapstructure:"groups"`
+}
+
+// ActionLocksConfig prevents the concurrent remediations of a same resource by a same rule
+type ActionLocksConfig struct {
+	Backend    string `mapstructure:"backend"`
+	Enabled    bool   `mapstructure:"enabled"`
+	TTLSeconds int    `mapstructure:"ttl_seconds"`
+}
+
+// VerificationConfig checks the effect of the actions after their run (pod gone, networkpolicy present, node cordoned),
+// within a bounded wait, the acceptance of the request by the API isn't enough for a verified remediation
+type VerificationConfig struct {
+	Enabled         bool `mapstructure:"enabled"` // the `verify` setting of an action overrides it
+	TimeoutSeconds  int  `mapstructure:"timeout_seconds"`
+	IntervalSeconds int  `mapstructure:"interval_seconds"`
+}
+
+// AsyncActionsConfig runs the long actions (kubernetes:drain, kubernetes:tcpdump) in the background, the workers
+// don't wait for them, their status is returned by the API with their id
+type AsyncActionsConfig struct {
+	Enabled                 bool `mapstructure:"enabled"`
+	ProgressIntervalSeconds int  `mapstructure:"progress_interval_seconds"` // minimal delay between two notifications of the progress
+	MaxAgeHours             int  `mapstructure:"max_age_hours"`             // the ended actions are forgotten after this delay
+}
+
+// ManualActionsConfig allows the responders to run the actionners through the API, outside of the rules,
+// the guardrails, the guard policy and the blast-radius limits still apply
+type ManualActionsConfig struct {
+	Actionners []string `mapstructure:"actionners"` // the allowed actionners (category:name), all if empty
+	Enabled    bool     `mapstructure:"enabled"`
+}
+
+// IdempotencyConfig drops the events with a Falco uuid already received during the TTL, eg: the retries of
+// falcosidekick or the same event sent by several outputs of Falco, the redis backend is shared by the instances
+type IdempotencyConfig struct {
+	Backend    string      `mapstructure:"backend"`
+	Redis      RedisConfig `mapstructure:"redis"`
+	Enabled    bool        `mapstructure:"enabled"`
+	TTLSeconds int         `mapstructure:"ttl_seconds"`
+}
+
+// RedisConfig is the connection to a redis server
+type RedisConfig struct {
+	Address    string `mapstructure:"address"` // host:port
+	Username   string `mapstructure:"username"`
+	Password   string `mapstructure:"password"`
+	KeyPrefix  string `mapstructure:"key_prefix"`
+	CACertFile string `mapstructure:"ca_cert_file"`
+	DB         int    `mapstructure:"db"`
+	TLS        bool   `mapstructure:"tls"`
+}
+
+// DeadLetterConfig stores the events with a failed action, to re-drive them later
+type DeadLetterConfig struct {
+	Store       string `mapstructure:"store"`
+	Directory   string `mapstructure:"directory"`
+	MaxAgeHours int    `mapstructure:"max_age_hours"`
+}
+
+// HistoryConfig stores the results of the actions, to query them with the API
+type HistoryConfig struct {
+	Store      string `mapstructure:"store"`
+	Directory  string `mapstructure:"directory"`
+	MaxAgeDays int    `mapstructure:"max_age_days"`
+}
+
+// UndoConfig stores the state before the reversible actions, to revert them
+type UndoConfig struct {
+	Store       string `mapstructure:"store"`
+	Directory   string `mapstructure:"directory"`
+	MaxAgeHours int    `mapstructure:"max_age_hours"`
+}
+
+// ExpiryConfig removes the resources and labels created by the actionners once their ttl is expired
+type ExpiryConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	IntervalSeconds int  `mapstructure:"interval_seconds"`
+}
+
+// KubernetesEventsConfig creates an event on the targeted pod and its workload for each executed action
+type KubernetesEventsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// KubernetesCacheConfig reads the pods, the workloads and the nodes from shared informers instead
+// of getting them from the API server for each event, it can be disabled for the small clusters
+type KubernetesCacheConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	ResyncSeconds int  `mapstructure:"resync_seconds"`
+}
+
+// KubernetesClientConfig limits the rate of the requests to the API servers, to not throttle the other
+// controllers during the alert storms, the requests rejected by the priority and fairness are retried
+type KubernetesClientConfig struct {
+	QPS               float32 `mapstructure:"qps"`
+	Burst             int     `mapstructure:"burst"`
+	MaxRetries        int     `mapstructure:"max_retries"`
+	InitialBackoffMs  int     `mapstructure:"initial_backoff_ms"`
+	MaxBackoffSeconds int     `mapstructure:"max_backoff_seconds"`
+}
+
+// MultiClusterConfig allows the actions to target other clusters than the one of the kubeconfig,
+// the cluster is set by the rule or by a field of the event
+type MultiClusterConfig struct {
+	Clusters       map[string]ClusterConfig `mapstructure:"clusters"`
+	EventField     string                   `mapstructure:"event_field"`
+	DefaultCluster string                   `mapstructure:"default_cluster"`
+}
+
+// ClusterConfig is a cluster the actions can target, the context of the kubeconfig is used
+type ClusterConfig struct {
+	KubeConfig string `mapstructure:"kubeconfig"`
+	Context    string `mapstructure:"context"`
+}
+
+// TracingConfig exports the spans of the processing of the events to an OTLP/HTTP endpoint
+type TracingConfig struct {
+	Headers     map[string]string `mapstructure:"headers"`
+	Endpoint    string            `mapstructure:"endpoint"`
+	SampleRatio float64           `mapstructure:"sample_ratio"`
+	Enabled     bool              `mapstructure:"enabled"`
+}
+
+// OTLPConfig pushes the metrics and the logs to an OTLP/HTTP endpoint, besides the Prometheus endpoint and stdout
+type OTLPConfig struct {
+	Headers  map[string]string `mapstructure:"headers"`
+	Endpoint string            `mapstructure:"endpoint"`
+	Metrics  OTLPMetricsConfig `mapstructure:"metrics"`
+	Logs     OTLPLogsConfig    `mapstructure:"logs"`
+}
+
+type OTLPMetricsConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	IntervalSeconds int  `mapstructure:"interval_seconds"`
+}
+
+type OTLPLogsConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	IntervalSeconds int  `mapstructure:"interval_seconds"`
+}
+
+// DiagnosticsConfig exposes pprof and the runtime stats on a separate listener
+type DiagnosticsConfig struct {
+	ListenAddress string   `mapstructure:"listen_address"`
+	BearerTokens  []string `mapstructure:"bearer_tokens"`
+	ListenPort    int      `mapstructure:"listen_port"`
+	Enabled       bool     `mapstructure:"enabled"`
+}
+
+// GrpcServerConfig exposes the control API with gRPC, with the TLS and the authentication of the http server
+type GrpcServerConfig struct {
+	ListenAddress string `mapstructure:"listen_address"`
+	ListenPort    int    `mapstructure:"listen_port"`
+	Enabled       bool   `mapstructure:"enabled"`
+}
+
+// OutboundConfig sets the proxy and the CA bundle of the outbound connections (notifiers, outputs, clouds,
+// secrets), the HTTPS_PROXY, HTTP_PROXY and NO_PROXY env vars are used if the proxy isn't set
+type OutboundConfig struct {
+	HTTPProxy  string `mapstructure:"http_proxy"`
+	HTTPSProxy string `mapstructure:"https_proxy"`
+	NoProxy    string `mapstructure:"no_proxy"`
+	CACertFile string `mapstructure:"ca_cert_file"` // CAs trusted in addition to the ones of the system
+}
+
+// TLSPolicyConfig restricts the TLS versions and the cipher suites of the server and of the outbound connections,
+// the FIPS mode allows only the FIPS approved settings, it's always enabled in the builds with BoringCrypto
+type TLSPolicyConfig struct {
+	MinVersion   string   `mapstructure:"min_version"`
+	CipherSuites []string `mapstructure:"cipher_suites"` // the suites of TLS 1.3 can't be restricted
+	FIPS         bool     `mapstructure:"fips"`
+}
+
+// SecretsConfig resolves the ${<provider>:<path>#<key>} references of the settings at runtime, the dynamic
+// secrets are renewed before the end of their lease, the others are read again every refresh interval
+type SecretsConfig struct {
+	Vault                  VaultConfig           `mapstructure:"vault"`
+	AwsSecretsManager      SecretsProviderConfig `mapstructure:"aws_secrets_manager"`
+	AwsSSM                 SecretsProviderConfig `mapstructure:"aws_ssm"`
+	GcpSecretManager       SecretsProviderConfig `mapstructure:"gcp_secret_manager"`
+	AzureKeyVault          SecretsProviderConfig `mapstructure:"azure_key_vault"`
+	RefreshIntervalSeconds int                   `mapstructure:"refresh_interval_seconds"`
+}
+
+// SecretsProviderConfig enables a secrets provider of a cloud, with the credentials of its section (aws, gcp, azure),
+// the refresh interval overrides the default one if it's set
+type SecretsProviderConfig struct {
+	Enabled                bool `mapstructure:"enabled"`
+	RefreshIntervalSeconds int  `mapstructure:"refresh_interval_seconds"`
+}
+
+// VaultConfig is the access to HashiCorp Vault, with the Kubernetes auth or a token
+type VaultConfig struct {
+	Address                string `mapstructure:"address"`
+	AuthMethod             string `mapstructure:"auth_method"`
+	AuthMountPath          string `mapstructure:"auth_mount_path"`
+	Role                   string `mapstructure:"role"`
+	TokenFile              string `mapstructure:"service_account_token_file"`
+	Token                  string `mapstructure:"token"`
+	Namespace              string `mapstructure:"namespace"`
+	CACertFile             string `mapstructure:"ca_cert_file"`
+	Enabled                bool   `mapstructure:"enabled"`
+	InsecureSkipVerify     bool   `mapstructure:"insecure_skip_verify"`
+	RefreshIntervalSeconds int    `mapstructure:"refresh_interval_seconds"`
+}
+
+// AuditConfig appends the decisions of Falco Talon to a tamper-evident log
+type AuditConfig struct {
+	File    string `mapstructure:"file"`
+	Enabled bool   `mapstructure:"enabled"`
+}
+
+// ConcurrencyConfig limits the number of actions running at the same time, globally and by actionner
+type ConcurrencyConfig struct {
+	Actionners map[string]int `mapstructure:"actionners"`
+	MaxActions int            `mapstructure:"max_actions"`
+}
+
+// WorkersConfig sets the number of workers for each class of priorities
+type WorkersConfig struct {
+	Critical      int `mapstructure:"critical"`
+	Warning       int `mapstructure:"warning"`
+	Informational int `mapstructure:"informational"`
+}
+
+// Get returns the number of workers of the class, at least 1
+func (workers WorkersConfig) Get(class string) int {
+	var n int
+	switch class {
+	case "critical":
+		n = workers.Critical
+	case "warning":
+		n = workers.Warning
+	default:
+		n = workers.Informational
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// FalcoGrpcConfig allows to receive the events from the gRPC output of Falco
+type FalcoGrpcConfig struct {
+	Address    string `mapstructure:"address"`
+	CertFile   string `mapstructure:"cert_file"`
+	KeyFile    string `mapstructure:"key_file"`
+	CACertFile string `mapstructure:"ca_cert_file"`
+	Enabled    bool   `mapstructure:"enabled"`
+}
+
+// KafkaConfig allows to consume the events from a Kafka topic, with a consumer group
+type KafkaConfig struct {
+	Brokers            []string `mapstructure:"brokers"`
+	Topic              string   `mapstructure:"topic"`
+	ConsumerGroup      string   `mapstructure:"consumer_group"`
+	AutoOffsetReset    string   `mapstructure:"auto_offset_reset"`
+	SASLMechanism      string   `mapstructure:"sasl_mechanism"`
+	Username           string   `mapstructure:"username"`
+	Password           string   `mapstructure:"password"`
+	OAuthToken         string   `mapstructure:"oauth_token"`
+	CACertFile         string   `mapstructure:"ca_cert_file"`
+	CertFile           string   `mapstructure:"cert_file"`
+	KeyFile            string   `mapstructure:"key_file"`
+	Enabled            bool     `mapstructure:"enabled"`
+	TLS                bool     `mapstructure:"tls"`
+	InsecureSkipVerify bool     `mapstructure:"insecure_skip_verify"`
+}
+
+// JetStreamConfig allows to consume the events from a stream of an external NATS JetStream
+type JetStreamConfig struct {
+	URL             string `mapstructure:"url"`
+	Stream          string `mapstructure:"stream"`
+	Subject         string `mapstructure:"subject"`
+	Durable         string `mapstructure:"durable"`
+	QueueGroup      string `mapstructure:"queue_group"`
+	DeliverPolicy   string `mapstructure:"deliver_policy"`
+	CredentialsFile string `mapstructure:"credentials_file"`
+	Token           string `mapstructure:"token"`
+	User            string `mapstructure:"user"`
+	Password        string `mapstructure:"password"`
+	CACertFile      string `mapstructure:"ca_cert_file"`
+	CertFile        string `mapstructure:"cert_file"`
+	KeyFile         string `mapstructure:"key_file"`
+	AckWaitSeconds  int    `mapstructure:"ack_wait_seconds"`
+	MaxDeliver      int    `mapstructure:"max_deliver"`
+	Enabled         bool   `mapstructure:"enabled"`
+}
+
+// SQSConfig allows to poll the events from an AWS SQS queue
+type SQSConfig struct {
+	QueueURL                 string `mapstructure:"queue_url"`
+	Region                   string `mapstructure:"region"`
+	MaxMessages              int    `mapstructure:"max_messages"`
+	WaitTimeSeconds          int    `mapstructure:"wait_time_seconds"`
+	VisibilityTimeoutSeconds int    `mapstructure:"visibility_timeout_seconds"`
+	Enabled                  bool   `mapstructure:"enabled"`
+}
+
+// PubSubConfig allows to pull the events from a GCP Pub/Sub subscription
+type PubSubConfig struct {
+	ProjectID    string `mapstructure:"project_id"`
+	Subscription string `mapstructure:"subscription"`
+	MaxMessages  int    `mapstructure:"max_messages"`
+	Enabled      bool   `mapstructure:"enabled"`
 }
 
 type AwsConfig struct {
@@ -47,53 +684,391 @@ type AwsConfig struct {
 	ExternalID string `mapstructure:"external_id"`
 }
 
+type AzureConfig struct {
+	TenantID     string `mapstructure:"tenant_id"`
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+}
+
+type GcpConfig struct {
+	CredentialsFile string `mapstructure:"credentials_file"`
+}
+
 type MinioConfig struct {
-	Endpoint  string `mapstructure:"endpoint"`
-	AccessKey string `mapstructure:"access_key"`
-	SecretKey string `mapstructure:"secret_key"`
-	UseSSL    bool   `mapstructure:"use_ssl"`
+	Endpoint           string `mapstructure:"endpoint"`
+	AccessKey          string `mapstructure:"access_key"`
+	SecretKey          string `mapstructure:"secret_key"`
+	Region             string `mapstructure:"region"`
+	BucketLookup       string `mapstructure:"bucket_lookup"`
+	CACertFile         string `mapstructure:"ca_cert_file"`
+	UseSSL             bool   `mapstructure:"use_ssl"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
 }
 
-var config *Configuration
+// settings applied by a reload of the configuration, the other ones require a restart
+var reloadableSettings = []string{
+	"notifiers",
+	"notifier_limits",
+	"templates",
+	"attachments",
+	"routing",
+	"field_labels_file",
+	"default_notifiers",
+	"integrity",
+	"authentication",
+	"retries",
+	"log_level",
+	"log_levels",
+	"log_format",
+	"print_all_events",
+	"shutdown_timeout_seconds",
+}
+
+// actionners downgraded by the guardrails by default, those which delete, isolate or run commands in the workloads
+var defaultGuardrailsActionners = []string{
+	"kubernetes:terminate",
+	"kubernetes:delete",
+	"kubernetes:drain",
+	"kubernetes:cordon",
+	"kubernetes:networkpolicy",
+	"kubernetes:exec",
+	"kubernetes:script",
+	"calico:networkpolicy",
+	"cilium:networkpolicy",
+}
+
+// actionners allowed to the rules of the tenants by default, those which act on the pods of the event only
+var defaultTenantActionners = []string{
+	"kubernetes:terminate",
+	"kubernetes:label",
+	"kubernetes:networkpolicy",
+	"kubernetes:exec",
+	"kubernetes:script",
+	"kubernetes:log",
+	"kubernetes:download",
+	"kubernetes:tcpdump",
+}
+
+var config atomic.Pointer[Configuration]
 
 func init() {
-	config = new(Configuration)
+	config.Store(new(Configuration))
 }
 
 func CreateConfiguration(configFile string) *Configuration {
+	c, err := LoadConfiguration(configFile)
+	if err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "config"})
+	}
+	config.Store(c)
+	return c
+}
+
+// LoadConfiguration reads the config file and the env vars into a new configuration, without replacing
+// the current one
+func LoadConfiguration(configFile string) (*Configuration, error) {
 	v := viper.New()
 	v.SetDefault("listen_address", defaultListenAddress)
 	v.SetDefault("listen_port", defaultListPort)
 	v.SetDefault("rules_files", []string{defaultRulesFile})
 	v.SetDefault("kubeconfig", "")
+	v.SetDefault("kube_context", "")
 	v.SetDefault("log_format", "color")
+	v.SetDefault("log_level", "info")
 	v.SetDefault("default_notifiers", []string{})
+	v.SetDefault("field_labels_file", "")
 	v.SetDefault("watch_rules", defaultWatchRules)
+	v.SetDefault("watch_config", defaultWatchConfig)
 	v.SetDefault("print_all_events", defaultPrintAllEvents)
+	v.SetDefault("shutdown_timeout_seconds", defaultShutdownTimeout)
 	v.SetDefault("deduplication.leader_election", defaultDeduplicationLeaderElection)
 	v.SetDefault("deduplication.time_window_seconds", defaultDeduplicationTimeWindow)
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	v.AutomaticEnv()
+	v.SetDefault("deduplication.lease_name", defaultLeaseName)
+	v.SetDefault("deduplication.lease_duration_seconds", defaultLeaseDuration)
+	v.SetDefault("deduplication.renew_deadline_seconds", defaultRenewDeadline)
+	v.SetDefault("deduplication.retry_period_seconds", defaultRetryPeriod)
+	v.SetDefault("incidents.enabled", false)
+	v.SetDefault("incidents.time_window_seconds", defaultIncidentsTimeWindow)
+	v.SetDefault("incidents.max_events", defaultIncidentsMaxEvents)
+	v.SetDefault("tls.enabled", false)
+	v.SetDefault("authentication.hmac_header", defaultHMACHeader)
+	v.SetDefault("authentication.hmac_timestamp_header", defaultHMACTimestampHeader)
+	v.SetDefault("authentication.hmac_tolerance_seconds", defaultHMACTolerance)
+	v.SetDefault("persistence.enabled", false)
+	v.SetDefault("persistence.store_dir", defaultStoreDir)
+	v.SetDefault("persistence.max_age_hours", defaultPersistenceMaxAge)
+	v.SetDefault("persistence.ack_wait_seconds", defaultPersistenceAckWait)
+	v.SetDefault("deadletter.store", "")
+	v.SetDefault("circuit_breaker.enabled", false)
+	v.SetDefault("action_locks.enabled", false)
+	v.SetDefault("action_locks.backend", defaultLockBackend)
+	v.SetDefault("action_locks.ttl_seconds", defaultLockTTL)
+	v.SetDefault("verification.enabled", false)
+	v.SetDefault("verification.timeout_seconds", defaultVerificationTimeout)
+	v.SetDefault("verification.interval_seconds", defaultVerificationInterval)
+	v.SetDefault("async_actions.enabled", false)
+	v.SetDefault("async_actions.progress_interval_seconds", defaultAsyncProgressInterval)
+	v.SetDefault("async_actions.max_age_hours", defaultAsyncMaxAge)
+	v.SetDefault("templates.message", "")
+	v.SetDefault("templates.directory", "")
+	v.SetDefault("manual_actions.enabled", false)
+	v.SetDefault("manual_actions.actionners", []string{})
+	v.SetDefault("idempotency.enabled", false)
+	v.SetDefault("idempotency.backend", defaultIdempotencyBackend)
+	v.SetDefault("idempotency.ttl_seconds", defaultIdempotencyTTL)
+	v.SetDefault("idempotency.redis.address", "")
+	v.SetDefault("idempotency.redis.username", "")
+	v.SetDefault("idempotency.redis.password", "")
+	v.SetDefault("idempotency.redis.key_prefix", defaultRedisKeyPrefix)
+	v.SetDefault("idempotency.redis.ca_cert_file", "")
+	v.SetDefault("idempotency.redis.db", 0)
+	v.SetDefault("idempotency.redis.tls", false)
+	v.SetDefault("circuit_breaker.failure_threshold", defaultFailureThreshold)
+	v.SetDefault("guardrails.namespaces", []string{})
+	v.SetDefault("guardrails.label_selectors", []string{})
+	v.SetDefault("guardrails.actionners", defaultGuardrailsActionners)
+	v.SetDefault("blast_radius.max_resources_per_event", 0)
+	v.SetDefault("guard_policy.enabled", false)
+	v.SetDefault("guard_policy.url", "")
+	v.SetDefault("guard_policy.actionners", []string{})
+	v.SetDefault("guard_policy.timeout_seconds", defaultGuardPolicyTimeout)
+	v.SetDefault("guard_policy.fail_open", false)
+	v.SetDefault("blast_radius.hold_max_age_hours", defaultHoldMaxAge)
+	v.SetDefault("circuit_breaker.open_duration_seconds", defaultOpenDuration)
+	v.SetDefault("deadletter.max_age_hours", defaultDeadLetterMaxAge)
+	v.SetDefault("history.store", "")
+	v.SetDefault("undo.store", "")
+	v.SetDefault("expiry.enabled", false)
+	v.SetDefault("audit.enabled", false)
+	v.SetDefault("kubernetes_events.enabled", false)
+	v.SetDefault("kubernetes_cache.enabled", true)
+	v.SetDefault("kubernetes_client.qps", defaultKubernetesQPS)
+	v.SetDefault("kubernetes_client.burst", defaultKubernetesBurst)
+	v.SetDefault("kubernetes_client.max_retries", defaultKubernetesMaxRetries)
+	v.SetDefault("kubernetes_client.initial_backoff_ms", defaultKubernetesInitialBackoff)
+	v.SetDefault("kubernetes_client.max_backoff_seconds", defaultKubernetesMaxBackoff)
+	v.SetDefault("multi_cluster.event_field", "")
+	v.SetDefault("multi_cluster.default_cluster", "")
+	v.SetDefault("kubernetes_cache.resync_seconds", defaultKubernetesCacheResync)
+	v.SetDefault("tracing.enabled", false)
+	v.SetDefault("tracing.endpoint", defaultTracingEndpoint)
+	v.SetDefault("tracing.sample_ratio", 1)
+	v.SetDefault("otlp.endpoint", defaultTracingEndpoint)
+	v.SetDefault("diagnostics.enabled", false)
+	v.SetDefault("diagnostics.listen_address", defaultDiagnosticsAddress)
+	v.SetDefault("diagnostics.listen_port", defaultDiagnosticsPort)
+	v.SetDefault("grpc_server.enabled", false)
+	v.SetDefault("grpc_server.listen_address", defaultListenAddress)
+	v.SetDefault("grpc_server.listen_port", defaultGrpcServerPort)
+	v.SetDefault("outbound.http_proxy", "")
+	v.SetDefault("outbound.https_proxy", "")
+	v.SetDefault("outbound.no_proxy", "")
+	v.SetDefault("outbound.ca_cert_file", "")
+	v.SetDefault("tls_policy.min_version", "1.2")
+	v.SetDefault("tls_policy.cipher_suites", []string{})
+	v.SetDefault("tls_policy.fips", false)
+	v.SetDefault("secrets.refresh_interval_seconds", defaultSecretsRefreshInterval)
+	v.SetDefault("secrets.vault.enabled", false)
+	v.SetDefault("secrets.vault.address", "")
+	v.SetDefault("secrets.vault.auth_method", defaultVaultAuthMethod)
+	v.SetDefault("secrets.vault.auth_mount_path", defaultVaultAuthMethod)
+	v.SetDefault("secrets.vault.role", defaultLeaseName)
+	v.SetDefault("secrets.vault.service_account_token_file", defaultServiceAccountTokenFile)
+	v.SetDefault("secrets.vault.token", "")
+	v.SetDefault("secrets.vault.namespace", "")
+	v.SetDefault("secrets.vault.ca_cert_file", "")
+	v.SetDefault("secrets.vault.insecure_skip_verify", false)
+	v.SetDefault("secrets.vault.refresh_interval_seconds", 0)
+	for _, i := range []string{"aws_secrets_manager", "aws_ssm", "gcp_secret_manager", "azure_key_vault"} {
+		v.SetDefault("secrets."+i+".enabled", false)
+		v.SetDefault("secrets."+i+".refresh_interval_seconds", 0)
+	}
+	v.SetDefault("otlp.metrics.enabled", false)
+	v.SetDefault("otlp.metrics.interval_seconds", defaultOTLPMetricsInterval)
+	v.SetDefault("otlp.logs.enabled", false)
+	v.SetDefault("otlp.logs.interval_seconds", defaultOTLPLogsInterval)
+	v.SetDefault("audit.file", defaultAuditFile)
+	v.SetDefault("expiry.interval_seconds", defaultExpiryInterval)
+	v.SetDefault("undo.max_age_hours", defaultUndoMaxAge)
+	v.SetDefault("history.max_age_days", defaultHistoryMaxAge)
+	v.SetDefault("ingestion.rate_limit", 0)
+	v.SetDefault("ingestion.max_queue_size", defaultMaxQueueSize)
+	v.SetDefault("ingestion.retry_after_seconds", defaultRetryAfter)
+	v.SetDefault("ingestion.workers.critical", defaultCriticalWorkers)
+	v.SetDefault("ingestion.workers.warning", 1)
+	v.SetDefault("ingestion.workers.informational", 1)
+	v.SetDefault("falco_grpc.enabled", false)
+	v.SetDefault("falco_grpc.address", defaultFalcoGrpcAddress)
+	v.SetDefault("kafka.enabled", false)
+	v.SetDefault("kafka.topic", defaultKafkaTopic)
+	v.SetDefault("kafka.consumer_group", defaultKafkaConsumerGroup)
+	v.SetDefault("kafka.auto_offset_reset", defaultKafkaAutoOffsetReset)
+	v.SetDefault("kafka.tls", false)
+	v.SetDefault("jetstream.enabled", false)
+	v.SetDefault("jetstream.subject", defaultJetStreamSubject)
+	v.SetDefault("jetstream.queue_group", defaultJetStreamQueueGroup)
+	v.SetDefault("jetstream.deliver_policy", defaultJetStreamDeliverPolicy)
+	v.SetDefault("jetstream.ack_wait_seconds", defaultJetStreamAckWait)
+	v.SetDefault("sqs.enabled", false)
+	v.SetDefault("sqs.max_messages", defaultSQSMaxMessages)
+	v.SetDefault("sqs.wait_time_seconds", defaultSQSWaitTime)
+	v.SetDefault("pubsub.enabled", false)
+	v.SetDefault("pubsub.max_messages", defaultPubSubMaxMessages)
 
 	if configFile != "" {
 		v.SetConfigFile(configFile)
-		err := v.ReadInConfig()
+		err := readConfig(v, configFile)
 		if err != nil {
-			utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("error when reading config file: '%v'", err.Error()), Message: "config"})
+			return nil, fmt.Errorf("error when reading config file: '%v'", err.Error())
+		}
+	}
+	bindEnv(v)
+
+	c := new(Configuration)
+	if err := v.Unmarshal(c, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		expandEnvHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	))); err != nil {
+		return nil, fmt.Errorf("error unmarshalling config file: '%v'", err.Error())
+	}
+	for i, j := range c.Tenants {
+		if len(j.Actionners) == 0 {
+			j.Actionners = defaultTenantActionners
+			c.Tenants[i] = j
+		}
+	}
+
+	return c, nil
+}
+
+// PrepareReload compares a new configuration with the current one and returns the keys of the changed
+// settings, those which require a restart keep their current values in the new configuration
+func PrepareReload(next *Configuration) ([]string, []string) {
+	current := reflect.ValueOf(config.Load()).Elem()
+	n := reflect.ValueOf(next).Elem()
+	reloaded, ignored := make([]string, 0), make([]string, 0)
+	for i := 0; i < n.NumField(); i++ {
+		if reflect.DeepEqual(current.Field(i).Interface(), n.Field(i).Interface()) {
+			continue
+		}
+		key := n.Type().Field(i).Tag.Get("mapstructure")
+		if slices.Contains(reloadableSettings, key) {
+			reloaded = append(reloaded, key)
+			continue
+		}
+		ignored = append(ignored, key)
+		n.Field(i).Set(current.Field(i))
+	}
+	return reloaded, ignored
+}
+
+// SetConfiguration replaces the current configuration, after a reload
+func SetConfiguration(c *Configuration) {
+	config.Store(c)
+}
+
+// readConfig reads the config file, the files encrypted by sops are decrypted in memory
+func readConfig(v *viper.Viper, configFile string) error {
+	b, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	if !sops.IsEncrypted(b) {
+		return v.ReadInConfig()
+	}
+	b, err = sops.Decrypt(b)
+	if err != nil {
+		return fmt.Errorf("can't decrypt the file: %v", err)
+	}
+	v.SetConfigType("yaml")
+	return v.ReadConfig(bytes.NewReader(b))
+}
+
+// bindEnv binds the env vars to the keys of the configuration, TALON_<KEY> then <KEY>, with the dots of the
+// key replaced by '_' (TALON_DEDUPLICATION_LEADER_ELECTION). The keys unknown in advance, as the settings
+// of the notifiers missing in the config file, are set with '__' as separator (TALON_NOTIFIERS__SLACK__WEBHOOK_URL)
+func bindEnv(v *viper.Viper) {
+	keys := v.AllKeys()
+	keys = append(keys, structKeys(reflect.TypeOf(Configuration{}), "")...)
+	for _, i := range utils.Deduplicate(keys) {
+		env := strings.ToUpper(strings.ReplaceAll(i, ".", "_"))
+		_ = v.BindEnv(i, envPrefix+env, env)
+	}
+	for _, i := range os.Environ() {
+		name, value, _ := strings.Cut(i, "=")
+		if key, ok := strings.CutPrefix(name, envPrefix); ok && strings.Contains(key, "__") {
+			v.Set(strings.ToLower(strings.ReplaceAll(key, "__", ".")), value)
 		}
 	}
+}
 
-	if err := v.Unmarshal(config); err != nil {
-		utils.PrintLog("fatal", utils.LogLine{Error: fmt.Sprintf("error unmarshalling config file: '%v'", err.Error()), Message: "config"})
+// structKeys returns the keys of the fields of the struct, the maps are skipped, their keys are unknown
+func structKeys(t reflect.Type, prefix string) []string {
+	keys := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		switch f.Type.Kind() {
+		case reflect.Map:
+			continue
+		case reflect.Struct:
+			keys = append(keys, structKeys(f.Type, prefix+tag+".")...)
+		default:
+			keys = append(keys, prefix+tag)
+		}
 	}
+	return keys
+}
 
-	return config
+// expandEnvHook replaces the ${VAR} of the values by the env vars, the secrets don't need to be in the config file
+func expandEnvHook(from, _ reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String {
+		return data, nil
+	}
+	return utils.ExpandEnv(reflect.ValueOf(data).String()), nil
 }
 
 func GetConfiguration() *Configuration {
-	return config
+	return config.Load()
 }
 
 func (c *Configuration) GetDefaultNotifiers() []string {
 	return c.DefaultNotifiers
 }
+
+// GetTenant returns the settings of the tenant, false if it doesn't exist
+func (c *Configuration) GetTenant(name string) (TenantConfig, bool) {
+	t, ok := c.Tenants[name]
+	return t, ok
+}
+
+// IsNamespaced returns true if Falco Talon watches and acts in an allow-list of namespaces only,
+// with namespaced Roles instead of a ClusterRole
+func (c *Configuration) IsNamespaced() bool {
+	return len(c.Namespaces) != 0
+}
+
+// IsNamespaceAllowed returns true if the namespace can be targeted, all the namespaces are allowed out of the namespace-scoped mode
+func (c *Configuration) IsNamespaceAllowed(namespace string) bool {
+	if !c.IsNamespaced() {
+		return true
+	}
+	return slices.Contains(c.Namespaces, namespace)
+}
+
+// IsNamespaceAllowed returns true if the namespace belongs to the tenant, its namespaces are patterns (team-a-*)
+func (t TenantConfig) IsNamespaceAllowed(namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+	for _, i := range t.Namespaces {
+		if ok, _ := path.Match(i, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
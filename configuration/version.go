@@ -24,13 +24,13 @@ var (
 )
 
 type Info struct {
-	GitVersion   string
-	GitCommit    string
-	GitTreeState string
-	BuildDate    string
-	GoVersion    string
-	Compiler     string
-	Platform     string
+	GitVersion   string `json:"git_version"`
+	GitCommit    string `json:"git_commit"`
+	GitTreeState string `json:"git_tree_state"`
+	BuildDate    string `json:"build_date"`
+	GoVersion    string `json:"go_version"`
+	Compiler     string `json:"compiler"`
+	Platform     string `json:"platform"`
 }
 
 func GetInfo() *Info {
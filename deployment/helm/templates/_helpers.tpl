@@ -59,4 +59,237 @@ Return if ingress supports pathType.
 */}}
 {{- define "falco-talon.ingress.supportsPathType" -}}
   {{- or (eq (include "falco-talon.ingress.isStable" .) "true") (and (eq (include "falco-talon.ingress.apiVersion" .) "networking.k8s.io/v1beta1") (semverCompare ">= 1.18-0" .Capabilities.KubeVersion.Version)) -}}
-{{- end -}}
\ No newline at end of file
+{{- end -}}
+
+{{/*
+Rules of the ClusterRole, or of the Roles in the namespace-scoped mode (without the cluster-scoped resources)
+*/}}
+{{- define "falco-talon.rbacRules" -}}
+{{- $root := .root -}}
+{{- $namespaced := .namespaced -}}
+  {{- if not $namespaced }}
+  {{- if $root.Values.rbac.namespaces }}
+  - apiGroups:
+      - ""
+    resources:
+      - namespaces
+    verbs:
+{{ toYaml $root.Values.rbac.namespaces | indent 6 }}
+  {{- end }}
+  {{- end }}
+  {{- if $root.Values.rbac.pods }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods
+    verbs:
+{{ toYaml $root.Values.rbac.pods | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.podsEphemeralcontainers }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods/ephemeralcontainers
+    verbs:
+{{ toYaml $root.Values.rbac.podsEphemeralcontainers | indent 6 }}
+  {{- end }}
+  {{- if not $namespaced }}
+  {{- if $root.Values.rbac.nodes }}
+  - apiGroups:
+      - ""
+    resources:
+      - nodes
+    verbs:
+{{ toYaml $root.Values.rbac.nodes | indent 6 }}
+  {{- end }}
+  {{- end }}
+  {{- if $root.Values.rbac.podsExec }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods/exec
+    verbs:
+{{ toYaml $root.Values.rbac.podsExec | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.podsEviction }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods/eviction
+    verbs:
+{{ toYaml $root.Values.rbac.podsEviction | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.podsPortforward }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods/portforward
+    verbs:
+{{ toYaml $root.Values.rbac.podsPortforward | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.podsProxy }}
+  - apiGroups:
+      - ""
+    resources:
+      - pods/proxy
+    verbs:
+{{ toYaml $root.Values.rbac.podsProxy | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.servicesProxy }}
+  - apiGroups:
+      - ""
+    resources:
+      - services/proxy
+    verbs:
+{{ toYaml $root.Values.rbac.servicesProxy | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.events }}
+  - apiGroups:
+      - ""
+    resources:
+      - events
+    verbs:
+{{ toYaml $root.Values.rbac.events | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.daemonsets }}
+  - apiGroups:
+      - "apps"
+    resources:
+      - daemonsets
+    verbs:
+{{ toYaml $root.Values.rbac.daemonsets | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.deployments }}
+  - apiGroups:
+      - "apps"
+    resources:
+      - deployments
+    verbs:
+{{ toYaml $root.Values.rbac.deployments | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.replicasets }}
+  - apiGroups:
+      - "apps"
+    resources:
+      - replicasets
+    verbs:
+{{ toYaml $root.Values.rbac.replicasets | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.statefulsets }}
+  - apiGroups:
+      - "apps"
+    resources:
+      - statefulsets
+    verbs:
+{{ toYaml $root.Values.rbac.statefulsets | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.jobs }}
+  - apiGroups:
+      - "batch"
+    resources:
+      - jobs
+      - cronjobs
+    verbs:
+{{ toYaml $root.Values.rbac.jobs | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.networkpolicies }}
+  - apiGroups:
+      - "networking.k8s.io"
+    resources:
+      - networkpolicies
+    verbs:
+{{ toYaml $root.Values.rbac.networkpolicies | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.caliconetworkpolicies }}
+  - apiGroups:
+      - "projectcalico.org"
+    resources:
+      - caliconetworkpolicies
+    verbs:
+{{ toYaml $root.Values.rbac.caliconetworkpolicies | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.ciliumnetworkpolicies }}
+  - apiGroups:
+      - "cilium.io"
+    resources:
+      - ciliumnetworkpolicies
+    verbs:
+{{ toYaml $root.Values.rbac.ciliumnetworkpolicies | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.roles }}
+  - apiGroups:
+      - "rbac.authorization.k8s.io"
+    resources:
+      - roles
+    verbs:
+{{ toYaml $root.Values.rbac.roles | indent 6 }}
+  {{- end }}
+  {{- if not $namespaced }}
+  {{- if $root.Values.rbac.clusterroles }}
+  - apiGroups:
+      - "rbac.authorization.k8s.io"
+    resources:
+      - clusterroles
+    verbs:
+{{ toYaml $root.Values.rbac.clusterroles | indent 6 }}
+  {{- end }}
+  {{- end }}
+  {{- if $root.Values.rbac.configmaps }}
+  - apiGroups:
+      - ""
+    resources:
+      - configmaps
+    verbs:
+{{ toYaml $root.Values.rbac.configmaps | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.secrets }}
+  - apiGroups:
+      - ""
+    resources:
+      - secrets
+    verbs:
+{{ toYaml $root.Values.rbac.secrets | indent 6 }}
+  {{- end }}
+  {{- if $root.Values.rbac.leases }}
+  - apiGroups:
+      - "coordination.k8s.io"
+    resources:
+      - leases
+    verbs:
+{{ toYaml $root.Values.rbac.leases | indent 6 }}
+  {{- end }}
+  {{- if not $namespaced }}
+  {{- with $root.Values.rbac.impersonate }}
+  {{- if .users }}
+  - apiGroups:
+      - ""
+    resources:
+      - users
+    resourceNames:
+{{ toYaml .users | indent 6 }}
+    verbs:
+      - impersonate
+  {{- end }}
+  {{- if .groups }}
+  - apiGroups:
+      - ""
+    resources:
+      - groups
+    resourceNames:
+{{ toYaml .groups | indent 6 }}
+    verbs:
+      - impersonate
+  {{- end }}
+  {{- range .serviceaccounts }}
+  - apiGroups:
+      - ""
+    resources:
+      - serviceaccounts
+    resourceNames:
+      - {{ (split "/" .)._1 }}
+    verbs:
+      - impersonate
+  {{- end }}
+  {{- end }}
+  {{- end }}
+{{- end }}
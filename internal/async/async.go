@@ -0,0 +1,175 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const Running string = "running"
+
+// Action is an action run in the background, its status is updated until its end
+type Action struct {
+	StartedAt        time.Time         `json:"started_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	EndedAt          *time.Time        `json:"ended_at,omitempty"`
+	Objects          map[string]string `json:"objects,omitempty"`
+	ID               string            `json:"id"`
+	TraceID          string            `json:"trace_id"`
+	Rule             string            `json:"rule"`
+	Action           string            `json:"action"`
+	Actionner        string            `json:"actionner"`
+	Status           string            `json:"status"`
+	Progress         string            `json:"progress,omitempty"`
+	Output           string            `json:"output,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	lastNotification time.Time
+	notify           func(progress string)
+}
+
+type ctxKey struct{}
+
+// the actions are kept in memory, the running actions are lost with a restart
+var (
+	actions  = make(map[string]*Action)
+	maxAge   time.Duration
+	interval time.Duration
+	mu       sync.Mutex
+	ErrNoID  = errors.New("unknown action")
+)
+
+// Init sets the minimal delay between two notifications of the progress, the ended actions older than
+// maxAge are dropped
+func Init(age, progressInterval time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	maxAge = age
+	interval = progressInterval
+}
+
+// Start registers an action run in the background, the returned context carries its id for the updates of
+// its progress, notify is called with the progress at most once per interval
+func Start(ctx context.Context, rule, action, actionner, traceID string, notify func(progress string)) (context.Context, string) {
+	now := time.Now().UTC()
+	a := &Action{
+		ID:               uuid.NewString(),
+		TraceID:          traceID,
+		Rule:             rule,
+		Action:           action,
+		Actionner:        actionner,
+		Status:           Running,
+		StartedAt:        now,
+		UpdatedAt:        now,
+		lastNotification: now,
+		notify:           notify,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	purge()
+	actions[a.ID] = a
+	return context.WithValue(ctx, ctxKey{}, a.ID), a.ID
+}
+
+// GetID returns the id of the action of the context, empty if the action isn't run in the background
+func GetID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Progress updates the progress of the action of the context, nothing is done if it isn't run in the background
+func Progress(ctx context.Context, progress string) {
+	id := GetID(ctx)
+	if id == "" {
+		return
+	}
+
+	mu.Lock()
+	a, ok := actions[id]
+	if !ok || a.EndedAt != nil {
+		mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	a.Progress = progress
+	a.UpdatedAt = now
+	notify := a.notify
+	if notify == nil || now.Sub(a.lastNotification) < interval {
+		notify = nil
+	} else {
+		a.lastNotification = now
+	}
+	mu.Unlock()
+
+	if notify != nil {
+		notify(progress)
+	}
+}
+
+// End sets the result of the action, from the last log of its run
+func End(id string, log utils.LogLine) {
+	mu.Lock()
+	defer mu.Unlock()
+	a, ok := actions[id]
+	if !ok {
+		return
+	}
+	now := time.Now().UTC()
+	a.EndedAt = &now
+	a.UpdatedAt = now
+	a.Status = log.Status
+	if a.Status == "" {
+		a.Status = "success"
+		if log.Error != "" {
+			a.Status = "failure"
+		}
+	}
+	a.Output = log.Output
+	a.Error = log.Error
+	a.Objects = log.Objects
+	a.notify = nil
+}
+
+// List returns the actions run in the background, the oldest first
+func List() []Action {
+	mu.Lock()
+	defer mu.Unlock()
+	purge()
+	list := make([]Action, 0, len(actions))
+	for _, i := range actions {
+		list = append(list, *i)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+func Get(id string) (Action, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	purge()
+	a, ok := actions[id]
+	if !ok {
+		return Action{}, ErrNoID
+	}
+	return *a, nil
+}
+
+// purge drops the ended actions older than maxAge, the lock must be held
+func purge() {
+	if maxAge <= 0 {
+		return
+	}
+	for i, j := range actions {
+		if j.EndedAt != nil && time.Since(*j.EndedAt) > maxAge {
+			delete(actions, i)
+		}
+	}
+}
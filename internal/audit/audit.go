@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is an entry of the audit log, its hash covers the hash of the previous record,
+// a modification or a removal of a record breaks the chain
+type Record struct {
+	Time      time.Time         `json:"time"`
+	Details   map[string]string `json:"details,omitempty"`
+	Type      string            `json:"type"`
+	Identity  string            `json:"identity"`
+	TraceID   string            `json:"trace_id,omitempty"`
+	Rule      string            `json:"rule,omitempty"`
+	Action    string            `json:"action,omitempty"`
+	Actionner string            `json:"actionner,omitempty"`
+	Status    string            `json:"status,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+	Seq       uint64            `json:"seq"`
+}
+
+// types of the records
+const (
+	EventReceived  string = "event_received"
+	RulesEvaluated string = "rules_evaluated"
+	RuleMatched    string = "rule_matched"
+	ActionExecuted string = "action_executed"
+)
+
+const serviceAccountToken string = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
+
+var (
+	file     *os.File
+	identity string
+	lastHash string
+	seq      uint64
+	mu       sync.Mutex
+)
+
+// Init opens the audit log in append mode, the chain continues from its last record
+func Init(path string) error {
+	if path == "" {
+		return errors.New("wrong `file` setting")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+
+	last, err := readLast(path)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		lastHash = last.Hash
+		seq = last.Seq
+	}
+
+	file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	identity = getIdentity()
+	return nil
+}
+
+func IsEnabled() bool {
+	return file != nil
+}
+
+// Add appends a record to the audit log, if it's enabled
+func Add(record Record) error {
+	if file == nil {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	seq++
+	record.Seq = seq
+	record.Time = time.Now().UTC()
+	record.Identity = identity
+	record.PrevHash = lastHash
+	record.Hash = ""
+	hash, err := getHash(&record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	lastHash = hash
+	return nil
+}
+
+// Verify checks the chain of the records of the audit log, it returns the number of valid records
+// and an error for the first invalid one
+func Verify(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	var prev string
+	var n uint64
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return n, fmt.Errorf("record %v: %v", n+1, err)
+		}
+		if record.PrevHash != prev {
+			return n, fmt.Errorf("record %v: the chain is broken, a previous record has been modified or removed", record.Seq)
+		}
+		hash := record.Hash
+		record.Hash = ""
+		expected, err := getHash(&record)
+		if err != nil {
+			return n, err
+		}
+		if hash != expected {
+			return n, fmt.Errorf("record %v: wrong hash, the record has been modified", record.Seq)
+		}
+		prev = hash
+		n++
+	}
+	return n, scanner.Err()
+}
+
+func getHash(record *Record) (string, error) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+func readLast(path string) (*Record, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		last = append(last[:0], scanner.Bytes()...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(last) == 0 {
+		return nil, nil
+	}
+	var record Record
+	if err := json.Unmarshal(last, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// getIdentity returns the service account of Falco Talon in k8s, its hostname otherwise
+func getIdentity() string {
+	if b, err := os.ReadFile(serviceAccountToken); err == nil {
+		if parts := strings.Split(string(b), "."); len(parts) == 3 {
+			if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil {
+				var claims struct {
+					Sub string `json:"sub"`
+				}
+				if json.Unmarshal(payload, &claims) == nil && claims.Sub != "" {
+					return claims.Sub
+				}
+			}
+		}
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
@@ -2,25 +2,39 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 type AWSClient struct {
-	lambdaClient *lambda.Client
-	imdsClient   *imds.Client
-	s3Client     *s3.Client
-	cfg          aws.Config
+	lambdaClient         *lambda.Client
+	imdsClient           *imds.Client
+	s3Client             *s3.Client
+	sqsClient            *sqs.Client
+	eventBridgeClient    *eventbridge.Client
+	secretsManagerClient *secretsmanager.Client
+	ssmClient            *ssm.Client
+	cfg                  aws.Config
 }
 
 var (
@@ -40,15 +54,18 @@ func Init() error {
 		var cfg aws.Config
 		var err error
 
+		opts := []func(*config.LoadOptions) error{config.WithHTTPClient(newHTTPClient())}
 		if awsConfig.AccessKey != "" && awsConfig.SecretKey != "" && awsConfig.Region != "" {
-			cfg, err = config.LoadDefaultConfig(
-				context.TODO(),
+			opts = append(opts,
 				config.WithRegion(awsConfig.Region),
-				config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(awsConfig.AccessKey, awsConfig.SecretKey, "")),
+				config.WithCredentialsProvider(aws.NewCredentialsCache(aws.CredentialsProviderFunc(staticCredentials))),
 			)
-		} else {
-			cfg, err = config.LoadDefaultConfig(context.TODO())
 		}
+		// in FIPS mode, the services are reached through their FIPS endpoints
+		if tlspolicy.IsFIPS() {
+			opts = append(opts, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+		}
+		cfg, err = config.LoadDefaultConfig(context.TODO(), opts...)
 		if err != nil {
 			initErr = err
 			return
@@ -81,6 +98,33 @@ func Init() error {
 	return initErr
 }
 
+// newHTTPClient returns the http client of the SDK, with the proxy and the CA bundle of the outbound connections
+func newHTTPClient() *awshttp.BuildableClient {
+	return awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		tr.Proxy = outbound.Proxy
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		tlspolicy.Apply(tr.TLSClientConfig)
+		if pool := outbound.RootCAs(); pool != nil {
+			tr.TLSClientConfig.RootCAs = pool
+		}
+	})
+}
+
+// staticCredentials returns the keys of the configuration, read again every minute as they can be
+// renewed from a secrets provider
+func staticCredentials(_ context.Context) (aws.Credentials, error) {
+	awsConfig := configuration.GetConfiguration().AwsConfig
+	return aws.Credentials{
+		AccessKeyID:     awsConfig.AccessKey,
+		SecretAccessKey: awsConfig.SecretKey,
+		Source:          credentials.StaticCredentialsName,
+		CanExpire:       true,
+		Expires:         time.Now().Add(time.Minute),
+	}, nil
+}
+
 func GetAWSClient() *AWSClient {
 	return awsClient
 }
@@ -118,6 +162,50 @@ func GetS3Client() *s3.Client {
 	return c.s3Client
 }
 
+func GetSQSClient() *sqs.Client {
+	c := GetAWSClient()
+	if c == nil {
+		return nil
+	}
+	if c.sqsClient == nil {
+		c.sqsClient = sqs.NewFromConfig(c.cfg)
+	}
+	return c.sqsClient
+}
+
+func GetEventBridgeClient() *eventbridge.Client {
+	c := GetAWSClient()
+	if c == nil {
+		return nil
+	}
+	if c.eventBridgeClient == nil {
+		c.eventBridgeClient = eventbridge.NewFromConfig(c.cfg)
+	}
+	return c.eventBridgeClient
+}
+
+func GetSecretsManagerClient() *secretsmanager.Client {
+	c := GetAWSClient()
+	if c == nil {
+		return nil
+	}
+	if c.secretsManagerClient == nil {
+		c.secretsManagerClient = secretsmanager.NewFromConfig(c.cfg)
+	}
+	return c.secretsManagerClient
+}
+
+func GetSSMClient() *ssm.Client {
+	c := GetAWSClient()
+	if c == nil {
+		return nil
+	}
+	if c.ssmClient == nil {
+		c.ssmClient = ssm.NewFromConfig(c.cfg)
+	}
+	return c.ssmClient
+}
+
 func (client AWSClient) GetRegion() string {
 	return client.cfg.Region
 }
@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+)
+
+type AzureClient struct {
+	httpClient *http.Client
+	tokens     map[string]*token
+	config     configuration.AzureConfig
+	mu         sync.Mutex
+}
+
+type token struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+	ExpiresOn   json.Number `json:"expires_on"`
+	expiration  time.Time
+}
+
+const (
+	imdsEndpoint         string = "http://169.254.169.254/metadata/identity/oauth2/token"
+	defaultAuthorityHost string = "https://login.microsoftonline.com/"
+	clientAssertionType  string = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+var (
+	azureClient *AzureClient
+	once        sync.Once
+)
+
+func Init() error {
+	if azureClient != nil {
+		return nil
+	}
+
+	once.Do(func() {
+		azureClient = &AzureClient{
+			httpClient: &http.Client{Timeout: 10 * time.Second},
+			tokens:     make(map[string]*token),
+			config:     configuration.GetConfiguration().AzureConfig,
+		}
+	})
+
+	return nil
+}
+
+func GetAzureClient() *AzureClient {
+	return azureClient
+}
+
+// GetToken returns a bearer token for the resource, the credentials are
+// (in order) a service principal, a workload identity or a managed identity
+func (client *AzureClient) GetToken(resource string) (string, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if t, ok := client.tokens[resource]; ok && time.Now().Add(time.Minute).Before(t.expiration) {
+		return t.AccessToken, nil
+	}
+
+	var t *token
+	var err error
+	switch {
+	case client.config.ClientSecret != "":
+		t, err = client.getTokenFromClientSecret(resource)
+	case os.Getenv("AZURE_FEDERATED_TOKEN_FILE") != "":
+		t, err = client.getTokenFromWorkloadIdentity(resource)
+	default:
+		t, err = client.getTokenFromManagedIdentity(resource)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	client.tokens[resource] = t
+	return t.AccessToken, nil
+}
+
+func (client *AzureClient) getTokenFromManagedIdentity(resource string) (*token, error) {
+	v := url.Values{}
+	v.Set("api-version", "2018-02-01")
+	v.Set("resource", resource)
+	if client.config.ClientID != "" {
+		v.Set("client_id", client.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, imdsEndpoint+"?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	return client.doTokenRequest(req)
+}
+
+func (client *AzureClient) getTokenFromWorkloadIdentity(resource string) (*token, error) {
+	assertion, err := os.ReadFile(os.Getenv("AZURE_FEDERATED_TOKEN_FILE"))
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := client.config.ClientID
+	if clientID == "" {
+		clientID = os.Getenv("AZURE_CLIENT_ID")
+	}
+
+	v := url.Values{}
+	v.Set("grant_type", "client_credentials")
+	v.Set("client_id", clientID)
+	v.Set("scope", strings.TrimSuffix(resource, "/")+"/.default")
+	v.Set("client_assertion_type", clientAssertionType)
+	v.Set("client_assertion", strings.TrimSpace(string(assertion)))
+
+	return client.postTokenRequest(v)
+}
+
+func (client *AzureClient) getTokenFromClientSecret(resource string) (*token, error) {
+	v := url.Values{}
+	v.Set("grant_type", "client_credentials")
+	v.Set("client_id", client.config.ClientID)
+	v.Set("client_secret", client.config.ClientSecret)
+	v.Set("scope", strings.TrimSuffix(resource, "/")+"/.default")
+
+	return client.postTokenRequest(v)
+}
+
+func (client *AzureClient) postTokenRequest(v url.Values) (*token, error) {
+	tenantID := client.config.TenantID
+	if tenantID == "" {
+		tenantID = os.Getenv("AZURE_TENANT_ID")
+	}
+	if tenantID == "" {
+		return nil, errors.New("missing tenant id")
+	}
+
+	authorityHost := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authorityHost == "" {
+		authorityHost = defaultAuthorityHost
+	}
+
+	u := strings.TrimSuffix(authorityHost, "/") + "/" + tenantID + "/oauth2/v2.0/token"
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, u, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return client.doTokenRequest(req)
+}
+
+func (client *AzureClient) doTokenRequest(req *http.Request) (*token, error) {
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("can't get a token: %v", resp.Status)
+	}
+
+	var t token
+	if err := json.Unmarshal(body, &t); err != nil {
+		return nil, err
+	}
+
+	if s, err := t.ExpiresOn.Int64(); err == nil {
+		t.expiration = time.Unix(s, 0)
+	} else if s, err := t.ExpiresIn.Int64(); err == nil {
+		t.expiration = time.Now().Add(time.Duration(s) * time.Second)
+	} else {
+		t.expiration = time.Now().Add(5 * time.Minute)
+	}
+
+	return &t, nil
+}
+
+// GetSASToken generates a Shared Access Signature for a Service Bus or an Event Hubs resource
+func GetSASToken(uri, keyName, key string, ttl time.Duration) string {
+	encoded := url.QueryEscape(strings.ToLower(uri))
+	expiry := fmt.Sprintf("%v", time.Now().Add(ttl).Unix())
+
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(encoded + "\n" + expiry))
+	sig := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	return fmt.Sprintf("SharedAccessSignature sr=%v&sig=%v&se=%v&skn=%v", encoded, url.QueryEscape(sig), expiry, keyName)
+}
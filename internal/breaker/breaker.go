@@ -0,0 +1,149 @@
+package breaker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type State string
+
+const (
+	Closed   State = "closed"
+	Open     State = "open"
+	HalfOpen State = "half-open"
+)
+
+type circuit struct {
+	openedAt time.Time
+	state    State
+	failures int
+	trial    bool
+}
+
+var (
+	circuits  map[string]*circuit
+	threshold int
+	cooldown  time.Duration
+	onChange  func(actionner string, open bool)
+	mu        sync.Mutex
+)
+
+// Init enables the circuit breakers, a circuit opens after `failureThreshold` consecutive failures
+// of the actionner and a new attempt is allowed after `openDuration`, `onStateChange` is called
+// when a circuit opens or closes
+func Init(failureThreshold int, openDuration time.Duration, onStateChange func(actionner string, open bool)) {
+	mu.Lock()
+	defer mu.Unlock()
+	circuits = make(map[string]*circuit)
+	threshold = failureThreshold
+	cooldown = openDuration
+	onChange = onStateChange
+}
+
+func IsEnabled() bool {
+	return circuits != nil
+}
+
+// Allow returns false if the circuit of the actionner is open, when the cooldown is over,
+// a single trial is allowed to close it again
+func Allow(actionner string) bool {
+	if !IsEnabled() {
+		return true
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, ok := circuits[actionner]
+	if !ok {
+		return true
+	}
+	switch c.state {
+	case Open:
+		if time.Since(c.openedAt) < cooldown {
+			return false
+		}
+		c.setState(actionner, HalfOpen)
+		c.trial = true
+		return true
+	case HalfOpen:
+		if c.trial {
+			return false
+		}
+		c.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success closes the circuit of the actionner
+func Success(actionner string) {
+	if !IsEnabled() {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, ok := circuits[actionner]
+	if !ok {
+		return
+	}
+	c.failures = 0
+	c.trial = false
+	if c.state != Closed {
+		c.setState(actionner, Closed)
+	}
+}
+
+// Failure records a failure of the actionner, the circuit opens when the threshold is reached
+// or when the trial of a half-open circuit fails
+func Failure(actionner string) {
+	if !IsEnabled() {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, ok := circuits[actionner]
+	if !ok {
+		c = &circuit{state: Closed}
+		circuits[actionner] = c
+	}
+	c.failures++
+	c.trial = false
+	if c.state == HalfOpen || c.state == Closed && c.failures >= threshold {
+		c.openedAt = time.Now()
+		c.setState(actionner, Open)
+	}
+}
+
+// GetOpenCircuits returns the actionners with an open or half-open circuit
+func GetOpenCircuits() []string {
+	if !IsEnabled() {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := []string{}
+	for i, j := range circuits {
+		if j.state != Closed {
+			list = append(list, i)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (c *circuit) setState(actionner string, state State) {
+	changed := (c.state == Closed) != (state == Closed)
+	c.state = state
+	if changed && onChange != nil {
+		onChange(actionner, state != Closed)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/falco-talon/falco-talon/configuration"
 	kubernetes "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/internal/tracing"
 )
 
 type Client struct {
@@ -33,6 +34,8 @@ func Init() error {
 	if err != nil {
 		return err
 	}
+	// the requests sent with the context of an event are traced
+	restConfig.Wrap(tracing.WrapTransport)
 
 	// creates the clientset
 	client.Clientset, err = calico.NewForConfig(restConfig)
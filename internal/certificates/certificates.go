@@ -0,0 +1,136 @@
+package certificates
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// Reloader serves the certificate and the client CA of the server, they're reloaded
+// when their files change, the rotations (ex: by cert-manager) don't require a restart
+type Reloader struct {
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTime  time.Time
+	config   configuration.ServerTLSConfig
+	mu       sync.Mutex
+}
+
+// minimum delay between two checks of the files
+const checkInterval = 10 * time.Second
+
+// the protocols negotiated with ALPN, the config returned for each client replaces the one of the server
+var nextProtos = []string{"h2", "http/1.1"}
+
+func NewReloader(config configuration.ServerTLSConfig) (*Reloader, error) {
+	if config.CertFile == "" {
+		return nil, errors.New("wrong `cert_file` setting")
+	}
+	if config.KeyFile == "" {
+		return nil, errors.New("wrong `key_file` setting")
+	}
+
+	r := &Reloader{config: config}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetTLSConfig returns the configuration of the server, the client certificates
+// are verified if a client CA is set, they're required by the RequireClientCert middleware
+func (r *Reloader) GetTLSConfig() *tls.Config {
+	return tlspolicy.Apply(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.reload()
+
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			c := tlspolicy.Apply(&tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				NextProtos:   nextProtos,
+			})
+			if r.clientCA != nil {
+				c.ClientCAs = r.clientCA
+				c.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+			return c, nil
+		},
+	})
+}
+
+func (r *Reloader) reload() {
+	r.mu.Lock()
+	last := r.modTime
+	r.mu.Unlock()
+
+	if time.Since(last) < checkInterval {
+		return
+	}
+	if !r.isModified(last) {
+		r.mu.Lock()
+		r.modTime = time.Now()
+		r.mu.Unlock()
+		return
+	}
+
+	if err := r.load(); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "tls"})
+		return
+	}
+	utils.PrintLog("info", utils.LogLine{Result: "certificates reloaded", Message: "tls"})
+}
+
+func (r *Reloader) isModified(since time.Time) bool {
+	for _, i := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if i == "" {
+			continue
+		}
+		s, err := os.Stat(i)
+		if err != nil {
+			continue
+		}
+		if s.ModTime().After(since) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reloader) load() error {
+	now := time.Now()
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		ca, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return errors.New("wrong `client_ca_file` setting")
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTime = now
+	return nil
+}
@@ -1,31 +1,36 @@
 package kubernetes
 
 import (
+	"fmt"
+
 	"github.com/falco-talon/falco-talon/internal/events"
 	kubernetes "github.com/falco-talon/falco-talon/internal/kubernetes/client"
 )
 
+// GetNodeContext returns the fields of the node of the pod of the event, or of the node of the event
 func GetNodeContext(event *events.Event) (map[string]interface{}, error) {
-	podName := event.GetPodName()
-	namespace := event.GetNamespaceName()
-
-	client := kubernetes.GetClient()
-	pod, err := client.GetPod(podName, namespace)
-	if err != nil {
-		return nil, err
+	client := kubernetes.GetClientFor(event.Cluster)
+	if client == nil {
+		return nil, fmt.Errorf("wrong k8s client for the cluster '%v'", event.Cluster)
 	}
-	node, err := client.GetNodeFromPod(pod)
+	node, err := client.GetNodeFromEvent(event)
 	if err != nil {
 		return nil, err
 	}
 
+	providerID := kubernetes.ParseProviderID(node.Spec.ProviderID)
+
 	elements := make(map[string]interface{})
+	elements["node.name"] = node.Name
 	elements["node.hostname"] = node.Labels["kubernetes.io/hostname"]
 	elements["node.instancetype"] = node.Labels["node.kubernetes.io/instance-type"]
 	elements["node.role"] = node.Labels["kubernetes.io/role"]
 	elements["node.topology.region"] = node.Labels["topology.kubernetes.io/region"]
 	elements["node.topology.zone"] = node.Labels["topology.kubernetes.io/zone"]
 	elements["node.spec.providerid"] = node.Spec.ProviderID
+	elements["node.provider"] = providerID.Provider
+	elements["node.instanceid"] = providerID.InstanceID
+	elements["node.unschedulable"] = node.Spec.Unschedulable
 
 	return elements, nil
 }
@@ -0,0 +1,182 @@
+package control
+
+//go:generate protoc -I ../../proto --go_out=../../proto --go_opt=paths=source_relative --go-grpc_out=../../proto --go-grpc_opt=paths=source_relative falcotalon/v1/control.proto
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/handler"
+	"github.com/falco-talon/falco-talon/internal/history"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	falcotalonv1 "github.com/falco-talon/falco-talon/proto/falcotalon/v1"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// number of the results buffered for a slow client of StreamResults
+const streamBuffer int = 100
+
+// the methods managing the rules require an admin credential, the others those to send events
+var adminMethods = []string{falcotalonv1.Control_ListRules_FullMethodName, falcotalonv1.Control_UpdateRule_FullMethodName}
+
+type server struct {
+	falcotalonv1.UnimplementedControlServer
+}
+
+// NewServer returns the gRPC server of the falcotalon.v1.Control service
+func NewServer() *grpc.Server {
+	s := grpc.NewServer(grpc.UnaryInterceptor(unaryInterceptor), grpc.StreamInterceptor(streamInterceptor))
+	falcotalonv1.RegisterControlServer(s, &server{})
+	return s
+}
+
+// Handler serves the gRPC server over the HTTP/2 of net/http, the calls are protected by the credentials to send
+// events, as the http server, the HMAC signature covers the body (the framed request message). The methods managing
+// the rules require an admin credential in addition, they're unauthenticated without
+func Handler(protect func(http.HandlerFunc) http.HandlerFunc) http.HandlerFunc {
+	return protect(NewServer().ServeHTTP)
+}
+
+// unaryInterceptor checks the admin credentials of the methods managing the rules and logs the failed calls
+func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+	if slices.Contains(adminMethods, info.FullMethod) && getAdminIdentity(ctx) == "" {
+		utils.PrintLog("warning", utils.LogLine{Error: "admin request rejected, missing or wrong credentials", Message: "control", Result: info.FullMethod})
+		return nil, status.Error(codes.Unauthenticated, "an admin credential is required")
+	}
+	resp, err := h(ctx, req)
+	if err != nil {
+		utils.PrintLog("warning", utils.LogLine{Error: err.Error(), Message: "control", Result: info.FullMethod})
+	}
+	return resp, err
+}
+
+func streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, h grpc.StreamHandler) error {
+	err := h(srv, ss)
+	if err != nil {
+		utils.PrintLog("warning", utils.LogLine{Error: err.Error(), Message: "control", Result: info.FullMethod})
+	}
+	return err
+}
+
+// getAdminIdentity returns the admin identity of the bearer token of the `authorization` metadata
+// or of the client certificate, empty if none matches
+func getAdminIdentity(ctx context.Context) string {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) != 0 {
+			authorization = v[0]
+		}
+	}
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+	}
+	return handler.GetAdminIdentity(authorization, state, configuration.GetConfiguration().Admin)
+}
+
+func (*server) SubmitEvent(_ context.Context, req *falcotalonv1.Event) (*falcotalonv1.SubmitEventResponse, error) {
+	payload, err := newEventPayload(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	event, err := events.DecodeEvent(bytes.NewReader(payload))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := handler.PublishEvent(event); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &falcotalonv1.SubmitEventResponse{TraceId: event.TraceID}, nil
+}
+
+func (*server) QueryHistory(_ context.Context, req *falcotalonv1.QueryHistoryRequest) (*falcotalonv1.QueryHistoryResponse, error) {
+	store := history.GetStore()
+	if store == nil {
+		return nil, status.Error(codes.FailedPrecondition, "the history is disabled")
+	}
+	filter, err := newHistoryFilter(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	list, err := store.Query(filter)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &falcotalonv1.QueryHistoryResponse{Results: make([]*falcotalonv1.ActionResult, 0, len(list))}
+	for _, i := range list {
+		resp.Results = append(resp.Results, newActionResult(i))
+	}
+	return resp, nil
+}
+
+func (*server) ListRules(_ context.Context, _ *falcotalonv1.ListRulesRequest) (*falcotalonv1.ListRulesResponse, error) {
+	list := *rules.GetRules()
+	resp := &falcotalonv1.ListRulesResponse{Rules: make([]*falcotalonv1.Rule, 0, len(list))}
+	for _, i := range list {
+		resp.Rules = append(resp.Rules, newRule(i))
+	}
+	return resp, nil
+}
+
+func (*server) UpdateRule(_ context.Context, req *falcotalonv1.UpdateRuleRequest) (*falcotalonv1.Rule, error) {
+	log := utils.LogLine{Message: "config", Rule: req.GetName()}
+	if req.Enabled != nil {
+		if err := rules.SetEnabled(req.GetName(), req.GetEnabled()); err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		log.Result = "rule enabled: " + strconv.FormatBool(req.GetEnabled())
+		utils.PrintLog("info", log)
+	}
+	if req.DryRun != nil {
+		if err := rules.SetDryRun(req.GetName(), req.GetDryRun()); err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		log.Result = "rule dry-run: " + strconv.FormatBool(req.GetDryRun())
+		utils.PrintLog("info", log)
+	}
+	rule := rules.FindRule(req.GetName())
+	if rule == nil {
+		return nil, status.Error(codes.NotFound, rules.ErrUnknownRule.Error())
+	}
+	return newRule(rule), nil
+}
+
+// StreamResults sends the results of the actions selected by the filter until the client cancels the call
+func (*server) StreamResults(req *falcotalonv1.StreamResultsRequest, stream falcotalonv1.Control_StreamResultsServer) error {
+	filter := history.Filter{
+		Rule:      req.GetRule(),
+		Actionner: req.GetActionner(),
+		Namespace: req.GetNamespace(),
+		Status:    req.GetStatus(),
+	}
+	c, cancel := history.Subscribe(streamBuffer)
+	defer cancel()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case entry := <-c:
+			if !filter.Match(entry) {
+				continue
+			}
+			if err := stream.Send(newActionResult(entry)); err != nil {
+				return err
+			}
+		}
+	}
+}
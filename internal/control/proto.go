@@ -0,0 +1,115 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/falco-talon/falco-talon/internal/history"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	falcotalonv1 "github.com/falco-talon/falco-talon/proto/falcotalon/v1"
+)
+
+// the messages are generated from proto/falcotalon/v1/control.proto
+
+// newEventPayload converts an Event message into the JSON payload of a Falco event
+func newEventPayload(e *falcotalonv1.Event) ([]byte, error) {
+	payload := map[string]interface{}{}
+	for k, v := range map[string]string{
+		"uuid":     e.GetUuid(),
+		"output":   e.GetOutput(),
+		"priority": e.GetPriority(),
+		"rule":     e.GetRule(),
+		"time":     e.GetTime(),
+		"source":   e.GetSource(),
+		"hostname": e.GetHostname(),
+	} {
+		if v != "" {
+			payload[k] = v
+		}
+	}
+	if e.GetTime() != "" {
+		if _, err := time.Parse(time.RFC3339Nano, e.GetTime()); err != nil {
+			return nil, errors.New("wrong `time` field")
+		}
+	}
+	outputFields := e.GetOutputFields()
+	if outputFields == nil {
+		outputFields = map[string]string{}
+	}
+	payload["output_fields"] = outputFields
+	tags := e.GetTags()
+	if tags == nil {
+		tags = []string{}
+	}
+	payload["tags"] = tags
+	return json.Marshal(payload)
+}
+
+// newHistoryFilter converts a QueryHistoryRequest message
+func newHistoryFilter(req *falcotalonv1.QueryHistoryRequest) (history.Filter, error) {
+	filter := history.Filter{
+		ID:        req.GetId(),
+		Rule:      req.GetRule(),
+		Action:    req.GetAction(),
+		Actionner: req.GetActionner(),
+		Namespace: req.GetNamespace(),
+		Status:    req.GetStatus(),
+		Limit:     int(req.GetLimit()),
+	}
+	var err error
+	if req.GetSince() != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, req.GetSince()); err != nil {
+			return filter, errors.New("wrong `since` field")
+		}
+	}
+	if req.GetUntil() != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, req.GetUntil()); err != nil {
+			return filter, errors.New("wrong `until` field")
+		}
+	}
+	if filter.Limit < 0 {
+		return filter, errors.New("wrong `limit` field")
+	}
+	return filter, nil
+}
+
+// newActionResult converts an entry of the history into an ActionResult message
+func newActionResult(entry *history.Entry) *falcotalonv1.ActionResult {
+	r := &falcotalonv1.ActionResult{
+		Id:         entry.ID,
+		Time:       entry.Time.Format(time.RFC3339Nano),
+		TraceId:    entry.TraceID,
+		Rule:       entry.Rule,
+		Action:     entry.Action,
+		Actionner:  entry.Actionner,
+		Namespace:  entry.Namespace,
+		Pod:        entry.Pod,
+		Status:     entry.Status,
+		Output:     entry.Output,
+		Error:      entry.Error,
+		DurationMs: entry.DurationMs,
+		Objects:    entry.Objects,
+	}
+	if len(entry.Parameters) != 0 {
+		if p, err := json.Marshal(entry.Parameters); err == nil {
+			r.Parameters = string(p)
+		}
+	}
+	return r
+}
+
+// newRule converts a loaded rule into a Rule message, with its runtime status
+func newRule(rule *rules.Rule) *falcotalonv1.Rule {
+	r := &falcotalonv1.Rule{
+		Name:        rule.GetName(),
+		Description: rule.Description,
+		Notifiers:   rule.GetNotifiers(),
+		Enabled:     rule.IsEnabled(),
+		DryRun:      rule.IsDryRun(),
+	}
+	for _, i := range rule.GetActions() {
+		r.Actions = append(r.Actions, i.GetName())
+	}
+	return r
+}
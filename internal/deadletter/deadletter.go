@@ -0,0 +1,249 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/nats"
+)
+
+// Entry is an event with an action which failed
+type Entry struct {
+	Event     events.Event `json:"event"`
+	Time      time.Time    `json:"time"`
+	ID        string       `json:"id"`
+	Rule      string       `json:"rule"`
+	Action    string       `json:"action"`
+	Actionner string       `json:"actionner"`
+	Error     string       `json:"error"`
+	// Pending is set if the event wasn't processed, the action and the next ones of the rule are run by the re-drive
+	Pending bool `json:"pending,omitempty"`
+}
+
+// Store keeps the entries until they're re-driven or deleted
+type Store interface {
+	Add(entry *Entry) error
+	List() ([]*Entry, error)
+	Get(id string) (*Entry, error)
+	Delete(id string) error
+}
+
+const (
+	FileStore      string = "file"
+	JetStreamStore string = "jetstream"
+
+	bucket string = "DEADLETTERS"
+)
+
+var (
+	store   Store
+	regID   = regexp.MustCompile(`^[a-f0-9-]+$`)
+	ErrNoID = errors.New("unknown entry")
+)
+
+// Init creates the store, the dead-letter queue is disabled without store
+func Init(config configuration.DeadLetterConfig) error {
+	switch config.Store {
+	case "":
+		return nil
+	case FileStore:
+		if config.Directory == "" {
+			return errors.New("wrong `directory` setting")
+		}
+		if err := os.MkdirAll(config.Directory, 0750); err != nil {
+			return err
+		}
+		store = &fileStore{directory: config.Directory}
+	case JetStreamStore:
+		kv, err := nats.GetConsumer().GetBucket(bucket, time.Duration(config.MaxAgeHours)*time.Hour)
+		if err != nil {
+			return err
+		}
+		store = &jetStreamStore{kv: kv}
+	default:
+		return fmt.Errorf("wrong `store` setting, must be '%v' or '%v'", FileStore, JetStreamStore)
+	}
+	return nil
+}
+
+func GetStore() Store {
+	return store
+}
+
+// Add stores the event with the failure of the action, if the dead-letter queue is enabled
+func Add(rule, action, actionner string, event *events.Event, failure error) error {
+	if store == nil {
+		return nil
+	}
+	return store.Add(&Entry{
+		ID:        uuid.NewString(),
+		Time:      time.Now().UTC(),
+		Rule:      rule,
+		Action:    action,
+		Actionner: actionner,
+		Error:     failure.Error(),
+		Event:     *event,
+	})
+}
+
+// AddPending adds an entry for the rule matching an event which wasn't processed, from the first action of the rule
+func AddPending(rule, action, actionner string, event *events.Event, reason error) error {
+	if store == nil {
+		return nil
+	}
+	return store.Add(&Entry{
+		ID:        uuid.NewString(),
+		Time:      time.Now().UTC(),
+		Rule:      rule,
+		Action:    action,
+		Actionner: actionner,
+		Error:     reason.Error(),
+		Event:     *event,
+		Pending:   true,
+	})
+}
+
+// CheckID returns an error if the id can't be the one of an entry
+func CheckID(id string) error {
+	if !regID.MatchString(id) {
+		return ErrNoID
+	}
+	return nil
+}
+
+type fileStore struct {
+	directory string
+}
+
+func (s *fileStore) Add(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.directory, entry.ID+".json"), b, 0600)
+}
+
+func (s *fileStore) List() ([]*Entry, error) {
+	files, err := os.ReadDir(s.directory)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Entry, 0, len(files))
+	for _, i := range files {
+		if i.IsDir() || !strings.HasSuffix(i.Name(), ".json") {
+			continue
+		}
+		entry, err := s.Get(strings.TrimSuffix(i.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		list = append(list, entry)
+	}
+	sortEntries(list)
+	return list, nil
+}
+
+func (s *fileStore) Get(id string) (*Entry, error) {
+	if err := CheckID(id); err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(s.directory, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoID
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *fileStore) Delete(id string) error {
+	if err := CheckID(id); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.directory, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoID
+	}
+	return err
+}
+
+type jetStreamStore struct {
+	kv natsgo.KeyValue
+}
+
+func (s *jetStreamStore) Add(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Put(entry.ID, b)
+	return err
+}
+
+func (s *jetStreamStore) List() ([]*Entry, error) {
+	keys, err := s.kv.Keys()
+	if errors.Is(err, natsgo.ErrNoKeysFound) {
+		return []*Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Entry, 0, len(keys))
+	for _, i := range keys {
+		entry, err := s.Get(i)
+		if err != nil {
+			continue
+		}
+		list = append(list, entry)
+	}
+	sortEntries(list)
+	return list, nil
+}
+
+func (s *jetStreamStore) Get(id string) (*Entry, error) {
+	if err := CheckID(id); err != nil {
+		return nil, err
+	}
+	v, err := s.kv.Get(id)
+	if errors.Is(err, natsgo.ErrKeyNotFound) {
+		return nil, ErrNoID
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(v.Value(), &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *jetStreamStore) Delete(id string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return s.kv.Purge(id)
+}
+
+func sortEntries(list []*Entry) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Time.Before(list[j].Time)
+	})
+}
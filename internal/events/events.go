@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,18 +14,28 @@ import (
 )
 
 type Event struct {
-	TraceID      string
-	Output       string                 `json:"output"`
-	Priority     string                 `json:"priority"`
-	Rule         string                 `json:"rule"`
-	Hostname     string                 `json:"hostname"`
-	Time         time.Time              `json:"time"`
-	Source       string                 `json:"source"`
-	OutputFields map[string]interface{} `json:"output_fields"`
-	Context      map[string]interface{} `json:"context"`
-	Tags         []interface{}          `json:"tags"`
+	traceCtx   context.Context
+	TraceID    string
+	IncidentID string
+	Cluster    string
+	// the identity impersonated by the Kubernetes actions, set by the rule
+	ImpersonateUser   string
+	ImpersonateGroups []string
+	UUID              string                 `json:"uuid,omitempty"`
+	Output            string                 `json:"output"`
+	Priority          string                 `json:"priority"`
+	Rule              string                 `json:"rule"`
+	Hostname          string                 `json:"hostname"`
+	Time              time.Time              `json:"time"`
+	Source            string                 `json:"source"`
+	OutputFields      map[string]interface{} `json:"output_fields"`
+	Context           map[string]interface{} `json:"context"`
+	Tags              []interface{}          `json:"tags"`
 }
 
+// TestTag marks the test events, their actions run in dry-run mode
+const TestTag string = "falco-talon:test"
+
 const (
 	trimPrefix = "(?i)^\\d{2}:\\d{2}:\\d{2}\\.\\d{9}\\:\\ (Debug|Info|Informational|Notice|Warning|Error|Critical|Alert|Emergency)"
 )
@@ -50,6 +61,10 @@ func DecodeEvent(payload io.Reader) (*Event, error) {
 		event.Source = "syscall"
 	}
 
+	// the uuid set by falcosidekick is kept as trace id to correlate the logs of both
+	if event.TraceID == "" {
+		event.TraceID = event.UUID
+	}
 	if event.TraceID == "" {
 		event.TraceID = uuid.New().String()
 	}
@@ -60,6 +75,16 @@ func DecodeEvent(payload io.Reader) (*Event, error) {
 	return &event, nil
 }
 
+// IsTest returns true if the event is tagged as a test, its actions aren't run
+func (event *Event) IsTest() bool {
+	for _, i := range event.Tags {
+		if fmt.Sprintf("%v", i) == TestTag {
+			return true
+		}
+	}
+	return false
+}
+
 func (event *Event) GetPodName() string {
 	if event.OutputFields["k8s.pod.name"] != nil {
 		return event.OutputFields["k8s.pod.name"].(string)
@@ -74,6 +99,17 @@ func (event *Event) GetNamespaceName() string {
 	return ""
 }
 
+// GetNodeName returns the node of the event, set by the k8smeta plugin or by the target of an audit event
+func (event *Event) GetNodeName() string {
+	if event.OutputFields["k8smeta.node.name"] != nil {
+		return event.OutputFields["k8smeta.node.name"].(string)
+	}
+	if event.GetTargetResource() == "nodes" {
+		return event.GetTargetName()
+	}
+	return ""
+}
+
 func (event *Event) GetHostname() string {
 	return event.Hostname
 }
@@ -129,6 +165,20 @@ func (event *Event) GetRemoteProtocol() string {
 	return ""
 }
 
+// GetTraceContext returns the context holding the current span of the processing of the event
+func (event *Event) GetTraceContext() context.Context {
+	if event.traceCtx == nil {
+		return context.Background()
+	}
+	return event.traceCtx
+}
+
+// SetTraceContext sets the context holding the current span of the processing of the event,
+// the calls to the APIs with this context are traced as its children
+func (event *Event) SetTraceContext(ctx context.Context) {
+	event.traceCtx = ctx
+}
+
 func (event *Event) AddContext(elements map[string]interface{}) {
 	if event.Context == nil {
 		event.Context = make(map[string]interface{})
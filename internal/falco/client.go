@@ -0,0 +1,143 @@
+package falco
+
+//go:generate protoc -I ../../proto --go_out=../../proto --go_opt=paths=source_relative --go-grpc_out=../../proto --go-grpc_opt=paths=source_relative falco/schema/schema.proto falco/outputs/outputs.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/handler"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/proto/falco/outputs"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	unixPrefix string = "unix://"
+	maxBackoff        = 30 * time.Second
+)
+
+// StartSubscriber receives the events from the gRPC output of Falco, the gRPC output of Falco
+// is a server, Falco Talon subscribes to its stream of outputs and reconnects if it's interrupted
+func StartSubscriber() {
+	config := configuration.GetConfiguration().FalcoGrpc
+
+	conn, err := newConn(config)
+	if err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "grpc"})
+	}
+	client := outputs.NewServiceClient(conn)
+
+	backoff := time.Second
+	for {
+		utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("subscribing to the Falco gRPC output '%v'", config.Address), Message: "grpc"})
+		start := time.Now()
+		err := subscribe(client)
+		if err == nil {
+			err = errors.New("stream closed")
+		}
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "grpc"})
+
+		if time.Since(start) > maxBackoff {
+			backoff = time.Second
+		}
+		time.Sleep(backoff)
+		if backoff < maxBackoff {
+			backoff *= 2
+		}
+	}
+}
+
+// newConn returns the connection to the gRPC output, the unix socket isn't encrypted,
+// a network address requires the mTLS
+func newConn(config configuration.FalcoGrpcConfig) (*grpc.ClientConn, error) {
+	if strings.HasPrefix(config.Address, unixPrefix) {
+		return grpc.NewClient(config.Address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	if config.Address == "" {
+		return nil, errors.New("wrong `address` setting")
+	}
+	if config.CertFile == "" || config.KeyFile == "" || config.CACertFile == "" {
+		return nil, errors.New("the `cert_file`, `key_file` and `ca_cert_file` settings are required for a network address")
+	}
+
+	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := os.ReadFile(config.CACertFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("wrong `ca_cert_file` setting")
+	}
+
+	tlsConfig := tlspolicy.Apply(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	})
+	return grpc.NewClient(config.Address, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+}
+
+// subscribe opens the bidirectional stream of the `sub` method, a single empty request
+// is sent, the stream is kept open to receive the outputs as they are emitted by Falco
+func subscribe(client outputs.ServiceClient) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Sub(ctx)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(&outputs.Request{}); err != nil {
+		return err
+	}
+	if _, err := stream.Header(); err != nil {
+		return err
+	}
+
+	utils.PrintLog("info", utils.LogLine{Result: "subscribed to the Falco gRPC output", Message: "grpc"})
+
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		publish(newEvent(res))
+	}
+}
+
+func publish(event *events.Event) {
+	if event.Source == "" {
+		event.Source = "syscall"
+	}
+	event.TraceID = uuid.New().String()
+
+	if err := handler.PublishEvent(event); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "grpc", TraceID: event.TraceID})
+		// the events from the stream can't be sent again
+		metrics.IncreaseDroppedEvents("publish_error", 1)
+	}
+}
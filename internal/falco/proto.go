@@ -0,0 +1,45 @@
+package falco
+
+import (
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/proto/falco/outputs"
+)
+
+// the messages are generated from proto/falco/outputs/outputs.proto
+
+// names of the values of the falco.schema.priority enum
+var priorities = []string{
+	"Emergency",
+	"Alert",
+	"Critical",
+	"Error",
+	"Warning",
+	"Notice",
+	"Informational",
+	"Debug",
+}
+
+// newEvent converts a falco.outputs.response message into an event
+func newEvent(res *outputs.Response) *events.Event {
+	event := &events.Event{
+		OutputFields: make(map[string]interface{}, len(res.GetOutputFields())),
+		Priority:     priorities[0],
+		Rule:         res.GetRule(),
+		Output:       res.GetOutput(),
+		Hostname:     res.GetHostname(),
+		Source:       res.GetSource(),
+	}
+	if p := int(res.GetPriority()); p >= 0 && p < len(priorities) {
+		event.Priority = priorities[p]
+	}
+	if res.GetTime() != nil {
+		event.Time = res.GetTime().AsTime()
+	}
+	for k, v := range res.GetOutputFields() {
+		event.OutputFields[k] = v
+	}
+	for _, i := range res.GetTags() {
+		event.Tags = append(event.Tags, i)
+	}
+	return event
+}
@@ -0,0 +1,223 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+)
+
+type GCPClient struct {
+	httpClient  *http.Client
+	tokens      map[string]*token
+	credentials *serviceAccount
+	mu          sync.Mutex
+}
+
+type token struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	expiration  time.Time
+}
+
+type serviceAccount struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+const (
+	metadataEndpoint string = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	defaultTokenURI  string = "https://oauth2.googleapis.com/token"
+	jwtBearerGrant   string = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+)
+
+var (
+	gcpClient *GCPClient
+	// the init is tried again after a failure, eg: a credentials file not mounted yet
+	initMu sync.Mutex
+)
+
+func Init() error {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	if gcpClient != nil {
+		return nil
+	}
+
+	client := &GCPClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		tokens:     make(map[string]*token),
+	}
+
+	credentialsFile := configuration.GetConfiguration().GcpConfig.CredentialsFile
+	if credentialsFile == "" {
+		credentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if credentialsFile != "" {
+		b, err := os.ReadFile(credentialsFile)
+		if err != nil {
+			return err
+		}
+		var sa serviceAccount
+		if err := json.Unmarshal(b, &sa); err != nil {
+			return err
+		}
+		if sa.Type != "service_account" {
+			return fmt.Errorf("unsupported credentials type '%v'", sa.Type)
+		}
+		client.credentials = &sa
+	}
+
+	gcpClient = client
+	return nil
+}
+
+func GetGCPClient() *GCPClient {
+	initMu.Lock()
+	defer initMu.Unlock()
+	return gcpClient
+}
+
+// GetToken returns a bearer token for the scope, the credentials are a service account key
+// if it's specified, the workload identity or the service account of the node otherwise
+func (client *GCPClient) GetToken(scope string) (string, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if t, ok := client.tokens[scope]; ok && time.Now().Add(time.Minute).Before(t.expiration) {
+		return t.AccessToken, nil
+	}
+
+	var t *token
+	var err error
+	if client.credentials != nil {
+		t, err = client.getTokenFromServiceAccount(scope)
+	} else {
+		t, err = client.getTokenFromMetadata(scope)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	client.tokens[scope] = t
+	return t.AccessToken, nil
+}
+
+func (client *GCPClient) getTokenFromMetadata(scope string) (*token, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, metadataEndpoint+"?scopes="+url.QueryEscape(scope), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	return client.doTokenRequest(req)
+}
+
+func (client *GCPClient) getTokenFromServiceAccount(scope string) (*token, error) {
+	tokenURI := client.credentials.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultTokenURI
+	}
+
+	assertion, err := client.signJWT(scope, tokenURI)
+	if err != nil {
+		return nil, err
+	}
+
+	v := url.Values{}
+	v.Set("grant_type", jwtBearerGrant)
+	v.Set("assertion", assertion)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, tokenURI, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return client.doTokenRequest(req)
+}
+
+// signJWT creates the assertion to exchange against a token, signed with the key of the service account
+func (client *GCPClient) signJWT(scope, audience string) (string, error) {
+	block, _ := pem.Decode([]byte(client.credentials.PrivateKey))
+	if block == nil {
+		return "", errors.New("wrong private key")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	key, ok := k.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("the private key is not a RSA key")
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": client.credentials.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   client.credentials.ClientEmail,
+		"scope": scope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (client *GCPClient) doTokenRequest(req *http.Request) (*token, error) {
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("can't get a token: %v", resp.Status)
+	}
+
+	var t token
+	if err := json.Unmarshal(body, &t); err != nil {
+		return nil, err
+	}
+	t.expiration = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+
+	return &t, nil
+}
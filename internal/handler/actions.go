@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/falco-talon/falco-talon/actionners"
+	"github.com/falco-talon/falco-talon/internal/async"
+)
+
+// ActionsHandler lists the actions run in the background, running or ended
+func ActionsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(async.List())
+}
+
+// ActionHandler returns the status of an action run in the background
+func ActionHandler(w http.ResponseWriter, r *http.Request) {
+	a, err := async.Get(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(a)
+}
+
+// RunActionHandler runs an actionner on the target of the request, outside of the rules, the body is
+// a manual action with the parameters of the actionner, the requester is the identity of the admin credential
+func RunActionHandler(w http.ResponseWriter, r *http.Request) {
+	var m actionners.ManualAction
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
+		return
+	}
+	m.Requester = GetRequester(r)
+
+	result, err := actionners.RunManualAction(&m)
+	if errors.Is(err, actionners.ErrManualAction) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	switch {
+	case err != nil:
+		w.WriteHeader(http.StatusInternalServerError)
+	case result.Status == async.Running:
+		w.WriteHeader(http.StatusAccepted)
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/holds"
+	"github.com/falco-talon/falco-talon/internal/nats"
+	"github.com/falco-talon/falco-talon/internal/queue"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// RuleStatus is a loaded rule with its settings overridden at runtime
+type RuleStatus struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Actions     []string `json:"actions"`
+	Notifiers   []string `json:"notifiers"`
+	Enabled     bool     `json:"enabled"`
+	DryRun      bool     `json:"dry_run"`
+}
+
+// RuleUpdate is the body of the update of a rule, the omitted fields are unchanged
+type RuleUpdate struct {
+	Enabled *bool `json:"enabled"`
+	DryRun  *bool `json:"dry_run"`
+}
+
+// QueueStatus is the number of the events waiting for their actions
+type QueueStatus struct {
+	Classes  map[string]int `json:"classes"`
+	Queued   int            `json:"queued"`
+	Received int            `json:"received"`
+	Capacity int            `json:"capacity"`
+}
+
+type requesterKey struct{}
+
+// RequireAdmin rejects the requests without an admin credential, a named bearer token or a client certificate
+// with an allowed common name, the name of the token or the common name is the identity of the requester
+func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity := GetAdminIdentity(r.Header.Get("Authorization"), r.TLS, configuration.GetConfiguration().Admin)
+		if identity == "" {
+			utils.PrintLog("warning", utils.LogLine{Error: "admin request rejected, missing or wrong credentials", Message: "auth", Result: r.RemoteAddr})
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), requesterKey{}, identity)))
+	}
+}
+
+// GetAdminIdentity returns the name of the admin token of the header or the common name of the verified client
+// certificate, empty if none is allowed
+func GetAdminIdentity(header string, state *tls.ConnectionState, config configuration.AdminConfig) string {
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok && token != "" {
+		identity := ""
+		for _, i := range config.Tokens {
+			if i.Name != "" && i.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(i.Token)) == 1 {
+				identity = i.Name
+			}
+		}
+		if identity != "" {
+			return identity
+		}
+	}
+	if len(config.AllowedCommonNames) != 0 && state != nil && len(state.VerifiedChains) != 0 && len(state.VerifiedChains[0]) != 0 {
+		if cn := state.VerifiedChains[0][0].Subject.CommonName; cn != "" && slices.Contains(config.AllowedCommonNames, cn) {
+			return cn
+		}
+	}
+	return ""
+}
+
+// GetRequester returns the identity of the admin credential of the request
+func GetRequester(r *http.Request) string {
+	s, _ := r.Context().Value(requesterKey{}).(string)
+	return s
+}
+
+func newRuleStatus(rule *rules.Rule) RuleStatus {
+	s := RuleStatus{
+		Name:        rule.GetName(),
+		Description: rule.Description,
+		Actions:     make([]string, 0, len(rule.Actions)),
+		Notifiers:   rule.GetNotifiers(),
+		Enabled:     rule.IsEnabled(),
+		DryRun:      rule.IsDryRun(),
+	}
+	if s.Notifiers == nil {
+		s.Notifiers = make([]string, 0)
+	}
+	for _, i := range rule.GetActions() {
+		s.Actions = append(s.Actions, i.GetName())
+	}
+	return s
+}
+
+// AdminRulesHandler returns the loaded rules with their status
+func AdminRulesHandler(w http.ResponseWriter, _ *http.Request) {
+	list := make([]RuleStatus, 0)
+	for _, i := range *rules.GetRules() {
+		list = append(list, newRuleStatus(i))
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// AdminRuleHandler enables or disables a rule and overrides its dry-run setting, until the restart
+func AdminRuleHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var update RuleUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
+		return
+	}
+
+	log := utils.LogLine{Message: "config", Rule: name}
+	if update.Enabled != nil {
+		if err := rules.SetEnabled(name, *update.Enabled); err != nil {
+			adminError(w, err)
+			return
+		}
+		log.Result = "rule enabled: " + strconv.FormatBool(*update.Enabled)
+		utils.PrintLog("info", log)
+	}
+	if update.DryRun != nil {
+		if err := rules.SetDryRun(name, *update.DryRun); err != nil {
+			adminError(w, err)
+			return
+		}
+		log.Result = "rule dry-run: " + strconv.FormatBool(*update.DryRun)
+		utils.PrintLog("info", log)
+	}
+
+	rule := rules.FindRule(name)
+	if rule == nil {
+		adminError(w, rules.ErrUnknownRule)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(newRuleStatus(rule))
+}
+
+// AdminQueueHandler returns the number of the events waiting for their actions, by class of priority,
+// and the usage of the queue of the received events
+func AdminQueueHandler(w http.ResponseWriter, _ *http.Request) {
+	status := QueueStatus{
+		Classes: make(map[string]int, len(queue.Classes)),
+		Queued:  queue.Len(),
+	}
+	for _, i := range queue.Classes {
+		status.Classes[i] = queue.Count(i)
+	}
+	if consumer := nats.GetConsumer(); consumer != nil {
+		status.Received, status.Capacity = consumer.GetQueueUsage()
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// AdminApprovalsHandler returns the actions pending an approval, held by the blast-radius limits until an operator
+// releases them (POST /api/v1/holds/{id}/release) or drops them
+func AdminApprovalsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(holds.List())
+}
+
+func adminError(w http.ResponseWriter, err error) {
+	if errors.Is(err, rules.ErrUnknownRule) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// maximum size of a signed body
+const maxBodySize int64 = 16 * 1024 * 1024
+
+// Authenticate rejects the requests without a valid bearer token or a valid HMAC signature of the request,
+// several tokens and secrets can be set for their rotations, the requests are accepted if no token and no secret are set
+func Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := configuration.GetConfiguration().Authentication
+		if len(config.BearerTokens) == 0 && len(config.HMACSecrets) == 0 {
+			next(w, r)
+			return
+		}
+
+		if len(config.BearerTokens) != 0 && checkBearerToken(r.Header.Get("Authorization"), config.BearerTokens) {
+			next(w, r)
+			return
+		}
+
+		if len(config.HMACSecrets) != 0 && r.Header.Get(config.HMACHeader) != "" && r.Body != nil {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			if err != nil {
+				http.Error(w, "Please send a valid request body", http.StatusBadRequest)
+				return
+			}
+			if checkTimestamp(r.Header.Get(config.HMACTimestampHeader), config.HMACToleranceSeconds) &&
+				checkSignature(r.Header.Get(config.HMACHeader), r.Header.Get(config.HMACTimestampHeader), r.Method, r.URL.RequestURI(), body, config.HMACSecrets) {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				next(w, r)
+				return
+			}
+		}
+
+		utils.PrintLog("warning", utils.LogLine{Error: "request rejected, missing or wrong credentials", Message: "auth", Result: r.RemoteAddr})
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+}
+
+func checkBearerToken(header string, tokens []string) bool {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	valid := false
+	for _, i := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(i)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// checkTimestamp returns true if the timestamp of the signature, in seconds since the epoch, is within the
+// tolerance, the replays of a captured request are rejected once it's over
+func checkTimestamp(header string, tolerance int) bool {
+	ts, err := strconv.ParseInt(header, 10, 64)
+	if err != nil {
+		return false
+	}
+	d := time.Since(time.Unix(ts, 0))
+	if d < 0 {
+		d = -d
+	}
+	return d <= time.Duration(tolerance)*time.Second
+}
+
+// checkSignature verifies the HMAC-SHA256 of the request, the signature is hex encoded,
+// with or without the prefix `sha256=` (as for the webhooks of Github)
+func checkSignature(header, timestamp, method, uri string, body []byte, secrets []string) bool {
+	signature, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil {
+		return false
+	}
+	valid := false
+	for _, i := range secrets {
+		if hmac.Equal(signature, sign(i, timestamp, method, uri, body)) {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the timestamp, the method, the path with the query and the body
+// of the request, separated by new lines
+func Sign(secret, timestamp, method, uri string, body []byte) string {
+	return hex.EncodeToString(sign(secret, timestamp, method, uri, body))
+}
+
+func sign(secret, timestamp, method, uri string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp + "\n" + method + "\n" + uri + "\n"))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/actionners"
+	"github.com/falco-talon/falco-talon/internal/deadletter"
+	"github.com/falco-talon/falco-talon/internal/idempotency"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// DeadLettersHandler lists the events with a failed action
+func DeadLettersHandler(w http.ResponseWriter, _ *http.Request) {
+	store := deadletter.GetStore()
+	if store == nil {
+		http.Error(w, "The dead-letter queue is disabled", http.StatusNotFound)
+		return
+	}
+	list, err := store.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// DeadLetterHandler returns (GET) or deletes (DELETE) an entry of the dead-letter queue
+func DeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	store := deadletter.GetStore()
+	if store == nil {
+		http.Error(w, "The dead-letter queue is disabled", http.StatusNotFound)
+		return
+	}
+
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		entry, err := store.Get(id)
+		if err != nil {
+			deadLetterError(w, err)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entry)
+	case http.MethodDelete:
+		if err := store.Delete(id); err != nil {
+			deadLetterError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// RedriveHandler runs again the failed action of an entry on its event, with a new trace id, the entry is removed
+// once the action is run, a new entry is added if it fails again. The pending entries, of the events not processed
+// before a shutdown, run all the actions of their rule. The event of an entry without action is published again,
+// the rules it matches are evaluated again, even with the idempotency
+func RedriveHandler(w http.ResponseWriter, r *http.Request) {
+	store := deadletter.GetStore()
+	if store == nil {
+		http.Error(w, "The dead-letter queue is disabled", http.StatusNotFound)
+		return
+	}
+
+	entry, err := store.Get(r.PathValue("id"))
+	if err != nil {
+		deadLetterError(w, err)
+		return
+	}
+
+	event := entry.Event
+	event.TraceID = uuid.NewString()
+	event.IncidentID = ""
+	event.Context = nil
+	var errAction error
+	if entry.Action == "" {
+		// the event was forwarded to falcosidekick when it was received, its uuid is released to not be ignored
+		// as a duplicate
+		if err := idempotency.Release(event.UUID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := publishEvent(&event); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		errAction = actionners.Redrive(entry, &event)
+		if errors.Is(errAction, actionners.ErrNotLoaded) {
+			http.Error(w, errAction.Error(), http.StatusConflict)
+			return
+		}
+	}
+	if err := store.Delete(entry.ID); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "deadletter", TraceID: event.TraceID})
+	}
+
+	if errAction != nil {
+		utils.PrintLog("error", utils.LogLine{Error: errAction.Error(), Result: "the action re-driven from the entry " + entry.ID + " failed again", Message: "deadletter", Rule: entry.Rule, Action: entry.Action, TraceID: event.TraceID})
+		http.Error(w, "the action failed again, a new entry is added: "+errAction.Error(), http.StatusBadGateway)
+		return
+	}
+
+	utils.PrintLog("info", utils.LogLine{Result: "event re-driven from the entry " + entry.ID, Message: "deadletter", Rule: entry.Rule, Action: entry.Action, TraceID: event.TraceID})
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"id": entry.ID, "trace_id": event.TraceID})
+}
+
+func deadLetterError(w http.ResponseWriter, err error) {
+	if errors.Is(err, deadletter.ErrNoID) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
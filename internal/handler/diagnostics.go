@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/breaker"
+	"github.com/falco-talon/falco-talon/internal/nats"
+	"github.com/falco-talon/falco-talon/internal/queue"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+var startTime = time.Now()
+
+// DiagnosticsHandler serves pprof, the runtime and queue stats and the loaded rules,
+// it's exposed on its own listener, the requests require one of the tokens if any
+func DiagnosticsHandler(tokens []string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/goroutines", goroutinesHandler)
+	mux.HandleFunc("GET /debug/runtime", runtimeHandler)
+	mux.HandleFunc("GET /debug/queue", queueHandler)
+	mux.HandleFunc("GET /debug/rules", RulesHandler)
+
+	if len(tokens) == 0 {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkBearerToken(r.Header.Get("Authorization"), tokens) {
+			utils.PrintLog("warning", utils.LogLine{Error: "request rejected, missing or wrong credentials", Message: "diagnostics", Result: r.RemoteAddr})
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// goroutinesHandler dumps the stacks of all the goroutines
+func goroutinesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	q.Set("debug", "2")
+	r.URL.RawQuery = q.Encode()
+	pprof.Handler("goroutine").ServeHTTP(w, r)
+}
+
+func runtimeHandler(w http.ResponseWriter, _ *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"version":        configuration.GetInfo().GitVersion,
+		"go_version":     runtime.Version(),
+		"uptime_seconds": int64(time.Since(startTime).Seconds()),
+		"goroutines":     runtime.NumGoroutine(),
+		"cpus":           runtime.NumCPU(),
+		"heap_alloc":     m.HeapAlloc,
+		"heap_objects":   m.HeapObjects,
+		"sys":            m.Sys,
+		"num_gc":         m.NumGC,
+		"pause_total_ns": m.PauseTotalNs,
+	})
+}
+
+// queueHandler returns the events waiting in the queue of nats and in the queues of the workers
+func queueHandler(w http.ResponseWriter, _ *http.Request) {
+	classes := make(map[string]int, len(queue.Classes))
+	for _, i := range queue.Classes {
+		classes[i] = queue.Count(i)
+	}
+	stats := map[string]interface{}{
+		"workers":           classes,
+		"open_actionners":   breaker.GetOpenCircuits(),
+		"ingestion_stopped": stopped.Load(),
+	}
+	if consumer := nats.GetConsumer(); consumer != nil {
+		length, capacity := consumer.GetQueueUsage()
+		stats["nats"] = map[string]int{"length": length, "capacity": capacity}
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+)
+
+const (
+	cloudEventsContentType      string = "application/cloudevents+json"
+	cloudEventsBatchContentType string = "application/cloudevents-batch+json"
+	cloudEventsSpecVersion      string = "1.0"
+	ndjsonContentType           string = "application/x-ndjson"
+	jsonlContentType            string = "application/jsonl"
+	// maximum number of events in a batch
+	maxBatchSize int = 1000
+	// maximum size of an event in a ndjson batch
+	maxEventSize int = 1024 * 1024
+)
+
+// cloudEvent is a CloudEvent in structured mode, the data is the Falco event
+type cloudEvent struct {
+	SpecVersion string          `json:"specversion"`
+	ID          string          `json:"id"`
+	Data        json.RawMessage `json:"data"`
+	DataBase64  []byte          `json:"data_base64"`
+}
+
+var (
+	errSpecVersion = errors.New("unsupported version of the cloudevents specification")
+	errBatchSize   = fmt.Errorf("too many events in the batch, the maximum is %v", maxBatchSize)
+)
+
+// decodeEvents returns the events of the request, the format is detected from the headers:
+//   - CloudEvents in structured mode, with the content-type application/cloudevents+json
+//   - batch of CloudEvents, with the content-type application/cloudevents-batch+json
+//   - CloudEvents in binary mode, with the ce-specversion header, the body is the Falco event
+//   - raw Falco events and the payloads of falcosidekick otherwise, their uuid is kept as trace id
+func decodeEvents(r *http.Request) ([]*events.Event, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch {
+	case mediaType == cloudEventsContentType:
+		var ce cloudEvent
+		if err := json.NewDecoder(r.Body).Decode(&ce); err != nil {
+			return nil, err
+		}
+		event, err := decodeCloudEvent(ce)
+		if err != nil {
+			return nil, err
+		}
+		return []*events.Event{event}, nil
+	case mediaType == cloudEventsBatchContentType:
+		var batch []cloudEvent
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			return nil, err
+		}
+		list := make([]*events.Event, 0, len(batch))
+		for _, i := range batch {
+			event, err := decodeCloudEvent(i)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, event)
+		}
+		return list, nil
+	case r.Header.Get("Ce-Specversion") != "":
+		if r.Header.Get("Ce-Specversion") != cloudEventsSpecVersion {
+			return nil, errSpecVersion
+		}
+		event, err := events.DecodeEvent(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if event.UUID == "" && r.Header.Get("Ce-Id") != "" {
+			event.TraceID = r.Header.Get("Ce-Id")
+		}
+		return []*events.Event{event}, nil
+	default:
+		event, err := events.DecodeEvent(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return []*events.Event{event}, nil
+	}
+}
+
+func decodeCloudEvent(ce cloudEvent) (*events.Event, error) {
+	if ce.SpecVersion != cloudEventsSpecVersion {
+		return nil, errSpecVersion
+	}
+
+	data := []byte(ce.Data)
+	if len(ce.DataBase64) != 0 {
+		data = ce.DataBase64
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty data")
+	}
+
+	event, err := events.DecodeEvent(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if event.UUID == "" && ce.ID != "" {
+		event.TraceID = ce.ID
+	}
+	return event, nil
+}
+
+// decodeBatch returns the raw events of a batch, as a json array or as ndjson
+func decodeBatch(r *http.Request) ([]json.RawMessage, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case ndjsonContentType, jsonlContentType:
+		var list []json.RawMessage
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			list = append(list, json.RawMessage(append([]byte{}, line...)))
+			if len(list) > maxBatchSize {
+				return nil, errBatchSize
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return list, nil
+	default:
+		var list []json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+			return nil, err
+		}
+		if len(list) > maxBatchSize {
+			return nil, errBatchSize
+		}
+		return list, nil
+	}
+}
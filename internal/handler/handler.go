@@ -1,23 +1,35 @@
 package handler
 
 import (
+	"bytes"
 	"crypto/md5" //nolint:gosec
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/jinzhu/copier"
 	"gopkg.in/yaml.v2"
 
 	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/breaker"
 	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/health"
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
 	"github.com/falco-talon/falco-talon/internal/nats"
 	"github.com/falco-talon/falco-talon/internal/rules"
 	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/notifiers"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
+// header of the id of the request, it's returned with the trace ids of the received events
+const requestIDHeader string = "X-Request-Id"
+
 func MainHandler(w http.ResponseWriter, r *http.Request) {
-	config := configuration.GetConfiguration()
 	if r.Method != http.MethodPost {
 		http.Error(w, "Please send with POST http method", http.StatusBadRequest)
 		return
@@ -28,12 +40,121 @@ func MainHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event, err := events.DecodeEvent(r.Body)
+	list, err := decodeEvents(r)
 	if err != nil {
 		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
 		return
 	}
 
+	// the request id of the client is used as trace id, to find the log lines of its event
+	if id := r.Header.Get(requestIDHeader); id != "" && len(list) == 1 && list[0].UUID == "" {
+		list[0].TraceID = id
+	}
+	ids := make([]string, 0, len(list))
+	for _, i := range list {
+		ids = append(ids, i.TraceID)
+	}
+	w.Header().Set(requestIDHeader, strings.Join(ids, ","))
+
+	if ok, delay := allowClient(r, len(list)); !ok {
+		tooManyRequests(w, r, delay, errRateLimit, len(list))
+		return
+	}
+
+	for n, i := range list {
+		if err := PublishEvent(i); err != nil {
+			if errors.Is(err, errQueueFull) || errors.Is(err, errShuttingDown) {
+				tooManyRequests(w, r, 0, err, len(list)-n)
+				return
+			}
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// BatchResult is the status of an event of a batch
+type BatchResult struct {
+	Status  string `json:"status"`
+	TraceID string `json:"trace_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Index   int    `json:"index"`
+}
+
+// BatchHandler receives several events in a request, as a json array or as ndjson,
+// the response contains the status of each event, 207 is returned if some of them are rejected
+func BatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Please send with POST http method", http.StatusBadRequest)
+		return
+	}
+
+	if r.Body == nil {
+		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := decodeBatch(r)
+	if err != nil {
+		http.Error(w, "Please send a valid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if ok, delay := allowClient(r, len(list)); !ok {
+		tooManyRequests(w, r, delay, errRateLimit, len(list))
+		return
+	}
+	if err := checkQueue(); err != nil {
+		tooManyRequests(w, r, 0, err, len(list))
+		return
+	}
+
+	results := make([]BatchResult, len(list))
+	status := http.StatusOK
+	for i, j := range list {
+		results[i].Index = i
+		event, err := events.DecodeEvent(bytes.NewReader(j))
+		if err != nil {
+			results[i].Status = "rejected"
+			results[i].Error = err.Error()
+			status = http.StatusMultiStatus
+			continue
+		}
+		results[i].TraceID = event.TraceID
+		if err := PublishEvent(event); err != nil {
+			results[i].Status = "failed"
+			results[i].Error = err.Error()
+			status = http.StatusMultiStatus
+			continue
+		}
+		results[i].Status = "accepted"
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string][]BatchResult{"results": results})
+}
+
+// PublishEvent counts the event and sends it to the consumers, the hash of its output is used for the deduplication,
+// an error is returned if the queue of the events is full, the accepted events are forwarded to falcosidekick
+func PublishEvent(event *events.Event) error {
+	if err := publishEvent(event); err != nil {
+		return err
+	}
+	notifiers.Forward(event)
+	return nil
+}
+
+// publishEvent sends the event to the consumers without forwarding it, for the events already received
+func publishEvent(event *events.Event) error {
+	config := configuration.GetConfiguration()
+
+	release, err := reserveQueue(1)
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	log := utils.LogLine{
 		Message:  "event",
 		Event:    event.Rule,
@@ -51,16 +172,41 @@ func MainHandler(w http.ResponseWriter, r *http.Request) {
 
 	hasher := md5.New() //nolint:gosec
 	hasher.Write([]byte(event.Output))
-	err = nats.GetPublisher().PublishMsg(hex.EncodeToString(hasher.Sum(nil)), event.String())
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-	}
+	return nats.GetPublisher().PublishMsg(hex.EncodeToString(hasher.Sum(nil)), event.String())
 }
 
-// HealthHandler is a simple handler to test if daemon is UP.
+// HealthHandler runs the liveness checks (rules, queue), a 503 is returned if one of them fails.
+// The status is degraded while the circuit breaker of an actionner is open.
 func HealthHandler(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, true)
+}
+
+// ReadyHandler runs all the checks (rules, queue, kubernetes, notifiers), a 503 is returned
+// if one of them fails, Falco Talon shouldn't receive the events
+func ReadyHandler(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, false)
+}
+
+func writeHealth(w http.ResponseWriter, liveness bool) {
+	healthy, checks := health.Run(liveness)
+	result := map[string]interface{}{
+		"status": "ok",
+		"checks": checks,
+	}
+	if open := breaker.GetOpenCircuits(); len(open) != 0 {
+		result["status"] = "degraded"
+		result["open_actionners"] = open
+	}
+	if configuration.GetConfiguration().Deduplication.LeaderElection {
+		result["leader"] = k8s.IsLeader()
+	}
+
 	w.Header().Add("Content-Type", "application/json")
-	_, _ = w.Write([]byte(`{"status": "ok"}`))
+	if !healthy {
+		result["status"] = "failure"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(result)
 }
 
 // Download the rule files
@@ -98,3 +244,24 @@ func RulesHandler(w http.ResponseWriter, _ *http.Request) {
 	b, _ := yaml.Marshal(q)
 	_, _ = w.Write(b)
 }
+
+// RequireClientCert rejects the requests without a verified client certificate, if a list of
+// common names is set, the certificate must have one of them (or a matching DNS SAN)
+func RequireClientCert(allowedNames []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			http.Error(w, "A valid client certificate is required", http.StatusUnauthorized)
+			return
+		}
+		if len(allowedNames) != 0 {
+			cert := r.TLS.VerifiedChains[0][0]
+			names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+			if !slices.ContainsFunc(names, func(s string) bool { return slices.Contains(allowedNames, s) }) {
+				utils.PrintLog("warning", utils.LogLine{Error: fmt.Sprintf("client certificate '%v' not allowed", cert.Subject.CommonName), Message: "tls"})
+				http.Error(w, "Client certificate not allowed", http.StatusForbidden)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
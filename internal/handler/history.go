@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/falco-talon/falco-talon/internal/history"
+)
+
+// HistoryHandler returns the results of the actions, filtered by the parameters
+// rule, action, actionner, namespace, status, since and until (RFC3339) and limit
+func HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	store := history.GetStore()
+	if store == nil {
+		http.Error(w, "The history is disabled", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := history.Filter{
+		Rule:      q.Get("rule"),
+		Action:    q.Get("action"),
+		Actionner: q.Get("actionner"),
+		Namespace: q.Get("namespace"),
+		Status:    q.Get("status"),
+	}
+	var err error
+	if s := q.Get("since"); s != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			http.Error(w, fmt.Sprintf("wrong `since` parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := q.Get("until"); s != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, s); err != nil {
+			http.Error(w, fmt.Sprintf("wrong `until` parameter: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		if filter.Limit, err = strconv.Atoi(s); err != nil || filter.Limit < 0 {
+			http.Error(w, "wrong `limit` parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	list, err := store.Query(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// HistoryEntryHandler returns the result of an action, with its parameters and its output
+func HistoryEntryHandler(w http.ResponseWriter, r *http.Request) {
+	if history.GetStore() == nil {
+		http.Error(w, "The history is disabled", http.StatusNotFound)
+		return
+	}
+
+	entry, err := history.Get(r.PathValue("id"))
+	if errors.Is(err, history.ErrNoID) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/falco-talon/falco-talon/internal/holds"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// HoldsHandler lists the actions held by the blast-radius limits
+func HoldsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(holds.List())
+}
+
+// HoldHandler returns (GET) or drops (DELETE) a held action
+func HoldHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		entry, err := holds.Get(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entry)
+	case http.MethodDelete:
+		if err := holds.Delete(id); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		utils.PrintLog("info", utils.LogLine{Result: "held action dropped", Message: "holds", Objects: map[string]string{"id": id}})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ReleaseHandler runs a held action without the blast-radius limits, the override of an operator
+func ReleaseHandler(w http.ResponseWriter, r *http.Request) {
+	entry, err := holds.Release(r.PathValue("id"))
+	if errors.Is(err, holds.ErrNoID) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.PrintLog("info", utils.LogLine{Result: "held action released", Message: "holds", Rule: entry.Rule, Action: entry.Action, Actionner: entry.Actionner, TraceID: entry.Event.TraceID})
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/nats"
+	"github.com/falco-talon/falco-talon/internal/queue"
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type clientLimiter struct {
+	lastSeen time.Time
+	*rate.Limiter
+}
+
+var (
+	clientLimiters map[string]*clientLimiter
+	limitersMu     sync.Mutex
+
+	errRateLimit    = errors.New("rate limit exceeded")
+	errQueueFull    = errors.New("the queue of events is full")
+	errShuttingDown = errors.New("falco talon is shutting down")
+
+	// reasons of the rejections in the metrics
+	dropReasons = map[error]string{
+		errRateLimit:    "rate_limit",
+		errQueueFull:    "queue_full",
+		errShuttingDown: "shutting_down",
+	}
+
+	stopped atomic.Bool
+
+	// places of the queue reserved by the events being published
+	reserved int
+	queueMu  sync.Mutex
+)
+
+// delay after which an unused limiter is removed
+const limiterTTL = 10 * time.Minute
+
+func init() {
+	clientLimiters = make(map[string]*clientLimiter)
+}
+
+// StopIngestion rejects the new events, for the shutdown
+func StopIngestion() {
+	stopped.Store(true)
+}
+
+// checkQueue returns an error if the queue of the events waiting for their actions is full
+// or if the ingestion is stopped, the sources which receive this error must retry later
+func checkQueue() error {
+	release, err := reserveQueue(1)
+	if err != nil {
+		return err
+	}
+	release()
+	return nil
+}
+
+// reserveQueue reserves n places in the queue of the events, the check and the reservation are atomic, the
+// concurrent requests can't exceed the capacity, the places are released once the events are published
+func reserveQueue(n int) (func(), error) {
+	if stopped.Load() {
+		return nil, errShuttingDown
+	}
+	consumer := nats.GetConsumer()
+	if consumer == nil {
+		return func() {}, nil
+	}
+
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	length, capacity := consumer.GetQueueUsage()
+	if capacity != 0 && length+queue.Len()+reserved+n > capacity {
+		return nil, errQueueFull
+	}
+	reserved += n
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			queueMu.Lock()
+			reserved -= n
+			queueMu.Unlock()
+		})
+	}, nil
+}
+
+// allowClient checks the rate limit of the client of the request for n events,
+// it returns the delay before the client can retry if the limit is exceeded
+func allowClient(r *http.Request, n int) (bool, time.Duration) {
+	config := configuration.GetConfiguration().Ingestion
+	if config.RateLimit <= 0 {
+		return true, 0
+	}
+
+	client, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		client = r.RemoteAddr
+	}
+
+	now := time.Now()
+
+	limitersMu.Lock()
+	l, ok := clientLimiters[client]
+	if !ok {
+		burst := config.Burst
+		if burst < 1 {
+			burst = int(math.Max(1, config.RateLimit))
+		}
+		l = &clientLimiter{Limiter: rate.NewLimiter(rate.Limit(config.RateLimit), burst)}
+		clientLimiters[client] = l
+	}
+	l.lastSeen = now
+	for i, j := range clientLimiters {
+		if now.Sub(j.lastSeen) > limiterTTL {
+			delete(clientLimiters, i)
+		}
+	}
+	limitersMu.Unlock()
+
+	reservation := l.ReserveN(now, n)
+	if !reservation.OK() {
+		// more events than the burst, the request can't be accepted at once
+		return false, time.Duration(float64(n) / config.RateLimit * float64(time.Second))
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// tooManyRequests answers with a 429 and the delay before retrying, the n events of the request are counted as dropped
+func tooManyRequests(w http.ResponseWriter, r *http.Request, delay time.Duration, reason error, n int) {
+	config := configuration.GetConfiguration().Ingestion
+	seconds := int(math.Ceil(delay.Seconds()))
+	if seconds < config.RetryAfterSeconds {
+		seconds = config.RetryAfterSeconds
+	}
+	utils.PrintLog("warning", utils.LogLine{Error: fmt.Sprintf("request rejected, %v", reason), Message: "ingestion", Result: r.RemoteAddr, TraceID: r.Header.Get(requestIDHeader)})
+	metrics.IncreaseDroppedEvents(dropReasons[reason], n)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+}
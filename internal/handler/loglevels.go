@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// LogLevels are the default level and the levels of the modules (engine, actionners, notifiers, kubernetes)
+type LogLevels struct {
+	Modules map[string]string `json:"modules"`
+	Level   string            `json:"level"`
+}
+
+// LogLevelsHandler returns the log levels
+func LogLevelsHandler(w http.ResponseWriter, _ *http.Request) {
+	level, modules := utils.GetLogLevels()
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(LogLevels{Level: level, Modules: modules})
+}
+
+// SetLogLevelsHandler replaces the log levels, the modules without a level use the default one,
+// they're reset to the configuration on restart or with a SIGHUP
+func SetLogLevelsHandler(w http.ResponseWriter, r *http.Request) {
+	var levels LogLevels
+	if err := json.NewDecoder(r.Body).Decode(&levels); err != nil {
+		http.Error(w, "Please send a valid request body", http.StatusBadRequest)
+		return
+	}
+	if err := utils.SetLogLevels(levels.Level, levels.Modules); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.PrintLog("info", utils.LogLine{Result: "log levels updated", Message: "config"})
+	LogLevelsHandler(w, r)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/falco-talon/falco-talon/internal/slo"
+)
+
+// SLOHandler returns the statistics of the automated response by rule (matches, success ratio, MTTR),
+// filtered by the `rule` query parameter
+func SLOHandler(w http.ResponseWriter, r *http.Request) {
+	list := slo.List()
+	if rule := r.URL.Query().Get("rule"); rule != "" {
+		filtered := make([]slo.RuleStats, 0, 1)
+		for _, i := range list {
+			if i.Rule == rule {
+				filtered = append(filtered, i)
+			}
+		}
+		list = filtered
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/falco-talon/falco-talon/internal/history"
+	"github.com/falco-talon/falco-talon/internal/stream"
+)
+
+const (
+	// types of the messages of the stream
+	streamEvents  string = "events"
+	streamResults string = "results"
+
+	// number of the messages buffered for a slow client
+	streamBuffer int = 100
+	// interval of the comments keeping the connection open through the proxies
+	keepAliveInterval = 15 * time.Second
+)
+
+// StreamHandler streams the processed events and the results of the actions as server-sent events (falco-event and
+// action-result), the parameter types (events,results) selects the messages, rule and namespace filter them
+func StreamHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	types := []string{streamEvents, streamResults}
+	if s := q.Get("types"); s != "" {
+		types = strings.Split(s, ",")
+		for _, i := range types {
+			if i != streamEvents && i != streamResults {
+				http.Error(w, fmt.Sprintf("wrong `types` parameter, must be '%v' or '%v'", streamEvents, streamResults), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	rule, namespace := q.Get("rule"), q.Get("namespace")
+
+	// the timeouts of the server don't apply to the stream, the read one would cancel its context
+	rc := http.NewResponseController(w)
+	err := rc.SetWriteDeadline(time.Time{})
+	if err == nil {
+		err = rc.SetReadDeadline(time.Time{})
+	}
+	if err != nil {
+		http.Error(w, "Streaming isn't supported", http.StatusInternalServerError)
+		return
+	}
+
+	var events <-chan *stream.Event
+	if slices.Contains(types, streamEvents) {
+		c, cancel := stream.Events.Subscribe(streamBuffer)
+		defer cancel()
+		events = c
+	}
+	var results <-chan *history.Entry
+	if slices.Contains(types, streamResults) {
+		c, cancel := history.Subscribe(streamBuffer)
+		defer cancel()
+		results = c
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case i := <-events:
+			if rule != "" && !slices.Contains(i.MatchedRules, rule) || namespace != "" && i.Namespace != namespace {
+				continue
+			}
+			err = writeServerSentEvent(w, "falco-event", i.TraceID, i)
+		case i := <-results:
+			if rule != "" && i.Rule != rule || namespace != "" && i.Namespace != namespace {
+				continue
+			}
+			err = writeServerSentEvent(w, "action-result", i.ID, i)
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeServerSentEvent(w http.ResponseWriter, name, id string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %v\nid: %v\ndata: %s\n\n", name, id, b)
+	return err
+}
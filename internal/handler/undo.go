@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/falco-talon/falco-talon/internal/undo"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// UndoHandler lists the reversible actions
+func UndoHandler(w http.ResponseWriter, _ *http.Request) {
+	store := undo.GetStore()
+	if store == nil {
+		http.Error(w, "The undo is disabled", http.StatusNotFound)
+		return
+	}
+	list, err := store.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list)
+}
+
+// RevertHandler restores the state before a reversible action, the entry is removed once reverted
+func RevertHandler(w http.ResponseWriter, r *http.Request) {
+	if undo.GetStore() == nil {
+		http.Error(w, "The undo is disabled", http.StatusNotFound)
+		return
+	}
+
+	entry, err := undo.Revert(r.PathValue("id"))
+	if errors.Is(err, undo.ErrNoID) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	utils.PrintLog("info", utils.LogLine{Result: "action reverted", Message: "undo", Rule: entry.Rule, Action: entry.Action, Actionner: entry.Actionner, Objects: entry.Objects, TraceID: entry.TraceID})
+
+	w.Header().Add("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entry)
+}
@@ -0,0 +1,75 @@
+package health
+
+import (
+	"errors"
+	"sync"
+)
+
+// Check is the result of a check of a dependency
+type Check struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type checker struct {
+	check    func() error
+	name     string
+	liveness bool
+}
+
+const (
+	OkStatus       string = "ok"
+	FailureStatus  string = "failure"
+	DisabledStatus string = "disabled"
+)
+
+// ErrDisabled is returned by the checks of the dependencies which aren't used
+var ErrDisabled = errors.New("disabled")
+
+var (
+	checkers []checker
+	mu       sync.RWMutex
+)
+
+// Register adds a check, the liveness ones are also used to know if Falco Talon must be restarted,
+// the other ones only to know if it's ready to receive the events
+func Register(name string, liveness bool, check func() error) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, j := range checkers {
+		if j.name == name {
+			checkers[i] = checker{name: name, liveness: liveness, check: check}
+			return
+		}
+	}
+	checkers = append(checkers, checker{name: name, liveness: liveness, check: check})
+}
+
+// Run runs the checks, only the liveness ones if liveness is true,
+// it returns false if one of them failed
+func Run(liveness bool) (bool, map[string]Check) {
+	mu.RLock()
+	list := make([]checker, 0, len(checkers))
+	for _, i := range checkers {
+		if !liveness || i.liveness {
+			list = append(list, i)
+		}
+	}
+	mu.RUnlock()
+
+	healthy := true
+	results := make(map[string]Check, len(list))
+	for _, i := range list {
+		err := i.check()
+		switch {
+		case err == nil:
+			results[i.name] = Check{Status: OkStatus}
+		case errors.Is(err, ErrDisabled):
+			results[i.name] = Check{Status: DisabledStatus}
+		default:
+			healthy = false
+			results[i.name] = Check{Status: FailureStatus, Error: err.Error()}
+		}
+	}
+	return healthy, results
+}
@@ -0,0 +1,323 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/nats"
+	"github.com/falco-talon/falco-talon/internal/stream"
+)
+
+// Entry is the result of an action triggered by a rule
+type Entry struct {
+	Time         time.Time              `json:"time"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	Objects      map[string]string      `json:"objects,omitempty"`
+	ID           string                 `json:"id"`
+	TraceID      string                 `json:"trace_id"`
+	Rule         string                 `json:"rule"`
+	Action       string                 `json:"action"`
+	Actionner    string                 `json:"actionner"`
+	Namespace    string                 `json:"namespace,omitempty"`
+	Pod          string                 `json:"pod,omitempty"`
+	Status       string                 `json:"status"`
+	Verification string                 `json:"verification,omitempty"`
+	Output       string                 `json:"output,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+	DurationMs   int64                  `json:"duration_ms"`
+}
+
+// Filter selects the entries, the empty fields match all the entries
+type Filter struct {
+	Since     time.Time
+	Until     time.Time
+	ID        string
+	Rule      string
+	Action    string
+	Actionner string
+	Namespace string
+	Status    string
+	Limit     int
+}
+
+// Store keeps the entries until their max age, the queries read all the entries of their period, without index
+type Store interface {
+	Add(entry *Entry) error
+	Get(id string) (*Entry, error)
+	Query(filter Filter) ([]*Entry, error)
+}
+
+const (
+	FileStore      string = "file"
+	JetStreamStore string = "jetstream"
+
+	bucket     string = "HISTORY"
+	filePrefix string = "history-"
+	dateFormat string = "2006-01-02"
+)
+
+var (
+	store   Store
+	results stream.Broadcaster[*Entry]
+	ErrNoID = errors.New("unknown entry")
+)
+
+// Init creates the store, the history is disabled without store
+func Init(config configuration.HistoryConfig) error {
+	maxAge := time.Duration(config.MaxAgeDays) * 24 * time.Hour
+	switch config.Store {
+	case "":
+		return nil
+	case FileStore:
+		if config.Directory == "" {
+			return errors.New("wrong `directory` setting")
+		}
+		if err := os.MkdirAll(config.Directory, 0750); err != nil {
+			return err
+		}
+		store = &fileStore{directory: config.Directory, maxAge: maxAge}
+	case JetStreamStore:
+		kv, err := nats.GetConsumer().GetBucket(bucket, maxAge)
+		if err != nil {
+			return err
+		}
+		threads, err := nats.GetConsumer().GetBucket(threadsBucket, threadsMaxAge)
+		if err != nil {
+			return err
+		}
+		store = &jetStreamStore{kv: kv, threads: threads}
+	default:
+		return fmt.Errorf("wrong `store` setting, must be '%v' or '%v'", FileStore, JetStreamStore)
+	}
+	return nil
+}
+
+func GetStore() Store {
+	return store
+}
+
+// Add stores the entry, if the history is enabled, and sends it to the subscribers
+func Add(entry *Entry) error {
+	entry.ID = uuid.NewString()
+	results.Publish(entry)
+	if store == nil {
+		return nil
+	}
+	return store.Add(entry)
+}
+
+// Subscribe returns a channel receiving the new entries, even if the history is disabled, the entries are
+// dropped if the subscriber is too slow to read them, the returned function ends the subscription
+func Subscribe(buffer int) (<-chan *Entry, func()) {
+	return results.Subscribe(buffer)
+}
+
+// Get returns the entry with the id
+func Get(id string) (*Entry, error) {
+	if store == nil {
+		return nil, errors.New("the history is disabled")
+	}
+	return store.Get(id)
+}
+
+// Match returns true if the entry is selected by the filter
+func (f Filter) Match(entry *Entry) bool {
+	switch {
+	case !f.Since.IsZero() && entry.Time.Before(f.Since),
+		!f.Until.IsZero() && entry.Time.After(f.Until),
+		f.ID != "" && entry.ID != f.ID,
+		f.Rule != "" && entry.Rule != f.Rule,
+		f.Action != "" && entry.Action != f.Action,
+		f.Actionner != "" && entry.Actionner != f.Actionner,
+		f.Namespace != "" && entry.Namespace != f.Namespace,
+		f.Status != "" && entry.Status != f.Status:
+		return false
+	}
+	return true
+}
+
+// fileStore appends the entries to a JSON Lines file by day, the files older than the max age are removed
+type fileStore struct {
+	directory string
+	day       string
+	maxAge    time.Duration
+	mu        sync.Mutex
+}
+
+func (s *fileStore) Add(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := entry.Time.UTC().Format(dateFormat)
+	if day != s.day {
+		s.day = day
+		s.cleanup()
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.directory, filePrefix+day+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+func (s *fileStore) cleanup() {
+	if s.maxAge <= 0 {
+		return
+	}
+	limit := time.Now().UTC().Add(-s.maxAge).Format(dateFormat)
+	for _, i := range s.files() {
+		if strings.TrimSuffix(strings.TrimPrefix(i, filePrefix), ".jsonl") < limit {
+			_ = os.Remove(filepath.Join(s.directory, i))
+		}
+	}
+	threads, _ := filepath.Glob(filepath.Join(s.directory, threadsFilePrefix+"*.jsonl"))
+	for _, i := range threads {
+		if strings.TrimSuffix(strings.TrimPrefix(filepath.Base(i), threadsFilePrefix), ".jsonl") < limit {
+			_ = os.Remove(i)
+		}
+	}
+}
+
+// files returns the names of the files, sorted by day
+func (s *fileStore) files() []string {
+	list, _ := filepath.Glob(filepath.Join(s.directory, filePrefix+"*.jsonl"))
+	for i := range list {
+		list[i] = filepath.Base(list[i])
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (s *fileStore) Get(id string) (*Entry, error) {
+	list, err := s.Query(Filter{ID: id})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNoID
+	}
+	return list[0], nil
+}
+
+// Query reads the files without the lock, to not block the new entries, a line being written is skipped and
+// a file removed by the cleanup is ignored
+func (s *fileStore) Query(filter Filter) ([]*Entry, error) {
+	s.mu.Lock()
+	files := s.files()
+	s.mu.Unlock()
+
+	list := []*Entry{}
+	for _, i := range files {
+		day := strings.TrimSuffix(strings.TrimPrefix(i, filePrefix), ".jsonl")
+		if !filter.Since.IsZero() && day < filter.Since.UTC().Format(dateFormat) ||
+			!filter.Until.IsZero() && day > filter.Until.UTC().Format(dateFormat) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(s.directory, i))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var entry Entry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if filter.Match(&entry) {
+				list = append(list, &entry)
+			}
+		}
+		f.Close()
+	}
+	return limit(list, filter.Limit), nil
+}
+
+type jetStreamStore struct {
+	kv      natsgo.KeyValue
+	threads natsgo.KeyValue // the references of the threads of the notifiers
+}
+
+func (s *jetStreamStore) Add(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Put(entry.ID, b)
+	return err
+}
+
+func (s *jetStreamStore) Get(id string) (*Entry, error) {
+	v, err := s.kv.Get(id)
+	if errors.Is(err, natsgo.ErrKeyNotFound) {
+		return nil, ErrNoID
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(v.Value(), &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Query gets all the keys of the bucket, the entries are only kept until their max age
+func (s *jetStreamStore) Query(filter Filter) ([]*Entry, error) {
+	keys, err := s.kv.Keys()
+	if errors.Is(err, natsgo.ErrNoKeysFound) {
+		return []*Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Entry, 0, len(keys))
+	for _, i := range keys {
+		v, err := s.kv.Get(i)
+		if err != nil {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(v.Value(), &entry); err != nil {
+			continue
+		}
+		if filter.Match(&entry) {
+			list = append(list, &entry)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Time.Before(list[j].Time)
+	})
+	return limit(list, filter.Limit), nil
+}
+
+// limit keeps the most recent entries
+func limit(list []*Entry, n int) []*Entry {
+	if n > 0 && len(list) > n {
+		return list[len(list)-n:]
+	}
+	return list
+}
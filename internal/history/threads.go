@@ -0,0 +1,173 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+)
+
+// Thread is the reference of the first message of a notifier for an event, the next messages of the
+// notifier for the event are replies to it
+type Thread struct {
+	Time      time.Time `json:"time"`
+	TraceID   string    `json:"trace_id"`
+	Notifier  string    `json:"notifier"` // the notifier and its destination, eg: slack:#alerts
+	Reference string    `json:"reference"`
+}
+
+const (
+	threadsBucket     string = "THREADS"
+	threadsFilePrefix string = "threads-"
+	// the references kept in memory, the actions of an event run in the minutes after it
+	threadsMaxAge = 24 * time.Hour
+)
+
+var (
+	threads   = make(map[string]Thread)
+	threadsMu sync.Mutex
+)
+
+func threadKey(notifier, traceID string) string {
+	return notifier + "/" + traceID
+}
+
+// SetThread keeps the reference of the first message of the notifier for the event, it's persisted with the
+// history, the replies stay in the thread after a restart
+func SetThread(notifier, traceID, reference string) error {
+	t := Thread{Time: time.Now().UTC(), TraceID: traceID, Notifier: notifier, Reference: reference}
+	threadsMu.Lock()
+	for i, j := range threads {
+		if time.Since(j.Time) > threadsMaxAge {
+			delete(threads, i)
+		}
+	}
+	threads[threadKey(notifier, traceID)] = t
+	threadsMu.Unlock()
+
+	switch s := store.(type) {
+	case *fileStore:
+		return s.addThread(&t)
+	case *jetStreamStore:
+		return s.addThread(&t)
+	}
+	return nil
+}
+
+// GetThread returns the reference of the first message of the notifier for the event, an empty string if
+// the notifier hasn't sent a message for it
+func GetThread(notifier, traceID string) (string, error) {
+	threadsMu.Lock()
+	t, ok := threads[threadKey(notifier, traceID)]
+	threadsMu.Unlock()
+	if ok {
+		return t.Reference, nil
+	}
+
+	var (
+		r   *Thread
+		err error
+	)
+	switch s := store.(type) {
+	case *fileStore:
+		r, err = s.getThread(notifier, traceID)
+	case *jetStreamStore:
+		r, err = s.getThread(notifier, traceID)
+	}
+	if err != nil || r == nil {
+		return "", err
+	}
+	return r.Reference, nil
+}
+
+func (s *fileStore) addThread(t *Thread) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(s.directory, threadsFilePrefix+t.Time.Format(dateFormat)+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// getThread reads the files of the last two days, the files of the references are removed with the others
+func (s *fileStore) getThread(notifier, traceID string) (*Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, day := range []string{now.Format(dateFormat), now.Add(-threadsMaxAge).Format(dateFormat)} {
+		f, err := os.Open(filepath.Join(s.directory, threadsFilePrefix+day+".jsonl"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if !strings.Contains(scanner.Text(), traceID) {
+				continue
+			}
+			var t Thread
+			if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+				continue
+			}
+			if t.Notifier == notifier && t.TraceID == traceID {
+				f.Close()
+				return &t, nil
+			}
+		}
+		f.Close()
+	}
+	return nil, nil
+}
+
+// the keys of the buckets only accept some characters, the notifiers are encoded
+func (s *jetStreamStore) threadKey(notifier, traceID string) string {
+	return strings.NewReplacer(":", "_", "#", "_", "/", "_", " ", "_", "@", "_").Replace(notifier) + "." + traceID
+}
+
+func (s *jetStreamStore) addThread(t *Thread) error {
+	if s.threads == nil {
+		return nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = s.threads.Put(s.threadKey(t.Notifier, t.TraceID), b)
+	return err
+}
+
+func (s *jetStreamStore) getThread(notifier, traceID string) (*Thread, error) {
+	if s.threads == nil {
+		return nil, nil
+	}
+	v, err := s.threads.Get(s.threadKey(notifier, traceID))
+	if errors.Is(err, natsgo.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t Thread
+	if err := json.Unmarshal(v.Value(), &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,124 @@
+package holds
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+)
+
+// Entry is an action held because it exceeds the blast-radius limits, it's run once released by an operator
+type Entry struct {
+	Event     events.Event `json:"event"`
+	Time      time.Time    `json:"time"`
+	ID        string       `json:"id"`
+	Rule      string       `json:"rule"`
+	Action    string       `json:"action"`
+	Actionner string       `json:"actionner"`
+	Reason    string       `json:"reason"`
+}
+
+// the entries are kept in memory, the held actions are lost with a restart
+var (
+	entries  = make(map[string]*Entry)
+	maxAge   time.Duration
+	releaser func(entry *Entry) error
+	mu       sync.Mutex
+	ErrNoID  = errors.New("unknown entry")
+)
+
+// Init sets the function which runs the released actions, the entries older than maxAge are dropped
+func Init(age time.Duration, release func(entry *Entry) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	maxAge = age
+	releaser = release
+}
+
+// Add holds an action
+func Add(rule, action, actionner, reason string, event *events.Event) *Entry {
+	entry := &Entry{
+		ID:        uuid.NewString(),
+		Time:      time.Now().UTC(),
+		Rule:      rule,
+		Action:    action,
+		Actionner: actionner,
+		Reason:    reason,
+		Event:     *event,
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	purge()
+	entries[entry.ID] = entry
+	return entry
+}
+
+// List returns the held actions, the oldest first
+func List() []*Entry {
+	mu.Lock()
+	defer mu.Unlock()
+	purge()
+	list := make([]*Entry, 0, len(entries))
+	for _, i := range entries {
+		list = append(list, i)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Time.Before(list[j].Time) })
+	return list
+}
+
+func Get(id string) (*Entry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	purge()
+	entry, ok := entries[id]
+	if !ok {
+		return nil, ErrNoID
+	}
+	return entry, nil
+}
+
+// Delete drops a held action, it won't be run
+func Delete(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := entries[id]; !ok {
+		return ErrNoID
+	}
+	delete(entries, id)
+	return nil
+}
+
+// Release runs a held action without the blast-radius limits, the entry is removed before, the action
+// can't be run twice
+func Release(id string) (*Entry, error) {
+	mu.Lock()
+	entry, ok := entries[id]
+	if ok {
+		delete(entries, id)
+	}
+	release := releaser
+	mu.Unlock()
+	if !ok {
+		return nil, ErrNoID
+	}
+	if release == nil {
+		return entry, errors.New("the release of the actions isn't initialized")
+	}
+	return entry, release(entry)
+}
+
+// purge drops the expired entries, the lock must be held
+func purge() {
+	if maxAge <= 0 {
+		return
+	}
+	for i, j := range entries {
+		if time.Since(j.Time) > maxAge {
+			delete(entries, i)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package idempotency
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+)
+
+const (
+	Local string = "local"
+	Redis string = "redis"
+)
+
+type Store interface {
+	// Claim records the key for the TTL, false is returned if it's already recorded
+	Claim(key string) (bool, error)
+	// Release forgets the key
+	Release(key string) error
+}
+
+type localStore struct {
+	keys      map[string]time.Time
+	lastPurge time.Time
+	ttl       time.Duration
+	mu        sync.Mutex
+}
+
+var store Store
+
+// Init sets the backend of the received uuids, the uuids are forgotten after the TTL
+func Init(config configuration.IdempotencyConfig) error {
+	if config.TTLSeconds <= 0 {
+		return errors.New("wrong `ttl_seconds` setting")
+	}
+	ttl := time.Duration(config.TTLSeconds) * time.Second
+
+	switch config.Backend {
+	case Redis:
+		s, err := newRedisStore(config.Redis, ttl)
+		if err != nil {
+			return err
+		}
+		store = s
+	case Local, "":
+		store = &localStore{keys: make(map[string]time.Time), ttl: ttl}
+	default:
+		return errors.New("wrong `backend` setting")
+	}
+	return nil
+}
+
+func IsEnabled() bool {
+	return store != nil
+}
+
+// IsDuplicate returns true if an event with this uuid has already been received, the uuid is recorded
+// on receipt, to ignore the duplicates received during the actions, and released if they fail, the events
+// without uuid are never duplicates
+func IsDuplicate(uuid string) (bool, error) {
+	if store == nil || uuid == "" {
+		return false, nil
+	}
+	ok, err := store.Claim(uuid)
+	if err != nil {
+		return false, err
+	}
+	return !ok, nil
+}
+
+// Release forgets the uuid, for a new delivery of the event to be processed, after a failure or for a redrive
+func Release(uuid string) error {
+	if store == nil || uuid == "" {
+		return nil
+	}
+	return store.Release(uuid)
+}
+
+func (s *localStore) Claim(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPurge) > s.ttl {
+		for k, t := range s.keys {
+			if now.Sub(t) >= s.ttl {
+				delete(s.keys, k)
+			}
+		}
+		s.lastPurge = now
+	}
+
+	if t, ok := s.keys[key]; ok && now.Sub(t) < s.ttl {
+		return false, nil
+	}
+	s.keys[key] = now
+	return true, nil
+}
+
+func (s *localStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
@@ -0,0 +1,82 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+)
+
+const redisTimeout = 2 * time.Second
+
+type redisStore struct {
+	client    *redis.Client
+	keyPrefix string
+	ttl       time.Duration
+}
+
+func newRedisStore(config configuration.RedisConfig, ttl time.Duration) (*redisStore, error) {
+	if config.Address == "" {
+		return nil, errors.New("wrong `redis.address` setting")
+	}
+
+	opts := &redis.Options{
+		Addr:         config.Address,
+		Username:     config.Username,
+		Password:     config.Password,
+		DB:           config.DB,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	}
+	if config.TLS {
+		opts.TLSConfig = tlspolicy.Apply(&tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ServerName: strings.Split(config.Address, ":")[0],
+			RootCAs:    outbound.RootCAs(),
+		})
+		if config.CACertFile != "" {
+			pool := outbound.CertPool()
+			b, err := os.ReadFile(config.CACertFile)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("no valid certificate in '%v'", config.CACertFile)
+			}
+			opts.TLSConfig.RootCAs = pool
+		}
+	}
+
+	s := &redisStore{client: redis.NewClient(opts), keyPrefix: config.KeyPrefix, ttl: ttl}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		s.client.Close()
+		return nil, fmt.Errorf("can't connect to redis: %v", err)
+	}
+	return s, nil
+}
+
+func (s *redisStore) Claim(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	// false is returned if the key exists
+	return s.client.SetNX(ctx, s.keyPrefix+key, "1", s.ttl).Result()
+}
+
+func (s *redisStore) Release(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.Del(ctx, s.keyPrefix+key).Err()
+}
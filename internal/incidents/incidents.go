@@ -0,0 +1,173 @@
+package incidents
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+)
+
+// Incident groups the events of a same workload, or of a same node, received within a time window
+type Incident struct {
+	Start  time.Time
+	Last   time.Time
+	ID     string
+	Key    string
+	Events []Entry
+	// Dropped counts the events received once the timeline is full
+	Dropped int
+	mu      sync.Mutex
+}
+
+// Entry is an event of the timeline of an incident
+type Entry struct {
+	Time     time.Time
+	TraceID  string
+	Rule     string
+	Priority string
+	Output   string
+}
+
+var (
+	incidents  map[string]*Incident
+	byID       map[string]*Incident
+	timeWindow time.Duration
+	maxEvents  int
+	mu         sync.Mutex
+)
+
+func init() {
+	incidents = make(map[string]*Incident)
+	byID = make(map[string]*Incident)
+}
+
+// Init sets the time window and the max number of events in a timeline, the incidents are disabled with a window of 0,
+// the timelines aren't limited with a max of 0
+func Init(window time.Duration, limit int) {
+	mu.Lock()
+	defer mu.Unlock()
+	timeWindow = window
+	maxEvents = limit
+}
+
+func IsEnabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return timeWindow > 0
+}
+
+// GetKey returns the key used to correlate the events, the pod if it's set, the node otherwise
+func GetKey(event *events.Event) string {
+	if pod, namespace := event.GetPodName(), event.GetNamespaceName(); pod != "" && namespace != "" {
+		return "pod/" + namespace + "/" + pod
+	}
+	if event.Hostname != "" {
+		return "node/" + event.Hostname
+	}
+	return ""
+}
+
+// Correlate adds the event to the open incident with the same key or creates a new one,
+// an incident is closed when no event is received during the time window, once its timeline
+// is full the events are only counted
+func Correlate(event *events.Event) *Incident {
+	key := GetKey(event)
+	if key == "" {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if timeWindow <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i, j := range incidents {
+		if now.Sub(j.getLast()) > timeWindow {
+			delete(incidents, i)
+			delete(byID, j.ID)
+		}
+	}
+
+	incident, ok := incidents[key]
+	if !ok {
+		incident = &Incident{
+			ID:    uuid.NewString(),
+			Key:   key,
+			Start: now,
+		}
+		incidents[key] = incident
+		byID[incident.ID] = incident
+	}
+
+	t := event.Time
+	if t.IsZero() {
+		t = now
+	}
+
+	incident.mu.Lock()
+	incident.Last = now
+	if maxEvents > 0 && len(incident.Events) >= maxEvents {
+		incident.Dropped++
+		incident.mu.Unlock()
+		return incident
+	}
+	incident.Events = append(incident.Events, Entry{
+		Time:     t,
+		TraceID:  event.TraceID,
+		Rule:     event.Rule,
+		Priority: event.Priority,
+		Output:   event.Output,
+	})
+	incident.mu.Unlock()
+
+	return incident
+}
+
+// GetIncident returns the incident with the id, nil if it's unknown or closed
+func GetIncident(id string) *Incident {
+	if id == "" {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return byID[id]
+}
+
+// GetTimeline returns a copy of the events of the incident, in chronological order
+func (incident *Incident) GetTimeline() []Entry {
+	incident.mu.Lock()
+	defer incident.mu.Unlock()
+
+	timeline := make([]Entry, len(incident.Events))
+	copy(timeline, incident.Events)
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline
+}
+
+// GetDropped returns the number of events not added to the full timeline
+func (incident *Incident) GetDropped() int {
+	incident.mu.Lock()
+	defer incident.mu.Unlock()
+	return incident.Dropped
+}
+
+// GetCount returns the number of events of the incident, the dropped ones included
+func (incident *Incident) GetCount() int {
+	incident.mu.Lock()
+	defer incident.mu.Unlock()
+	return len(incident.Events) + incident.Dropped
+}
+
+func (incident *Incident) getLast() time.Time {
+	incident.mu.Lock()
+	defer incident.mu.Unlock()
+	return incident.Last
+}
@@ -0,0 +1,128 @@
+package jetstream
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/handler"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// StartSource consumes the events from a stream of an external NATS JetStream with a durable consumer,
+// the events published while Falco Talon was down are replayed, the replicas in the same queue group share the events
+func StartSource() error {
+	config := configuration.GetConfiguration().JetStream
+
+	if config.URL == "" {
+		return errors.New("wrong `url` setting")
+	}
+	if config.Subject == "" {
+		return errors.New("wrong `subject` setting")
+	}
+	if config.QueueGroup == "" {
+		return errors.New("wrong `queue_group` setting")
+	}
+
+	opts := []nats.Option{
+		nats.Name("falco-talon"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "jetstream"})
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("reconnected to '%v'", nc.ConnectedUrl()), Message: "jetstream"})
+		}),
+	}
+	switch {
+	case config.CredentialsFile != "":
+		opts = append(opts, nats.UserCredentials(config.CredentialsFile))
+	case config.Token != "":
+		opts = append(opts, nats.Token(config.Token))
+	case config.User != "":
+		opts = append(opts, nats.UserInfo(config.User, config.Password))
+	}
+	// the TLS connections follow the TLS policy, the option must be before the ones of the certificates,
+	// nats.Secure isn't used as it would require the TLS
+	opts = append(opts, func(o *nats.Options) error {
+		o.TLSConfig = tlspolicy.Apply(&tls.Config{MinVersion: tls.VersionTLS12, RootCAs: outbound.RootCAs()})
+		return nil
+	})
+	if config.CACertFile != "" {
+		opts = append(opts, nats.RootCAs(config.CACertFile))
+	}
+	if config.CertFile != "" {
+		opts = append(opts, nats.ClientCert(config.CertFile, config.KeyFile))
+	}
+
+	nc, err := nats.Connect(config.URL, opts...)
+	if err != nil {
+		return err
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		return err
+	}
+
+	durable := config.Durable
+	if durable == "" {
+		durable = config.QueueGroup
+	}
+	subOpts := []nats.SubOpt{
+		nats.Durable(durable),
+		nats.ManualAck(),
+		nats.AckWait(time.Duration(config.AckWaitSeconds) * time.Second),
+	}
+	if config.Stream != "" {
+		subOpts = append(subOpts, nats.BindStream(config.Stream))
+	}
+	if config.MaxDeliver > 0 {
+		subOpts = append(subOpts, nats.MaxDeliver(config.MaxDeliver))
+	}
+	switch config.DeliverPolicy {
+	case "all":
+		subOpts = append(subOpts, nats.DeliverAll())
+	case "new":
+		subOpts = append(subOpts, nats.DeliverNew())
+	default:
+		return errors.New("wrong `deliver_policy` setting, must be 'all' or 'new'")
+	}
+
+	if _, err := js.QueueSubscribe(config.Subject, config.QueueGroup, handleMsg, subOpts...); err != nil {
+		return err
+	}
+
+	utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("consuming the subject '%v' with the durable consumer '%v'", config.Subject, durable), Message: "jetstream"})
+	return nil
+}
+
+// handleMsg acknowledges the message once the event is published, the message is redelivered otherwise
+func handleMsg(m *nats.Msg) {
+	event, err := events.DecodeEvent(bytes.NewReader(m.Data))
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("can't decode the message of the subject '%v': %v", m.Subject, err), Message: "jetstream"})
+		// a message which can't be decoded won't be better at the next delivery
+		_ = m.Term()
+		return
+	}
+
+	if err := handler.PublishEvent(event); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "jetstream", TraceID: event.TraceID})
+		_ = m.Nak()
+		return
+	}
+
+	if err := m.Ack(); err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "jetstream", TraceID: event.TraceID})
+	}
+}
@@ -0,0 +1,244 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/oauth"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/handler"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// Consumer reads the events from a Kafka topic as a member of a consumer group, the offsets are committed once
+// the events have been published, a consumer which fails restarts from the last committed offsets
+type Consumer struct {
+	opts   []kgo.Opt
+	client *kgo.Client
+}
+
+const (
+	PlainMechanism       string = "PLAIN"
+	ScramSHA256Mechanism string = "SCRAM-SHA-256"
+	ScramSHA512Mechanism string = "SCRAM-SHA-512"
+	OAuthMechanism       string = "OAUTHBEARER"
+
+	maxBackoff = 30 * time.Second
+)
+
+func NewConsumer(config configuration.KafkaConfig) (*Consumer, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("wrong `brokers` setting")
+	}
+	if config.Topic == "" {
+		return nil, errors.New("wrong `topic` setting")
+	}
+	if config.ConsumerGroup == "" {
+		return nil, errors.New("wrong `consumer_group` setting")
+	}
+
+	hostname, _ := os.Hostname()
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.ClientID("falco-talon-" + hostname),
+		kgo.ConsumerGroup(config.ConsumerGroup),
+		kgo.ConsumeTopics(config.Topic),
+		kgo.DisableAutoCommit(),
+		// the partitions aren't revoked while their records are published, their offsets can be committed
+		kgo.BlockRebalanceOnPoll(),
+	}
+
+	switch config.AutoOffsetReset {
+	case "earliest":
+		opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+	case "latest":
+		opts = append(opts, kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()))
+	default:
+		return nil, errors.New("wrong `auto_offset_reset` setting, must be 'earliest' or 'latest'")
+	}
+
+	if config.TLS {
+		tlsConfig, err := getTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+
+	if config.SASLMechanism != "" {
+		mechanism, err := getSASLMechanism(config.SASLMechanism)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	return &Consumer{opts: opts}, nil
+}
+
+func getTLSConfig(config configuration.KafkaConfig) (*tls.Config, error) {
+	tlsConfig := tlspolicy.Apply(&tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.InsecureSkipVerify, //nolint:gosec
+		RootCAs:            outbound.RootCAs(),
+	})
+	if config.CACertFile != "" {
+		ca, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("wrong `ca_cert_file` setting")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// getSASLMechanism returns the mechanism of the SASL authentication, the credentials are read again at each
+// authentication as they can be renewed from a secrets provider
+func getSASLMechanism(name string) (sasl.Mechanism, error) {
+	switch strings.ToUpper(name) {
+	case PlainMechanism:
+		return plain.Plain(func(context.Context) (plain.Auth, error) {
+			config := configuration.GetConfiguration().Kafka
+			return plain.Auth{User: config.Username, Pass: config.Password}, nil
+		}), nil
+	case ScramSHA256Mechanism:
+		return scram.Sha256(func(context.Context) (scram.Auth, error) {
+			config := configuration.GetConfiguration().Kafka
+			return scram.Auth{User: config.Username, Pass: config.Password}, nil
+		}), nil
+	case ScramSHA512Mechanism:
+		return scram.Sha512(func(context.Context) (scram.Auth, error) {
+			config := configuration.GetConfiguration().Kafka
+			return scram.Auth{User: config.Username, Pass: config.Password}, nil
+		}), nil
+	case OAuthMechanism:
+		return oauth.Oauth(func(context.Context) (oauth.Auth, error) {
+			return oauth.Auth{Token: configuration.GetConfiguration().Kafka.OAuthToken}, nil
+		}), nil
+	default:
+		return nil, fmt.Errorf("wrong `sasl_mechanism` setting, must be '%v', '%v', '%v' or '%v'", PlainMechanism, ScramSHA256Mechanism, ScramSHA512Mechanism, OAuthMechanism)
+	}
+}
+
+// StartConsumer consumes the topic until the end of the process, the consumer
+// joins the group again with a backoff if an error occurs
+func StartConsumer() {
+	config := configuration.GetConfiguration().Kafka
+
+	consumer, err := NewConsumer(config)
+	if err != nil {
+		utils.PrintLog("fatal", utils.LogLine{Error: err.Error(), Message: "kafka"})
+	}
+
+	backoff := time.Second
+	for {
+		err := consumer.subscribe()
+		if err == nil {
+			utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("consuming the topic '%v' with the group '%v'", config.Topic, config.ConsumerGroup), Message: "kafka"})
+			backoff = time.Second
+			err = consumer.consume()
+		}
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "kafka"})
+		consumer.close()
+
+		time.Sleep(backoff)
+		if backoff < maxBackoff {
+			backoff *= 2
+		}
+	}
+}
+
+// subscribe creates the client, it joins the group at the first poll
+func (consumer *Consumer) subscribe() error {
+	client, err := kgo.NewClient(consumer.opts...)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return err
+	}
+	consumer.client = client
+	return nil
+}
+
+func (consumer *Consumer) consume() error {
+	ctx := context.Background()
+	for {
+		fetches := consumer.client.PollFetches(ctx)
+		if fetches.IsClientClosed() {
+			return kgo.ErrClientClosed
+		}
+		// the client retries the fetches by itself, the errors are only logged
+		fetches.EachError(func(topic string, partition int32, err error) {
+			utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("can't fetch the partition %v/%v: %v", topic, partition, err), Message: "kafka"})
+		})
+
+		published := make([]*kgo.Record, 0, fetches.NumRecords())
+		for iter := fetches.RecordIter(); !iter.Done(); {
+			r := iter.Next()
+			event, err := events.DecodeEvent(bytes.NewReader(r.Value))
+			if err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("can't decode the record %v/%v/%v: %v", r.Topic, r.Partition, r.Offset, err), Message: "kafka"})
+			} else if err := handler.PublishEvent(event); err != nil {
+				// the offsets of the records already published are committed,
+				// the others are read again once the group is joined again
+				if err2 := consumer.commit(ctx, published); err2 != nil {
+					return err2
+				}
+				return err
+			}
+			published = append(published, r)
+		}
+
+		if err := consumer.commit(ctx, published); err != nil {
+			return err
+		}
+		consumer.client.AllowRebalance()
+	}
+}
+
+// commit commits the offsets of the records, the committed offset of a partition is the one of the next record to read
+func (consumer *Consumer) commit(ctx context.Context, records []*kgo.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return consumer.client.CommitRecords(ctx, records...)
+}
+
+// close leaves the group, its partitions are assigned to the other members
+func (consumer *Consumer) close() {
+	if consumer.client == nil {
+		return
+	}
+	consumer.client.Close()
+	consumer.client = nil
+}
@@ -2,9 +2,11 @@ package checks
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"strconv"
 
+	"github.com/falco-talon/falco-talon/configuration"
 	"github.com/falco-talon/falco-talon/internal/rules"
 
 	"github.com/falco-talon/falco-talon/internal/events"
@@ -35,11 +37,11 @@ func CheckPodExist(event *events.Event, _ *rules.Action) error {
 		return err
 	}
 
-	client := kubernetes.GetClient()
-	if client == nil {
-		return errors.New("wrong k8s client")
+	client, err := getClient(event)
+	if err != nil {
+		return err
 	}
-	_, err := client.GetPod(event.GetPodName(), event.GetNamespaceName())
+	_, err = client.GetPod(event.GetPodName(), event.GetNamespaceName())
 	return err
 }
 
@@ -116,10 +118,47 @@ func CheckTargetExist(event *events.Event, _ *rules.Action) error {
 		return err
 	}
 
-	client := kubernetes.GetClient()
-	if client == nil {
-		return errors.New("wrong k8s client")
+	client, err := getClient(event)
+	if err != nil {
+		return err
 	}
-	_, err := client.GetTarget(event.GetTargetResource(), event.GetTargetName(), event.GetTargetNamespace())
+	_, err = client.GetTarget(event.GetTargetResource(), event.GetTargetName(), event.GetTargetNamespace())
 	return err
 }
+
+// CheckClusterScope fails in the namespace-scoped mode, for the actionners targeting the nodes
+func CheckClusterScope(_ *events.Event, _ *rules.Action) error {
+	if configuration.GetConfiguration().IsNamespaced() {
+		return errors.New("the nodes can't be targeted in the namespace-scoped mode")
+	}
+	return nil
+}
+
+// CheckTargetNamespaceAllowed fails in the namespace-scoped mode if the target isn't in an allowed namespace,
+// the cluster-scoped resources (without namespace) and the namespaces themselves can't be targeted
+func CheckTargetNamespaceAllowed(event *events.Event, _ *rules.Action) error {
+	config := configuration.GetConfiguration()
+	if !config.IsNamespaced() {
+		return nil
+	}
+	namespace := event.GetTargetNamespace()
+	if namespace == "" || event.GetTargetResource() == "namespaces" {
+		return errors.New("the cluster-scoped resources can't be targeted in the namespace-scoped mode")
+	}
+	if !config.IsNamespaceAllowed(namespace) {
+		return fmt.Errorf("the namespace '%v' isn't allowed", namespace)
+	}
+	return nil
+}
+
+// getClient returns the client of the cluster targeted by the event
+func getClient(event *events.Event) (*kubernetes.Client, error) {
+	client := kubernetes.GetClientForEvent(event)
+	if client == nil {
+		if event.Cluster != "" {
+			return nil, fmt.Errorf("wrong k8s client for the cluster '%v'", event.Cluster)
+		}
+		return nil, errors.New("wrong k8s client")
+	}
+	return client, nil
+}
@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	errorsv1 "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/kubectl/pkg/scheme"
+
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// FieldManager is the manager of the fields applied by Falco Talon (server-side apply)
+const FieldManager string = utils.FalcoTalonStr
+
+// Apply creates or updates the object with a server-side apply, the object contains only the fields
+// managed by Falco Talon, the fields it managed before and not set anymore are removed. The apply
+// isn't forced, a conflict with the fields of another manager returns an error.
+func (client Client) Apply(ctx context.Context, obj runtime.Object) (*unstructured.Unstructured, error) {
+	u, err := toApplyConfiguration(obj)
+	if err != nil {
+		return nil, err
+	}
+	return client.ApplyUnstructured(ctx, u)
+}
+
+// ApplyUnstructured applies the object with the dynamic client, for the built-in kinds as the custom resources
+func (client Client) ApplyUnstructured(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	gvk := obj.GroupVersionKind()
+	if gvk.Kind == "" {
+		return nil, fmt.Errorf("missing kind for the object '%v'", obj.GetName())
+	}
+
+	dc, mapper, err := client.getDynamic()
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	ri, err := scopedResourceInterface(dc, mapping, obj.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	force := false
+	u, err := ri.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: FieldManager, Force: &force})
+	if errorsv1.IsConflict(err) {
+		return nil, fmt.Errorf("the %v '%v' has fields managed by another controller: %v", strings.ToLower(gvk.Kind), obj.GetName(), err)
+	}
+	return u, err
+}
+
+// ApplyLabels sets the labels of the pod (or of the node if the namespace is empty) with a server-side apply,
+// the labels applied before by Falco Talon are kept, except the removed ones
+func (client Client) ApplyLabels(ctx context.Context, name, namespace string, labels map[string]string, removed []string) error {
+	var current metav1.Object
+	var err error
+	u := new(unstructured.Unstructured)
+	if namespace == "" {
+		// a direct GET, the managed fields are stripped from the cache
+		current, err = client.Clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		u.SetAPIVersion("v1")
+		u.SetKind("Node")
+	} else {
+		current, err = client.Clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		u.SetAPIVersion("v1")
+		u.SetKind("Pod")
+		u.SetNamespace(namespace)
+	}
+	if err != nil {
+		return err
+	}
+	u.SetName(name)
+
+	applied := make(map[string]string)
+	for _, i := range GetAppliedLabels(current) {
+		if v, ok := current.GetLabels()[i]; ok {
+			applied[i] = v
+		}
+	}
+	for i, j := range labels {
+		applied[i] = j
+	}
+	for _, i := range removed {
+		delete(applied, i)
+	}
+	u.SetLabels(applied)
+
+	_, err = client.ApplyUnstructured(ctx, u)
+	return err
+}
+
+// GetAppliedLabels returns the keys of the labels of the object applied by Falco Talon, from its managed fields
+func GetAppliedLabels(obj metav1.Object) []string {
+	keys := make([]string, 0)
+	for _, i := range obj.GetManagedFields() {
+		if i.Manager != FieldManager || i.Operation != metav1.ManagedFieldsOperationApply || i.Subresource != "" || i.FieldsV1 == nil {
+			continue
+		}
+		var fields struct {
+			Metadata struct {
+				Labels map[string]interface{} `json:"f:labels"`
+			} `json:"f:metadata"`
+		}
+		if err := json.Unmarshal(i.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		for j := range fields.Metadata.Labels {
+			if strings.HasPrefix(j, "f:") {
+				keys = append(keys, strings.TrimPrefix(j, "f:"))
+			}
+		}
+	}
+	return keys
+}
+
+// toApplyConfiguration converts a typed object to the configuration of an apply, with its kind
+// and without the fields set by the API server
+func toApplyConfiguration(obj runtime.Object) (*unstructured.Unstructured, error) {
+	kinds, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil {
+		return nil, err
+	}
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: m}
+	u.SetGroupVersionKind(kinds[0])
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(u.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(u.Object, "status")
+	return u, nil
+}
@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type listers struct {
+	pods         corelisters.PodLister
+	nodes        corelisters.NodeLister
+	deployments  appslisters.DeploymentLister
+	daemonSets   appslisters.DaemonSetLister
+	statefulSets appslisters.StatefulSetLister
+	replicaSets  appslisters.ReplicaSetLister
+}
+
+// startCache starts the shared informers of the pods, the workloads and the nodes, the listers are
+// set once the caches are synced, the getters use direct GETs before, the informers live as long as the process.
+// The informers of a namespace (namespace-scoped mode) don't watch the nodes.
+func (client *Client) startCache(resync time.Duration, namespace string, cached *atomic.Pointer[listers]) {
+	factory := informers.NewSharedInformerFactoryWithOptions(client.Clientset, resync, informers.WithTransform(stripManagedFields), informers.WithNamespace(namespace))
+	l := &listers{
+		pods:         factory.Core().V1().Pods().Lister(),
+		deployments:  factory.Apps().V1().Deployments().Lister(),
+		daemonSets:   factory.Apps().V1().DaemonSets().Lister(),
+		statefulSets: factory.Apps().V1().StatefulSets().Lister(),
+		replicaSets:  factory.Apps().V1().ReplicaSets().Lister(),
+	}
+	if namespace == "" {
+		l.nodes = factory.Core().V1().Nodes().Lister()
+	}
+
+	stop := make(chan struct{})
+	factory.Start(stop)
+
+	go func() {
+		start := time.Now()
+		for i, synced := range factory.WaitForCacheSync(stop) {
+			if !synced {
+				utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("can't sync the cache of %v", i), Message: "kubernetes", Objects: map[string]string{"namespace": namespace}})
+				return
+			}
+		}
+		cached.Store(l)
+		utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("cache synced in %v", time.Since(start).Round(time.Millisecond)), Message: "kubernetes", Objects: map[string]string{"namespace": namespace}})
+	}()
+}
+
+// cacheFor returns the cache of the namespace, the one of the cluster out of the namespace-scoped mode
+func (client Client) cacheFor(namespace string) *atomic.Pointer[listers] {
+	if client.namespacedCache != nil {
+		return client.namespacedCache[namespace]
+	}
+	return client.cached
+}
+
+// fromCache returns a copy of the object from the cache, false if the cache isn't synced or
+// doesn't contain the object yet (the events can be received before the informers are notified)
+func fromCache[T interface{ DeepCopy() T }](cached *atomic.Pointer[listers], get func(*listers) (T, error)) (T, bool) {
+	var zero T
+	if cached == nil {
+		return zero, false
+	}
+	l := cached.Load()
+	if l == nil {
+		return zero, false
+	}
+	o, err := get(l)
+	if err != nil {
+		return zero, false
+	}
+	return o.DeepCopy(), true
+}
+
+// stripManagedFields reduces the memory used by the cache, the managed fields are never read
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if o, ok := obj.(metav1.Object); ok {
+		o.SetManagedFields(nil)
+	}
+	return obj, nil
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -20,9 +21,10 @@ import (
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/apimachinery/pkg/watch"
 	k8s "k8s.io/client-go/kubernetes"
+	// the legacy auth providers of the kubeconfigs (oidc), the exec plugins are supported by client-go
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/client-go/tools/remotecommand"
@@ -32,17 +34,24 @@ import (
 	klog "k8s.io/klog/v2"
 
 	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/tracing"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
 type Client struct {
 	*k8s.Clientset
 	RestConfig *rest.Config
+	load       configLoader
+	cached     *atomic.Pointer[listers]
+	// the caches of the namespaces in the namespace-scoped mode
+	namespacedCache map[string]*atomic.Pointer[listers]
+	dynamic         *dynamicResources
 }
 
 var (
 	client          *Client
 	leaseHolderChan chan string
+	leader          atomic.Bool
 	once            sync.Once
 )
 
@@ -56,23 +65,29 @@ func Init() error {
 	once.Do(func() {
 		client = new(Client)
 		config := configuration.GetConfiguration()
-		var err error
-		if config.KubeConfig != "" {
-			client.RestConfig, err = clientcmd.BuildConfigFromFlags("", config.KubeConfig)
-		} else {
-			client.RestConfig, err = rest.InClusterConfig()
+		load := func() (*rest.Config, error) {
+			if config.KubeConfig != "" || config.KubeContext != "" {
+				return loadRestConfig(config.KubeConfig, config.KubeContext)
+			}
+			restConfig, err := rest.InClusterConfig()
+			// out of a cluster, the kubeconfig of $KUBECONFIG or ~/.kube/config is used
+			if errors.Is(err, rest.ErrNotInCluster) {
+				return loadRestConfig("", "")
+			}
+			return restConfig, err
 		}
+		restConfig, err := load()
 		if err != nil {
 			initErr = err
 			return
 		}
 
-		// creates the clientset
-		client.Clientset, err = k8s.NewForConfig(client.RestConfig)
+		c, err := newClient(restConfig, load, true)
 		if err != nil {
 			initErr = err
 			return
 		}
+		client = c
 
 		// // disable klog
 		klog.InitFlags(nil)
@@ -90,6 +105,58 @@ func Init() error {
 	return initErr
 }
 
+// newClient creates the clientset for the rest config, with its cache if it's enabled and requested,
+// the config is loaded again to rebuild the transport when the credentials or the CA are rotated
+func newClient(restConfig *rest.Config, load configLoader, withCache bool) (*Client, error) {
+	config := configuration.GetConfiguration()
+	restConfig.QPS = config.KubernetesClient.QPS
+	restConfig.Burst = config.KubernetesClient.Burst
+	restConfig.Wrap(newReloadingTransport(load, restConfig.Impersonate))
+	restConfig.Wrap(newBackoffTransport(config.KubernetesClient))
+	// the requests sent with the context of an event are traced
+	restConfig.Wrap(tracing.WrapTransport)
+
+	clientset, err := k8s.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		Clientset:  clientset,
+		RestConfig: restConfig,
+		load:       load,
+		cached:     new(atomic.Pointer[listers]),
+		dynamic:    new(dynamicResources),
+	}
+
+	if withCache && config.KubernetesCache.Enabled {
+		resync := time.Duration(config.KubernetesCache.ResyncSeconds) * time.Second
+		if config.IsNamespaced() {
+			// the namespaced Roles don't allow to list the objects of the whole cluster
+			c.namespacedCache = make(map[string]*atomic.Pointer[listers], len(config.Namespaces))
+			for _, i := range config.Namespaces {
+				c.namespacedCache[i] = new(atomic.Pointer[listers])
+				c.startCache(resync, i, c.namespacedCache[i])
+			}
+		} else {
+			c.startCache(resync, "", c.cached)
+		}
+	}
+	return c, nil
+}
+
+// IsInitialized returns true if the client has been initialized, by the actionners which use it
+func IsInitialized() bool {
+	return client != nil && client.Clientset != nil
+}
+
+// Ping checks if the API server is reachable
+func (client Client) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return client.Clientset.Discovery().RESTClient().Get().AbsPath("/readyz").Do(ctx).Error()
+}
+
 func GetClient() *Client {
 	if client == nil {
 		if err := Init(); err != nil {
@@ -100,6 +167,9 @@ func GetClient() *Client {
 }
 
 func (client Client) GetPod(pod, namespace string) (*corev1.Pod, error) {
+	if p, ok := fromCache(client.cacheFor(namespace), func(l *listers) (*corev1.Pod, error) { return l.pods.Pods(namespace).Get(pod) }); ok {
+		return p, nil
+	}
 	p, err := client.Clientset.CoreV1().Pods(namespace).Get(context.Background(), pod, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("the pod '%v' in the namespace '%v' doesn't exist", pod, namespace)
@@ -107,6 +177,18 @@ func (client Client) GetPod(pod, namespace string) (*corev1.Pod, error) {
 	return p, nil
 }
 
+// ListPods returns the pods of the namespace matching the label and field selectors
+func (client Client) ListPods(namespace, labelSelector, fieldSelector string) ([]corev1.Pod, error) {
+	p, err := client.Clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.Items, nil
+}
+
 func GetContainers(pod *corev1.Pod) []string {
 	c := make([]string, 0)
 	for _, i := range pod.Spec.Containers {
@@ -116,6 +198,9 @@ func GetContainers(pod *corev1.Pod) []string {
 }
 
 func (client Client) GetDeployment(name, namespace string) (*appsv1.Deployment, error) {
+	if p, ok := fromCache(client.cacheFor(namespace), func(l *listers) (*appsv1.Deployment, error) { return l.deployments.Deployments(namespace).Get(name) }); ok {
+		return p, nil
+	}
 	p, err := client.Clientset.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("the deployment '%v' in the namespace '%v' doesn't exist", name, namespace)
@@ -124,6 +209,9 @@ func (client Client) GetDeployment(name, namespace string) (*appsv1.Deployment,
 }
 
 func (client Client) GetDaemonSet(name, namespace string) (*appsv1.DaemonSet, error) {
+	if p, ok := fromCache(client.cacheFor(namespace), func(l *listers) (*appsv1.DaemonSet, error) { return l.daemonSets.DaemonSets(namespace).Get(name) }); ok {
+		return p, nil
+	}
 	p, err := client.Clientset.AppsV1().DaemonSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("the daemonset '%v' in the namespace '%v' doesn't exist", name, namespace)
@@ -132,6 +220,9 @@ func (client Client) GetDaemonSet(name, namespace string) (*appsv1.DaemonSet, er
 }
 
 func (client Client) GetStatefulSet(name, namespace string) (*appsv1.StatefulSet, error) {
+	if p, ok := fromCache(client.cacheFor(namespace), func(l *listers) (*appsv1.StatefulSet, error) { return l.statefulSets.StatefulSets(namespace).Get(name) }); ok {
+		return p, nil
+	}
 	p, err := client.Clientset.AppsV1().StatefulSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("the statefulset '%v' in the namespace '%v' doesn't exist", name, namespace)
@@ -140,6 +231,9 @@ func (client Client) GetStatefulSet(name, namespace string) (*appsv1.StatefulSet
 }
 
 func (client Client) GetReplicaSet(name, namespace string) (*appsv1.ReplicaSet, error) {
+	if p, ok := fromCache(client.cacheFor(namespace), func(l *listers) (*appsv1.ReplicaSet, error) { return l.replicaSets.ReplicaSets(namespace).Get(name) }); ok {
+		return p, nil
+	}
 	p, err := client.Clientset.AppsV1().ReplicaSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("the replicaset '%v' in the namespace '%v' doesn't exist", name, namespace)
@@ -148,6 +242,9 @@ func (client Client) GetReplicaSet(name, namespace string) (*appsv1.ReplicaSet,
 }
 
 func (client Client) GetNode(name string) (*corev1.Node, error) {
+	if p, ok := fromCache(client.cached, func(l *listers) (*corev1.Node, error) { return l.nodes.Get(name) }); ok {
+		return p, nil
+	}
 	p, err := client.Clientset.CoreV1().Nodes().Get(context.Background(), name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("error getting node '%v': %v", name, err)
@@ -155,17 +252,20 @@ func (client Client) GetNode(name string) (*corev1.Node, error) {
 	return p, nil
 }
 
+// GetDeploymentFromPod returns the deployment of the pod, the pods are owned by a replicaset which is owned by the deployment
 func (client Client) GetDeploymentFromPod(pod *corev1.Pod) (*appsv1.Deployment, error) {
-	podName := pod.OwnerReferences[0].Name
-	namespace := pod.ObjectMeta.Namespace
-	r, err := client.GetDeployment(podName, namespace)
+	podName := pod.GetName()
+	namespace := pod.GetNamespace()
+	chain, err := client.ResolveOwnerChain(pod)
+	for _, i := range chain {
+		if i.Kind == "Deployment" {
+			return client.GetDeployment(i.Name, namespace)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
-	if r == nil {
-		return nil, fmt.Errorf("can't find the deployment for the pod'%v' in namespace '%v'", podName, namespace)
-	}
-	return r, nil
+	return nil, fmt.Errorf("can't find the deployment for the pod '%v' in namespace '%v'", podName, namespace)
 }
 
 func (client Client) GetDaemonsetFromPod(pod *corev1.Pod) (*appsv1.DaemonSet, error) {
@@ -232,7 +332,7 @@ func (client Client) GetTarget(resource, name, namespace string) (interface{}, e
 	case "deployments":
 		return client.GetDeployment(name, namespace)
 	case "daemonsets":
-		return client.GetDeployment(name, namespace)
+		return client.GetDaemonSet(name, namespace)
 	case "statefulsets":
 		return client.GetStatefulSet(name, namespace)
 	case "replicasets":
@@ -245,9 +345,12 @@ func (client Client) GetTarget(resource, name, namespace string) (interface{}, e
 		return client.GetRole(name, namespace)
 	case "clusterroles":
 		return client.GetClusterRole(name, namespace)
+	case "nodes":
+		return client.GetNode(name)
 	}
 
-	return nil, errors.New("the resource doesn't exist or its type is not yet managed")
+	// the other resources, the custom resources included, are retrieved with the dynamic client
+	return client.GetUnstructured(resource, name, namespace)
 }
 
 func (client Client) GetNamespace(name string) (*corev1.Namespace, error) {
@@ -324,6 +427,7 @@ func (client Client) GetLeaseHolder() (<-chan string, error) {
 		return leaseHolderChan, nil
 	}
 
+	config := configuration.GetConfiguration().Deduplication
 	leaseHolderChan = make(chan string, 20)
 	namespace := os.Getenv("NAMESPACE")
 	if namespace == "" {
@@ -332,7 +436,7 @@ func (client Client) GetLeaseHolder() (<-chan string, error) {
 	leaderElectionConfig := leaderelection.LeaderElectionConfig{
 		Lock: &resourcelock.LeaseLock{
 			LeaseMeta: metav1.ObjectMeta{
-				Name:      "falco-talon",
+				Name:      config.LeaseName,
 				Namespace: namespace,
 				Labels: map[string]string{
 					"app.kubernetes.io/part-of": "falco-talon",
@@ -344,12 +448,16 @@ func (client Client) GetLeaseHolder() (<-chan string, error) {
 				Identity: *utils.GetLocalIP(),
 			},
 		},
-		LeaseDuration: time.Duration(4) * time.Second,
-		RenewDeadline: time.Duration(3) * time.Second,
-		RetryPeriod:   time.Duration(2) * time.Second,
+		LeaseDuration: time.Duration(config.LeaseDurationSeconds) * time.Second,
+		RenewDeadline: time.Duration(config.RenewDeadlineSeconds) * time.Second,
+		RetryPeriod:   time.Duration(config.RetryPeriodSeconds) * time.Second,
 		Callbacks: leaderelection.LeaderCallbacks{
-			OnStartedLeading: func(_ context.Context) {},
-			OnStoppedLeading: func() {},
+			OnStartedLeading: func(_ context.Context) {
+				leader.Store(true)
+			},
+			OnStoppedLeading: func() {
+				leader.Store(false)
+			},
 			OnNewLeader: func(identity string) {
 				leaseHolderChan <- identity
 			},
@@ -362,15 +470,21 @@ func (client Client) GetLeaseHolder() (<-chan string, error) {
 		return nil, err
 	}
 
+	// Run returns when the lease is lost, the replica then becomes a candidate again
 	go func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-		leaderElector.Run(ctx)
+		for {
+			leaderElector.Run(context.Background())
+		}
 	}()
 
 	return leaseHolderChan, nil
 }
 
+// IsLeader returns true if the replica holds the lease
+func IsLeader() bool {
+	return leader.Load()
+}
+
 func (client Client) Exec(namespace, pod, container string, command []string, script string) (*bytes.Buffer, error) {
 	var err error
 	buf := &bytes.Buffer{}
@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+var (
+	clusters   = make(map[string]*Client)
+	clustersMu sync.Mutex
+)
+
+// GetClientFor returns the client of the cluster, the default client if the name is empty
+// or is the default cluster, nil if the cluster isn't configured or its client can't be created
+func GetClientFor(cluster string) *Client {
+	// the keys of the maps are lowercased by the configuration
+	cluster = strings.ToLower(cluster)
+	config := configuration.GetConfiguration().MultiCluster
+	if cluster == "" || cluster == strings.ToLower(config.DefaultCluster) {
+		return GetClient()
+	}
+
+	clustersMu.Lock()
+	defer clustersMu.Unlock()
+
+	if c, ok := clusters[cluster]; ok {
+		return c
+	}
+
+	cfg, ok := config.Clusters[cluster]
+	if !ok {
+		return nil
+	}
+
+	load := func() (*rest.Config, error) {
+		return loadRestConfig(cfg.KubeConfig, cfg.Context)
+	}
+	restConfig, err := load()
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "kubernetes", Objects: map[string]string{"cluster": cluster}})
+		return nil
+	}
+	c, err := newClient(restConfig, load, true)
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "kubernetes", Objects: map[string]string{"cluster": cluster}})
+		return nil
+	}
+
+	clusters[cluster] = c
+	return c
+}
+
+// loadRestConfig loads the context of the kubeconfig, the default loading rules ($KUBECONFIG, ~/.kube/config)
+// are used if the path is empty, the current context if the context is empty. The exec plugins of the
+// kubeconfigs (aws-iam-authenticator, gke-gcloud-auth-plugin, kubelogin) are run without interaction,
+// their credentials are refreshed by client-go when they expire.
+func loadRestConfig(kubeconfig, context string) (*rest.Config, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		loadingRules.ExplicitPath = kubeconfig
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: context}).ClientConfig()
+}
+
+// IsDefaultCluster returns true if the cluster is the one of the default client
+func IsDefaultCluster(cluster string) bool {
+	return cluster == "" || strings.EqualFold(cluster, configuration.GetConfiguration().MultiCluster.DefaultCluster)
+}
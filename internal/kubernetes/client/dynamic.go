@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+type dynamicResources struct {
+	client dynamic.Interface
+	mapper meta.RESTMapper
+	err    error
+	once   sync.Once
+}
+
+// getDynamic returns the dynamic client and the RESTMapper, the mapper discovers
+// the resources of the API server lazily and refreshes them for the unknown kinds
+func (client Client) getDynamic() (dynamic.Interface, meta.RESTMapper, error) {
+	if client.dynamic == nil {
+		return nil, nil, errors.New("the k8s client isn't initialized")
+	}
+	d := client.dynamic
+	d.once.Do(func() {
+		d.client, d.err = dynamic.NewForConfig(client.RestConfig)
+		d.mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(client.Clientset.Discovery()))
+	})
+	return d.client, d.mapper, d.err
+}
+
+// getResourceInterface returns the dynamic interface for the resource, the resource is a plural name
+// ("rollouts"), qualified by its group ("rollouts.argoproj.io") or by its version and group
+// ("rollouts.v1alpha1.argoproj.io") if the name is ambiguous
+func (client Client) getResourceInterface(resource, namespace string) (dynamic.ResourceInterface, error) {
+	dc, mapper, err := client.getDynamic()
+	if err != nil {
+		return nil, err
+	}
+
+	gvr, gr := schema.ParseResourceArg(resource)
+	var full schema.GroupVersionResource
+	if gvr != nil {
+		full, err = mapper.ResourceFor(*gvr)
+	}
+	if gvr == nil || err != nil {
+		full, err = mapper.ResourceFor(gr.WithVersion(""))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unknown resource '%v': %v", resource, err)
+	}
+
+	gvk, err := mapper.KindFor(full)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	return scopedResourceInterface(dc, mapping, namespace)
+}
+
+// scopedResourceInterface returns the dynamic interface of the mapping, in the namespace for the namespaced resources
+func scopedResourceInterface(dc dynamic.Interface, mapping *meta.RESTMapping, namespace string) (dynamic.ResourceInterface, error) {
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return dc.Resource(mapping.Resource), nil
+	}
+	if namespace == "" {
+		return nil, fmt.Errorf("missing namespace for the resource '%v'", mapping.Resource.Resource)
+	}
+	return dc.Resource(mapping.Resource).Namespace(namespace), nil
+}
+
+// GetUnstructured returns any resource of the cluster, the built-in kinds as the custom resources
+func (client Client) GetUnstructured(resource, name, namespace string) (*unstructured.Unstructured, error) {
+	ri, err := client.getResourceInterface(resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	u, err := ri.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("the %v '%v' in the namespace '%v' doesn't exist", resource, name, namespace)
+	}
+	return u, nil
+}
+
+// CreateUnstructured creates the object, the namespace of the object is used for the namespaced resources
+func (client Client) CreateUnstructured(ctx context.Context, resource string, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if obj == nil {
+		return nil, errors.New("missing object")
+	}
+	ri, err := client.getResourceInterface(resource, obj.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+	return ri.Create(ctx, obj, metav1.CreateOptions{})
+}
+
+// PatchUnstructured patches the object with a merge, a strategic merge (built-in kinds only) or a JSON patch
+func (client Client) PatchUnstructured(ctx context.Context, resource, name, namespace string, patchType types.PatchType, data []byte) (*unstructured.Unstructured, error) {
+	ri, err := client.getResourceInterface(resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return ri.Patch(ctx, name, patchType, data, metav1.PatchOptions{})
+}
+
+// DeleteUnstructured deletes the object
+func (client Client) DeleteUnstructured(ctx context.Context, resource, name, namespace string) error {
+	ri, err := client.getResourceInterface(resource, namespace)
+	if err != nil {
+		return err
+	}
+	return ri.Delete(ctx, name, metav1.DeleteOptions{})
+}
@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	eventComponent  string = "falco-talon"
+	eventController string = "falcosecurity.org/falco-talon"
+)
+
+// GetObjectReferences returns the references of the pod and of its top-level controller (deployment, daemonset,
+// statefulset, cronjob, custom controller...), they're resolved before the actions which can delete the pod
+func (client Client) GetObjectReferences(podName, namespace string) []corev1.ObjectReference {
+	pod, err := client.GetPod(podName, namespace)
+	if err != nil {
+		return nil
+	}
+	refs := []corev1.ObjectReference{
+		{Kind: "Pod", APIVersion: "v1", Namespace: pod.Namespace, Name: pod.Name, UID: pod.UID},
+	}
+	// the chain may be incomplete, its last resolved owner is used anyway
+	owner, _ := client.GetTopLevelOwner(pod)
+	if owner != nil {
+		refs = append(refs, corev1.ObjectReference{Kind: owner.Kind, APIVersion: owner.APIVersion, Namespace: owner.Namespace, Name: owner.Name, UID: owner.UID})
+	}
+	return refs
+}
+
+// CreateEvent creates an event for the object, the events of the cluster-scoped objects are in the default namespace
+func (client Client) CreateEvent(ref corev1.ObjectReference, eventType, reason, action, message string) error {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	now := metav1.Now()
+	_, err := client.Clientset.CoreV1().Events(namespace).Create(context.Background(), &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: eventComponent + "-",
+			Namespace:    namespace,
+		},
+		InvolvedObject:      ref,
+		Type:                eventType,
+		Reason:              reason,
+		Action:              action,
+		Message:             message,
+		Source:              corev1.EventSource{Component: eventComponent},
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		ReportingController: eventController,
+		ReportingInstance:   eventComponent,
+	}, metav1.CreateOptions{})
+	return err
+}
@@ -0,0 +1,36 @@
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// ExpiresAtAnnotation is the expiration date (RFC3339) of a resource created by Falco Talon
+	ExpiresAtAnnotation string = "falco-talon.io/expires-at"
+	// ExpiringLabelsAnnotation is the expiration dates (RFC3339) of the labels set by Falco Talon, in JSON
+	ExpiringLabelsAnnotation string = "falco-talon.io/expiring-labels"
+	// ExpiringLabel selects the pods and nodes with expiring labels
+	ExpiringLabel string = "falco-talon.io/expiring"
+)
+
+// GetExpiration returns the expiration date after the ttl, an empty string without ttl
+func GetExpiration(ttl string) (string, error) {
+	if ttl == "" {
+		return "", nil
+	}
+	d, err := time.ParseDuration(ttl)
+	if err != nil || d <= 0 {
+		return "", fmt.Errorf("wrong ttl '%v'", ttl)
+	}
+	return time.Now().UTC().Add(d).Format(time.RFC3339), nil
+}
+
+// GetExpiryAnnotations returns the annotations of a resource expiring after the ttl, nil without ttl
+func GetExpiryAnnotations(ttl string) map[string]string {
+	expiration, err := GetExpiration(ttl)
+	if err != nil || expiration == "" {
+		return nil
+	}
+	return map[string]string{ExpiresAtAnnotation: expiration}
+}
@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"strings"
+	"sync"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// the keys of the state of the reversible actions, the revert uses the identity of the action
+const (
+	clusterStateKey            string = "cluster"
+	impersonateUserStateKey    string = "impersonate_user"
+	impersonateGroupsStateKey  string = "impersonate_groups"
+	impersonateGroupsSeparator string = ","
+)
+
+var (
+	impersonatingClients   = make(map[string]*Client)
+	impersonatingClientsMu sync.Mutex
+)
+
+// GetClientForEvent returns the client for the actions of the event, for its cluster and with
+// the impersonation set by the rule, nil if the client can't be created
+func GetClientForEvent(event *events.Event) *Client {
+	return GetImpersonatingClient(event.Cluster, event.ImpersonateUser, event.ImpersonateGroups)
+}
+
+// GetClientForState returns the client to revert an action, with the cluster and the identity of the action
+func GetClientForState(state map[string]string) *Client {
+	var groups []string
+	if g := state[impersonateGroupsStateKey]; g != "" {
+		groups = strings.Split(g, impersonateGroupsSeparator)
+	}
+	return GetImpersonatingClient(state[clusterStateKey], state[impersonateUserStateKey], groups)
+}
+
+// AddEventToState adds the cluster and the identity of the event to the state of a reversible action
+func AddEventToState(state map[string]string, event *events.Event) {
+	if state == nil {
+		return
+	}
+	if event.Cluster != "" {
+		state[clusterStateKey] = event.Cluster
+	}
+	if event.ImpersonateUser != "" {
+		state[impersonateUserStateKey] = event.ImpersonateUser
+	}
+	if len(event.ImpersonateGroups) != 0 {
+		state[impersonateGroupsStateKey] = strings.Join(event.ImpersonateGroups, impersonateGroupsSeparator)
+	}
+}
+
+// GetImpersonatingClient returns the client of the cluster impersonating the user and the groups,
+// the reads from the cache use the identity of Falco Talon, the other requests the impersonated one
+func GetImpersonatingClient(cluster, user string, groups []string) *Client {
+	base := GetClientFor(cluster)
+	if base == nil || (user == "" && len(groups) == 0) {
+		return base
+	}
+
+	key := strings.ToLower(cluster) + "|" + user + "|" + strings.Join(groups, impersonateGroupsSeparator)
+
+	impersonatingClientsMu.Lock()
+	defer impersonatingClientsMu.Unlock()
+
+	if c, ok := impersonatingClients[key]; ok {
+		return c
+	}
+
+	restConfig := rest.CopyConfig(base.RestConfig)
+	// the transports are wrapped again by newClient
+	restConfig.WrapTransport = nil
+	restConfig.Impersonate = rest.ImpersonationConfig{UserName: user, Groups: groups}
+	c, err := newClient(restConfig, base.load, false)
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "kubernetes", Objects: map[string]string{"cluster": cluster, "user": user}})
+		return nil
+	}
+	c.cached = base.cached
+	c.namespacedCache = base.namespacedCache
+
+	impersonatingClients[key] = c
+	return c
+}
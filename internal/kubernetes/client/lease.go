@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var ErrLeaseHeld = errors.New("the lease is held by another holder")
+
+// AcquireLease creates a lease with the name, or takes it over if it has expired,
+// the returned function deletes it
+func (client Client) AcquireLease(name, holder string, ttl time.Duration) (func(), error) {
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		namespace = "falco"
+	}
+	leases := client.Clientset.CoordinationV1().Leases(namespace)
+
+	now := metav1.NewMicroTime(time.Now())
+	seconds := int32(ttl.Seconds())
+	spec := coordinationv1.LeaseSpec{
+		HolderIdentity:       &holder,
+		LeaseDurationSeconds: &seconds,
+		AcquireTime:          &now,
+		RenewTime:            &now,
+	}
+
+	lease, err := leases.Create(context.Background(), &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of": "falco-talon",
+				"app.kubernetes.io/name":    "falco-talon",
+			},
+		},
+		Spec: spec,
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		lease, err = leases.Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if s := lease.Spec; s.RenewTime != nil && s.LeaseDurationSeconds != nil &&
+			time.Since(s.RenewTime.Time) < time.Duration(*s.LeaseDurationSeconds)*time.Second {
+			return nil, ErrLeaseHeld
+		}
+		// the lease has expired, the update fails if another holder took it over in the meantime
+		lease.Spec = spec
+		lease, err = leases.Update(context.Background(), lease, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			return nil, ErrLeaseHeld
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uid := lease.UID
+	return func() {
+		_ = leases.Delete(context.Background(), name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}})
+	}, nil
+}
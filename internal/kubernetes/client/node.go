@@ -0,0 +1,94 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+)
+
+// ProviderID is the instance of the cloud provider of a node, parsed from its spec.providerID
+// (ex: aws:///us-east-1a/i-0123456789abcdef0, gce://project/europe-west1-b/instance)
+type ProviderID struct {
+	Provider   string
+	Zone       string
+	InstanceID string
+}
+
+// ParseProviderID parses the provider ID of a node, the instance ID is the last element of the path
+// for the unknown providers, an empty ProviderID is returned for the nodes without provider ID
+func ParseProviderID(providerID string) ProviderID {
+	provider, path, found := strings.Cut(providerID, "://")
+	if !found {
+		return ProviderID{}
+	}
+	elements := strings.Split(strings.Trim(path, "/"), "/")
+	p := ProviderID{
+		Provider:   provider,
+		InstanceID: elements[len(elements)-1],
+	}
+	switch provider {
+	case "aws":
+		// aws:///<zone>/<instance-id>
+		if len(elements) == 2 {
+			p.Zone = elements[0]
+		}
+	case "gce":
+		// gce://<project>/<zone>/<instance-name>
+		if len(elements) == 3 {
+			p.Zone = elements[1]
+		}
+	}
+	return p
+}
+
+// GetNodeProviderID returns the instance of the cloud provider of the node
+func (client Client) GetNodeProviderID(name string) (ProviderID, error) {
+	node, err := client.GetNode(name)
+	if err != nil {
+		return ProviderID{}, err
+	}
+	if node.Spec.ProviderID == "" {
+		return ProviderID{}, fmt.Errorf("the node '%v' has no provider ID", name)
+	}
+	return ParseProviderID(node.Spec.ProviderID), nil
+}
+
+// ListPodsOnNode returns the pods scheduled on the node, of all the namespaces
+func (client Client) ListPodsOnNode(name string) ([]corev1.Pod, error) {
+	if client.cached != nil {
+		if l := client.cached.Load(); l != nil {
+			cached, err := l.pods.List(labels.Everything())
+			if err == nil {
+				pods := make([]corev1.Pod, 0)
+				for _, i := range cached {
+					if i.Spec.NodeName == name {
+						pods = append(pods, *i.DeepCopy())
+					}
+				}
+				return pods, nil
+			}
+		}
+	}
+	return client.ListPods("", "", "spec.nodeName="+name)
+}
+
+// GetNodeFromEvent returns the node of the event, the node of its pod or the node
+// set by the event (k8smeta.node.name or the target of an audit event)
+func (client Client) GetNodeFromEvent(event *events.Event) (*corev1.Node, error) {
+	if podName, namespace := event.GetPodName(), event.GetNamespaceName(); podName != "" && namespace != "" {
+		pod, err := client.GetPod(podName, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return client.GetNodeFromPod(pod)
+	}
+	if name := event.GetNodeName(); name != "" {
+		return client.GetNode(name)
+	}
+	return nil, errors.New("missing pod or node name")
+}
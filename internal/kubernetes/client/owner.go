@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Owner is a controller in the owner chain of a pod
+type Owner struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+	UID        types.UID
+}
+
+// the owner chains are short, the limit protects from the cycles of the custom controllers
+const maxOwnerDepth int = 10
+
+// ResolveOwnerChain walks the controller references of the pod (ReplicaSet → Deployment, Job → CronJob,
+// custom controllers with the dynamic client) and returns the chain, the last owner is the top-level controller.
+// If an owner can't be retrieved, the chain up to this owner is returned with the error.
+func (client Client) ResolveOwnerChain(pod *corev1.Pod) ([]Owner, error) {
+	chain := []Owner{}
+	namespace := pod.Namespace
+	ref := metav1.GetControllerOf(pod)
+	for ref != nil {
+		if len(chain) == maxOwnerDepth {
+			return chain, fmt.Errorf("the owner chain of the pod '%v' in the namespace '%v' is too long", pod.Name, namespace)
+		}
+		chain = append(chain, Owner{APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name, Namespace: namespace, UID: ref.UID})
+		obj, err := client.getOwner(*ref, namespace)
+		if err != nil {
+			return chain, err
+		}
+		if obj.GetNamespace() == "" {
+			chain[len(chain)-1].Namespace = ""
+		}
+		ref = metav1.GetControllerOfNoCopy(obj)
+	}
+	return chain, nil
+}
+
+// GetTopLevelOwner returns the top-level controller of the pod, nil if the pod has no controller
+func (client Client) GetTopLevelOwner(pod *corev1.Pod) (*Owner, error) {
+	chain, err := client.ResolveOwnerChain(pod)
+	if len(chain) == 0 {
+		return nil, err
+	}
+	return &chain[len(chain)-1], err
+}
+
+// getOwner returns the object referenced by the owner reference, the common kinds are read with
+// the typed client (and its cache), the others with the dynamic client
+func (client Client) getOwner(ref metav1.OwnerReference, namespace string) (metav1.Object, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	switch gv.WithKind(ref.Kind) {
+	case schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}:
+		return client.GetReplicaSet(ref.Name, namespace)
+	case schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}:
+		return client.GetDeployment(ref.Name, namespace)
+	case schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}:
+		return client.GetDaemonSet(ref.Name, namespace)
+	case schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}:
+		return client.GetStatefulSet(ref.Name, namespace)
+	case schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}:
+		return client.Clientset.BatchV1().Jobs(namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+	case schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}:
+		return client.Clientset.BatchV1().CronJobs(namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+	}
+
+	dc, mapper, err := client.getDynamic()
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := mapper.RESTMapping(gv.WithKind(ref.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return nil, fmt.Errorf("unknown kind '%v' for the owner '%v': %v", ref.Kind, ref.Name, err)
+	}
+	resource := dc.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		return resource.Get(context.Background(), ref.Name, metav1.GetOptions{})
+	}
+	if namespace == "" {
+		return nil, errors.New("missing namespace")
+	}
+	return resource.Namespace(namespace).Get(context.Background(), ref.Name, metav1.GetOptions{})
+}
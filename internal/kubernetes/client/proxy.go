@@ -0,0 +1,117 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const portForwardTimeout = 10 * time.Second
+
+// ProxyTarget is a service or a pod reached through the proxy of the API server, the port is a number or a name
+type ProxyTarget struct {
+	Namespace string
+	Name      string
+	Scheme    string // http if empty
+	Port      string
+}
+
+// ProxyService sends a request to the service through the proxy of the API server, the endpoints
+// don't need to be exposed outside the cluster, the errors of the service are returned with their body
+func (client Client) ProxyService(ctx context.Context, target ProxyTarget, method, path string, body []byte) ([]byte, error) {
+	return client.proxy(ctx, "services", target, method, path, body)
+}
+
+// ProxyPod sends a request to the pod through the proxy of the API server
+func (client Client) ProxyPod(ctx context.Context, target ProxyTarget, method, path string, body []byte) ([]byte, error) {
+	return client.proxy(ctx, "pods", target, method, path, body)
+}
+
+func (client Client) proxy(ctx context.Context, resource string, target ProxyTarget, method, path string, body []byte) ([]byte, error) {
+	if target.Namespace == "" || target.Name == "" {
+		return nil, errors.New("missing namespace or name for the proxy")
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	request := client.Clientset.CoreV1().RESTClient().
+		Verb(strings.ToUpper(method)).
+		Namespace(target.Namespace).
+		Resource(resource).
+		Name(utilnet.JoinSchemeNamePort(target.Scheme, target.Name, target.Port)).
+		SubResource("proxy").
+		Suffix(path)
+	if body != nil {
+		request = request.Body(body)
+	}
+	return request.DoRaw(ctx)
+}
+
+// PortForward forwards a random local port to the port of the pod, it returns the local port and
+// the function to stop the forwarding, the forwarding is stopped with the context too
+func (client Client) PortForward(ctx context.Context, pod, namespace string, port int) (uint16, func(), error) {
+	transport, upgrader, err := spdy.RoundTripperFor(client.RestConfig)
+	if err != nil {
+		return 0, nil, err
+	}
+	url := client.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward").
+		URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stopChan := make(chan struct{})
+	readyChan := make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%v", port)}, stopChan, readyChan, io.Discard, io.Discard)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { close(stopChan) })
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyChan:
+	case err := <-errChan:
+		return 0, nil, fmt.Errorf("can't forward the port %v of the pod '%v' in the namespace '%v': %v", port, pod, namespace, err)
+	case <-ctx.Done():
+		stop()
+		return 0, nil, ctx.Err()
+	case <-time.After(portForwardTimeout):
+		stop()
+		return 0, nil, fmt.Errorf("timeout while forwarding the port %v of the pod '%v' in the namespace '%v'", port, pod, namespace)
+	}
+
+	ports, err := fw.GetPorts()
+	if err != nil || len(ports) == 0 {
+		stop()
+		return 0, nil, fmt.Errorf("can't get the forwarded port of the pod '%v' in the namespace '%v'", pod, namespace)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			stop()
+		case <-stopChan:
+		}
+	}()
+	return ports[0].Local, stop, nil
+}
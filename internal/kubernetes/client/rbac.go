@@ -0,0 +1,37 @@
+package kubernetes
+
+// Permission is a rule of the RBAC required in the cluster by an actionner or a notifier
+type Permission struct {
+	APIGroups     []string `json:"apiGroups" yaml:"apiGroups"`
+	Resources     []string `json:"resources" yaml:"resources"`
+	ResourceNames []string `json:"resourceNames,omitempty" yaml:"resourceNames,omitempty"`
+	Verbs         []string `json:"verbs" yaml:"verbs"`
+}
+
+// the cluster-scoped resources, they require a ClusterRole
+var clusterScopedResources = map[string]bool{
+	"namespaces":   true,
+	"nodes":        true,
+	"clusterroles": true,
+	"users":        true,
+	"groups":       true,
+}
+
+// IsClusterScoped returns true if one of the resources of the permission is cluster-scoped,
+// the impersonation is always allowed by a ClusterRole
+func (permission Permission) IsClusterScoped() bool {
+	for _, i := range permission.Verbs {
+		if i == "impersonate" {
+			return true
+		}
+	}
+	for _, i := range permission.Resources {
+		if clusterScopedResources[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// PodGetPermission is required by the actionners checking the pod of the event
+var PodGetPermission = Permission{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}
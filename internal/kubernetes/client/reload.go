@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// minRebuildInterval limits the rebuilds when the credentials are really invalid
+const minRebuildInterval = 10 * time.Second
+
+// configLoader loads the rest config of a client again, to get the rotated credentials and CA
+type configLoader func() (*rest.Config, error)
+
+// reloadingTransport rebuilds the transport of the client, with the config loaded again, when the credentials
+// are rejected (401) or the certificate of the API server isn't trusted anymore (rotated CA), and closes the
+// stale connections, the pods don't need to be restarted. The failed request is retried once.
+type reloadingTransport struct {
+	current     http.RoundTripper
+	load        configLoader
+	impersonate rest.ImpersonationConfig
+	lastRebuild time.Time
+	mu          sync.RWMutex
+}
+
+func newReloadingTransport(load configLoader, impersonate rest.ImpersonationConfig) func(http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		if load == nil {
+			return rt
+		}
+		return &reloadingTransport{current: rt, load: load, impersonate: impersonate}
+	}
+}
+
+func (t *reloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.RLock()
+	rt := t.current
+	t.mu.RUnlock()
+
+	resp, err := rt.RoundTrip(req)
+	// the upgraded connections (exec, port-forward) use their own transport
+	if httpstream.IsUpgradeRequest(req) {
+		return resp, err
+	}
+
+	var reason string
+	switch {
+	case err == nil && resp.StatusCode == http.StatusUnauthorized:
+		reason = "unauthorized"
+	case err != nil && isCertificateError(err):
+		reason = "certificate"
+	case err != nil && (utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err)):
+		// the stale connections are closed, the next requests open new ones
+		utilnet.CloseIdleConnectionsFor(rt)
+		metrics.IncreaseClientRebuilds("stale_connection")
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return resp, err
+		}
+		return rt.RoundTrip(req)
+	default:
+		return resp, err
+	}
+
+	if req.Body != nil && req.GetBody == nil {
+		return resp, err
+	}
+	next, rebuilt := t.rebuild(rt, reason)
+	if !rebuilt {
+		return resp, err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if req.GetBody != nil {
+		body, err2 := req.GetBody()
+		if err2 != nil {
+			return nil, err2
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	return next.RoundTrip(req)
+}
+
+// rebuild replaces the transport, once for the concurrent requests, false if it has been rebuilt recently
+func (t *reloadingTransport) rebuild(failed http.RoundTripper, reason string) (http.RoundTripper, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// another request has already rebuilt it
+	if t.current != failed {
+		return t.current, true
+	}
+	if time.Since(t.lastRebuild) < minRebuildInterval {
+		return nil, false
+	}
+	t.lastRebuild = time.Now()
+
+	log := utils.LogLine{Message: "kubernetes", Objects: map[string]string{"reason": reason}}
+	restConfig, err := t.load()
+	if err == nil {
+		restConfig.Impersonate = t.impersonate
+		var rt http.RoundTripper
+		rt, err = rest.TransportFor(restConfig)
+		if err == nil {
+			utilnet.CloseIdleConnectionsFor(t.current)
+			t.current = rt
+		}
+	}
+	if err != nil {
+		log.Error = err.Error()
+		utils.PrintLog("error", log)
+		return nil, false
+	}
+
+	metrics.IncreaseClientRebuilds(reason)
+	log.Result = "client rebuilt"
+	utils.PrintLog("warning", log)
+	return t.current, true
+}
+
+// isCertificateError returns true if the certificate of the API server isn't trusted
+func isCertificateError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var verification *tls.CertificateVerificationError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &verification)
+}
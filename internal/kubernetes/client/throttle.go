@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// backoffTransport retries the requests throttled by the API priority and fairness (429),
+// with an exponential backoff, the Retry-After header of the API server is a minimum
+type backoffTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	initial    time.Duration
+	max        time.Duration
+}
+
+func newBackoffTransport(config configuration.KubernetesClientConfig) func(http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return &backoffTransport{
+			next:       rt,
+			maxRetries: config.MaxRetries,
+			initial:    time.Duration(config.InitialBackoffMs) * time.Millisecond,
+			max:        time.Duration(config.MaxBackoffSeconds) * time.Second,
+		}
+	}
+}
+
+func (t *backoffTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	delay := t.initial
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= t.maxRetries {
+			return resp, err
+		}
+		// the body of the request must be replayable to retry it
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		wait := delay
+		if s, err2 := strconv.Atoi(resp.Header.Get("Retry-After")); err2 == nil && time.Duration(s)*time.Second > wait {
+			wait = time.Duration(s) * time.Second
+		}
+		if wait > t.max {
+			wait = t.max
+		}
+		resp.Body.Close()
+
+		metrics.IncreaseThrottledRequests(req.Method)
+		utils.PrintLog("warning", utils.LogLine{
+			Message: "kubernetes",
+			Error:   "request throttled by the API server",
+			Result:  "retry in " + wait.String(),
+			Objects: map[string]string{"method": req.Method, "path": req.URL.Path},
+		})
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+
+		if req.GetBody != nil {
+			body, err2 := req.GetBody()
+			if err2 != nil {
+				return nil, err2
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		delay *= 2
+	}
+}
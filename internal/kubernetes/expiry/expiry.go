@@ -0,0 +1,182 @@
+package expiry
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	kubernetes "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const managedBySelector string = "app.kubernetes.io/managed-by=" + utils.FalcoTalonStr
+
+// the networkpolicies created by the actionners, the missing CRDs are ignored
+var policies = []schema.GroupVersionResource{
+	{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	{Group: "projectcalico.org", Version: "v3", Resource: "networkpolicies"},
+	{Group: "cilium.io", Version: "v2", Resource: "ciliumnetworkpolicies"},
+}
+
+var dynamicClient dynamic.Interface
+
+// Start removes periodically the expired resources and labels created by Falco Talon
+func Start(interval time.Duration) error {
+	if err := kubernetes.Init(); err != nil {
+		return err
+	}
+	var err error
+	dynamicClient, err = dynamic.NewForConfig(kubernetes.GetClient().RestConfig)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		for range ticker.C {
+			reconcilePolicies()
+			reconcileLabels()
+		}
+	}()
+	return nil
+}
+
+// getNamespaces returns the namespaces to reconcile, the allowed ones in the namespace-scoped mode
+func getNamespaces() []string {
+	if config := configuration.GetConfiguration(); config.IsNamespaced() {
+		return config.Namespaces
+	}
+	return []string{metav1.NamespaceAll}
+}
+
+func isExpired(expiration string) bool {
+	t, err := time.Parse(time.RFC3339, expiration)
+	return err == nil && time.Now().After(t)
+}
+
+func reconcilePolicies() {
+	for _, i := range policies {
+		for _, namespace := range getNamespaces() {
+			reconcilePoliciesOf(i, namespace)
+		}
+	}
+}
+
+// reconcilePoliciesOf deletes the expired policies of the resource in the namespace
+func reconcilePoliciesOf(i schema.GroupVersionResource, namespace string) {
+	list, err := dynamicClient.Resource(i).Namespace(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: managedBySelector})
+	if apierrors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "expiry", Target: i.Resource})
+		return
+	}
+	for _, j := range list.Items {
+		if !isExpired(j.GetAnnotations()[kubernetes.ExpiresAtAnnotation]) {
+			continue
+		}
+		log := utils.LogLine{
+			Message: "expiry",
+			Objects: map[string]string{i.Resource: j.GetName(), "namespace": j.GetNamespace()},
+		}
+		err := dynamicClient.Resource(i).Namespace(j.GetNamespace()).Delete(context.Background(), j.GetName(), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.Error = err.Error()
+			utils.PrintLog("error", log)
+			continue
+		}
+		log.Result = "expired resource deleted"
+		utils.PrintLog("info", log)
+	}
+}
+
+func reconcileLabels() {
+	client := kubernetes.GetClient()
+	options := metav1.ListOptions{LabelSelector: kubernetes.ExpiringLabel + "=true"}
+
+	for _, namespace := range getNamespaces() {
+		pods, err := client.Clientset.CoreV1().Pods(namespace).List(context.Background(), options)
+		if err != nil {
+			utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "expiry", Target: "pods"})
+			continue
+		}
+		for _, i := range pods.Items {
+			patch := getLabelsPatch(i.ObjectMeta)
+			if patch == nil {
+				continue
+			}
+			_, err := client.Clientset.CoreV1().Pods(i.Namespace).Patch(context.Background(), i.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			printLabelsLog(map[string]string{"pod": i.Name, "namespace": i.Namespace}, err)
+		}
+	}
+
+	// the nodes can't be labeled in the namespace-scoped mode
+	if configuration.GetConfiguration().IsNamespaced() {
+		return
+	}
+	nodes, err := client.Clientset.CoreV1().Nodes().List(context.Background(), options)
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "expiry", Target: "nodes"})
+		return
+	}
+	for _, i := range nodes.Items {
+		patch := getLabelsPatch(i.ObjectMeta)
+		if patch == nil {
+			continue
+		}
+		_, err := client.Clientset.CoreV1().Nodes().Patch(context.Background(), i.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		printLabelsLog(map[string]string{"node": i.Name}, err)
+	}
+}
+
+// getLabelsPatch returns the merge patch removing the expired labels, nil if none has expired
+func getLabelsPatch(meta metav1.ObjectMeta) []byte {
+	expirations := map[string]string{}
+	_ = json.Unmarshal([]byte(meta.Annotations[kubernetes.ExpiringLabelsAnnotation]), &expirations)
+
+	labels := map[string]interface{}{}
+	for i, j := range expirations {
+		if isExpired(j) {
+			labels[i] = nil
+			delete(expirations, i)
+		}
+	}
+	if len(labels) == 0 && len(expirations) != 0 {
+		return nil
+	}
+
+	annotations := map[string]interface{}{kubernetes.ExpiringLabelsAnnotation: nil}
+	if len(expirations) == 0 {
+		labels[kubernetes.ExpiringLabel] = nil
+	} else {
+		b, _ := json.Marshal(expirations)
+		annotations[kubernetes.ExpiringLabelsAnnotation] = string(b)
+	}
+
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      labels,
+			"annotations": annotations,
+		},
+	})
+	return patch
+}
+
+func printLabelsLog(objects map[string]string, err error) {
+	log := utils.LogLine{Message: "expiry", Objects: objects}
+	if err != nil {
+		log.Error = err.Error()
+		utils.PrintLog("error", log)
+		return
+	}
+	log.Result = "expired labels removed"
+	utils.PrintLog("info", log)
+}
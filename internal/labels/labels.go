@@ -0,0 +1,127 @@
+package labels
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Field sets how a field of the events or of the objects of the actions is displayed in the notifications and
+// the reports, the fields are displayed in the order of the file, before the others
+type Field struct {
+	Name   string `yaml:"name"`             // eg: k8s.ns.name, or a key of the objects of the actions, eg: pod
+	Label  string `yaml:"label,omitempty"`  // eg: Namespace, the name is kept if empty
+	Hidden bool   `yaml:"hidden,omitempty"` // eg: for the digests of the images
+}
+
+type mapping struct {
+	fields   map[string]Field // by lowercase name
+	position map[string]int   // by lowercase name and label
+}
+
+var (
+	current = &mapping{}
+	mu      sync.RWMutex
+)
+
+// Init loads the mapping file, a YAML list of fields, an empty file name removes the mapping
+func Init(file string) error {
+	set, err := Reload(file)
+	if err != nil {
+		return err
+	}
+	set()
+	return nil
+}
+
+// Reload loads the mapping file, the returned function applies it, once the rest of the configuration is checked
+func Reload(file string) (func(), error) {
+	m := &mapping{}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var fields []Field
+		if err := yaml.Unmarshal(b, &fields); err != nil {
+			return nil, fmt.Errorf("wrong file of the field labels '%v': %v", file, err)
+		}
+		m.fields = make(map[string]Field, len(fields))
+		m.position = make(map[string]int, 2*len(fields))
+		for i, j := range fields {
+			if j.Name == "" {
+				return nil, fmt.Errorf("wrong file of the field labels '%v': the field %v has no name", file, i+1)
+			}
+			name := strings.ToLower(j.Name)
+			m.fields[name] = j
+			m.position[name] = i
+			if j.Label != "" {
+				m.position[strings.ToLower(j.Label)] = i
+			}
+		}
+	}
+	return func() {
+		mu.Lock()
+		current = m
+		mu.Unlock()
+	}, nil
+}
+
+// Label returns the label of the field, false if it's hidden
+func Label(name string) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := current.fields[strings.ToLower(name)]
+	if !ok {
+		return name, true
+	}
+	if f.Hidden {
+		return "", false
+	}
+	if f.Label == "" {
+		return name, true
+	}
+	return f.Label, true
+}
+
+// Apply returns the fields with their labels, without the hidden ones
+func Apply(fields map[string]string) map[string]string {
+	if len(fields) == 0 {
+		return fields
+	}
+	m := make(map[string]string, len(fields))
+	for i, j := range fields {
+		if l, ok := Label(i); ok {
+			m[l] = j
+		}
+	}
+	return m
+}
+
+// Keys returns the keys of the fields in the order of the mapping, the others after them by alphabetical order,
+// the keys can be the names or the labels
+func Keys[T any](fields map[string]T) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	keys := make([]string, 0, len(fields))
+	for i := range fields {
+		keys = append(keys, i)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, oki := current.position[strings.ToLower(keys[i])]
+		pj, okj := current.position[strings.ToLower(keys[j])]
+		switch {
+		case oki && okj && pi != pj:
+			return pi < pj
+		case oki != okj:
+			return oki
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+	return keys
+}
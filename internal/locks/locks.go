@@ -0,0 +1,99 @@
+package locks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+)
+
+const (
+	Lease string = "lease"
+	Local string = "local"
+)
+
+var ErrLocked = errors.New("an action is already in progress for this resource")
+
+type Locker interface {
+	// Acquire takes the lock for the key, the returned function releases it
+	Acquire(key string) (func(), error)
+}
+
+type leaseLocker struct {
+	client   *k8s.Client
+	holder   string
+	duration time.Duration
+}
+
+type localLocker struct {
+	locks    map[string]time.Time
+	duration time.Duration
+	mu       sync.Mutex
+}
+
+var locker Locker
+
+// Init sets the backend of the locks, the locks are released after their duration
+// if the holder didn't release them
+func Init(backend string, duration time.Duration) error {
+	switch backend {
+	case Lease:
+		if err := k8s.Init(); err != nil {
+			return err
+		}
+		holder, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		locker = &leaseLocker{client: k8s.GetClient(), holder: holder, duration: duration}
+	case Local, "":
+		locker = &localLocker{locks: make(map[string]time.Time), duration: duration}
+	default:
+		return errors.New("wrong `backend` setting")
+	}
+	return nil
+}
+
+func IsEnabled() bool {
+	return locker != nil
+}
+
+// Acquire takes the lock for the key, ErrLocked is returned if it's held
+func Acquire(key string) (func(), error) {
+	if locker == nil {
+		return func() {}, nil
+	}
+	return locker.Acquire(key)
+}
+
+func (l *leaseLocker) Acquire(key string) (func(), error) {
+	h := sha256.Sum256([]byte(key))
+	release, err := l.client.AcquireLease("falco-talon-lock-"+hex.EncodeToString(h[:])[:32], l.holder, l.duration)
+	if errors.Is(err, k8s.ErrLeaseHeld) {
+		return nil, ErrLocked
+	}
+	return release, err
+}
+
+func (l *localLocker) Acquire(key string) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if t, ok := l.locks[key]; ok && time.Since(t) < l.duration {
+		return nil, ErrLocked
+	}
+	t := time.Now()
+	l.locks[key] = t
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.locks[key] == t {
+			delete(l.locks, key)
+		}
+	}, nil
+}
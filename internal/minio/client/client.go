@@ -1,12 +1,20 @@
 package client
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
 	"sync"
 
 	minio "github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 
 	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
 )
 
 type MinioClient struct {
@@ -28,10 +36,30 @@ func Init() error {
 	once.Do(func() {
 		config := configuration.GetConfiguration().MinioConfig
 
-		c, err := minio.New(config.Endpoint, &minio.Options{
-			Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
-			Secure: config.UseSSL,
-		})
+		bucketLookup, err := getBucketLookup(config.BucketLookup)
+		if err != nil {
+			initErr = err
+			return
+		}
+
+		options := &minio.Options{
+			Creds:        credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+			Secure:       config.UseSSL,
+			Region:       config.Region,
+			BucketLookup: bucketLookup,
+		}
+
+		if config.UseSSL && (config.CACertFile != "" || config.InsecureSkipVerify || outbound.RootCAs() != nil) {
+			options.Transport, err = newTransport(config.CACertFile, config.InsecureSkipVerify)
+			if err != nil {
+				initErr = err
+				return
+			}
+		}
+
+		// the endpoint is expected without scheme
+		endpoint := strings.TrimPrefix(strings.TrimPrefix(config.Endpoint, "https://"), "http://")
+		c, err := minio.New(strings.TrimSuffix(endpoint, "/"), options)
 		if err != nil {
 			initErr = err
 			return
@@ -48,3 +76,45 @@ func Init() error {
 func GetClient() *minio.Client {
 	return minioClient.minioClient
 }
+
+func getBucketLookup(lookup string) (minio.BucketLookupType, error) {
+	switch strings.ToLower(lookup) {
+	case "", "auto":
+		return minio.BucketLookupAuto, nil
+	case "path":
+		return minio.BucketLookupPath, nil
+	case "dns":
+		return minio.BucketLookupDNS, nil
+	default:
+		return minio.BucketLookupAuto, errors.New("wrong `bucket_lookup` setting")
+	}
+}
+
+// newTransport returns a transport trusting the CA bundle, for the endpoints with a self-signed certificate
+func newTransport(caCertFile string, insecureSkipVerify bool) (*http.Transport, error) {
+	tlsConfig := tlspolicy.Apply(&tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
+		RootCAs:            outbound.RootCAs(),
+	})
+
+	if caCertFile != "" {
+		pool := outbound.CertPool()
+		b, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no valid certificate in '%v'", caCertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport, err := minio.DefaultTransport(true)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
@@ -0,0 +1,156 @@
+package monitoring
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	warningSeverity  string = "warning"
+	criticalSeverity string = "critical"
+)
+
+type RuleGroups struct {
+	Groups []RuleGroup `yaml:"groups"`
+}
+
+type RuleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []AlertRule `yaml:"rules"`
+}
+
+type AlertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// PrometheusRule is the custom resource of the Prometheus Operator
+type PrometheusRule struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   map[string]string `yaml:"metadata"`
+	Spec       RuleGroups        `yaml:"spec"`
+}
+
+// GenerateAlerts returns the Prometheus alerting rules for Falco Talon, the failures of the actions
+// are alerted for each rule of the rules files, with the list of their actions in the description
+func GenerateAlerts(rules *[]*ruleengine.Rule) RuleGroups {
+	global := RuleGroup{
+		Name: "falco-talon",
+		Rules: []AlertRule{
+			{
+				Alert:       "FalcoTalonEventsDropped",
+				Expr:        fmt.Sprintf("sum by (reason) (increase(%v[5m])) > 0", droppedEventMetric),
+				Labels:      map[string]string{"severity": warningSeverity},
+				Annotations: annotations("Falco Talon is dropping events", "{{ $value }} event(s) rejected by the ingestion in the last 5 minutes (reason: {{ $labels.reason }})"),
+			},
+			{
+				Alert:       "FalcoTalonNotificationFailures",
+				Expr:        fmt.Sprintf(`sum by (notifier) (increase(%v{status="failure"}[10m])) > 0`, notificationMetric),
+				Labels:      map[string]string{"severity": warningSeverity},
+				Annotations: annotations("Falco Talon can't send notifications", "{{ $value }} notification(s) failed for the notifier {{ $labels.notifier }} in the last 10 minutes"),
+			},
+			{
+				Alert:       "FalcoTalonNotificationsDropped",
+				Expr:        fmt.Sprintf("sum(increase(%v[10m])) > 0", droppedNotificationMetric),
+				Labels:      map[string]string{"severity": warningSeverity},
+				Annotations: annotations("Falco Talon is dropping notifications", "{{ $value }} notification(s) dropped in the last 10 minutes"),
+			},
+			{
+				Alert:       "FalcoTalonCircuitBreakerOpen",
+				Expr:        fmt.Sprintf("max by (actionner) (%v) > 0", openCircuitMetric),
+				For:         "5m",
+				Labels:      map[string]string{"severity": criticalSeverity},
+				Annotations: annotations("A circuit breaker of Falco Talon is open", "the actions of the actionner {{ $labels.actionner }} are skipped since 5 minutes"),
+			},
+			{
+				Alert:       "FalcoTalonQueueBacklog",
+				Expr:        fmt.Sprintf("sum by (class) (%v) > 100", queueDepthMetric),
+				For:         "10m",
+				Labels:      map[string]string{"severity": warningSeverity},
+				Annotations: annotations("Falco Talon has a backlog of events", "{{ $value }} event(s) of the class {{ $labels.class }} are waiting for their actions"),
+			},
+			{
+				Alert:       "FalcoTalonSlowActions",
+				Expr:        fmt.Sprintf("histogram_quantile(0.95, sum by (le, actionner) (rate(%v[10m]))) > 30", actionDurationMetric),
+				For:         "10m",
+				Labels:      map[string]string{"severity": warningSeverity},
+				Annotations: annotations("The actions of Falco Talon are slow", "the p95 of the duration of the actions of the actionner {{ $labels.actionner }} is {{ $value }}s"),
+			},
+		},
+	}
+
+	groups := RuleGroups{Groups: []RuleGroup{global}}
+	if rules == nil {
+		return groups
+	}
+
+	perRule := RuleGroup{Name: "falco-talon-rules"}
+	for _, i := range *rules {
+		if len(i.GetActions()) == 0 {
+			continue
+		}
+		actions := make([]string, 0, len(i.GetActions()))
+		for _, j := range i.GetActions() {
+			actions = append(actions, fmt.Sprintf("%v (%v)", j.GetName(), j.GetActionner()))
+		}
+		perRule.Rules = append(perRule.Rules, AlertRule{
+			Alert:  "FalcoTalonActionFailure",
+			Expr:   fmt.Sprintf(`sum by (rule, action, actionner) (increase(%v{rule=%v, status="failure"}[10m])) > 0`, actionMetric, strconv.Quote(i.GetName())),
+			Labels: map[string]string{"severity": criticalSeverity, "rule": i.GetName()},
+			Annotations: annotations(
+				fmt.Sprintf("An action of the rule '%v' failed", i.GetName()),
+				"the action {{ $labels.action }} ({{ $labels.actionner }}) failed {{ $value }} time(s) in the last 10 minutes, the actions of the rule are: "+strings.Join(actions, ", "),
+			),
+		})
+	}
+	if len(perRule.Rules) != 0 {
+		groups.Groups = append(groups.Groups, perRule)
+	}
+
+	return groups
+}
+
+// MarshalAlerts returns the rules as a Prometheus rules file or as a PrometheusRule resource
+func MarshalAlerts(groups RuleGroups, prometheusRule bool, namespace string) ([]byte, error) {
+	if !prometheusRule {
+		return marshalYAML(groups)
+	}
+
+	metadata := map[string]string{"name": "falco-talon"}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	return marshalYAML(PrometheusRule{
+		APIVersion: "monitoring.coreos.com/v1",
+		Kind:       "PrometheusRule",
+		Metadata:   metadata,
+		Spec:       groups,
+	})
+}
+
+func marshalYAML(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func annotations(summary, description string) map[string]string {
+	return map[string]string{"summary": summary, "description": description}
+}
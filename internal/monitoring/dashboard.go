@@ -0,0 +1,244 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+)
+
+// the names of the metrics, as exposed by the prometheus endpoint
+const (
+	eventMetric               string = "event_total"
+	matchMetric               string = "match_total"
+	actionMetric              string = "action_total"
+	actionDurationMetric      string = "action_duration_seconds_bucket"
+	timeToRemediateMetric     string = "time_to_remediate_seconds_bucket"
+	notificationMetric        string = "notification_total"
+	droppedNotificationMetric string = "dropped_notification_total"
+	droppedEventMetric        string = "dropped_event_total"
+	queueDepthMetric          string = "queue_depth"
+	openCircuitMetric         string = "open_circuit_breaker"
+)
+
+const (
+	datasourceVariable string = "${datasource}"
+	ruleVariable       string = "rule"
+	panelWidth         int    = 12
+	panelHeight        int    = 8
+)
+
+type Dashboard struct {
+	Title         string     `json:"title"`
+	UID           string     `json:"uid"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Tags          []string   `json:"tags"`
+	Panels        []Panel    `json:"panels"`
+	Templating    Templating `json:"templating"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Editable      bool       `json:"editable"`
+}
+
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+type Variable struct {
+	Current    *Option  `json:"current,omitempty"`
+	Name       string   `json:"name"`
+	Label      string   `json:"label"`
+	Type       string   `json:"type"`
+	Query      string   `json:"query"`
+	Options    []Option `json:"options,omitempty"`
+	IncludeAll bool     `json:"includeAll"`
+	Multi      bool     `json:"multi"`
+}
+
+type Option struct {
+	Text     string `json:"text"`
+	Value    string `json:"value"`
+	Selected bool   `json:"selected"`
+}
+
+type Panel struct {
+	Datasource  Datasource  `json:"datasource"`
+	Title       string      `json:"title"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Repeat      string      `json:"repeat,omitempty"`
+	FieldConfig FieldConfig `json:"fieldConfig"`
+	Targets     []Target    `json:"targets"`
+	GridPos     GridPos     `json:"gridPos"`
+	ID          int         `json:"id"`
+}
+
+type Datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type FieldConfig struct {
+	Defaults FieldDefaults `json:"defaults"`
+}
+
+type FieldDefaults struct {
+	Unit string `json:"unit,omitempty"`
+}
+
+type Target struct {
+	Datasource   Datasource `json:"datasource"`
+	Expr         string     `json:"expr"`
+	LegendFormat string     `json:"legendFormat"`
+	RefID        string     `json:"refId"`
+}
+
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// GenerateDashboard returns the JSON model of a Grafana dashboard for the metrics of Falco Talon,
+// the panels filtered by rule can be repeated for each rule of the rules files
+func GenerateDashboard(rules *[]*ruleengine.Rule) ([]byte, error) {
+	ds := Datasource{Type: "prometheus", UID: datasourceVariable}
+	ruleFilter := fmt.Sprintf(`rule=~"$%v"`, ruleVariable)
+
+	panels := []Panel{
+		{
+			Title:       "Received events",
+			Targets:     []Target{{Expr: fmt.Sprintf("sum(rate(%v[5m]))", eventMetric), LegendFormat: "events"}},
+			FieldConfig: unit("reqps"),
+		},
+		{
+			Title:       "Matched events by rule",
+			Targets:     []Target{{Expr: fmt.Sprintf("sum by (rule) (rate(%v{%v}[5m]))", matchMetric, ruleFilter), LegendFormat: "{{rule}}"}},
+			FieldConfig: unit("reqps"),
+		},
+		{
+			Title:       "Actions by actionner and status",
+			Targets:     []Target{{Expr: fmt.Sprintf("sum by (actionner, status) (rate(%v{%v}[5m]))", actionMetric, ruleFilter), LegendFormat: "{{actionner}} ({{status}})"}},
+			FieldConfig: unit("ops"),
+		},
+		{
+			Title:       "Duration of the actions (p95)",
+			Targets:     []Target{{Expr: fmt.Sprintf("histogram_quantile(0.95, sum by (le, actionner) (rate(%v{%v}[5m])))", actionDurationMetric, ruleFilter), LegendFormat: "{{actionner}}"}},
+			FieldConfig: unit("s"),
+		},
+		{
+			Title:       "Notifications by notifier and status",
+			Targets:     []Target{{Expr: fmt.Sprintf("sum by (notifier, status) (rate(%v[5m]))", notificationMetric), LegendFormat: "{{notifier}} ({{status}})"}},
+			FieldConfig: unit("ops"),
+		},
+		{
+			Title: "Dropped events and notifications",
+			Targets: []Target{
+				{Expr: fmt.Sprintf("sum by (reason) (rate(%v[5m]))", droppedEventMetric), LegendFormat: "events ({{reason}})"},
+				{Expr: fmt.Sprintf("sum(rate(%v[5m]))", droppedNotificationMetric), LegendFormat: "notifications"},
+			},
+			FieldConfig: unit("reqps"),
+		},
+		{
+			Title:       "Queue depth by class",
+			Targets:     []Target{{Expr: fmt.Sprintf("sum by (class) (%v)", queueDepthMetric), LegendFormat: "{{class}}"}},
+			FieldConfig: unit("short"),
+		},
+		{
+			Title:       "Open circuit breakers",
+			Targets:     []Target{{Expr: fmt.Sprintf("max by (actionner) (%v)", openCircuitMetric), LegendFormat: "{{actionner}}"}},
+			FieldConfig: unit("short"),
+		},
+		{
+			Title: "Time to remediate by rule",
+			Targets: []Target{
+				{Expr: fmt.Sprintf("histogram_quantile(0.5, sum by (le, rule) (rate(%v{%v}[1h])))", timeToRemediateMetric, ruleFilter), LegendFormat: "{{rule}} (p50)"},
+				{Expr: fmt.Sprintf("histogram_quantile(0.95, sum by (le, rule) (rate(%v{%v}[1h])))", timeToRemediateMetric, ruleFilter), LegendFormat: "{{rule}} (p95)"},
+			},
+			FieldConfig: unit("s"),
+		},
+		{
+			Title:       "Success ratio of the actions by rule",
+			Targets:     []Target{{Expr: fmt.Sprintf(`sum by (rule) (rate(%v{%v,status="success"}[1h])) / sum by (rule) (rate(%v{%v,status=~"success|failure"}[1h]))`, actionMetric, ruleFilter, actionMetric, ruleFilter), LegendFormat: "{{rule}}"}},
+			FieldConfig: unit("percentunit"),
+		},
+		{
+			Title:       "Actions of the rule $" + ruleVariable,
+			Description: "repeated for each selected rule",
+			Repeat:      ruleVariable,
+			Targets:     []Target{{Expr: fmt.Sprintf(`sum by (action, status) (increase(%v{rule="$%v"}[$__rate_interval]))`, actionMetric, ruleVariable), LegendFormat: "{{action}} ({{status}})"}},
+			FieldConfig: unit("short"),
+		},
+	}
+
+	for i := range panels {
+		panels[i].ID = i + 1
+		panels[i].Type = "timeseries"
+		panels[i].Datasource = ds
+		panels[i].GridPos = GridPos{H: panelHeight, W: panelWidth, X: (i % 2) * panelWidth, Y: (i / 2) * panelHeight}
+		for j := range panels[i].Targets {
+			panels[i].Targets[j].Datasource = ds
+			panels[i].Targets[j].RefID = string(rune('A' + j))
+		}
+	}
+
+	options := []Option{}
+	if rules != nil {
+		for _, i := range *rules {
+			options = append(options, Option{Text: i.GetName(), Value: i.GetName()})
+		}
+	}
+
+	dashboard := Dashboard{
+		Title:         "Falco Talon",
+		UID:           "falco-talon",
+		Refresh:       "30s",
+		Time:          TimeRange{From: "now-6h", To: "now"},
+		Tags:          []string{"falco", "falco-talon"},
+		Panels:        panels,
+		SchemaVersion: 39,
+		Editable:      true,
+		Templating: Templating{
+			List: []Variable{
+				{
+					Name:  "datasource",
+					Label: "Data source",
+					Type:  "datasource",
+					Query: "prometheus",
+				},
+				{
+					Current:    &Option{Text: "All", Value: "$__all", Selected: true},
+					Name:       ruleVariable,
+					Label:      "Rule",
+					Type:       "custom",
+					Query:      ruleNames(options),
+					Options:    options,
+					IncludeAll: true,
+					Multi:      true,
+				},
+			},
+		},
+	}
+
+	return json.MarshalIndent(dashboard, "", "  ")
+}
+
+func unit(u string) FieldConfig {
+	return FieldConfig{Defaults: FieldDefaults{Unit: u}}
+}
+
+// ruleNames returns the query of a custom variable, the values are separated by commas
+func ruleNames(options []Option) string {
+	s := make([]string, 0, len(options))
+	for _, i := range options {
+		s = append(s, strings.ReplaceAll(i.Value, ",", `\,`))
+	}
+	return strings.Join(s, ",")
+}
@@ -1,7 +1,11 @@
 package nats
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	// "github.com/nats-io/nats.go"
@@ -12,21 +16,51 @@ import (
 
 type Client struct {
 	nats.JetStreamContext
+	conn      *nats.Conn
+	queue     chan *Message
+	processed nats.KeyValue
+}
+
+// Message is an event received from the stream, with the persistence it must be
+// acknowledged once processed, it's delivered again after a restart otherwise
+type Message struct {
+	msg  *nats.Msg
+	stop chan struct{}
+	once sync.Once
+	Data string
+}
+
+// Persistence stores the stream on disk, the events survive the restarts and are processed at least once
+type Persistence struct {
+	StoreDir string
+	MaxAge   time.Duration
+	AckWait  time.Duration
 }
 
 const (
-	streamName     = "EVENTS"
-	streamSubjects = "EVENTS.*"
+	streamName      = "EVENTS"
+	streamSubjects  = "EVENTS.*"
+	durableName     = "falco-talon"
+	processedBucket = "PROCESSED"
 )
 
-var consumer, publisher *Client
+var (
+	consumer, publisher *Client
+	persistence         *Persistence
+)
 
-func StartServer(timeWindow int) (*natsserver.Server, error) {
-	ns, err := natsserver.NewServer(
-		&natsserver.Options{
-			JetStream: true,
-			// StoreDir:  nats.MemoryStorage.String(),
-		})
+// StartServer starts the embedded NATS, the stream is in memory if persistence is nil
+func StartServer(timeWindow int, p *Persistence) (*natsserver.Server, error) {
+	opts := &natsserver.Options{
+		JetStream: true,
+		// StoreDir:  nats.MemoryStorage.String(),
+	}
+	if p != nil {
+		opts.StoreDir = p.StoreDir
+	}
+	persistence = p
+
+	ns, err := natsserver.NewServer(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +99,18 @@ func (client *Client) SetJetStreamContext(addr string) error {
 	}
 
 	client.JetStreamContext = jsc
+	client.conn = nc
+	return nil
+}
+
+// Check returns an error if the client isn't connected to the nats server
+func (client *Client) Check() error {
+	if client == nil || client.conn == nil {
+		return errors.New("not initialized")
+	}
+	if !client.conn.IsConnected() {
+		return fmt.Errorf("not connected, status: %v", client.conn.Status())
+	}
 	return nil
 }
 
@@ -76,16 +122,35 @@ func GetPublisher() *Client {
 	return publisher
 }
 
-func (client *Client) ConsumeMsg() (chan string, error) {
-	c := make(chan string, 20)
-	_, err := client.JetStreamContext.Subscribe(streamSubjects, func(m *nats.Msg) {
-		if err := m.Ack(); err != nil {
-			return
+// ConsumeMsg returns the queue of the events waiting for their actions
+func (client *Client) ConsumeMsg(queueSize int) (chan *Message, error) {
+	c := make(chan *Message, queueSize)
+	client.queue = c
+
+	if persistence == nil {
+		_, err := client.JetStreamContext.Subscribe(streamSubjects, func(m *nats.Msg) {
+			if err := m.Ack(); err != nil {
+				return
+			}
+			c <- &Message{Data: string(m.Data)}
+		},
+			nats.DeliverNew())
+		if err != nil {
+			return nil, err
 		}
-		c <- string(m.Data)
-	},
-		nats.DeliverNew())
+		return c, nil
+	}
 
+	// with the persistence, a durable consumer replays the events not acknowledged before a stop
+	_, err := client.JetStreamContext.Subscribe(streamSubjects, func(m *nats.Msg) {
+		msg := &Message{Data: string(m.Data), msg: m, stop: make(chan struct{})}
+		go msg.keepAlive(persistence.AckWait / 2)
+		c <- msg
+	},
+		nats.Durable(durableName),
+		nats.ManualAck(),
+		nats.DeliverAll(),
+		nats.AckWait(persistence.AckWait))
 	if err != nil {
 		return nil, err
 	}
@@ -93,6 +158,93 @@ func (client *Client) ConsumeMsg() (chan string, error) {
 	return c, nil
 }
 
+// Ack acknowledges the message, the event is removed from the stream
+func (m *Message) Ack() {
+	if m.msg == nil {
+		return
+	}
+	m.once.Do(func() { close(m.stop) })
+	_ = m.msg.Ack()
+}
+
+// IsRedelivery returns true if the message has already been delivered before a restart, with the persistence,
+// its processing was interrupted before the acknowledgement
+func (m *Message) IsRedelivery() bool {
+	if m.msg == nil {
+		return false
+	}
+	meta, err := m.msg.Metadata()
+	return err == nil && meta.NumDelivered > 1
+}
+
+// keepAlive tells the server the message is in progress until it's acknowledged, the events waiting in the queue
+// or with long actions aren't delivered again once the ack wait is over
+func (m *Message) keepAlive(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			_ = m.msg.InProgress()
+		}
+	}
+}
+
+// IsProcessed returns true if the event with this idempotency key has already been processed
+func (client *Client) IsProcessed(key string) bool {
+	if client.processed == nil || key == "" {
+		return false
+	}
+	_, err := client.processed.Get(getKey(key))
+	return err == nil
+}
+
+// SetProcessed records the idempotency key of a processed event
+func (client *Client) SetProcessed(key string) error {
+	if client.processed == nil || key == "" {
+		return nil
+	}
+	_, err := client.processed.Put(getKey(key), []byte(time.Now().Format(time.RFC3339)))
+	return err
+}
+
+// GetBucket returns the key-value bucket, it's created if it doesn't exist,
+// it's stored on disk with the persistence
+func (client *Client) GetBucket(bucket string, ttl time.Duration) (nats.KeyValue, error) {
+	kv, err := client.JetStreamContext.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		storage := nats.MemoryStorage
+		if persistence != nil {
+			storage = nats.FileStorage
+		}
+		kv, err = client.JetStreamContext.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:  bucket,
+			TTL:     ttl,
+			Storage: storage,
+		})
+	}
+	return kv, err
+}
+
+// getKey hashes the idempotency key, the keys of the buckets have a restricted set of characters
+func getKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// GetQueueUsage returns the number of events in the queue and its capacity
+func (client *Client) GetQueueUsage() (int, int) {
+	if client.queue == nil {
+		return 0, 0
+	}
+	return len(client.queue), cap(client.queue)
+}
+
 func (client *Client) PublishMsg(id, msg string) error {
 	if _, err := client.JetStreamContext.Publish(
 		streamName+"."+id,
@@ -108,22 +260,39 @@ func (client *Client) PublishMsg(id, msg string) error {
 func (client *Client) createStream(timeWindow int) error {
 	stream, err := client.JetStreamContext.StreamInfo(streamName)
 	if err != nil {
-		if err != nats.ErrStreamNotFound {
+		if !errors.Is(err, nats.ErrStreamNotFound) {
 			return err
 		}
 	}
 	if stream == nil {
-		_, err = client.JetStreamContext.AddStream(&nats.StreamConfig{
+		config := &nats.StreamConfig{
 			Name:              streamName,
 			Subjects:          []string{streamSubjects},
 			Duplicates:        time.Duration(timeWindow) * time.Second,
 			MaxAge:            time.Duration(timeWindow) * time.Second,
 			MaxMsgsPerSubject: 1,
 			Storage:           nats.MemoryStorage,
-		})
+		}
+		if persistence != nil {
+			// the events are kept until they're acknowledged
+			config.Storage = nats.FileStorage
+			config.Retention = nats.WorkQueuePolicy
+			config.MaxAge = persistence.MaxAge
+		}
+		_, err = client.JetStreamContext.AddStream(config)
 		if err != nil {
 			return err
 		}
 	}
+
+	if persistence == nil {
+		return nil
+	}
+
+	kv, err := client.GetBucket(processedBucket, persistence.MaxAge)
+	if err != nil {
+		return err
+	}
+	client.processed = kv
 	return nil
 }
@@ -0,0 +1,186 @@
+package otlp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type logsRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  Resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type scopeLogs struct {
+	Scope      Scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityText         string     `json:"severityText"`
+	Body                 Value      `json:"body"`
+	Attributes           []KeyValue `json:"attributes"`
+	SeverityNumber       int        `json:"severityNumber"`
+}
+
+// Logs buffers the log lines and sends them by batches, the lines are dropped if the buffer is full
+type Logs struct {
+	client   *Client
+	records  chan logRecord
+	flush    chan chan struct{}
+	resource Resource
+	scope    Scope
+}
+
+const (
+	scopeName      string = "github.com/falco-talon/falco-talon"
+	logsBufferSize int    = 4096
+	logsBatchSize  int    = 512
+)
+
+// severities of OTLP
+var severities = map[string]int{
+	"debug":   5,
+	"info":    9,
+	"warning": 13,
+	"error":   17,
+	"fatal":   21,
+}
+
+// NewLogs starts the batching of the log lines, they're sent every interval or once a batch is full
+func NewLogs(client *Client, interval time.Duration) *Logs {
+	l := &Logs{
+		client:  client,
+		records: make(chan logRecord, logsBufferSize),
+		flush:   make(chan chan struct{}),
+		resource: Resource{Attributes: NewAttributes([]attribute.KeyValue{
+			semconv.ServiceNameKey.String(utils.FalcoTalonStr),
+			semconv.ServiceVersionKey.String(configuration.GetInfo().GitVersion),
+		})},
+		scope: Scope{Name: scopeName, Version: configuration.GetInfo().GitVersion},
+	}
+	go l.run(interval)
+	return l
+}
+
+// Add converts the log line, it's the hook of the logger
+func (l *Logs) Add(level string, line utils.LogLine) {
+	level = strings.ToLower(level)
+	severity, ok := severities[level]
+	if !ok {
+		level, severity = "info", severities["info"]
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("module", utils.GetModule(line))}
+	for i, j := range map[string]string{
+		"rule":               line.Rule,
+		"event":              line.Event,
+		"priority":           line.Priority,
+		"source":             line.Source,
+		"notifier":           line.Notifier,
+		"context":            line.Context,
+		"output":             line.Output,
+		"actionner":          line.Actionner,
+		"actionner_category": line.ActionnerCategory,
+		"output_category":    line.OutputCategory,
+		"action":             line.Action,
+		"status":             line.Status,
+		"target":             line.Target,
+		"result":             line.Result,
+		"trace_id":           line.TraceID,
+		"incident_id":        line.IncidentID,
+		"error":              line.Error,
+	} {
+		if j != "" {
+			attrs = append(attrs, attribute.String(i, j))
+		}
+	}
+	for i, j := range line.Objects {
+		attrs = append(attrs, attribute.String(strings.ToLower(i), j))
+	}
+
+	now := FormatTime(time.Now())
+	select {
+	case l.records <- logRecord{
+		TimeUnixNano:         now,
+		ObservedTimeUnixNano: now,
+		SeverityText:         strings.ToUpper(level),
+		SeverityNumber:       severity,
+		Body:                 NewStringValue(line.Message),
+		Attributes:           NewAttributes(attrs),
+	}:
+	default:
+	}
+}
+
+// Flush sends the buffered log lines, it waits until the timeout at most
+func (l *Logs) Flush(timeout time.Duration) {
+	done := make(chan struct{})
+	select {
+	case l.flush <- done:
+	case <-time.After(timeout):
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+func (l *Logs) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]logRecord, 0, logsBatchSize)
+	for {
+		select {
+		case r := <-l.records:
+			batch = append(batch, r)
+			if len(batch) >= logsBatchSize {
+				batch = l.send(batch)
+			}
+		case <-ticker.C:
+			batch = l.send(batch)
+		case done := <-l.flush:
+			for len(l.records) != 0 {
+				batch = append(batch, <-l.records)
+			}
+			batch = l.send(batch)
+			close(done)
+		}
+	}
+}
+
+// send posts the batch, it returns the emptied batch
+func (l *Logs) send(batch []logRecord) []logRecord {
+	if len(batch) == 0 {
+		return batch
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := l.client.Send(ctx, LogsPath, logsRequest{
+		ResourceLogs: []resourceLogs{
+			{
+				Resource:  l.resource,
+				ScopeLogs: []scopeLogs{{Scope: l.scope, LogRecords: batch}},
+			},
+		},
+	})
+	if err != nil {
+		// the line of the error is in the next batch, it doesn't loop while the collector is unreachable
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "otlp"})
+	}
+	return batch[:0]
+}
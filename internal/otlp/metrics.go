@@ -0,0 +1,156 @@
+package otlp
+
+import (
+	"strconv"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type MetricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     Resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   Scope    `json:"scope"`
+	Metrics []metric `json:"metrics"`
+}
+
+type metric struct {
+	Sum         *sum       `json:"sum,omitempty"`
+	Gauge       *gauge     `json:"gauge,omitempty"`
+	Histogram   *histogram `json:"histogram,omitempty"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Unit        string     `json:"unit,omitempty"`
+}
+
+type sum struct {
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type histogram struct {
+	DataPoints             []histogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type numberDataPoint struct {
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+	AsInt             *string    `json:"asInt,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Attributes        []KeyValue `json:"attributes"`
+}
+
+type histogramDataPoint struct {
+	Min               *float64   `json:"min,omitempty"`
+	Max               *float64   `json:"max,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	Count             string     `json:"count"`
+	Attributes        []KeyValue `json:"attributes"`
+	BucketCounts      []string   `json:"bucketCounts"`
+	ExplicitBounds    []float64  `json:"explicitBounds"`
+	Sum               float64    `json:"sum"`
+}
+
+// temporalities of OTLP, they differ from the ones of the SDK
+const (
+	temporalityDelta      int = 1
+	temporalityCumulative int = 2
+)
+
+// NewMetricsRequest converts the metrics collected by a reader, the exponential histograms
+// and the summaries aren't used by Falco Talon and are ignored
+func NewMetricsRequest(rm *metricdata.ResourceMetrics) MetricsRequest {
+	r := resourceMetrics{Resource: NewResource(rm.Resource)}
+	for _, i := range rm.ScopeMetrics {
+		s := scopeMetrics{Scope: NewScope(i.Scope)}
+		for _, j := range i.Metrics {
+			m := metric{Name: j.Name, Description: j.Description, Unit: j.Unit}
+			switch data := j.Data.(type) {
+			case metricdata.Sum[int64]:
+				m.Sum = &sum{DataPoints: newNumberDataPoints(data.DataPoints), AggregationTemporality: newTemporality(data.Temporality), IsMonotonic: data.IsMonotonic}
+			case metricdata.Sum[float64]:
+				m.Sum = &sum{DataPoints: newNumberDataPoints(data.DataPoints), AggregationTemporality: newTemporality(data.Temporality), IsMonotonic: data.IsMonotonic}
+			case metricdata.Gauge[int64]:
+				m.Gauge = &gauge{DataPoints: newNumberDataPoints(data.DataPoints)}
+			case metricdata.Gauge[float64]:
+				m.Gauge = &gauge{DataPoints: newNumberDataPoints(data.DataPoints)}
+			case metricdata.Histogram[int64]:
+				m.Histogram = &histogram{DataPoints: newHistogramDataPoints(data.DataPoints), AggregationTemporality: newTemporality(data.Temporality)}
+			case metricdata.Histogram[float64]:
+				m.Histogram = &histogram{DataPoints: newHistogramDataPoints(data.DataPoints), AggregationTemporality: newTemporality(data.Temporality)}
+			default:
+				continue
+			}
+			s.Metrics = append(s.Metrics, m)
+		}
+		r.ScopeMetrics = append(r.ScopeMetrics, s)
+	}
+	return MetricsRequest{ResourceMetrics: []resourceMetrics{r}}
+}
+
+func newTemporality(t metricdata.Temporality) int {
+	if t == metricdata.DeltaTemporality {
+		return temporalityDelta
+	}
+	return temporalityCumulative
+}
+
+func newNumberDataPoints[N int64 | float64](points []metricdata.DataPoint[N]) []numberDataPoint {
+	list := make([]numberDataPoint, 0, len(points))
+	for _, i := range points {
+		p := numberDataPoint{
+			StartTimeUnixNano: FormatTime(i.StartTime),
+			TimeUnixNano:      FormatTime(i.Time),
+			Attributes:        NewAttributes(i.Attributes.ToSlice()),
+		}
+		switch v := any(i.Value).(type) {
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			p.AsInt = &s
+		case float64:
+			p.AsDouble = &v
+		}
+		list = append(list, p)
+	}
+	return list
+}
+
+func newHistogramDataPoints[N int64 | float64](points []metricdata.HistogramDataPoint[N]) []histogramDataPoint {
+	list := make([]histogramDataPoint, 0, len(points))
+	for _, i := range points {
+		p := histogramDataPoint{
+			StartTimeUnixNano: FormatTime(i.StartTime),
+			TimeUnixNano:      FormatTime(i.Time),
+			Count:             strconv.FormatUint(i.Count, 10),
+			Attributes:        NewAttributes(i.Attributes.ToSlice()),
+			ExplicitBounds:    i.Bounds,
+			Sum:               float64(i.Sum),
+		}
+		for _, j := range i.BucketCounts {
+			p.BucketCounts = append(p.BucketCounts, strconv.FormatUint(j, 10))
+		}
+		if v, ok := i.Min.Value(); ok {
+			f := float64(v)
+			p.Min = &f
+		}
+		if v, ok := i.Max.Value(); ok {
+			f := float64(v)
+			p.Max = &f
+		}
+		list = append(list, p)
+	}
+	return list
+}
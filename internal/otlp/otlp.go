@@ -0,0 +1,175 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// paths of the signals on an OTLP/HTTP endpoint
+const (
+	TracesPath  string = "/v1/traces"
+	MetricsPath string = "/v1/metrics"
+	LogsPath    string = "/v1/logs"
+)
+
+// Client sends the signals to an OTLP/HTTP endpoint with the JSON encoding,
+// it allows to export them without embedding the gRPC stack of the official exporters
+type Client struct {
+	httpClient *http.Client
+	headers    map[string]string
+	endpoint   string
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+type Scope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type KeyValue struct {
+	Value Value  `json:"value"`
+	Key   string `json:"key"`
+}
+
+type Value struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    *string     `json:"intValue,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue `json:"arrayValue,omitempty"`
+}
+
+type ArrayValue struct {
+	Values []Value `json:"values"`
+}
+
+// NewClient returns a client for the endpoint of the collector (ex: http://localhost:4318),
+// the path of the signal is appended to it
+func NewClient(endpoint string, headers map[string]string) *Client {
+	return &Client{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		headers:    headers,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+	}
+}
+
+// Send posts the payload to the path of the signal
+func (client *Client) Send(ctx context.Context, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for i, j := range client.headers {
+		req.Header.Set(i, j)
+	}
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("can't export to '%v': %v", path, resp.Status)
+	}
+	return nil
+}
+
+// CloseIdleConnections closes the connections once the exports are over
+func (client *Client) CloseIdleConnections() {
+	client.httpClient.CloseIdleConnections()
+}
+
+func NewResource(r *resource.Resource) Resource {
+	if r == nil {
+		return Resource{Attributes: []KeyValue{}}
+	}
+	return Resource{Attributes: NewAttributes(r.Attributes())}
+}
+
+func NewScope(scope instrumentation.Scope) Scope {
+	return Scope{Name: scope.Name, Version: scope.Version}
+}
+
+func NewAttributes(attrs []attribute.KeyValue) []KeyValue {
+	list := make([]KeyValue, 0, len(attrs))
+	for _, i := range attrs {
+		list = append(list, KeyValue{Key: string(i.Key), Value: NewValue(i.Value)})
+	}
+	return list
+}
+
+func NewValue(v attribute.Value) Value {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return Value{BoolValue: &b}
+	case attribute.INT64:
+		s := strconv.FormatInt(v.AsInt64(), 10)
+		return Value{IntValue: &s}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return Value{DoubleValue: &f}
+	case attribute.BOOLSLICE:
+		values := []Value{}
+		for _, i := range v.AsBoolSlice() {
+			values = append(values, NewValue(attribute.BoolValue(i)))
+		}
+		return Value{ArrayValue: &ArrayValue{Values: values}}
+	case attribute.INT64SLICE:
+		values := []Value{}
+		for _, i := range v.AsInt64Slice() {
+			values = append(values, NewValue(attribute.Int64Value(i)))
+		}
+		return Value{ArrayValue: &ArrayValue{Values: values}}
+	case attribute.FLOAT64SLICE:
+		values := []Value{}
+		for _, i := range v.AsFloat64Slice() {
+			values = append(values, NewValue(attribute.Float64Value(i)))
+		}
+		return Value{ArrayValue: &ArrayValue{Values: values}}
+	case attribute.STRINGSLICE:
+		values := []Value{}
+		for _, i := range v.AsStringSlice() {
+			values = append(values, NewValue(attribute.StringValue(i)))
+		}
+		return Value{ArrayValue: &ArrayValue{Values: values}}
+	default:
+		s := v.Emit()
+		return Value{StringValue: &s}
+	}
+}
+
+// NewStringValue returns the value of a string
+func NewStringValue(s string) Value {
+	return Value{StringValue: &s}
+}
+
+// FormatTime returns the time in nanoseconds, as a string like the other 64 bits integers of the JSON encoding
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
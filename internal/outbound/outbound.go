@@ -0,0 +1,101 @@
+package outbound
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+)
+
+// the outbound connections go through the proxy and trust the CA bundle of the configuration, the default
+// transport of net/http is updated, the clients using it or a clone of it don't need any change
+
+var (
+	rootCAs *x509.CertPool
+	proxy   = http.ProxyFromEnvironment
+)
+
+// Init sets the proxy, the CA bundle and the TLS policy of the default transport, before the init of the clients
+func Init(config configuration.OutboundConfig) error {
+	if config.HTTPProxy != "" || config.HTTPSProxy != "" || config.NoProxy != "" {
+		env := httpproxy.FromEnvironment()
+		// the env vars are set too, for the SDKs which build their own transports
+		for _, i := range []struct {
+			value *string
+			set   string
+			env   string
+		}{
+			{&env.HTTPProxy, config.HTTPProxy, "HTTP_PROXY"},
+			{&env.HTTPSProxy, config.HTTPSProxy, "HTTPS_PROXY"},
+			{&env.NoProxy, config.NoProxy, "NO_PROXY"},
+		} {
+			if i.set == "" {
+				continue
+			}
+			*i.value = i.set
+			if err := os.Setenv(i.env, i.set); err != nil {
+				return err
+			}
+		}
+		f := env.ProxyFunc()
+		proxy = func(req *http.Request) (*url.URL, error) {
+			return f(req.URL)
+		}
+	}
+
+	if config.CACertFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		b, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no valid certificate in '%v'", config.CACertFile)
+		}
+		rootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport)
+	transport.Proxy = proxy
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	// the TLS policy must be set before
+	tlspolicy.Apply(transport.TLSClientConfig)
+	if rootCAs != nil {
+		transport.TLSClientConfig.RootCAs = rootCAs
+	}
+	return nil
+}
+
+// Proxy returns the proxy of the request, nil for a direct connection
+func Proxy(req *http.Request) (*url.URL, error) {
+	return proxy(req)
+}
+
+// RootCAs returns the CAs trusted by the outbound connections, nil for the CAs of the system
+func RootCAs() *x509.CertPool {
+	return rootCAs
+}
+
+// CertPool returns a copy of the trusted CAs, to add the CA of an endpoint
+func CertPool() *x509.CertPool {
+	if rootCAs != nil {
+		return rootCAs.Clone()
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		return x509.NewCertPool()
+	}
+	return pool
+}
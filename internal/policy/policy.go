@@ -0,0 +1,128 @@
+package policy
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+)
+
+// the policy is a Rego rule queried with the Data API of OPA (POST /v1/data/<path>), its result is a boolean
+// or an object {"allow": <bool>, "reason": <string>}, an undefined result vetoes the action
+
+// Input is the document sent to the policy, the proposed action and its target
+type Input struct {
+	Event      *events.Event          `json:"event"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Target     interface{}            `json:"target,omitempty"`
+	Rule       string                 `json:"rule"`
+	Action     string                 `json:"action"`
+	Actionner  string                 `json:"actionner"`
+	Cluster    string                 `json:"cluster,omitempty"`
+}
+
+// Decision is the result of the policy
+type Decision struct {
+	Reason string `json:"reason"`
+	Allow  bool   `json:"allow"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+}
+
+var (
+	client   *http.Client
+	settings configuration.GuardPolicyConfig
+)
+
+// Init creates the client of the OPA server
+func Init(config configuration.GuardPolicyConfig) error {
+	if config.URL == "" {
+		return errors.New("wrong `url` setting")
+	}
+	tlsConfig := tlspolicy.Apply(&tls.Config{MinVersion: tls.VersionTLS12, RootCAs: outbound.RootCAs()})
+	if config.CACertFile != "" {
+		pool := outbound.CertPool()
+		b, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no valid certificate in '%v'", config.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	client = &http.Client{Timeout: time.Duration(config.TimeoutSeconds) * time.Second, Transport: transport}
+	settings = config
+	return nil
+}
+
+// IsEnabled returns true if the actions of the actionner are checked by the policy, all the actionners
+// are checked if the list is empty
+func IsEnabled(actionner string) bool {
+	if client == nil {
+		return false
+	}
+	return len(settings.Actionners) == 0 || slices.Contains(settings.Actionners, actionner)
+}
+
+// IsFailOpen returns true if the actions are allowed when the policy can't be evaluated
+func IsFailOpen() bool {
+	return settings.FailOpen
+}
+
+// Evaluate queries the policy with the input
+func Evaluate(input *Input) (*Decision, error) {
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if settings.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+settings.BearerToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("wrong response from the policy server: %v", resp.Status)
+	}
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+	if len(r.Result) == 0 {
+		return &Decision{Reason: "the result of the policy is undefined"}, nil
+	}
+	var allow bool
+	if err := json.Unmarshal(r.Result, &allow); err == nil {
+		return &Decision{Allow: allow}, nil
+	}
+	d := new(Decision)
+	if err := json.Unmarshal(r.Result, d); err != nil {
+		return nil, fmt.Errorf("wrong result of the policy: %v", err)
+	}
+	return d, nil
+}
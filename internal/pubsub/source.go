@@ -0,0 +1,153 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	gcp "github.com/falco-talon/falco-talon/internal/gcp/client"
+	"github.com/falco-talon/falco-talon/internal/handler"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type pullRequest struct {
+	MaxMessages int `json:"maxMessages"`
+}
+
+type pullResponse struct {
+	ReceivedMessages []struct {
+		AckID   string `json:"ackId"`
+		Message struct {
+			MessageID string `json:"messageId"`
+			Data      []byte `json:"data"`
+		} `json:"message"`
+	} `json:"receivedMessages"`
+}
+
+type acknowledgeRequest struct {
+	AckIDs []string `json:"ackIds"`
+}
+
+const (
+	subscriptionURL string = "https://pubsub.googleapis.com/v1/projects/%v/subscriptions/%v"
+	scope           string = "https://www.googleapis.com/auth/pubsub"
+	maxBackoff             = 30 * time.Second
+)
+
+// StartSource pulls the messages of the subscription, a message is acknowledged once its event
+// is published, it's redelivered after the ack deadline of the subscription otherwise
+func StartSource() error {
+	config := configuration.GetConfiguration().PubSub
+	if config.ProjectID == "" {
+		return errors.New("wrong `project_id` setting")
+	}
+	if config.Subscription == "" {
+		return errors.New("wrong `subscription` setting")
+	}
+	if config.MaxMessages < 1 {
+		return errors.New("wrong `max_messages` setting")
+	}
+
+	if err := gcp.Init(); err != nil {
+		return err
+	}
+	client := gcp.GetGCPClient()
+	if client == nil {
+		return errors.New("client error")
+	}
+
+	u := fmt.Sprintf(subscriptionURL, config.ProjectID, config.Subscription)
+	// the pull requests are held by the server until messages are available
+	httpClient := &http.Client{Timeout: 90 * time.Second}
+
+	go func() {
+		utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("pulling the subscription '%v'", config.Subscription), Message: "pubsub"})
+		backoff := time.Second
+		for {
+			var output pullResponse
+			if err := call(httpClient, client, u+":pull", pullRequest{MaxMessages: config.MaxMessages}, &output); err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "pubsub"})
+				time.Sleep(backoff)
+				if backoff < maxBackoff {
+					backoff *= 2
+				}
+				continue
+			}
+			backoff = time.Second
+
+			ackIDs := make([]string, 0, len(output.ReceivedMessages))
+			for _, i := range output.ReceivedMessages {
+				event, err := events.DecodeEvent(bytes.NewReader(i.Message.Data))
+				if err != nil {
+					utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("can't decode the message '%v': %v", i.Message.MessageID, err), Message: "pubsub"})
+				} else if err := handler.PublishEvent(event); err != nil {
+					utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "pubsub", TraceID: event.TraceID})
+					continue
+				}
+				ackIDs = append(ackIDs, i.AckID)
+			}
+
+			if len(ackIDs) == 0 {
+				continue
+			}
+			if err := call(httpClient, client, u+":acknowledge", acknowledgeRequest{AckIDs: ackIDs}, nil); err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "pubsub"})
+			}
+		}
+	}()
+
+	return nil
+}
+
+func call(httpClient *http.Client, client *gcp.GCPClient, u string, input, output interface{}) error {
+	token, err := client.GetToken(scope)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(b, &e); err == nil && e.Error.Message != "" {
+			return fmt.Errorf("%v: %v", resp.Status, e.Error.Message)
+		}
+		return errors.New(resp.Status)
+	}
+
+	if output == nil {
+		return nil
+	}
+	return json.Unmarshal(b, output)
+}
@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+)
+
+// classes of priorities, each one is processed by its own pool of workers
+const (
+	Critical      string = "critical"      // Emergency, Alert, Critical
+	Warning       string = "warning"       // Error, Warning
+	Informational string = "informational" // Notice, Informational, Debug and the unknown priorities
+)
+
+var Classes = []string{Critical, Warning, Informational}
+
+type item struct {
+	event    *events.Event
+	done     func()
+	priority int
+	seq      uint64
+}
+
+// items is a heap of the events by priority, then by order of arrival
+type items []*item
+
+func (h items) Len() int { return len(h) }
+func (h items) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h items) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *items) Push(x interface{}) { *h = append(*h, x.(*item)) }
+func (h *items) Pop() interface{} {
+	old := *h
+	n := len(old)
+	i := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return i
+}
+
+var (
+	queues map[string]*items
+	seq    uint64
+	length int
+	closed bool
+	mu     sync.Mutex
+	cond   *sync.Cond
+)
+
+func init() {
+	queues = make(map[string]*items, len(Classes))
+	for _, i := range Classes {
+		queues[i] = new(items)
+	}
+	cond = sync.NewCond(&mu)
+}
+
+// GetClass returns the class of the priority
+func GetClass(priority string) string {
+	p := rules.GetPriorityNumber(priority)
+	switch {
+	case p >= rules.Critical:
+		return Critical
+	case p >= rules.Warning:
+		return Warning
+	default:
+		return Informational
+	}
+}
+
+// Push adds the event to the queue of its class, done is called once the event is processed
+func Push(event *events.Event, done func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	seq++
+	heap.Push(queues[GetClass(event.Priority)], &item{
+		event:    event,
+		done:     done,
+		priority: rules.GetPriorityNumber(event.Priority),
+		seq:      seq,
+	})
+	length++
+	cond.Broadcast()
+}
+
+// Pop waits for an event of the class, the events with the highest priority come first,
+// the returned function must be called once the event is processed, a nil event is returned once the queues are closed
+func Pop(class string) (*events.Event, func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	q := queues[class]
+	for q.Len() == 0 && !closed {
+		cond.Wait()
+	}
+	if closed {
+		return nil, nil
+	}
+	length--
+	i := heap.Pop(q).(*item)
+	if i.done == nil {
+		return i.event, func() {}
+	}
+	return i.event, i.done
+}
+
+// Close stops the distribution of the events, the waiting workers are released
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	closed = true
+	cond.Broadcast()
+}
+
+// Drain removes and returns the events still waiting in the queues, their done functions aren't called
+func Drain() []*events.Event {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]*events.Event, 0, length)
+	for _, i := range Classes {
+		q := queues[i]
+		for q.Len() != 0 {
+			list = append(list, heap.Pop(q).(*item).event)
+		}
+	}
+	length = 0
+	return list
+}
+
+// Count returns the number of events waiting in the queue of the class
+func Count(class string) int {
+	mu.Lock()
+	defer mu.Unlock()
+	q, ok := queues[class]
+	if !ok {
+		return 0
+	}
+	return q.Len()
+}
+
+// Len returns the number of events waiting in the queues
+func Len() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return length
+}
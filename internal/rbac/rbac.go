@@ -0,0 +1,295 @@
+package rbac
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/falco-talon/falco-talon/actionners"
+	"github.com/falco-talon/falco-talon/configuration"
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	ruleengine "github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/notifiers"
+)
+
+const (
+	rbacAPIVersion string = "rbac.authorization.k8s.io/v1"
+	leaseBackend   string = "lease"
+	namesSeparator string = "\x00" // the names of the users can contain any character
+)
+
+// Options are the names of the generated resources and of the identity of Falco Talon
+type Options struct {
+	Name           string // name of the roles and the bindings
+	Namespace      string // namespace of Falco Talon, for its service account and its leases
+	ServiceAccount string
+}
+
+// Object is a Role, a ClusterRole or one of their bindings
+type Object struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   map[string]string `yaml:"metadata"`
+	Rules      []k8s.Permission  `yaml:"rules,omitempty"`
+	RoleRef    *RoleRef          `yaml:"roleRef,omitempty"`
+	Subjects   []Subject         `yaml:"subjects,omitempty"`
+}
+
+type RoleRef struct {
+	APIGroup string `yaml:"apiGroup"`
+	Kind     string `yaml:"kind"`
+	Name     string `yaml:"name"`
+}
+
+type Subject struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// GetPermissions returns the permissions required by the actionners and the notifiers of the rules and by the
+// enabled features, the permissions in the namespace of Falco Talon (leases) are returned apart, with warnings
+func GetPermissions(rules *[]*ruleengine.Rule, config *configuration.Configuration) ([]k8s.Permission, []k8s.Permission, []string) {
+	permissions := make([]k8s.Permission, 0)
+	local := make([]k8s.Permission, 0)
+	warnings := make([]string, 0)
+
+	defaultActionners := actionners.GetDefaultActionners()
+	availableNotifiers := notifiers.GetAvailableNotifiers()
+
+	usesKubernetes := false
+	usedNotifiers := make(map[string]bool)
+	for _, i := range config.DefaultNotifiers {
+		usedNotifiers[i] = true
+	}
+	for _, i := range *rules {
+		for _, j := range i.GetActions() {
+			actionner := defaultActionners.FindActionner(j.GetActionner())
+			if actionner == nil {
+				warnings = append(warnings, fmt.Sprintf("unknown actionner '%v'", j.GetActionner()))
+				continue
+			}
+			if len(actionner.Permissions) != 0 {
+				usesKubernetes = true
+				permissions = append(permissions, actionner.Permissions...)
+			}
+		}
+		for _, j := range i.GetNotifiers() {
+			usedNotifiers[j] = true
+		}
+		if user := i.GetImpersonatedUser(); user != "" {
+			permissions = append(permissions, impersonation(user))
+		}
+		if groups := i.GetImpersonatedGroups(); len(groups) != 0 {
+			permissions = append(permissions, k8s.Permission{APIGroups: []string{""}, Resources: []string{"groups"}, ResourceNames: groups, Verbs: []string{"impersonate"}})
+		}
+	}
+	for i := range usedNotifiers {
+		if n := availableNotifiers.FindNotifier(i); n != nil {
+			permissions = append(permissions, n.Permissions...)
+		}
+	}
+
+	if usesKubernetes && config.KubernetesCache.Enabled {
+		permissions = append(permissions,
+			k8s.Permission{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list", "watch"}},
+			k8s.Permission{APIGroups: []string{"apps"}, Resources: []string{"deployments", "daemonsets", "statefulsets", "replicasets"}, Verbs: []string{"list", "watch"}},
+		)
+		if !config.IsNamespaced() {
+			permissions = append(permissions, k8s.Permission{APIGroups: []string{""}, Resources: []string{"nodes"}, Verbs: []string{"list", "watch"}})
+		}
+	}
+	if usesKubernetes && config.KubernetesEvents.Enabled {
+		permissions = append(permissions,
+			k8s.Permission{APIGroups: []string{""}, Resources: []string{"pods", "nodes"}, Verbs: []string{"get"}},
+			k8s.Permission{APIGroups: []string{"apps"}, Resources: []string{"deployments", "daemonsets", "statefulsets", "replicasets"}, Verbs: []string{"get"}},
+			k8s.Permission{APIGroups: []string{"batch"}, Resources: []string{"jobs", "cronjobs"}, Verbs: []string{"get"}},
+			k8s.Permission{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+		)
+	}
+	if config.Expiry.Enabled {
+		// the expiry removes the resources of all the containment actionners
+		permissions = append(permissions,
+			k8s.Permission{APIGroups: []string{""}, Resources: []string{"pods", "nodes"}, Verbs: []string{"list", "patch"}},
+			k8s.Permission{APIGroups: []string{"networking.k8s.io"}, Resources: []string{"networkpolicies"}, Verbs: []string{"list", "delete"}},
+			k8s.Permission{APIGroups: []string{"projectcalico.org"}, Resources: []string{"networkpolicies"}, Verbs: []string{"list", "delete"}},
+			k8s.Permission{APIGroups: []string{"cilium.io"}, Resources: []string{"ciliumnetworkpolicies"}, Verbs: []string{"list", "delete"}},
+		)
+	}
+
+	if config.Deduplication.LeaderElection {
+		local = append(local, k8s.Permission{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: []string{"get", "create", "update", "watch"}})
+	}
+	if config.ActionLocks.Enabled && config.ActionLocks.Backend == leaseBackend {
+		local = append(local, k8s.Permission{APIGroups: []string{"coordination.k8s.io"}, Resources: []string{"leases"}, Verbs: []string{"get", "create", "update", "delete"}})
+	}
+
+	return Merge(permissions), Merge(local), warnings
+}
+
+// impersonation returns the permission to impersonate the user, or the service account for the
+// "system:serviceaccount:<namespace>:<name>" users
+func impersonation(user string) k8s.Permission {
+	if s := strings.Split(user, ":"); len(s) == 4 && s[0] == "system" && s[1] == "serviceaccount" {
+		return k8s.Permission{APIGroups: []string{""}, Resources: []string{"serviceaccounts"}, ResourceNames: []string{s[3]}, Verbs: []string{"impersonate"}}
+	}
+	return k8s.Permission{APIGroups: []string{""}, Resources: []string{"users"}, ResourceNames: []string{user}, Verbs: []string{"impersonate"}}
+}
+
+// Merge merges the verbs of the same resources, then the resources with the same verbs, the rules are sorted
+func Merge(permissions []k8s.Permission) []k8s.Permission {
+	type resource struct {
+		group, name, names string
+	}
+	verbs := make(map[resource]map[string]bool)
+	for _, i := range permissions {
+		names := strings.Join(i.ResourceNames, namesSeparator)
+		for _, g := range i.APIGroups {
+			for _, r := range i.Resources {
+				key := resource{group: g, name: r, names: names}
+				if verbs[key] == nil {
+					verbs[key] = make(map[string]bool)
+				}
+				for _, v := range i.Verbs {
+					verbs[key][v] = true
+				}
+			}
+		}
+	}
+
+	type rule struct {
+		group, verbs, names string
+	}
+	resources := make(map[rule][]string)
+	for i, j := range verbs {
+		key := rule{group: i.group, verbs: strings.Join(sortedKeys(j), ","), names: i.names}
+		resources[key] = append(resources[key], i.name)
+	}
+
+	merged := make([]k8s.Permission, 0, len(resources))
+	for i, j := range resources {
+		sort.Strings(j)
+		p := k8s.Permission{
+			APIGroups: []string{i.group},
+			Resources: j,
+			Verbs:     strings.Split(i.verbs, ","),
+		}
+		if i.names != "" {
+			p.ResourceNames = strings.Split(i.names, namesSeparator)
+		}
+		merged = append(merged, p)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		if a.APIGroups[0] != b.APIGroups[0] {
+			return a.APIGroups[0] < b.APIGroups[0]
+		}
+		if a.Resources[0] != b.Resources[0] {
+			return a.Resources[0] < b.Resources[0]
+		}
+		if v, w := strings.Join(a.Verbs, ","), strings.Join(b.Verbs, ","); v != w {
+			return v < w
+		}
+		return strings.Join(a.ResourceNames, ",") < strings.Join(b.ResourceNames, ",")
+	})
+	return merged
+}
+
+// Generate returns the Roles and the ClusterRoles with their bindings, a ClusterRole for all the
+// namespaces or a Role in each allowed namespace in the namespace-scoped mode, without the
+// cluster-scoped permissions
+func Generate(permissions, local []k8s.Permission, namespaces []string, options Options) ([]Object, []string) {
+	objects := make([]Object, 0)
+	warnings := make([]string, 0)
+
+	if len(namespaces) == 0 {
+		if len(permissions) != 0 {
+			objects = append(objects, role("ClusterRole", options.Name, "", permissions), binding("ClusterRole", options.Name, "", options))
+		}
+	} else {
+		namespaced := make([]k8s.Permission, 0, len(permissions))
+		for _, i := range permissions {
+			if i.IsClusterScoped() {
+				warnings = append(warnings, fmt.Sprintf("the permission to %v the %v isn't allowed in the namespace-scoped mode", strings.Join(i.Verbs, ","), strings.Join(i.Resources, ",")))
+				continue
+			}
+			namespaced = append(namespaced, i)
+		}
+		if len(namespaced) != 0 {
+			for _, i := range namespaces {
+				objects = append(objects, role("Role", options.Name, i, namespaced), binding("Role", options.Name, i, options))
+			}
+		}
+	}
+
+	if len(local) != 0 {
+		name := options.Name + "-leases"
+		objects = append(objects, role("Role", name, options.Namespace, local), binding("Role", name, options.Namespace, options))
+	}
+	return objects, warnings
+}
+
+// Marshal returns the objects as a multi-documents YAML
+func Marshal(objects []Object) ([]byte, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	for _, i := range objects {
+		if err := enc.Encode(i); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func role(kind, name, namespace string, permissions []k8s.Permission) Object {
+	return Object{
+		APIVersion: rbacAPIVersion,
+		Kind:       kind,
+		Metadata:   metadata(name, namespace),
+		Rules:      permissions,
+	}
+}
+
+func binding(kind, name, namespace string, options Options) Object {
+	return Object{
+		APIVersion: rbacAPIVersion,
+		Kind:       kind + "Binding",
+		Metadata:   metadata(name, namespace),
+		RoleRef: &RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     kind,
+			Name:     name,
+		},
+		Subjects: []Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      options.ServiceAccount,
+				Namespace: options.Namespace,
+			},
+		},
+	}
+}
+
+func metadata(name, namespace string) map[string]string {
+	m := map[string]string{"name": name}
+	if namespace != "" {
+		m["namespace"] = namespace
+	}
+	return m
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for i := range m {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	return keys
+}
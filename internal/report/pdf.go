@@ -0,0 +1,106 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfFontSize   int = 9
+	pdfLineHeight int = 11
+	pdfMargin     int = 40
+	pdfPageWidth  int = 595 // A4
+	pdfPageHeight int = 842
+	// the glyphs of Courier are 600/1000 of the font size wide, 95 characters in the 515pt between the margins
+	pdfCharWidth    int = 600
+	pdfCharsPerLine int = (pdfPageWidth - 2*pdfMargin) * 1000 / (pdfCharWidth * pdfFontSize)
+)
+
+// NewPDF renders the text as a minimal PDF document, with a monospace font and a line wrapping
+func NewPDF(title, text string) []byte {
+	lines := wrapLines(text)
+	linesPerPage := (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+
+	var pages [][]string
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// objects: 1 catalog, 2 pages, 3 font, 4 info, then a page and a content stream for each page
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>",
+		fmt.Sprintf("<< /Title (%v) /Producer (falco-talon) >>", escapePDF(title)),
+	}
+
+	kids := make([]string, 0, len(pages))
+	for _, p := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT /F1 %v Tf %v TL %v %v Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, l := range p {
+			fmt.Fprintf(&content, "(%v) '\n", escapePDF(l))
+		}
+		content.WriteString("ET")
+
+		pageID := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%v 0 R", pageID))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %v %v] /Resources << /Font << /F1 3 0 R >> >> /Contents %v 0 R >>", pdfPageWidth, pdfPageHeight, pageID+1),
+			fmt.Sprintf("<< /Length %v >>\nstream\n%v\nendstream", content.Len(), content.String()),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%v] /Count %v >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%v 0 obj\n%v\nendobj\n", i+1, o)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %v\n0000000000 65535 f \n", len(objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %v /Root 1 0 R /Info 4 0 R >>\nstartxref\n%v\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+func wrapLines(text string) []string {
+	var lines []string
+	for _, l := range strings.Split(text, "\n") {
+		r := []rune(l)
+		for len(r) > pdfCharsPerLine {
+			lines = append(lines, string(r[:pdfCharsPerLine]))
+			r = r[pdfCharsPerLine:]
+		}
+		lines = append(lines, string(r))
+	}
+	return lines
+}
+
+// escapePDF escapes the special characters of the PDF strings, the characters out of the
+// standard encoding of the fonts are replaced
+func escapePDF(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		case c == '\t':
+			b.WriteString("    ")
+		case c < 32 || c > 126:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
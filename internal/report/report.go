@@ -0,0 +1,189 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	htmlTemplate "html/template"
+	"strings"
+	"sync"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/incidents"
+	"github.com/falco-talon/falco-talon/internal/labels"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	HTML     string = "html"
+	Markdown string = "markdown"
+	PDF      string = "pdf"
+)
+
+// Report gathers the event and the results of the actions of a triggered rule
+type Report struct {
+	Context     map[string]string
+	Fields      map[string]string
+	Event       events.Event
+	Start       time.Time
+	Rule        string
+	Description string
+	IncidentID  string
+	Incident    []incidents.Entry
+	Dropped     int // events of the incident not in its timeline
+	Steps       []Step
+	Artifacts   []Artifact
+	mu          sync.Mutex
+}
+
+// Step is an entry of the timeline of the actions
+type Step struct {
+	Time      time.Time
+	Objects   map[string]string
+	Message   string
+	Action    string
+	Actionner string
+	Target    string
+	Status    string
+	Output    string
+	Error     string
+}
+
+// Artifact is a file stored by an output
+type Artifact struct {
+	Objects map[string]string
+	Action  string
+	Target  string
+	File    string
+	SHA256  string
+}
+
+var (
+	htmlTmpl     *htmlTemplate.Template
+	markdownTmpl *textTemplate.Template
+)
+
+func init() {
+	funcs := map[string]interface{}{
+		"keys":   labels.Keys[string],
+		"time":   func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+		"inline": func(s string) string { return strings.ReplaceAll(s, "\n", " ") },
+	}
+	htmlTmpl = htmlTemplate.Must(htmlTemplate.New(HTML).Funcs(funcs).Parse(htmlReport))
+	markdownTmpl = textTemplate.Must(textTemplate.New(Markdown).Funcs(funcs).Parse(markdownReport))
+}
+
+// CheckFormat returns an error if the format is unknown
+func CheckFormat(format string) error {
+	switch format {
+	case "", HTML, Markdown, PDF:
+		return nil
+	default:
+		return fmt.Errorf("wrong format '%v' for the report, must be 'html', 'markdown' or 'pdf'", format)
+	}
+}
+
+func NewReport(rule, description string, event *events.Event) *Report {
+	r := &Report{
+		Rule:        rule,
+		Description: description,
+		Event:       *event,
+		Start:       time.Now(),
+		Fields:      make(map[string]string, len(event.OutputFields)),
+		Context:     make(map[string]string),
+	}
+	for i, j := range event.OutputFields {
+		if l, ok := labels.Label(i); ok {
+			r.Fields[l] = fmt.Sprintf("%v", j)
+		}
+	}
+	return r
+}
+
+// AddContext adds the enrichment of the event, the internal elements of falco talon are ignored
+func (r *Report) AddContext(context map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, j := range context {
+		if strings.HasPrefix(i, "falco-talon.") {
+			continue
+		}
+		if l, ok := labels.Label(i); ok {
+			r.Context[l] = fmt.Sprintf("%v", j)
+		}
+	}
+}
+
+// SetIncident adds the timeline of the incident the event belongs to, with the number of events not in it
+func (r *Report) SetIncident(id string, timeline []incidents.Entry, dropped int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.IncidentID = id
+	r.Incident = timeline
+	r.Dropped = dropped
+}
+
+// Add adds a step to the timeline, the results of the outputs are also indexed as artifacts
+func (r *Report) Add(log utils.LogLine) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Steps = append(r.Steps, Step{
+		Time:      time.Now(),
+		Message:   log.Message,
+		Action:    log.Action,
+		Actionner: log.Actionner,
+		Target:    log.Target,
+		Status:    log.Status,
+		Output:    log.Output,
+		Error:     log.Error,
+		Objects:   log.Objects,
+	})
+
+	if log.Message == "output" && log.Status == "success" {
+		r.Artifacts = append(r.Artifacts, Artifact{
+			Action:  log.Action,
+			Target:  log.Target,
+			File:    log.Objects["file"],
+			SHA256:  log.Objects["sha256"],
+			Objects: log.Objects,
+		})
+	}
+}
+
+// Render returns the report in the format, html by default
+func (r *Report) Render(format string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var buf bytes.Buffer
+	switch format {
+	case Markdown:
+		if err := markdownTmpl.Execute(&buf, r); err != nil {
+			return nil, err
+		}
+	case PDF:
+		if err := markdownTmpl.Execute(&buf, r); err != nil {
+			return nil, err
+		}
+		return NewPDF("Falco Talon - "+r.Rule, buf.String()), nil
+	default:
+		if err := htmlTmpl.Execute(&buf, r); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// GetFileName returns the name of the report file for the format
+func GetFileName(format string) string {
+	switch format {
+	case Markdown:
+		return "report.md"
+	case PDF:
+		return "report.pdf"
+	default:
+		return "report.html"
+	}
+}
@@ -0,0 +1,97 @@
+package report
+
+var markdownReport = `# Incident report: {{ .Rule }}
+{{ if .Description }}
+{{ .Description }}
+{{ end }}
+- Trace ID: {{ .Event.TraceID }}{{ if .IncidentID }}
+- Incident ID: {{ .IncidentID }}{{ end }}
+- Falco rule: {{ .Event.Rule }}
+- Priority: {{ .Event.Priority }}
+- Source: {{ .Event.Source }}
+- Hostname: {{ .Event.Hostname }}
+- Event time: {{ time .Event.Time }}
+
+## Event
+
+{{ .Event.Output }}
+
+## Fields
+{{ range $k := keys .Fields }}
+- {{ $k }}: {{ index $.Fields $k }}{{ end }}
+{{ if .Context }}
+## Enrichment
+{{ range $k := keys .Context }}
+- {{ $k }}: {{ index $.Context $k }}{{ end }}
+{{ end }}{{ if .Incident }}
+## Incident
+{{ range .Incident }}
+- {{ time .Time }} [{{ .Priority }}] {{ .Rule }}: {{ inline .Output }}{{ end }}{{ if .Dropped }}
+- {{ .Dropped }} more event(s){{ end }}
+{{ end }}
+## Timeline
+{{ range .Steps }}
+- {{ time .Time }} [{{ .Message }}][{{ .Status }}] {{ .Action }}{{ if .Actionner }} ({{ .Actionner }}){{ end }}{{ if .Target }} -> {{ .Target }}{{ end }}{{ if .Output }}: {{ inline .Output }}{{ end }}{{ if .Error }} error: {{ inline .Error }}{{ end }}{{ end }}
+{{ if .Artifacts }}
+## Artifacts
+{{ range .Artifacts }}
+- {{ .File }} ({{ .Target }}, action '{{ .Action }}'){{ if .SHA256 }} sha256:{{ .SHA256 }}{{ end }}{{ range $k, $v := .Objects }}{{ if and (ne $k "file") (ne $k "sha256") }}
+  - {{ $k }}: {{ $v }}{{ end }}{{ end }}{{ end }}
+{{ end }}`
+
+var htmlReport = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Incident report: {{ .Rule }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #333; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f2f2f2; }
+pre { background: #f6f6f6; padding: 1em; white-space: pre-wrap; }
+.success { color: #23ba47; }
+.failure { color: #e20b0b; }
+</style>
+</head>
+<body>
+<h1>Incident report: {{ .Rule }}</h1>
+{{ if .Description }}<p>{{ .Description }}</p>{{ end }}
+<table>
+<tr><th>Trace ID</th><td>{{ .Event.TraceID }}</td></tr>
+{{ if .IncidentID }}<tr><th>Incident ID</th><td>{{ .IncidentID }}</td></tr>
+{{ end }}<tr><th>Falco rule</th><td>{{ .Event.Rule }}</td></tr>
+<tr><th>Priority</th><td>{{ .Event.Priority }}</td></tr>
+<tr><th>Source</th><td>{{ .Event.Source }}</td></tr>
+<tr><th>Hostname</th><td>{{ .Event.Hostname }}</td></tr>
+<tr><th>Event time</th><td>{{ time .Event.Time }}</td></tr>
+</table>
+<h2>Event</h2>
+<pre>{{ .Event.Output }}</pre>
+<h2>Fields</h2>
+<table>
+{{ range $k := keys .Fields }}<tr><th>{{ $k }}</th><td>{{ index $.Fields $k }}</td></tr>
+{{ end }}</table>
+{{ if .Context }}<h2>Enrichment</h2>
+<table>
+{{ range $k := keys .Context }}<tr><th>{{ $k }}</th><td>{{ index $.Context $k }}</td></tr>
+{{ end }}</table>
+{{ end }}{{ if .Incident }}<h2>Incident</h2>
+<table>
+<tr><th>Time</th><th>Priority</th><th>Falco rule</th><th>Event</th></tr>
+{{ range .Incident }}<tr><td>{{ time .Time }}</td><td>{{ .Priority }}</td><td>{{ .Rule }}</td><td>{{ .Output }}</td></tr>
+{{ end }}{{ if .Dropped }}<tr><td colspan="4">{{ .Dropped }} more event(s)</td></tr>
+{{ end }}</table>
+{{ end }}<h2>Timeline</h2>
+<table>
+<tr><th>Time</th><th>Step</th><th>Action</th><th>Actionner / Target</th><th>Status</th><th>Details</th></tr>
+{{ range .Steps }}<tr><td>{{ time .Time }}</td><td>{{ .Message }}</td><td>{{ .Action }}</td><td>{{ .Actionner }}{{ .Target }}</td><td class="{{ .Status }}">{{ .Status }}</td><td>{{ if .Output }}<pre>{{ .Output }}</pre>{{ end }}{{ if .Error }}<pre class="failure">{{ .Error }}</pre>{{ end }}</td></tr>
+{{ end }}</table>
+{{ if .Artifacts }}<h2>Artifacts</h2>
+<table>
+<tr><th>File</th><th>Action</th><th>Target</th><th>SHA256</th><th>Location</th></tr>
+{{ range .Artifacts }}<tr><td>{{ .File }}</td><td>{{ .Action }}</td><td>{{ .Target }}</td><td><code>{{ .SHA256 }}</code></td><td>{{ range $k, $v := .Objects }}{{ if and (ne $k "file") (ne $k "sha256") }}{{ $k }}: {{ $v }}<br>{{ end }}{{ end }}</td></tr>
+{{ end }}</table>
+{{ end }}</body>
+</html>
+`
@@ -0,0 +1,74 @@
+package rules
+
+import (
+	"errors"
+	"sync"
+)
+
+// the overrides of the rules set at runtime with the API, by name of rule, they're kept
+// when the rules are reloaded and reset on restart, each replica has its own overrides
+var (
+	overrides struct {
+		disabled map[string]bool
+		dryRun   map[string]bool
+		mu       sync.RWMutex
+	}
+	ErrUnknownRule = errors.New("unknown rule")
+)
+
+func init() {
+	overrides.disabled = make(map[string]bool)
+	overrides.dryRun = make(map[string]bool)
+}
+
+// FindRule returns the loaded rule with the name
+func FindRule(name string) *Rule {
+	for _, i := range *rules {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// SetEnabled enables or disables the rule at runtime, the disabled rules don't match any event
+func SetEnabled(name string, enabled bool) error {
+	if FindRule(name) == nil {
+		return ErrUnknownRule
+	}
+	overrides.mu.Lock()
+	defer overrides.mu.Unlock()
+	if enabled {
+		delete(overrides.disabled, name)
+	} else {
+		overrides.disabled[name] = true
+	}
+	return nil
+}
+
+// SetDryRun overrides the dry-run setting of the rule at runtime
+func SetDryRun(name string, dryRun bool) error {
+	if FindRule(name) == nil {
+		return ErrUnknownRule
+	}
+	overrides.mu.Lock()
+	defer overrides.mu.Unlock()
+	overrides.dryRun[name] = dryRun
+	return nil
+}
+
+func (rule *Rule) IsEnabled() bool {
+	overrides.mu.RLock()
+	defer overrides.mu.RUnlock()
+	return !overrides.disabled[rule.Name]
+}
+
+// IsDryRun returns the dry-run setting of the rule, the override set at runtime comes first
+func (rule *Rule) IsDryRun() bool {
+	overrides.mu.RLock()
+	defer overrides.mu.RUnlock()
+	if v, ok := overrides.dryRun[rule.Name]; ok {
+		return v
+	}
+	return rule.DryRun == trueStr
+}
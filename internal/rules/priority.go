@@ -14,7 +14,8 @@ const (
 	Emergency
 )
 
-func getPriorityNumber(priority string) int {
+// GetPriorityNumber returns the rank of the priority, Default (0) if the priority is unknown
+func GetPriorityNumber(priority string) int {
 	switch strings.ToLower(priority) {
 	case "emergency":
 		return Emergency
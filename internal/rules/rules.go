@@ -8,32 +8,68 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/report"
+	"github.com/falco-talon/falco-talon/internal/sops"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
 type Action struct {
 	Output             Output                 `yaml:"output,omitempty"`
+	Targets            *Targets               `yaml:"targets,omitempty"`
 	Parameters         map[string]interface{} `yaml:"parameters,omitempty"`
 	Name               string                 `yaml:"action"`
 	Description        string                 `yaml:"description"`
 	Actionner          string                 `yaml:"actionner"`
 	Continue           string                 `yaml:"continue,omitempty"`      // can't be a bool because an omitted value == false by default
 	IgnoreErrors       string                 `yaml:"ignore_errors,omitempty"` // can't be a bool because an omitted value == false by default
+	RevertAfter        string                 `yaml:"revert_after,omitempty"`
+	Verify             string                 `yaml:"verify,omitempty"` // can't be a bool, the setting of the configuration is used if omitted
 	AdditionalContexts []string               `yaml:"additional_contexts,omitempty"`
 }
 
+// Targets selects the pods targeted by the action, instead of the pod of the event
+type Targets struct {
+	LabelSelector string `yaml:"label_selector,omitempty" json:"label_selector,omitempty"`
+	FieldSelector string `yaml:"field_selector,omitempty" json:"field_selector,omitempty"`
+	Namespace     string `yaml:"namespace,omitempty" json:"namespace,omitempty"` // the namespace of the pod of the event if empty
+	MaxObjects    int    `yaml:"max_objects,omitempty" json:"max_objects,omitempty"`
+}
+
 type Rule struct {
-	Name        string    `yaml:"rule"`
-	Description string    `yaml:"description"`
-	Continue    string    `yaml:"continue"`          // can't be a bool because an omitted value == false by default
-	DryRun      string    `yaml:"dry_run,omitempty"` // can't be a bool because an omitted value == false by default
-	Actions     []*Action `yaml:"actions"`
-	Notifiers   []string  `yaml:"notifiers"`
-	Match       Match     `yaml:"match"`
+	Report      *Report        `yaml:"report,omitempty"`
+	Impersonate *Impersonation `yaml:"impersonate,omitempty"`
+	Name        string         `yaml:"rule"`
+	Description string         `yaml:"description"`
+	Continue    string         `yaml:"continue"`          // can't be a bool because an omitted value == false by default
+	DryRun      string         `yaml:"dry_run,omitempty"` // can't be a bool because an omitted value == false by default
+	Cluster     string         `yaml:"cluster,omitempty"`
+	Schedule    string         `yaml:"schedule,omitempty"` // cron expression, the rule is run periodically instead of by the events
+	Tenant      string         `yaml:"-"`                  // set for the rules of the rule files of a tenant
+	Actions     []*Action      `yaml:"actions"`
+	Notifiers   []string       `yaml:"notifiers"`
+	Match       Match          `yaml:"match"`
+	schedule    *Schedule
+}
+
+// Impersonation is the identity used by the Kubernetes actions of the rule, instead of the one of Falco Talon
+type Impersonation struct {
+	User           string   `yaml:"user,omitempty"`
+	ServiceAccount string   `yaml:"service_account,omitempty"` // namespace/name
+	Groups         []string `yaml:"groups,omitempty"`
+}
+
+// Tenant is a team with its own rule files, its rules aren't merged with the others and use its impersonation
+type Tenant struct {
+	Impersonate *Impersonation
+	Name        string
+	RulesFiles  []string
 }
 
 type Match struct {
@@ -48,6 +84,12 @@ type Match struct {
 	PriorityNumber     int
 }
 
+// Report renders an incident report once all the actions of the rule are done
+type Report struct {
+	Output Output `yaml:"output"`
+	Format string `yaml:"format,omitempty"`
+}
+
 type Output struct {
 	Parameters map[string]interface{} `yaml:"parameters"`
 	Target     string                 `yaml:"target"`
@@ -63,6 +105,7 @@ const (
 	trueStr                 string = "true"
 	falseStr                string = "false"
 	falcoTalonContextPrefix string = "falco-talon."
+	defaultMaxObjects       int    = 10
 )
 
 var rules *[]*Rule
@@ -85,16 +128,40 @@ func init() {
 	rules = new([]*Rule)
 }
 
-func ParseRules(files []string) *[]*Rule {
+func ParseRules(files []string, tenants []Tenant) *[]*Rule {
 	a, r, err := extractActionsRules(files)
 	if err != nil {
 		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "rules"})
 		return nil
 	}
 
+	// the rules of the tenants can use their own actions and the ones of the main rule files
+	tenantActions := make(map[string][]*Action, len(tenants))
+	for _, i := range tenants {
+		at, rt, err := extractActionsRules(i.RulesFiles)
+		if err != nil {
+			utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "rules", Objects: map[string]string{"tenant": i.Name}})
+			return nil
+		}
+		for _, j := range *rt {
+			if j.Impersonate != nil {
+				utils.PrintLog("error", utils.LogLine{Error: "'impersonate' can't be set in the rules of a tenant", Message: "rules", Rule: j.Name, Objects: map[string]string{"tenant": i.Name}})
+				return nil
+			}
+			j.Tenant = i.Name
+			j.Impersonate = i.Impersonate
+		}
+		tenantActions[i.Name] = append(*at, *a...)
+		*r = append(*r, *rt...)
+	}
+
 	for _, rule := range *r {
+		actions := *a
+		if rule.Tenant != "" {
+			actions = tenantActions[rule.Tenant]
+		}
 		for n := range rule.Actions {
-			for _, action := range *a {
+			for _, action := range actions {
 				if rule.Actions[n].Name == action.Name {
 					if rule.Actions[n].Description == "" && action.Description != "" {
 						rule.Actions[n].Description = action.Description
@@ -108,6 +175,15 @@ func ParseRules(files []string) *[]*Rule {
 					if rule.Actions[n].Continue == "" && action.Continue != "" {
 						rule.Actions[n].Continue = action.Continue
 					}
+					if rule.Actions[n].RevertAfter == "" && action.RevertAfter != "" {
+						rule.Actions[n].RevertAfter = action.RevertAfter
+					}
+					if rule.Actions[n].Verify == "" && action.Verify != "" {
+						rule.Actions[n].Verify = action.Verify
+					}
+					if rule.Actions[n].Targets == nil && action.Targets != nil {
+						rule.Actions[n].Targets = action.Targets
+					}
 					if len(rule.Actions[n].AdditionalContexts) == 0 && len(action.AdditionalContexts) != 0 {
 						rule.Actions[n].AdditionalContexts = make([]string, len(action.AdditionalContexts))
 						rule.Actions[n].AdditionalContexts = action.AdditionalContexts
@@ -226,6 +302,40 @@ func ParseRules(files []string) *[]*Rule {
 	return rules
 }
 
+// expandEnv replaces the ${VAR} of the values of the rule files by the env vars, the secrets don't need to be
+// in the ConfigMap of the rules. The parameters of the actions are skipped, their ${VAR} are the fields of
+// the events, expanded by the actionners, and the undefined vars are kept.
+func expandEnv(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		v := utils.ExpandDefinedEnv(node.Value)
+		if v != node.Value {
+			node.Value = v
+			if node.Style == 0 {
+				// the type of the unquoted values is resolved from the value of the env var (int, bool, ...)
+				node.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		var isAction bool
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "action" || node.Content[i].Value == "actionner" {
+				isAction = true
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if isAction && node.Content[i].Value == "parameters" {
+				continue
+			}
+			expandEnv(node.Content[i+1])
+		}
+	default:
+		for _, i := range node.Content {
+			expandEnv(i)
+		}
+	}
+}
+
 func extractActionsRules(files []string) (*[]*Action, *[]*Rule, error) {
 	if len(files) == 0 {
 		return nil, nil, errors.New("no rule file is provided")
@@ -241,11 +351,21 @@ func extractActionsRules(files []string) (*[]*Action, *[]*Rule, error) {
 		if err != nil {
 			return nil, nil, err
 		}
+		if sops.IsEncrypted(f) {
+			if f, err = sops.Decrypt(f); err != nil {
+				return nil, nil, fmt.Errorf("can't decrypt the rule file '%v': %v", i, err)
+			}
+		}
 
-		if err := yaml.Unmarshal(f, &at); err != nil {
+		var node yaml.Node
+		if err := yaml.Unmarshal(f, &node); err != nil {
 			return nil, nil, fmt.Errorf("wrong syntax for the rule file '%v': %v", files[0], err.Error())
 		}
-		if err := yaml.Unmarshal(f, &rt); err != nil {
+		expandEnv(&node)
+		if err := node.Decode(&at); err != nil {
+			return nil, nil, fmt.Errorf("wrong syntax for the rule file '%v': %v", files[0], err.Error())
+		}
+		if err := node.Decode(&rt); err != nil {
 			return nil, nil, fmt.Errorf("wrong syntax for the rule file '%v': %v", files[0], err.Error())
 		}
 
@@ -274,6 +394,15 @@ func extractActionsRules(files []string) (*[]*Action, *[]*Rule, error) {
 				if l.IgnoreErrors != "" {
 					i.IgnoreErrors = l.IgnoreErrors
 				}
+				if l.RevertAfter != "" {
+					i.RevertAfter = l.RevertAfter
+				}
+				if l.Verify != "" {
+					i.Verify = l.Verify
+				}
+				if l.Targets != nil {
+					i.Targets = l.Targets
+				}
 				if i.Parameters == nil && len(l.Parameters) != 0 {
 					i.Parameters = make(map[string]interface{})
 				}
@@ -352,6 +481,15 @@ func extractActionsRules(files []string) (*[]*Action, *[]*Rule, error) {
 				if l.DryRun != "" {
 					i.DryRun = l.DryRun
 				}
+				if l.Cluster != "" {
+					i.Cluster = l.Cluster
+				}
+				if l.Schedule != "" {
+					i.Schedule = l.Schedule
+				}
+				if l.Impersonate != nil {
+					i.Impersonate = l.Impersonate
+				}
 				if l.Description != "" {
 					i.Description = l.Description
 				}
@@ -362,6 +500,9 @@ func extractActionsRules(files []string) (*[]*Action, *[]*Rule, error) {
 				i.Match.Rules = append(i.Match.Rules, l.Match.Rules...)
 				i.Match.Tags = append(i.Match.Tags, l.Match.Tags...)
 				i.Actions = append(i.Actions, l.Actions...)
+				if l.Report != nil {
+					i.Report = l.Report
+				}
 				l.Name = ""
 			}
 		}
@@ -384,6 +525,39 @@ func extractActionsRules(files []string) (*[]*Action, *[]*Rule, error) {
 	return &af, &rf, nil
 }
 
+// FindDuplicates returns the names of the rules and the actions declared more than once in the file,
+// they are merged by ParseRules, which is expected between files but often a mistake in the same file
+func FindDuplicates(file string) ([]string, []string, error) {
+	f, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []struct {
+		Rule   string `yaml:"rule"`
+		Action string `yaml:"action"`
+	}
+	if err := yaml.Unmarshal(f, &items); err != nil {
+		return nil, nil, fmt.Errorf("wrong syntax for the rule file '%v': %v", file, err.Error())
+	}
+
+	var rules, actions []string
+	seenRules := make(map[string]int)
+	seenActions := make(map[string]int)
+	for _, i := range items {
+		if i.Rule != "" {
+			if seenRules[i.Rule]++; seenRules[i.Rule] == 2 {
+				rules = append(rules, i.Rule)
+			}
+		}
+		if i.Action != "" {
+			if seenActions[i.Action]++; seenActions[i.Action] == 2 {
+				actions = append(actions, i.Action)
+			}
+		}
+	}
+	return rules, actions, nil
+}
+
 func (rule *Rule) isValid() bool {
 	valid := true
 	if rule.Name == "" {
@@ -398,6 +572,32 @@ func (rule *Rule) isValid() bool {
 		utils.PrintLog("error", utils.LogLine{Error: "'dry_run' setting can be 'true' or 'false' only", Message: "rules", Rule: rule.Name})
 		valid = false
 	}
+	if i := rule.Impersonate; i != nil {
+		if i.User != "" && i.ServiceAccount != "" {
+			utils.PrintLog("error", utils.LogLine{Error: "'impersonate' can't have both a 'user' and a 'service_account'", Message: "rules", Rule: rule.Name})
+			valid = false
+		}
+		if s := strings.Split(i.ServiceAccount, "/"); i.ServiceAccount != "" && (len(s) != 2 || s[0] == "" || s[1] == "") {
+			utils.PrintLog("error", utils.LogLine{Error: "'impersonate.service_account' must be 'namespace/name'", Message: "rules", Rule: rule.Name})
+			valid = false
+		}
+		if i.User == "" && i.ServiceAccount == "" && len(i.Groups) != 0 {
+			utils.PrintLog("error", utils.LogLine{Error: "'impersonate.groups' requires a 'user' or a 'service_account'", Message: "rules", Rule: rule.Name})
+			valid = false
+		}
+	}
+	if rule.Schedule != "" {
+		s, err := ParseSchedule(rule.Schedule)
+		if err != nil {
+			utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("incorrect 'schedule': %v", err), Message: "rules", Rule: rule.Name})
+			valid = false
+		}
+		rule.schedule = s
+		if m := rule.Match; len(m.Rules) != 0 || len(m.Tags) != 0 || len(m.OutputFields) != 0 || m.Priority != "" || m.Source != "" {
+			utils.PrintLog("error", utils.LogLine{Error: "'match' can't be set for a rule with a 'schedule'", Message: "rules", Rule: rule.Name})
+			valid = false
+		}
+	}
 	if len(rule.Actions) == 0 {
 		utils.PrintLog("error", utils.LogLine{Error: "no action specified", Message: "rules", Rule: rule.Name})
 		valid = false
@@ -424,12 +624,48 @@ func (rule *Rule) isValid() bool {
 				utils.PrintLog("error", utils.LogLine{Error: "'ignore_errors' setting can be 'true' or 'false' only", Message: "rules", Action: i.Name, Actionner: i.Actionner, Rule: rule.Name})
 				valid = false
 			}
+			if i.Verify != "" && i.Verify != trueStr && i.Verify != falseStr {
+				utils.PrintLog("error", utils.LogLine{Error: "'verify' setting can be 'true' or 'false' only", Message: "rules", Action: i.Name, Actionner: i.Actionner, Rule: rule.Name})
+				valid = false
+			}
+			if _, err := time.ParseDuration(i.RevertAfter); i.RevertAfter != "" && err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: "'revert_after' setting must be a duration (ex: 2h)", Message: "rules", Action: i.Name, Actionner: i.Actionner, Rule: rule.Name})
+				valid = false
+			}
+			if t := i.Targets; t != nil {
+				if t.LabelSelector == "" && t.FieldSelector == "" {
+					utils.PrintLog("error", utils.LogLine{Error: "'targets' requires a 'label_selector' or a 'field_selector'", Message: "rules", Action: i.Name, Actionner: i.Actionner, Rule: rule.Name})
+					valid = false
+				}
+				if _, err := labels.Parse(t.LabelSelector); err != nil {
+					utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("incorrect 'label_selector': %v", err), Message: "rules", Action: i.Name, Actionner: i.Actionner, Rule: rule.Name})
+					valid = false
+				}
+				if _, err := fields.ParseSelector(t.FieldSelector); err != nil {
+					utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("incorrect 'field_selector': %v", err), Message: "rules", Action: i.Name, Actionner: i.Actionner, Rule: rule.Name})
+					valid = false
+				}
+				if t.MaxObjects < 0 {
+					utils.PrintLog("error", utils.LogLine{Error: "'max_objects' must be positive", Message: "rules", Action: i.Name, Actionner: i.Actionner, Rule: rule.Name})
+					valid = false
+				}
+			}
 			if i.Output.Target != "" && len(i.Output.Parameters) == 0 {
 				utils.PrintLog("error", utils.LogLine{Error: "missing 'parameters' for the output", Message: "rules", Action: i.Name, Actionner: i.Actionner, Rule: rule.Name, Target: i.Output.Target})
 				valid = false
 			}
 		}
 	}
+	if rule.Report != nil {
+		if err := report.CheckFormat(rule.Report.Format); err != nil {
+			utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "rules", Rule: rule.Name})
+			valid = false
+		}
+		if rule.Report.Output.Target == "" {
+			utils.PrintLog("error", utils.LogLine{Error: "missing 'target' for the output of the report", Message: "rules", Rule: rule.Name})
+			valid = false
+		}
+	}
 	if !priorityCheckRegex.MatchString(rule.Match.Priority) {
 		utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("incorrect priority '%v'", rule.Match.Priority), Message: "rules", Rule: rule.Name})
 		valid = false
@@ -463,7 +699,7 @@ func (rule *Rule) setPriorityNumberComparator() error {
 		return nil
 	}
 	rule.Match.PriorityComparator = priorityComparatorRegex.FindAllString(rule.Match.Priority, -1)[0]
-	rule.Match.PriorityNumber = getPriorityNumber(priorityComparatorRegex.ReplaceAllString(rule.Match.Priority, ""))
+	rule.Match.PriorityNumber = GetPriorityNumber(priorityComparatorRegex.ReplaceAllString(rule.Match.Priority, ""))
 	return nil
 }
 
@@ -483,6 +719,51 @@ func (rule *Rule) GetNotifiers() []string {
 	return rule.Notifiers
 }
 
+// GetTenant returns the tenant of the rule, empty for the rules of the main rule files
+func (rule *Rule) GetTenant() string {
+	return rule.Tenant
+}
+
+// GetReport returns the settings of the report, nil if the rule has no report
+func (rule *Rule) GetReport() *Report {
+	return rule.Report
+}
+
+// GetCluster returns the cluster targeted by the actions of the rule, empty to use the cluster of the event
+// IsScheduled returns true if the rule is run periodically, it isn't triggered by the events
+func (rule *Rule) IsScheduled() bool {
+	return rule.Schedule != ""
+}
+
+// IsDue returns true if the rule is scheduled for the minute of the time
+func (rule *Rule) IsDue(t time.Time) bool {
+	return rule.schedule != nil && rule.schedule.Matches(t)
+}
+
+func (rule *Rule) GetCluster() string {
+	return rule.Cluster
+}
+
+// GetImpersonatedUser returns the user impersonated by the Kubernetes actions of the rule,
+// the username of the service account if set, empty to use the identity of Falco Talon
+func (rule *Rule) GetImpersonatedUser() string {
+	if rule.Impersonate == nil {
+		return ""
+	}
+	if rule.Impersonate.ServiceAccount != "" {
+		return "system:serviceaccount:" + strings.Replace(rule.Impersonate.ServiceAccount, "/", ":", 1)
+	}
+	return rule.Impersonate.User
+}
+
+// GetImpersonatedGroups returns the groups impersonated by the Kubernetes actions of the rule
+func (rule *Rule) GetImpersonatedGroups() []string {
+	if rule.Impersonate == nil {
+		return nil
+	}
+	return rule.Impersonate.Groups
+}
+
 func (action *Action) GetName() string {
 	return action.Name
 }
@@ -507,6 +788,33 @@ func (action *Action) GetAdditionalContexts() []string {
 	return action.AdditionalContexts
 }
 
+// GetRevertAfter returns the delay after which the action is reverted, 0 if it's not
+func (action *Action) GetRevertAfter() time.Duration {
+	d, _ := time.ParseDuration(action.RevertAfter)
+	return d
+}
+
+// MustVerify returns true if the effect of the action is verified after its run, the default value is used if not set
+func (action *Action) MustVerify(defaultValue bool) bool {
+	if action.Verify == "" {
+		return defaultValue
+	}
+	return action.Verify == trueStr
+}
+
+// GetTargets returns the selection of the targeted pods, nil if the action targets the pod of the event
+func (action *Action) GetTargets() *Targets {
+	return action.Targets
+}
+
+// GetMaxObjects returns the maximum number of pods the action can target, the action fails if more pods are selected
+func (targets *Targets) GetMaxObjects() int {
+	if targets.MaxObjects == 0 {
+		return defaultMaxObjects
+	}
+	return targets.MaxObjects
+}
+
 func (action *Action) GetOutput() *Output {
 	if action.Output.Target == "" {
 		return nil
@@ -523,6 +831,9 @@ func (output *Output) GetParameters() map[string]interface{} {
 }
 
 func (rule *Rule) CompareRule(event *events.Event) bool {
+	if !rule.IsEnabled() || rule.IsScheduled() {
+		return false
+	}
 	if !rule.compareRules(event) {
 		return false
 	}
@@ -614,23 +925,23 @@ func (rule *Rule) comparePriority(event *events.Event) bool {
 	}
 	switch rule.Match.PriorityComparator {
 	case ">":
-		if getPriorityNumber(event.Priority) > rule.Match.PriorityNumber {
+		if GetPriorityNumber(event.Priority) > rule.Match.PriorityNumber {
 			return true
 		}
 	case ">=":
-		if getPriorityNumber(event.Priority) >= rule.Match.PriorityNumber {
+		if GetPriorityNumber(event.Priority) >= rule.Match.PriorityNumber {
 			return true
 		}
 	case "<":
-		if getPriorityNumber(event.Priority) < rule.Match.PriorityNumber {
+		if GetPriorityNumber(event.Priority) < rule.Match.PriorityNumber {
 			return true
 		}
 	case "<=":
-		if getPriorityNumber(event.Priority) <= rule.Match.PriorityNumber {
+		if GetPriorityNumber(event.Priority) <= rule.Match.PriorityNumber {
 			return true
 		}
 	default:
-		if getPriorityNumber(event.Priority) == rule.Match.PriorityNumber {
+		if GetPriorityNumber(event.Priority) == rule.Match.PriorityNumber {
 			return true
 		}
 	}
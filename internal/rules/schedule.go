@@ -0,0 +1,124 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron expression with 5 fields (minute, hour, day of month, month, day of week), evaluated in UTC
+type Schedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	anyDay   bool // the day of month starts with '*'
+	anyWeek  bool // the day of week starts with '*'
+}
+
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses a cron expression, the lists (1,2), the ranges (1-5), the steps (*/10) and the
+// descriptors (@hourly, @daily, ...) are supported
+func ParseSchedule(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if d, ok := scheduleDescriptors[expression]; ok {
+		expression = d
+	}
+	f := strings.Fields(expression)
+	if len(f) != 5 {
+		return nil, errors.New("a cron expression must have 5 fields (minute hour day-of-month month day-of-week)")
+	}
+
+	var (
+		s   Schedule
+		err error
+	)
+	if s.minutes, err = parseScheduleField(f[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("minute: %v", err)
+	}
+	if s.hours, err = parseScheduleField(f[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("hour: %v", err)
+	}
+	if s.days, err = parseScheduleField(f[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("day of month: %v", err)
+	}
+	if s.months, err = parseScheduleField(f[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("month: %v", err)
+	}
+	// 7 is sunday too
+	if s.weekdays, err = parseScheduleField(f[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("day of week: %v", err)
+	}
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+	s.anyDay = strings.HasPrefix(f[2], "*")
+	s.anyWeek = strings.HasPrefix(f[4], "*")
+	return &s, nil
+}
+
+// parseScheduleField returns the bitmask of the values of the field
+func parseScheduleField(field string, minValue, maxValue int) (uint64, error) {
+	var mask uint64
+	for _, i := range strings.Split(field, ",") {
+		step := 1
+		if r, s, ok := strings.Cut(i, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("wrong step '%v'", s)
+			}
+			i, step = r, n
+		}
+		start, end := minValue, maxValue
+		if i != "*" {
+			a, b, isRange := strings.Cut(i, "-")
+			var err error
+			if start, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("wrong value '%v'", a)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("wrong value '%v'", b)
+				}
+			} else if step != 1 {
+				// 5/10 is 5-max/10
+				end = maxValue
+			}
+		}
+		if start < minValue || end > maxValue || start > end {
+			return 0, fmt.Errorf("'%v' is out of the range %v-%v", i, minValue, maxValue)
+		}
+		for j := start; j <= end; j += step {
+			mask |= 1 << uint(j)
+		}
+	}
+	return mask, nil
+}
+
+// Matches returns true if the minute of the time is scheduled, when both the day of month and the day of week
+// are set, one of them is enough, like cron
+func (s *Schedule) Matches(t time.Time) bool {
+	t = t.UTC()
+	if s.minutes&(1<<uint(t.Minute())) == 0 || s.hours&(1<<uint(t.Hour())) == 0 || s.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeek {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	aws "github.com/falco-talon/falco-talon/internal/aws/client"
+)
+
+// AWSSecretsManager reads the secrets of AWS Secrets Manager, by name or ARN: ${awssm:<name>#<key>}
+type AWSSecretsManager struct {
+	client          *secretsmanager.Client
+	refreshInterval time.Duration
+}
+
+// AWSSSM reads the parameters of AWS SSM Parameter Store, the SecureString are decrypted: ${ssm:<name>}
+type AWSSSM struct {
+	client          *ssm.Client
+	refreshInterval time.Duration
+}
+
+func NewAWSSecretsManager(settings configuration.SecretsProviderConfig) (*AWSSecretsManager, error) {
+	if err := aws.Init(); err != nil {
+		return nil, err
+	}
+	client := aws.GetSecretsManagerClient()
+	if client == nil {
+		return nil, errors.New("client error")
+	}
+	return &AWSSecretsManager{client: client, refreshInterval: time.Duration(settings.RefreshIntervalSeconds) * time.Second}, nil
+}
+
+func NewAWSSSM(settings configuration.SecretsProviderConfig) (*AWSSSM, error) {
+	if err := aws.Init(); err != nil {
+		return nil, err
+	}
+	client := aws.GetSSMClient()
+	if client == nil {
+		return nil, errors.New("client error")
+	}
+	return &AWSSSM{client: client, refreshInterval: time.Duration(settings.RefreshIntervalSeconds) * time.Second}, nil
+}
+
+func (p *AWSSecretsManager) Name() string {
+	return "awssm"
+}
+
+func (p *AWSSecretsManager) Read(path string) (*Secret, error) {
+	output, err := p.client.GetSecretValue(context.Background(), &secretsmanager.GetSecretValueInput{SecretId: awssdk.String(path)}, func(o *secretsmanager.Options) {
+		if region := arnRegion(path); region != "" {
+			o.Region = region
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newSecret(awssdk.ToString(output.SecretString), p.refreshInterval), nil
+}
+
+func (p *AWSSecretsManager) Renew(_ string) (time.Duration, error) {
+	return 0, errNoLease
+}
+
+func (p *AWSSSM) Name() string {
+	return "ssm"
+}
+
+func (p *AWSSSM) Read(path string) (*Secret, error) {
+	output, err := p.client.GetParameter(context.Background(), &ssm.GetParameterInput{Name: awssdk.String(path), WithDecryption: awssdk.Bool(true)}, func(o *ssm.Options) {
+		if region := arnRegion(path); region != "" {
+			o.Region = region
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.Parameter == nil {
+		return nil, errors.New("empty parameter")
+	}
+	return newSecret(awssdk.ToString(output.Parameter.Value), p.refreshInterval), nil
+}
+
+func (p *AWSSSM) Renew(_ string) (time.Duration, error) {
+	return 0, errNoLease
+}
+
+// arnRegion returns the region of an ARN (arn:aws:secretsmanager:<region>:...), the default region is used otherwise
+func arnRegion(path string) string {
+	if s := strings.Split(path, ":"); len(s) > 3 && s[0] == "arn" {
+		return s[3]
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	azure "github.com/falco-talon/falco-talon/internal/azure/client"
+)
+
+const (
+	azureKeyVaultResource   string = "https://vault.azure.net"
+	azureKeyVaultAPIVersion string = "7.4"
+)
+
+type getSecretResponse struct {
+	Value string `json:"value"`
+}
+
+// AzureKeyVault reads the secrets of Azure Key Vault, the latest version if it's not specified:
+// ${azurekv:<vault>/<secret>} or ${azurekv:<vault>/<secret>/<version>}
+type AzureKeyVault struct {
+	client          *azure.AzureClient
+	httpClient      *http.Client
+	refreshInterval time.Duration
+}
+
+func NewAzureKeyVault(settings configuration.SecretsProviderConfig) (*AzureKeyVault, error) {
+	if err := azure.Init(); err != nil {
+		return nil, err
+	}
+	client := azure.GetAzureClient()
+	if client == nil {
+		return nil, errors.New("client error")
+	}
+	return &AzureKeyVault{
+		client:          client,
+		httpClient:      &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: time.Duration(settings.RefreshIntervalSeconds) * time.Second,
+	}, nil
+}
+
+func (p *AzureKeyVault) Name() string {
+	return "azurekv"
+}
+
+func (p *AzureKeyVault) Read(path string) (*Secret, error) {
+	s := strings.Split(strings.Trim(path, "/"), "/")
+	if len(s) != 2 && len(s) != 3 {
+		return nil, fmt.Errorf("wrong name '%v', must be '<vault>/<secret>' or '<vault>/<secret>/<version>'", path)
+	}
+	u := fmt.Sprintf("https://%v.vault.azure.net/secrets/%v", s[0], strings.Join(s[1:], "/"))
+
+	token, err := p.client.GetToken(azureKeyVaultResource)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, u+"?api-version="+azureKeyVaultAPIVersion, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v: %v", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var output getSecretResponse
+	if err := json.Unmarshal(b, &output); err != nil {
+		return nil, err
+	}
+	return newSecret(output.Value, p.refreshInterval), nil
+}
+
+func (p *AzureKeyVault) Renew(_ string) (time.Duration, error) {
+	return 0, errNoLease
+}
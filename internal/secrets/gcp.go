@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	gcp "github.com/falco-talon/falco-talon/internal/gcp/client"
+)
+
+const (
+	gcpSecretManagerURL   string = "https://secretmanager.googleapis.com/v1/"
+	gcpSecretManagerScope string = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+type accessSecretVersionResponse struct {
+	Payload struct {
+		Data string `json:"data"`
+	} `json:"payload"`
+}
+
+// GCPSecretManager reads the secrets of GCP Secret Manager, the latest version if it's not specified:
+// ${gcpsm:<project>/<secret>} or ${gcpsm:projects/<project>/secrets/<secret>/versions/<version>}
+type GCPSecretManager struct {
+	client          *gcp.GCPClient
+	httpClient      *http.Client
+	refreshInterval time.Duration
+}
+
+func NewGCPSecretManager(settings configuration.SecretsProviderConfig) (*GCPSecretManager, error) {
+	if err := gcp.Init(); err != nil {
+		return nil, err
+	}
+	client := gcp.GetGCPClient()
+	if client == nil {
+		return nil, errors.New("client error")
+	}
+	return &GCPSecretManager{
+		client:          client,
+		httpClient:      &http.Client{Timeout: 10 * time.Second},
+		refreshInterval: time.Duration(settings.RefreshIntervalSeconds) * time.Second,
+	}, nil
+}
+
+func (p *GCPSecretManager) Name() string {
+	return "gcpsm"
+}
+
+func (p *GCPSecretManager) Read(path string) (*Secret, error) {
+	name := strings.Trim(path, "/")
+	if !strings.HasPrefix(name, "projects/") {
+		s := strings.Split(name, "/")
+		if len(s) != 2 {
+			return nil, fmt.Errorf("wrong name '%v', must be '<project>/<secret>' or 'projects/<project>/secrets/<secret>'", path)
+		}
+		name = fmt.Sprintf("projects/%v/secrets/%v", s[0], s[1])
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	token, err := p.client.GetToken(gcpSecretManagerScope)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, gcpSecretManagerURL+name+":access", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v: %v", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var output accessSecretVersionResponse
+	if err := json.Unmarshal(b, &output); err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(output.Payload.Data)
+	if err != nil {
+		return nil, err
+	}
+	return newSecret(string(data), p.refreshInterval), nil
+}
+
+func (p *GCPSecretManager) Renew(_ string) (time.Duration, error) {
+	return 0, errNoLease
+}
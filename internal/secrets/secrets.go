@@ -0,0 +1,326 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// Secret is a secret read from a provider, the dynamic secrets have a lease, the others are read again
+// after the refresh interval (the default one if it's 0)
+type Secret struct {
+	Data            map[string]string
+	LeaseID         string
+	LeaseDuration   time.Duration
+	RefreshInterval time.Duration
+	Renewable       bool
+}
+
+// Provider reads the secrets from a backend
+type Provider interface {
+	Name() string
+	Read(path string) (*Secret, error)
+	// Renew extends the lease of a dynamic secret and returns its new duration
+	Renew(leaseID string) (time.Duration, error)
+}
+
+// ${<provider>:<path>#<key>}, eg: ${vault:secret/data/slack#webhook_url}, the key is optional for the
+// secrets with a single value (ssm:/falco-talon/token)
+var regRef = regexp.MustCompile(`\$\{([a-z]+):([^#}]+)(?:#([^}]+))?\}`)
+
+// the secrets are checked at this interval, to renew the leases in time
+const checkInterval = 10 * time.Second
+
+var errNoLease = errors.New("the secrets of this provider have no lease")
+
+// reference is a setting with references to secrets, set is called with its resolved value
+type reference struct {
+	set      func(string)
+	template string
+}
+
+type cachedSecret struct {
+	secret   *Secret
+	provider Provider
+	path     string
+	deadline time.Time // the secret is renewed or read again after this time
+}
+
+type resolver struct {
+	providers       map[string]Provider
+	secrets         map[string]*cachedSecret
+	refs            []*reference
+	onChange        []func()
+	refreshInterval time.Duration
+	watching        bool
+	mu              sync.Mutex
+}
+
+var r *resolver
+
+// Init replaces the references to secrets of the configuration by their values and starts their renewal
+func Init(config *configuration.Configuration, providers ...Provider) error {
+	r = &resolver{
+		providers:       make(map[string]Provider),
+		secrets:         make(map[string]*cachedSecret),
+		refreshInterval: time.Duration(config.Secrets.RefreshIntervalSeconds) * time.Second,
+	}
+	for _, i := range providers {
+		r.providers[i.Name()] = i
+	}
+
+	r.walk(reflect.ValueOf(config), nil)
+	if len(r.refs) == 0 {
+		return nil
+	}
+
+	for _, i := range r.refs {
+		for _, m := range regRef.FindAllStringSubmatch(i.template, -1) {
+			if err := r.read(m[1], m[2]); err != nil {
+				return err
+			}
+		}
+	}
+	if err := r.resolve(r.refs); err != nil {
+		return err
+	}
+	utils.PrintLog("info", utils.LogLine{Message: "secrets", Result: fmt.Sprintf("%v secret(s) resolved in %v setting(s)", len(r.secrets), len(r.refs))})
+
+	r.watching = true
+	go r.watch()
+	return nil
+}
+
+// Reload resolves the references to secrets of a new configuration, the returned function replaces the
+// watched references by the new ones, once the configuration is applied
+func Reload(config *configuration.Configuration) (func(), error) {
+	if r == nil {
+		return nil, errors.New("the secrets are not initialized")
+	}
+	n := new(resolver)
+	n.walk(reflect.ValueOf(config), nil)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, i := range n.refs {
+		for _, m := range regRef.FindAllStringSubmatch(i.template, -1) {
+			if err := r.read(m[1], m[2]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := r.resolve(n.refs); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.refs = n.refs
+		// the secrets which are no longer referenced are not read again
+		used := make(map[string]bool)
+		for _, i := range r.refs {
+			for _, m := range regRef.FindAllStringSubmatch(i.template, -1) {
+				used[m[1]+":"+m[2]] = true
+			}
+		}
+		for i := range r.secrets {
+			if !used[i] {
+				delete(r.secrets, i)
+			}
+		}
+		if len(r.refs) != 0 && !r.watching {
+			r.watching = true
+			go r.watch()
+		}
+	}, nil
+}
+
+// OnChange registers a function called when the values of the secrets change
+func OnChange(f func()) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, f)
+}
+
+// walk collects the strings with references, set replaces the value in its map or its interface
+func (r *resolver) walk(v reflect.Value, set func(string)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			r.walk(v.Elem(), nil)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if set == nil && v.CanSet() {
+			set = func(s string) { v.Set(reflect.ValueOf(s)) }
+		}
+		r.walk(v.Elem(), set)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				r.walk(v.Field(i), nil)
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			k := k
+			r.walk(v.MapIndex(k), func(s string) { v.SetMapIndex(k, reflect.ValueOf(s).Convert(v.Type().Elem())) })
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			r.walk(v.Index(i), nil)
+		}
+	case reflect.String:
+		if !regRef.MatchString(v.String()) {
+			return
+		}
+		if set == nil {
+			if !v.CanSet() {
+				return
+			}
+			set = v.SetString
+		}
+		r.refs = append(r.refs, &reference{template: v.String(), set: set})
+	}
+}
+
+// newSecret returns a secret with a single value, the fields of a JSON object are its keys
+func newSecret(value string, refreshInterval time.Duration) *Secret {
+	secret := &Secret{Data: map[string]string{"": value}, RefreshInterval: refreshInterval}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return secret
+	}
+	for i, j := range fields {
+		if s, ok := j.(string); ok {
+			secret.Data[i] = s
+			continue
+		}
+		b, _ := json.Marshal(j)
+		secret.Data[i] = string(b)
+	}
+	return secret
+}
+
+// read reads a secret from its provider, for the first time or after the end of its lease
+func (r *resolver) read(provider, path string) error {
+	key := provider + ":" + path
+	if _, ok := r.secrets[key]; ok {
+		return nil
+	}
+	p := r.providers[provider]
+	if p == nil {
+		return fmt.Errorf("unknown secrets provider '%v', is it enabled?", provider)
+	}
+	secret, err := p.Read(path)
+	if err != nil {
+		return fmt.Errorf("can't read the secret '%v': %v", key, err)
+	}
+	r.secrets[key] = &cachedSecret{secret: secret, provider: p, path: path, deadline: r.deadline(secret)}
+	return nil
+}
+
+// deadline returns the time to renew the lease, at 2/3 of its duration, or to read again the secret
+func (r *resolver) deadline(secret *Secret) time.Time {
+	if secret.LeaseDuration > 0 {
+		return time.Now().Add(secret.LeaseDuration * 2 / 3)
+	}
+	if secret.RefreshInterval > 0 {
+		return time.Now().Add(secret.RefreshInterval)
+	}
+	return time.Now().Add(r.refreshInterval)
+}
+
+// resolve sets the settings with the values of the secrets
+func (r *resolver) resolve(refs []*reference) error {
+	for _, i := range refs {
+		var err error
+		value := regRef.ReplaceAllStringFunc(i.template, func(m string) string {
+			sub := regRef.FindStringSubmatch(m)
+			c := r.secrets[sub[1]+":"+sub[2]]
+			if c == nil {
+				err = fmt.Errorf("unknown secret '%v:%v'", sub[1], sub[2])
+				return ""
+			}
+			v, ok := c.secret.Data[sub[3]]
+			switch {
+			case !ok && sub[3] == "":
+				err = fmt.Errorf("a key is required for the secret '%v:%v'", sub[1], sub[2])
+			case !ok:
+				err = fmt.Errorf("unknown key '%v' in the secret '%v:%v'", sub[3], sub[1], sub[2])
+			}
+			return v
+		})
+		if err != nil {
+			return err
+		}
+		i.set(value)
+	}
+	return nil
+}
+
+// watch renews the leases of the dynamic secrets or reads again the secrets, the settings are updated
+// and the registered functions are called if a value changed
+func (r *resolver) watch() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.mu.Lock()
+		changed := false
+		for key, c := range r.secrets {
+			if time.Now().Before(c.deadline) {
+				continue
+			}
+			if c.secret.Renewable && c.secret.LeaseID != "" {
+				d, err := c.provider.Renew(c.secret.LeaseID)
+				// the lease can't be extended beyond its max TTL, new credentials are read instead
+				if err == nil && d >= c.secret.LeaseDuration {
+					c.deadline = time.Now().Add(d * 2 / 3)
+					continue
+				}
+				if err != nil {
+					utils.PrintLog("warning", utils.LogLine{Message: "secrets", Error: err.Error(), Result: fmt.Sprintf("can't renew the lease of the secret '%v'", key)})
+				}
+			}
+			secret, err := c.provider.Read(c.path)
+			if err != nil {
+				// retried at the next check, the current value is kept until the end of its lease
+				utils.PrintLog("error", utils.LogLine{Message: "secrets", Error: err.Error(), Result: fmt.Sprintf("can't read the secret '%v'", key)})
+				continue
+			}
+			if !reflect.DeepEqual(secret.Data, c.secret.Data) {
+				changed = true
+				utils.PrintLog("info", utils.LogLine{Message: "secrets", Result: fmt.Sprintf("the secret '%v' has changed", key)})
+			}
+			c.secret = secret
+			c.deadline = r.deadline(secret)
+		}
+		if !changed {
+			r.mu.Unlock()
+			continue
+		}
+		if err := r.resolve(r.refs); err != nil {
+			utils.PrintLog("error", utils.LogLine{Message: "secrets", Error: err.Error()})
+			r.mu.Unlock()
+			continue
+		}
+		onChange := r.onChange
+		r.mu.Unlock()
+		for _, f := range onChange {
+			f()
+		}
+	}
+}
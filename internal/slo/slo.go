@@ -0,0 +1,141 @@
+package slo
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RuleStats are the statistics of the automated response of a rule since the start, for the MTTR reports
+type RuleStats struct {
+	LastMatch    *time.Time `json:"last_match,omitempty"`
+	Rule         string     `json:"rule"`
+	Matches      int64      `json:"matches"`
+	Actions      int64      `json:"actions"`
+	Successes    int64      `json:"successes"`
+	Failures     int64      `json:"failures"`
+	Remediations int64      `json:"remediations"` // the successful actions with a verified or unchecked effect
+	SuccessRatio float64    `json:"success_ratio"`
+	MTTRSeconds  float64    `json:"mttr_seconds"` // mean time from the event to the remediation
+	P50Seconds   float64    `json:"p50_seconds"`
+	P95Seconds   float64    `json:"p95_seconds"`
+}
+
+// the percentiles are computed over the most recent remediations
+const maxSamples int = 1000
+
+type ruleData struct {
+	lastMatch    time.Time
+	samples      []float64
+	matches      int64
+	actions      int64
+	successes    int64
+	failures     int64
+	remediations int64
+	total        float64 // sum of the times to remediate, for the mean
+	next         int     // index of the oldest sample once the buffer is full
+}
+
+var (
+	data = make(map[string]*ruleData)
+	mu   sync.Mutex
+)
+
+func get(rule string) *ruleData {
+	d, ok := data[rule]
+	if !ok {
+		d = &ruleData{}
+		data[rule] = d
+	}
+	return d
+}
+
+// RecordMatch counts a match of the rule by an event
+func RecordMatch(rule string) {
+	mu.Lock()
+	defer mu.Unlock()
+	d := get(rule)
+	d.matches++
+	d.lastMatch = time.Now().UTC()
+}
+
+// RecordAction counts an action of the rule, the time to remediate is recorded for the remediations only
+func RecordAction(rule string, success, failure, remediated bool, timeToRemediate time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	d := get(rule)
+	d.actions++
+	if success {
+		d.successes++
+	}
+	if failure {
+		d.failures++
+	}
+	if !remediated {
+		return
+	}
+	s := timeToRemediate.Seconds()
+	d.remediations++
+	d.total += s
+	if len(d.samples) < maxSamples {
+		d.samples = append(d.samples, s)
+		return
+	}
+	d.samples[d.next] = s
+	d.next = (d.next + 1) % maxSamples
+}
+
+// List returns the statistics of the rules, sorted by name
+func List() []RuleStats {
+	mu.Lock()
+	defer mu.Unlock()
+	list := make([]RuleStats, 0, len(data))
+	for i, j := range data {
+		list = append(list, j.stats(i))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Rule < list[j].Rule })
+	return list
+}
+
+// stats returns the statistics of the rule, the lock must be held
+func (d *ruleData) stats(rule string) RuleStats {
+	s := RuleStats{
+		Rule:         rule,
+		Matches:      d.matches,
+		Actions:      d.actions,
+		Successes:    d.successes,
+		Failures:     d.failures,
+		Remediations: d.remediations,
+	}
+	if !d.lastMatch.IsZero() {
+		t := d.lastMatch
+		s.LastMatch = &t
+	}
+	if n := d.successes + d.failures; n != 0 {
+		s.SuccessRatio = float64(d.successes) / float64(n)
+	}
+	if d.remediations != 0 {
+		s.MTTRSeconds = d.total / float64(d.remediations)
+	}
+	if len(d.samples) != 0 {
+		sorted := make([]float64, len(d.samples))
+		copy(sorted, d.samples)
+		sort.Float64s(sorted)
+		s.P50Seconds = percentile(sorted, 0.50)
+		s.P95Seconds = percentile(sorted, 0.95)
+	}
+	return s
+}
+
+// percentile returns the nearest-rank percentile of the sorted values
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
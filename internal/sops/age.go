@@ -0,0 +1,62 @@
+package sops
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// the data keys of sops are encrypted for the age recipients, only the X25519 recipients are supported
+
+var errNoAgeIdentity = errors.New("no age identity matches the recipients of the file")
+
+// getAgeIdentities returns the identities of $SOPS_AGE_KEY, $SOPS_AGE_KEY_FILE or of the default file of sops
+func getAgeIdentities() ([]age.Identity, error) {
+	keys := os.Getenv("SOPS_AGE_KEY")
+	if keys == "" {
+		file := os.Getenv("SOPS_AGE_KEY_FILE")
+		if file == "" {
+			dir, err := os.UserConfigDir()
+			if err != nil {
+				return nil, err
+			}
+			file = filepath.Join(dir, "sops", "age", "keys.txt")
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("can't read the age identities: %v", err)
+		}
+		keys = string(b)
+	}
+
+	identities, err := age.ParseIdentities(strings.NewReader(keys))
+	if err != nil {
+		return nil, fmt.Errorf("wrong age identity: %v", err)
+	}
+	return identities, nil
+}
+
+// decryptAge decrypts an age file, armored or not
+func decryptAge(enc string, identities []age.Identity) ([]byte, error) {
+	enc = strings.TrimSpace(enc)
+	var r io.Reader = strings.NewReader(enc)
+	if strings.HasPrefix(enc, armor.Header) {
+		r = armor.NewReader(r)
+	}
+
+	d, err := age.Decrypt(r, identities...)
+	var e *age.NoIdentityMatchError
+	if errors.As(err, &e) {
+		return nil, errNoAgeIdentity
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(d)
+}
@@ -0,0 +1,50 @@
+package sops
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const kmsTimeout = 10 * time.Second
+
+// decryptKMS decrypts the data key with AWS KMS, with the default credentials of AWS (the files are decrypted
+// before the load of the configuration), the role of the key is assumed if it's set
+func decryptKMS(arn, role, enc string, encryptionContext map[string]string) ([]byte, error) {
+	s := strings.Split(arn, ":")
+	if len(s) < 6 || s[0] != "arn" {
+		return nil, errors.New("wrong arn")
+	}
+	blob, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(s[3]))
+	if err != nil {
+		return nil, err
+	}
+	if role != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), role))
+	}
+
+	output, err := kms.NewFromConfig(cfg).Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob:    blob,
+		EncryptionContext: encryptionContext,
+		KeyId:             aws.String(arn),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Plaintext, nil
+}
@@ -0,0 +1,383 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// the files are decrypted in memory, as `sops --decrypt` does, the data key is obtained with an age identity
+// or with AWS KMS, see https://github.com/getsops/sops
+
+const (
+	metadataKey string = "sops"
+	// key of the content of the files encrypted as binary (sops --input-type binary), for the top-level lists
+	binaryKey string = "data"
+)
+
+// the MAC of the files with `mac_only_encrypted` starts with these bytes, for it to differ from the MAC of all the values
+var macOnlyEncryptedInitialization = []byte{0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0x0b,
+	0x0b, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69}
+
+var regEncrypted = regexp.MustCompile(`^ENC\[AES256_GCM,data:([^,]*),iv:([^,]*),tag:([^,]*),type:([a-z]*)\]$`)
+
+type metadata struct {
+	KMS []struct {
+		Arn     string            `yaml:"arn"`
+		Enc     string            `yaml:"enc"`
+		Role    string            `yaml:"role"`
+		Context map[string]string `yaml:"context"`
+	} `yaml:"kms"`
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+		Enc       string `yaml:"enc"`
+	} `yaml:"age"`
+	KeyGroups         []interface{} `yaml:"key_groups"`
+	LastModified      string        `yaml:"lastmodified"`
+	MAC               string        `yaml:"mac"`
+	UnencryptedSuffix string        `yaml:"unencrypted_suffix"`
+	EncryptedSuffix   string        `yaml:"encrypted_suffix"`
+	UnencryptedRegex  string        `yaml:"unencrypted_regex"`
+	EncryptedRegex    string        `yaml:"encrypted_regex"`
+	MACOnlyEncrypted  bool          `yaml:"mac_only_encrypted"`
+}
+
+// decrypter decrypts the values of the tree and computes its MAC
+type decrypter struct {
+	metadata         *metadata
+	mac              hash.Hash
+	aead             cipher.AEAD
+	unencryptedRegex *regexp.Regexp
+	encryptedRegex   *regexp.Regexp
+}
+
+// IsEncrypted returns true if the content is a file encrypted by sops, with its metadata in the `sops` key
+func IsEncrypted(b []byte) bool {
+	if !bytes.Contains(b, []byte(metadataKey)) {
+		return false
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return false
+	}
+	_, m := splitMetadata(&doc)
+	return m != nil
+}
+
+// Decrypt returns the decrypted content of a file encrypted by sops, as YAML, or the raw content of the
+// files encrypted as binary
+func Decrypt(b []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	root, node := splitMetadata(&doc)
+	if node == nil {
+		return nil, errors.New("no sops metadata")
+	}
+	m := new(metadata)
+	if err := node.Decode(m); err != nil {
+		return nil, fmt.Errorf("wrong sops metadata: %v", err)
+	}
+	if len(m.KeyGroups) != 0 {
+		return nil, errors.New("the key groups are not supported")
+	}
+
+	key, err := getDataKey(m)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decrypter{metadata: m, mac: sha512.New(), aead: aead}
+	if m.MACOnlyEncrypted {
+		d.mac.Write(macOnlyEncryptedInitialization)
+	}
+	if m.UnencryptedRegex != "" {
+		if d.unencryptedRegex, err = regexp.Compile(m.UnencryptedRegex); err != nil {
+			return nil, err
+		}
+	}
+	if m.EncryptedRegex != "" {
+		if d.encryptedRegex, err = regexp.Compile(m.EncryptedRegex); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.walk(root, nil); err != nil {
+		return nil, err
+	}
+	if err := d.checkMAC(); err != nil {
+		return nil, err
+	}
+
+	// the files encrypted as binary have a single `data` key with the content
+	if len(root.Content) == 2 && root.Content[0].Value == binaryKey && root.Content[1].Kind == yaml.ScalarNode {
+		return []byte(root.Content[1].Value), nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// splitMetadata removes the `sops` key of the root mapping and returns the mapping and the metadata
+func splitMetadata(doc *yaml.Node) (*yaml.Node, *yaml.Node) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != metadataKey || root.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		m := root.Content[i+1]
+		for j := 0; j+1 < len(m.Content); j += 2 {
+			if m.Content[j].Value == "mac" {
+				root.Content = append(root.Content[:i:i], root.Content[i+2:]...)
+				return root, m
+			}
+		}
+	}
+	return nil, nil
+}
+
+// getDataKey decrypts the data key with the age identities, or with AWS KMS
+func getDataKey(m *metadata) ([]byte, error) {
+	errs := make([]string, 0)
+	if len(m.Age) != 0 {
+		identities, err := getAgeIdentities()
+		if err != nil {
+			errs = append(errs, "age: "+err.Error())
+		} else {
+			matched := false
+			for _, i := range m.Age {
+				key, err := decryptAge(i.Enc, identities)
+				if err == nil {
+					return key, nil
+				}
+				if !errors.Is(err, errNoAgeIdentity) {
+					matched = true
+					errs = append(errs, fmt.Sprintf("age '%v': %v", i.Recipient, err))
+				}
+			}
+			if !matched {
+				errs = append(errs, "age: "+errNoAgeIdentity.Error())
+			}
+		}
+	}
+	for _, i := range m.KMS {
+		key, err := decryptKMS(i.Arn, i.Role, i.Enc, i.Context)
+		if err == nil {
+			return key, nil
+		}
+		errs = append(errs, fmt.Sprintf("kms '%v': %v", i.Arn, err))
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no age or kms key to decrypt the data key")
+	}
+	return nil, fmt.Errorf("can't decrypt the data key: %v", strings.Join(errs, ", "))
+}
+
+// walk decrypts the values, the path of a value (its keys) is the additional data of its encryption,
+// the items of the lists have the path of their list
+func (d *decrypter) walk(node *yaml.Node, path []string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			p := append(path[:len(path):len(path)], node.Content[i].Value)
+			if err := d.walk(node.Content[i+1], p); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, i := range node.Content {
+			if err := d.walk(i, path); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		return d.decryptScalar(node, path)
+	}
+	return nil
+}
+
+func (d *decrypter) decryptScalar(node *yaml.Node, path []string) error {
+	encrypted := d.isEncrypted(path)
+	if !encrypted {
+		if !d.metadata.MACOnlyEncrypted {
+			var v interface{}
+			if err := node.Decode(&v); err != nil {
+				return err
+			}
+			d.mac.Write(toBytes(v))
+		}
+		return nil
+	}
+
+	value, typ, err := d.decrypt(node.Value, strings.Join(path, ":")+":")
+	if err != nil {
+		return fmt.Errorf("can't decrypt '%v': %v", strings.Join(path, "."), err)
+	}
+	var v interface{} = value
+	node.Style = 0
+	switch typ {
+	case "int":
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		v, node.Tag = i, "!!int"
+	case "float":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v, node.Tag = f, "!!float"
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v, node.Tag, value = b, "!!bool", strconv.FormatBool(b)
+	default:
+		node.Tag = "!!str"
+	}
+	node.Value = value
+	d.mac.Write(toBytes(v))
+	return nil
+}
+
+// isEncrypted returns if the value is encrypted, with the suffixes and the regexes of the metadata
+func (d *decrypter) isEncrypted(path []string) bool {
+	m := d.metadata
+	encrypted := true
+	if m.UnencryptedSuffix != "" {
+		for _, i := range path {
+			if strings.HasSuffix(i, m.UnencryptedSuffix) {
+				encrypted = false
+				break
+			}
+		}
+	}
+	if m.EncryptedSuffix != "" {
+		encrypted = false
+		for _, i := range path {
+			if strings.HasSuffix(i, m.EncryptedSuffix) {
+				encrypted = true
+				break
+			}
+		}
+	}
+	if d.unencryptedRegex != nil {
+		for _, i := range path {
+			if d.unencryptedRegex.MatchString(i) {
+				encrypted = false
+				break
+			}
+		}
+	}
+	if d.encryptedRegex != nil {
+		encrypted = false
+		for _, i := range path {
+			if d.encryptedRegex.MatchString(i) {
+				encrypted = true
+				break
+			}
+		}
+	}
+	return encrypted
+}
+
+// decrypt returns the plaintext and the type of an encrypted value, the empty values are not encrypted
+func (d *decrypter) decrypt(value, additionalData string) (string, string, error) {
+	if value == "" {
+		return "", "str", nil
+	}
+	s := regEncrypted.FindStringSubmatch(value)
+	if s == nil {
+		return "", "", errors.New("wrong format of the encrypted value")
+	}
+	data, err := base64.StdEncoding.DecodeString(s[1])
+	if err != nil {
+		return "", "", err
+	}
+	iv, err := base64.StdEncoding.DecodeString(s[2])
+	if err != nil {
+		return "", "", err
+	}
+	tag, err := base64.StdEncoding.DecodeString(s[3])
+	if err != nil {
+		return "", "", err
+	}
+	if len(iv) != d.aead.NonceSize() {
+		return "", "", errors.New("wrong iv of the encrypted value")
+	}
+	plaintext, err := d.aead.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return "", "", errors.New("the value or its path has been modified")
+	}
+	return string(plaintext), s[4], nil
+}
+
+// checkMAC compares the MAC of the values with the one of the metadata, encrypted with the date of the
+// last modification
+func (d *decrypter) checkMAC() error {
+	lastModified, err := time.Parse(time.RFC3339, d.metadata.LastModified)
+	if err != nil {
+		return errors.New("wrong `lastmodified` in the sops metadata")
+	}
+	mac, _, err := d.decrypt(d.metadata.MAC, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("can't decrypt the mac: %v", err)
+	}
+	if !strings.EqualFold(mac, fmt.Sprintf("%X", d.mac.Sum(nil))) {
+		return errors.New("the mac doesn't match, the file has been modified")
+	}
+	return nil
+}
+
+// toBytes returns the value as sops hashes it for the MAC
+func toBytes(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case int:
+		return []byte(strconv.Itoa(v))
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		if v {
+			return []byte("True")
+		}
+		return []byte("False")
+	case nil:
+		return nil
+	default:
+		return []byte(fmt.Sprintf("%v", v))
+	}
+}
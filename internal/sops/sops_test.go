@@ -0,0 +1,115 @@
+package sops
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// the values, the comments and the mac of testdata/secrets.enc.yaml were encrypted by sops 3.6.1 (the file
+// functional-tests/res/comments.enc.yaml of sops), its data key is encrypted by the age CLI for the identity
+// of testdata/keys.txt, instead of the pgp key of the tests of sops
+
+const otherIdentity string = "AGE-SECRET-KEY-1FHPVKR05JWM2Q3AX334G5MGEQGYJFHC0QKRRWA97DLK0YN3YX2QSD5HVR3"
+
+var regValue = regexp.MustCompile(`(?m)^(lorem|dolor): (.*)$`)
+
+func readFixture(t *testing.T) []byte {
+	t.Helper()
+	t.Setenv("SOPS_AGE_KEY", "")
+	t.Setenv("SOPS_AGE_KEY_FILE", "testdata/keys.txt")
+	b, err := os.ReadFile("testdata/secrets.enc.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecrypt(t *testing.T) {
+	b := readFixture(t)
+	if !IsEncrypted(b) {
+		t.Fatal("the fixture isn't detected as encrypted")
+	}
+
+	out, err := Decrypt(b)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(out, &values); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"lorem": "ipsum", "dolor": "sit"}
+	if len(values) != len(want) {
+		t.Fatalf("Decrypt() = %v, want %v", values, want)
+	}
+	for k, v := range want {
+		if values[k] != v {
+			t.Errorf("Decrypt() %v = %v, want %v", k, values[k], v)
+		}
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	if IsEncrypted([]byte("lorem: ipsum\nsops: true\n")) {
+		t.Error("IsEncrypted() = true for a file without sops metadata")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	b := readFixture(t)
+	values := make(map[string]string)
+	for _, i := range regValue.FindAllStringSubmatch(string(b), -1) {
+		values[i[1]] = i[2]
+	}
+
+	tests := []struct {
+		name    string
+		replace func(string) string
+		wantErr string
+	}{
+		{
+			// the empty values aren't encrypted, only the mac covers them
+			name:    "emptied value",
+			replace: func(s string) string { return strings.Replace(s, values["dolor"], `""`, 1) },
+			wantErr: "the mac doesn't match",
+		},
+		{
+			name: "swapped values",
+			replace: func(s string) string {
+				s = strings.Replace(s, values["lorem"], "LOREM", 1)
+				s = strings.Replace(s, values["dolor"], values["lorem"], 1)
+				return strings.Replace(s, "LOREM", values["dolor"], 1)
+			},
+			wantErr: "the value or its path has been modified",
+		},
+		{
+			name:    "modified ciphertext",
+			replace: func(s string) string { return strings.Replace(s, "data:IgvT,", "data:IgvU,", 1) },
+			wantErr: "the value or its path has been modified",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := tt.replace(string(b))
+			if tampered == string(b) {
+				t.Fatal("the fixture isn't modified")
+			}
+			_, err := Decrypt([]byte(tampered))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decrypt() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecryptWrongIdentity(t *testing.T) {
+	b := readFixture(t)
+	t.Setenv("SOPS_AGE_KEY", otherIdentity)
+	if _, err := Decrypt(b); err == nil || !strings.Contains(err.Error(), errNoAgeIdentity.Error()) {
+		t.Errorf("Decrypt() error = %v, want %q", err, errNoAgeIdentity)
+	}
+}
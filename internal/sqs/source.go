@@ -0,0 +1,112 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	aws "github.com/falco-talon/falco-talon/internal/aws/client"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/handler"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// notification is the envelope of the messages of a SNS topic subscribed by the queue
+type notification struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+const maxBackoff = 30 * time.Second
+
+var regRegion = regexp.MustCompile(`^https://sqs\.([a-z0-9-]+)\.amazonaws\.com/`)
+
+// StartSource polls the queue with long polling, a message is deleted once its event is published,
+// it's received again after the visibility timeout otherwise
+func StartSource() error {
+	config := configuration.GetConfiguration().SQS
+	if config.QueueURL == "" {
+		return errors.New("wrong `queue_url` setting")
+	}
+	if config.MaxMessages < 1 || config.MaxMessages > 10 {
+		return errors.New("wrong `max_messages` setting, must be between 1 and 10")
+	}
+	if config.WaitTimeSeconds < 0 || config.WaitTimeSeconds > 20 {
+		return errors.New("wrong `wait_time_seconds` setting, must be between 0 and 20")
+	}
+
+	region := config.Region
+	if s := regRegion.FindStringSubmatch(config.QueueURL); region == "" && len(s) == 2 {
+		region = s[1]
+	}
+
+	if err := aws.Init(); err != nil {
+		return err
+	}
+	client := aws.GetSQSClient()
+	if client == nil {
+		return errors.New("client error")
+	}
+	opts := func(o *sqs.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	}
+
+	go func() {
+		utils.PrintLog("info", utils.LogLine{Result: fmt.Sprintf("polling the queue '%v'", config.QueueURL), Message: "sqs"})
+		backoff := time.Second
+		for {
+			output, err := client.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{
+				QueueUrl:            awssdk.String(config.QueueURL),
+				MaxNumberOfMessages: int32(config.MaxMessages),
+				WaitTimeSeconds:     int32(config.WaitTimeSeconds),
+				VisibilityTimeout:   int32(config.VisibilityTimeoutSeconds),
+			}, opts)
+			if err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "sqs"})
+				time.Sleep(backoff)
+				if backoff < maxBackoff {
+					backoff *= 2
+				}
+				continue
+			}
+			backoff = time.Second
+
+			for _, i := range output.Messages {
+				event, err := decodeBody(awssdk.ToString(i.Body))
+				if err != nil {
+					utils.PrintLog("error", utils.LogLine{Error: fmt.Sprintf("can't decode the message '%v': %v", awssdk.ToString(i.MessageId), err), Message: "sqs"})
+				} else if err := handler.PublishEvent(event); err != nil {
+					utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "sqs", TraceID: event.TraceID})
+					continue
+				}
+				if _, err := client.DeleteMessage(context.Background(), &sqs.DeleteMessageInput{
+					QueueUrl:      awssdk.String(config.QueueURL),
+					ReceiptHandle: i.ReceiptHandle,
+				}, opts); err != nil {
+					utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "sqs"})
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// decodeBody decodes the event of the message, the envelope of SNS is removed if present
+func decodeBody(body string) (*events.Event, error) {
+	var n notification
+	if err := json.Unmarshal([]byte(body), &n); err == nil && n.Type == "Notification" && n.Message != "" {
+		body = n.Message
+	}
+	return events.DecodeEvent(strings.NewReader(body))
+}
@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// Broadcaster sends the published values to all the subscribers, the values are dropped for a subscriber
+// too slow to read them, the live streams must not slow down the processing of the events
+type Broadcaster[T any] struct {
+	subscribers map[chan T]struct{}
+	mu          sync.Mutex
+}
+
+// Event is a Falco event processed by the rules, with the rules it matched
+type Event struct {
+	Time         time.Time `json:"time"`
+	TraceID      string    `json:"trace_id"`
+	Rule         string    `json:"rule"`
+	Priority     string    `json:"priority"`
+	Source       string    `json:"source"`
+	Hostname     string    `json:"hostname,omitempty"`
+	Output       string    `json:"output"`
+	Namespace    string    `json:"namespace,omitempty"`
+	Pod          string    `json:"pod,omitempty"`
+	MatchedRules []string  `json:"matched_rules"`
+}
+
+// Events are the processed events
+var Events Broadcaster[*Event]
+
+// Subscribe returns a channel receiving the published values, the returned function ends the subscription
+func (b *Broadcaster[T]) Subscribe(buffer int) (<-chan T, func()) {
+	c := make(chan T, buffer)
+	b.mu.Lock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[chan T]struct{})
+	}
+	b.subscribers[c] = struct{}{}
+	b.mu.Unlock()
+	return c, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[c]; ok {
+			delete(b.subscribers, c)
+			close(c)
+		}
+	}
+}
+
+// Publish sends the value to the subscribers, without waiting for them
+func (b *Broadcaster[T]) Publish(v T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.subscribers {
+		select {
+		case i <- v:
+		default:
+		}
+	}
+}
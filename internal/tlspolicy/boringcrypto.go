@@ -0,0 +1,9 @@
+//go:build boringcrypto
+
+package tlspolicy
+
+// the builds with GOEXPERIMENT=boringcrypto use the FIPS validated module, the import restricts
+// all the TLS configurations to the FIPS approved settings
+import _ "crypto/tls/fipsonly"
+
+const boringCrypto = true
@@ -0,0 +1,5 @@
+//go:build !boringcrypto
+
+package tlspolicy
+
+const boringCrypto = false
@@ -0,0 +1,118 @@
+package tlspolicy
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/falco-talon/falco-talon/configuration"
+)
+
+// the policy is applied to the TLS configurations of the server and of the clients, the clients using the
+// default transport of net/http get it from the outbound package
+
+var (
+	minVersion   uint16 = tls.VersionTLS12
+	maxVersion   uint16
+	cipherSuites []uint16
+	curves       []tls.CurveID
+	fips         = boringCrypto
+)
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// the FIPS approved settings, TLS 1.3 is excluded as its suites can't be restricted to the approved ones
+var (
+	fipsCipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	}
+	fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+)
+
+// Init sets the policy, before the init of the server and of the clients
+func Init(config configuration.TLSPolicyConfig) error {
+	v, ok := versions[config.MinVersion]
+	if !ok {
+		return fmt.Errorf("wrong `min_version` setting '%v', must be 1.2 or 1.3", config.MinVersion)
+	}
+
+	suites := make([]uint16, 0, len(config.CipherSuites))
+	for _, i := range config.CipherSuites {
+		id, ok := getCipherSuite(i)
+		if !ok {
+			return fmt.Errorf("unknown or insecure cipher suite '%v'", i)
+		}
+		suites = append(suites, id)
+	}
+
+	fips = boringCrypto || config.FIPS
+	if fips {
+		if v == tls.VersionTLS13 {
+			return errors.New("the `min_version` 1.3 isn't allowed in FIPS mode")
+		}
+		if len(suites) == 0 {
+			suites = fipsCipherSuites
+		}
+		for _, i := range suites {
+			if !slices.Contains(fipsCipherSuites, i) {
+				return fmt.Errorf("the cipher suite '%v' isn't FIPS approved", tls.CipherSuiteName(i))
+			}
+		}
+		maxVersion = tls.VersionTLS12
+		curves = fipsCurves
+	} else {
+		maxVersion = 0
+		curves = nil
+	}
+
+	minVersion = v
+	cipherSuites = nil
+	if len(suites) != 0 {
+		cipherSuites = suites
+	}
+	return nil
+}
+
+// Apply sets the versions, the cipher suites and the curves of the policy in the config and returns it
+func Apply(c *tls.Config) *tls.Config {
+	if c.MinVersion < minVersion {
+		c.MinVersion = minVersion
+	}
+	if maxVersion != 0 {
+		c.MaxVersion = maxVersion
+	}
+	if cipherSuites != nil {
+		c.CipherSuites = cipherSuites
+	}
+	if curves != nil {
+		c.CurvePreferences = curves
+	}
+	return c
+}
+
+// IsFIPS returns true if only the FIPS approved settings are allowed
+func IsFIPS() bool {
+	return fips
+}
+
+// IsBoringCrypto returns true if the binary is built with the BoringCrypto module
+func IsBoringCrypto() bool {
+	return boringCrypto
+}
+
+// getCipherSuite returns the id of a suite from its name, the insecure suites aren't allowed
+func getCipherSuite(name string) (uint16, bool) {
+	for _, i := range tls.CipherSuites() {
+		if i.Name == name {
+			return i.ID, true
+		}
+	}
+	return 0, false
+}
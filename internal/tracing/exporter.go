@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/falco-talon/falco-talon/internal/otlp"
+)
+
+// exporter sends the spans to an OTLP/HTTP endpoint
+type exporter struct {
+	client *otlp.Client
+}
+
+type request struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   otlp.Resource `json:"resource"`
+	ScopeSpans []scopeSpans  `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope otlp.Scope `json:"scope"`
+	Spans []span     `json:"spans"`
+}
+
+type span struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlp.KeyValue `json:"attributes,omitempty"`
+	Events            []spanEvent     `json:"events,omitempty"`
+	Status            status          `json:"status"`
+	Kind              int             `json:"kind"`
+}
+
+type spanEvent struct {
+	TimeUnixNano string          `json:"timeUnixNano"`
+	Name         string          `json:"name"`
+	Attributes   []otlp.KeyValue `json:"attributes,omitempty"`
+}
+
+type status struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code"`
+}
+
+// status codes of OTLP, they differ from the ones of the API
+const (
+	statusUnset int = 0
+	statusOk    int = 1
+	statusError int = 2
+)
+
+func (e *exporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	return e.client.Send(ctx, otlp.TracesPath, newRequest(spans))
+}
+
+func (e *exporter) Shutdown(_ context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// newRequest groups the spans by resource and instrumentation scope
+func newRequest(spans []sdktrace.ReadOnlySpan) request {
+	var r request
+	resources := make(map[string]int)
+	scopes := make(map[string]int)
+
+	for _, i := range spans {
+		key := ""
+		if res := i.Resource(); res != nil {
+			key = res.Encoded(attribute.DefaultEncoder())
+		}
+		k, ok := resources[key]
+		if !ok {
+			k = len(r.ResourceSpans)
+			resources[key] = k
+			r.ResourceSpans = append(r.ResourceSpans, resourceSpans{Resource: otlp.NewResource(i.Resource())})
+		}
+
+		scope := i.InstrumentationScope()
+		key += "/" + scope.Name + "/" + scope.Version
+		s, ok := scopes[key]
+		if !ok {
+			s = len(r.ResourceSpans[k].ScopeSpans)
+			scopes[key] = s
+			r.ResourceSpans[k].ScopeSpans = append(r.ResourceSpans[k].ScopeSpans, scopeSpans{Scope: otlp.NewScope(scope)})
+		}
+
+		r.ResourceSpans[k].ScopeSpans[s].Spans = append(r.ResourceSpans[k].ScopeSpans[s].Spans, newSpan(i))
+	}
+
+	return r
+}
+
+func newSpan(s sdktrace.ReadOnlySpan) span {
+	sc := s.SpanContext()
+	traceID := sc.TraceID()
+	spanID := sc.SpanID()
+	result := span{
+		TraceID:           hex.EncodeToString(traceID[:]),
+		SpanID:            hex.EncodeToString(spanID[:]),
+		Name:              s.Name(),
+		Kind:              int(s.SpanKind()),
+		StartTimeUnixNano: otlp.FormatTime(s.StartTime()),
+		EndTimeUnixNano:   otlp.FormatTime(s.EndTime()),
+		Attributes:        otlp.NewAttributes(s.Attributes()),
+	}
+	if parent := s.Parent(); parent.IsValid() {
+		parentID := parent.SpanID()
+		result.ParentSpanID = hex.EncodeToString(parentID[:])
+	}
+
+	switch s.Status().Code {
+	case codes.Error:
+		result.Status = status{Code: statusError, Message: s.Status().Description}
+	case codes.Ok:
+		result.Status = status{Code: statusOk}
+	default:
+		result.Status = status{Code: statusUnset}
+	}
+
+	for _, i := range s.Events() {
+		result.Events = append(result.Events, spanEvent{
+			TimeUnixNano: otlp.FormatTime(i.Time),
+			Name:         i.Name,
+			Attributes:   otlp.NewAttributes(i.Attributes),
+		})
+	}
+
+	return result
+}
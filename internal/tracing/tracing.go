@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/otlp"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const tracerName = "github.com/falco-talon/falco-talon"
+
+var provider *sdktrace.TracerProvider
+
+// Init exports the spans to the OTLP endpoint, without it the spans are discarded
+func Init(config configuration.TracingConfig) error {
+	if !config.Enabled {
+		return nil
+	}
+	if config.Endpoint == "" {
+		return errors.New("wrong `endpoint` setting")
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return errors.New("wrong `sample_ratio` setting, must be between 0 and 1")
+	}
+
+	resources := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String("falco-talon"),
+		semconv.ServiceVersionKey.String(configuration.GetInfo().GitVersion),
+	)
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(&exporter{client: otlp.NewClient(config.Endpoint, config.Headers)}),
+		sdktrace.WithResource(resources),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return nil
+}
+
+// Start creates a span, child of the one in the context if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End sets the status of the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// EndWithLog sets the status of the span from the log line of the step and ends it
+func EndWithLog(span trace.Span, log utils.LogLine) {
+	if log.Status != "" {
+		span.SetAttributes(attribute.String("falco_talon.status", log.Status))
+	}
+	if log.Error != "" {
+		span.SetStatus(codes.Error, log.Error)
+	}
+	span.End()
+}
+
+// Shutdown exports the remaining spans
+func Shutdown(timeout time.Duration) error {
+	if provider == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return provider.Shutdown(ctx)
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+// WrapTransport creates a span for each request sent in the context of a trace,
+// the other ones (watchers, leader election) aren't traced
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &transport{next: rt}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !trace.SpanFromContext(req.Context()).SpanContext().IsValid() {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), fmt.Sprintf("%v %v", req.Method, req.URL.Path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Host),
+			attribute.String("url.path", req.URL.Path),
+		),
+	)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		End(span, err)
+		return resp, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+	return resp, nil
+}
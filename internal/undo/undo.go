@@ -0,0 +1,290 @@
+package undo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/nats"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// Entry is the state of the resources before a reversible action
+type Entry struct {
+	Time      time.Time         `json:"time"`
+	RevertAt  *time.Time        `json:"revert_at,omitempty"`
+	State     map[string]string `json:"state"`
+	Objects   map[string]string `json:"objects,omitempty"`
+	ID        string            `json:"id"`
+	TraceID   string            `json:"trace_id"`
+	Rule      string            `json:"rule"`
+	Action    string            `json:"action"`
+	Actionner string            `json:"actionner"`
+}
+
+// Store keeps the entries until they're reverted or deleted
+type Store interface {
+	Add(entry *Entry) error
+	List() ([]*Entry, error)
+	Get(id string) (*Entry, error)
+	Delete(id string) error
+}
+
+const (
+	FileStore      string = "file"
+	JetStreamStore string = "jetstream"
+
+	bucket            string = "UNDO"
+	reconcileInterval        = time.Minute
+)
+
+var (
+	store    Store
+	reverter func(entry *Entry) error
+	regID    = regexp.MustCompile(`^[a-f0-9-]+$`)
+	ErrNoID  = errors.New("unknown entry")
+)
+
+// Init creates the store and starts the revert of the entries with an expired TTL,
+// the undo is disabled without store
+func Init(config configuration.UndoConfig, revert func(entry *Entry) error) error {
+	switch config.Store {
+	case "":
+		return nil
+	case FileStore:
+		if config.Directory == "" {
+			return errors.New("wrong `directory` setting")
+		}
+		if err := os.MkdirAll(config.Directory, 0750); err != nil {
+			return err
+		}
+		store = &fileStore{directory: config.Directory}
+	case JetStreamStore:
+		kv, err := nats.GetConsumer().GetBucket(bucket, time.Duration(config.MaxAgeHours)*time.Hour)
+		if err != nil {
+			return err
+		}
+		store = &jetStreamStore{kv: kv}
+	default:
+		return fmt.Errorf("wrong `store` setting, must be '%v' or '%v'", FileStore, JetStreamStore)
+	}
+	reverter = revert
+
+	go func() {
+		ticker := time.NewTicker(reconcileInterval)
+		for range ticker.C {
+			reconcile()
+		}
+	}()
+	return nil
+}
+
+func GetStore() Store {
+	return store
+}
+
+// Add stores the prior state of a reversible action, if the undo is enabled, it returns the id of the entry,
+// the action is reverted after the ttl if it's not 0
+func Add(rule, action, actionner, traceID string, state, objects map[string]string, ttl time.Duration) (string, error) {
+	if store == nil || state == nil {
+		return "", nil
+	}
+	entry := &Entry{
+		ID:        uuid.NewString(),
+		Time:      time.Now().UTC(),
+		Rule:      rule,
+		Action:    action,
+		Actionner: actionner,
+		TraceID:   traceID,
+		State:     state,
+		Objects:   objects,
+	}
+	if ttl > 0 {
+		t := entry.Time.Add(ttl)
+		entry.RevertAt = &t
+	}
+	if err := store.Add(entry); err != nil {
+		return "", err
+	}
+	return entry.ID, nil
+}
+
+// Revert restores the prior state of the entry, the entry is removed once reverted
+func Revert(id string) (*Entry, error) {
+	if store == nil {
+		return nil, errors.New("the undo is disabled")
+	}
+	entry, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := reverter(entry); err != nil {
+		return entry, err
+	}
+	return entry, store.Delete(entry.ID)
+}
+
+// CheckID returns an error if the id can't be the one of an entry
+func CheckID(id string) error {
+	if !regID.MatchString(id) {
+		return ErrNoID
+	}
+	return nil
+}
+
+// reconcile reverts the entries with an expired ttl
+func reconcile() {
+	list, err := store.List()
+	if err != nil {
+		utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "undo"})
+		return
+	}
+	for _, i := range list {
+		if i.RevertAt == nil || time.Now().Before(*i.RevertAt) {
+			continue
+		}
+		log := utils.LogLine{Message: "undo", Rule: i.Rule, Action: i.Action, Actionner: i.Actionner, Objects: i.Objects, TraceID: i.TraceID}
+		if _, err := Revert(i.ID); err != nil {
+			log.Error = err.Error()
+			utils.PrintLog("error", log)
+			continue
+		}
+		log.Result = "action reverted after its ttl"
+		utils.PrintLog("info", log)
+	}
+}
+
+type fileStore struct {
+	directory string
+}
+
+func (s *fileStore) Add(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.directory, entry.ID+".json"), b, 0600)
+}
+
+func (s *fileStore) List() ([]*Entry, error) {
+	files, err := os.ReadDir(s.directory)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Entry, 0, len(files))
+	for _, i := range files {
+		if i.IsDir() || !strings.HasSuffix(i.Name(), ".json") {
+			continue
+		}
+		entry, err := s.Get(strings.TrimSuffix(i.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		list = append(list, entry)
+	}
+	sortEntries(list)
+	return list, nil
+}
+
+func (s *fileStore) Get(id string) (*Entry, error) {
+	if err := CheckID(id); err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(s.directory, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoID
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *fileStore) Delete(id string) error {
+	if err := CheckID(id); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.directory, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoID
+	}
+	return err
+}
+
+type jetStreamStore struct {
+	kv natsgo.KeyValue
+}
+
+func (s *jetStreamStore) Add(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Put(entry.ID, b)
+	return err
+}
+
+func (s *jetStreamStore) List() ([]*Entry, error) {
+	keys, err := s.kv.Keys()
+	if errors.Is(err, natsgo.ErrNoKeysFound) {
+		return []*Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Entry, 0, len(keys))
+	for _, i := range keys {
+		entry, err := s.Get(i)
+		if err != nil {
+			continue
+		}
+		list = append(list, entry)
+	}
+	sortEntries(list)
+	return list, nil
+}
+
+func (s *jetStreamStore) Get(id string) (*Entry, error) {
+	if err := CheckID(id); err != nil {
+		return nil, err
+	}
+	v, err := s.kv.Get(id)
+	if errors.Is(err, natsgo.ErrKeyNotFound) {
+		return nil, ErrNoID
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(v.Value(), &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (s *jetStreamStore) Delete(id string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return s.kv.Purge(id)
+}
+
+func sortEntries(list []*Entry) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Time.Before(list[j].Time)
+	})
+}
@@ -0,0 +1,249 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/secrets"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+)
+
+const (
+	kubernetesAuth string = "kubernetes"
+	tokenAuth      string = "token"
+)
+
+// VaultClient reads the secrets of Vault, the token of the Kubernetes auth is renewed, or obtained
+// again, before its expiration
+type VaultClient struct {
+	httpClient      *http.Client
+	settings        configuration.VaultConfig
+	token           string
+	tokenTTL        time.Duration
+	tokenExpiration time.Time
+	renewable       bool
+	mu              sync.Mutex
+}
+
+type response struct {
+	Data          map[string]interface{} `json:"data"`
+	Auth          *auth                  `json:"auth"`
+	LeaseID       string                 `json:"lease_id"`
+	Errors        []string               `json:"errors"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+}
+
+type auth struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+// NewClient returns a client of Vault, VAULT_ADDR and VAULT_TOKEN are used if the settings are empty
+func NewClient(settings configuration.VaultConfig) (*VaultClient, error) {
+	if settings.Address == "" {
+		settings.Address = os.Getenv("VAULT_ADDR")
+	}
+	if settings.Address == "" {
+		return nil, errors.New("wrong `address` setting")
+	}
+	settings.Address = strings.TrimSuffix(settings.Address, "/")
+	if settings.Token == "" {
+		settings.Token = os.Getenv("VAULT_TOKEN")
+	}
+
+	switch settings.AuthMethod {
+	case kubernetesAuth:
+		if settings.Role == "" {
+			return nil, errors.New("wrong `role` setting")
+		}
+	case tokenAuth:
+		if settings.Token == "" {
+			return nil, errors.New("wrong `token` setting")
+		}
+	default:
+		return nil, fmt.Errorf("wrong `auth_method` setting, must be '%v' or '%v'", kubernetesAuth, tokenAuth)
+	}
+
+	tlsConfig := tlspolicy.Apply(&tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: settings.InsecureSkipVerify, //nolint:gosec
+		RootCAs:            outbound.RootCAs(),
+	})
+	if settings.CACertFile != "" {
+		pool := outbound.CertPool()
+		b, err := os.ReadFile(settings.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no valid certificate in '%v'", settings.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	c := &VaultClient{
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		settings:   settings,
+	}
+	if _, err := c.getToken(); err != nil {
+		return nil, fmt.Errorf("can't authenticate to vault: %v", err)
+	}
+	return c, nil
+}
+
+func (c *VaultClient) Name() string {
+	return "vault"
+}
+
+// Read reads a secret, the fields of the KV v2 secrets are in data.data, those of the other engines
+// (KV v1, database, aws, ...) in data
+func (c *VaultClient) Read(path string) (*secrets.Secret, error) {
+	resp, err := c.request(http.MethodGet, "/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	data := resp.Data
+	if d, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = d
+		}
+	}
+	secret := &secrets.Secret{
+		Data:            make(map[string]string, len(data)),
+		LeaseID:         resp.LeaseID,
+		LeaseDuration:   time.Duration(resp.LeaseDuration) * time.Second,
+		RefreshInterval: time.Duration(c.settings.RefreshIntervalSeconds) * time.Second,
+		Renewable:       resp.Renewable,
+	}
+	for i, j := range data {
+		if s, ok := j.(string); ok {
+			secret.Data[i] = s
+			continue
+		}
+		b, err := json.Marshal(j)
+		if err != nil {
+			return nil, err
+		}
+		secret.Data[i] = string(b)
+	}
+	return secret, nil
+}
+
+// Renew extends the lease of a dynamic secret
+func (c *VaultClient) Renew(leaseID string) (time.Duration, error) {
+	resp, err := c.request(http.MethodPut, "/v1/sys/leases/renew", map[string]string{"lease_id": leaseID})
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(resp.LeaseDuration) * time.Second, nil
+}
+
+// getToken returns the token, renewed after 2/3 of its TTL, or obtained again with a new login
+func (c *VaultClient) getToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.settings.AuthMethod == tokenAuth {
+		return c.settings.Token, nil
+	}
+	if c.token != "" && (c.tokenExpiration.IsZero() || time.Now().Before(c.tokenExpiration.Add(-c.tokenTTL/3))) {
+		return c.token, nil
+	}
+	if c.token != "" && c.renewable {
+		resp, err := c.do(http.MethodPost, "/v1/auth/token/renew-self", c.token, nil)
+		if err == nil && resp.Auth != nil && time.Duration(resp.Auth.LeaseDuration)*time.Second >= c.tokenTTL {
+			c.setToken(resp.Auth)
+			return c.token, nil
+		}
+	}
+
+	jwt, err := os.ReadFile(c.settings.TokenFile)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(http.MethodPost, "/v1/auth/"+strings.Trim(c.settings.AuthMountPath, "/")+"/login", "", map[string]string{
+		"role": c.settings.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return "", errors.New("no token in the response of the login")
+	}
+	c.setToken(resp.Auth)
+	return c.token, nil
+}
+
+func (c *VaultClient) setToken(a *auth) {
+	c.token = a.ClientToken
+	c.renewable = a.Renewable
+	c.tokenTTL = time.Duration(a.LeaseDuration) * time.Second
+	c.tokenExpiration = time.Time{}
+	if c.tokenTTL > 0 {
+		c.tokenExpiration = time.Now().Add(c.tokenTTL)
+	}
+}
+
+func (c *VaultClient) request(method, path string, body interface{}) (*response, error) {
+	token, err := c.getToken()
+	if err != nil {
+		return nil, err
+	}
+	return c.do(method, path, token, body)
+}
+
+func (c *VaultClient) do(method, path, token string, body interface{}) (*response, error) {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.settings.Address+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.settings.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.settings.Namespace)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r := new(response)
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("wrong response from vault (%v): %v", resp.Status, err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		if len(r.Errors) != 0 {
+			return nil, fmt.Errorf("%v: %v", resp.Status, strings.Join(r.Errors, ", "))
+		}
+		return nil, errors.New(resp.Status)
+	}
+	return r, nil
+}
@@ -63,6 +63,15 @@ func (Build) Local() error {
 	return sh.RunV("go", "build", "-trimpath", "-ldflags", ldFlags, "-o", "falco-talon", ".")
 }
 
+// build:fips builds a binary with the FIPS validated BoringCrypto module, the TLS policy is then always in FIPS mode
+func (Build) Fips() error {
+	ldFlags := generateLDFlags()
+
+	fmt.Println(ldFlags)
+	return sh.RunWithV(map[string]string{"CGO_ENABLED": "1", "GOEXPERIMENT": "boringcrypto"},
+		"go", "build", "-trimpath", "-ldflags", ldFlags, "-o", "falco-talon", ".")
+}
+
 // build:images builds images and not push
 func (Build) Images() error {
 	exportLDFlags()
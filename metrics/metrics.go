@@ -4,16 +4,20 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/prometheus"
 	"go.opentelemetry.io/otel/metric"
 	sdk "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 
 	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/otlp"
+	"github.com/falco-talon/falco-talon/internal/queue"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
@@ -25,8 +29,19 @@ var (
 	actionCounter       metric.Int64Counter
 	notificationCounter metric.Int64Counter
 	outputCounter       metric.Int64Counter
+	droppedCounter      metric.Int64Counter
+	droppedEventCounter metric.Int64Counter
+	throttledCounter    metric.Int64Counter
+	rebuildCounter      metric.Int64Counter
+	verificationCounter metric.Int64Counter
+	openCircuits        metric.Int64UpDownCounter
+	actionDuration      metric.Float64Histogram
+	timeToRemediate     metric.Float64Histogram
+)
+var (
+	ctx    context.Context
+	reader *sdk.ManualReader
 )
-var ctx context.Context
 
 func init() {
 	ctx = context.Background()
@@ -40,8 +55,11 @@ func init() {
 		semconv.ServiceNameKey.String("falco-talon"),
 		semconv.ServiceVersionKey.String(configuration.GetInfo().GitVersion),
 	)
+	// the metrics are also collected to be pushed with OTLP
+	reader = sdk.NewManualReader()
 	provider := sdk.NewMeterProvider(
 		sdk.WithReader(exporter),
+		sdk.WithReader(reader),
 		sdk.WithResource(resources),
 	)
 	meter := provider.Meter(
@@ -54,6 +72,31 @@ func init() {
 	actionCounter, _ = meter.Int64Counter("action", metric.WithDescription("number of actions"))
 	notificationCounter, _ = meter.Int64Counter("notification", metric.WithDescription("number of notifications"))
 	outputCounter, _ = meter.Int64Counter("output", metric.WithDescription("number of outputs"))
+	droppedCounter, _ = meter.Int64Counter("dropped_notification", metric.WithDescription("number of dropped notifications"))
+	droppedEventCounter, _ = meter.Int64Counter("dropped_event", metric.WithDescription("number of events rejected by the ingestion"))
+	throttledCounter, _ = meter.Int64Counter("kubernetes_throttled_request", metric.WithDescription("number of requests throttled by the kubernetes API server (429)"))
+	rebuildCounter, _ = meter.Int64Counter("kubernetes_client_rebuild", metric.WithDescription("number of rebuilds of the kubernetes clients, for the rotated credentials and the stale connections"))
+	verificationCounter, _ = meter.Int64Counter("action_verification", metric.WithDescription("number of verifications of the effect of the actions, verified or unverified"))
+	openCircuits, _ = meter.Int64UpDownCounter("open_circuit_breaker", metric.WithDescription("state of the circuit breakers of the actionners, 1 if open"))
+	actionDuration, _ = meter.Float64Histogram("action_duration",
+		metric.WithDescription("duration of the actions, retries included"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120),
+	)
+	timeToRemediate, _ = meter.Float64Histogram("time_to_remediate",
+		metric.WithDescription("duration from the event to the end of the successful action, its verification included"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800, 3600),
+	)
+	_, _ = meter.Int64ObservableGauge("queue_depth",
+		metric.WithDescription("number of events waiting for their actions, by class of priority"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			for _, i := range queue.Classes {
+				o.Observe(int64(queue.Count(i)), metric.WithAttributes(attribute.Key("class").String(i)))
+			}
+			return nil
+		}),
+	)
 }
 
 func IncreaseCounter(log utils.LogLine) {
@@ -69,7 +112,58 @@ func IncreaseCounter(log utils.LogLine) {
 		notificationCounter.Add(ctx, 1, opts)
 	case "output":
 		outputCounter.Add(ctx, 1, opts)
+	case "dropped_notification":
+		droppedCounter.Add(ctx, 1, opts)
+	}
+}
+
+// IncreaseDroppedEvents counts the events rejected by the ingestion, the reason is the cause of the rejection
+func IncreaseDroppedEvents(reason string, n int) {
+	droppedEventCounter.Add(ctx, int64(n), metric.WithAttributes(attribute.Key("reason").String(reason)))
+}
+
+// IncreaseVerifications counts the verifications of the effect of the actions, the result is verified or unverified
+func IncreaseVerifications(actionner, result string) {
+	verificationCounter.Add(ctx, 1, metric.WithAttributes(attribute.Key("actionner").String(actionner), attribute.Key("result").String(result)))
+}
+
+// IncreaseThrottledRequests counts the requests to the kubernetes API server rejected with a 429
+func IncreaseThrottledRequests(method string) {
+	throttledCounter.Add(ctx, 1, metric.WithAttributes(attribute.Key("method").String(method)))
+}
+
+// IncreaseClientRebuilds counts the rebuilds of the kubernetes clients, by reason
+func IncreaseClientRebuilds(reason string) {
+	rebuildCounter.Add(ctx, 1, metric.WithAttributes(attribute.Key("reason").String(reason)))
+}
+
+// ObserveActionDuration records the duration of the action, the objects aren't used as attributes to limit the cardinality
+func ObserveActionDuration(log utils.LogLine, duration time.Duration) {
+	actionDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.Key("rule").String(log.Rule),
+		attribute.Key("action").String(log.Action),
+		attribute.Key("actionner").String(log.Actionner),
+		attribute.Key("status").String(log.Status),
+	))
+}
+
+// ObserveTimeToRemediate records the duration from the event to the remediation, for the MTTR by rule
+func ObserveTimeToRemediate(log utils.LogLine, duration time.Duration) {
+	timeToRemediate.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		attribute.Key("rule").String(log.Rule),
+		attribute.Key("action").String(log.Action),
+		attribute.Key("actionner").String(log.Actionner),
+	))
+}
+
+// SetCircuitBreaker updates the state of the circuit breaker of the actionner
+func SetCircuitBreaker(actionner string, open bool) {
+	opts := metric.WithAttributes(attribute.Key("actionner").String(actionner))
+	if open {
+		openCircuits.Add(ctx, 1, opts)
+		return
 	}
+	openCircuits.Add(ctx, -1, opts)
 }
 
 func getMeasurementOption(log utils.LogLine) metric.MeasurementOption {
@@ -114,6 +208,30 @@ func getMeasurementOption(log utils.LogLine) metric.MeasurementOption {
 	return opts
 }
 
+// StartExport pushes the metrics to the OTLP endpoint every interval
+func StartExport(client *otlp.Client, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := Export(client, interval); err != nil {
+				utils.PrintLog("error", utils.LogLine{Error: err.Error(), Message: "otlp"})
+			}
+		}
+	}()
+}
+
+// Export collects the metrics and pushes them to the OTLP endpoint, it's also called on shutdown
+func Export(client *otlp.Client, timeout time.Duration) error {
+	c, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(c, &rm); err != nil {
+		return err
+	}
+	return client.Send(c, otlp.MetricsPath, otlp.NewMetricsRequest(&rm))
+}
+
 func Handler() http.Handler {
 	return promhttp.Handler()
 }
@@ -0,0 +1,152 @@
+package alertmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Settings struct {
+	CustomHeaders      map[string]string `field:"custom_headers"`
+	Labels             map[string]string `field:"labels"`
+	Annotations        map[string]string `field:"annotations"`
+	HostPort           string            `field:"host_port"`
+	User               string            `field:"user"`
+	Password           string            `field:"password"`
+	CACertFile         string            `field:"ca_cert_file"`
+	ExpiresMinutes     int               `field:"expires_in_minutes" default:"0"`
+	TimeoutSeconds     int               `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify bool              `field:"insecure_skip_verify" default:"false"`
+}
+
+// Alert is the model of the Alertmanager v2 API
+type Alert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     string            `json:"startsAt,omitempty"`
+	EndsAt       string            `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+const (
+	alertsPath string = "/api/v2/alerts"
+	alertName  string = "FalcoTalon"
+)
+
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings  *Settings
+	transport *http.Transport
+}
+
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	var tlsConfig *tls.Config
+	if n.settings.CACertFile != "" || n.settings.InsecureSkipVerify {
+		var err error
+		if tlsConfig, err = http.NewTLSConfig("", "", n.settings.CACertFile, n.settings.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.settings.TimeoutSeconds)*time.Second)
+	return n, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	client := http.NewClient("", "", "", n.settings.CustomHeaders)
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
+	if n.settings.User != "" && n.settings.Password != "" {
+		client.SetBasicAuth(n.settings.User, n.settings.Password)
+	}
+
+	return client.Request(strings.TrimSuffix(n.settings.HostPort, "/")+alertsPath, []Alert{NewAlert(n.settings, log)})
+}
+
+func checkSettings(settings *Settings) error {
+	if settings.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
+	if settings.HostPort == "" {
+		return errors.New("wrong `host_port` setting")
+	}
+	if settings.ExpiresMinutes < 0 {
+		return errors.New("wrong `expires_in_minutes` setting")
+	}
+	if err := http.CheckURL(settings.HostPort); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func NewAlert(settings *Settings, log utils.LogLine) Alert {
+	labels := map[string]string{}
+	for i, j := range settings.Labels {
+		labels[i] = j
+	}
+	labels["alertname"] = alertName
+	labels["source"] = utils.FalcoTalonStr
+	labels["step"] = log.Message
+	labels["status"] = log.Status
+	if log.Status == "failure" {
+		labels["severity"] = "critical"
+	} else {
+		labels["severity"] = "warning"
+	}
+	if log.Rule != "" {
+		labels["rule"] = log.Rule
+	}
+	if log.Action != "" {
+		labels["action"] = log.Action
+	}
+	if log.Actionner != "" {
+		labels["actionner"] = log.Actionner
+	}
+	if log.Target != "" {
+		labels["target"] = log.Target
+	}
+	for i, j := range log.Objects {
+		labels[strings.ToLower(i)] = j
+	}
+
+	annotations := map[string]string{}
+	for i, j := range settings.Annotations {
+		annotations[i] = j
+	}
+	if log.Output != "" {
+		annotations["output"] = log.Output
+	}
+	if log.Result != "" {
+		annotations["result"] = log.Result
+	}
+	if log.Error != "" {
+		annotations["error"] = log.Error
+	}
+	if log.Event != "" {
+		annotations["event"] = log.Event
+	}
+	if log.TraceID != "" {
+		annotations["trace_id"] = log.TraceID
+	}
+
+	now := time.Now()
+	alert := Alert{
+		Labels:      labels,
+		Annotations: annotations,
+		StartsAt:    now.Format(time.RFC3339),
+	}
+	if settings.ExpiresMinutes > 0 {
+		alert.EndsAt = now.Add(time.Duration(settings.ExpiresMinutes) * time.Minute).Format(time.RFC3339)
+	}
+
+	return alert
+}
@@ -0,0 +1,29 @@
+package notifiers
+
+import (
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// the key of the links to the artifacts in the objects of the notifications, once capitalized
+const urlObject string = "Url"
+
+// attach adds the evidence to the log for the notifier, the links to the artifacts are removed if the notifier
+// doesn't attach them, the pre-signed urls give access to the artifacts to anyone who reads them
+func attach(notifier string, event *events.Event, log utils.LogLine) utils.LogLine {
+	a := configuration.GetConfiguration().Attachments[notifier]
+	if a.Event {
+		log.RawEvent = event.String()
+	}
+	if _, ok := log.Objects[urlObject]; ok && !a.Artifacts {
+		obj := make(map[string]string, len(log.Objects))
+		for i, j := range log.Objects {
+			if i != urlObject {
+				obj[i] = j
+			}
+		}
+		log.Objects = obj
+	}
+	return log
+}
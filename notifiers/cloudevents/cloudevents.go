@@ -0,0 +1,119 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	SpecVersion string = "1.0"
+
+	// Structured mode: the whole event is the body of the request
+	Structured string = "structured"
+	// Binary mode: the attributes are sent as headers, the body contains the data
+	Binary string = "binary"
+
+	StructuredContentType string = "application/cloudevents+json; charset=utf-8"
+	DefaultSource         string = "falco-talon"
+
+	typePrefix string = "dev.falco-talon."
+)
+
+// Event is an event following the CloudEvents 1.0 specification
+type Event struct {
+	Data            interface{} `json:"data,omitempty"`
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            string      `json:"time"`
+	DataContentType string      `json:"datacontenttype,omitempty"`
+	TraceID         string      `json:"falcotalontraceid,omitempty"`
+}
+
+// CheckMode returns an error if the mode is not a valid one, an empty mode disables the CloudEvents format
+func CheckMode(mode string) error {
+	switch mode {
+	case "", Structured, Binary:
+		return nil
+	default:
+		return errors.New("wrong `cloudevents` setting, must be 'structured' or 'binary'")
+	}
+}
+
+// GetType returns the type of the event, it depends on the step of the workflow the log comes from
+func GetType(log utils.LogLine) string {
+	switch log.Message {
+	case "action", "output", "notification":
+		return typePrefix + log.Message
+	default:
+		return typePrefix + "event"
+	}
+}
+
+// GetSubject returns the subject of the event, the rule and the action which produced it
+func GetSubject(log utils.LogLine) string {
+	switch {
+	case log.Rule != "" && log.Action != "":
+		return log.Rule + "/" + log.Action
+	case log.Rule != "":
+		return log.Rule
+	default:
+		return log.Action
+	}
+}
+
+func NewEvent(log utils.LogLine, source string) Event {
+	if source == "" {
+		source = DefaultSource
+	}
+	return Event{
+		SpecVersion: SpecVersion,
+		ID:          uuid.NewString(),
+		Source:      source,
+		Type:        GetType(log),
+		Subject:     GetSubject(log),
+		Time:        time.Now().Format(time.RFC3339Nano),
+		TraceID:     log.TraceID,
+	}
+}
+
+// Encode wraps the data into a CloudEvent, in structured mode the returned body is the event,
+// in binary mode the attributes are set as headers of the client and the data is returned as it is
+func Encode(client *http.Client, mode, source, contentType string, log utils.LogLine, data []byte) ([]byte, error) {
+	event := NewEvent(log, source)
+	event.DataContentType = contentType
+
+	switch mode {
+	case Binary:
+		client.SetHeader("ce-specversion", event.SpecVersion)
+		client.SetHeader("ce-id", event.ID)
+		client.SetHeader("ce-source", event.Source)
+		client.SetHeader("ce-type", event.Type)
+		client.SetHeader("ce-time", event.Time)
+		if event.Subject != "" {
+			client.SetHeader("ce-subject", event.Subject)
+		}
+		if event.TraceID != "" {
+			client.SetHeader("ce-falcotalontraceid", event.TraceID)
+		}
+		return data, nil
+	case Structured:
+		if json.Valid(data) {
+			event.Data = json.RawMessage(data)
+		} else {
+			event.Data = string(data)
+		}
+		client.SetContentType(StructuredContentType)
+		return json.Marshal(event)
+	default:
+		return data, nil
+	}
+}
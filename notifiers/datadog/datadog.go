@@ -0,0 +1,241 @@
+package datadog
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/notifiers/templates"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Settings struct {
+	APIKey             string   `field:"api_key"`
+	Site               string   `field:"site" default:"datadoghq.com"`
+	CACertFile         string   `field:"ca_cert_file"`
+	Tags               []string `field:"tags"`
+	SendMetrics        bool     `field:"send_metrics" default:"false"`
+	TimeoutSeconds     int      `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify bool     `field:"insecure_skip_verify" default:"false"`
+}
+
+// Payload is an event for the Datadog Events API
+type Payload struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	AlertType      string   `json:"alert_type"`
+	SourceTypeName string   `json:"source_type_name"`
+	AggregationKey string   `json:"aggregation_key,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+}
+
+type series struct {
+	Series []serie `json:"series"`
+}
+
+type serie struct {
+	Metric string   `json:"metric"`
+	Type   int      `json:"type"`
+	Points []point  `json:"points"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+type point struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+const (
+	eventsPath string = "/api/v1/events"
+	seriesPath string = "/api/v2/series"
+	metricName string = "falco_talon."
+	countType  int    = 1
+	maxTextLen int    = 4000
+)
+
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings  *Settings
+	transport *http.Transport
+}
+
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	var tlsConfig *tls.Config
+	if n.settings.CACertFile != "" || n.settings.InsecureSkipVerify {
+		var err error
+		if tlsConfig, err = http.NewTLSConfig("", "", n.settings.CACertFile, n.settings.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.settings.TimeoutSeconds)*time.Second)
+	return n, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	client := http.DefaultClient()
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
+	client.SetHeader("DD-API-KEY", n.settings.APIKey)
+
+	u := "https://api." + n.settings.Site
+
+	payload := NewPayload(n.settings, log)
+	text, ok, err := templates.Render("datadog", log)
+	if err != nil {
+		return err
+	}
+	if ok {
+		payload.Text = truncateText("%%%\n" + text + "\n%%%")
+	}
+
+	if err := client.Request(u+eventsPath, payload); err != nil {
+		return err
+	}
+
+	if n.settings.SendMetrics {
+		s := series{
+			Series: []serie{
+				{
+					Metric: metricName + log.Message,
+					Type:   countType,
+					Points: []point{{Timestamp: time.Now().Unix(), Value: 1}},
+					Tags:   getTags(n.settings, log),
+				},
+			},
+		}
+		if err := client.Request(u+seriesPath, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkSettings(settings *Settings) error {
+	if settings.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
+	if settings.APIKey == "" {
+		return errors.New("wrong `api_key` setting")
+	}
+	if settings.Site == "" {
+		return errors.New("wrong `site` setting")
+	}
+
+	return nil
+}
+
+func NewPayload(settings *Settings, log utils.LogLine) Payload {
+	title := fmt.Sprintf("[falco-talon][%v][%v]", log.Status, log.Message)
+	if log.Action != "" {
+		title += fmt.Sprintf(" Action '%v'", log.Action)
+	}
+	if log.Rule != "" {
+		title += fmt.Sprintf(" Rule '%v'", log.Rule)
+	}
+
+	var alertType string
+	switch log.Status {
+	case "failure":
+		alertType = "error"
+	case "success":
+		alertType = "success"
+	default:
+		alertType = "info"
+	}
+
+	text := "%%%\n"
+	if log.Output != "" {
+		text += fmt.Sprintf("**Output**: %v\n\n", log.Output)
+	}
+	if log.Result != "" {
+		text += fmt.Sprintf("**Result**: %v\n\n", log.Result)
+	}
+	if log.Error != "" {
+		text += fmt.Sprintf("**Error**: %v\n\n", log.Error)
+	}
+	if log.Event != "" {
+		text += fmt.Sprintf("**Event**: %v\n\n", log.Event)
+	}
+	if log.RawEvent != "" {
+		text += fmt.Sprintf("**Event JSON**:\n```\n%v\n```\n\n", log.RawEvent)
+	}
+	text += "\n%%%"
+
+	return Payload{
+		Title:          title,
+		Text:           truncateText(text),
+		AlertType:      alertType,
+		SourceTypeName: utils.FalcoTalonStr,
+		AggregationKey: log.TraceID,
+		Tags:           getTags(settings, log),
+	}
+}
+
+// truncateText keeps the markdown delimiters of the text over the limit of the API, the text is cut at the
+// start of a rune to stay valid UTF-8
+func truncateText(text string) string {
+	if len(text) <= maxTextLen {
+		return text
+	}
+	i := maxTextLen - 4
+	for i > 0 && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return text[:i] + "\n%%%"
+}
+
+func getTags(settings *Settings, log utils.LogLine) []string {
+	tags := append([]string{}, settings.Tags...)
+	tags = append(tags, "source:"+utils.FalcoTalonStr)
+	if log.Status != "" {
+		tags = append(tags, "status:"+log.Status)
+	}
+	if log.Rule != "" {
+		tags = append(tags, "rule:"+normalizeTag(log.Rule))
+	}
+	if log.Action != "" {
+		tags = append(tags, "action:"+normalizeTag(log.Action))
+	}
+	if log.Actionner != "" {
+		tags = append(tags, "actionner:"+log.Actionner)
+	}
+	if log.Target != "" {
+		tags = append(tags, "target:"+log.Target)
+	}
+	if log.TraceID != "" {
+		tags = append(tags, "trace_id:"+log.TraceID)
+	}
+	keys := make([]string, 0, len(log.Objects))
+	for i := range log.Objects {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	for _, i := range keys {
+		switch strings.ToLower(i) {
+		case "namespace":
+			tags = append(tags, "kube_namespace:"+log.Objects[i])
+		case "pod":
+			tags = append(tags, "pod_name:"+log.Objects[i])
+		case "node":
+			tags = append(tags, "host:"+log.Objects[i])
+		default:
+			tags = append(tags, strings.ToLower(i)+":"+log.Objects[i])
+		}
+	}
+	return tags
+}
+
+func normalizeTag(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), " ", "_")
+}
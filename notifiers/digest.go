@@ -0,0 +1,206 @@
+package notifiers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const defaultDigestInterval int = 10
+
+type digest struct {
+	notifier          *Notifier
+	immediateRules    map[string]bool
+	logs              []utils.LogLine
+	stop              chan struct{}
+	interval          time.Duration
+	immediatePriority int
+	mu                sync.Mutex
+}
+
+var (
+	digests   map[string]*digest
+	digestsMu sync.RWMutex
+)
+
+// initDigests sets the digests of the notifiers, the previous ones are stopped, their buffered notifications are
+// kept if the notifier still has a digest, sent otherwise
+func initDigests(config *configuration.Configuration) {
+	list := make(map[string]*digest)
+
+	for name, c := range config.Digests {
+		n := GetNotifiers().FindNotifier(strings.ToLower(name))
+		if n == nil {
+			continue
+		}
+		if c.IntervalMinutes <= 0 {
+			c.IntervalMinutes = defaultDigestInterval
+		}
+
+		d := &digest{
+			notifier:          n,
+			interval:          time.Duration(c.IntervalMinutes) * time.Minute,
+			immediatePriority: rules.GetPriorityNumber(c.ImmediatePriority),
+			immediateRules:    make(map[string]bool, len(c.ImmediateRules)),
+			stop:              make(chan struct{}),
+		}
+		for _, i := range c.ImmediateRules {
+			d.immediateRules[i] = true
+		}
+		list[n.Name] = d
+
+		go d.run()
+		utils.PrintLog("info", utils.LogLine{Notifier: n.Name, Message: "digest", Result: fmt.Sprintf("digest every %v minute(s)", c.IntervalMinutes)})
+	}
+
+	digestsMu.Lock()
+	previous := digests
+	digests = list
+	digestsMu.Unlock()
+
+	for name, i := range previous {
+		close(i.stop)
+		i.mu.Lock()
+		logs := i.logs
+		i.logs = nil
+		i.mu.Unlock()
+		if d, ok := list[name]; ok {
+			d.mu.Lock()
+			d.logs = append(logs, d.logs...)
+			d.mu.Unlock()
+			continue
+		}
+		i.logs = logs
+		go i.flush()
+	}
+}
+
+// isDigested returns true if the notification is buffered for the digest instead of being sent immediately
+func isDigested(notifier, rule, priority string, log utils.LogLine) bool {
+	digestsMu.RLock()
+	d, ok := digests[notifier]
+	digestsMu.RUnlock()
+	if !ok {
+		return false
+	}
+	if d.immediateRules[rule] {
+		return false
+	}
+	if d.immediatePriority != 0 && rules.GetPriorityNumber(priority) >= d.immediatePriority {
+		return false
+	}
+
+	d.mu.Lock()
+	d.logs = append(d.logs, log)
+	d.mu.Unlock()
+
+	return true
+}
+
+// run sends the digest at each interval, until the digest is replaced
+func (d *digest) run() {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.flush()
+		}
+	}
+}
+
+// flush sends the buffered notifications
+func (d *digest) flush() {
+	d.mu.Lock()
+	logs := d.logs
+	d.logs = nil
+	d.mu.Unlock()
+
+	if len(logs) == 0 {
+		return
+	}
+
+	logN := utils.LogLine{
+		Message:  "notification",
+		Notifier: d.notifier.Name,
+		Result:   fmt.Sprintf("digest of %v notification(s)", len(logs)),
+	}
+	if err := sendFor("", d.notifier, summarize(logs, fmt.Sprintf("during the last %v", d.interval))); err != nil {
+		logN.Status = "failure"
+		logN.Error = err.Error()
+		utils.PrintLog("error", logN)
+		return
+	}
+	logN.Status = "success"
+	utils.PrintLog("info", logN)
+}
+
+// Flush sends the notifications buffered for the digests and queued by the quiet hours, then the batches of the
+// instances, before the shutdown
+func Flush() {
+	digestsMu.RLock()
+	list := make([]*digest, 0, len(digests))
+	for _, i := range digests {
+		list = append(list, i)
+	}
+	digestsMu.RUnlock()
+	for _, i := range list {
+		i.flush()
+	}
+
+	routingsMu.RLock()
+	routes := make([]*routing, 0, len(routings))
+	for _, i := range routings {
+		routes = append(routes, i)
+	}
+	routingsMu.RUnlock()
+	for _, i := range routes {
+		i.flush()
+	}
+
+	flushInstances(routes)
+}
+
+// summarize builds a single notification with the count of notifications by rule, action and status
+func summarize(logs []utils.LogLine, period string) utils.LogLine {
+	type key struct {
+		rule, action, status string
+	}
+	counts := make(map[key]int)
+	failures := 0
+	for _, i := range logs {
+		counts[key{i.Rule, i.Action, i.Status}]++
+		if i.Status == "failure" {
+			failures++
+		}
+	}
+
+	lines := make([]string, 0, len(counts))
+	for i, j := range counts {
+		lines = append(lines, fmt.Sprintf("- rule '%v', action '%v': %v %v", i.rule, i.action, j, i.status))
+	}
+	sort.Strings(lines)
+
+	status := "success"
+	if failures != 0 {
+		status = "failure"
+	}
+
+	return utils.LogLine{
+		Message: "digest",
+		Status:  status,
+		Objects: map[string]string{
+			"Notifications": fmt.Sprintf("%v", len(logs)),
+			"Failures":      fmt.Sprintf("%v", failures),
+		},
+		Output: fmt.Sprintf("%v notification(s) %v:\n%v", len(logs), period, strings.Join(lines, "\n")),
+	}
+}
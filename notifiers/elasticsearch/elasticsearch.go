@@ -1,13 +1,19 @@
 package elasticsearch
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/falco-talon/falco-talon/metrics"
 	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/notifiers/schema"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
@@ -18,71 +24,185 @@ type Settings struct {
 	Password            string            `field:"password"`
 	Suffix              string            `field:"suffix" default:"daily"`
 	Index               string            `field:"index" default:"falco-talon"`
+	ILMPolicy           string            `field:"ilm_policy"`
+	ILMRolloverMaxAge   string            `field:"ilm_rollover_max_age" default:"1d"`
+	ILMDeleteAfter      string            `field:"ilm_delete_after" default:"30d"`
+	Schema              string            `field:"schema"`
+	CACertFile          string            `field:"ca_cert_file"`
 	NumberOfShards      int               `field:"number_of_shards" default:"3"`
 	NumberOfReplicas    int               `field:"number_of_replicas" default:"3"`
+	BatchSize           int               `field:"batch_size" default:"1"`
+	FlushInterval       int               `field:"flush_interval_seconds" default:"5"`
+	MaxBufferSize       int               `field:"max_buffer_size" default:"10000"`
 	CreateIndexTemplate bool              `field:"create_index_template" default:"true"`
+	CreateILMPolicy     bool              `field:"create_ilm_policy" default:"false"`
+	DataStream          bool              `field:"data_stream" default:"false"`
+	TimeoutSeconds      int               `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify  bool              `field:"insecure_skip_verify" default:"false"`
 }
 
-const docType string = "/_doc"
-const indexTemplate string = "/_index_template/falco-talon"
+// document adds the @timestamp field required by the data streams
+type document struct {
+	Timestamp string `json:"@timestamp"`
+	utils.LogLine
+}
+
+// entry is a buffered document, with its timestamp for the name of its index
+type entry struct {
+	time     time.Time
+	document interface{}
+}
+
+type bulkResponse struct {
+	Items []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+	Errors bool `json:"errors"`
+}
+
+const (
+	bulkPath          string = "/_bulk"
+	indexTemplatePath string = "/_index_template/"
+	ilmPolicyPath     string = "/_ilm/policy/"
+	ndjsonContentType string = "application/x-ndjson"
+)
 
-var settings *Settings
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own, the index
+// template and the ILM policy are created once, when it's inited
+type Notifier struct {
+	settings  *Settings
+	transport *http.Transport
+	stop      chan struct{}
+	batch     []entry
+	mu        sync.Mutex
+}
 
-func Init(fields map[string]interface{}) error {
-	settings = new(Settings)
-	settings = utils.SetFields(settings, fields).(*Settings)
-	if err := checkSettings(settings); err != nil {
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	var tlsConfig *tls.Config
+	if n.settings.CACertFile != "" || n.settings.InsecureSkipVerify {
+		var err error
+		if tlsConfig, err = http.NewTLSConfig("", "", n.settings.CACertFile, n.settings.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+	if n.settings.CreateILMPolicy && n.settings.ILMPolicy != "" {
+		if err := n.createILMPolicy(); err != nil {
+			return nil, err
+		}
+	}
+	if n.settings.CreateIndexTemplate {
+		if err := n.createIndexTemplate(); err != nil {
+			return nil, err
+		}
+	}
+	if n.settings.BatchSize > 1 {
+		n.stop = make(chan struct{})
+		go n.run(time.Duration(n.settings.FlushInterval) * time.Second)
+	}
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.settings.TimeoutSeconds)*time.Second)
+	return n, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	now := time.Now()
+	log.Time = now.Format(time.RFC3339)
+	var d interface{} = document{
+		Timestamp: log.Time,
+		LogLine:   log,
+	}
+	if n.settings.Schema == schema.ECS {
+		d = schema.NewECSEvent(log)
+	}
+
+	e := entry{time: now, document: d}
+
+	if n.settings.BatchSize <= 1 {
+		_, err := n.bulk(ctx, []entry{e})
 		return err
 	}
-	if settings.CreateIndexTemplate {
-		client := http.NewClient("GET", "", "", settings.CustomHeaders)
-		if settings.User != "" && settings.Password != "" {
-			client.SetBasicAuth(settings.User, settings.Password)
-		}
-		if err := client.Request(settings.URL+indexTemplate, nil); err != nil {
-			if err.Error() == "resource not found" {
-				client.SetHTTPMethod("PUT")
-				m := strings.ReplaceAll(mapping, "${SHARDS}", fmt.Sprintf("%v", settings.NumberOfShards))
-				m = strings.ReplaceAll(m, "${REPLICAS}", fmt.Sprintf("%v", settings.NumberOfReplicas))
-				j := make(map[string]interface{})
-				if err := json.Unmarshal([]byte(m), &j); err != nil {
-					return err
-				}
-				if err := client.Request(settings.URL+indexTemplate, j); err != nil {
-					return err
-				}
+
+	n.mu.Lock()
+	n.batch = append(n.batch, e)
+	full := len(n.batch) >= n.settings.BatchSize
+	n.mu.Unlock()
+
+	if full {
+		return n.Flush()
+	}
+	return nil
+}
+
+// run sends the batch at each interval, until the notifier is closed
+func (n *Notifier) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			if err := n.Flush(); err != nil {
+				utils.PrintLog("error", utils.LogLine{Notifier: "elasticsearch", Message: "notification", Error: err.Error(), Status: "failure"})
 			}
 		}
 	}
-	return nil
 }
 
-func Notify(log utils.LogLine) error {
-	client := http.DefaultClient()
+// Flush sends the buffered documents
+func (n *Notifier) Flush() error {
+	n.mu.Lock()
+	d := n.batch
+	n.batch = nil
+	n.mu.Unlock()
 
-	current := time.Now()
-	var u string
-	switch settings.Suffix {
-	case "none":
-		u = settings.URL + "/" + settings.Index + docType
-	case "monthly":
-		u = settings.URL + "/" + settings.Index + "-" + current.Format("2006.01") + docType
-	case "annually":
-		u = settings.URL + "/" + settings.Index + "-" + current.Format("2006") + docType
-	default:
-		u = settings.URL + "/" + settings.Index + "-" + current.Format("2006.01.02") + docType
+	if len(d) == 0 {
+		return nil
+	}
+	retry, err := n.bulk(context.Background(), d)
+	if len(retry) != 0 {
+		n.requeue(retry)
 	}
+	return err
+}
 
-	log.Time = time.Now().Format(time.RFC3339)
+// requeue puts the documents of a failed batch back in front of the buffer, they're sent with the next batch, the
+// oldest documents are dropped beyond `max_buffer_size`
+func (n *Notifier) requeue(entries []entry) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 
-	if err := client.Request(u, log); err != nil {
-		return err
+	n.batch = append(entries, n.batch...)
+	over := len(n.batch) - n.settings.MaxBufferSize
+	if over <= 0 {
+		return
 	}
+	n.batch = n.batch[over:]
+	metrics.IncreaseCounter(utils.LogLine{Message: "dropped_notification", Notifier: "elasticsearch", Status: "buffer_full"})
+	utils.PrintLog("warning", utils.LogLine{Notifier: "elasticsearch", Message: "notification", Result: fmt.Sprintf("%v document(s) dropped, the buffer is full", over)})
+}
 
-	return nil
+// Close stops the flushes at regular intervals and sends the buffered documents, when the notifier is replaced
+func (n *Notifier) Close() {
+	if n.stop != nil {
+		close(n.stop)
+	}
+	if err := n.Flush(); err != nil {
+		utils.PrintLog("error", utils.LogLine{Notifier: "elasticsearch", Message: "notification", Error: err.Error(), Status: "failure"})
+	}
 }
 
 func checkSettings(settings *Settings) error {
+	if settings.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
 	if settings.URL == "" {
 		return errors.New("wrong `url` setting")
 	}
@@ -92,6 +212,19 @@ func checkSettings(settings *Settings) error {
 	if settings.NumberOfReplicas < 1 {
 		return errors.New("wrong `number_of_replcicas` setting")
 	}
+	if settings.BatchSize > 1 && settings.FlushInterval < 1 {
+		return errors.New("wrong `flush_interval_seconds` setting")
+	}
+	if settings.BatchSize > 1 && settings.MaxBufferSize < settings.BatchSize {
+		return errors.New("wrong `max_buffer_size` setting, it can't be lower than `batch_size`")
+	}
+	if settings.CreateILMPolicy && settings.ILMPolicy == "" {
+		return errors.New("wrong `ilm_policy` setting")
+	}
+	// the documents must have a @timestamp field, OCSF is not supported
+	if settings.Schema != "" && settings.Schema != schema.ECS {
+		return errors.New("wrong `schema` setting, must be 'ecs'")
+	}
 
 	if err := http.CheckURL(settings.URL); err != nil {
 		return err
@@ -99,3 +232,139 @@ func checkSettings(settings *Settings) error {
 
 	return nil
 }
+
+func (n *Notifier) newClient(ctx context.Context, method, contentType string) http.Client {
+	client := http.NewClient(method, contentType, "", n.settings.CustomHeaders)
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
+	if n.settings.User != "" && n.settings.Password != "" {
+		client.SetBasicAuth(n.settings.User, n.settings.Password)
+	}
+	return client
+}
+
+func (n *Notifier) getIndex(t time.Time) string {
+	if n.settings.DataStream {
+		return n.settings.Index
+	}
+	switch n.settings.Suffix {
+	case "none":
+		return n.settings.Index
+	case "monthly":
+		return n.settings.Index + "-" + t.Format("2006.01")
+	case "annually":
+		return n.settings.Index + "-" + t.Format("2006")
+	default:
+		return n.settings.Index + "-" + t.Format("2006.01.02")
+	}
+}
+
+// bulk sends the documents with the bulk API, each one in the index of its timestamp, the data streams accept only
+// the 'create' operation, it returns the documents to send again: all of them if the request failed, those rejected
+// with a 429 or a 5xx otherwise, the others are rejected for good
+func (n *Notifier) bulk(ctx context.Context, entries []entry) ([]entry, error) {
+	op := "index"
+	if n.settings.DataStream {
+		op = "create"
+	}
+
+	body := new(bytes.Buffer)
+	for _, i := range entries {
+		meta := map[string]map[string]string{op: {"_index": n.getIndex(i.time)}}
+		if err := json.NewEncoder(body).Encode(meta); err != nil {
+			return nil, err
+		}
+		if err := json.NewEncoder(body).Encode(i.document); err != nil {
+			return nil, err
+		}
+	}
+
+	client := n.newClient(ctx, "POST", ndjsonContentType)
+	resp, err := client.RequestBytesWithResponse(n.settings.URL+bulkPath, body.Bytes())
+	if err != nil {
+		return entries, err
+	}
+
+	var r bulkResponse
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return nil, err
+	}
+	if !r.Errors {
+		return nil, nil
+	}
+
+	var retry []entry
+	var count int
+	var reason string
+	// the items are in the order of the documents
+	for k, i := range r.Items {
+		for _, j := range i {
+			if j.Status < 300 {
+				continue
+			}
+			count++
+			reason = j.Error.Type + ": " + j.Error.Reason
+			if (j.Status == 429 || j.Status >= 500) && k < len(entries) {
+				retry = append(retry, entries[k])
+			}
+		}
+	}
+	return retry, fmt.Errorf("%v/%v document(s) rejected, %v sent again, last error: %v", count, len(entries), len(retry), reason)
+}
+
+func (n *Notifier) createIndexTemplate() error {
+	client := n.newClient(context.Background(), "GET", "")
+	if err := client.Request(n.settings.URL+indexTemplatePath+n.settings.Index, nil); err != nil {
+		if err.Error() != http.ErrNotFound.Error() {
+			return nil
+		}
+		client.SetHTTPMethod("PUT")
+		m := strings.ReplaceAll(mapping, "${SHARDS}", fmt.Sprintf("%v", n.settings.NumberOfShards))
+		m = strings.ReplaceAll(m, "${REPLICAS}", fmt.Sprintf("%v", n.settings.NumberOfReplicas))
+		m = strings.ReplaceAll(m, "${INDEX}", n.settings.Index)
+		j := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(m), &j); err != nil {
+			return err
+		}
+		if n.settings.DataStream {
+			j["data_stream"] = map[string]interface{}{}
+		}
+		if n.settings.ILMPolicy != "" {
+			t := j["template"].(map[string]interface{})
+			t["settings"].(map[string]interface{})["index.lifecycle.name"] = n.settings.ILMPolicy
+		}
+		if err := client.Request(n.settings.URL+indexTemplatePath+n.settings.Index, j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) createILMPolicy() error {
+	client := n.newClient(context.Background(), "GET", "")
+	if err := client.Request(n.settings.URL+ilmPolicyPath+n.settings.ILMPolicy, nil); err != nil {
+		if err.Error() != http.ErrNotFound.Error() {
+			return nil
+		}
+		hot := map[string]interface{}{}
+		if n.settings.DataStream && n.settings.ILMRolloverMaxAge != "" {
+			hot["rollover"] = map[string]interface{}{"max_age": n.settings.ILMRolloverMaxAge}
+		}
+		phases := map[string]interface{}{
+			"hot": map[string]interface{}{"actions": hot},
+		}
+		if n.settings.ILMDeleteAfter != "" {
+			phases["delete"] = map[string]interface{}{
+				"min_age": n.settings.ILMDeleteAfter,
+				"actions": map[string]interface{}{"delete": map[string]interface{}{}},
+			}
+		}
+		client.SetHTTPMethod("PUT")
+		if err := client.Request(n.settings.URL+ilmPolicyPath+n.settings.ILMPolicy, map[string]interface{}{
+			"policy": map[string]interface{}{"phases": phases},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
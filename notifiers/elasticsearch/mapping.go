@@ -2,7 +2,7 @@ package elasticsearch
 
 var mapping = `
 {
-    "index_patterns": ["falco-talon*"],
+    "index_patterns": ["${INDEX}*"],
     "template": {
       "settings": {
         "number_of_shards": ${SHARDS},
@@ -13,6 +13,9 @@ var mapping = `
           "enabled": true
         },
         "properties": {
+          "@timestamp": {
+            "type": "date"
+          },
           "action": {
             "type": "text",
             "fields": {
@@ -0,0 +1,140 @@
+package eventbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+
+	aws "github.com/falco-talon/falco-talon/internal/aws/client"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Settings struct {
+	EventBusName string `field:"event_bus_name" default:"default"`
+	Source       string `field:"source" default:"falco-talon"`
+	Region       string `field:"region"`
+}
+
+// Detail is the stable schema of the events sent to EventBridge,
+// it can be used in the patterns of the EventBridge rules
+type Detail struct {
+	Version   string            `json:"version"`
+	Time      string            `json:"time"`
+	Status    string            `json:"status"`
+	Message   string            `json:"message"`
+	Rule      string            `json:"rule,omitempty"`
+	Action    string            `json:"action,omitempty"`
+	Actionner string            `json:"actionner,omitempty"`
+	Target    string            `json:"target,omitempty"`
+	Event     string            `json:"event,omitempty"`
+	Objects   map[string]string `json:"objects,omitempty"`
+	Output    string            `json:"output,omitempty"`
+	Result    string            `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	TraceID   string            `json:"trace_id,omitempty"`
+}
+
+const detailVersion string = "1"
+
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings *Settings
+}
+
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+	if err := aws.Init(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	client := aws.GetEventBridgeClient()
+	if client == nil {
+		return errors.New("client error")
+	}
+
+	detail, err := json.Marshal(NewDetail(log))
+	if err != nil {
+		return err
+	}
+
+	input := &eventbridge.PutEventsInput{
+		Entries: []types.PutEventsRequestEntry{
+			{
+				Source:       awssdk.String(n.settings.Source),
+				DetailType:   awssdk.String(GetDetailType(log)),
+				Detail:       awssdk.String(string(detail)),
+				EventBusName: awssdk.String(n.settings.EventBusName),
+			},
+		},
+	}
+
+	output, err := client.PutEvents(ctx, input, func(o *eventbridge.Options) {
+		if n.settings.Region != "" {
+			o.Region = n.settings.Region
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if output.FailedEntryCount != 0 && len(output.Entries) != 0 {
+		return fmt.Errorf("%v: %v", awssdk.ToString(output.Entries[0].ErrorCode), awssdk.ToString(output.Entries[0].ErrorMessage))
+	}
+
+	return nil
+}
+
+func checkSettings(settings *Settings) error {
+	if settings.EventBusName == "" {
+		return errors.New("wrong `event_bus_name` setting")
+	}
+	if settings.Source == "" {
+		return errors.New("wrong `source` setting")
+	}
+
+	return nil
+}
+
+// GetDetailType returns the detail-type of the event, it depends on the step
+// of the workflow the notification comes from
+func GetDetailType(log utils.LogLine) string {
+	switch log.Message {
+	case "action":
+		return "Falco Talon Action"
+	case "output":
+		return "Falco Talon Output"
+	default:
+		return "Falco Talon Notification"
+	}
+}
+
+func NewDetail(log utils.LogLine) Detail {
+	return Detail{
+		Version:   detailVersion,
+		Time:      time.Now().Format(time.RFC3339),
+		Status:    log.Status,
+		Message:   log.Message,
+		Rule:      log.Rule,
+		Action:    log.Action,
+		Actionner: log.Actionner,
+		Target:    log.Target,
+		Event:     log.Event,
+		Objects:   log.Objects,
+		Output:    log.Output,
+		Result:    log.Result,
+		Error:     log.Error,
+		TraceID:   log.TraceID,
+	}
+}
@@ -0,0 +1,201 @@
+package falcosidekick
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/notifiers/templates"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Settings struct {
+	CustomHeaders      map[string]string `field:"custom_headers"`
+	Address            string            `field:"address"`
+	ClientCertFile     string            `field:"client_cert_file"`
+	ClientKeyFile      string            `field:"client_key_file"`
+	CACertFile         string            `field:"ca_cert_file"`
+	ForwardEvents      bool              `field:"forward_events" default:"true"`
+	TimeoutSeconds     int               `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify bool              `field:"insecure_skip_verify" default:"false"`
+}
+
+// Payload is the model of the events of Falco, as received by falcosidekick
+type Payload struct {
+	Time         time.Time              `json:"time"`
+	OutputFields map[string]interface{} `json:"output_fields"`
+	UUID         string                 `json:"uuid,omitempty"`
+	Output       string                 `json:"output"`
+	Priority     string                 `json:"priority"`
+	Rule         string                 `json:"rule"`
+	Source       string                 `json:"source"`
+	Hostname     string                 `json:"hostname,omitempty"`
+	Tags         []interface{}          `json:"tags,omitempty"`
+}
+
+// ForwardedTag marks the events forwarded by Talon, they aren't forwarded again if falcosidekick sends them back
+const ForwardedTag string = "falco-talon:forwarded"
+
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings  *Settings
+	transport *http.Transport
+}
+
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	var tlsConfig *tls.Config
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+	if n.settings.ClientCertFile != "" || n.settings.CACertFile != "" || n.settings.InsecureSkipVerify {
+		var err error
+		if tlsConfig, err = http.NewTLSConfig(n.settings.ClientCertFile, n.settings.ClientKeyFile, n.settings.CACertFile, n.settings.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
+	}
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.settings.TimeoutSeconds)*time.Second)
+	return n, nil
+}
+
+func checkSettings(settings *Settings) error {
+	if settings.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
+	if settings.Address == "" {
+		return errors.New("wrong `address` setting")
+	}
+	if err := http.CheckURL(settings.Address); err != nil {
+		return err
+	}
+	if (settings.ClientCertFile == "") != (settings.ClientKeyFile == "") {
+		return errors.New("`client_cert_file` and `client_key_file` must be set together")
+	}
+	return nil
+}
+
+// Notify sends the result of the action as an event of the source `falco-talon`, the outputs of falcosidekick
+// receive it as the events of Falco
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	return n.post(ctx, NewResult(log))
+}
+
+// IsForwarding returns true if the events received by Talon are forwarded
+func (n *Notifier) IsForwarding() bool {
+	return n.settings != nil && n.settings.ForwardEvents
+}
+
+// Forward sends the event received by Talon with the tag of the forwarded events, with the trace id as uuid if it
+// hasn't one, to correlate the logs of both
+func (n *Notifier) Forward(event *events.Event) error {
+	if !n.IsForwarding() {
+		return nil
+	}
+	return n.post(context.Background(), NewPayload(event))
+}
+
+func (n *Notifier) post(ctx context.Context, payload Payload) error {
+	client := http.NewClient("", "", "", n.settings.CustomHeaders)
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
+	return client.Request(strings.TrimSuffix(n.settings.Address, "/")+"/", payload)
+}
+
+// IsForwarded returns true if the event is a result of Talon or an event it already forwarded
+func IsForwarded(event *events.Event) bool {
+	if event.Source == utils.FalcoTalonStr {
+		return true
+	}
+	for _, i := range event.Tags {
+		if fmt.Sprintf("%v", i) == ForwardedTag {
+			return true
+		}
+	}
+	return false
+}
+
+func NewPayload(event *events.Event) Payload {
+	id := event.UUID
+	if id == "" {
+		id = event.TraceID
+	}
+	tags := make([]interface{}, 0, len(event.Tags)+1)
+	tags = append(tags, event.Tags...)
+	tags = append(tags, ForwardedTag)
+	return Payload{
+		UUID:         id,
+		Output:       event.Output,
+		Priority:     event.Priority,
+		Rule:         event.Rule,
+		Time:         event.Time,
+		OutputFields: event.OutputFields,
+		Source:       event.Source,
+		Hostname:     event.Hostname,
+		Tags:         tags,
+	}
+}
+
+// NewResult converts the result of an action into an event, the fields of the result are the output fields
+// prefixed with `talon.`
+func NewResult(log utils.LogLine) Payload {
+	var priority string
+	switch log.Status {
+	case "failure":
+		priority = "Error"
+	case "success":
+		priority = "Notice"
+	default:
+		priority = "Informational"
+	}
+
+	fields := map[string]interface{}{
+		"talon.status": log.Status,
+		"talon.step":   log.Message,
+	}
+	for i, j := range map[string]string{
+		"talon.rule":        log.Rule,
+		"talon.action":      log.Action,
+		"talon.actionner":   log.Actionner,
+		"talon.event":       log.Event,
+		"talon.target":      log.Target,
+		"talon.result":      log.Result,
+		"talon.output":      log.Output,
+		"talon.error":       log.Error,
+		"talon.trace_id":    log.TraceID,
+		"talon.incident_id": log.IncidentID,
+	} {
+		if j != "" {
+			fields[i] = j
+		}
+	}
+	for i, j := range log.Objects {
+		fields["talon."+strings.ToLower(i)] = j
+	}
+
+	output, err := templates.RenderPartial("title", log)
+	if err != nil {
+		output = log.Status + " " + log.Message
+	}
+
+	hostname, _ := os.Hostname()
+
+	return Payload{
+		UUID:         uuid.NewString(),
+		Output:       output,
+		Priority:     priority,
+		Rule:         log.Rule,
+		Time:         time.Now().UTC(),
+		OutputFields: fields,
+		Source:       utils.FalcoTalonStr,
+		Hostname:     hostname,
+		Tags:         []interface{}{utils.FalcoTalonStr, log.Status},
+	}
+}
@@ -0,0 +1,244 @@
+package file
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Settings struct {
+	Path                string `field:"path"`
+	MaxSizeMB           int    `field:"max_size_mb" default:"100"`
+	RotateIntervalHours int    `field:"rotate_interval_hours" default:"0"`
+	MaxBackups          int    `field:"max_backups" default:"5"`
+	Compress            bool   `field:"compress" default:"true"`
+}
+
+// the nanoseconds keep the names unique, their fixed width keeps the lexical order chronological
+const rotationTimeFormat string = "2006-01-02T15-04-05.000000000"
+
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings *Settings
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	mu       sync.Mutex
+}
+
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+
+	if err := n.open(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *Notifier) Notify(_ context.Context, log utils.LogLine) error {
+	log.Time = time.Now().Format(time.RFC3339)
+	b, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.file == nil {
+		if err := n.open(); err != nil {
+			return err
+		}
+	}
+
+	if n.mustRotate(int64(len(b))) {
+		if err := n.rotate(); err != nil {
+			return err
+		}
+	}
+
+	w, err := n.file.Write(b)
+	n.size += int64(w)
+	return err
+}
+
+// Close closes the file, when the notifier is replaced
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.file == nil {
+		return
+	}
+	if err := n.file.Close(); err != nil {
+		utils.PrintLog("error", utils.LogLine{Notifier: "file", Message: "notification", Error: err.Error(), Status: "failure"})
+	}
+	n.file = nil
+}
+
+func checkSettings(settings *Settings) error {
+	if settings.Path == "" {
+		return errors.New("wrong `path` setting")
+	}
+	if settings.MaxSizeMB < 0 {
+		return errors.New("wrong `max_size_mb` setting")
+	}
+	if settings.RotateIntervalHours < 0 {
+		return errors.New("wrong `rotate_interval_hours` setting")
+	}
+	if settings.MaxBackups < 0 {
+		return errors.New("wrong `max_backups` setting")
+	}
+	if _, err := os.Stat(filepath.Dir(settings.Path)); os.IsNotExist(err) {
+		return fmt.Errorf("folder '%v' does not exist", filepath.Dir(settings.Path))
+	}
+
+	return nil
+}
+
+func (n *Notifier) open() error {
+	f, err := os.OpenFile(n.settings.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	n.file = f
+	n.size = info.Size()
+	n.openedAt = time.Now()
+	return nil
+}
+
+func (n *Notifier) mustRotate(length int64) bool {
+	if n.settings.MaxSizeMB > 0 && n.size+length > int64(n.settings.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	if n.settings.RotateIntervalHours > 0 && time.Since(n.openedAt) > time.Duration(n.settings.RotateIntervalHours)*time.Hour {
+		return true
+	}
+	return false
+}
+
+// rotate renames the current file with a timestamp, compresses it if enabled
+// and removes the oldest backups
+func (n *Notifier) rotate() error {
+	if err := n.file.Close(); err != nil {
+		return err
+	}
+	n.file = nil
+
+	backup, err := n.getBackupName()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(n.settings.Path, backup); err != nil {
+		return err
+	}
+
+	if err := n.open(); err != nil {
+		return err
+	}
+
+	go func() {
+		if n.settings.Compress {
+			if err := compress(backup); err != nil {
+				utils.PrintLog("error", utils.LogLine{Notifier: "file", Message: "rotation", Error: err.Error(), Status: "failure"})
+			}
+		}
+		if err := n.cleanBackups(); err != nil {
+			utils.PrintLog("error", utils.LogLine{Notifier: "file", Message: "rotation", Error: err.Error(), Status: "failure"})
+		}
+	}()
+
+	return nil
+}
+
+// getBackupName returns the name of the next backup, with a sequence number if a backup, compressed or not, already
+// has its timestamp, an existing backup is never overwritten
+func (n *Notifier) getBackupName() (string, error) {
+	name := fmt.Sprintf("%v.%v", n.settings.Path, time.Now().Format(rotationTimeFormat))
+	for i := 0; i < 100; i++ {
+		backup := name
+		if i > 0 {
+			backup = fmt.Sprintf("%v-%02d", name, i)
+		}
+		if !exists(backup) && !exists(backup+".gz") {
+			return backup, nil
+		}
+	}
+	return "", fmt.Errorf("no free name for the backup '%v'", name)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
+
+func compress(src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(src+".gz", os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := gzip.NewWriter(out)
+	if _, err := io.Copy(w, in); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+func (n *Notifier) cleanBackups() error {
+	if n.settings.MaxBackups == 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(n.settings.Path + ".*")
+	if err != nil {
+		return err
+	}
+
+	// the names of the backups contain their timestamp, the lexical order is the chronological order
+	sort.Strings(backups)
+	for len(backups) > n.settings.MaxBackups {
+		if !strings.HasSuffix(backups[0], ".gz") && n.settings.Compress {
+			// still being compressed
+			backups = backups[1:]
+			continue
+		}
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
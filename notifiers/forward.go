@@ -0,0 +1,67 @@
+package notifiers
+
+import (
+	"sync"
+
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/notifiers/falcosidekick"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// the events waiting to be forwarded, they're dropped if falcosidekick is too slow, the intake isn't blocked
+const forwardQueueSize int = 1000
+
+var (
+	forwardQueue = make(chan *events.Event, forwardQueueSize)
+	forwardOnce  sync.Once
+)
+
+// Forward queues the event received by Talon for falcosidekick, if its notifier forwards the events, the test
+// events and those already forwarded aren't
+func Forward(event *events.Event) {
+	if GetNotifiers().FindNotifier("falcosidekick") == nil || event.IsTest() || falcosidekick.IsForwarded(event) {
+		return
+	}
+
+	forwardOnce.Do(func() { go runForward() })
+
+	select {
+	case forwardQueue <- event:
+	default:
+		metrics.IncreaseCounter(utils.LogLine{Message: "dropped_notification", Notifier: "falcosidekick", Event: event.Rule, Status: "queue_full"})
+	}
+}
+
+// runForward sends the queued events with the global settings of the notifier
+func runForward() {
+	for event := range forwardQueue {
+		f := GetNotifiers().FindNotifier("falcosidekick")
+		if f == nil {
+			continue
+		}
+		n, _ := getInstance(f, "").(*falcosidekick.Notifier)
+		if n == nil || !n.IsForwarding() {
+			continue
+		}
+		err := n.Forward(event)
+
+		logN := utils.LogLine{
+			Message:  "notification",
+			Notifier: "falcosidekick",
+			Event:    event.Rule,
+			Result:   "event forwarded",
+			TraceID:  event.TraceID,
+		}
+		if err != nil {
+			logN.Status = "failure"
+			logN.Error = err.Error()
+			utils.PrintLog("error", logN)
+			metrics.IncreaseCounter(logN)
+			continue
+		}
+		logN.Status = "success"
+		utils.PrintLog("debug", logN)
+		metrics.IncreaseCounter(logN)
+	}
+}
@@ -2,6 +2,9 @@ package http
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -9,8 +12,12 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"regexp"
+	"time"
 
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
@@ -21,14 +28,51 @@ var ErrNotFound = errors.New("resource not found")                    // ErrNotF
 var ErrUnprocessableEntityError = errors.New("wrong request")         // ErrUnprocessableEntityError = 422
 var ErrTooManyRequest = errors.New("exceeding post rate limit")       // ErrTooManyRequest = 429
 
+// StatusError is returned for the status codes without their own error
+type StatusError struct {
+	Status string
+	Code   int
+}
+
+func (e *StatusError) Error() string {
+	return e.Status
+}
+
 const DefaultContentType = "application/json; charset=utf-8"
 const DefaultHTTPMethod = "POST"
 const DefaultUserAgent = "falco-talon"
 
+// DefaultTimeout is the timeout of the requests sent without a transport
+const DefaultTimeout = 10 * time.Second
+
 type Client struct {
-	Headers    http.Header
-	HTTPMethod string
-	Compressed bool
+	Headers      http.Header
+	TLSConfig    *tls.Config
+	transport    *Transport
+	ctx          context.Context
+	HTTPMethod   string
+	SuccessCodes []int
+	Compressed   bool
+}
+
+// Transport sends the requests of a notifier, it's built once with its TLS configuration and its timeout, the
+// connections are reused between the requests
+type Transport struct {
+	client *http.Client
+}
+
+// NewTransport returns the transport of a notifier, its requests fail after the timeout
+func NewTransport(tlsConfig *tls.Config, timeout time.Duration) *Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig
+	}
+	return &Transport{
+		client: &http.Client{
+			Transport: t,
+			Timeout:   timeout,
+		},
+	}
 }
 
 func CheckURL(u string) error {
@@ -49,6 +93,34 @@ func CheckURL(u string) error {
 	return nil
 }
 
+// NewTLSConfig creates a TLS configuration with an optional client certificate (mTLS) and CA
+func NewTLSConfig(certFile, keyFile, caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	cfg := tlspolicy.Apply(&tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
+		RootCAs:            outbound.RootCAs(),
+	})
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("invalid CA certificate")
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
 func DefaultClient() Client {
 	h := http.Header{}
 	h.Set("Content-Type", DefaultContentType)
@@ -74,6 +146,26 @@ func (c *Client) SetHeader(key, value string) {
 	c.Headers.Set(key, value)
 }
 
+// SetTLSConfig sets the TLS configuration of the transport, eg: for mTLS
+func (c *Client) SetTLSConfig(cfg *tls.Config) {
+	c.TLSConfig = cfg
+}
+
+// SetTransport sets the transport sending the requests, shared by the requests of the notifier
+func (c *Client) SetTransport(t *Transport) {
+	c.transport = t
+}
+
+// SetContext sets the context of the requests, they're cancelled with it
+func (c *Client) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// SetSuccessCodes sets the status codes of the responses considered as a success
+func (c *Client) SetSuccessCodes(codes []int) {
+	c.SuccessCodes = codes
+}
+
 func (c *Client) DeleteHeader(key string) {
 	c.Headers.Del(key)
 }
@@ -110,13 +202,6 @@ func NewClient(httpMethod, contentType, userAgent string, headers map[string]str
 }
 
 func (c *Client) Request(u string, payload interface{}) error {
-	// defer + recover to catch panic if output doesn't respond
-	defer func() {
-		if err := recover(); err != nil {
-			utils.PrintLog("error", utils.LogLine{Error: "recover"})
-		}
-	}()
-
 	body := new(bytes.Buffer)
 
 	if c.HTTPMethod != "GET" {
@@ -125,43 +210,96 @@ func (c *Client) Request(u string, payload interface{}) error {
 		}
 	}
 
-	client := &http.Client{
-		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+	return c.RequestBytes(u, body.Bytes())
+}
+
+// RequestBytes sends the body as it is, for the payloads which are not a single JSON document
+func (c *Client) RequestBytes(u string, b []byte) error {
+	_, err := c.RequestBytesWithResponse(u, b)
+	return err
+}
+
+// RequestBytesWithResponse sends the body as it is and returns the body of the response
+func (c *Client) RequestBytesWithResponse(u string, b []byte) (respBody []byte, err error) {
+	// defer + recover to catch panic if output doesn't respond
+	defer func() {
+		if r := recover(); r != nil {
+			utils.PrintLog("error", utils.LogLine{Error: "recover"})
+			err = errors.New("recover")
+		}
+	}()
+
+	body := bytes.NewReader(b)
+
+	t := c.transport
+	if t == nil {
+		t = NewTransport(c.TLSConfig, DefaultTimeout)
+	}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
 	}
 
-	req, err := http.NewRequest(c.HTTPMethod, u, body)
+	req, err := http.NewRequestWithContext(ctx, c.HTTPMethod, u, body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header = c.Headers
 
-	resp, err := client.Do(req)
+	resp, err := t.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if len(c.SuccessCodes) != 0 {
+		for _, i := range c.SuccessCodes {
+			if resp.StatusCode == i {
+				return io.ReadAll(resp.Body)
+			}
+		}
+		return nil, &StatusError{Status: resp.Status, Code: resp.StatusCode}
+	}
+
 	switch resp.StatusCode {
 	case http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent: // 200, 201, 202, 204
-		return nil
+		return io.ReadAll(resp.Body)
 	case http.StatusBadRequest: // 400
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return ErrHeaderMissing
+		bodyBytes, err2 := io.ReadAll(resp.Body)
+		if err2 != nil {
+			return nil, ErrHeaderMissing
 		}
-		return fmt.Errorf("%v: %v", ErrHeaderMissing, string(bodyBytes))
+		return nil, fmt.Errorf("%w: %v", ErrHeaderMissing, string(bodyBytes))
 	case http.StatusUnauthorized: // 401
-		return ErrClientAuthenticationError
+		return nil, ErrClientAuthenticationError
 	case http.StatusForbidden: // 403
-		return ErrForbidden
+		return nil, ErrForbidden
 	case http.StatusNotFound: // 404
-		return ErrNotFound
+		return nil, ErrNotFound
 	case http.StatusUnprocessableEntity: // 422
-		return ErrUnprocessableEntityError
+		return nil, ErrUnprocessableEntityError
 	case http.StatusTooManyRequests: // 429
-		return ErrTooManyRequest
+		return nil, ErrTooManyRequest
+	default:
+		return nil, &StatusError{Status: resp.Status, Code: resp.StatusCode}
+	}
+}
+
+// IsRetryable returns true for the errors worth a retry: the network errors, the 429 and the 5xx
+func IsRetryable(err error) bool {
+	var s *StatusError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &s):
+		return s.Code == http.StatusTooManyRequests || s.Code >= http.StatusInternalServerError
+	case errors.Is(err, ErrTooManyRequest):
+		return true
+	case errors.Is(err, ErrHeaderMissing), errors.Is(err, ErrClientAuthenticationError), errors.Is(err, ErrForbidden),
+		errors.Is(err, ErrNotFound), errors.Is(err, ErrUnprocessableEntityError):
+		return false
 	default:
-		return errors.New(resp.Status)
+		return true
 	}
 }
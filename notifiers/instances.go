@@ -0,0 +1,178 @@
+package notifiers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// Instance is a notifier inited with its settings, the global settings, the settings of each tenant and of each
+// route have their own instance, the notification is cancelled with the context
+type Instance interface {
+	Notify(ctx context.Context, log utils.LogLine) error
+}
+
+// closer is implemented by the instances with a goroutine or an open file, they're closed once replaced
+type closer interface {
+	Close()
+}
+
+// flusher is implemented by the instances sending their notifications by batches
+type flusher interface {
+	Flush() error
+}
+
+type instanceKey struct {
+	notifier string
+	tenant   string // empty for the global settings
+}
+
+// notification is the instance of a notifier without settings
+type notification func(log utils.LogLine) error
+
+func (f notification) Notify(_ context.Context, log utils.LogLine) error {
+	return f(log)
+}
+
+var (
+	instances   = make(map[instanceKey]Instance)
+	instancesMu sync.RWMutex
+	// the reloads and the updates of the settings are serialized
+	reloadMu sync.Mutex
+)
+
+// newInstance adapts the constructor of a notifier, a failed init returns a nil Instance, not a nil pointer
+func newInstance[T Instance](f func(fields map[string]interface{}) (T, error)) func(fields map[string]interface{}) (Instance, error) {
+	return func(fields map[string]interface{}) (Instance, error) {
+		n, err := f(fields)
+		if err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+}
+
+// getInstance returns the instance of the notifier with the settings of the tenant, with the global settings if the
+// tenant is empty, nil if the notifier isn't inited with them
+func getInstance(notifier *Notifier, tenant string) Instance {
+	if notifier.New == nil {
+		return notification(notifier.Notification)
+	}
+	instancesMu.RLock()
+	defer instancesMu.RUnlock()
+	return instances[instanceKey{notifier: notifier.Name, tenant: tenant}]
+}
+
+// setInstances swaps the instances, the previous ones not kept are closed
+func setInstances(list map[instanceKey]Instance) {
+	instancesMu.Lock()
+	previous := instances
+	instances = list
+	instancesMu.Unlock()
+
+	for i, j := range previous {
+		if list[i] != j {
+			closeInstance(j)
+		}
+	}
+}
+
+// closeInstance stops the goroutines of the instance and sends its buffered notifications
+func closeInstance(instance Instance) {
+	if c, ok := instance.(closer); ok {
+		c.Close()
+	}
+}
+
+// flushInstances sends the batches of the instances, globally, for the tenants and for the routes
+func flushInstances(routes []*routing) {
+	type named struct {
+		instance Instance
+		notifier string
+	}
+	instancesMu.RLock()
+	list := make([]named, 0, len(instances))
+	for i, j := range instances {
+		list = append(list, named{instance: j, notifier: i.notifier})
+	}
+	instancesMu.RUnlock()
+	for _, i := range routes {
+		for _, j := range i.routes {
+			list = append(list, named{instance: j.instance, notifier: i.notifier.Name})
+		}
+		if i.escalation != nil {
+			list = append(list, named{instance: i.escalation.instance, notifier: i.notifier.Name})
+		}
+	}
+
+	for _, i := range list {
+		f, ok := i.instance.(flusher)
+		if !ok {
+			continue
+		}
+		if err := f.Flush(); err != nil {
+			utils.PrintLog("error", utils.LogLine{Notifier: i.notifier, Message: "notification", Error: err.Error(), Status: "failure"})
+		}
+	}
+}
+
+func closeInstances(list map[instanceKey]Instance) {
+	for _, i := range list {
+		closeInstance(i)
+	}
+}
+
+// reloadInstance inits the instance with the settings, the current one is kept if the init fails
+func reloadInstance(list map[instanceKey]Instance, notifier *Notifier, tenant string, fields map[string]interface{}) {
+	logN := utils.LogLine{Notifier: notifier.Name, Message: "init"}
+	if tenant != "" {
+		logN.Objects = map[string]string{"tenant": tenant}
+	}
+	instance, err := notifier.New(fields)
+	if err != nil {
+		logN.Status = "failure"
+		logN.Error = err.Error()
+		utils.PrintLog("error", logN)
+		if current := getInstance(notifier, tenant); current != nil {
+			list[instanceKey{notifier: notifier.Name, tenant: tenant}] = current
+		}
+		return
+	}
+	list[instanceKey{notifier: notifier.Name, tenant: tenant}] = instance
+	logN.Status = "success"
+	logN.Result = "settings reloaded"
+	utils.PrintLog("info", logN)
+}
+
+// reloadInstances inits again the instances of the enabled notifiers with the current settings, globally and for
+// each tenant
+func reloadInstances(config *configuration.Configuration) {
+	list := make(map[instanceKey]Instance)
+	for _, i := range *enabledNotifiers {
+		if i.New == nil {
+			continue
+		}
+		if _, ok := config.Notifiers[i.Name]; !ok && isUsedByTenants(i.Name) {
+			// the notifier is used by the tenants only
+			continue
+		}
+		reloadInstance(list, i, "", config.Notifiers[i.Name])
+	}
+	for tenant, names := range getTenantNotifiers() {
+		t, _ := config.GetTenant(tenant)
+		for _, i := range utils.Deduplicate(names) {
+			n := availableNotifiers.FindNotifier(i)
+			if n == nil || n.New == nil {
+				continue
+			}
+			fields, ok := t.Notifiers[i]
+			if !ok {
+				continue
+			}
+			reloadInstance(list, n, tenant, fields)
+		}
+	}
+	setInstances(list)
+}
@@ -1,7 +1,6 @@
 package k8sevents
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"strings"
@@ -9,9 +8,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	textTemplate "text/template"
-
 	kubernetes "github.com/falco-talon/falco-talon/internal/kubernetes/client"
+	"github.com/falco-talon/falco-talon/notifiers/templates"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
@@ -20,53 +18,19 @@ const (
 	defaultStr string = "default"
 )
 
-var plaintextTmpl = `Status: {{ .Status }}
-Message: {{ .Message }}
-{{- if .Rule }}
-Rule: {{ .Rule }}
-{{- end }}
-{{- if .Action }}
-Action: {{ .Action }}
-{{- end }}
-{{- if .Actionner }}
-Actionner: {{ .Actionner }}
-{{- end }}
-{{- if .Event }}
-Event: {{ .Event }}
-{{- end }}
-{{- range $key, $value := .Objects }}
-{{ $key }}: {{ $value }}
-{{- end }}
-{{- if .Error }}
-Error: {{ .Error }}
-{{- end }}
-{{- if .Result }}
-Result: {{ .Result }}
-{{- end }}
-{{- if .Output }}
-Output: {{ .Output }}
-{{- end }}
-{{- if .Target }}
-Target: {{ .Target }}
-{{- end }}
-TraceID: {{ .TraceID }}
-`
-
 func Notify(log utils.LogLine) error {
-	var err error
-	var message string
-	ttmpl := textTemplate.New("message")
-	ttmpl, err = ttmpl.Parse(plaintextTmpl)
+	message, ok, err := templates.Render("k8sevents", log)
 	if err != nil {
 		return err
 	}
-	var messageBuf bytes.Buffer
-	err = ttmpl.Execute(&messageBuf, log)
-	if err != nil {
-		return err
+	if !ok {
+		message, err = templates.RenderPartial("details", log)
+		if err != nil {
+			return err
+		}
 	}
 
-	message = utils.RemoveSpecialCharacters(messageBuf.String())
+	message = utils.RemoveSpecialCharacters(message)
 
 	if len(message) > 1024 {
 		message = message[:1024]
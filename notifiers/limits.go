@@ -0,0 +1,212 @@
+package notifiers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	defaultOpenSeconds    int = 30
+	defaultTimeoutSeconds int = 10
+	defaultMaxConcurrent  int = 10
+)
+
+var (
+	errRateLimited = errors.New("rate limit exceeded, notification dropped")
+	errCircuitOpen = errors.New("circuit open, notification dropped")
+	errTimeout     = errors.New("timeout")
+)
+
+type limiter struct {
+	rateLimiter      *rate.Limiter
+	openUntil        time.Time
+	slots            chan struct{}
+	deadLetter       string
+	timeout          time.Duration
+	openDuration     time.Duration
+	failureThreshold int
+	failures         int
+	mu               sync.Mutex
+}
+
+var (
+	limiters   map[string]*limiter
+	limitersMu sync.RWMutex
+)
+
+// initLimiters sets the limits of the notifiers, the state of the previous circuit breakers is reset
+func initLimiters(config *configuration.Configuration) {
+	list := make(map[string]*limiter)
+
+	for name, c := range config.NotifierLimits {
+		list[strings.ToLower(name)] = newLimiter(c)
+	}
+
+	limitersMu.Lock()
+	limiters = list
+	limitersMu.Unlock()
+}
+
+func newLimiter(c configuration.NotifierLimitsConfig) *limiter {
+	l := &limiter{
+		deadLetter:       strings.ToLower(c.DeadLetterNotifier),
+		timeout:          time.Duration(defaultTimeoutSeconds) * time.Second,
+		slots:            make(chan struct{}, defaultMaxConcurrent),
+		failureThreshold: c.FailureThreshold,
+		openDuration:     time.Duration(defaultOpenSeconds) * time.Second,
+	}
+	if c.TimeoutSeconds > 0 {
+		l.timeout = time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	if c.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, c.MaxConcurrent)
+	}
+	if c.OpenSeconds > 0 {
+		l.openDuration = time.Duration(c.OpenSeconds) * time.Second
+	}
+	if c.RateLimit > 0 {
+		burst := c.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		l.rateLimiter = rate.NewLimiter(rate.Limit(c.RateLimit), burst)
+	}
+	return l
+}
+
+// getLimiter returns the limits of the notifier, those without `notifier_limits` get the default timeout and
+// concurrency, without rate limit nor circuit breaker
+func getLimiter(name string) *limiter {
+	limitersMu.RLock()
+	l, ok := limiters[name]
+	limitersMu.RUnlock()
+	if ok {
+		return l
+	}
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	if l, ok := limiters[name]; ok {
+		return l
+	}
+	if limiters == nil {
+		limiters = make(map[string]*limiter)
+	}
+	l = newLimiter(configuration.NotifierLimitsConfig{})
+	limiters[name] = l
+	return l
+}
+
+// send sends the notification with the instance within the limits of the notifier, the notification is dropped
+// if the rate limit is exceeded or if the circuit breaker is open
+func send(notifier *Notifier, instance Instance, log utils.LogLine) error {
+	l := getLimiter(notifier.Name)
+
+	if l.rateLimiter != nil && !l.rateLimiter.Allow() {
+		l.drop(notifier.Name, "rate_limited", log)
+		return errRateLimited
+	}
+
+	if l.isOpen() {
+		l.drop(notifier.Name, "circuit_open", log)
+		return errCircuitOpen
+	}
+
+	err := l.call(instance, log)
+	l.record(notifier.Name, err)
+	return err
+}
+
+// call runs the notification, with a timeout to not block the actions if the notifier is slow, the context of the
+// notification is cancelled after it. A notification keeps its slot until it returns, even after the timeout, the
+// notifiers ignoring their context can't pile up the goroutines
+func (l *limiter) call(instance Instance, log utils.LogLine) error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return errTimeout
+	}
+
+	c := make(chan error, 1)
+	go func() {
+		defer func() { <-l.slots }()
+		c <- instance.Notify(ctx, log)
+	}()
+
+	select {
+	case err := <-c:
+		return err
+	case <-ctx.Done():
+		return errTimeout
+	}
+}
+
+func (l *limiter) isOpen() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().Before(l.openUntil)
+}
+
+// record opens the circuit after `failure_threshold` consecutive failures, after `open_seconds`
+// a new try is allowed and a new failure opens the circuit again
+func (l *limiter) record(notifier string, err error) {
+	if l.failureThreshold <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err == nil {
+		l.failures = 0
+		return
+	}
+
+	l.failures++
+	if l.failures >= l.failureThreshold {
+		l.openUntil = time.Now().Add(l.openDuration)
+		utils.PrintLog("warning", utils.LogLine{Notifier: notifier, Message: "notification", Result: fmt.Sprintf("circuit open for %v after %v failure(s)", l.openDuration, l.failures)})
+	}
+}
+
+// drop counts the dropped notification and sends it to the dead letter notifier if it's set
+func (l *limiter) drop(notifier, reason string, log utils.LogLine) {
+	metrics.IncreaseCounter(utils.LogLine{Message: "dropped_notification", Notifier: notifier, Rule: log.Rule, Action: log.Action, Status: reason})
+
+	if l.deadLetter == "" || l.deadLetter == notifier {
+		return
+	}
+
+	n := GetNotifiers().FindNotifier(l.deadLetter)
+	if n == nil {
+		return
+	}
+	instance := getInstance(n, "")
+	if instance == nil {
+		return
+	}
+
+	log.Result = fmt.Sprintf("notification dropped by '%v': %v", notifier, reason)
+	logN := utils.LogLine{Message: "notification", Notifier: l.deadLetter, Rule: log.Rule, Action: log.Action, Result: "dead letter"}
+	if err := getLimiter(l.deadLetter).call(instance, log); err != nil {
+		logN.Status = "failure"
+		logN.Error = err.Error()
+		utils.PrintLog("error", logN)
+		return
+	}
+	logN.Status = "success"
+	utils.PrintLog("info", logN)
+}
@@ -1,6 +1,9 @@
 package loki
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -11,11 +14,16 @@ import (
 )
 
 type Settings struct {
-	CustomHeaders map[string]string `field:"custom_headers"`
-	HostPort      string            `field:"host_port"`
-	User          string            `field:"user"`
-	APIKey        string            `field:"api_key"`
-	Tenant        string            `field:"tenant"`
+	CustomHeaders      map[string]string `field:"custom_headers"`
+	Labels             map[string]string `field:"labels"`
+	HostPort           string            `field:"host_port"`
+	User               string            `field:"user"`
+	APIKey             string            `field:"api_key"`
+	Tenant             string            `field:"tenant"`
+	Format             string            `field:"format" default:"json"`
+	CACertFile         string            `field:"ca_cert_file"`
+	TimeoutSeconds     int               `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify bool              `field:"insecure_skip_verify" default:"false"`
 }
 
 type Payload struct {
@@ -29,39 +37,49 @@ type Stream struct {
 
 type Value []string
 
-const contentType = "application/json"
-
-var settings *Settings
+const (
+	contentType string = "application/json"
+	pushPath    string = "/loki/api/v1/push"
+	jsonStr     string = "json"
+	textStr     string = "text"
+)
 
-func Init(fields map[string]interface{}) error {
-	settings = new(Settings)
-	settings = utils.SetFields(settings, fields).(*Settings)
-	if err := checkSettings(settings); err != nil {
-		return err
-	}
-	return nil
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings  *Settings
+	transport *http.Transport
 }
 
-func Notify(log utils.LogLine) error {
-	if settings.HostPort == "" {
-		return errors.New("wrong `host_port` setting")
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	var tlsConfig *tls.Config
+	if n.settings.CACertFile != "" || n.settings.InsecureSkipVerify {
+		var err error
+		if tlsConfig, err = http.NewTLSConfig("", "", n.settings.CACertFile, n.settings.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
 	}
-
-	if err := http.CheckURL(settings.HostPort); err != nil {
-		return err
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
 	}
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.settings.TimeoutSeconds)*time.Second)
+	return n, nil
+}
 
-	client := http.NewClient("", contentType, "", settings.CustomHeaders)
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	client := http.NewClient("", contentType, "", n.settings.CustomHeaders)
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
 
-	if settings.User != "" && settings.APIKey != "" {
-		client.SetBasicAuth(settings.User, settings.APIKey)
+	if n.settings.User != "" && n.settings.APIKey != "" {
+		client.SetBasicAuth(n.settings.User, n.settings.APIKey)
 	}
 
-	if settings.Tenant != "" {
-		client.SetHeader("X-Scope-OrgID", settings.Tenant)
+	if n.settings.Tenant != "" {
+		client.SetHeader("X-Scope-OrgID", n.settings.Tenant)
 	}
 
-	err := client.Request(settings.HostPort+"/loki/api/v1/push", NewPayload(log))
+	err := client.Request(strings.TrimSuffix(n.settings.HostPort, "/")+pushPath, n.NewPayload(log))
 	if err != nil {
 		return err
 	}
@@ -69,17 +87,34 @@ func Notify(log utils.LogLine) error {
 }
 
 func checkSettings(settings *Settings) error {
+	if settings.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
 	if settings.HostPort == "" {
 		return errors.New("wrong `host_port` setting")
 	}
+	if err := http.CheckURL(settings.HostPort); err != nil {
+		return err
+	}
+	if settings.Format != jsonStr && settings.Format != textStr {
+		return errors.New("wrong `format` setting")
+	}
 
 	return nil
 }
 
-func NewPayload(log utils.LogLine) Payload {
+// NewPayload creates the payload for Loki, only the fields with a low cardinality
+// are used as labels, the others are in the log line to keep the index small
+func (n *Notifier) NewPayload(log utils.LogLine) Payload {
 	s := make(map[string]string)
 
+	for k, v := range n.settings.Labels {
+		s[k] = v
+	}
+
+	s["source"] = utils.FalcoTalonStr
 	s["status"] = log.Status
+	s["message"] = log.Message
 	if log.Rule != "" {
 		s["rule"] = strings.ReplaceAll(strings.ToLower(log.Rule), " ", "_")
 	}
@@ -92,23 +127,29 @@ func NewPayload(log utils.LogLine) Payload {
 	if log.Target != "" {
 		s["target"] = log.Target
 	}
-	s["message"] = log.Message
-	s["traceid"] = log.TraceID
-
 	for k, v := range log.Objects {
-		s[strings.ToLower(k)] = v
+		if strings.ToLower(k) == "namespace" {
+			s["namespace"] = v
+		}
 	}
 
 	var t string
 
-	if log.Output != "" {
-		t = log.Output
-	}
-	if log.Result != "" {
-		t = log.Result
-	}
-	if log.Error != "" {
-		t = log.Error
+	switch n.settings.Format {
+	case textStr:
+		if log.Output != "" {
+			t = log.Output
+		}
+		if log.Result != "" {
+			t = log.Result
+		}
+		if log.Error != "" {
+			t = log.Error
+		}
+	default:
+		log.Time = time.Now().Format(time.RFC3339)
+		b, _ := json.Marshal(log)
+		t = string(b)
 	}
 
 	return Payload{Streams: []Stream{
@@ -1,33 +1,54 @@
 package notifiers
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/falco-talon/falco-talon/configuration"
 	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/incidents"
+	k8s "github.com/falco-talon/falco-talon/internal/kubernetes/client"
 	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/internal/tracing"
 	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/notifiers/alertmanager"
+	"github.com/falco-talon/falco-talon/notifiers/datadog"
 	"github.com/falco-talon/falco-talon/notifiers/elasticsearch"
+	"github.com/falco-talon/falco-talon/notifiers/eventbridge"
+	"github.com/falco-talon/falco-talon/notifiers/eventhub"
+	"github.com/falco-talon/falco-talon/notifiers/falcosidekick"
+	"github.com/falco-talon/falco-talon/notifiers/file"
 	"github.com/falco-talon/falco-talon/notifiers/k8sevents"
 	"github.com/falco-talon/falco-talon/notifiers/loki"
+	"github.com/falco-talon/falco-talon/notifiers/servicebus"
 	"github.com/falco-talon/falco-talon/notifiers/slack"
 	"github.com/falco-talon/falco-talon/notifiers/smtp"
+	"github.com/falco-talon/falco-talon/notifiers/splunk"
+	"github.com/falco-talon/falco-talon/notifiers/syslog"
+	"github.com/falco-talon/falco-talon/notifiers/templates"
 	"github.com/falco-talon/falco-talon/notifiers/webhook"
 	"github.com/falco-talon/falco-talon/utils"
 
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
 type Notifier struct {
-	Init         func(fields map[string]interface{}) error
-	Notification func(log utils.LogLine) error
+	New          func(fields map[string]interface{}) (Instance, error)
+	Notification func(log utils.LogLine) error // for the notifiers without settings
+	Settings     interface{}                   // the struct of the settings, for the docs
+	Permissions  []k8s.Permission              // the RBAC required in the cluster
 	Name         string
 }
 
 type Notifiers []*Notifier
 
 var enabledNotifiers *Notifiers
+
+// notifiers which failed to init, with their errors
+var failedNotifiers map[string]string
 var availableNotifiers *Notifiers
 
 func init() {
@@ -41,33 +62,81 @@ func GetAvailableNotifiers() *Notifiers {
 		availableNotifiers.Add(
 			&Notifier{
 				Name:         "k8sevents",
-				Init:         nil,
+				New:          nil,
 				Notification: k8sevents.Notify,
+				Permissions: []k8s.Permission{
+					{APIGroups: []string{""}, Resources: []string{"events"}, Verbs: []string{"create"}},
+				},
+			},
+			&Notifier{
+				Name:     "slack",
+				New:      newInstance(slack.New),
+				Settings: slack.Settings{},
+			},
+			&Notifier{
+				Name:     "smtp",
+				New:      newInstance(smtp.New),
+				Settings: smtp.Settings{},
+			},
+			&Notifier{
+				Name:     "webhook",
+				New:      newInstance(webhook.New),
+				Settings: webhook.Configuration{},
+			},
+			&Notifier{
+				Name:     "loki",
+				New:      newInstance(loki.New),
+				Settings: loki.Settings{},
+			},
+			&Notifier{
+				Name:     "elasticsearch",
+				New:      newInstance(elasticsearch.New),
+				Settings: elasticsearch.Settings{},
+			},
+			&Notifier{
+				Name:     "eventbridge",
+				New:      newInstance(eventbridge.New),
+				Settings: eventbridge.Settings{},
+			},
+			&Notifier{
+				Name:     "eventhub",
+				New:      newInstance(eventhub.New),
+				Settings: eventhub.Settings{},
+			},
+			&Notifier{
+				Name:     "servicebus",
+				New:      newInstance(servicebus.New),
+				Settings: servicebus.Settings{},
+			},
+			&Notifier{
+				Name:     "splunk",
+				New:      newInstance(splunk.New),
+				Settings: splunk.Settings{},
 			},
 			&Notifier{
-				Name:         "slack",
-				Init:         slack.Init,
-				Notification: slack.Notify,
+				Name:     "datadog",
+				New:      newInstance(datadog.New),
+				Settings: datadog.Settings{},
 			},
 			&Notifier{
-				Name:         "smtp",
-				Init:         smtp.Init,
-				Notification: smtp.Notify,
+				Name:     "syslog",
+				New:      newInstance(syslog.New),
+				Settings: syslog.Settings{},
 			},
 			&Notifier{
-				Name:         "webhook",
-				Init:         webhook.Init,
-				Notification: webhook.Notify,
+				Name:     "alertmanager",
+				New:      newInstance(alertmanager.New),
+				Settings: alertmanager.Settings{},
 			},
 			&Notifier{
-				Name:         "loki",
-				Init:         loki.Init,
-				Notification: loki.Notify,
+				Name:     "falcosidekick",
+				New:      newInstance(falcosidekick.New),
+				Settings: falcosidekick.Settings{},
 			},
 			&Notifier{
-				Name:         "elasticsearch",
-				Init:         elasticsearch.Init,
-				Notification: elasticsearch.Notify,
+				Name:     "file",
+				New:      newInstance(file.New),
+				Settings: file.Settings{},
 			},
 		)
 	}
@@ -76,6 +145,13 @@ func GetAvailableNotifiers() *Notifiers {
 
 func Init() {
 	config := configuration.GetConfiguration()
+	failedNotifiers = make(map[string]string)
+
+	// the templates are parsed before the notifiers, their own templates use the partials
+	if err := templates.Init(templatesConfig(config)); err != nil {
+		utils.PrintLog("error", utils.LogLine{Message: "init", Error: err.Error(), Status: "failure", Result: "templates of the notifiers"})
+		failedNotifiers["templates"] = err.Error()
+	}
 
 	specifiedNotifiers := map[string]bool{}
 
@@ -84,25 +160,141 @@ func Init() {
 	}
 	rules := rules.GetRules()
 	for _, i := range *rules {
+		if i.GetTenant() != "" {
+			continue
+		}
 		for _, j := range i.GetNotifiers() {
 			specifiedNotifiers[j] = true
 		}
 	}
 
+	list := make(map[instanceKey]Instance)
 	for i := range specifiedNotifiers {
 		for _, j := range *availableNotifiers {
 			if strings.ToLower(i) == j.Name {
-				if j.Init != nil {
-					if err := j.Init(config.Notifiers[i]); err != nil {
+				if j.New != nil {
+					instance, err := j.New(config.Notifiers[i])
+					if err != nil {
 						utils.PrintLog("error", utils.LogLine{Notifier: i, Message: "init", Error: err.Error(), Status: "failure"})
+						failedNotifiers[i] = err.Error()
 						continue
 					}
+					list[instanceKey{notifier: j.Name}] = instance
 					utils.PrintLog("info", utils.LogLine{Notifier: i, Message: "init", Status: "success"})
 				}
 				enabledNotifiers.Add(j)
 			}
 		}
 	}
+
+	initTenants(config, list)
+	setInstances(list)
+	initDigests(config)
+	initLimiters(config)
+	if err := initRoutings(config); err != nil {
+		utils.PrintLog("error", utils.LogLine{Message: "init", Error: err.Error(), Status: "failure", Result: "routing of the notifiers"})
+		failedNotifiers["routing"] = err.Error()
+	}
+}
+
+// Reload inits again the enabled notifiers with their current settings, after a change of the secrets, the
+// notifiers failing to init keep their previous settings
+func Reload() {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	config := configuration.GetConfiguration()
+	reloadInstances(config)
+	if err := initRoutings(config); err != nil {
+		utils.PrintLog("error", utils.LogLine{Message: "init", Error: err.Error(), Status: "failure", Result: "routing of the notifiers"})
+	}
+}
+
+// Update inits the notifiers with the settings of a new configuration, before its swap, the current instances
+// are kept if one of them fails
+func Update(next *configuration.Configuration) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	// the routing is checked first, the notifiers are inited with the new settings after
+	list, err := newRoutings(next)
+	if err != nil {
+		return err
+	}
+
+	if err := templates.Init(templatesConfig(next)); err != nil {
+		closeRoutings(list)
+		return fmt.Errorf("templates: %v", err)
+	}
+
+	specifiedNotifiers := map[string]bool{}
+	for _, i := range next.GetDefaultNotifiers() {
+		specifiedNotifiers[strings.ToLower(i)] = true
+	}
+	for _, i := range *rules.GetRules() {
+		if i.GetTenant() != "" {
+			continue
+		}
+		for _, j := range i.GetNotifiers() {
+			specifiedNotifiers[strings.ToLower(j)] = true
+		}
+	}
+
+	updated := new(Notifiers)
+	instanceList := make(map[instanceKey]Instance)
+	for _, i := range *availableNotifiers {
+		if !specifiedNotifiers[i.Name] {
+			continue
+		}
+		if i.New != nil {
+			instance, err := i.New(next.Notifiers[i.Name])
+			if err != nil {
+				closeInstances(instanceList)
+				closeRoutings(list)
+				_ = templates.Init(templatesConfig(configuration.GetConfiguration()))
+				return fmt.Errorf("%v: %v", i.Name, err)
+			}
+			instanceList[instanceKey{notifier: i.Name}] = instance
+		}
+		updated.Add(i)
+	}
+	// the rules aren't reloaded, the notifiers of the tenants stay enabled, with their new settings
+	if err := updateTenants(next, updated, instanceList); err != nil {
+		closeInstances(instanceList)
+		closeRoutings(list)
+		_ = templates.Init(templatesConfig(configuration.GetConfiguration()))
+		return err
+	}
+
+	enabledNotifiers = updated
+	setInstances(instanceList)
+	failedNotifiers = make(map[string]string)
+	initDigests(next)
+	initLimiters(next)
+	setRoutings(list)
+	return nil
+}
+
+func templatesConfig(config *configuration.Configuration) templates.Config {
+	return templates.Config{
+		Partials:  config.Templates.Partials,
+		Notifiers: config.Templates.Notifiers,
+		Message:   config.Templates.Message,
+		Directory: config.Templates.Directory,
+	}
+}
+
+// Check returns an error if some notifiers failed to init
+func Check() error {
+	if len(failedNotifiers) == 0 {
+		return nil
+	}
+	list := make([]string, 0, len(failedNotifiers))
+	for i, j := range failedNotifiers {
+		list = append(list, fmt.Sprintf("%v: %v", i, j))
+	}
+	sort.Strings(list)
+	return fmt.Errorf("init failed for %v", strings.Join(list, ", "))
 }
 
 func GetNotifiers() *Notifiers {
@@ -116,41 +308,91 @@ func Notify(rule *rules.Rule, action *rules.Action, event *events.Event, log uti
 		return
 	}
 
-	enabledNotifiers := map[string]bool{}
+	// the notifiers of the rules of a tenant use the settings of the tenant, the default ones the global settings
+	type target struct {
+		notifier, tenant string
+	}
+	enabledNotifiers := map[target]bool{}
 
 	for _, i := range config.DefaultNotifiers {
-		enabledNotifiers[i] = true
+		enabledNotifiers[target{notifier: i}] = true
 	}
 	for _, i := range rule.Notifiers {
-		enabledNotifiers[i] = true
+		enabledNotifiers[target{notifier: i, tenant: rule.GetTenant()}] = true
 	}
 
 	logN := utils.LogLine{
-		Message:   "notification",
-		Rule:      rule.GetName(),
-		Action:    action.GetName(),
-		Actionner: action.GetActionner(),
-		TraceID:   event.TraceID,
+		Message:    "notification",
+		Rule:       rule.GetName(),
+		Action:     action.GetName(),
+		Actionner:  action.GetActionner(),
+		TraceID:    event.TraceID,
+		IncidentID: event.IncidentID,
 	}
 
 	obj := make(map[string]string, len(log.Objects))
 	for i, j := range log.Objects {
 		obj[cases.Title(language.Und, cases.NoLower).String(strings.ToLower(i))] = j
 	}
+	if incident := incidents.GetIncident(event.IncidentID); incident != nil {
+		obj["Incident"] = incident.ID
+		obj["Incident_events"] = fmt.Sprintf("%v", incident.GetCount())
+	}
 	log.Objects = obj
+	log.IncidentID = event.IncidentID
 
-	for i := range enabledNotifiers {
+	for t := range enabledNotifiers {
+		i := t.notifier
 		if n := GetNotifiers().FindNotifier(i); n != nil {
 			logN.Notifier = i
-			if err := n.Notification(log); err != nil {
+			logN.Result = ""
+			l := attach(n.Name, event, log)
+			// the routing applies to the global settings, the escalations aren't held
+			var route Instance
+			escalated := 0
+			r := getRouting(n.Name)
+			if t.tenant == "" && r != nil {
+				if escalated = r.escalate(l); escalated == 0 && r.hold(event.Priority, l) {
+					continue
+				}
+				route = r.getRoute(event)
+			}
+			// the digests are sent with the global settings
+			if t.tenant == "" && escalated == 0 && route == nil && isDigested(n.Name, rule.GetName(), event.Priority, l) {
+				continue
+			}
+			_, span := tracing.Start(event.GetTraceContext(), "notification "+i, attribute.String("falco_talon.notifier", i))
+			var err error
+			if route != nil {
+				err = send(n, route, l)
+			} else {
+				err = sendFor(t.tenant, n, l)
+			}
+			tracing.End(span, err)
+			if err != nil {
+				logN.Status = "failure"
+				logN.Error = err.Error()
+				utils.PrintLog("error", logN)
+				metrics.IncreaseCounter(logN)
+			} else {
+				logN.Status = "success"
+				utils.PrintLog("info", logN)
+				metrics.IncreaseCounter(logN)
+			}
+			if escalated == 0 {
+				continue
+			}
+			logN.Result = fmt.Sprintf("escalation after %v failure(s)", escalated)
+			err = r.sendEscalation(escalated, l)
+			if err != nil {
 				logN.Status = "failure"
 				logN.Error = err.Error()
 				utils.PrintLog("error", logN)
-				metrics.IncreaseCounter(log)
+				metrics.IncreaseCounter(logN)
 			} else {
 				logN.Status = "success"
 				utils.PrintLog("info", logN)
-				metrics.IncreaseCounter(log)
+				metrics.IncreaseCounter(logN)
 			}
 		}
 	}
@@ -0,0 +1,415 @@
+package notifiers
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/events"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	defaultEscalationWindow int    = 60
+	defaultQuietPriority    string = "warning"
+	quietHoursDrop          string = "drop"
+	quietHoursQueue         string = "queue"
+)
+
+type route struct {
+	instance    Instance // inited with the settings of the route over the global ones
+	namespaces  []string
+	minPriority int
+}
+
+type quietHours struct {
+	location    *time.Location
+	logs        []utils.LogLine
+	start       int // minutes since midnight
+	end         int
+	maxPriority int
+	queue       bool
+	mu          sync.Mutex
+}
+
+type failures struct {
+	last  time.Time
+	count int
+}
+
+type escalation struct {
+	instance  Instance
+	failures  map[string]*failures // by rule and action
+	window    time.Duration
+	threshold int
+	mu        sync.Mutex
+}
+
+type routing struct {
+	notifier   *Notifier
+	quietHours *quietHours
+	escalation *escalation
+	routes     []route
+}
+
+var (
+	routings     map[string]*routing
+	routingsMu   sync.RWMutex
+	routingsOnce sync.Once
+)
+
+// initRoutings sets the routing of the notifiers
+func initRoutings(config *configuration.Configuration) error {
+	list, err := newRoutings(config)
+	if err != nil {
+		return err
+	}
+	setRoutings(list)
+	return nil
+}
+
+// setRoutings swaps the routing of the notifiers, the notifications queued by the quiet hours of the previous
+// settings are kept if the notifier still queues them, sent otherwise, the instances of the previous routes are closed
+func setRoutings(list map[string]*routing) {
+	routingsMu.Lock()
+	previous := routings
+	routings = list
+	routingsMu.Unlock()
+
+	for name, i := range previous {
+		i.close()
+		if i.quietHours == nil {
+			continue
+		}
+		i.quietHours.mu.Lock()
+		logs := i.quietHours.logs
+		i.quietHours.logs = nil
+		i.quietHours.mu.Unlock()
+		if len(logs) == 0 {
+			continue
+		}
+		if r, ok := list[name]; ok && r.quietHours != nil && r.quietHours.queue {
+			r.quietHours.mu.Lock()
+			r.quietHours.logs = append(logs, r.quietHours.logs...)
+			r.quietHours.mu.Unlock()
+			continue
+		}
+		go flushQuietHours(i.notifier, logs)
+	}
+
+	routingsOnce.Do(func() { go runQuietHours() })
+}
+
+// newRoutings checks the routing of the notifiers and inits an instance of the notifier for each route and
+// escalation, with their settings over the global ones
+func newRoutings(config *configuration.Configuration) (map[string]*routing, error) {
+	list := make(map[string]*routing)
+	for name, c := range config.Routing {
+		r, err := newRouting(name, c, config)
+		if r != nil {
+			// the instances inited before the error are closed with the others
+			list[r.notifier.Name] = r
+		}
+		if err != nil {
+			closeRoutings(list)
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func newRouting(name string, c configuration.RoutingConfig, config *configuration.Configuration) (*routing, error) {
+	n := availableNotifiers.FindNotifier(strings.ToLower(name))
+	if n == nil {
+		return nil, fmt.Errorf("routing: unknown notifier '%v'", name)
+	}
+	if n.New == nil && (len(c.Routes) != 0 || c.Escalation.Failures > 0) {
+		return nil, fmt.Errorf("routing: the notifier '%v' has no settings to override", n.Name)
+	}
+
+	r := &routing{notifier: n}
+	for i, j := range c.Routes {
+		if len(j.Settings) == 0 {
+			return r, fmt.Errorf("routing: %v: route %v: no settings", n.Name, i+1)
+		}
+		p, err := getPriority(j.MinPriority)
+		if err != nil {
+			return r, fmt.Errorf("routing: %v: route %v: %v", n.Name, i+1, err)
+		}
+		instance, err := n.New(mergeSettings(config.Notifiers[n.Name], j.Settings))
+		if err != nil {
+			return r, fmt.Errorf("routing: %v: route %v: %v", n.Name, i+1, err)
+		}
+		r.routes = append(r.routes, route{instance: instance, namespaces: j.Namespaces, minPriority: p})
+	}
+
+	q, err := newQuietHours(c.QuietHours)
+	if err != nil {
+		return r, fmt.Errorf("routing: %v: quiet_hours: %v", n.Name, err)
+	}
+	r.quietHours = q
+
+	if c.Escalation.Failures > 0 {
+		if len(c.Escalation.Settings) == 0 {
+			return r, fmt.Errorf("routing: %v: escalation: no settings", n.Name)
+		}
+		window := c.Escalation.WindowMinutes
+		if window <= 0 {
+			window = defaultEscalationWindow
+		}
+		instance, err := n.New(mergeSettings(config.Notifiers[n.Name], c.Escalation.Settings))
+		if err != nil {
+			return r, fmt.Errorf("routing: %v: escalation: %v", n.Name, err)
+		}
+		r.escalation = &escalation{
+			instance:  instance,
+			failures:  make(map[string]*failures),
+			window:    time.Duration(window) * time.Minute,
+			threshold: c.Escalation.Failures,
+		}
+	}
+	return r, nil
+}
+
+// close closes the instances of the routes and of the escalation
+func (r *routing) close() {
+	for _, i := range r.routes {
+		closeInstance(i.instance)
+	}
+	if r.escalation != nil {
+		closeInstance(r.escalation.instance)
+	}
+}
+
+func closeRoutings(list map[string]*routing) {
+	for _, i := range list {
+		i.close()
+	}
+}
+
+func getPriority(priority string) (int, error) {
+	if priority == "" {
+		return rules.Default, nil
+	}
+	p := rules.GetPriorityNumber(priority)
+	if p == rules.Default {
+		return 0, fmt.Errorf("unknown priority '%v'", priority)
+	}
+	return p, nil
+}
+
+func newQuietHours(c configuration.QuietHoursConfig) (*quietHours, error) {
+	if c.Start == "" && c.End == "" {
+		return nil, nil
+	}
+	start, err := time.Parse("15:04", c.Start)
+	if err != nil {
+		return nil, fmt.Errorf("wrong start '%v', the format is HH:MM", c.Start)
+	}
+	end, err := time.Parse("15:04", c.End)
+	if err != nil {
+		return nil, fmt.Errorf("wrong end '%v', the format is HH:MM", c.End)
+	}
+	if start.Equal(end) {
+		return nil, errors.New("the start and the end are equal")
+	}
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("wrong timezone '%v': %v", c.Timezone, err)
+	}
+	if c.MaxPriority == "" {
+		c.MaxPriority = defaultQuietPriority
+	}
+	p, err := getPriority(c.MaxPriority)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &quietHours{
+		location:    location,
+		start:       start.Hour()*60 + start.Minute(),
+		end:         end.Hour()*60 + end.Minute(),
+		maxPriority: p,
+	}
+	switch strings.ToLower(c.Mode) {
+	case "", quietHoursQueue:
+		q.queue = true
+	case quietHoursDrop:
+	default:
+		return nil, fmt.Errorf("wrong mode '%v', it must be '%v' or '%v'", c.Mode, quietHoursDrop, quietHoursQueue)
+	}
+	return q, nil
+}
+
+func getRouting(notifier string) *routing {
+	routingsMu.RLock()
+	defer routingsMu.RUnlock()
+	return routings[notifier]
+}
+
+// isQuiet returns true during the quiet hours, the end is excluded, the hours can span midnight
+func (q *quietHours) isQuiet(t time.Time) bool {
+	t = t.In(q.location)
+	m := t.Hour()*60 + t.Minute()
+	if q.start < q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+// hold returns true if the notification is dropped or queued by the quiet hours
+func (r *routing) hold(priority string, log utils.LogLine) bool {
+	q := r.quietHours
+	if q == nil || rules.GetPriorityNumber(priority) > q.maxPriority || !q.isQuiet(time.Now()) {
+		return false
+	}
+	if !q.queue {
+		metrics.IncreaseCounter(utils.LogLine{Message: "dropped_notification", Notifier: r.notifier.Name, Rule: log.Rule, Action: log.Action, Status: "quiet_hours"})
+		return true
+	}
+	q.mu.Lock()
+	q.logs = append(q.logs, log)
+	q.mu.Unlock()
+	return true
+}
+
+// getRoute returns the instance of the first route matching the event, nil if no route matches
+func (r *routing) getRoute(event *events.Event) Instance {
+	priority := rules.GetPriorityNumber(event.Priority)
+	namespace := event.GetNamespaceName()
+	if namespace == "" {
+		namespace = event.GetTargetNamespace()
+	}
+	for _, i := range r.routes {
+		if priority < i.minPriority {
+			continue
+		}
+		if len(i.namespaces) != 0 && !matchNamespace(i.namespaces, namespace) {
+			continue
+		}
+		return i.instance
+	}
+	return nil
+}
+
+func matchNamespace(patterns []string, namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+	for _, i := range patterns {
+		if ok, _ := path.Match(i, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeSettings(global, override map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(global)+len(override))
+	for i, j := range global {
+		fields[i] = j
+	}
+	for i, j := range override {
+		fields[strings.ToLower(i)] = j
+	}
+	return fields
+}
+
+// escalate counts the consecutive failures of the action of the rule, it returns their number once it reaches
+// the threshold, 0 otherwise, a success or the end of the window resets the count
+func (r *routing) escalate(log utils.LogLine) int {
+	e := r.escalation
+	if e == nil || (log.Status != "failure" && log.Status != "success") {
+		return 0
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := log.Rule + "/" + log.Action
+	if log.Status == "success" {
+		delete(e.failures, key)
+		return 0
+	}
+	f, ok := e.failures[key]
+	if !ok || time.Since(f.last) > e.window {
+		f = &failures{}
+		e.failures[key] = f
+	}
+	f.count++
+	f.last = time.Now()
+	if f.count < e.threshold {
+		return 0
+	}
+	return f.count
+}
+
+// sendEscalation sends the notification with the settings of the escalation, with the number of failures
+func (r *routing) sendEscalation(count int, log utils.LogLine) error {
+	obj := make(map[string]string, len(log.Objects)+1)
+	for i, j := range log.Objects {
+		obj[i] = j
+	}
+	obj["Escalation"] = fmt.Sprintf("%v consecutive failure(s)", count)
+	log.Objects = obj
+	return send(r.notifier, r.escalation.instance, log)
+}
+
+// runQuietHours sends the queued notifications once the quiet hours are over
+func runQuietHours() {
+	ticker := time.NewTicker(time.Minute)
+	for range ticker.C {
+		routingsMu.RLock()
+		list := make([]*routing, 0, len(routings))
+		for _, i := range routings {
+			list = append(list, i)
+		}
+		routingsMu.RUnlock()
+
+		for _, i := range list {
+			if i.quietHours == nil || i.quietHours.isQuiet(time.Now()) {
+				continue
+			}
+			i.flush()
+		}
+	}
+}
+
+// flush sends the notifications queued by the quiet hours
+func (r *routing) flush() {
+	if r.quietHours == nil {
+		return
+	}
+	r.quietHours.mu.Lock()
+	logs := r.quietHours.logs
+	r.quietHours.logs = nil
+	r.quietHours.mu.Unlock()
+
+	flushQuietHours(r.notifier, logs)
+}
+
+func flushQuietHours(notifier *Notifier, logs []utils.LogLine) {
+	if len(logs) == 0 {
+		return
+	}
+
+	logN := utils.LogLine{
+		Message:  "notification",
+		Notifier: notifier.Name,
+		Result:   fmt.Sprintf("%v notification(s) held during the quiet hours", len(logs)),
+	}
+	if err := sendFor("", notifier, summarize(logs, "during the quiet hours")); err != nil {
+		logN.Status = "failure"
+		logN.Error = err.Error()
+		utils.PrintLog("error", logN)
+		return
+	}
+	logN.Status = "success"
+	utils.PrintLog("info", logN)
+}
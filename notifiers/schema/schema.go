@@ -0,0 +1,339 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const (
+	// OCSF is the Open Cybersecurity Schema Framework
+	OCSF string = "ocsf"
+	// ECS is the Elastic Common Schema
+	ECS string = "ecs"
+
+	ocsfVersion string = "1.3.0"
+	ecsVersion  string = "8.11.0"
+
+	// Remediation Activity class of the Remediation category
+	ocsfCategoryUID int = 7
+	ocsfClassUID    int = 7001
+
+	successStr string = "success"
+	failureStr string = "failure"
+)
+
+var hostname, _ = os.Hostname()
+
+// CheckSchema returns an error if the schema is unknown, an empty schema keeps the native format
+func CheckSchema(schema string) error {
+	switch schema {
+	case "", OCSF, ECS:
+		return nil
+	default:
+		return errors.New("wrong `schema` setting, must be 'ocsf' or 'ecs'")
+	}
+}
+
+// Serialize maps the log onto the field names of the schema, the log is returned as it is for the native format
+func Serialize(log utils.LogLine, schema string) interface{} {
+	switch schema {
+	case OCSF:
+		return NewOCSFEvent(log)
+	case ECS:
+		return NewECSEvent(log)
+	default:
+		return log
+	}
+}
+
+type OCSFEvent struct {
+	Unmapped     map[string]string `json:"unmapped,omitempty"`
+	Metadata     OCSFMetadata      `json:"metadata"`
+	Message      string            `json:"message,omitempty"`
+	Activity     string            `json:"activity_name"`
+	Category     string            `json:"category_name"`
+	Class        string            `json:"class_name"`
+	Severity     string            `json:"severity"`
+	Status       string            `json:"status"`
+	StatusDetail string            `json:"status_detail,omitempty"`
+	Resources    []OCSFResource    `json:"resources,omitempty"`
+	Time         int64             `json:"time"`
+	ActivityID   int               `json:"activity_id"`
+	CategoryUID  int               `json:"category_uid"`
+	ClassUID     int               `json:"class_uid"`
+	TypeUID      int               `json:"type_uid"`
+	SeverityID   int               `json:"severity_id"`
+	StatusID     int               `json:"status_id"`
+}
+
+type OCSFMetadata struct {
+	Product        OCSFProduct `json:"product"`
+	Version        string      `json:"version"`
+	CorrelationUID string      `json:"correlation_uid,omitempty"`
+	EventCode      string      `json:"event_code,omitempty"`
+}
+
+type OCSFProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Feature    string `json:"feature,omitempty"`
+}
+
+type OCSFResource struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func NewOCSFEvent(log utils.LogLine) OCSFEvent {
+	activityID, activity := getOCSFActivity(log.Actionner)
+	severityID, severity := getOCSFSeverity(log.Priority)
+
+	statusID, status := 0, "Unknown"
+	switch log.Status {
+	case successStr:
+		statusID, status = 1, "Success"
+	case failureStr:
+		statusID, status = 2, "Failure"
+	}
+
+	e := OCSFEvent{
+		Metadata: OCSFMetadata{
+			Product: OCSFProduct{
+				Name:       utils.FalcoTalonStr,
+				VendorName: utils.FalcoTalonStr,
+				Feature:    log.Actionner,
+			},
+			Version:        ocsfVersion,
+			CorrelationUID: log.TraceID,
+			EventCode:      log.Message,
+		},
+		Message:      getMessage(log),
+		Activity:     activity,
+		ActivityID:   activityID,
+		Category:     "Remediation",
+		CategoryUID:  ocsfCategoryUID,
+		Class:        "Remediation Activity",
+		ClassUID:     ocsfClassUID,
+		TypeUID:      ocsfClassUID*100 + activityID,
+		Severity:     severity,
+		SeverityID:   severityID,
+		Status:       status,
+		StatusID:     statusID,
+		StatusDetail: log.Error,
+		Time:         time.Now().UnixMilli(),
+		Unmapped: map[string]string{
+			"rule":      log.Rule,
+			"action":    log.Action,
+			"actionner": log.Actionner,
+			"target":    log.Target,
+			"result":    log.Result,
+			"output":    log.Output,
+		},
+	}
+
+	namespace := getObject(log.Objects, "namespace")
+	for i, j := range log.Objects {
+		if strings.EqualFold(i, "namespace") {
+			continue
+		}
+		e.Resources = append(e.Resources, OCSFResource{Name: j, Type: strings.ToLower(i), Namespace: namespace})
+	}
+	for i, j := range e.Unmapped {
+		if j == "" {
+			delete(e.Unmapped, i)
+		}
+	}
+
+	return e
+}
+
+// getOCSFActivity maps the actionner onto the activities of the Remediation Activity class
+func getOCSFActivity(actionner string) (int, string) {
+	_, name, _ := strings.Cut(actionner, ":")
+	switch name {
+	case "networkpolicy", "cordon":
+		return 1, "Isolate"
+	case "terminate", "delete", "drain":
+		return 2, "Evict"
+	case "script", "exec", "labelize", "annotate":
+		return 4, "Harden"
+	case "log", "tcpdump", "download":
+		return 5, "Detect"
+	default:
+		return 99, "Other"
+	}
+}
+
+// getOCSFSeverity maps the priority of the Falco event onto the severities of OCSF
+func getOCSFSeverity(priority string) (int, string) {
+	switch strings.ToLower(priority) {
+	case "emergency":
+		return 6, "Fatal"
+	case "alert", "critical":
+		return 5, "Critical"
+	case "error":
+		return 4, "High"
+	case "warning":
+		return 3, "Medium"
+	case "notice":
+		return 2, "Low"
+	case "informational", "info", "debug":
+		return 1, "Informational"
+	default:
+		return 0, "Unknown"
+	}
+}
+
+type ECSEvent struct {
+	Labels       map[string]string `json:"labels,omitempty"`
+	Error        *ECSError         `json:"error,omitempty"`
+	Rule         *ECSRule          `json:"rule,omitempty"`
+	Orchestrator *ECSOrchestrator  `json:"orchestrator,omitempty"`
+	Trace        *ECSTrace         `json:"trace,omitempty"`
+	Host         ECSHost           `json:"host"`
+	Observer     ECSObserver       `json:"observer"`
+	ECS          ECSVersion        `json:"ecs"`
+	Timestamp    string            `json:"@timestamp"`
+	Message      string            `json:"message,omitempty"`
+	Event        ECSEventField     `json:"event"`
+}
+
+type ECSEventField struct {
+	Kind     string   `json:"kind"`
+	Category []string `json:"category"`
+	Type     []string `json:"type"`
+	Action   string   `json:"action,omitempty"`
+	Outcome  string   `json:"outcome"`
+	Module   string   `json:"module"`
+	Dataset  string   `json:"dataset"`
+	Reason   string   `json:"reason,omitempty"`
+	Severity int      `json:"severity,omitempty"`
+}
+
+type ECSError struct {
+	Message string `json:"message"`
+}
+
+type ECSRule struct {
+	Name string `json:"name"`
+}
+
+type ECSOrchestrator struct {
+	Resource  *ECSOrchestratorItem `json:"resource,omitempty"`
+	Namespace string               `json:"namespace,omitempty"`
+	Type      string               `json:"type"`
+}
+
+type ECSOrchestratorItem struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+type ECSTrace struct {
+	ID string `json:"id"`
+}
+
+type ECSHost struct {
+	Name string `json:"name,omitempty"`
+}
+
+type ECSObserver struct {
+	Product string `json:"product"`
+	Vendor  string `json:"vendor"`
+	Type    string `json:"type"`
+}
+
+type ECSVersion struct {
+	Version string `json:"version"`
+}
+
+func NewECSEvent(log utils.LogLine) ECSEvent {
+	outcome := "unknown"
+	switch log.Status {
+	case successStr, failureStr:
+		outcome = log.Status
+	}
+
+	severityID, _ := getOCSFSeverity(log.Priority)
+
+	e := ECSEvent{
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Message:   getMessage(log),
+		Event: ECSEventField{
+			Kind:     "event",
+			Category: []string{"intrusion_detection"},
+			Type:     []string{"change"},
+			Action:   log.Action,
+			Outcome:  outcome,
+			Module:   utils.FalcoTalonStr,
+			Dataset:  utils.FalcoTalonStr + "." + log.Message,
+			Reason:   log.Result,
+			Severity: severityID,
+		},
+		Host: ECSHost{Name: hostname},
+		Observer: ECSObserver{
+			Product: utils.FalcoTalonStr,
+			Vendor:  utils.FalcoTalonStr,
+			Type:    "response",
+		},
+		ECS: ECSVersion{Version: ecsVersion},
+	}
+
+	if log.Error != "" {
+		e.Error = &ECSError{Message: log.Error}
+	}
+	if log.Rule != "" {
+		e.Rule = &ECSRule{Name: log.Rule}
+	}
+	if log.TraceID != "" {
+		e.Trace = &ECSTrace{ID: log.TraceID}
+	}
+
+	namespace := getObject(log.Objects, "namespace")
+	if namespace != "" {
+		e.Orchestrator = &ECSOrchestrator{Type: "kubernetes", Namespace: namespace}
+		for _, i := range []string{"pod", "node", "networkpolicy"} {
+			if v := getObject(log.Objects, i); v != "" {
+				e.Orchestrator.Resource = &ECSOrchestratorItem{Name: v, Type: i}
+				break
+			}
+		}
+	}
+	if node := getObject(log.Objects, "node"); node != "" {
+		e.Host.Name = node
+	}
+
+	if len(log.Objects) != 0 || log.Actionner != "" {
+		e.Labels = make(map[string]string, len(log.Objects)+1)
+		for i, j := range log.Objects {
+			e.Labels[strings.ToLower(i)] = j
+		}
+		if log.Actionner != "" {
+			e.Labels["actionner"] = log.Actionner
+		}
+	}
+
+	return e
+}
+
+func getMessage(log utils.LogLine) string {
+	if log.Output != "" {
+		return log.Output
+	}
+	return log.Error
+}
+
+// getObject returns the value of an object, the keys of the objects are title-cased before the notifications
+func getObject(objects map[string]string, key string) string {
+	for i, j := range objects {
+		if strings.EqualFold(i, key) {
+			return j
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package servicebus
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	azure "github.com/falco-talon/falco-talon/internal/azure/client"
+	"github.com/falco-talon/falco-talon/notifiers/cloudevents"
+	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Settings struct {
+	Namespace           string `field:"namespace"`
+	Queue               string `field:"queue"`
+	SharedAccessKeyName string `field:"shared_access_key_name"`
+	SharedAccessKey     string `field:"shared_access_key"`
+	CloudEvents         string `field:"cloudevents"`
+	CloudEventsSource   string `field:"cloudevents_source" default:"falco-talon"`
+	CACertFile          string `field:"ca_cert_file"`
+	TimeoutSeconds      int    `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify  bool   `field:"insecure_skip_verify" default:"false"`
+}
+
+const (
+	resource    string = "https://servicebus.azure.net"
+	contentType string = "application/json"
+	sasTTL             = 1 * time.Hour
+)
+
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings  *Settings
+	transport *http.Transport
+}
+
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	var tlsConfig *tls.Config
+	if n.settings.CACertFile != "" || n.settings.InsecureSkipVerify {
+		var err error
+		if tlsConfig, err = http.NewTLSConfig("", "", n.settings.CACertFile, n.settings.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+	if err := azure.Init(); err != nil {
+		return nil, err
+	}
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.settings.TimeoutSeconds)*time.Second)
+	return n, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	uri := fmt.Sprintf("https://%v.servicebus.windows.net/%v", n.settings.Namespace, n.settings.Queue)
+
+	var authorization string
+	if n.settings.SharedAccessKeyName != "" && n.settings.SharedAccessKey != "" {
+		authorization = azure.GetSASToken(uri, n.settings.SharedAccessKeyName, n.settings.SharedAccessKey, sasTTL)
+	} else {
+		token, err := azure.GetAzureClient().GetToken(resource)
+		if err != nil {
+			return err
+		}
+		authorization = "Bearer " + token
+	}
+
+	client := http.NewClient("", contentType, "", nil)
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
+	client.SetHeader("Authorization", authorization)
+	if log.TraceID != "" {
+		client.SetHeader("BrokerProperties", fmt.Sprintf(`{"MessageId":"%v","Label":"%v"}`, log.TraceID, log.Message))
+	}
+
+	log.Time = time.Now().Format(time.RFC3339)
+
+	if n.settings.CloudEvents == "" {
+		return client.Request(uri+"/messages", log)
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+	body, err := cloudevents.Encode(&client, n.settings.CloudEvents, n.settings.CloudEventsSource, "application/json", log, data)
+	if err != nil {
+		return err
+	}
+
+	return client.RequestBytes(uri+"/messages", body)
+}
+
+func checkSettings(settings *Settings) error {
+	if settings.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
+	if settings.Namespace == "" {
+		return errors.New("wrong `namespace` setting")
+	}
+	if settings.Queue == "" {
+		return errors.New("wrong `queue` setting")
+	}
+	if (settings.SharedAccessKeyName == "") != (settings.SharedAccessKey == "") {
+		return errors.New("`shared_access_key_name` and `shared_access_key` must be set together")
+	}
+	if err := cloudevents.CheckMode(settings.CloudEvents); err != nil {
+		return err
+	}
+
+	return nil
+}
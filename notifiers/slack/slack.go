@@ -1,11 +1,17 @@
 package slack
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"path"
 	"strings"
+	"time"
 
+	"github.com/falco-talon/falco-talon/internal/labels"
 	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/notifiers/templates"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
@@ -20,11 +26,18 @@ const (
 )
 
 type Settings struct {
-	WebhookURL string `field:"webhook_url"`
-	Icon       string `field:"icon" default:"https://upload.wikimedia.org/wikipedia/commons/2/26/Circaetus_gallicus_claw.jpg"`
-	Username   string `field:"username" default:"Falco Talon"`
-	Footer     string `field:"footer" default:"http://github.com/falco-talon/falco-talon"`
-	Format     string `field:"format" default:"long"`
+	WebhookURL         string `field:"webhook_url"`
+	Token              string `field:"token"`   // token of a bot, to post with the Web API instead of a webhook
+	Channel            string `field:"channel"` // channel of the messages posted with the token
+	UndoURL            string `field:"undo_url"`
+	Icon               string `field:"icon" default:"https://upload.wikimedia.org/wikipedia/commons/2/26/Circaetus_gallicus_claw.jpg"`
+	Username           string `field:"username" default:"Falco Talon"`
+	Footer             string `field:"footer" default:"http://github.com/falco-talon/falco-talon"`
+	Format             string `field:"format" default:"long"`
+	CACertFile         string `field:"ca_cert_file"`
+	Threads            bool   `field:"threads" default:"false"` // post the results of the actions as replies to the first message of the event
+	TimeoutSeconds     int    `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify bool   `field:"insecure_skip_verify" default:"false"`
 }
 
 type Field struct {
@@ -44,27 +57,55 @@ type Attachment struct {
 
 // Payload
 type Payload struct {
+	Channel     string       `json:"channel,omitempty"`
+	ThreadTS    string       `json:"thread_ts,omitempty"`
 	Text        string       `json:"text,omitempty"`
 	Username    string       `json:"username,omitempty"`
 	IconURL     string       `json:"icon_url,omitempty"`
 	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
-var settings *Settings
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings  *Settings
+	transport *http.Transport
+}
 
-func Init(fields map[string]interface{}) error {
-	settings = new(Settings)
-	settings = utils.SetFields(settings, fields).(*Settings)
-	if err := checkSettings(settings); err != nil {
-		return err
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	var tlsConfig *tls.Config
+	if n.settings.CACertFile != "" || n.settings.InsecureSkipVerify {
+		var err error
+		if tlsConfig, err = http.NewTLSConfig("", "", n.settings.CACertFile, n.settings.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
 	}
-	return nil
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.settings.TimeoutSeconds)*time.Second)
+	return n, nil
 }
 
-func Notify(log utils.LogLine) error {
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
 	client := http.DefaultClient()
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
 
-	err := client.Request(settings.WebhookURL, NewPayload(log))
+	payload := n.NewPayload(log)
+	text, ok, err := templates.Render("slack", log)
+	if err != nil {
+		return err
+	}
+	if ok {
+		payload = n.newTemplatedPayload(log, text)
+	}
+
+	if n.settings.Token != "" {
+		return n.postMessage(client, log, payload)
+	}
+
+	err = client.Request(n.settings.WebhookURL, payload)
 	if err != nil {
 		return err
 	}
@@ -72,6 +113,20 @@ func Notify(log utils.LogLine) error {
 }
 
 func checkSettings(settings *Settings) error {
+	if settings.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
+	if settings.Token != "" {
+		if settings.Channel == "" {
+			return errors.New("`channel` is required with `token`")
+		}
+		return nil
+	}
+
+	if settings.Threads {
+		return errors.New("`threads` requires `token`, the webhooks don't return the references of the messages")
+	}
+
 	if settings.WebhookURL == "" {
 		return errors.New("wrong `webhook_url` setting")
 	}
@@ -83,20 +138,11 @@ func checkSettings(settings *Settings) error {
 	return nil
 }
 
-func NewPayload(log utils.LogLine) Payload {
+func (n *Notifier) NewPayload(log utils.LogLine) Payload {
 	var attachments []Attachment
 	var attachment Attachment
 
-	var color string
-	switch log.Status {
-	case failureStr:
-		color = Red
-	case successStr:
-		color = Green
-	case ignoredStr:
-		color = Grey
-	}
-	attachment.Color = color
+	attachment.Color = getColor(log.Status)
 
 	text := fmt.Sprintf("[%v][%v] ", log.Status, log.Message)
 	if log.Target != "" {
@@ -111,7 +157,7 @@ func NewPayload(log utils.LogLine) Payload {
 
 	text = strings.TrimSuffix(text, " ")
 
-	if settings.Format == "short" {
+	if n.settings.Format == "short" {
 		attachment.Text = text
 		text = ""
 	} else {
@@ -140,10 +186,21 @@ func NewPayload(log utils.LogLine) Payload {
 		field.Value = "`" + log.Status + "`"
 		field.Short = true
 		fields = append(fields, field)
+		if log.Verification != "" {
+			field.Title = "Verification"
+			field.Value = "`" + log.Verification + "`"
+			field.Short = true
+			fields = append(fields, field)
+		}
 		if len(log.Objects) > 0 {
-			for i, j := range log.Objects {
+			objects := labels.Apply(log.Objects)
+			for _, i := range labels.Keys(objects) {
 				field.Title = i
-				field.Value = "`" + j + "`"
+				field.Value = "`" + objects[i] + "`"
+				if strings.HasPrefix(objects[i], "https://") {
+					// the links to the artifacts stay clickable
+					field.Value = "<" + objects[i] + "|" + path.Base(strings.Split(objects[i], "?")[0]) + ">"
+				}
 				field.Short = true
 				fields = append(fields, field)
 			}
@@ -170,6 +227,15 @@ func NewPayload(log utils.LogLine) Payload {
 			field.Short = false
 			fields = append(fields, field)
 		}
+		if log.UndoID != "" {
+			field.Title = "Undo"
+			field.Value = "`POST /undo/" + log.UndoID + "`"
+			if n.settings.UndoURL != "" {
+				field.Value = "<" + strings.ReplaceAll(n.settings.UndoURL, "{id}", log.UndoID) + "|undo the action>"
+			}
+			field.Short = false
+			fields = append(fields, field)
+		}
 		if log.TraceID != "" {
 			field.Title = "Trace ID"
 			field.Value = "`" + log.TraceID + "`"
@@ -188,9 +254,15 @@ func NewPayload(log utils.LogLine) Payload {
 			field.Short = false
 			fields = append(fields, field)
 		}
+		if log.RawEvent != "" {
+			field.Title = "Event JSON"
+			field.Value = fmt.Sprintf("```\n%v```", log.RawEvent)
+			field.Short = false
+			fields = append(fields, field)
+		}
 
-		if settings.Footer != "" {
-			attachment.Footer = settings.Footer
+		if n.settings.Footer != "" {
+			attachment.Footer = n.settings.Footer
 		}
 
 		attachment.Fallback = ""
@@ -201,10 +273,39 @@ func NewPayload(log utils.LogLine) Payload {
 
 	s := Payload{
 		Text:        text,
-		Username:    settings.Username,
-		IconURL:     settings.Icon,
+		Username:    n.settings.Username,
+		IconURL:     n.settings.Icon,
 		Attachments: attachments,
 	}
 
 	return s
 }
+
+// newTemplatedPayload returns the payload with the message rendered by the templates, as the text of the attachment
+func (n *Notifier) newTemplatedPayload(log utils.LogLine, text string) Payload {
+	return Payload{
+		Username: n.settings.Username,
+		IconURL:  n.settings.Icon,
+		Attachments: []Attachment{
+			{
+				Color:  getColor(log.Status),
+				Text:   text,
+				Footer: n.settings.Footer,
+				Fields: []Field{},
+			},
+		},
+	}
+}
+
+func getColor(status string) string {
+	switch status {
+	case failureStr:
+		return Red
+	case successStr:
+		return Green
+	case ignoredStr:
+		return Grey
+	default:
+		return ""
+	}
+}
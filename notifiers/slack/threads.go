@@ -0,0 +1,65 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/falco-talon/falco-talon/internal/history"
+	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+const postMessageURL string = "https://slack.com/api/chat.postMessage"
+
+type postMessageResponse struct {
+	TS    string `json:"ts"`
+	Error string `json:"error"`
+	OK    bool   `json:"ok"`
+}
+
+// the first message of an event and the replies wait for each other, a reply could start its own thread otherwise
+var threadsMu sync.Mutex
+
+// postMessage posts the message with the Web API, with the threads the messages of an event after the first one
+// are replies to it, its reference is stored with the history to survive the restarts
+func (n *Notifier) postMessage(client http.Client, log utils.LogLine, payload Payload) error {
+	client.SetHeader("Authorization", "Bearer "+n.settings.Token)
+	payload.Channel = n.settings.Channel
+
+	threaded := n.settings.Threads && log.TraceID != ""
+	notifier := "slack:" + n.settings.Channel
+	if threaded {
+		threadsMu.Lock()
+		defer threadsMu.Unlock()
+		ts, err := history.GetThread(notifier, log.TraceID)
+		if err != nil {
+			utils.PrintLog("warning", utils.LogLine{Notifier: "slack", Message: "notification", Error: err.Error(), Result: "the thread of the event can't be read", TraceID: log.TraceID})
+		}
+		payload.ThreadTS = ts
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := client.RequestBytesWithResponse(postMessageURL, b)
+	if err != nil {
+		return err
+	}
+	// the errors of the Web API are returned with a 200
+	var r postMessageResponse
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return err
+	}
+	if !r.OK {
+		return errors.New(r.Error)
+	}
+
+	if threaded && payload.ThreadTS == "" && r.TS != "" {
+		if err := history.SetThread(notifier, log.TraceID, r.TS); err != nil {
+			utils.PrintLog("warning", utils.LogLine{Notifier: "slack", Message: "notification", Error: err.Error(), Result: "the thread of the event can't be stored", TraceID: log.TraceID})
+		}
+	}
+	return nil
+}
@@ -2,9 +2,13 @@ package smtp
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"html"
+	"os"
 	"strings"
 	"time"
 
@@ -13,6 +17,10 @@ import (
 	sasl "github.com/emersion/go-sasl"
 	gosmtp "github.com/emersion/go-smtp"
 
+	"github.com/falco-talon/falco-talon/internal/labels"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+	"github.com/falco-talon/falco-talon/notifiers/templates"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
@@ -23,16 +31,31 @@ const (
 	Text  string = "text"
 
 	rfc2822 string = "Mon Jan 02 15:04:05 -0700 2006"
+
+	tlsNone     string = "none"
+	tlsStartTLS string = "starttls"
+	tlsImplicit string = "implicit"
+
+	authPlain string = "plain"
+	authLogin string = "login"
+	authNone  string = "none"
 )
 
 type Settings struct {
-	HostPort string `field:"host_port"`
-	User     string `field:"user"`
-	Password string `field:"password"`
-	From     string `field:"from"`
-	To       string `field:"to"`
-	Format   string `field:"format" default:"html"`
-	TLS      bool   `field:"tls" default:"false"`
+	RecipientsByRule   map[string]string `field:"recipients_by_rule"`
+	HostPort           string            `field:"host_port"`
+	User               string            `field:"user"`
+	Password           string            `field:"password"`
+	From               string            `field:"from"`
+	To                 string            `field:"to"`
+	Format             string            `field:"format" default:"html"`
+	TLSMode            string            `field:"tls_mode"`
+	AuthMechanism      string            `field:"auth_mechanism" default:"plain"`
+	HTMLTemplateFile   string            `field:"html_template_file"`
+	TextTemplateFile   string            `field:"text_template_file"`
+	CACertFile         string            `field:"ca_cert_file"`
+	TLS                bool              `field:"tls" default:"false"`
+	InsecureSkipVerify bool              `field:"insecure_skip_verify" default:"false"`
 }
 
 // Payload
@@ -45,27 +68,79 @@ type Payload struct {
 	Date    string
 }
 
-var settings *Settings
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings *Settings
+	ttmpl    *textTemplate.Template
+	htmpl    *textTemplate.Template
+	rootCAs  *x509.CertPool
+}
 
-func Init(fields map[string]interface{}) error {
-	settings = new(Settings)
-	settings = utils.SetFields(settings, fields).(*Settings)
-	if err := checkSettings(settings); err != nil {
-		return err
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	if n.settings.TLSMode == "" {
+		// keep the compatibility with the former `tls` setting
+		n.settings.TLSMode = tlsNone
+		if n.settings.TLS {
+			n.settings.TLSMode = tlsStartTLS
+		}
 	}
-	return nil
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+
+	n.rootCAs = outbound.RootCAs()
+	if n.settings.CACertFile != "" {
+		ca, err := os.ReadFile(n.settings.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		n.rootCAs = x509.NewCertPool()
+		if !n.rootCAs.AppendCertsFromPEM(ca) {
+			return nil, errors.New("wrong `ca_cert_file` setting")
+		}
+	}
+
+	var err error
+	t := plaintextTmpl
+	if n.settings.TextTemplateFile != "" {
+		b, err2 := os.ReadFile(n.settings.TextTemplateFile)
+		if err2 != nil {
+			return nil, err2
+		}
+		t = string(b)
+	}
+	n.ttmpl, err = templates.New(Text).Parse(t)
+	if err != nil {
+		return nil, err
+	}
+
+	h := htmlTmpl
+	if n.settings.HTMLTemplateFile != "" {
+		b, err2 := os.ReadFile(n.settings.HTMLTemplateFile)
+		if err2 != nil {
+			return nil, err2
+		}
+		h = string(b)
+	}
+	n.htmpl, err = templates.New("html").Parse(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, nil
 }
 
-func Notify(log utils.LogLine) error {
-	if settings.HostPort == "" {
+func (n *Notifier) Notify(_ context.Context, log utils.LogLine) error {
+	if n.settings.HostPort == "" {
 		return errors.New("wrong `host_port` setting")
 	}
 
-	payload, err := NewPayload(log)
+	payload, err := n.NewPayload(log)
 	if err != nil {
 		return err
 	}
-	err = Send(payload)
+	err = n.Send(payload)
 	if err != nil {
 		return err
 	}
@@ -76,11 +151,25 @@ func checkSettings(settings *Settings) error {
 	if settings.HostPort == "" {
 		return errors.New("wrong `host_port` setting")
 	}
+	if settings.TLSMode != tlsNone && settings.TLSMode != tlsStartTLS && settings.TLSMode != tlsImplicit {
+		return errors.New("wrong `tls_mode` setting")
+	}
+	if settings.AuthMechanism != authPlain && settings.AuthMechanism != authLogin && settings.AuthMechanism != authNone {
+		return errors.New("wrong `auth_mechanism` setting")
+	}
 
 	return nil
 }
 
-func NewPayload(log utils.LogLine) (Payload, error) {
+// getRecipients returns the recipients of the rule if they are set, the default ones otherwise
+func (n *Notifier) getRecipients(rule string) string {
+	if r, ok := n.settings.RecipientsByRule[rule]; ok && r != "" {
+		return r
+	}
+	return n.settings.To
+}
+
+func (n *Notifier) NewPayload(log utils.LogLine) (Payload, error) {
 	subject := fmt.Sprintf("Subject: [falco-talon][%v][%v] ", log.Status, log.Message)
 	if log.Target != "" {
 		subject += fmt.Sprintf("Target '%v' ", log.Target)
@@ -94,51 +183,54 @@ func NewPayload(log utils.LogLine) (Payload, error) {
 	subject = strings.TrimSuffix(subject, " ")
 
 	payload := Payload{
-		From:    fmt.Sprintf("From: %v", settings.From),
-		To:      fmt.Sprintf("To: %v", settings.To),
+		From:    fmt.Sprintf("From: %v", n.settings.From),
+		To:      fmt.Sprintf("To: %v", n.getRecipients(log.Rule)),
 		Subject: subject,
 		Mime:    "MIME-version: 1.0;",
 		Date:    "Date: " + time.Now().Format(rfc2822),
 	}
 
-	if settings.Format != Text {
+	if n.settings.Format != Text {
 		payload.Mime += "\nContent-Type: multipart/alternative; boundary=4t74weu9byeSdJTM\n\n\n--4t74weu9byeSdJTM"
 	}
 
 	payload.Mime += "\nContent-Type: text/plain; charset=\"UTF-8\";\n\n"
 
-	var err error
+	log.Objects = labels.Apply(log.Objects)
 
-	ttmpl := textTemplate.New(Text)
-	ttmpl, err = ttmpl.Parse(plaintextTmpl)
+	var outtext bytes.Buffer
+	// the shared templates replace the built-in text, not the one of the `text_template_file` setting
+	text, ok, err := templates.Render("smtp", log)
 	if err != nil {
 		return Payload{}, err
 	}
-	var outtext bytes.Buffer
-	err = ttmpl.Execute(&outtext, log)
-	if err != nil {
+	if ok && n.settings.TextTemplateFile == "" {
+		outtext.WriteString(text)
+	} else if err := n.ttmpl.Execute(&outtext, log); err != nil {
 		return Payload{}, err
 	}
 
-	if settings.Format == Text {
+	if n.settings.Format == Text {
+		payload.Body = fmt.Sprintf("%v\n%v\n%v\n%v\n%v\n%v",
+			payload.From,
+			payload.To,
+			payload.Subject,
+			payload.Date,
+			payload.Mime,
+			outtext.String(),
+		)
 		return payload, nil
 	}
 
-	htmpl := textTemplate.New("html")
-	htmpl, err = htmpl.Parse(htmlTmpl)
-	if err != nil {
-		return Payload{}, err
-	}
 	var outhtml bytes.Buffer
-	log.Output = strings.ReplaceAll(utils.RemoveSpecialCharacters(log.Output), "\n", "<br>")
-	err = htmpl.Execute(&outhtml, log)
-	if err != nil {
+	if err := n.htmpl.Execute(&outhtml, escapeLog(log)); err != nil {
 		return Payload{}, err
 	}
 
-	payload.Body = fmt.Sprintf("%v\n%v\n%v\n%v\n%v\n%v\n\n%v",
+	payload.Body = fmt.Sprintf("%v\n%v\n%v\n%v\n%v\n%v\n%v\n\n%v",
 		payload.From,
 		payload.To,
+		payload.Subject,
 		payload.Date,
 		payload.Mime,
 		outtext.String(),
@@ -149,30 +241,65 @@ func NewPayload(log utils.LogLine) (Payload, error) {
 	return payload, nil
 }
 
-func Send(payload Payload) error {
-	to := strings.Split(strings.ReplaceAll(settings.To, " ", ""), ",")
-	auth := sasl.NewPlainClient("", settings.User, settings.Password)
+// escapeLog escapes the fields before their insertion in the html template,
+// the events can contain strings controlled by an attacker
+func escapeLog(log utils.LogLine) utils.LogLine {
+	log.Rule = html.EscapeString(log.Rule)
+	log.Action = html.EscapeString(log.Action)
+	log.Actionner = html.EscapeString(log.Actionner)
+	log.Event = html.EscapeString(log.Event)
+	log.Target = html.EscapeString(log.Target)
+	log.Message = html.EscapeString(log.Message)
+	log.Error = html.EscapeString(log.Error)
+	log.Result = html.EscapeString(log.Result)
+	log.Output = strings.ReplaceAll(html.EscapeString(utils.RemoveSpecialCharacters(log.Output)), "\n", "<br>")
+	log.RawEvent = html.EscapeString(log.RawEvent)
+	objects := make(map[string]string, len(log.Objects))
+	for i, j := range log.Objects {
+		objects[html.EscapeString(i)] = html.EscapeString(j)
+	}
+	log.Objects = objects
+	return log
+}
+
+func (n *Notifier) Send(payload Payload) error {
+	to := strings.Split(strings.ReplaceAll(strings.TrimPrefix(payload.To, "To: "), " ", ""), ",")
+
+	tlsCfg := tlspolicy.Apply(&tls.Config{
+		ServerName:         strings.Split(n.settings.HostPort, ":")[0],
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: n.settings.InsecureSkipVerify, //nolint:gosec
+		RootCAs:            n.rootCAs,
+	})
 
 	var smtpClient *gosmtp.Client
 	var err error
-	if settings.TLS {
-		tlsCfg := &tls.Config{
-			ServerName: strings.Split(settings.HostPort, ":")[0],
-			MinVersion: tls.VersionTLS12,
-		}
-		smtpClient, err = gosmtp.DialStartTLS(settings.HostPort, tlsCfg)
-	} else {
-		smtpClient, err = gosmtp.Dial(settings.HostPort)
+	switch n.settings.TLSMode {
+	case tlsStartTLS:
+		smtpClient, err = gosmtp.DialStartTLS(n.settings.HostPort, tlsCfg)
+	case tlsImplicit:
+		smtpClient, err = gosmtp.DialTLS(n.settings.HostPort, tlsCfg)
+	default:
+		smtpClient, err = gosmtp.Dial(n.settings.HostPort)
 	}
 	if err != nil {
 		return err
 	}
+	defer smtpClient.Close()
 
-	err = smtpClient.Auth(auth)
-	if err != nil {
-		return err
+	if n.settings.AuthMechanism != authNone && n.settings.User != "" {
+		var auth sasl.Client
+		if n.settings.AuthMechanism == authLogin {
+			auth = sasl.NewLoginClient(n.settings.User, n.settings.Password)
+		} else {
+			auth = sasl.NewPlainClient("", n.settings.User, n.settings.Password)
+		}
+		if err := smtpClient.Auth(auth); err != nil {
+			return err
+		}
 	}
-	err = smtpClient.SendMail(settings.From, to, strings.NewReader(payload.Body))
+
+	err = smtpClient.SendMail(n.settings.From, to, strings.NewReader(payload.Body))
 	if err != nil {
 		return err
 	}
@@ -14,8 +14,8 @@ Rule: {{ .Rule }}
 Event: {{ .Event }}
 {{- end }}
 Message: {{ .Message }}
-{{- range $key, $value := .Objects }}
-{{ $key }}: {{ $value }}
+{{- range $key := orderedKeys .Objects }}
+{{ $key }}: {{ index $.Objects $key }}
 {{- end }}
 {{- if .Error }}
 Error: {{ .Error }}
@@ -30,6 +30,10 @@ Output:
 {{- if .Target }}
 Target: {{ .Target }}
 {{- end }}
+{{- if .RawEvent }}
+Event JSON:
+{{ .RawEvent }}
+{{- end }}
 Trace ID: {{ .TraceID }}
 `
 
@@ -81,10 +85,10 @@ var htmlTmpl = `
             <td style="background-color:#d1d6da">{{ .Rule }}</td>
         </tr>
         {{ end }}
-        {{ range $key, $value := .Objects }}
+        {{ range $key := orderedKeys .Objects }}
             <tr>
                 <td style="background-color:#858585"><span style="font-size:14px;color:#fff;"><strong>{{ $key }}</strong></span></td>
-                <td style="background-color:#d1d6da">{{ $value }}</td>
+                <td style="background-color:#d1d6da">{{ with index $.Objects $key }}{{ if hasPrefix "https://" . }}<a href="{{ . }}">{{ . }}</a>{{ else }}{{ . }}{{ end }}{{ end }}</td>
             </tr>
         {{ end }}
         {{ if .Event }}
@@ -125,6 +129,12 @@ var htmlTmpl = `
             <td style="background-color:#d1d6da;max-width:502px;overflow:hidden;">{{ printf "%s" .Output }}</td>
         </tr>
         {{ end }}
+        {{ if .RawEvent }}
+        <tr>
+            <td style="background-color:#858585"><span style="font-size:14px;color:#fff;"><strong>Event JSON</strong></span></td>
+            <td style="background-color:#d1d6da;max-width:502px;overflow:hidden;"><pre>{{ .RawEvent }}</pre></td>
+        </tr>
+        {{ end }}
     </tbody>
 </table>
 <br>
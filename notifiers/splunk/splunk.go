@@ -0,0 +1,214 @@
+package splunk
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/falco-talon/falco-talon/metrics"
+	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/notifiers/schema"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Settings struct {
+	CustomHeaders      map[string]string `field:"custom_headers"`
+	URL                string            `field:"url"`
+	Token              string            `field:"token"`
+	Index              string            `field:"index"`
+	Source             string            `field:"source" default:"falco-talon"`
+	SourceType         string            `field:"sourcetype" default:"_json"`
+	Schema             string            `field:"schema"`
+	CACertFile         string            `field:"ca_cert_file"`
+	BatchSize          int               `field:"batch_size" default:"1"`
+	FlushInterval      int               `field:"flush_interval_seconds" default:"5"`
+	MaxBufferSize      int               `field:"max_buffer_size" default:"10000"`
+	TimeoutSeconds     int               `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify bool              `field:"insecure_skip_verify" default:"false"`
+}
+
+// Payload is the format expected by the HTTP Event Collector
+type Payload struct {
+	Event      interface{} `json:"event"`
+	Host       string      `json:"host,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	SourceType string      `json:"sourcetype,omitempty"`
+	Index      string      `json:"index,omitempty"`
+	Time       float64     `json:"time"`
+}
+
+const collectorPath string = "/services/collector/event"
+
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings  *Settings
+	transport *http.Transport
+	stop      chan struct{}
+	batch     []Payload
+	mu        sync.Mutex
+}
+
+var hostname, _ = os.Hostname()
+
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	var tlsConfig *tls.Config
+	if n.settings.CACertFile != "" || n.settings.InsecureSkipVerify {
+		var err error
+		if tlsConfig, err = http.NewTLSConfig("", "", n.settings.CACertFile, n.settings.InsecureSkipVerify); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+
+	if n.settings.BatchSize > 1 {
+		n.stop = make(chan struct{})
+		go n.run(time.Duration(n.settings.FlushInterval) * time.Second)
+	}
+
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.settings.TimeoutSeconds)*time.Second)
+	return n, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	p := n.NewPayload(log)
+
+	if n.settings.BatchSize <= 1 {
+		return n.send(ctx, []Payload{p})
+	}
+
+	n.mu.Lock()
+	n.batch = append(n.batch, p)
+	full := len(n.batch) >= n.settings.BatchSize
+	n.mu.Unlock()
+
+	if full {
+		return n.Flush()
+	}
+	return nil
+}
+
+// run sends the batch at each interval, until the notifier is closed
+func (n *Notifier) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			if err := n.Flush(); err != nil {
+				utils.PrintLog("error", utils.LogLine{Notifier: "splunk", Message: "notification", Error: err.Error(), Status: "failure"})
+			}
+		}
+	}
+}
+
+// Close stops the flushes at regular intervals and sends the buffered events, when the notifier is replaced
+func (n *Notifier) Close() {
+	if n.stop != nil {
+		close(n.stop)
+	}
+	if err := n.Flush(); err != nil {
+		utils.PrintLog("error", utils.LogLine{Notifier: "splunk", Message: "notification", Error: err.Error(), Status: "failure"})
+	}
+}
+
+func checkSettings(settings *Settings) error {
+	if settings.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
+	if settings.URL == "" {
+		return errors.New("wrong `url` setting")
+	}
+	if settings.Token == "" {
+		return errors.New("wrong `token` setting")
+	}
+	if settings.BatchSize > 1 && settings.FlushInterval < 1 {
+		return errors.New("wrong `flush_interval_seconds` setting")
+	}
+	if settings.BatchSize > 1 && settings.MaxBufferSize < settings.BatchSize {
+		return errors.New("wrong `max_buffer_size` setting, it can't be lower than `batch_size`")
+	}
+
+	if err := http.CheckURL(settings.URL); err != nil {
+		return err
+	}
+
+	if err := schema.CheckSchema(settings.Schema); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *Notifier) NewPayload(log utils.LogLine) Payload {
+	now := time.Now()
+	log.Time = now.Format(time.RFC3339)
+	return Payload{
+		Time:       float64(now.UnixMilli()) / 1000,
+		Host:       hostname,
+		Source:     n.settings.Source,
+		SourceType: n.settings.SourceType,
+		Index:      n.settings.Index,
+		Event:      schema.Serialize(log, n.settings.Schema),
+	}
+}
+
+// Flush sends the buffered events
+func (n *Notifier) Flush() error {
+	n.mu.Lock()
+	p := n.batch
+	n.batch = nil
+	n.mu.Unlock()
+
+	if len(p) == 0 {
+		return nil
+	}
+	if err := n.send(context.Background(), p); err != nil {
+		n.requeue(p)
+		return err
+	}
+	return nil
+}
+
+// requeue puts the events of a failed batch back in front of the buffer, they're sent with the next batch, the
+// oldest events are dropped beyond `max_buffer_size`
+func (n *Notifier) requeue(payloads []Payload) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.batch = append(payloads, n.batch...)
+	over := len(n.batch) - n.settings.MaxBufferSize
+	if over <= 0 {
+		return
+	}
+	n.batch = n.batch[over:]
+	metrics.IncreaseCounter(utils.LogLine{Message: "dropped_notification", Notifier: "splunk", Status: "buffer_full"})
+	utils.PrintLog("warning", utils.LogLine{Notifier: "splunk", Message: "notification", Result: fmt.Sprintf("%v event(s) dropped, the buffer is full", over)})
+}
+
+// send posts the events in a single request, the HEC expects concatenated JSON objects
+func (n *Notifier) send(ctx context.Context, payloads []Payload) error {
+	body := new(bytes.Buffer)
+	for _, i := range payloads {
+		if err := json.NewEncoder(body).Encode(i); err != nil {
+			return err
+		}
+	}
+
+	client := http.NewClient("", "", "", n.settings.CustomHeaders)
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
+	client.SetHeader("Authorization", "Splunk "+n.settings.Token)
+
+	return client.RequestBytes(n.settings.URL+collectorPath, body.Bytes())
+}
@@ -0,0 +1,303 @@
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/outbound"
+	"github.com/falco-talon/falco-talon/internal/tlspolicy"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Settings struct {
+	Host               string `field:"host"`
+	Protocol           string `field:"protocol" default:"udp"`
+	Format             string `field:"format" default:"cef"`
+	CACertFile         string `field:"ca_cert_file"`
+	Facility           int    `field:"facility" default:"16"`
+	InsecureSkipVerify bool   `field:"insecure_skip_verify" default:"false"`
+}
+
+const (
+	udpStr  string = "udp"
+	tcpStr  string = "tcp"
+	tlsStr  string = "tls"
+	cefStr  string = "cef"
+	leefStr string = "leef"
+	jsonStr string = "json"
+
+	vendor  string = "Falcosecurity"
+	product string = "Falco Talon"
+
+	timeout = 5 * time.Second
+)
+
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	settings  *Settings
+	tlsConfig *tls.Config
+}
+
+var hostname string
+
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{settings: utils.SetFields(new(Settings), fields).(*Settings)}
+	if err := checkSettings(n.settings); err != nil {
+		return nil, err
+	}
+
+	hostname, _ = os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	if n.settings.Protocol == tlsStr {
+		n.tlsConfig = tlspolicy.Apply(&tls.Config{
+			ServerName:         strings.Split(n.settings.Host, ":")[0],
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: n.settings.InsecureSkipVerify, //nolint:gosec
+			RootCAs:            outbound.RootCAs(),
+		})
+		if n.settings.CACertFile != "" {
+			ca, err := os.ReadFile(n.settings.CACertFile)
+			if err != nil {
+				return nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, errors.New("wrong `ca_cert_file` setting")
+			}
+			n.tlsConfig.RootCAs = pool
+		}
+	}
+	return n, nil
+}
+
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: timeout}
+	switch n.settings.Protocol {
+	case tlsStr:
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: n.tlsConfig}).DialContext(ctx, tcpStr, n.settings.Host)
+	default:
+		conn, err = dialer.DialContext(ctx, n.settings.Protocol, n.settings.Host)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	msg := n.NewMessage(log)
+	if n.settings.Protocol != udpStr {
+		// octet counting framing (RFC6587)
+		msg = fmt.Sprintf("%v %v", len(msg), msg)
+	}
+
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	_, err = conn.Write([]byte(msg))
+	return err
+}
+
+func checkSettings(settings *Settings) error {
+	if settings.Host == "" {
+		return errors.New("wrong `host` setting")
+	}
+	if _, _, err := net.SplitHostPort(settings.Host); err != nil {
+		return errors.New("wrong `host` setting")
+	}
+	if settings.Protocol != udpStr && settings.Protocol != tcpStr && settings.Protocol != tlsStr {
+		return errors.New("wrong `protocol` setting")
+	}
+	if settings.Format != cefStr && settings.Format != leefStr && settings.Format != jsonStr {
+		return errors.New("wrong `format` setting")
+	}
+	if settings.Facility < 0 || settings.Facility > 23 {
+		return errors.New("wrong `facility` setting")
+	}
+
+	return nil
+}
+
+// NewMessage returns the message with the RFC5424 format
+func (n *Notifier) NewMessage(log utils.LogLine) string {
+	var severity int
+	switch log.Status {
+	case "failure":
+		severity = 3 // error
+	case "success":
+		severity = 6 // informational
+	default:
+		severity = 5 // notice
+	}
+
+	msgID := log.Message
+	if msgID == "" {
+		msgID = "-"
+	}
+
+	var body string
+	switch n.settings.Format {
+	case leefStr:
+		body = NewLEEF(log)
+	case jsonStr:
+		log.Time = time.Now().Format(time.RFC3339)
+		b, _ := json.Marshal(log)
+		body = string(b)
+	default:
+		body = NewCEF(log)
+	}
+
+	return fmt.Sprintf("<%v>1 %v %v %v %v %v - %v",
+		n.settings.Facility*8+severity,
+		time.Now().Format(time.RFC3339),
+		hostname,
+		utils.FalcoTalonStr,
+		os.Getpid(),
+		msgID,
+		body,
+	)
+}
+
+func NewCEF(log utils.LogLine) string {
+	severity := 3
+	if log.Status == "failure" {
+		severity = 7
+	}
+
+	name := log.Message
+	if log.Action != "" {
+		name += " " + log.Action
+	}
+
+	ext := []string{
+		"rt=" + fmt.Sprintf("%v", time.Now().UnixMilli()),
+		"outcome=" + escapeCEFExtension(log.Status),
+	}
+	if log.Rule != "" {
+		ext = append(ext, "cs1Label=rule", "cs1="+escapeCEFExtension(log.Rule))
+	}
+	if log.Actionner != "" {
+		ext = append(ext, "cs2Label=actionner", "cs2="+escapeCEFExtension(log.Actionner))
+	}
+	if log.Target != "" {
+		ext = append(ext, "cs3Label=target", "cs3="+escapeCEFExtension(log.Target))
+	}
+	if o := getObjects(log); o != "" {
+		ext = append(ext, "cs4Label=objects", "cs4="+escapeCEFExtension(o))
+	}
+	if log.Action != "" {
+		ext = append(ext, "act="+escapeCEFExtension(log.Action))
+	}
+	if m := getText(log); m != "" {
+		ext = append(ext, "msg="+escapeCEFExtension(m))
+	}
+	if log.TraceID != "" {
+		ext = append(ext, "externalId="+escapeCEFExtension(log.TraceID))
+	}
+
+	return fmt.Sprintf("CEF:0|%v|%v|%v|%v|%v|%v|%v",
+		escapeCEFHeader(vendor),
+		escapeCEFHeader(product),
+		escapeCEFHeader(configuration.GetInfo().GitVersion),
+		escapeCEFHeader(log.Message),
+		escapeCEFHeader(name),
+		severity,
+		strings.Join(ext, " "),
+	)
+}
+
+func NewLEEF(log utils.LogLine) string {
+	attrs := []string{
+		"devTime=" + time.Now().Format("Jan 02 2006 15:04:05"),
+		"devTimeFormat=MMM dd yyyy HH:mm:ss",
+		"status=" + escapeLEEF(log.Status),
+	}
+	if log.Rule != "" {
+		attrs = append(attrs, "rule="+escapeLEEF(log.Rule))
+	}
+	if log.Action != "" {
+		attrs = append(attrs, "action="+escapeLEEF(log.Action))
+	}
+	if log.Actionner != "" {
+		attrs = append(attrs, "actionner="+escapeLEEF(log.Actionner))
+	}
+	if log.Target != "" {
+		attrs = append(attrs, "target="+escapeLEEF(log.Target))
+	}
+	keys := make([]string, 0, len(log.Objects))
+	for i := range log.Objects {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	for _, i := range keys {
+		attrs = append(attrs, strings.ToLower(i)+"="+escapeLEEF(log.Objects[i]))
+	}
+	if m := getText(log); m != "" {
+		attrs = append(attrs, "msg="+escapeLEEF(m))
+	}
+	if log.TraceID != "" {
+		attrs = append(attrs, "traceId="+escapeLEEF(log.TraceID))
+	}
+
+	return fmt.Sprintf("LEEF:1.0|%v|%v|%v|%v|%v",
+		escapeCEFHeader(vendor),
+		escapeCEFHeader(product),
+		escapeCEFHeader(configuration.GetInfo().GitVersion),
+		escapeCEFHeader(log.Message),
+		strings.Join(attrs, "\t"),
+	)
+}
+
+func getText(log utils.LogLine) string {
+	if log.Error != "" {
+		return log.Error
+	}
+	if log.Result != "" {
+		return log.Result
+	}
+	return log.Output
+}
+
+func getObjects(log utils.LogLine) string {
+	keys := make([]string, 0, len(log.Objects))
+	for i := range log.Objects {
+		keys = append(keys, i)
+	}
+	sort.Strings(keys)
+	o := make([]string, 0, len(keys))
+	for _, i := range keys {
+		o = append(o, strings.ToLower(i)+":"+log.Objects[i])
+	}
+	return strings.Join(o, ",")
+}
+
+func escapeCEFHeader(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func escapeCEFExtension(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "=", `\=`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+func escapeLEEF(s string) string {
+	s = strings.ReplaceAll(s, "\t", " ")
+	s = strings.ReplaceAll(s, "\r\n", " ")
+	return strings.ReplaceAll(s, "\n", " ")
+}
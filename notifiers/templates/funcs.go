@@ -0,0 +1,212 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	textTemplate "text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/falco-talon/falco-talon/internal/labels"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// FuncMap returns the functions of the templates, a small set named after the functions of sprig with the same
+// order of arguments, not a compatible implementation: the functions not listed here aren't available, and the
+// ones reading the environment (env, expandenv) are deliberately absent
+func FuncMap() textTemplate.FuncMap {
+	return textTemplate.FuncMap{
+		// strings
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      func(s string) string { return cases.Title(language.Und, cases.NoLower).String(s) },
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"replace":    func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+		"repeat":     func(n int, s string) string { return strings.Repeat(s, max(n, 0)) },
+		"trunc":      trunc,
+		"abbrev":     abbrev,
+		"quote":      func(s interface{}) string { return fmt.Sprintf("%q", toString(s)) },
+		"squote":     func(s interface{}) string { return "'" + toString(s) + "'" },
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"toString":   toString,
+		// defaults
+		"default":  dfault,
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary": func(vt, vf interface{}, b bool) interface{} {
+			if b {
+				return vt
+			}
+			return vf
+		},
+		// lists and dicts
+		"list": func(v ...interface{}) []interface{} { return v },
+		"dict": dict,
+		"keys": keys,
+		"get":  func(m map[string]string, k string) string { return m[k] },
+		"has":  has,
+		// the keys in the order of the mapping of the fields
+		"orderedKeys": labels.Keys[string],
+		// encoding
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		// dates
+		"now":       time.Now,
+		"date":      func(layout string, t time.Time) string { return t.Format(layout) },
+		"unixEpoch": func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) },
+	}
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case fmt.Stringer:
+		return s.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// trunc keeps the n first characters, or the n last ones for a negative n
+func trunc(n int, s string) string {
+	r := []rune(s)
+	switch {
+	case n >= 0 && len(r) > n:
+		return string(r[:n])
+	case n < 0 && len(r) > -n:
+		return string(r[len(r)+n:])
+	default:
+		return s
+	}
+}
+
+// abbrev truncates the string with an ellipsis, n is the maximum length with the ellipsis
+func abbrev(n int, s string) string {
+	if n < 4 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return trunc(n-3, s) + "..."
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", max(n, 0))
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func join(sep string, v interface{}) string {
+	switch l := v.(type) {
+	case []string:
+		return strings.Join(l, sep)
+	case nil:
+		return ""
+	}
+	r := reflect.ValueOf(v)
+	if r.Kind() != reflect.Slice && r.Kind() != reflect.Array {
+		return toString(v)
+	}
+	s := make([]string, 0, r.Len())
+	for i := 0; i < r.Len(); i++ {
+		s = append(s, toString(r.Index(i).Interface()))
+	}
+	return strings.Join(s, sep)
+}
+
+// empty returns true for the zero values, the empty lists and the empty maps
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.String:
+		return r.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return r.IsNil()
+	default:
+		return r.IsZero()
+	}
+}
+
+// dfault returns the value if it isn't empty, the default otherwise, the value is the last argument as for
+// the pipelines: {{ .Rule | default "none" }}
+func dfault(d interface{}, v ...interface{}) interface{} {
+	if len(v) == 0 || empty(v[0]) {
+		return d
+	}
+	return v[0]
+}
+
+func coalesce(v ...interface{}) interface{} {
+	for _, i := range v {
+		if !empty(i) {
+			return i
+		}
+	}
+	return nil
+}
+
+func dict(v ...interface{}) (map[string]interface{}, error) {
+	if len(v)%2 != 0 {
+		return nil, errors.New("dict requires pairs of keys and values")
+	}
+	d := make(map[string]interface{}, len(v)/2)
+	for i := 0; i < len(v); i += 2 {
+		d[toString(v[i])] = v[i+1]
+	}
+	return d, nil
+}
+
+func has(m map[string]string, k string) bool {
+	_, ok := m[k]
+	return ok
+}
+
+// keys returns the sorted keys of the maps
+func keys(v interface{}) []string {
+	r := reflect.ValueOf(v)
+	if r.Kind() != reflect.Map {
+		return nil
+	}
+	k := make([]string, 0, r.Len())
+	for _, i := range r.MapKeys() {
+		k = append(k, toString(i.Interface()))
+	}
+	sort.Strings(k)
+	return k
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func toPrettyJSON(v interface{}) (string, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
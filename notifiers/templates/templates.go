@@ -0,0 +1,213 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	textTemplate "text/template"
+
+	"github.com/falco-talon/falco-talon/internal/labels"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// the name of the default message template, in the settings and in the directory
+const messageTemplate string = "message"
+
+// built-in partials, the settings and the files can override them
+var builtinPartials = map[string]string{
+	// the one-line summary, as the subjects of the emails and the titles of the notifications
+	"title": `[{{ .Status }}][{{ .Message }}]
+{{- with .Target }} Target '{{ . }}'{{ end }}
+{{- with .Action }} Action '{{ . }}'{{ end }}
+{{- with .Rule }} Rule '{{ . }}'{{ end }}`,
+	// the fields of the log, one by line
+	"details": `Status: {{ .Status }}
+Message: {{ .Message }}
+{{- with .Rule }}
+Rule: {{ . }}
+{{- end }}
+{{- with .Action }}
+Action: {{ . }}
+{{- end }}
+{{- with .Actionner }}
+Actionner: {{ . }}
+{{- end }}
+{{- with .Verification }}
+Verification: {{ . }}
+{{- end }}
+{{- with .Event }}
+Event: {{ . }}
+{{- end }}
+{{- range $key := orderedKeys .Objects }}
+{{ $key }}: {{ index $.Objects $key }}
+{{- end }}
+{{- with .Error }}
+Error: {{ . }}
+{{- end }}
+{{- with .Result }}
+Result: {{ . }}
+{{- end }}
+{{- with .Output }}
+Output: {{ . }}
+{{- end }}
+{{- with .Target }}
+Target: {{ . }}
+{{- end }}
+{{- with .RawEvent }}
+Event JSON: {{ . }}
+{{- end }}
+TraceID: {{ .TraceID }}
+`,
+}
+
+// Config are the shared templates of the messages of the notifiers
+type Config struct {
+	Partials  map[string]string // templates usable by the others with {{ template "name" . }}
+	Notifiers map[string]string // message template by notifier
+	Message   string            // default message template of the notifiers
+	Directory string            // directory of *.tmpl files, eg: a mounted ConfigMap
+}
+
+type set struct {
+	base     *textTemplate.Template
+	messages map[string]*textTemplate.Template
+	message  *textTemplate.Template
+}
+
+var (
+	current *set
+	mu      sync.RWMutex
+)
+
+func init() {
+	s, err := newSet(Config{})
+	if err != nil {
+		panic(err)
+	}
+	current = s
+}
+
+// Init parses the templates, the files of the directory are partials named after the files without their
+// extension, those named after a notifier or `message` are message templates if the settings don't set them
+func Init(config Config) error {
+	s, err := newSet(config)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	current = s
+	mu.Unlock()
+	return nil
+}
+
+func newSet(config Config) (*set, error) {
+	base := textTemplate.New("").Funcs(FuncMap())
+	for i, j := range builtinPartials {
+		if _, err := base.New(i).Parse(j); err != nil {
+			return nil, fmt.Errorf("wrong built-in template '%v': %v", i, err)
+		}
+	}
+
+	files := make(map[string]bool)
+	if config.Directory != "" {
+		list, err := filepath.Glob(filepath.Join(config.Directory, "*.tmpl"))
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range list {
+			b, err := os.ReadFile(i)
+			if err != nil {
+				return nil, err
+			}
+			name := strings.TrimSuffix(filepath.Base(i), ".tmpl")
+			if _, err := base.New(name).Parse(string(b)); err != nil {
+				return nil, fmt.Errorf("wrong template file '%v': %v", i, err)
+			}
+			files[name] = true
+		}
+	}
+
+	for i, j := range config.Partials {
+		if _, err := base.New(i).Parse(j); err != nil {
+			return nil, fmt.Errorf("wrong partial '%v': %v", i, err)
+		}
+	}
+
+	s := &set{base: base, messages: make(map[string]*textTemplate.Template)}
+	for i, j := range config.Notifiers {
+		t, err := base.New("notifiers." + i).Parse(j)
+		if err != nil {
+			return nil, fmt.Errorf("wrong template of the notifier '%v': %v", i, err)
+		}
+		s.messages[i] = t
+	}
+	for i := range files {
+		if _, ok := s.messages[i]; !ok && i != messageTemplate {
+			s.messages[i] = base.Lookup(i)
+		}
+	}
+
+	switch {
+	case config.Message != "":
+		t, err := base.New("notifiers." + messageTemplate).Parse(config.Message)
+		if err != nil {
+			return nil, fmt.Errorf("wrong message template: %v", err)
+		}
+		s.message = t
+	case files[messageTemplate]:
+		s.message = base.Lookup(messageTemplate)
+	}
+
+	return s, nil
+}
+
+// New returns a template with the functions and the partials, for the templates of the settings of the notifiers
+func New(name string) *textTemplate.Template {
+	mu.RLock()
+	defer mu.RUnlock()
+	t, err := current.base.Clone()
+	if err != nil {
+		// the base templates are never executed, the clone can't fail
+		return textTemplate.New(name).Funcs(FuncMap())
+	}
+	return t.New(name)
+}
+
+// Render returns the message of the notifier rendered with its template or the default one, false is returned
+// if none is set, the notifier keeps its own format, the objects have the labels of the mapping of the fields
+func Render(notifier string, log utils.LogLine) (string, bool, error) {
+	mu.RLock()
+	t, ok := current.messages[notifier]
+	if !ok {
+		t = current.message
+	}
+	mu.RUnlock()
+	if t == nil {
+		return "", false, nil
+	}
+	var buf bytes.Buffer
+	log.Objects = labels.Apply(log.Objects)
+	if err := t.Execute(&buf, log); err != nil {
+		return "", true, err
+	}
+	return buf.String(), true, nil
+}
+
+// RenderPartial returns the partial rendered with the log, for the built-in formats of the notifiers
+func RenderPartial(name string, log utils.LogLine) (string, error) {
+	mu.RLock()
+	t := current.base.Lookup(name)
+	mu.RUnlock()
+	if t == nil {
+		return "", fmt.Errorf("unknown template '%v'", name)
+	}
+	var buf bytes.Buffer
+	log.Objects = labels.Apply(log.Objects)
+	if err := t.Execute(&buf, log); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
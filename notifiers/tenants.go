@@ -0,0 +1,111 @@
+package notifiers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// getTenantNotifiers returns the notifiers used by the rules of each tenant
+func getTenantNotifiers() map[string][]string {
+	list := make(map[string][]string)
+	for _, i := range *rules.GetRules() {
+		if i.GetTenant() == "" {
+			continue
+		}
+		for _, j := range i.GetNotifiers() {
+			list[i.GetTenant()] = append(list[i.GetTenant()], strings.ToLower(j))
+		}
+	}
+	return list
+}
+
+// isUsedByTenants returns true if the notifier is used by the rules of a tenant
+func isUsedByTenants(notifier string) bool {
+	for _, i := range getTenantNotifiers() {
+		if slices.Contains(i, notifier) {
+			return true
+		}
+	}
+	return false
+}
+
+// initTenants checks the settings of the notifiers of the tenants, enables the notifiers they use and adds their
+// instances to the list, the notifiers without settings in their tenant can't be used by its rules, the global
+// settings aren't shared
+func initTenants(config *configuration.Configuration, list map[instanceKey]Instance) {
+	for tenant, names := range getTenantNotifiers() {
+		t, _ := config.GetTenant(tenant)
+		for _, i := range utils.Deduplicate(names) {
+			n := availableNotifiers.FindNotifier(i)
+			if n == nil {
+				continue
+			}
+			if n.New != nil {
+				fields, ok := t.Notifiers[i]
+				if !ok {
+					failedNotifiers[tenant+"/"+i] = "no settings in the tenant"
+					utils.PrintLog("error", utils.LogLine{Notifier: i, Message: "init", Error: "no settings in the tenant", Status: "failure", Objects: map[string]string{"tenant": tenant}})
+					continue
+				}
+				instance, err := n.New(fields)
+				if err != nil {
+					failedNotifiers[tenant+"/"+i] = err.Error()
+					utils.PrintLog("error", utils.LogLine{Notifier: i, Message: "init", Error: err.Error(), Status: "failure", Objects: map[string]string{"tenant": tenant}})
+					continue
+				}
+				list[instanceKey{notifier: n.Name, tenant: tenant}] = instance
+				utils.PrintLog("info", utils.LogLine{Notifier: i, Message: "init", Status: "success", Objects: map[string]string{"tenant": tenant}})
+			}
+			if enabledNotifiers.FindNotifier(i) == nil {
+				enabledNotifiers.Add(n)
+			}
+		}
+	}
+}
+
+// updateTenants inits the notifiers of the tenants with the settings of a new configuration, the notifiers without
+// settings in their tenant stay disabled for it
+func updateTenants(next *configuration.Configuration, updated *Notifiers, list map[instanceKey]Instance) error {
+	for tenant, names := range getTenantNotifiers() {
+		t, _ := next.GetTenant(tenant)
+		for _, i := range utils.Deduplicate(names) {
+			n := availableNotifiers.FindNotifier(i)
+			if n == nil {
+				continue
+			}
+			if n.New != nil {
+				fields, ok := t.Notifiers[i]
+				if !ok {
+					continue
+				}
+				instance, err := n.New(fields)
+				if err != nil {
+					return fmt.Errorf("%v/%v: %v", tenant, i, err)
+				}
+				list[instanceKey{notifier: n.Name, tenant: tenant}] = instance
+			}
+			if updated.FindNotifier(i) == nil {
+				updated.Add(n)
+			}
+		}
+	}
+	return nil
+}
+
+// sendFor sends the notification with the instance of the tenant, with the global one if the tenant is empty
+func sendFor(tenant string, notifier *Notifier, log utils.LogLine) error {
+	instance := getInstance(notifier, tenant)
+	if instance == nil {
+		if tenant != "" {
+			return fmt.Errorf("no settings for the notifier in the tenant '%v'", tenant)
+		}
+		return errors.New("no global settings for the notifier")
+	}
+	return send(notifier, instance, log)
+}
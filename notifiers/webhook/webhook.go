@@ -1,51 +1,180 @@
 package webhook
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	textTemplate "text/template"
+	"time"
 
+	"github.com/falco-talon/falco-talon/notifiers/cloudevents"
 	"github.com/falco-talon/falco-talon/notifiers/http"
+	"github.com/falco-talon/falco-talon/notifiers/schema"
+	"github.com/falco-talon/falco-talon/notifiers/templates"
 	"github.com/falco-talon/falco-talon/utils"
 )
 
 type Configuration struct {
-	CustomHeaders map[string]string `field:"custom_headers"`
-	URL           string            `field:"url"`
-	HTTPMethod    string            `field:"http_method" default:"POST"`
-	ContentType   string            `field:"content_type" default:"application/json; charset=utf-8"`
-	UserAgent     string            `field:"user_agent" default:"falco-talon"`
+	CustomHeaders      map[string]string `field:"custom_headers"`
+	SuccessCodes       []string          `field:"success_codes"`
+	URL                string            `field:"url"`
+	HTTPMethod         string            `field:"http_method" default:"POST"`
+	ContentType        string            `field:"content_type" default:"application/json; charset=utf-8"`
+	UserAgent          string            `field:"user_agent" default:"falco-talon"`
+	BodyTemplate       string            `field:"body_template"`
+	ClientCertFile     string            `field:"client_cert_file"`
+	ClientKeyFile      string            `field:"client_key_file"`
+	CACertFile         string            `field:"ca_cert_file"`
+	CloudEvents        string            `field:"cloudevents"`
+	CloudEventsSource  string            `field:"cloudevents_source" default:"falco-talon"`
+	Schema             string            `field:"schema"`
+	MaxRetries         int               `field:"max_retries" default:"0"`
+	RetryBackoffMs     int               `field:"retry_backoff_ms" default:"500"`
+	MaxRetryBackoffMs  int               `field:"max_retry_backoff_ms" default:"30000"`
+	TimeoutSeconds     int               `field:"timeout_seconds" default:"10"`
+	InsecureSkipVerify bool              `field:"insecure_skip_verify" default:"false"`
 }
 
-var config *Configuration
+// Notifier is the notifier inited with its settings, the tenants and the routes have their own
+type Notifier struct {
+	config       *Configuration
+	transport    *http.Transport
+	bodyTemplate *textTemplate.Template
+	successCodes []int
+}
 
-func Init(fields map[string]interface{}) error {
-	config = new(Configuration)
-	config = utils.SetFields(config, fields).(*Configuration)
-	return nil
+func New(fields map[string]interface{}) (*Notifier, error) {
+	n := &Notifier{config: utils.SetFields(new(Configuration), fields).(*Configuration)}
+	if err := checkSettings(n.config); err != nil {
+		return nil, err
+	}
+
+	var err error
+	var tlsConfig *tls.Config
+	if n.config.ClientCertFile != "" || n.config.CACertFile != "" || n.config.InsecureSkipVerify {
+		tlsConfig, err = http.NewTLSConfig(n.config.ClientCertFile, n.config.ClientKeyFile, n.config.CACertFile, n.config.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if n.config.BodyTemplate != "" {
+		n.bodyTemplate, err = templates.New("body").Funcs(textTemplate.FuncMap{
+			"json": toJSON,
+		}).Parse(n.config.BodyTemplate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, i := range n.config.SuccessCodes {
+		c, err2 := strconv.Atoi(strings.TrimSpace(i))
+		if err2 != nil {
+			return nil, fmt.Errorf("wrong `success_codes` setting: %v", err2)
+		}
+		n.successCodes = append(n.successCodes, c)
+	}
+
+	n.transport = http.NewTransport(tlsConfig, time.Duration(n.config.TimeoutSeconds)*time.Second)
+	return n, nil
 }
 
-func CheckParameters(settings map[string]interface{}) error {
-	if settings["url"].(string) == "" {
+func checkSettings(config *Configuration) error {
+	if config.TimeoutSeconds < 1 {
+		return errors.New("wrong `timeout_seconds` setting")
+	}
+	if config.URL == "" {
 		return errors.New("wrong `url` setting")
 	}
 
-	if err := http.CheckURL(settings["url"].(string)); err != nil {
+	if err := http.CheckURL(config.URL); err != nil {
+		return err
+	}
+
+	if (config.ClientCertFile == "") != (config.ClientKeyFile == "") {
+		return errors.New("`client_cert_file` and `client_key_file` must be set together")
+	}
+
+	if config.MaxRetries < 0 {
+		return errors.New("wrong `max_retries` setting")
+	}
+
+	if config.MaxRetries > 0 && (config.RetryBackoffMs < 1 || config.MaxRetryBackoffMs < config.RetryBackoffMs) {
+		return errors.New("wrong `retry_backoff_ms` or `max_retry_backoff_ms` setting")
+	}
+
+	if err := cloudevents.CheckMode(config.CloudEvents); err != nil {
+		return err
+	}
+
+	if err := schema.CheckSchema(config.Schema); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func Notify(log utils.LogLine) error {
+func (n *Notifier) Notify(ctx context.Context, log utils.LogLine) error {
 	client := http.NewClient(
-		config.HTTPMethod,
-		config.ContentType,
-		config.UserAgent,
-		config.CustomHeaders,
+		n.config.HTTPMethod,
+		n.config.ContentType,
+		n.config.UserAgent,
+		n.config.CustomHeaders,
 	)
+	client.SetTransport(n.transport)
+	client.SetContext(ctx)
+	if len(n.successCodes) != 0 {
+		client.SetSuccessCodes(n.successCodes)
+	}
+
+	var body []byte
+	if n.bodyTemplate != nil {
+		var buf bytes.Buffer
+		if err := n.bodyTemplate.Execute(&buf, log); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	} else {
+		var err error
+		body, err = json.Marshal(schema.Serialize(log, n.config.Schema))
+		if err != nil {
+			return err
+		}
+	}
 
-	err := client.Request(config.URL, log)
+	body, err := cloudevents.Encode(&client, n.config.CloudEvents, n.config.CloudEventsSource, n.config.ContentType, log, body)
 	if err != nil {
 		return err
 	}
-	return nil
+
+	backoff := time.Duration(n.config.RetryBackoffMs) * time.Millisecond
+	maxBackoff := time.Duration(n.config.MaxRetryBackoffMs) * time.Millisecond
+	for i := 0; ; i++ {
+		err = client.RequestBytes(n.config.URL, body)
+		if err == nil || i >= n.config.MaxRetries || !http.IsRetryable(err) {
+			return err
+		}
+		// exponential backoff, capped, with a jitter to not retry all at once: between half and all of it,
+		// the retries stop with the timeout of the notifier
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))): //nolint:gosec
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+func toJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
 }
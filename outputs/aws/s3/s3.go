@@ -10,6 +10,7 @@ import (
 
 	awssdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 
 	aws "github.com/falco-talon/falco-talon/internal/aws/client"
 	"github.com/falco-talon/falco-talon/internal/rules"
@@ -18,9 +19,13 @@ import (
 )
 
 type Config struct {
-	Bucket string `mapstructure:"bucket" validate:"required"`
-	Prefix string `mapstructure:"prefix" validate:""`
-	Region string `mapstructure:"region" validate:""`
+	Bucket               string `mapstructure:"bucket" validate:"required"`
+	Prefix               string `mapstructure:"prefix" validate:""`
+	Region               string `mapstructure:"region" validate:""`
+	ServerSideEncryption string `mapstructure:"server_side_encryption" validate:"omitempty,oneof=AES256 aws:kms aws:kms:dsse"`
+	KMSKeyID             string `mapstructure:"kms_key_id" validate:""`
+	StorageClass         string `mapstructure:"storage_class" validate:""`
+	PresignExpiry        int    `mapstructure:"presign_expiry_minutes" validate:"gte=0,lte=10080"` // 7 days max for the SigV4 signatures
 }
 
 func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
@@ -35,16 +40,17 @@ func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
 		}, err
 	}
 
-	config.Prefix = strings.TrimSuffix(config.Prefix, "/")
-	config.Prefix = strings.TrimPrefix(config.Prefix, "/") + "/"
-
-	var key string
-	if data.Namespace != "" && data.Pod != "" {
-		key = fmt.Sprintf("%v_%v_%v_%v", time.Now().Format("2006-01-02T15-04-05Z"), data.Namespace, data.Pod, strings.ReplaceAll(data.Name, "/", "_"))
-	} else {
-		key = fmt.Sprintf("%v_%v_%v", time.Now().Format("2006-01-02T15-04-05Z"), data.Hostname, strings.ReplaceAll(data.Name, "/", "_"))
+	config.Prefix, err = data.RenderPrefix(config.Prefix)
+	if err != nil {
+		return utils.LogLine{
+			Objects: nil,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
 	}
 
+	key := data.GetKey()
+
 	var region string
 	awsClient := aws.GetAWSClient()
 	if awsClient != nil {
@@ -62,7 +68,11 @@ func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
 		"region": region,
 	}
 
-	if err := putObject(region, config.Bucket, config.Prefix, key, *data); err != nil {
+	if config.ServerSideEncryption != "" {
+		objects["sse"] = config.ServerSideEncryption
+	}
+
+	if err := putObject(region, key, config, *data); err != nil {
 		return utils.LogLine{
 			Objects: objects,
 			Error:   err.Error(),
@@ -70,6 +80,16 @@ func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
 		}, err
 	}
 
+	if config.PresignExpiry != 0 {
+		// the upload succeeded, the notifications are sent without the link if it can't be signed
+		u, err := presignObject(region, key, config)
+		if err != nil {
+			utils.PrintLog("warning", utils.LogLine{Message: "output", Target: "aws:s3", Error: err.Error(), Result: "the pre-signed url can't be created"})
+		} else {
+			objects["url"] = u
+		}
+	}
+
 	return utils.LogLine{
 		Objects: objects,
 		Output:  fmt.Sprintf("the file '%v' has been uploaded as the key '%v' to the bucket '%v'", data.Name, config.Prefix+key, config.Bucket),
@@ -92,10 +112,18 @@ func CheckParameters(output *rules.Output) error {
 		return err
 	}
 
+	if config.KMSKeyID != "" && !strings.HasPrefix(config.ServerSideEncryption, "aws:kms") {
+		return errors.New("`kms_key_id` requires `server_side_encryption` to be 'aws:kms' or 'aws:kms:dsse'")
+	}
+
+	if _, err := new(model.Data).RenderPrefix(config.Prefix); err != nil {
+		return fmt.Errorf("wrong `prefix`: %v", err)
+	}
+
 	return nil
 }
 
-func putObject(region, bucket, prefix, key string, data model.Data) error {
+func putObject(region, key string, config Config, data model.Data) error {
 	client := aws.GetS3Client()
 	if client == nil {
 		return errors.New("client error")
@@ -108,17 +136,44 @@ func putObject(region, bucket, prefix, key string, data model.Data) error {
 		o.Region = region
 	}
 
-	_, err := client.PutObject(
-		ctx,
-		&s3.PutObjectInput{
-			Bucket: awssdk.String(bucket),
-			Key:    awssdk.String(prefix + key),
-			Body:   body,
-		},
-		opts,
-	)
+	input := &s3.PutObjectInput{
+		Bucket: awssdk.String(config.Bucket),
+		Key:    awssdk.String(config.Prefix + key),
+		Body:   body,
+	}
+	if config.ServerSideEncryption != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(config.ServerSideEncryption)
+	}
+	if config.KMSKeyID != "" {
+		input.SSEKMSKeyId = awssdk.String(config.KMSKeyID)
+	}
+	if config.StorageClass != "" {
+		input.StorageClass = types.StorageClass(config.StorageClass)
+	}
+
+	_, err := client.PutObject(ctx, input, opts)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// presignObject returns a pre-signed url to download the object, for the responders without access to the bucket
+func presignObject(region, key string, config Config) (string, error) {
+	client := aws.GetS3Client()
+	if client == nil {
+		return "", errors.New("client error")
+	}
+
+	req, err := s3.NewPresignClient(client).PresignGetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: awssdk.String(config.Bucket),
+		Key:    awssdk.String(config.Prefix + key),
+	},
+		s3.WithPresignExpires(time.Duration(config.PresignExpiry)*time.Minute),
+		s3.WithPresignClientFromClientOptions(func(o *s3.Options) { o.Region = region }),
+	)
+	if err != nil {
+		return "", err
+	}
+	return req.URL, nil
+}
@@ -0,0 +1,168 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	azure "github.com/falco-talon/falco-talon/internal/azure/client"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/outputs/model"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Config struct {
+	StorageAccount string `mapstructure:"storage_account" validate:"required"`
+	Container      string `mapstructure:"container" validate:"required"`
+	Prefix         string `mapstructure:"prefix" validate:""`
+	SASToken       string `mapstructure:"sas_token" validate:""`
+	RetentionMode  string `mapstructure:"retention_mode" validate:"omitempty,oneof=Unlocked Locked"`
+	RetentionDays  int    `mapstructure:"retention_days" validate:"gte=0"`
+}
+
+const (
+	blobURL            string = "https://%v.blob.core.windows.net/%v/%v"
+	resource           string = "https://storage.azure.com/"
+	apiVersion         string = "2021-12-02"
+	defaultContentType string = "text/plain; charset=utf-8"
+)
+
+func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
+	parameters := output.GetParameters()
+	var config Config
+	err := utils.DecodeParams(parameters, &config)
+	if err != nil {
+		return utils.LogLine{
+			Objects: nil,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+
+	config.Prefix, err = data.RenderPrefix(config.Prefix)
+	if err != nil {
+		return utils.LogLine{
+			Objects: nil,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+
+	key := data.GetKey()
+
+	objects := map[string]string{
+		"file":            data.Name,
+		"storage_account": config.StorageAccount,
+		"container":       config.Container,
+		"prefix":          config.Prefix,
+		"key":             key,
+	}
+
+	if err := putBlob(key, config, *data); err != nil {
+		return utils.LogLine{
+			Objects: objects,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+	// the link requires an identity with read access to the container, the SAS token is for the uploads only
+	objects["url"] = strings.ReplaceAll(fmt.Sprintf(blobURL, config.StorageAccount, url.PathEscape(config.Container), url.PathEscape(config.Prefix+key)), "%2F", "/")
+
+	return utils.LogLine{
+		Objects: objects,
+		Output:  fmt.Sprintf("the file '%v' has been uploaded as the blob '%v' to the container '%v'", data.Name, config.Prefix+key, config.Container),
+		Status:  "success",
+	}, nil
+}
+
+func CheckParameters(output *rules.Output) error {
+	parameters := output.GetParameters()
+
+	var config Config
+
+	err := utils.DecodeParams(parameters, &config)
+	if err != nil {
+		return err
+	}
+
+	err = utils.ValidateStruct(config)
+	if err != nil {
+		return err
+	}
+
+	if config.RetentionMode != "" && config.RetentionDays == 0 {
+		return errors.New("`retention_mode` requires `retention_days`")
+	}
+
+	if _, err := new(model.Data).RenderPrefix(config.Prefix); err != nil {
+		return fmt.Errorf("wrong `prefix`: %v", err)
+	}
+
+	return nil
+}
+
+func putBlob(key string, config Config, data model.Data) error {
+	u := fmt.Sprintf(blobURL, config.StorageAccount, url.PathEscape(config.Container), url.PathEscape(config.Prefix+key))
+	u = strings.ReplaceAll(u, "%2F", "/")
+	if config.SASToken != "" {
+		u += "?" + strings.TrimPrefix(config.SASToken, "?")
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPut, u, bytes.NewReader(data.Bytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", apiVersion)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("Content-Type", defaultContentType)
+	req.Header.Set("x-ms-meta-source", utils.FalcoTalonStr)
+	if data.Namespace != "" {
+		req.Header.Set("x-ms-meta-namespace", data.Namespace)
+	}
+	if data.Pod != "" {
+		req.Header.Set("x-ms-meta-pod", data.Pod)
+	}
+	if data.Hostname != "" {
+		req.Header.Set("x-ms-meta-hostname", data.Hostname)
+	}
+	if config.RetentionDays > 0 {
+		until := time.Now().UTC().AddDate(0, 0, config.RetentionDays)
+		req.Header.Set("x-ms-meta-retain_until", until.Format(time.RFC3339))
+		if config.RetentionMode != "" {
+			// requires the version-level immutability support on the container
+			req.Header.Set("x-ms-immutability-policy-until-date", until.Format(http.TimeFormat))
+			req.Header.Set("x-ms-immutability-policy-mode", config.RetentionMode)
+		}
+	}
+
+	if config.SASToken == "" {
+		client := azure.GetAzureClient()
+		if client == nil {
+			return errors.New("client error")
+		}
+		token, err := client.GetToken(resource)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%v: %v", resp.Status, string(b))
+	}
+
+	return nil
+}
@@ -1,9 +1,12 @@
 package file
 
 import (
+	"compress/gzip"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,8 +16,14 @@ import (
 	"github.com/falco-talon/falco-talon/utils"
 )
 
+// the files created by the output are prefixed by their date
+var keyRegex = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}-[0-9]{2}-[0-9]{2}Z_`)
+
 type Config struct {
 	Destination string `mapstructure:"destination" validate:"required"`
+	MaxFiles    int    `mapstructure:"max_files" validate:"gte=0"`
+	MaxAgeDays  int    `mapstructure:"max_age_days" validate:"gte=0"`
+	Compress    bool   `mapstructure:"compress" validate:""`
 }
 
 func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
@@ -29,11 +38,9 @@ func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
 		}, err
 	}
 
-	var key string
-	if data.Namespace != "" && data.Pod != "" {
-		key = fmt.Sprintf("%v_%v_%v_%v", time.Now().Format("2006-01-02T15-04-05Z"), data.Namespace, data.Pod, strings.ReplaceAll(data.Name, "/", "_"))
-	} else {
-		key = fmt.Sprintf("%v_%v_%v", time.Now().Format("2006-01-02T15-04-05Z"), data.Hostname, strings.ReplaceAll(data.Name, "/", "_"))
+	key := data.GetKey()
+	if config.Compress {
+		key += ".gz"
 	}
 
 	dstfile := fmt.Sprintf("%v/%v", strings.TrimSuffix(config.Destination, "/"), key)
@@ -43,7 +50,7 @@ func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
 		"destination": dstfile,
 	}
 
-	if err := os.WriteFile(dstfile, data.Bytes, 0600); err != nil {
+	if err := writeFile(dstfile, data.Bytes, config.Compress); err != nil {
 		return utils.LogLine{
 			Objects: objects,
 			Error:   err.Error(),
@@ -51,6 +58,10 @@ func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
 		}, err
 	}
 
+	if err := cleanFolder(config); err != nil {
+		utils.PrintLog("warning", utils.LogLine{Message: "output", OutputCategory: "local", Error: err.Error()})
+	}
+
 	return utils.LogLine{
 		Objects: objects,
 		Output:  fmt.Sprintf("the file '%v' has been copied to '%v'", filepath.Base(data.Name), dstfile),
@@ -93,3 +104,59 @@ func CheckFolderExist(output *rules.Output, event *events.Event) error {
 
 	return nil
 }
+
+func writeFile(dstfile string, b []byte, compress bool) error {
+	if !compress {
+		return os.WriteFile(dstfile, b, 0600)
+	}
+
+	f, err := os.OpenFile(dstfile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := gzip.NewWriter(f)
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// cleanFolder removes the oldest files created by the output in the destination, according to `max_files` and `max_age_days`
+func cleanFolder(config Config) error {
+	if config.MaxFiles == 0 && config.MaxAgeDays == 0 {
+		return nil
+	}
+
+	entries, err := os.ReadDir(config.Destination)
+	if err != nil {
+		return err
+	}
+
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, i := range entries {
+		if i.IsDir() || !keyRegex.MatchString(i.Name()) {
+			continue
+		}
+		info, err := i.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+
+	limit := time.Now().AddDate(0, 0, -config.MaxAgeDays)
+	for i, j := range files {
+		if (config.MaxFiles != 0 && i >= config.MaxFiles) || (config.MaxAgeDays != 0 && j.ModTime().Before(limit)) {
+			if err := os.Remove(filepath.Join(config.Destination, j.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	gcp "github.com/falco-talon/falco-talon/internal/gcp/client"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/outputs/model"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Config struct {
+	Bucket        string `mapstructure:"bucket" validate:"required"`
+	Prefix        string `mapstructure:"prefix" validate:""`
+	RetentionMode string `mapstructure:"retention_mode" validate:"omitempty,oneof=Unlocked Locked"`
+	RetentionDays int    `mapstructure:"retention_days" validate:"gte=0"`
+}
+
+type objectMetadata struct {
+	Retention   *retention        `json:"retention,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Name        string            `json:"name"`
+	ContentType string            `json:"contentType"`
+}
+
+type retention struct {
+	Mode            string `json:"mode"`
+	RetainUntilTime string `json:"retainUntilTime"`
+}
+
+const (
+	uploadURL          string = "https://storage.googleapis.com/upload/storage/v1/b/%v/o?uploadType=multipart"
+	objectURL          string = "https://storage.cloud.google.com/%v/%v"
+	scope              string = "https://www.googleapis.com/auth/devstorage.read_write"
+	defaultContentType string = "text/plain; charset=utf-8"
+)
+
+func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
+	parameters := output.GetParameters()
+	var config Config
+	err := utils.DecodeParams(parameters, &config)
+	if err != nil {
+		return utils.LogLine{
+			Objects: nil,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+
+	config.Prefix, err = data.RenderPrefix(config.Prefix)
+	if err != nil {
+		return utils.LogLine{
+			Objects: nil,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+
+	key := data.GetKey()
+
+	objects := map[string]string{
+		"file":   data.Name,
+		"bucket": config.Bucket,
+		"prefix": config.Prefix,
+		"key":    key,
+	}
+
+	if err := putObject(key, config, *data); err != nil {
+		return utils.LogLine{
+			Objects: objects,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+	// the link requires an identity with read access to the bucket
+	objects["url"] = fmt.Sprintf(objectURL, config.Bucket, strings.ReplaceAll(url.PathEscape(config.Prefix+key), "%2F", "/"))
+
+	return utils.LogLine{
+		Objects: objects,
+		Output:  fmt.Sprintf("the file '%v' has been uploaded as the key '%v' to the bucket '%v'", data.Name, config.Prefix+key, config.Bucket),
+		Status:  "success",
+	}, nil
+}
+
+func CheckParameters(output *rules.Output) error {
+	parameters := output.GetParameters()
+
+	var config Config
+
+	err := utils.DecodeParams(parameters, &config)
+	if err != nil {
+		return err
+	}
+
+	err = utils.ValidateStruct(config)
+	if err != nil {
+		return err
+	}
+
+	if config.RetentionMode != "" && config.RetentionDays == 0 {
+		return errors.New("`retention_mode` requires `retention_days`")
+	}
+
+	if _, err := new(model.Data).RenderPrefix(config.Prefix); err != nil {
+		return fmt.Errorf("wrong `prefix`: %v", err)
+	}
+
+	return nil
+}
+
+func putObject(key string, config Config, data model.Data) error {
+	client := gcp.GetGCPClient()
+	if client == nil {
+		return errors.New("client error")
+	}
+
+	token, err := client.GetToken(scope)
+	if err != nil {
+		return err
+	}
+
+	metadata := objectMetadata{
+		Name:        config.Prefix + key,
+		ContentType: defaultContentType,
+		Metadata: map[string]string{
+			"source": utils.FalcoTalonStr,
+		},
+	}
+	if data.Namespace != "" {
+		metadata.Metadata["namespace"] = data.Namespace
+	}
+	if data.Pod != "" {
+		metadata.Metadata["pod"] = data.Pod
+	}
+	if data.Hostname != "" {
+		metadata.Metadata["hostname"] = data.Hostname
+	}
+	if config.RetentionDays > 0 {
+		until := time.Now().UTC().AddDate(0, 0, config.RetentionDays).Format(time.RFC3339)
+		metadata.Metadata["retain-until"] = until
+		if config.RetentionMode != "" {
+			metadata.Retention = &retention{Mode: config.RetentionMode, RetainUntilTime: until}
+		}
+	}
+
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", "application/json; charset=UTF-8")
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(part).Encode(metadata); err != nil {
+		return err
+	}
+
+	h = textproto.MIMEHeader{}
+	h.Set("Content-Type", defaultContentType)
+	part, err = w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data.Bytes); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, fmt.Sprintf(uploadURL, url.PathEscape(config.Bucket)), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "multipart/related; boundary="+w.Boundary())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%v: %v", resp.Status, string(b))
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package outputs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+
+	"github.com/falco-talon/falco-talon/configuration"
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/outputs/model"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+// Manifest describes a stored artifact, it allows to check its integrity
+type Manifest struct {
+	Location  map[string]string `json:"location"`
+	Version   string            `json:"version"`
+	File      string            `json:"file"`
+	SHA256    string            `json:"sha256"`
+	Time      string            `json:"time"`
+	Target    string            `json:"target"`
+	Rule      string            `json:"rule,omitempty"`
+	Action    string            `json:"action,omitempty"`
+	TraceID   string            `json:"trace_id,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Pod       string            `json:"pod,omitempty"`
+	Hostname  string            `json:"hostname,omitempty"`
+	Size      int               `json:"size"`
+}
+
+const (
+	manifestVersion string = "1"
+	manifestSuffix  string = ".manifest.json"
+	signatureSuffix string = ".sig"
+)
+
+// signer is replaced by the reloads of the configuration while the artifacts are stored
+var signer atomic.Pointer[signature.Signer]
+
+// initSigner loads the key used to sign the manifests
+func initSigner() error {
+	s, err := loadSigner(configuration.GetConfiguration().Integrity.SigningKeyFile)
+	if err != nil {
+		return err
+	}
+	setSigner(s)
+	return nil
+}
+
+func setSigner(s signature.Signer) {
+	if s == nil {
+		signer.Store(nil)
+		return
+	}
+	signer.Store(&s)
+}
+
+// ReloadSigner loads the signing key of a new configuration, the returned function replaces the
+// current key, which is kept if the new one is wrong
+func ReloadSigner(config configuration.IntegrityConfig) (func(), error) {
+	s, err := loadSigner(config.SigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return func() { setSigner(s) }, nil
+}
+
+// loadSigner reads the signing key with the loader of sigstore, the cosign keys are decrypted with the password
+// in the COSIGN_PASSWORD env var
+func loadSigner(keyFile string) (signature.Signer, error) {
+	if keyFile == "" {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := cryptoutils.UnmarshalPEMToPrivateKey(b, cryptoutils.StaticPasswordFunc([]byte(os.Getenv("COSIGN_PASSWORD"))))
+	if err != nil {
+		return nil, fmt.Errorf("wrong signing key: %v", err)
+	}
+	return signature.LoadSigner(key, crypto.SHA256)
+}
+
+// sign returns the base64 encoded signature of the payload, in the format of `cosign sign-blob`
+func sign(s signature.Signer, payload []byte) (string, error) {
+	sig, err := s.SignMessage(bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// Store sends the data to the output, with its SHA256 digest in the objects of the result,
+// and stores a manifest, signed if a key is set, next to the artifact if enabled
+func (output *Output) Store(params *rules.Output, data *model.Data, log utils.LogLine) (utils.LogLine, error) {
+	hash := sha256.Sum256(data.Bytes)
+	digest := hex.EncodeToString(hash[:])
+	if data.Time.IsZero() {
+		// the manifest and the signature get the key and the prefix of the artifact
+		data.Time = time.Now().UTC()
+	}
+
+	result, err := output.Output(params, data)
+	if result.Objects == nil {
+		result.Objects = make(map[string]string)
+	}
+	result.Objects["sha256"] = digest
+	if err != nil || !configuration.GetConfiguration().Integrity.Manifest {
+		return result, err
+	}
+
+	location := make(map[string]string, len(result.Objects))
+	for i, j := range result.Objects {
+		location[i] = j
+	}
+	manifest, err := json.MarshalIndent(Manifest{
+		Version:   manifestVersion,
+		File:      data.Name,
+		SHA256:    digest,
+		Size:      len(data.Bytes),
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		Target:    output.GetFullName(),
+		Location:  location,
+		Rule:      log.Rule,
+		Action:    log.Action,
+		TraceID:   log.TraceID,
+		Namespace: data.Namespace,
+		Pod:       data.Pod,
+		Hostname:  data.Hostname,
+	}, "", "  ")
+	if err != nil {
+		return storeFailure(result, err)
+	}
+
+	m := &model.Data{Name: data.Name, Namespace: data.Namespace, Pod: data.Pod, Hostname: data.Hostname, Time: data.Time, Suffix: manifestSuffix, Bytes: manifest}
+	if _, err := output.Output(params, m); err != nil {
+		return storeFailure(result, fmt.Errorf("can't store the manifest: %v", err))
+	}
+	result.Objects["manifest"] = m.GetKey()
+
+	s := signer.Load()
+	if s == nil {
+		return result, nil
+	}
+
+	sig, err := sign(*s, manifest)
+	if err != nil {
+		return storeFailure(result, err)
+	}
+	d := &model.Data{Name: data.Name, Namespace: data.Namespace, Pod: data.Pod, Hostname: data.Hostname, Time: data.Time, Suffix: manifestSuffix + signatureSuffix, Bytes: []byte(sig)}
+	if _, err := output.Output(params, d); err != nil {
+		return storeFailure(result, fmt.Errorf("can't store the signature: %v", err))
+	}
+	result.Objects["signature"] = d.GetKey()
+
+	return result, nil
+}
+
+func storeFailure(result utils.LogLine, err error) (utils.LogLine, error) {
+	result.Status = "failure"
+	result.Error = err.Error()
+	return result, err
+}
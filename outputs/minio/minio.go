@@ -5,7 +5,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	miniosdk "github.com/minio/minio-go/v7"
@@ -18,8 +17,9 @@ import (
 )
 
 type Config struct {
-	Bucket string `mapstructure:"bucket" validate:"required"`
-	Prefix string `mapstructure:"prefix" validate:""`
+	Bucket        string `mapstructure:"bucket" validate:"required"`
+	Prefix        string `mapstructure:"prefix" validate:""`
+	PresignExpiry int    `mapstructure:"presign_expiry_minutes" validate:"gte=0,lte=10080"` // 7 days max for the SigV4 signatures
 }
 
 const (
@@ -38,15 +38,17 @@ func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
 		}, err
 	}
 
-	config.Prefix = strings.TrimSuffix(config.Prefix, "/") + "/"
-
-	var key string
-	if data.Namespace != "" && data.Pod != "" {
-		key = fmt.Sprintf("%v_%v_%v_%v", time.Now().Format("2006-01-02T15-04-05Z"), data.Namespace, data.Pod, strings.ReplaceAll(data.Name, "/", "_"))
-	} else {
-		key = fmt.Sprintf("%v_%v_%v", time.Now().Format("2006-01-02T15-04-05Z"), data.Hostname, strings.ReplaceAll(data.Name, "/", "_"))
+	config.Prefix, err = data.RenderPrefix(config.Prefix)
+	if err != nil {
+		return utils.LogLine{
+			Objects: nil,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
 	}
 
+	key := data.GetKey()
+
 	objects := map[string]string{
 		"file":   data.Name,
 		"bucket": config.Bucket,
@@ -62,6 +64,16 @@ func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
 		}, err
 	}
 
+	if config.PresignExpiry != 0 {
+		// the upload succeeded, the notifications are sent without the link if it can't be signed
+		u, err := minio.GetClient().PresignedGetObject(context.Background(), config.Bucket, config.Prefix+key, time.Duration(config.PresignExpiry)*time.Minute, nil)
+		if err != nil {
+			utils.PrintLog("warning", utils.LogLine{Message: "output", Target: "minio:s3", Error: err.Error(), Result: "the pre-signed url can't be created"})
+		} else {
+			objects["url"] = u.String()
+		}
+	}
+
 	return utils.LogLine{
 		Objects: objects,
 		Output:  fmt.Sprintf("the file '%v' has been uploaded as the key '%v' to the bucket '%v'", data.Name, config.Prefix+key, config.Bucket),
@@ -84,6 +96,10 @@ func CheckParameters(output *rules.Output) error {
 		return err
 	}
 
+	if _, err := new(model.Data).RenderPrefix(config.Prefix); err != nil {
+		return fmt.Errorf("wrong `prefix`: %v", err)
+	}
+
 	return nil
 }
 
@@ -1,9 +1,83 @@
 package model
 
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
 type Data struct {
 	Name      string
 	Namespace string
 	Pod       string
 	Hostname  string
 	Bytes     []byte
+	// Time is the date in the key and the prefix, now if it's zero
+	Time time.Time
+	// Suffix is appended to the key, the objects stored next to an artifact have its key with their suffix
+	Suffix string
+}
+
+// prefixData contains the fields available in the templated prefixes
+type prefixData struct {
+	Name      string
+	Namespace string
+	Pod       string
+	Hostname  string
+	Date      string
+	Year      string
+	Month     string
+	Day       string
+	Hour      string
+}
+
+// GetKey returns the name of the object to store, with the date and the origin of the data
+func (data *Data) GetKey() string {
+	now := data.getTime().Format("2006-01-02T15-04-05Z")
+	if data.Namespace != "" && data.Pod != "" {
+		return fmt.Sprintf("%v_%v_%v_%v%v", now, data.Namespace, data.Pod, strings.ReplaceAll(data.Name, "/", "_"), data.Suffix)
+	}
+	return fmt.Sprintf("%v_%v_%v%v", now, data.Hostname, strings.ReplaceAll(data.Name, "/", "_"), data.Suffix)
+}
+
+func (data *Data) getTime() time.Time {
+	if data.Time.IsZero() {
+		return time.Now().UTC()
+	}
+	return data.Time.UTC()
+}
+
+// RenderPrefix executes the prefix as a template, eg: "{{ .Namespace }}/{{ .Pod }}/{{ .Date }}",
+// the returned prefix is empty or ends with a "/"
+func (data *Data) RenderPrefix(prefix string) (string, error) {
+	if strings.Contains(prefix, "{{") {
+		t, err := template.New("prefix").Option("missingkey=zero").Parse(prefix)
+		if err != nil {
+			return "", err
+		}
+		now := data.getTime()
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, prefixData{
+			Name:      data.Name,
+			Namespace: data.Namespace,
+			Pod:       data.Pod,
+			Hostname:  data.Hostname,
+			Date:      now.Format("2006-01-02"),
+			Year:      now.Format("2006"),
+			Month:     now.Format("01"),
+			Day:       now.Format("02"),
+			Hour:      now.Format("15"),
+		}); err != nil {
+			return "", err
+		}
+		prefix = buf.String()
+	}
+
+	prefix = strings.Trim(strings.ReplaceAll(prefix, "//", "/"), "/")
+	if prefix == "" {
+		return "", nil
+	}
+	return prefix + "/", nil
 }
@@ -1,15 +1,21 @@
 package outputs
 
 import (
+	"errors"
 	"fmt"
 
 	aws "github.com/falco-talon/falco-talon/internal/aws/client"
+	azure "github.com/falco-talon/falco-talon/internal/azure/client"
 	"github.com/falco-talon/falco-talon/internal/events"
+	gcp "github.com/falco-talon/falco-talon/internal/gcp/client"
 	minio "github.com/falco-talon/falco-talon/internal/minio/client"
 	"github.com/falco-talon/falco-talon/internal/rules"
 	awss3Out "github.com/falco-talon/falco-talon/outputs/aws/s3"
+	azureBlobOut "github.com/falco-talon/falco-talon/outputs/azure/blob"
 	"github.com/falco-talon/falco-talon/outputs/file"
+	gcpGcsOut "github.com/falco-talon/falco-talon/outputs/gcp/gcs"
 	minioOut "github.com/falco-talon/falco-talon/outputs/minio"
+	"github.com/falco-talon/falco-talon/outputs/sftp"
 
 	"github.com/falco-talon/falco-talon/outputs/model"
 	"github.com/falco-talon/falco-talon/utils"
@@ -70,6 +76,27 @@ func GetDefaultOutputs() *Outputs {
 				CheckParameters: awss3Out.CheckParameters,
 				Output:          awss3Out.Output,
 			},
+			&Output{
+				Category:        "gcp",
+				Name:            "gcs",
+				Init:            gcp.Init,
+				CheckParameters: gcpGcsOut.CheckParameters,
+				Output:          gcpGcsOut.Output,
+			},
+			&Output{
+				Category:        "azure",
+				Name:            "blob",
+				Init:            azure.Init,
+				CheckParameters: azureBlobOut.CheckParameters,
+				Output:          azureBlobOut.Output,
+			},
+			&Output{
+				Category:        "ssh",
+				Name:            "sftp",
+				Init:            nil,
+				CheckParameters: sftp.CheckParameters,
+				Output:          sftp.Output,
+			},
 		)
 	}
 
@@ -101,6 +128,11 @@ func (outputs *Outputs) FindOutput(fullname string) *Output {
 }
 
 func Init() error {
+	if err := initSigner(); err != nil {
+		utils.PrintLog("error", utils.LogLine{Message: "init", Error: err.Error(), Result: "signing key"})
+		return err
+	}
+
 	rules := rules.GetRules()
 
 	categories := map[string]bool{}
@@ -108,6 +140,11 @@ func Init() error {
 
 	// list actionner categories to init
 	for _, i := range *rules {
+		if r := i.GetReport(); r != nil {
+			if o := GetDefaultOutputs().FindOutput(r.Output.Target); o != nil {
+				categories[o.GetCategory()] = true
+			}
+		}
 		for _, j := range i.Actions {
 			if j.GetOutput() != nil {
 				if o := GetDefaultOutputs().FindOutput(j.GetOutput().Target); o != nil {
@@ -155,3 +192,18 @@ func (output *Output) GetName() string {
 func (output *Output) GetCategory() string {
 	return output.Category
 }
+
+// CheckOutput returns an error if the target of the output is unknown or if its parameters are wrong
+func CheckOutput(output *rules.Output) error {
+	o := GetDefaultOutputs().FindOutput(output.GetTarget())
+	if o == nil {
+		return fmt.Errorf("unknown target '%v'", output.GetTarget())
+	}
+	if len(output.Parameters) == 0 {
+		return errors.New("missing parameters for the output")
+	}
+	if o.CheckParameters != nil {
+		return o.CheckParameters(output)
+	}
+	return nil
+}
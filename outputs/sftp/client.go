@@ -0,0 +1,199 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// minimal client of the version 3 of the SFTP protocol, enough to upload files
+
+const (
+	fxpInit    byte = 1
+	fxpVersion byte = 2
+	fxpOpen    byte = 3
+	fxpClose   byte = 4
+	fxpWrite   byte = 6
+	fxpRename  byte = 18
+	fxpStatus  byte = 101
+	fxpHandle  byte = 102
+
+	fxfWrite uint32 = 0x02
+	fxfCreat uint32 = 0x08
+	fxfTrunc uint32 = 0x10
+
+	attrPermissions uint32 = 0x04
+
+	fxOK uint32 = 0
+
+	protocolVersion uint32 = 3
+	maxChunkSize    int    = 32768
+)
+
+type client struct {
+	session *ssh.Session
+	w       io.WriteCloser
+	r       io.Reader
+	id      uint32
+}
+
+func newClient(conn *ssh.Client) (*client, error) {
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	w, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	c := &client{session: session, w: w, r: r}
+
+	if err := c.send(fxpInit, uint32Bytes(protocolVersion)); err != nil {
+		c.Close()
+		return nil, err
+	}
+	t, _, err := c.recv()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if t != fxpVersion {
+		c.Close()
+		return nil, fmt.Errorf("unexpected sftp packet type %v", t)
+	}
+
+	return c, nil
+}
+
+func (c *client) Close() error {
+	c.w.Close()
+	return c.session.Close()
+}
+
+// Upload writes the content into a temporary file renamed once complete,
+// to not leave a truncated file on the server if the upload fails
+func (c *client) Upload(path string, b []byte) error {
+	tmp := path + ".part"
+
+	payload := stringBytes(tmp)
+	payload = append(payload, uint32Bytes(fxfWrite|fxfCreat|fxfTrunc)...)
+	payload = append(payload, uint32Bytes(attrPermissions)...)
+	payload = append(payload, uint32Bytes(0600)...)
+	handle, err := c.request(fxpOpen, payload)
+	if err != nil {
+		return err
+	}
+
+	for offset := 0; offset < len(b); offset += maxChunkSize {
+		end := offset + maxChunkSize
+		if end > len(b) {
+			end = len(b)
+		}
+		payload := stringBytes(string(handle))
+		payload = binary.BigEndian.AppendUint64(payload, uint64(offset))
+		payload = append(payload, stringBytes(string(b[offset:end]))...)
+		if _, err := c.request(fxpWrite, payload); err != nil {
+			c.request(fxpClose, stringBytes(string(handle))) //nolint:errcheck
+			return err
+		}
+	}
+
+	if _, err := c.request(fxpClose, stringBytes(string(handle))); err != nil {
+		return err
+	}
+
+	_, err = c.request(fxpRename, append(stringBytes(tmp), stringBytes(path)...))
+	return err
+}
+
+// request sends a packet with a new id and returns the handle or an error from the status
+func (c *client) request(t byte, payload []byte) ([]byte, error) {
+	c.id++
+	if err := c.send(t, append(uint32Bytes(c.id), payload...)); err != nil {
+		return nil, err
+	}
+
+	rt, data, err := c.recv()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != c.id {
+		return nil, errors.New("unexpected sftp response id")
+	}
+	data = data[4:]
+
+	switch rt {
+	case fxpHandle:
+		handle, _, err := readString(data)
+		return handle, err
+	case fxpStatus:
+		if len(data) < 4 {
+			return nil, errors.New("malformed sftp status")
+		}
+		code := binary.BigEndian.Uint32(data)
+		if code == fxOK {
+			return nil, nil
+		}
+		msg, _, _ := readString(data[4:])
+		return nil, fmt.Errorf("sftp error %v: %v", code, string(msg))
+	default:
+		return nil, fmt.Errorf("unexpected sftp packet type %v", rt)
+	}
+}
+
+func (c *client) send(t byte, payload []byte) error {
+	packet := uint32Bytes(uint32(len(payload) + 1))
+	packet = append(packet, t)
+	packet = append(packet, payload...)
+	_, err := c.w.Write(packet)
+	return err
+}
+
+func (c *client) recv() (byte, []byte, error) {
+	var l [4]byte
+	if _, err := io.ReadFull(c.r, l[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(l[:])
+	if length == 0 || length > 256*1024 {
+		return 0, nil, errors.New("malformed sftp packet")
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return 0, nil, err
+	}
+	return b[0], b[1:], nil
+}
+
+func uint32Bytes(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func stringBytes(s string) []byte {
+	return append(uint32Bytes(uint32(len(s))), s...)
+}
+
+func readString(b []byte) ([]byte, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("malformed sftp string")
+	}
+	l := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < l {
+		return nil, nil, errors.New("malformed sftp string")
+	}
+	return b[4 : 4+l], b[4+l:], nil
+}
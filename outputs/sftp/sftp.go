@@ -0,0 +1,170 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/falco-talon/falco-talon/internal/rules"
+	"github.com/falco-talon/falco-talon/outputs/model"
+	"github.com/falco-talon/falco-talon/utils"
+)
+
+type Config struct {
+	Host           string `mapstructure:"host" validate:"required"`
+	User           string `mapstructure:"user" validate:"required"`
+	PrivateKeyFile string `mapstructure:"private_key_file" validate:"required"`
+	Passphrase     string `mapstructure:"passphrase" validate:""`
+	HostKey        string `mapstructure:"host_key" validate:"required"`
+	Destination    string `mapstructure:"destination" validate:"required"`
+	Port           int    `mapstructure:"port" validate:"omitempty,gte=1,lte=65535"`
+}
+
+const (
+	defaultPort int = 22
+	dialTimeout     = 10 * time.Second
+)
+
+func Output(output *rules.Output, data *model.Data) (utils.LogLine, error) {
+	parameters := output.GetParameters()
+	var config Config
+	err := utils.DecodeParams(parameters, &config)
+	if err != nil {
+		return utils.LogLine{
+			Objects: nil,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+
+	prefix, err := data.RenderPrefix(config.Destination)
+	if err != nil {
+		return utils.LogLine{
+			Objects: nil,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+	if strings.HasPrefix(config.Destination, "/") {
+		prefix = "/" + prefix
+	}
+
+	dstfile := path.Join(prefix, data.GetKey())
+
+	objects := map[string]string{
+		"file":        data.Name,
+		"host":        config.Host,
+		"destination": dstfile,
+	}
+
+	if err := upload(config, dstfile, data.Bytes); err != nil {
+		return utils.LogLine{
+			Objects: objects,
+			Error:   err.Error(),
+			Status:  "failure",
+		}, err
+	}
+
+	return utils.LogLine{
+		Objects: objects,
+		Output:  fmt.Sprintf("the file '%v' has been uploaded to '%v' on '%v'", data.Name, dstfile, config.Host),
+		Status:  "success",
+	}, nil
+}
+
+func CheckParameters(output *rules.Output) error {
+	parameters := output.GetParameters()
+
+	var config Config
+
+	err := utils.DecodeParams(parameters, &config)
+	if err != nil {
+		return err
+	}
+
+	err = utils.ValidateStruct(config)
+	if err != nil {
+		return err
+	}
+
+	if _, err := getHostKeyCallback(config.HostKey); err != nil {
+		return err
+	}
+
+	if _, err := new(model.Data).RenderPrefix(config.Destination); err != nil {
+		return fmt.Errorf("wrong `destination`: %v", err)
+	}
+
+	return nil
+}
+
+func upload(config Config, dstfile string, b []byte) error {
+	key, err := os.ReadFile(config.PrivateKeyFile)
+	if err != nil {
+		return err
+	}
+	var signer ssh.Signer
+	if config.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(config.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+	if err != nil {
+		return err
+	}
+
+	hostKeyCallback, err := getHostKeyCallback(config.HostKey)
+	if err != nil {
+		return err
+	}
+
+	port := config.Port
+	if port == 0 {
+		port = defaultPort
+	}
+
+	conn, err := ssh.Dial("tcp", net.JoinHostPort(config.Host, fmt.Sprintf("%v", port)), &ssh.ClientConfig{
+		User:            config.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         dialTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client, err := newClient(conn)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Upload(dstfile, b)
+}
+
+// getHostKeyCallback pins the host key, it can be a public key in the authorized_keys format
+// or its SHA256 fingerprint, eg: "SHA256:xxxx"
+func getHostKeyCallback(hostKey string) (ssh.HostKeyCallback, error) {
+	hostKey = strings.TrimSpace(hostKey)
+	if strings.HasPrefix(hostKey, "SHA256:") {
+		return func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) != hostKey {
+				return errors.New("host key mismatch")
+			}
+			return nil
+		}, nil
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hostKey))
+	if err != nil {
+		return nil, fmt.Errorf("wrong `host_key`: %v", err)
+	}
+	return ssh.FixedHostKey(key), nil
+}
@@ -0,0 +1,213 @@
+// Enums of the gRPC output of Falco, from userspace/falco/schema.proto of falcosecurity/falco (Apache-2.0).
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: falco/schema/schema.proto
+
+package schema
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Priority int32
+
+const (
+	Priority_EMERGENCY     Priority = 0
+	Priority_ALERT         Priority = 1
+	Priority_CRITICAL      Priority = 2
+	Priority_ERROR         Priority = 3
+	Priority_WARNING       Priority = 4
+	Priority_NOTICE        Priority = 5
+	Priority_INFORMATIONAL Priority = 6
+	Priority_DEBUG         Priority = 7
+)
+
+// Enum value maps for Priority.
+var (
+	Priority_name = map[int32]string{
+		0: "EMERGENCY",
+		1: "ALERT",
+		2: "CRITICAL",
+		3: "ERROR",
+		4: "WARNING",
+		5: "NOTICE",
+		6: "INFORMATIONAL",
+		7: "DEBUG",
+	}
+	Priority_value = map[string]int32{
+		"EMERGENCY":     0,
+		"ALERT":         1,
+		"CRITICAL":      2,
+		"ERROR":         3,
+		"WARNING":       4,
+		"NOTICE":        5,
+		"INFORMATIONAL": 6,
+		"DEBUG":         7,
+	}
+)
+
+func (x Priority) Enum() *Priority {
+	p := new(Priority)
+	*p = x
+	return p
+}
+
+func (x Priority) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Priority) Descriptor() protoreflect.EnumDescriptor {
+	return file_falco_schema_schema_proto_enumTypes[0].Descriptor()
+}
+
+func (Priority) Type() protoreflect.EnumType {
+	return &file_falco_schema_schema_proto_enumTypes[0]
+}
+
+func (x Priority) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Priority.Descriptor instead.
+func (Priority) EnumDescriptor() ([]byte, []int) {
+	return file_falco_schema_schema_proto_rawDescGZIP(), []int{0}
+}
+
+type Source int32
+
+const (
+	Source_SYSCALL   Source = 0
+	Source_K8S_AUDIT Source = 1
+	Source_INTERNAL  Source = 2
+	Source_PLUGIN    Source = 3
+)
+
+// Enum value maps for Source.
+var (
+	Source_name = map[int32]string{
+		0: "SYSCALL",
+		1: "K8S_AUDIT",
+		2: "INTERNAL",
+		3: "PLUGIN",
+	}
+	Source_value = map[string]int32{
+		"SYSCALL":   0,
+		"K8S_AUDIT": 1,
+		"INTERNAL":  2,
+		"PLUGIN":    3,
+	}
+)
+
+func (x Source) Enum() *Source {
+	p := new(Source)
+	*p = x
+	return p
+}
+
+func (x Source) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Source) Descriptor() protoreflect.EnumDescriptor {
+	return file_falco_schema_schema_proto_enumTypes[1].Descriptor()
+}
+
+func (Source) Type() protoreflect.EnumType {
+	return &file_falco_schema_schema_proto_enumTypes[1]
+}
+
+func (x Source) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Source.Descriptor instead.
+func (Source) EnumDescriptor() ([]byte, []int) {
+	return file_falco_schema_schema_proto_rawDescGZIP(), []int{1}
+}
+
+var File_falco_schema_schema_proto protoreflect.FileDescriptor
+
+var file_falco_schema_schema_proto_rawDesc = []byte{
+	0x0a, 0x19, 0x66, 0x61, 0x6c, 0x63, 0x6f, 0x2f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2f, 0x73,
+	0x63, 0x68, 0x65, 0x6d, 0x61, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0c, 0x66, 0x61, 0x6c,
+	0x63, 0x6f, 0x2e, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x2a, 0x74, 0x0a, 0x08, 0x70, 0x72, 0x69,
+	0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x0d, 0x0a, 0x09, 0x45, 0x4d, 0x45, 0x52, 0x47, 0x45, 0x4e,
+	0x43, 0x59, 0x10, 0x00, 0x12, 0x09, 0x0a, 0x05, 0x41, 0x4c, 0x45, 0x52, 0x54, 0x10, 0x01, 0x12,
+	0x0c, 0x0a, 0x08, 0x43, 0x52, 0x49, 0x54, 0x49, 0x43, 0x41, 0x4c, 0x10, 0x02, 0x12, 0x09, 0x0a,
+	0x05, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07, 0x57, 0x41, 0x52, 0x4e,
+	0x49, 0x4e, 0x47, 0x10, 0x04, 0x12, 0x0a, 0x0a, 0x06, 0x4e, 0x4f, 0x54, 0x49, 0x43, 0x45, 0x10,
+	0x05, 0x12, 0x11, 0x0a, 0x0d, 0x49, 0x4e, 0x46, 0x4f, 0x52, 0x4d, 0x41, 0x54, 0x49, 0x4f, 0x4e,
+	0x41, 0x4c, 0x10, 0x06, 0x12, 0x09, 0x0a, 0x05, 0x44, 0x45, 0x42, 0x55, 0x47, 0x10, 0x07, 0x2a,
+	0x3e, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x59, 0x53,
+	0x43, 0x41, 0x4c, 0x4c, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x4b, 0x38, 0x53, 0x5f, 0x41, 0x55,
+	0x44, 0x49, 0x54, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x4e, 0x41,
+	0x4c, 0x10, 0x02, 0x12, 0x0a, 0x0a, 0x06, 0x50, 0x4c, 0x55, 0x47, 0x49, 0x4e, 0x10, 0x03, 0x42,
+	0x3e, 0x5a, 0x3c, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x66, 0x61,
+	0x6c, 0x63, 0x6f, 0x2d, 0x74, 0x61, 0x6c, 0x6f, 0x6e, 0x2f, 0x66, 0x61, 0x6c, 0x63, 0x6f, 0x2d,
+	0x74, 0x61, 0x6c, 0x6f, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x66, 0x61, 0x6c, 0x63,
+	0x6f, 0x2f, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x3b, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_falco_schema_schema_proto_rawDescOnce sync.Once
+	file_falco_schema_schema_proto_rawDescData = file_falco_schema_schema_proto_rawDesc
+)
+
+func file_falco_schema_schema_proto_rawDescGZIP() []byte {
+	file_falco_schema_schema_proto_rawDescOnce.Do(func() {
+		file_falco_schema_schema_proto_rawDescData = protoimpl.X.CompressGZIP(file_falco_schema_schema_proto_rawDescData)
+	})
+	return file_falco_schema_schema_proto_rawDescData
+}
+
+var file_falco_schema_schema_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_falco_schema_schema_proto_goTypes = []any{
+	(Priority)(0), // 0: falco.schema.priority
+	(Source)(0),   // 1: falco.schema.source
+}
+var file_falco_schema_schema_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_falco_schema_schema_proto_init() }
+func file_falco_schema_schema_proto_init() {
+	if File_falco_schema_schema_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_falco_schema_schema_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   0,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_falco_schema_schema_proto_goTypes,
+		DependencyIndexes: file_falco_schema_schema_proto_depIdxs,
+		EnumInfos:         file_falco_schema_schema_proto_enumTypes,
+	}.Build()
+	File_falco_schema_schema_proto = out.File
+	file_falco_schema_schema_proto_rawDesc = nil
+	file_falco_schema_schema_proto_goTypes = nil
+	file_falco_schema_schema_proto_depIdxs = nil
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"os"
+	"regexp"
+)
+
+// ${VAR} or ${VAR:-default}, $${ is an escaped ${
+var regEnvVar = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// ExpandEnv replaces the ${VAR} and ${VAR:-default} by the values of the env vars, the default is used
+// if the var is unset or empty, the $VAR syntax isn't expanded to keep the shell scripts as they are.
+// The undefined vars without default are replaced by an empty string, with a warning.
+func ExpandEnv(s string) string {
+	return expandEnv(s, false)
+}
+
+// ExpandDefinedEnv is as ExpandEnv but the undefined vars without default are kept, they can be the fields
+// of the events, expanded later by the actionners
+func ExpandDefinedEnv(s string) string {
+	return expandEnv(s, true)
+}
+
+func expandEnv(s string, keepUndefined bool) string {
+	return regEnvVar.ReplaceAllStringFunc(s, func(m string) string {
+		if m == "$${" {
+			return "${"
+		}
+		sub := regEnvVar.FindStringSubmatch(m)
+		if v := os.Getenv(sub[1]); v != "" {
+			return v
+		}
+		if sub[2] != "" {
+			return sub[3]
+		}
+		if _, ok := os.LookupEnv(sub[1]); !ok {
+			if keepUndefined {
+				return m
+			}
+			PrintLog(warningStr, LogLine{Error: "undefined environment variable '" + sub[1] + "'", Message: "config"})
+		}
+		return ""
+	})
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// modules of the log lines, each one can have its own level
+const (
+	EngineModule     string = "engine"
+	ActionnersModule string = "actionners"
+	NotifiersModule  string = "notifiers"
+	KubernetesModule string = "kubernetes"
+)
+
+var Modules = []string{EngineModule, ActionnersModule, NotifiersModule, KubernetesModule}
+
+// messages of the log lines and their modules, the other ones belong to the engine
+var messageModules = map[string]string{
+	"action":               ActionnersModule,
+	"actionners":           ActionnersModule,
+	"output":               ActionnersModule,
+	"outputs":              ActionnersModule,
+	"context":              ActionnersModule,
+	"undo":                 ActionnersModule,
+	"holds":                ActionnersModule,
+	"circuit-breaker":      ActionnersModule,
+	"report":               ActionnersModule,
+	"notification":         NotifiersModule,
+	"digest":               NotifiersModule,
+	"dropped_notification": NotifiersModule,
+	"lease":                KubernetesModule,
+	"expiry":               KubernetesModule,
+	"kubernetes-event":     KubernetesModule,
+}
+
+var (
+	defaultLevel = zerolog.InfoLevel
+	moduleLevels = map[string]zerolog.Level{}
+	levelsMu     sync.RWMutex
+)
+
+// SetLogLevels sets the default level and the levels of the modules, it can be called at runtime
+func SetLogLevels(level string, levels map[string]string) error {
+	d, m, err := parseLevels(level, levels)
+	if err != nil {
+		return err
+	}
+
+	levelsMu.Lock()
+	defaultLevel = d
+	moduleLevels = m
+	levelsMu.Unlock()
+	return nil
+}
+
+// CheckLogLevels returns an error if the default level or the levels of the modules are wrong
+func CheckLogLevels(level string, levels map[string]string) error {
+	_, _, err := parseLevels(level, levels)
+	return err
+}
+
+func parseLevels(level string, levels map[string]string) (zerolog.Level, map[string]zerolog.Level, error) {
+	d := zerolog.InfoLevel
+	if level != "" {
+		var err error
+		d, err = parseLevel(level)
+		if err != nil {
+			return d, nil, err
+		}
+	}
+
+	m := make(map[string]zerolog.Level, len(levels))
+	for i, j := range levels {
+		if !isModule(i) {
+			return d, nil, fmt.Errorf("unknown module '%v', must be one of: %v", i, strings.Join(Modules, ", "))
+		}
+		l, err := parseLevel(j)
+		if err != nil {
+			return d, nil, err
+		}
+		m[i] = l
+	}
+	return d, m, nil
+}
+
+// GetLogLevels returns the default level and the levels of the modules
+func GetLogLevels() (string, map[string]string) {
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	levels := make(map[string]string, len(moduleLevels))
+	for i, j := range moduleLevels {
+		levels[i] = formatLevel(j)
+	}
+	return formatLevel(defaultLevel), levels
+}
+
+// GetModule returns the module of the log line
+func GetModule(line LogLine) string {
+	if m, ok := messageModules[line.Message]; ok {
+		return m
+	}
+	switch {
+	case line.Notifier != "":
+		return NotifiersModule
+	case line.Actionner != "":
+		return ActionnersModule
+	default:
+		return EngineModule
+	}
+}
+
+// isLevelEnabled returns if the lines of the level must be printed for the module, the fatal lines are always printed
+func isLevelEnabled(level, module string) bool {
+	l, err := parseLevel(level)
+	if err != nil {
+		l = zerolog.InfoLevel
+	}
+	if l == zerolog.FatalLevel {
+		return true
+	}
+	levelsMu.RLock()
+	defer levelsMu.RUnlock()
+	threshold, ok := moduleLevels[module]
+	if !ok {
+		threshold = defaultLevel
+	}
+	return l >= threshold
+}
+
+func isModule(module string) bool {
+	for _, i := range Modules {
+		if i == module {
+			return true
+		}
+	}
+	return false
+}
+
+func parseLevel(level string) (zerolog.Level, error) {
+	switch strings.ToLower(level) {
+	case debugStr:
+		return zerolog.DebugLevel, nil
+	case "info", "":
+		return zerolog.InfoLevel, nil
+	case warningStr, "warn":
+		return zerolog.WarnLevel, nil
+	case errorStr:
+		return zerolog.ErrorLevel, nil
+	case fatalStr:
+		return zerolog.FatalLevel, nil
+	default:
+		return zerolog.NoLevel, fmt.Errorf("unknown log level '%v', must be one of: debug, info, warning, error, fatal", level)
+	}
+}
+
+func formatLevel(level zerolog.Level) string {
+	if level == zerolog.WarnLevel {
+		return warningStr
+	}
+	return level.String()
+}
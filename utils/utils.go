@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"reflect"
@@ -26,10 +27,12 @@ const (
 	IntStr            string = "int"
 	Int64Str          string = "int64"
 	SliceInterfaceStr string = "[]interface {}"
+	SliceStringStr    string = "[]string"
 	MapStringStr      string = "map[string]string"
 	MapIntStr         string = "map[string]int"
 	MapInterfaceStr   string = "map[string]interface {}"
 
+	debugStr   string = "debug"
 	errorStr   string = "error"
 	warningStr string = "warning"
 	fatalStr   string = "fatal"
@@ -44,6 +47,7 @@ type LogLine struct {
 	Time              string            `json:"time,omitempty"`
 	Objects           map[string]string `json:"objects,omitempty"`
 	TraceID           string            `json:"trace_id,omitempty"`
+	IncidentID        string            `json:"incident_id,omitempty"`
 	Rule              string            `json:"rule,omitempty"`
 	Event             string            `json:"event,omitempty"`
 	Message           string            `json:"message,omitempty"`
@@ -60,11 +64,16 @@ type LogLine struct {
 	Action            string            `json:"action,omitempty"`
 	Error             string            `json:"error,omitempty"`
 	Status            string            `json:"status,omitempty"`
+	Verification      string            `json:"verification,omitempty"` // verified or unverified, the effect of the action is checked after its run
+	RawEvent          string            `json:"raw_event,omitempty"`    // the JSON of the event, for the notifiers with attachments
+	UndoID            string            `json:"undo_id,omitempty"`      // the id to revert the action, `POST /undo/<id>`
 }
 
 var validate *validator.Validate
 var localIP *string
 var logFormat *string
+var logHook func(level string, line LogLine)
+var logOutput io.Writer = os.Stdout
 
 func init() {
 	logFormat = new(string)
@@ -78,12 +87,32 @@ func SetLogFormat(format string) {
 	}
 }
 
+// SetLogHook sets a function called with each log line, to export them
+func SetLogHook(hook func(level string, line LogLine)) {
+	logHook = hook
+}
+
+// SetLogOutput sets the writer of the logs (default: stdout), to keep stdout for a machine-readable output
+func SetLogOutput(w io.Writer) {
+	logOutput = w
+}
+
+// PrintLog prints the line if its level is enabled for its module
 func PrintLog(level string, line LogLine) {
+	module := GetModule(line)
+	if !isLevelEnabled(level, module) {
+		return
+	}
+
+	if logHook != nil {
+		logHook(level, line)
+	}
+
 	var output zerolog.ConsoleWriter
 
 	var log zerolog.Logger
 	if *logFormat == textStr || *logFormat == colorStr {
-		output = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+		output = zerolog.ConsoleWriter{Out: logOutput, TimeFormat: time.RFC3339}
 		if *logFormat != colorStr {
 			output.NoColor = true
 		}
@@ -92,11 +121,13 @@ func PrintLog(level string, line LogLine) {
 		}
 		log = zerolog.New(output).With().Timestamp().Logger()
 	} else {
-		log = zerolog.New(os.Stdout).With().Timestamp().Logger()
+		log = zerolog.New(logOutput).With().Timestamp().Logger()
 	}
 
 	var l *zerolog.Event
 	switch strings.ToLower(level) {
+	case debugStr:
+		l = log.Debug()
 	case warningStr:
 		l = log.Warn()
 	case errorStr:
@@ -106,6 +137,7 @@ func PrintLog(level string, line LogLine) {
 	default:
 		l = log.Info()
 	}
+	l.Str("module", module)
 	if line.Rule != "" {
 		l.Str("rule", line.Rule)
 	}
@@ -142,6 +174,9 @@ func PrintLog(level string, line LogLine) {
 	if line.Status != "" {
 		l.Str("status", line.Status)
 	}
+	if line.Verification != "" {
+		l.Str("verification", line.Verification)
+	}
 	if line.Target != "" {
 		l.Str("target", line.Target)
 	}
@@ -151,6 +186,9 @@ func PrintLog(level string, line LogLine) {
 	if line.TraceID != "" {
 		l.Str("trace_id", line.TraceID)
 	}
+	if line.IncidentID != "" {
+		l.Str("incident_id", line.IncidentID)
+	}
 	if len(line.Objects) > 0 {
 		for i, j := range line.Objects {
 			l.Str(strings.ToLower(i), j)
@@ -203,7 +241,35 @@ func SetFields(structure interface{}, fields map[string]interface{}) interface{}
 					valueOf.Field(i).SetBool(d)
 				}
 			case MapStringStr:
-				valueOf.Field(i).SetMapIndex(reflect.ValueOf(fields[field]), reflect.ValueOf(fields[field]).Elem())
+				m := make(map[string]string)
+				switch v := fields[field].(type) {
+				case map[string]string:
+					for k, l := range v {
+						m[k] = l
+					}
+				case map[string]interface{}:
+					for k, l := range v {
+						m[k] = fmt.Sprintf("%v", l)
+					}
+				}
+				valueOf.Field(i).Set(reflect.ValueOf(m))
+			case SliceStringStr:
+				var l []string
+				switch v := fields[field].(type) {
+				case []string:
+					l = append(l, v...)
+				case []interface{}:
+					for _, k := range v {
+						l = append(l, fmt.Sprintf("%v", k))
+					}
+				case string:
+					for _, k := range strings.Split(v, ",") {
+						if k = strings.TrimSpace(k); k != "" {
+							l = append(l, k)
+						}
+					}
+				}
+				valueOf.Field(i).Set(reflect.ValueOf(l))
 			}
 		} else if deflt != "" {
 			switch valueOf.Type().Field(i).Type.String() {
@@ -225,6 +291,50 @@ func SetFields(structure interface{}, fields map[string]interface{}) interface{}
 	return structure
 }
 
+// FieldDoc describes a parameter or a setting, from the tags of the field of its struct
+type FieldDoc struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required"`
+}
+
+// DescribeFields returns the docs of the fields of the struct, named with their tag (mapstructure
+// for the parameters of the actionners, field for the settings of the notifiers)
+func DescribeFields(structure interface{}, tag string) []FieldDoc {
+	docs := make([]FieldDoc, 0)
+	if structure == nil {
+		return docs
+	}
+	typeOf := reflect.TypeOf(structure)
+	if typeOf.Kind() == reflect.Ptr {
+		typeOf = typeOf.Elem()
+	}
+	if typeOf.Kind() != reflect.Struct {
+		return docs
+	}
+
+	for i := 0; i < typeOf.NumField(); i++ {
+		field := typeOf.Field(i)
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		doc := FieldDoc{
+			Name:    name,
+			Type:    field.Type.String(),
+			Default: field.Tag.Get("default"),
+		}
+		for _, j := range strings.Split(field.Tag.Get("validate"), ",") {
+			if j == "required" {
+				doc.Required = true
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
 func ValidateStruct(s interface{}) error {
 	err := validate.Struct(s)
 	if err != nil {